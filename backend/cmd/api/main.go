@@ -10,6 +10,8 @@ import (
 	"time"
 
 	"tuyul/backend/internal/config"
+	indodaxex "tuyul/backend/internal/exchange/indodax"
+	"tuyul/backend/internal/exchange/recorder"
 	"tuyul/backend/internal/handler"
 	"tuyul/backend/internal/middleware"
 	"tuyul/backend/internal/model"
@@ -57,8 +59,12 @@ func main() {
 	defer redisClient.Close()
 	log.Info("✓ Redis connected")
 
-	// Initialize Redis key prefix
-	redis.InitKeys(cfg.Redis.Prefix)
+	// Initialize Redis key prefix (replays keep their data apart from the live one)
+	if cfg.Market.ReplayPath != "" {
+		redis.InitKeys(cfg.Redis.Prefix + "replay:")
+	} else {
+		redis.InitKeys(cfg.Redis.Prefix)
+	}
 
 	// Set Gin mode
 	if cfg.Server.IsProduction() {
@@ -75,26 +81,6 @@ func main() {
 	router.Use(middleware.CORS(cfg.CORS.AllowedOrigins)) // CORS
 	// router.Use(middleware.RateLimit(redisClient, cfg.RateLimit.RequestsPerMinute)) // Rate limiting
 
-	// Health check endpoint
-	router.GET("/health", func(c *gin.Context) {
-		// Test Redis connection
-		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
-		defer cancel()
-
-		if err := redisClient.Ping(ctx); err != nil {
-			c.JSON(http.StatusServiceUnavailable, gin.H{
-				"status": "unhealthy",
-				"error":  "Redis connection failed",
-			})
-			return
-		}
-
-		c.JSON(http.StatusOK, gin.H{
-			"status": "healthy",
-			"redis":  "connected",
-		})
-	})
-
 	// Initialize JWT manager
 	jwtManager := jwt.NewJWTManager(
 		cfg.JWT.Secret,
@@ -102,8 +88,32 @@ func main() {
 		cfg.JWT.RefreshTokenExpire,
 	)
 
-	// Initialize Indodax client
+	// Initialize exchange adapter (Indodax REST + public WebSocket)
 	indodaxClient := indodax.NewClient(cfg.Indodax.APIURL)
+	indodaxClient.SetPrivateWSURL(cfg.Indodax.PrivateWSURL)
+	publicWSClient := indodax.NewWSClient(cfg.Indodax.WSURL, cfg.Indodax.WSToken)
+	ex := indodaxex.New(indodaxClient, publicWSClient)
+	marketData := ex.MarketData()
+
+	// Market data replay (public data from recorded files) or recording
+	if cfg.Market.ReplayPath != "" {
+		replay, err := recorder.NewReplay(cfg.Market.ReplayPath, cfg.Market.ReplaySpeed)
+		if err != nil {
+			log.Fatal("Failed to open market data replay", err)
+		}
+		ex = indodaxex.New(indodaxClient, replay)
+		ex.DisableTrading("live trading is disabled while replaying market data")
+		marketData = replay
+		log.Warnf("Replaying market data from %s, live trading is disabled (paper bots only)", cfg.Market.ReplayPath)
+	} else if cfg.Market.RecordDir != "" {
+		rec, err := recorder.New(cfg.Market.RecordDir, time.Duration(cfg.Market.RecordRotate)*time.Minute)
+		if err != nil {
+			log.Fatal("Failed to start market data recorder", err)
+		}
+		defer rec.Close()
+		publicWSClient.AddMessageHandler(rec.RecordFrame)
+		marketData = recorder.NewMarketData(marketData, rec)
+	}
 
 	// Initialize repositories
 	userRepo := repository.NewUserRepository(redisClient)
@@ -113,39 +123,71 @@ func main() {
 	orderRepo := repository.NewOrderRepository(redisClient)
 	posRepo := repository.NewPositionRepository(redisClient)
 	balanceRepo := repository.NewBalanceRepository(redisClient)
+	candleRepo := repository.NewCandleRepository(redisClient, cfg.Market.CandleRetention)
+	scoreProfileRepo := repository.NewPumpScoreProfileRepository(redisClient)
+	screenRepo := repository.NewScreenRepository(redisClient)
+	alertRepo := repository.NewAlertRepository(redisClient)
+	backtestRepo := repository.NewBacktestRepository(redisClient)
 
 	// Initialize services
 	notificationService := service.NewNotificationService(redisClient)
 	authService := service.NewAuthService(userRepo, jwtManager)
-	apiKeyService := service.NewAPIKeyService(apiKeyRepo, userRepo, indodaxClient, notificationService, cfg.Encryption.Key)
+	apiKeyService := service.NewAPIKeyService(apiKeyRepo, userRepo, botRepo, ex, notificationService, cfg.Encryption.Key)
 	userService := service.NewUserService(userRepo, botRepo, tradeRepo, apiKeyService)
 
 	// Initialize Market Analysis services
-	publicWSClient := indodax.NewWSClient(cfg.Indodax.WSURL, cfg.Indodax.WSToken)
-	marketDataService := market.NewMarketDataService(redisClient, publicWSClient, indodaxClient)
-	subManager := market.NewSubscriptionManager(publicWSClient)
+	timeframes, err := model.ParseTimeframes(cfg.Market.Timeframes)
+	if err != nil {
+		log.Fatal("Invalid MARKET_TIMEFRAMES", err)
+	}
+	marketDataService := market.NewMarketDataService(redisClient, marketData, ex.MarketStream(), candleRepo, timeframes)
+	marketDataService.SetDepthConfig(market.DepthConfig{
+		SampleInterval: time.Duration(cfg.Market.DepthSampleInterval) * time.Second,
+		BandPct:        cfg.Market.DepthBandPct,
+		WallMultiplier: cfg.Market.DepthWallMultiplier,
+	})
+	marketDataService.SetFreshnessConfig(market.FreshnessConfig{
+		FeedStaleAfter: time.Duration(cfg.Market.FeedStaleSeconds) * time.Second,
+		PairStaleAfter: time.Duration(cfg.Market.PairStaleSeconds) * time.Second,
+	})
+	subManager := market.NewSubscriptionManager(ex.MarketStream(), marketData)
+	subManager.OnBook(marketDataService.ApplyOrderBook)
+	paperExchange := service.NewPaperExchange(ex.MarketStream(), marketData, subManager, cfg.Market.PaperMakerFeePct, cfg.Market.PaperTakerFeePct)
 	timeframeManager := market.NewTimeframeManager(marketDataService, redisClient)
+	scoreProfileService := market.NewScoreProfileService(scoreProfileRepo, botRepo, marketDataService)
+	if err := scoreProfileService.Load(context.Background()); err != nil {
+		log.Errorf("Failed to load pump score profiles: %v", err)
+	}
 
 	// Start Market Analysis
 	marketDataService.Start()
 	timeframeManager.Start()
 
 	// Initialize Order Monitor
-	orderMonitor := service.NewOrderMonitor(tradeRepo, orderRepo, apiKeyRepo, apiKeyService, notificationService, indodaxClient)
+	orderMonitor := service.NewOrderMonitor(tradeRepo, orderRepo, apiKeyRepo, apiKeyService, notificationService, ex, cfg.Market.LiveFeeFallbackPct)
 	
 	// Set orderMonitor in APIKeyService to enable subscription on API key create/update
 	apiKeyService.SetOrderMonitor(orderMonitor)
 
 	// Initialize Copilot service
-	copilotService := service.NewCopilotService(tradeRepo, orderRepo, balanceRepo, apiKeyService, marketDataService, orderMonitor, indodaxClient)
+	copilotService := service.NewCopilotService(tradeRepo, orderRepo, balanceRepo, apiKeyService, marketDataService, orderMonitor, paperExchange, ex)
 
 	// Initialize Market Maker service
-	mmService := service.NewMarketMakerService(botRepo, orderRepo, apiKeyService, marketDataService, subManager, orderMonitor, notificationService, indodaxClient)
+	// Initialize deadman switch (countdownCancelAll heartbeats for live bots)
+	deadmanService := service.NewDeadmanService(apiKeyService, notificationService, ex)
+
+	mmService := service.NewMarketMakerService(botRepo, orderRepo, apiKeyService, marketDataService, subManager, orderMonitor, notificationService, deadmanService, paperExchange, ex)
 
 	// Initialize Pump Hunter service
-	phService := service.NewPumpHunterService(botRepo, posRepo, orderRepo, apiKeyService, marketDataService, orderMonitor, notificationService, indodaxClient)
+	phService := service.NewPumpHunterService(botRepo, posRepo, orderRepo, apiKeyService, marketDataService, orderMonitor, notificationService, deadmanService, paperExchange, ex)
+
+	// Initialize Grid service
+	gridService := service.NewGridService(botRepo, orderRepo, apiKeyService, marketDataService, orderMonitor, notificationService, deadmanService, paperExchange, ex, cfg.Market.LiveFeeFallbackPct)
+
+	// Initialize DCA service
+	dcaService := service.NewDCAService(botRepo, orderRepo, apiKeyService, marketDataService, orderMonitor, notificationService, deadmanService, paperExchange, ex, cfg.Market.LiveFeeFallbackPct)
 
-	botHandler := handler.NewBotHandler(botRepo, orderRepo, mmService, phService)
+	botHandler := handler.NewBotHandler(botRepo, orderRepo, mmService, phService, gridService, dcaService)
 
 	// Note: Pump Hunter coin update handler is already registered in phService constructor
 	// Register Market Update and Pump Signal Notifications
@@ -153,14 +195,32 @@ func main() {
 		// Always broadcast market_update for all coin updates (price changes, timeframe resets, gap updates)
 		notificationService.NotifyMarketUpdate(context.Background(), coin)
 
-		// Only broadcast pump_signal for very high pump scores (>= 1000)
-		if coin.PumpScore >= 1000 {
+		// Only broadcast pump_signal when a score profile reaches its threshold
+		if marketDataService.IsPumpSignal(coin) {
 			notificationService.NotifyPumpSignal(context.Background(), coin)
 		}
 	})
 
+	// Initialize market screener (streams saved screens over the WebSocket)
+	screenerService := service.NewScreenerService(screenRepo, marketDataService, notificationService)
+	if err := screenerService.LoadStreams(context.Background()); err != nil {
+		log.Errorf("Failed to load streamed screens: %v", err)
+	}
+
+	// Initialize user alerts (evaluated on every market update)
+	alertService := service.NewAlertService(alertRepo, marketDataService, notificationService)
+	if err := alertService.LoadAlerts(context.Background()); err != nil {
+		log.Errorf("Failed to load alerts: %v", err)
+	}
+
+	// Initialize backtests (jobs of a previous run cannot resume)
+	backtestService := service.NewBacktestService(backtestRepo, marketDataService, cfg.Market.BacktestConcurrency)
+	if err := backtestService.FailInterrupted(context.Background()); err != nil {
+		log.Errorf("Failed to clean up interrupted backtests: %v", err)
+	}
+
 	// Initialize Stop-Loss Monitor
-	stopLossMonitor := service.NewStopLossMonitor(tradeRepo, apiKeyService, indodaxClient, marketDataService, notificationService, balanceRepo)
+	stopLossMonitor := service.NewStopLossMonitor(tradeRepo, apiKeyService, ex, marketDataService, notificationService, balanceRepo, paperExchange)
 
 	// Initialize WebSocket Hub
 	wsHub := service.NewWSHub(redisClient.GetClient())
@@ -187,6 +247,19 @@ func main() {
 	// Start monitors
 	stopLossMonitor.Start()
 
+	// Move single per-user API keys of older versions to the multi-key layout
+	// before bots and order streams look them up
+	if err := apiKeyService.MigrateLegacyKeys(context.Background()); err != nil {
+		log.Errorf("Failed to migrate legacy API keys: %v", err)
+	}
+
+	// Keys stored before permission detection are checked in the background, off the startup path
+	go func() {
+		if err := apiKeyService.CheckStoredKeyPermissions(context.Background()); err != nil {
+			log.Errorf("Failed to check stored API key permissions: %v", err)
+		}
+	}()
+
 	// Restore running bots after server restart
 	go func() {
 		ctx := context.Background()
@@ -202,6 +275,11 @@ func main() {
 		log.Infof("Found %d running bot(s) to restore", len(runningBots))
 
 		for _, bot := range runningBots {
+			if cfg.Market.ReplayPath != "" && !bot.IsPaperTrading {
+				log.Warnf("Not restoring live bot %d during market data replay", bot.ID)
+				continue
+			}
+
 			log.Infof("Restoring bot %d (Type: %s, Pair: %s, User: %s)",
 				bot.ID, bot.Type, bot.Pair, bot.UserID)
 
@@ -217,6 +295,10 @@ func main() {
 				err = mmService.StartBot(ctx, bot.UserID, bot.ID)
 			} else if bot.Type == model.BotTypePumpHunter {
 				err = phService.StartBot(ctx, bot.UserID, bot.ID)
+			} else if bot.Type == model.BotTypeGrid {
+				err = gridService.StartBot(ctx, bot.UserID, bot.ID)
+			} else if bot.Type == model.BotTypeDCA {
+				err = dcaService.StartBot(ctx, bot.UserID, bot.ID)
 			} else {
 				log.Warnf("Unknown bot type %s for bot %d, skipping", bot.Type, bot.ID)
 				// Set status back to running since we're not handling it
@@ -240,7 +322,40 @@ func main() {
 	userHandler := handler.NewUserHandler(userService)
 	apiKeyHandler := handler.NewAPIKeyHandler(apiKeyService)
 	marketHandler := handler.NewMarketHandler(marketDataService)
+	scoreProfileHandler := handler.NewScoreProfileHandler(scoreProfileService)
+	screenerHandler := handler.NewScreenerHandler(screenerService)
+	alertHandler := handler.NewAlertHandler(alertService)
+	backtestHandler := handler.NewBacktestHandler(backtestService)
 	copilotHandler := handler.NewCopilotHandler(copilotService)
+	systemHandler := handler.NewSystemHandler(indodaxClient)
+
+	// Health check endpoint
+	router.GET("/health", func(c *gin.Context) {
+		// Test Redis connection
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+
+		if err := redisClient.Ping(ctx); err != nil {
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"status": "unhealthy",
+				"error":  "Redis connection failed",
+			})
+			return
+		}
+
+		// A silent market feed degrades the service, the stream reconnects on its own
+		feed := marketDataService.FeedHealth()
+		status := "healthy"
+		if feed.Status == model.FeedStatusStale {
+			status = "degraded"
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"status":      status,
+			"redis":       "connected",
+			"market_feed": feed,
+		})
+	})
 
 	// API v1 group
 	v1 := router.Group("/api/v1")
@@ -266,9 +381,10 @@ func main() {
 			}
 
 			c.JSON(status, gin.H{
-				"status": "ready",
-				"redis":  redisStatus,
-				"time":   time.Now().Unix(),
+				"status":      "ready",
+				"redis":       redisStatus,
+				"market_feed": marketDataService.FeedHealth(),
+				"time":        time.Now().Unix(),
 			})
 		})
 
@@ -314,6 +430,20 @@ func main() {
 			apiKeys.DELETE("", apiKeyHandler.Delete)
 			apiKeys.POST("/validate", apiKeyHandler.Validate)
 			apiKeys.GET("/account-info", apiKeyHandler.GetAccountInfo)
+			apiKeys.GET("/list", apiKeyHandler.List)
+			apiKeys.GET("/:id", apiKeyHandler.GetByID)
+			apiKeys.DELETE("/:id", apiKeyHandler.DeleteByID)
+			apiKeys.POST("/:id/validate", apiKeyHandler.ValidateByID)
+			apiKeys.GET("/:id/account-info", apiKeyHandler.GetAccountInfoByID)
+			apiKeys.POST("/:id/default", apiKeyHandler.SetDefault)
+		}
+
+		// System routes (admin only)
+		system := v1.Group("/system")
+		system.Use(middleware.AuthMiddleware(authService), middleware.RequireAdmin())
+		{
+			// Private API retry counters (rate limit / nonce rejections)
+			system.GET("/exchange-metrics", systemHandler.GetExchangeMetrics)
 		}
 
 		// Market routes
@@ -323,10 +453,44 @@ func main() {
 			marketRoutes.GET("/summary", marketHandler.GetSummary)
 			marketRoutes.GET("/pump-scores", marketHandler.GetPumpScores)
 			marketRoutes.GET("/gaps", marketHandler.GetGaps)
+			marketRoutes.GET("/score-profiles", scoreProfileHandler.List)
+			marketRoutes.POST("/score-profiles", middleware.RequireAdmin(), scoreProfileHandler.Create)
+			marketRoutes.PUT("/score-profiles/:name", middleware.RequireAdmin(), scoreProfileHandler.Update)
+			marketRoutes.DELETE("/score-profiles/:name", middleware.RequireAdmin(), scoreProfileHandler.Delete)
+			marketRoutes.GET("/screener", screenerHandler.Screen)
+			marketRoutes.GET("/screens", screenerHandler.ListScreens)
+			marketRoutes.POST("/screens", screenerHandler.CreateScreen)
+			marketRoutes.PUT("/screens/:id", screenerHandler.UpdateScreen)
+			marketRoutes.DELETE("/screens/:id", screenerHandler.DeleteScreen)
+			marketRoutes.GET("/screens/:id/results", screenerHandler.RunScreen)
 			marketRoutes.GET("/:pair", marketHandler.GetPairDetail)
+			marketRoutes.GET("/:pair/trades", marketHandler.GetRecentTrades)
+			marketRoutes.GET("/:pair/candles", marketHandler.GetCandles)
+			marketRoutes.GET("/:pair/depth", marketHandler.GetDepth)
 			marketRoutes.POST("/sync", middleware.AuthMiddleware(authService), marketHandler.SyncMetadata)
 		}
 
+		// Alert routes
+		alerts := v1.Group("/alerts")
+		alerts.Use(middleware.AuthMiddleware(authService))
+		{
+			alerts.GET("", alertHandler.ListAlerts)
+			alerts.POST("", alertHandler.CreateAlert)
+			alerts.GET("/:id", alertHandler.GetAlert)
+			alerts.PUT("/:id", alertHandler.UpdateAlert)
+			alerts.DELETE("/:id", alertHandler.DeleteAlert)
+		}
+
+		// Backtest routes
+		backtests := v1.Group("/backtests")
+		backtests.Use(middleware.AuthMiddleware(authService))
+		{
+			backtests.GET("", backtestHandler.ListBacktests)
+			backtests.POST("", backtestHandler.CreateBacktest)
+			backtests.GET("/:id", backtestHandler.GetBacktest)
+			backtests.DELETE("/:id", backtestHandler.DeleteBacktest)
+		}
+
 		// Copilot routes
 		copilot := v1.Group("/copilot")
 		copilot.Use(middleware.AuthMiddleware(authService))
@@ -388,6 +552,9 @@ func main() {
 		} else {
 			log.Info("✓ Subscribed to order updates for all users with API keys")
 		}
+
+		// Replay fills missed while the API was down or a private stream was reconnecting
+		orderMonitor.StartReconciliation()
 	}()
 
 	// Wait for interrupt signal to gracefully shutdown the server
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+
+	"tuyul/backend/internal/config"
+	indodaxex "tuyul/backend/internal/exchange/indodax"
+	"tuyul/backend/internal/exchange/recorder"
+	"tuyul/backend/internal/model"
+	"tuyul/backend/internal/repository"
+	"tuyul/backend/internal/service"
+	"tuyul/backend/internal/service/market"
+	"tuyul/backend/pkg/logger"
+	"tuyul/backend/pkg/redis"
+
+	"github.com/joho/godotenv"
+)
+
+// backtest runs a strategy backtest from the command line. Pump Hunter runs
+// over the candle store in Redis or over recorded market data, Market Maker
+// over the order books and trade prints of a recording:
+//
+//	go run ./cmd/backtest -request strategy.json
+//	go run ./cmd/backtest -request strategy.json -recording recordings/ -out result.json
+//	go run ./cmd/backtest -bot market_maker -request configs.json -recording recordings/
+//
+// A Pump Hunter request file has the body of POST /api/v1/backtests.
+func main() {
+	botType := flag.String("bot", model.BotTypePumpHunter, "Bot type to simulate: pump_hunter or market_maker")
+	requestPath := flag.String("request", "", "Path to the backtest request JSON (required)")
+	recordingPath := flag.String("recording", "", "Recorded market data file or directory (default: candle store in Redis, Pump Hunter only)")
+	outPath := flag.String("out", "", "Write the full result JSON to this file")
+	logLevel := flag.String("log-level", "warn", "Log level")
+	flag.Parse()
+
+	if *requestPath == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	_ = godotenv.Load()
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Printf("Failed to load config: %v\n", err)
+		os.Exit(1)
+	}
+	logger.Init(*logLevel, "pretty")
+
+	data, err := os.ReadFile(*requestPath)
+	if err != nil {
+		fail("Failed to read request", err)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	var result any
+	switch *botType {
+	case model.BotTypePumpHunter:
+		result = pumpHunterBacktest(ctx, cfg, data, *recordingPath)
+	case model.BotTypeMarketMaker:
+		if *recordingPath == "" {
+			fail("Invalid flags", fmt.Errorf("market_maker backtests need -recording"))
+		}
+		result = marketMakerBacktest(ctx, data, *recordingPath)
+	default:
+		fail("Invalid flags", fmt.Errorf("unknown bot type %q", *botType))
+	}
+
+	if *outPath != "" {
+		out, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			fail("Failed to encode result", err)
+		}
+		if err := os.WriteFile(*outPath, out, 0o644); err != nil {
+			fail("Failed to write result", err)
+		}
+		fmt.Printf("\nFull result written to %s\n", *outPath)
+	}
+}
+
+// pumpHunterBacktest runs a Pump Hunter request and prints its summary
+func pumpHunterBacktest(ctx context.Context, cfg *config.Config, data []byte, recordingPath string) *model.BacktestResult {
+	timeframes, err := model.ParseTimeframes(cfg.Market.Timeframes)
+	if err != nil {
+		fail("Invalid MARKET_TIMEFRAMES", err)
+	}
+	var req model.BacktestRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		fail("Invalid request", err)
+	}
+
+	// 1. Candles and score profiles
+	var candles map[string][]model.Candle
+	var profiles []*model.PumpScoreProfile
+	if recordingPath != "" {
+		candles, err = recordedCandles(recordingPath, timeframes[0], req.BacktestPairs())
+		profiles = market.ActiveProfiles(nil)
+	} else {
+		candles, profiles, err = storedCandles(ctx, cfg, timeframes[0], &req)
+	}
+	if err != nil {
+		fail("Failed to load market data", err)
+	}
+
+	// 2. Run
+	runner, err := service.NewPumpHunterBacktest(&req, timeframes, profiles)
+	if err != nil {
+		fail("Invalid request", err)
+	}
+	result, err := runner.Run(ctx, candles)
+	if err != nil {
+		fail("Backtest failed", err)
+	}
+
+	// 3. Report
+	printSummary(result)
+	return result
+}
+
+// marketMakerBacktest runs a Market Maker request over a recording and prints one line per configuration
+func marketMakerBacktest(ctx context.Context, data []byte, recordingPath string) *model.MarketMakerBacktestResult {
+	var req model.MarketMakerBacktestRequest
+	if err := json.Unmarshal(data, &req); err != nil {
+		fail("Invalid request", err)
+	}
+	runner, err := service.NewMarketMakerBacktest(&req)
+	if err != nil {
+		fail("Invalid request", err)
+	}
+	replay, err := recorder.NewReplay(recordingPath, 0)
+	if err != nil {
+		fail("Failed to open recording", err)
+	}
+	result, err := runner.Run(ctx, replay, indodaxex.NewMarketStream(replay))
+	if err != nil {
+		fail("Backtest failed", err)
+	}
+
+	fmt.Printf("Period: %s → %s\n\n", result.From.Format("2006-01-02 15:04"), result.To.Format("2006-01-02 15:04"))
+	fmt.Printf("%-16s %-10s %12s %10s %8s %7s %7s %9s %12s %12s %8s\n",
+		"Config", "Pair", "Net IDR", "Return", "MaxDD", "Orders", "Fills", "Capture", "MaxInv IDR", "MaxUnrl IDR", "InInv")
+	for _, c := range result.Configs {
+		fmt.Printf("%-16s %-10s %12.0f %9.2f%% %7.2f%% %7d %7d %8.3f%% %12.0f %12.0f %7.1f%%\n",
+			c.Name, c.Pair, c.NetProfitIDR, c.ReturnPercent, c.MaxDrawdownPercent, c.OrdersPlaced, c.Fills,
+			c.SpreadCapturePercent, c.MaxInventoryIDR, c.MaxUnrealizedLossIDR, c.InventoryTimePercent)
+		if c.StoppedAt != nil {
+			fmt.Printf("  stopped: max loss reached at %s\n", c.StoppedAt.Format("2006-01-02 15:04"))
+		}
+	}
+	return result
+}
+
+// recordedCandles aggregates a recording into candles of the requested pairs
+func recordedCandles(path string, tf model.TimeframeSpec, pairs []string) (map[string][]model.Candle, error) {
+	replay, err := recorder.NewReplay(path, 0)
+	if err != nil {
+		return nil, err
+	}
+	all := recorder.BuildCandles(replay, indodaxex.NewMarketStream(replay), tf)
+
+	candles := make(map[string][]model.Candle, len(pairs))
+	for _, pair := range pairs {
+		candles[pair] = all[pair]
+	}
+	return candles, nil
+}
+
+// storedCandles loads the candles of the requested pairs and the score profiles from Redis
+func storedCandles(ctx context.Context, cfg *config.Config, tf model.TimeframeSpec, req *model.BacktestRequest) (map[string][]model.Candle, []*model.PumpScoreProfile, error) {
+	redisClient, err := redis.New(redis.Config{
+		Host:     cfg.Redis.Host,
+		Port:     cfg.Redis.Port,
+		Password: cfg.Redis.Password,
+		DB:       cfg.Redis.DB,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	defer redisClient.Close()
+	redis.InitKeys(cfg.Redis.Prefix)
+
+	stored, err := repository.NewPumpScoreProfileRepository(redisClient).List(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	candleRepo := repository.NewCandleRepository(redisClient, cfg.Market.CandleRetention)
+	candles := make(map[string][]model.Candle)
+	for _, pair := range req.BacktestPairs() {
+		series, err := candleRepo.List(ctx, pair, tf.Name, req.From.Add(-service.BacktestWarmup), req.To, 0)
+		if err != nil {
+			return nil, nil, err
+		}
+		candles[pair] = series
+	}
+	return candles, market.ActiveProfiles(stored), nil
+}
+
+func printSummary(result *model.BacktestResult) {
+	fmt.Printf("Candles:       %d (%s)\n", result.Candles, result.Timeframe)
+	fmt.Printf("Balance:       %.0f → %.0f IDR\n", result.InitialBalanceIDR, result.FinalBalanceIDR)
+	fmt.Printf("Profit:        %.0f IDR (%.2f%%), fees %.0f IDR\n", result.ProfitIDR, result.ReturnPercent, result.FeesIDR)
+	fmt.Printf("Max drawdown:  %.2f%% (%.0f IDR)\n", result.MaxDrawdownPercent, result.MaxDrawdownIDR)
+	fmt.Printf("Trades:        %d, %d winning (%.1f%%)\n", result.TotalTrades, result.WinningTrades, result.WinRate)
+	if result.StoppedAt != nil {
+		fmt.Printf("Stopped:       total loss limit reached at %s\n", result.StoppedAt.Format("2006-01-02 15:04"))
+	}
+
+	// Close reasons
+	reasons := make(map[string]int)
+	for _, t := range result.Trades {
+		reasons[t.CloseReason]++
+	}
+	names := make([]string, 0, len(reasons))
+	for name := range reasons {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("  %-16s %d\n", name, reasons[name])
+	}
+}
+
+func fail(msg string, err error) {
+	fmt.Printf("%s: %v\n", msg, err)
+	os.Exit(1)
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"tuyul/backend/internal/simulator"
+	"tuyul/backend/pkg/logger"
+)
+
+// exchange-sim runs a local fake Indodax exchange for end-to-end testing.
+// Point the backend at it with:
+//
+//	INDODAX_API_URL=http://localhost:9090
+//	INDODAX_WS_URL=ws://localhost:9090/ws/
+//	INDODAX_PRIVATE_WS_URL=ws://localhost:9090/pws/
+func main() {
+	configPath := flag.String("config", "", "Path to simulator JSON config (default: built-in market)")
+	listen := flag.String("listen", "", "Address to listen on (overrides config)")
+	logLevel := flag.String("log-level", "info", "Log level")
+	flag.Parse()
+
+	logger.Init(*logLevel, "pretty")
+	log := logger.GetLogger()
+
+	cfg, err := simulator.LoadConfig(*configPath)
+	if err != nil {
+		fmt.Printf("Failed to load simulator config: %v\n", err)
+		os.Exit(1)
+	}
+	if *listen != "" {
+		cfg.Listen = *listen
+	}
+
+	sim := simulator.New(cfg)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go sim.Run(ctx)
+
+	srv := &http.Server{
+		Addr:    cfg.Listen,
+		Handler: sim.Handler(),
+	}
+
+	go func() {
+		log.Infof("Exchange simulator listening on %s (%d pairs, %d accounts)", cfg.Listen, len(cfg.Pairs), len(cfg.Accounts))
+		for _, acc := range cfg.Accounts {
+			log.Infof("Account %s (user %s): API key %s", acc.Name, acc.UserID, acc.APIKey)
+		}
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatal("Failed to start simulator", err)
+		}
+	}()
+
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
+	<-quit
+
+	log.Info("Shutting down simulator...")
+	cancel()
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer shutdownCancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Fatal("Simulator forced to shutdown", err)
+	}
+}
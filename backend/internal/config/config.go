@@ -17,6 +17,7 @@ type Config struct {
 	Indodax    IndodaxConfig
 	CORS       CORSConfig
 	RateLimit  RateLimitConfig
+	Market     MarketConfig
 	Log        LogConfig
 }
 
@@ -67,6 +68,37 @@ type RateLimitConfig struct {
 	AuthRequestsPerMinute int
 }
 
+// MarketConfig holds market data configuration
+type MarketConfig struct {
+	CandleRetention int    // Closed candles kept per pair and timeframe
+	Timeframes      string // Tracked timeframes as name[:pump score weight], comma-separated
+
+	// Order book depth metrics
+	DepthSampleInterval int     // Seconds between REST depth samples of all pairs, 0 disables
+	DepthBandPct        float64 // Depth is summed within this percentage of the mid price
+	DepthWallMultiplier float64 // A level is a wall at this multiple of the median level in the band
+
+	// Market data freshness
+	FeedStaleSeconds int // Stream silent this long marks every pair stale and forces a reconnect
+	PairStaleSeconds int // A pair neither streamed nor polled this long is stale
+
+	// Market data recording and replay
+	RecordDir    string  // Directory receiving recorded market data, empty disables recording
+	RecordRotate int     // Minutes per recorded file
+	ReplayPath   string  // Recorded file or directory to replay instead of the live venue, empty disables replay
+	ReplaySpeed  float64 // Replay speed multiple, 0 plays as fast as possible
+
+	// Backtests
+	BacktestConcurrency int // Backtest jobs run at once, the others wait in the queue
+
+	// Paper trading
+	PaperMakerFeePct float64 // Fee of paper fills resting in the book, percent of the notional
+	PaperTakerFeePct float64 // Fee of paper fills taking liquidity, percent of the notional
+
+	// Live trading
+	LiveFeeFallbackPct float64 // Fee, tax and clearing booked on live fills the exchange reports without them, percent of the notional
+}
+
 // LogConfig holds logging configuration
 type LogConfig struct {
 	Level  string
@@ -100,7 +132,7 @@ func Load() (*Config, error) {
 			APIURL:       getEnv("INDODAX_API_URL", "https://indodax.com"),
 			WSURL:        getEnv("INDODAX_WS_URL", "wss://ws3.indodax.com/ws/"),
 			WSToken:      getEnv("INDODAX_WS_TOKEN", ""),
-			PrivateWSURL: getEnv("INDODAX_PRIVATE_WS_URL", "wss://pws.indodax.com/ws/"),
+			PrivateWSURL: getEnv("INDODAX_PRIVATE_WS_URL", "wss://pws.indodax.com/ws/?cf_ws_frame_ping_pong=true"),
 		},
 		CORS: CORSConfig{
 			AllowedOrigins: getEnvAsSlice("CORS_ALLOWED_ORIGINS", []string{"http://localhost:5173"}, ","),
@@ -109,6 +141,29 @@ func Load() (*Config, error) {
 			RequestsPerMinute:     getEnvAsInt("RATE_LIMIT_REQUESTS_PER_MINUTE", 60),
 			AuthRequestsPerMinute: getEnvAsInt("RATE_LIMIT_AUTH_REQUESTS_PER_MINUTE", 5),
 		},
+		Market: MarketConfig{
+			CandleRetention: getEnvAsInt("CANDLE_RETENTION", 1440),
+			Timeframes:      getEnv("MARKET_TIMEFRAMES", "1m:0.20,5m:0.40,15m:0.30,30m:0.10"),
+
+			DepthSampleInterval: getEnvAsInt("DEPTH_SAMPLE_INTERVAL", 300),
+			DepthBandPct:        getEnvAsFloat("DEPTH_BAND_PCT", 2),
+			DepthWallMultiplier: getEnvAsFloat("DEPTH_WALL_MULTIPLIER", 5),
+
+			FeedStaleSeconds: getEnvAsInt("FEED_STALE_SECONDS", 30),
+			PairStaleSeconds: getEnvAsInt("PAIR_STALE_SECONDS", 180),
+
+			RecordDir:    getEnv("MARKET_RECORD_DIR", ""),
+			RecordRotate: getEnvAsInt("MARKET_RECORD_ROTATE_MINUTES", 60),
+			ReplayPath:   getEnv("MARKET_REPLAY_PATH", ""),
+			ReplaySpeed:  getEnvAsFloat("MARKET_REPLAY_SPEED", 1),
+
+			BacktestConcurrency: getEnvAsInt("BACKTEST_CONCURRENCY", 2),
+
+			PaperMakerFeePct: getEnvAsFloat("PAPER_MAKER_FEE_PERCENT", 0.2),
+			PaperTakerFeePct: getEnvAsFloat("PAPER_TAKER_FEE_PERCENT", 0.3),
+
+			LiveFeeFallbackPct: getEnvAsFloat("LIVE_FEE_FALLBACK_PERCENT", 0.35),
+		},
 		Log: LogConfig{
 			Level:  getEnv("LOG_LEVEL", "info"),
 			Format: getEnv("LOG_FORMAT", "json"),
@@ -168,6 +223,14 @@ func getEnvAsInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+func getEnvAsFloat(key string, defaultValue float64) float64 {
+	valueStr := getEnv(key, "")
+	if value, err := strconv.ParseFloat(valueStr, 64); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
 func getEnvAsSlice(key string, defaultValue []string, separator string) []string {
 	valueStr := getEnv(key, "")
 	if valueStr == "" {
@@ -0,0 +1,66 @@
+package exchange
+
+import "errors"
+
+// ErrorKind classifies venue failures so callers can recover without parsing messages
+type ErrorKind string
+
+const (
+	ErrorKindUnknown             ErrorKind = "unknown"
+	ErrorKindInsufficientBalance ErrorKind = "insufficient_balance"
+	ErrorKindInvalidPair         ErrorKind = "invalid_pair"
+	ErrorKindOrderBelowMinimum   ErrorKind = "order_below_minimum"
+	ErrorKindOrderNotFound       ErrorKind = "order_not_found"
+	ErrorKindInvalidNonce        ErrorKind = "invalid_nonce"
+	ErrorKindAuth                ErrorKind = "auth"
+	ErrorKindPermission          ErrorKind = "permission"
+	ErrorKindRateLimited         ErrorKind = "rate_limited"
+	ErrorKindMaintenance         ErrorKind = "maintenance"
+	ErrorKindNetwork             ErrorKind = "network"
+	ErrorKindTradingDisabled     ErrorKind = "trading_disabled" // Refused by this process, e.g. during a market data replay
+)
+
+// Error is a classified venue failure returned by adapters.
+// The venue error is kept in Err, so errors.As also works on it.
+type Error struct {
+	Kind  ErrorKind
+	Venue string
+	Err   error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Transient reports whether the same request may succeed later without user action
+func (e *Error) Transient() bool {
+	switch e.Kind {
+	case ErrorKindRateLimited, ErrorKindInvalidNonce, ErrorKindMaintenance, ErrorKindNetwork:
+		return true
+	}
+	return false
+}
+
+// KindOf returns the kind of err, or ErrorKindUnknown if it is not an exchange error
+func KindOf(err error) ErrorKind {
+	var exErr *Error
+	if errors.As(err, &exErr) {
+		return exErr.Kind
+	}
+	return ErrorKindUnknown
+}
+
+// IsKind reports whether err is an exchange error of the given kind
+func IsKind(err error, kind ErrorKind) bool {
+	return err != nil && KindOf(err) == kind
+}
+
+// IsTransient reports whether err is a transient exchange error
+func IsTransient(err error) bool {
+	var exErr *Error
+	return errors.As(err, &exErr) && exErr.Transient()
+}
@@ -0,0 +1,122 @@
+// Package exchange defines the venue-neutral interfaces used by bots and
+// market services. Each venue is implemented as an adapter in a subpackage
+// (e.g. internal/exchange/indodax).
+package exchange
+
+import (
+	"context"
+	"time"
+)
+
+// Exchange is a trading venue
+type Exchange interface {
+	// Name returns the venue identifier (e.g. "indodax")
+	Name() string
+
+	// MarketData returns the public REST market data API
+	MarketData() MarketData
+
+	// MarketStream returns the shared public market data stream
+	MarketStream() MarketStream
+
+	// NewTrader creates an order entry client for one set of credentials
+	NewTrader(apiKey, apiSecret string) Trader
+
+	// NewOrderStream creates a private order update stream for one set of credentials
+	NewOrderStream(apiKey, apiSecret string) OrderStream
+
+	// ValidateCredentials returns false (without error) if the venue rejects the credentials
+	ValidateCredentials(ctx context.Context, apiKey, apiSecret string) (bool, error)
+}
+
+// MarketData provides pair metadata and market summaries
+type MarketData interface {
+	GetPairs(ctx context.Context) ([]Pair, error)
+
+	// GetPriceIncrements returns the tick size of every pair, keyed by pair ID
+	GetPriceIncrements(ctx context.Context) (map[string]float64, error)
+
+	// GetTickers returns 24h summaries including best bid/ask for every pair
+	GetTickers(ctx context.Context) ([]Ticker, error)
+
+	// GetTrades returns the most recent trades of a pair, oldest first
+	GetTrades(ctx context.Context, pair string) ([]Trade, error)
+
+	// GetOrderBook returns a depth snapshot of one pair, used to resync the stream
+	GetOrderBook(ctx context.Context, pair string) (*OrderBook, error)
+}
+
+// MarketStream delivers public market data pushes.
+// Handlers are additive so several services can share one connection.
+type MarketStream interface {
+	Connect() error
+	AddTickerHandler(handler func(tickers []Ticker))
+	AddOrderBookHandler(handler func(book *OrderBook))
+	AddTradeHandler(handler func(trades []Trade))
+
+	// AddConnectHandler registers a callback run after every (re)connect,
+	// so consumers can backfill what they missed while disconnected
+	AddConnectHandler(handler func())
+
+	SubscribeTickers()
+	SubscribeOrderBook(pair string)
+	UnsubscribeOrderBook(pair string)
+	SubscribeTrades(pair string)
+	UnsubscribeTrades(pair string)
+}
+
+// Trader places and manages orders for one account.
+// Pairs are given in internal format (e.g. "btcidr").
+type Trader interface {
+	GetInfo(ctx context.Context) (*AccountInfo, error)
+
+	// Trade places an order. For market buys amount is in quote currency,
+	// otherwise it is in base currency.
+	Trade(ctx context.Context, side, pair string, price, amount float64, type_ string, clientOrderID string) (*OrderResult, error)
+
+	// CancelOrder cancels by exchange order ID or client order ID
+	CancelOrder(ctx context.Context, pair string, orderID string, side string) error
+
+	// GetOrder looks up by exchange order ID or client order ID
+	GetOrder(ctx context.Context, pair string, orderID string) (*Order, error)
+
+	// GetOpenOrders returns the open orders of a pair
+	GetOpenOrders(ctx context.Context, pair string) ([]Order, error)
+
+	// GetOrderHistory returns up to limit recent orders of a pair, including closed ones
+	GetOrderHistory(ctx context.Context, pair string, limit int) ([]Order, error)
+}
+
+// OrderStream delivers private order updates for one account
+type OrderStream interface {
+	SetOrderUpdateHandler(handler func(update *OrderUpdate))
+	SetErrorHandler(handler func(err error))
+
+	// SetReconnectHandler registers a callback run after the stream reconnected on its own.
+	// Updates sent while disconnected are not replayed by the venue.
+	SetReconnectHandler(handler func())
+	Connect(ctx context.Context) error
+
+	// WaitForSubscription blocks until the stream is authenticated and subscribed
+	WaitForSubscription(ctx context.Context, timeout time.Duration) error
+	Close()
+}
+
+// DeadmanSwitch is implemented by venues that can cancel all open orders of a
+// pair unless the countdown is refreshed in time
+type DeadmanSwitch interface {
+	// CountdownCancelAll (re)arms the countdown of the pairs in one call; zero disarms them
+	CountdownCancelAll(ctx context.Context, apiKey, apiSecret string, pairs []string, countdown time.Duration) error
+}
+
+// StreamReconnector is implemented by market streams that can force a reconnect
+// of a connection that is open but no longer delivering data
+type StreamReconnector interface {
+	Reconnect()
+}
+
+// PermissionInspector is implemented by venues that can report the scopes of API credentials
+type PermissionInspector interface {
+	// GetKeyPermissions detects what the credentials are allowed to do
+	GetKeyPermissions(ctx context.Context, apiKey, apiSecret string) (*KeyPermissions, error)
+}
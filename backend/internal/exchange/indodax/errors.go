@@ -0,0 +1,53 @@
+package indodax
+
+import (
+	"errors"
+
+	"tuyul/backend/internal/exchange"
+	api "tuyul/backend/pkg/indodax"
+)
+
+// wrapError converts Indodax API errors to exchange errors (other errors pass through)
+func wrapError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var apiErr *api.APIError
+	if !errors.As(err, &apiErr) {
+		return err
+	}
+
+	return &exchange.Error{
+		Kind:  toErrorKind(apiErr.Kind),
+		Venue: Name,
+		Err:   err,
+	}
+}
+
+func toErrorKind(kind api.ErrorKind) exchange.ErrorKind {
+	switch kind {
+	case api.ErrKindInsufficientBalance:
+		return exchange.ErrorKindInsufficientBalance
+	case api.ErrKindInvalidPair:
+		return exchange.ErrorKindInvalidPair
+	case api.ErrKindOrderBelowMinimum:
+		return exchange.ErrorKindOrderBelowMinimum
+	case api.ErrKindOrderNotFound:
+		return exchange.ErrorKindOrderNotFound
+	case api.ErrKindInvalidNonce:
+		return exchange.ErrorKindInvalidNonce
+	case api.ErrKindAuth:
+		return exchange.ErrorKindAuth
+	case api.ErrKindPermission:
+		return exchange.ErrorKindPermission
+	case api.ErrKindRateLimited:
+		return exchange.ErrorKindRateLimited
+	case api.ErrKindMaintenance:
+		return exchange.ErrorKindMaintenance
+	case api.ErrKindNetwork:
+		return exchange.ErrorKindNetwork
+	default:
+		return exchange.ErrorKindUnknown
+	}
+}
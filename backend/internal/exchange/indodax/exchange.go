@@ -0,0 +1,133 @@
+// Package indodax adapts pkg/indodax to the exchange interfaces
+package indodax
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"tuyul/backend/internal/exchange"
+	api "tuyul/backend/pkg/indodax"
+)
+
+// Name is the venue identifier
+const Name = "indodax"
+
+// Exchange implements exchange.Exchange, exchange.DeadmanSwitch and
+// exchange.PermissionInspector for Indodax
+type Exchange struct {
+	client *api.Client
+	stream *MarketStream
+
+	// Set when order entry is refused (market data replay)
+	tradingDisabled error
+}
+
+// New creates the Indodax adapter from a REST client and a public WebSocket client
+// (or a replay of recorded frames)
+func New(client *api.Client, wsClient FrameSource) *Exchange {
+	return &Exchange{
+		client: client,
+		stream: NewMarketStream(wsClient),
+	}
+}
+
+func (e *Exchange) Name() string {
+	return Name
+}
+
+func (e *Exchange) MarketData() exchange.MarketData {
+	return &MarketData{client: e.client}
+}
+
+func (e *Exchange) MarketStream() exchange.MarketStream {
+	return e.stream
+}
+
+// DisableTrading makes traders and the deadman switch fail with reason instead of
+// reaching the venue, so nothing trades real funds on replayed prices
+func (e *Exchange) DisableTrading(reason string) {
+	e.tradingDisabled = &exchange.Error{Kind: exchange.ErrorKindTradingDisabled, Venue: Name, Err: errors.New(reason)}
+}
+
+func (e *Exchange) NewTrader(apiKey, apiSecret string) exchange.Trader {
+	if e.tradingDisabled != nil {
+		return disabledTrader{err: e.tradingDisabled}
+	}
+	return NewTrader(e.client, apiKey, apiSecret)
+}
+
+func (e *Exchange) NewOrderStream(apiKey, apiSecret string) exchange.OrderStream {
+	return NewOrderStream(e.client, apiKey, apiSecret)
+}
+
+func (e *Exchange) ValidateCredentials(ctx context.Context, apiKey, apiSecret string) (bool, error) {
+	valid, err := e.client.ValidateAPIKey(ctx, apiKey, apiSecret)
+	return valid, wrapError(err)
+}
+
+// GetKeyPermissions probes the scopes of Indodax credentials
+func (e *Exchange) GetKeyPermissions(ctx context.Context, apiKey, apiSecret string) (*exchange.KeyPermissions, error) {
+	perms, err := e.client.GetPermissions(ctx, apiKey, apiSecret)
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	return &exchange.KeyPermissions{
+		View:     perms.View,
+		Trade:    perms.Trade,
+		Withdraw: perms.Withdraw,
+	}, nil
+}
+
+// CountdownCancelAll arms (or disarms with zero) the Indodax deadman switch for the pairs
+func (e *Exchange) CountdownCancelAll(ctx context.Context, apiKey, apiSecret string, pairs []string, countdown time.Duration) error {
+	if e.tradingDisabled != nil {
+		return e.tradingDisabled
+	}
+	tickers := make([]string, len(pairs))
+	for i, pair := range pairs {
+		tickers[i] = ToIndodaxPair(pair)
+	}
+	err := e.client.CountdownCancelAll(ctx, apiKey, apiSecret, tickers, countdown.Milliseconds())
+	return wrapError(err)
+}
+
+// ToIndodaxPair converts internal pair format to Indodax format
+// Internal: "cstidr" -> Indodax: "cst_idr"
+// Internal: "btc_idr" -> Indodax: "btc_idr" (already correct)
+func ToIndodaxPair(pair string) string {
+	// If already has underscore, return as is
+	if strings.Contains(pair, "_") {
+		return pair
+	}
+	// Convert "cstidr" to "cst_idr"
+	if strings.HasSuffix(pair, "idr") {
+		base := strings.TrimSuffix(pair, "idr")
+		return base + "_idr"
+	}
+	// If format is unknown, return as is (let Indodax handle the error)
+	return pair
+}
+
+// FromIndodaxPair converts Indodax pair format to internal format ("btc_idr" -> "btcidr")
+func FromIndodaxPair(pair string) string {
+	return strings.ReplaceAll(pair, "_", "")
+}
+
+// normalizeStatus maps Indodax order statuses (REST and private WS) to exchange statuses
+func normalizeStatus(status string) string {
+	switch strings.ToLower(status) {
+	case "new", "open":
+		return exchange.OrderStatusOpen
+	case "fill":
+		// Private WS sends FILL for each execution and DONE once complete
+		return exchange.OrderStatusPartiallyFilled
+	case "filled", "done":
+		return exchange.OrderStatusFilled
+	case "cancelled", "canceled":
+		return exchange.OrderStatusCancelled
+	default:
+		return strings.ToLower(status)
+	}
+}
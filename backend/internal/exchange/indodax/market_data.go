@@ -0,0 +1,166 @@
+package indodax
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"tuyul/backend/internal/exchange"
+	api "tuyul/backend/pkg/indodax"
+)
+
+// MarketData implements exchange.MarketData over the Indodax public REST API
+type MarketData struct {
+	client *api.Client
+}
+
+// GetPairs returns all Indodax pairs.
+// Indodax calls the quote asset "base_currency" and the traded asset "traded_currency".
+func (m *MarketData) GetPairs(ctx context.Context) ([]exchange.Pair, error) {
+	pairs, err := m.client.GetPairs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]exchange.Pair, 0, len(pairs))
+	for _, p := range pairs {
+		result = append(result, exchange.Pair{
+			ID:              p.ID,
+			Symbol:          p.Symbol,
+			BaseCurrency:    p.TradedCurrency,
+			QuoteCurrency:   p.BaseCurrency,
+			Description:     p.Description,
+			VolumePrecision: p.VolumePrecision,
+			PricePrecision:  p.PricePrecision,
+			PriceRound:      p.PriceRound,
+			MinBaseAmount:   p.TradeMinTradedCurrency,
+			MinQuoteAmount:  float64(p.TradeMinBaseCurrency),
+		})
+	}
+	return result, nil
+}
+
+// GetPriceIncrements returns tick sizes keyed by internal pair ID (cst_idr -> cstidr)
+func (m *MarketData) GetPriceIncrements(ctx context.Context) (map[string]float64, error) {
+	increments, err := m.client.GetPriceIncrements(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]float64, len(increments))
+	for pair, inc := range increments {
+		f, err := strconv.ParseFloat(inc, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid price increment %q for %s: %w", inc, pair, err)
+		}
+		result[FromIndodaxPair(pair)] = f
+	}
+	return result, nil
+}
+
+// GetTickers returns 24h summaries with best bid/ask from /api/summaries
+func (m *MarketData) GetTickers(ctx context.Context) ([]exchange.Ticker, error) {
+	summaries, err := m.client.GetSummaries(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]exchange.Ticker, 0, len(summaries.Tickers))
+	for tickerID, detail := range summaries.Tickers {
+		result = append(result, exchange.Ticker{
+			Pair:        FromIndodaxPair(tickerID),
+			Last:        parseFloat(detail.Last),
+			High:        parseFloat(detail.High),
+			Low:         parseFloat(detail.Low),
+			Bid:         parseFloat(detail.Buy),
+			Ask:         parseFloat(detail.Sell),
+			QuoteVolume: parseFloat(detail.VolIDR),
+		})
+	}
+	return result, nil
+}
+
+// GetTrades returns recent trades from /api/trades (Indodax sends newest first)
+func (m *MarketData) GetTrades(ctx context.Context, pair string) ([]exchange.Trade, error) {
+	trades, err := m.client.GetTrades(ctx, FromIndodaxPair(pair))
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]exchange.Trade, 0, len(trades))
+	for _, t := range trades {
+		id, err := strconv.ParseInt(t.TID, 10, 64)
+		if err != nil {
+			continue
+		}
+		date, _ := strconv.ParseInt(t.Date, 10, 64)
+		result = append(result, exchange.Trade{
+			Pair:   pair,
+			ID:     id,
+			Side:   t.Type,
+			Price:  parseFloat(t.Price),
+			Amount: parseFloat(t.Amount),
+			Time:   time.Unix(date, 0),
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].ID < result[j].ID
+	})
+	return result, nil
+}
+
+// GetOrderBook returns a depth snapshot from /api/depth.
+// REST depth carries no stream offset, so Sequence is left at 0.
+func (m *MarketData) GetOrderBook(ctx context.Context, pair string) (*exchange.OrderBook, error) {
+	depth, err := m.client.GetDepth(ctx, FromIndodaxPair(pair))
+	if err != nil {
+		return nil, err
+	}
+
+	return &exchange.OrderBook{
+		Pair: pair,
+		Bids: toDepthLevels(depth.Buy),
+		Asks: toDepthLevels(depth.Sell),
+	}, nil
+}
+
+// toDepthLevels converts [price, volume] rows, skipping malformed ones
+func toDepthLevels(rows [][]interface{}) []exchange.OrderBookLevel {
+	levels := make([]exchange.OrderBookLevel, 0, len(rows))
+	for _, row := range rows {
+		if len(row) < 2 {
+			continue
+		}
+		price := parseFloat(row[0])
+		if price <= 0 {
+			continue
+		}
+		volume := parseFloat(row[1])
+		levels = append(levels, exchange.OrderBookLevel{
+			Price:       price,
+			BaseVolume:  volume,
+			QuoteVolume: price * volume,
+		})
+	}
+	return levels
+}
+
+// parseFloat parses numbers that Indodax sends either as JSON numbers or strings
+func parseFloat(v interface{}) float64 {
+	switch val := v.(type) {
+	case float64:
+		return val
+	case int:
+		return float64(val)
+	case int64:
+		return float64(val)
+	case string:
+		f, _ := strconv.ParseFloat(val, 64)
+		return f
+	default:
+		return 0
+	}
+}
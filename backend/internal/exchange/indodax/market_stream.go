@@ -0,0 +1,260 @@
+package indodax
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"tuyul/backend/internal/exchange"
+	"tuyul/backend/pkg/logger"
+)
+
+const (
+	summaryChannel         = "market:summary-24h"
+	orderBookChannelPrefix = "market:order-book-"
+	tradeChannelPrefix     = "market:trade-activity-"
+)
+
+// FrameSource delivers public WebSocket channel frames. Implemented by
+// api.WSClient and by recorded market data replays.
+type FrameSource interface {
+	Connect() error
+	AddMessageHandler(handler func(channel string, data []byte))
+	AddConnectHandler(handler func())
+	Subscribe(channel string)
+	Unsubscribe(channel string)
+}
+
+// MarketStream implements exchange.MarketStream over the Indodax public WebSocket
+type MarketStream struct {
+	wsClient FrameSource
+	log      *logger.Logger
+
+	tickerHandlers    []func(tickers []exchange.Ticker)
+	orderBookHandlers []func(book *exchange.OrderBook)
+	tradeHandlers     []func(trades []exchange.Trade)
+	mu                sync.RWMutex
+}
+
+// NewMarketStream wraps a public WebSocket client or a replay
+func NewMarketStream(wsClient FrameSource) *MarketStream {
+	s := &MarketStream{
+		wsClient: wsClient,
+		log:      logger.GetLogger(),
+	}
+
+	// Add message handler on WSClient (don't replace existing handlers)
+	wsClient.AddMessageHandler(s.handleWSMessage)
+
+	return s
+}
+
+func (s *MarketStream) Connect() error {
+	return s.wsClient.Connect()
+}
+
+// Reconnect drops and re-establishes the connection when the frame source supports it
+// (replays do not), implementing exchange.StreamReconnector
+func (s *MarketStream) Reconnect() {
+	if r, ok := s.wsClient.(interface{ Reconnect() }); ok {
+		r.Reconnect()
+	}
+}
+
+func (s *MarketStream) AddTickerHandler(handler func(tickers []exchange.Ticker)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tickerHandlers = append(s.tickerHandlers, handler)
+}
+
+func (s *MarketStream) AddOrderBookHandler(handler func(book *exchange.OrderBook)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.orderBookHandlers = append(s.orderBookHandlers, handler)
+}
+
+func (s *MarketStream) AddTradeHandler(handler func(trades []exchange.Trade)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tradeHandlers = append(s.tradeHandlers, handler)
+}
+
+func (s *MarketStream) AddConnectHandler(handler func()) {
+	s.wsClient.AddConnectHandler(handler)
+}
+
+// SubscribeTickers subscribes to market summaries of all pairs
+func (s *MarketStream) SubscribeTickers() {
+	s.wsClient.Subscribe(summaryChannel)
+}
+
+func (s *MarketStream) SubscribeOrderBook(pair string) {
+	s.wsClient.Subscribe(orderBookChannelPrefix + pair)
+}
+
+func (s *MarketStream) UnsubscribeOrderBook(pair string) {
+	s.wsClient.Unsubscribe(orderBookChannelPrefix + pair)
+}
+
+func (s *MarketStream) SubscribeTrades(pair string) {
+	s.wsClient.Subscribe(tradeChannelPrefix + pair)
+}
+
+func (s *MarketStream) UnsubscribeTrades(pair string) {
+	s.wsClient.Unsubscribe(tradeChannelPrefix + pair)
+}
+
+func (s *MarketStream) handleWSMessage(channel string, data []byte) {
+	if channel == summaryChannel {
+		s.handleSummary(data)
+	} else if strings.HasPrefix(channel, orderBookChannelPrefix) {
+		s.handleOrderBook(strings.TrimPrefix(channel, orderBookChannelPrefix), data)
+	} else if strings.HasPrefix(channel, tradeChannelPrefix) {
+		s.handleTrades(strings.TrimPrefix(channel, tradeChannelPrefix), data)
+	}
+}
+
+// handleSummary parses market:summary-24h rows:
+// [pair, timestamp, last, low, high, open, vol_idr, vol_base]
+func (s *MarketStream) handleSummary(data []byte) {
+	var summaryData struct {
+		Data [][]interface{} `json:"data"`
+	}
+
+	if err := json.Unmarshal(data, &summaryData); err != nil {
+		s.log.Errorf("Failed to parse summary data: %v", err)
+		return
+	}
+
+	tickers := make([]exchange.Ticker, 0, len(summaryData.Data))
+	for _, item := range summaryData.Data {
+		if len(item) < 8 {
+			continue
+		}
+
+		pairID, ok := item[0].(string)
+		if !ok {
+			continue
+		}
+
+		tickers = append(tickers, exchange.Ticker{
+			Pair:        pairID,
+			Last:        parseFloat(item[2]),
+			Low:         parseFloat(item[3]),
+			High:        parseFloat(item[4]),
+			Open:        parseFloat(item[5]),
+			QuoteVolume: parseFloat(item[6]),
+			BaseVolume:  parseFloat(item[7]),
+		})
+	}
+
+	s.mu.RLock()
+	handlers := s.tickerHandlers
+	s.mu.RUnlock()
+	for _, h := range handlers {
+		h(tickers)
+	}
+}
+
+// handleOrderBook parses market:order-book-<pair> data.
+// The ws_client already extracts result.data, so we receive:
+//
+//	{"data": {"pair": "btcidr", "ask": [...], "bid": [...]}, "offset": 67409}
+func (s *MarketStream) handleOrderBook(pair string, data []byte) {
+	type level struct {
+		Price      string `json:"price"`
+		BaseVolume string `json:"btc_volume"` // Note: field name varies by pair (btc_volume, eth_volume, etc.)
+		IDRVolume  string `json:"idr_volume"`
+	}
+	var obData struct {
+		Data struct {
+			Pair string  `json:"pair"`
+			Ask  []level `json:"ask"`
+			Bid  []level `json:"bid"`
+		} `json:"data"`
+		Offset int64 `json:"offset"`
+	}
+
+	if err := json.Unmarshal(data, &obData); err != nil {
+		s.log.Errorf("Failed to unmarshal orderbook data for %s: %v. Raw data: %s", pair, err, string(data))
+		return
+	}
+
+	toLevels := func(side string, raw []level) []exchange.OrderBookLevel {
+		levels := make([]exchange.OrderBookLevel, 0, len(raw))
+		for _, l := range raw {
+			price := parseFloat(l.Price)
+			if price <= 0 {
+				s.log.Warnf("Invalid %s price %q for %s (skipping level)", side, l.Price, pair)
+				continue
+			}
+			// Volumes might be missing or empty, parseFloat yields 0
+			levels = append(levels, exchange.OrderBookLevel{
+				Price:       price,
+				BaseVolume:  parseFloat(l.BaseVolume),
+				QuoteVolume: parseFloat(l.IDRVolume),
+			})
+		}
+		return levels
+	}
+
+	book := &exchange.OrderBook{
+		Pair:     pair,
+		Sequence: obData.Offset,
+		Bids:     toLevels("bid", obData.Data.Bid),
+		Asks:     toLevels("ask", obData.Data.Ask),
+	}
+
+	s.mu.RLock()
+	handlers := s.orderBookHandlers
+	s.mu.RUnlock()
+	for _, h := range handlers {
+		h(book)
+	}
+}
+
+// handleTrades parses market:trade-activity-<pair> rows:
+// [pair, timestamp, trade_id, side, price, idr_volume, coin_volume]
+func (s *MarketStream) handleTrades(pair string, data []byte) {
+	var tradeData struct {
+		Data [][]interface{} `json:"data"`
+	}
+
+	if err := json.Unmarshal(data, &tradeData); err != nil {
+		s.log.Errorf("Failed to parse trade activity for %s: %v", pair, err)
+		return
+	}
+
+	trades := make([]exchange.Trade, 0, len(tradeData.Data))
+	for _, item := range tradeData.Data {
+		if len(item) < 7 {
+			continue
+		}
+
+		side, _ := item[3].(string)
+		price := parseFloat(item[4])
+		if price <= 0 {
+			continue
+		}
+
+		trades = append(trades, exchange.Trade{
+			Pair:   pair,
+			ID:     int64(parseFloat(item[2])),
+			Side:   side,
+			Price:  price,
+			Amount: parseFloat(item[6]),
+			Time:   time.Unix(int64(parseFloat(item[1])), 0),
+		})
+	}
+	if len(trades) == 0 {
+		return
+	}
+
+	s.mu.RLock()
+	handlers := s.tradeHandlers
+	s.mu.RUnlock()
+	for _, h := range handlers {
+		h(trades)
+	}
+}
@@ -0,0 +1,84 @@
+package indodax
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"tuyul/backend/internal/exchange"
+	api "tuyul/backend/pkg/indodax"
+)
+
+// OrderStream implements exchange.OrderStream over the Indodax private WebSocket
+type OrderStream struct {
+	wsClient *api.PrivateWSClient
+}
+
+func NewOrderStream(client *api.Client, apiKey, apiSecret string) *OrderStream {
+	return &OrderStream{
+		wsClient: api.NewPrivateWSClient(client, apiKey, apiSecret),
+	}
+}
+
+func (s *OrderStream) SetOrderUpdateHandler(handler func(update *exchange.OrderUpdate)) {
+	s.wsClient.SetOrderUpdateHandler(func(order *api.OrderUpdate) {
+		handler(toOrderUpdate(order))
+	})
+}
+
+func (s *OrderStream) SetErrorHandler(handler func(err error)) {
+	s.wsClient.SetErrorHandler(handler)
+}
+
+func (s *OrderStream) SetReconnectHandler(handler func()) {
+	s.wsClient.SetReconnectHandler(handler)
+}
+
+func (s *OrderStream) Connect(ctx context.Context) error {
+	return wrapError(s.wsClient.Connect(ctx))
+}
+
+func (s *OrderStream) WaitForSubscription(ctx context.Context, timeout time.Duration) error {
+	return s.wsClient.WaitForSubscription(ctx, timeout)
+}
+
+func (s *OrderStream) Close() {
+	s.wsClient.Close()
+}
+
+// toOrderUpdate converts an Indodax order_update event.
+// Indodax sends quantities as strings and side/status in upper case (BUY, FILL, DONE).
+func toOrderUpdate(order *api.OrderUpdate) *exchange.OrderUpdate {
+	symbol := strings.ToLower(order.Symbol)
+	price := parseFloat(order.Price)
+	return &exchange.OrderUpdate{
+		OrderID:         order.OrderID,
+		ClientOrderID:   order.ClientOrderID,
+		Pair:            FromIndodaxPair(symbol),
+		Side:            strings.ToLower(order.Side),
+		Price:           price,
+		OrigQty:         parseFloat(order.OrigQty),
+		ExecutedQty:     parseFloat(order.ExecutedQty),
+		UnfilledQty:     parseFloat(order.UnfilledQty),
+		Status:          normalizeStatus(order.Status),
+		TransactionTime: order.TransactionTime,
+		Fee:             fillCharges(order.FillInformation, symbol, price),
+	}
+}
+
+// fillCharges is what a fill cost in quote currency: the exchange fee, tax and clearing.
+// Charges taken in the base coin are valued at the fill price.
+func fillCharges(fill *api.FillInformation, symbol string, price float64) float64 {
+	if fill == nil {
+		return 0
+	}
+
+	charge := func(amount, asset string) float64 {
+		value := parseFloat(amount)
+		if asset != "" && !strings.HasSuffix(symbol, strings.ToLower(asset)) {
+			value *= price
+		}
+		return value
+	}
+	return charge(fill.Fee, fill.FeeAsset) + charge(fill.Tax, fill.TaxAsset) + charge(fill.Clearing, fill.ClearingAsset)
+}
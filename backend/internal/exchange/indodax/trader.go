@@ -0,0 +1,230 @@
+package indodax
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"tuyul/backend/internal/exchange"
+	api "tuyul/backend/pkg/indodax"
+	"tuyul/backend/pkg/logger"
+)
+
+// Trader implements exchange.Trader over the Indodax private REST API
+type Trader struct {
+	client    *api.Client
+	apiKey    string
+	apiSecret string
+	log       *logger.Logger
+}
+
+func NewTrader(client *api.Client, apiKey, apiSecret string) *Trader {
+	return &Trader{
+		client:    client,
+		apiKey:    apiKey,
+		apiSecret: apiSecret,
+		log:       logger.GetLogger(),
+	}
+}
+
+func (t *Trader) GetInfo(ctx context.Context) (*exchange.AccountInfo, error) {
+	info, err := t.client.GetInfo(ctx, t.apiKey, t.apiSecret)
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	if info == nil {
+		return nil, fmt.Errorf("empty getInfo response")
+	}
+
+	return &exchange.AccountInfo{
+		UserID:     info.UserID.String(),
+		Name:       info.Name,
+		Email:      info.Email,
+		ServerTime: info.ServerTime,
+		Balances:   toBalances(info.Balance),
+		Held:       toBalances(info.BalanceHold),
+	}, nil
+}
+
+func (t *Trader) Trade(ctx context.Context, side, pair string, price, amount float64, type_ string, clientOrderID string) (*exchange.OrderResult, error) {
+	// Convert pair format from internal format (e.g., "cstidr") to Indodax format (e.g., "cst_idr")
+	indodaxPair := ToIndodaxPair(pair)
+
+	// If no clientOrderID provided, generate one (fallback)
+	// Format: {pair}-{side}-{timestamp}
+	if clientOrderID == "" {
+		clientOrderID = fmt.Sprintf("%s-%s-%d", pair, strings.ToLower(side), time.Now().UnixMilli())
+	}
+
+	// Handle market vs limit orders according to Indodax API:
+	// - Market BUY: use IDR amount (amount parameter contains IDR), no price
+	// - Market SELL: use Coin amount (amount parameter contains coin), no price
+	// - Limit BUY: use Coin amount (amount parameter contains coin), with price
+	// - Limit SELL: use Coin amount (amount parameter contains coin), with price
+	req := api.TradeRequest{
+		Pair:          indodaxPair,
+		Type:          side,
+		OrderType:     type_,
+		ClientOrderID: clientOrderID,
+	}
+
+	if type_ == "market" {
+		// Market orders: no price parameter
+		req.Price = 0
+		if side == "buy" {
+			// Market buy: use IDR amount
+			req.IDR = amount
+			req.Coin = 0
+		} else {
+			// Market sell: use coin amount
+			req.Coin = amount
+			req.IDR = 0
+		}
+	} else {
+		// Limit orders: use price and coin amount
+		req.Price = price
+		req.Coin = amount
+		req.IDR = 0
+	}
+
+	t.log.Debugf("Indodax: Sending trade request with ClientOrderID: %s (pair=%s, side=%s, type=%s, price=%.2f, coin=%.8f, idr=%.2f)",
+		clientOrderID, indodaxPair, side, type_, req.Price, req.Coin, req.IDR)
+
+	result, err := t.client.Trade(ctx, t.apiKey, t.apiSecret, req)
+	if err != nil {
+		return nil, wrapError(err)
+	}
+
+	t.log.Debugf("Indodax: Trade response - OrderID: %d, ClientOrderID: %s",
+		result.OrderID, result.ClientOrderID)
+
+	return &exchange.OrderResult{
+		OrderID:       strconv.FormatInt(result.OrderID, 10),
+		ClientOrderID: result.ClientOrderID,
+	}, nil
+}
+
+func (t *Trader) CancelOrder(ctx context.Context, pair string, orderID string, side string) error {
+	// Convert pair format for Indodax
+	indodaxPair := ToIndodaxPair(pair)
+
+	// Try to cancel by ClientOrderID first (orderID format: "cstidr-buy-1767968961234")
+	// If it's a numeric ID (old format), use numeric cancel
+	if id, err := strconv.ParseInt(orderID, 10, 64); err == nil {
+		// Numeric ID, use old cancelOrder method
+		_, err := t.client.CancelOrder(ctx, t.apiKey, t.apiSecret, indodaxPair, id, side)
+		return wrapError(err)
+	}
+
+	// ClientOrderID format, use new cancelByClientOrderId method
+	_, err := t.client.CancelByClientOrderID(ctx, t.apiKey, t.apiSecret, indodaxPair, orderID, side)
+	return wrapError(err)
+}
+
+func (t *Trader) GetOrder(ctx context.Context, pair string, orderID string) (*exchange.Order, error) {
+	var info *api.OrderInfo
+	var err error
+
+	// Try to parse as numeric ID first (old format)
+	if id, parseErr := strconv.ParseInt(orderID, 10, 64); parseErr == nil {
+		// Numeric ID, use getOrder method
+		info, err = t.client.GetOrder(ctx, t.apiKey, t.apiSecret, ToIndodaxPair(pair), id)
+	} else {
+		// ClientOrderID format, use getOrderByClientOrderId method
+		info, err = t.client.GetOrderByClientOrderID(ctx, t.apiKey, t.apiSecret, orderID)
+	}
+	if err != nil {
+		return nil, wrapError(err)
+	}
+
+	order := toOrder(pair, info)
+	return &order, nil
+}
+
+func (t *Trader) GetOpenOrders(ctx context.Context, pair string) ([]exchange.Order, error) {
+	infos, err := t.client.OpenOrders(ctx, t.apiKey, t.apiSecret, ToIndodaxPair(pair))
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	return toOrders(pair, infos), nil
+}
+
+func (t *Trader) GetOrderHistory(ctx context.Context, pair string, limit int) ([]exchange.Order, error) {
+	infos, err := t.client.OrderHistory(ctx, t.apiKey, t.apiSecret, ToIndodaxPair(pair), limit)
+	if err != nil {
+		return nil, wrapError(err)
+	}
+	return toOrders(pair, infos), nil
+}
+
+// toOrder converts an Indodax order. Market buys are sized in IDR (order_idr/remain_idr).
+func toOrder(pair string, info *api.OrderInfo) exchange.Order {
+	amount, remaining := info.OrderCoin, info.RemainCoin
+	if amount == "" && info.OrderIDR != "" {
+		amount, remaining = info.OrderIDR, info.RemainIDR
+	}
+
+	return exchange.Order{
+		OrderID:       info.OrderID,
+		ClientOrderID: info.ClientOrderID,
+		Pair:          pair,
+		Side:          strings.ToLower(info.Type),
+		Type:          strings.ToLower(info.OrderType),
+		Price:         parseFloat(info.Price),
+		Amount:        parseFloat(amount),
+		Remaining:     parseFloat(remaining),
+		Status:        normalizeStatus(info.Status),
+	}
+}
+
+func toOrders(pair string, infos []api.OrderInfo) []exchange.Order {
+	orders := make([]exchange.Order, 0, len(infos))
+	for i := range infos {
+		orders = append(orders, toOrder(pair, &infos[i]))
+	}
+	return orders
+}
+
+// toBalances parses Indodax balance strings, skipping unparsable values
+func toBalances(balances map[string]api.BalanceValue) map[string]float64 {
+	result := make(map[string]float64, len(balances))
+	for asset, v := range balances {
+		f, err := strconv.ParseFloat(v.String(), 64)
+		if err != nil {
+			continue
+		}
+		result[strings.ToLower(asset)] = f
+	}
+	return result
+}
+
+// disabledTrader refuses every call, see Exchange.DisableTrading
+type disabledTrader struct {
+	err error
+}
+
+func (t disabledTrader) GetInfo(ctx context.Context) (*exchange.AccountInfo, error) {
+	return nil, t.err
+}
+
+func (t disabledTrader) Trade(ctx context.Context, side, pair string, price, amount float64, type_ string, clientOrderID string) (*exchange.OrderResult, error) {
+	return nil, t.err
+}
+
+func (t disabledTrader) CancelOrder(ctx context.Context, pair string, orderID string, side string) error {
+	return t.err
+}
+
+func (t disabledTrader) GetOrder(ctx context.Context, pair string, orderID string) (*exchange.Order, error) {
+	return nil, t.err
+}
+
+func (t disabledTrader) GetOpenOrders(ctx context.Context, pair string) ([]exchange.Order, error) {
+	return nil, t.err
+}
+
+func (t disabledTrader) GetOrderHistory(ctx context.Context, pair string, limit int) ([]exchange.Order, error) {
+	return nil, t.err
+}
@@ -0,0 +1,118 @@
+package recorder
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"tuyul/backend/internal/exchange"
+	"tuyul/backend/internal/model"
+)
+
+// BuildCandles plays a recording as fast as possible and aggregates it into
+// closed candles of one timeframe per pair, oldest first. stream must be the
+// venue market stream decoding the frames of replay.
+//
+// Trades set the volume and trade count, tickers move the price of pairs
+// without trades. Times come from the trade prints and the replay clock.
+func BuildCandles(replay *Replay, stream exchange.MarketStream, tf model.TimeframeSpec) map[string][]model.Candle {
+	b := &candleBuilder{
+		tf:      tf,
+		live:    make(map[string]*model.Candle),
+		lastID:  make(map[string]int64),
+		candles: make(map[string][]model.Candle),
+	}
+	stream.AddTradeHandler(b.addTrades)
+	stream.AddTickerHandler(func(tickers []exchange.Ticker) {
+		b.addTickers(replay.Clock(), tickers)
+	})
+	stream.SubscribeTickers()
+	stream.Connect()
+	<-replay.Done()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for pair := range b.live {
+		b.close(pair)
+	}
+	return b.candles
+}
+
+// candleBuilder aggregates market data into candles
+type candleBuilder struct {
+	tf      model.TimeframeSpec
+	live    map[string]*model.Candle
+	lastID  map[string]int64 // Last trade applied per pair, the stream may repeat prints
+	candles map[string][]model.Candle
+	mu      sync.Mutex
+}
+
+func (b *candleBuilder) addTrades(trades []exchange.Trade) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sort.SliceStable(trades, func(i, j int) bool { return trades[i].ID < trades[j].ID })
+	for _, t := range trades {
+		if t.Price <= 0 || (t.ID != 0 && t.ID <= b.lastID[t.Pair]) {
+			continue
+		}
+		b.lastID[t.Pair] = max(b.lastID[t.Pair], t.ID)
+
+		c := b.candle(t.Pair, t.Time, t.Price)
+		total := t.Price * t.Amount
+		c.Volume += t.Amount
+		c.QuoteVolume += total
+		if t.Side == "buy" {
+			c.BuyVolume += total
+		} else {
+			c.SellVolume += total
+		}
+		c.Trades++
+		c.VWAP = c.QuoteVolume / c.Volume
+	}
+}
+
+func (b *candleBuilder) addTickers(now time.Time, tickers []exchange.Ticker) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, t := range tickers {
+		if t.Last > 0 {
+			b.candle(t.Pair, now, t.Last)
+		}
+	}
+}
+
+// candle applies a price to the candle of a pair covering t, closing the previous one
+func (b *candleBuilder) candle(pair string, t time.Time, price float64) *model.Candle {
+	start := t.Truncate(b.tf.Duration)
+	c := b.live[pair]
+	if c != nil && start.Before(c.OpenTime) {
+		// Late print of a closed candle, count it in the current one
+		start = c.OpenTime
+	}
+	if c != nil && !c.OpenTime.Equal(start) {
+		b.close(pair)
+		c = nil
+	}
+	if c == nil {
+		c = &model.Candle{
+			Pair:      pair,
+			Timeframe: b.tf.Name,
+			OpenTime:  start,
+			CloseTime: start.Add(b.tf.Duration),
+			Open:      price,
+			High:      price,
+			Low:       price,
+		}
+		b.live[pair] = c
+	}
+	c.High = max(c.High, price)
+	c.Low = min(c.Low, price)
+	c.Close = price
+	return c
+}
+
+func (b *candleBuilder) close(pair string) {
+	b.candles[pair] = append(b.candles[pair], *b.live[pair])
+	delete(b.live, pair)
+}
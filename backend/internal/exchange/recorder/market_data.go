@@ -0,0 +1,58 @@
+package recorder
+
+import (
+	"context"
+
+	"tuyul/backend/internal/exchange"
+)
+
+// MarketData records the responses of a wrapped exchange.MarketData
+type MarketData struct {
+	inner exchange.MarketData
+	rec   *Recorder
+}
+
+// NewMarketData wraps a market data API so its responses are recorded
+func NewMarketData(inner exchange.MarketData, rec *Recorder) *MarketData {
+	return &MarketData{inner: inner, rec: rec}
+}
+
+func (m *MarketData) GetPairs(ctx context.Context) ([]exchange.Pair, error) {
+	pairs, err := m.inner.GetPairs(ctx)
+	if err == nil {
+		m.rec.RecordJSON(KindPairs, "", pairs)
+	}
+	return pairs, err
+}
+
+func (m *MarketData) GetPriceIncrements(ctx context.Context) (map[string]float64, error) {
+	increments, err := m.inner.GetPriceIncrements(ctx)
+	if err == nil {
+		m.rec.RecordJSON(KindIncrements, "", increments)
+	}
+	return increments, err
+}
+
+func (m *MarketData) GetTickers(ctx context.Context) ([]exchange.Ticker, error) {
+	tickers, err := m.inner.GetTickers(ctx)
+	if err == nil {
+		m.rec.RecordJSON(KindTickers, "", tickers)
+	}
+	return tickers, err
+}
+
+func (m *MarketData) GetTrades(ctx context.Context, pair string) ([]exchange.Trade, error) {
+	trades, err := m.inner.GetTrades(ctx, pair)
+	if err == nil {
+		m.rec.RecordJSON(KindTrades, pair, trades)
+	}
+	return trades, err
+}
+
+func (m *MarketData) GetOrderBook(ctx context.Context, pair string) (*exchange.OrderBook, error) {
+	book, err := m.inner.GetOrderBook(ctx, pair)
+	if err == nil {
+		m.rec.RecordJSON(KindOrderBook, pair, book)
+	}
+	return book, err
+}
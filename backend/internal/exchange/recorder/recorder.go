@@ -0,0 +1,190 @@
+// Package recorder captures public market data to compressed files and
+// replays them through the same exchange interfaces, so what the market
+// services and bots saw can be reproduced locally.
+//
+// Files are gzip-compressed JSON lines, one Event per line, rotated on a fixed
+// interval. Stream frames are stored as received from the venue WebSocket,
+// REST responses in their exchange-neutral form.
+package recorder
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"tuyul/backend/pkg/logger"
+)
+
+// Event kinds
+const (
+	KindFrame      = "frame"      // Public WebSocket channel frame
+	KindTickers    = "tickers"    // GetTickers response (summary and gap polls)
+	KindOrderBook  = "order_book" // GetOrderBook response
+	KindTrades     = "trades"     // GetTrades response
+	KindPairs      = "pairs"      // GetPairs response
+	KindIncrements = "increments" // GetPriceIncrements response
+)
+
+const (
+	// File name pattern, the time is the UTC start of the file
+	fileTimeLayout = "20060102T150405Z"
+	fileSuffix     = ".jsonl.gz"
+	// Buffered events are flushed to disk at least this often
+	flushInterval = 5 * time.Second
+)
+
+// Event is one recorded message
+type Event struct {
+	Time    time.Time       `json:"t"`
+	Kind    string          `json:"k"`
+	Channel string          `json:"c,omitempty"` // WebSocket channel of frames
+	Pair    string          `json:"p,omitempty"` // Pair of per-pair REST responses
+	Data    json.RawMessage `json:"d"`
+}
+
+// Recorder appends events to rotating files in a directory
+type Recorder struct {
+	dir    string
+	rotate time.Duration
+	log    *logger.Logger
+
+	file    *os.File
+	gz      *gzip.Writer
+	enc     *json.Encoder
+	opened  time.Time
+	closed  bool
+	stop    chan struct{}
+	mu      sync.Mutex
+	written int64
+}
+
+// New creates a recorder writing to dir, starting a new file every rotate
+func New(dir string, rotate time.Duration) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create record directory: %w", err)
+	}
+	if rotate <= 0 {
+		rotate = time.Hour
+	}
+
+	r := &Recorder{
+		dir:    dir,
+		rotate: rotate,
+		log:    logger.GetLogger(),
+		stop:   make(chan struct{}),
+	}
+	if err := r.openFile(time.Now()); err != nil {
+		return nil, err
+	}
+	go r.flushLoop()
+	return r, nil
+}
+
+func (r *Recorder) openFile(now time.Time) error {
+	name := filepath.Join(r.dir, "market-"+now.UTC().Format(fileTimeLayout)+fileSuffix)
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open record file: %w", err)
+	}
+	r.file = f
+	r.gz = gzip.NewWriter(f)
+	r.enc = json.NewEncoder(r.gz)
+	r.opened = now
+	r.log.Infof("Recording market data to %s", name)
+	return nil
+}
+
+func (r *Recorder) closeFile() {
+	if r.gz != nil {
+		if err := r.gz.Close(); err != nil {
+			r.log.Errorf("Failed to finish record file: %v", err)
+		}
+	}
+	if r.file != nil {
+		r.file.Close()
+	}
+	r.file, r.gz, r.enc = nil, nil, nil
+}
+
+// Record appends an event with raw JSON data
+func (r *Recorder) Record(kind, channel, pair string, data []byte) {
+	if !json.Valid(data) {
+		return
+	}
+	now := time.Now()
+	ev := Event{Time: now, Kind: kind, Channel: channel, Pair: pair, Data: json.RawMessage(data)}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return
+	}
+
+	if now.Sub(r.opened) >= r.rotate {
+		r.closeFile()
+		if err := r.openFile(now); err != nil {
+			r.log.Errorf("Failed to rotate record file: %v", err)
+			return
+		}
+	}
+	if r.enc == nil {
+		return
+	}
+	if err := r.enc.Encode(&ev); err != nil {
+		r.log.Errorf("Failed to record %s event: %v", kind, err)
+		return
+	}
+	r.written++
+}
+
+// RecordJSON appends an event with a value encoded as JSON
+func (r *Recorder) RecordJSON(kind, pair string, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		r.log.Errorf("Failed to encode %s event: %v", kind, err)
+		return
+	}
+	r.Record(kind, "", pair, data)
+}
+
+// RecordFrame is a WebSocket message handler recording every channel frame
+func (r *Recorder) RecordFrame(channel string, data []byte) {
+	r.Record(KindFrame, channel, "", data)
+}
+
+func (r *Recorder) flushLoop() {
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			r.mu.Lock()
+			if r.gz != nil {
+				if err := r.gz.Flush(); err != nil {
+					r.log.Errorf("Failed to flush record file: %v", err)
+				}
+			}
+			r.mu.Unlock()
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+// Close flushes and closes the current file
+func (r *Recorder) Close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return
+	}
+	r.closed = true
+	close(r.stop)
+	r.closeFile()
+	r.log.Infof("Market data recorder closed (%d events)", r.written)
+}
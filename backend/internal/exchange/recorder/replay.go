@@ -0,0 +1,317 @@
+package recorder
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"tuyul/backend/internal/exchange"
+	"tuyul/backend/pkg/logger"
+)
+
+// Reader reads the events of recorded files in order
+type Reader struct {
+	files []string
+	next  int
+	file  *os.File
+	dec   *json.Decoder
+}
+
+// OpenReader opens a recorded file, or every recorded file of a directory in time order
+func OpenReader(path string) (*Reader, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{path}
+	if info.IsDir() {
+		files, err = filepath.Glob(filepath.Join(path, "*"+fileSuffix))
+		if err != nil {
+			return nil, err
+		}
+		if len(files) == 0 {
+			return nil, fmt.Errorf("no %s files in %s", fileSuffix, path)
+		}
+		sort.Strings(files) // Names carry the start time
+	}
+	return &Reader{files: files}, nil
+}
+
+// Next returns the next event, io.EOF after the last one
+func (r *Reader) Next() (*Event, error) {
+	for {
+		if r.dec == nil {
+			if r.next >= len(r.files) {
+				return nil, io.EOF
+			}
+			if err := r.openNext(); err != nil {
+				return nil, err
+			}
+		}
+
+		var ev Event
+		err := r.dec.Decode(&ev)
+		if err == nil {
+			return &ev, nil
+		}
+		if err != io.EOF && err != io.ErrUnexpectedEOF {
+			return nil, fmt.Errorf("%s: %w", r.file.Name(), err)
+		}
+		// End of file (a truncated last line is expected if the recorder was killed)
+		r.closeFile()
+	}
+}
+
+func (r *Reader) openNext() error {
+	f, err := os.Open(r.files[r.next])
+	if err != nil {
+		return err
+	}
+	r.next++
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("%s: %w", f.Name(), err)
+	}
+	r.file = f
+	r.dec = json.NewDecoder(gz)
+	return nil
+}
+
+func (r *Reader) closeFile() {
+	if r.file != nil {
+		r.file.Close()
+	}
+	r.file, r.dec = nil, nil
+}
+
+// Close releases the open file
+func (r *Reader) Close() {
+	r.closeFile()
+	r.next = len(r.files)
+}
+
+// Replay plays recorded market data back. It is a frame source for the venue
+// market stream and serves the REST market data API from the recorded responses.
+//
+// Events are delivered in recorded order from one goroutine, at the recorded
+// pace divided by speed (0 plays as fast as possible). REST calls return the
+// latest response recorded up to the replay clock, or the first one of its kind
+// before the clock reaches it.
+type Replay struct {
+	path  string
+	speed float64
+	log   *logger.Logger
+
+	handlers     []func(channel string, data []byte)
+	connHandlers []func()
+
+	// REST responses, key: kind|pair
+	first  map[string]json.RawMessage
+	latest map[string]json.RawMessage
+	clock  time.Time
+
+	events  int64
+	started sync.Once
+	done    chan struct{}
+	mu      sync.RWMutex
+}
+
+// NewReplay prepares a replay of a recorded file or directory
+func NewReplay(path string, speed float64) (*Replay, error) {
+	if speed < 0 {
+		return nil, fmt.Errorf("replay speed must not be negative")
+	}
+	r := &Replay{
+		path:   path,
+		speed:  speed,
+		log:    logger.GetLogger(),
+		first:  make(map[string]json.RawMessage),
+		latest: make(map[string]json.RawMessage),
+		done:   make(chan struct{}),
+	}
+
+	// Index the first REST response of every kind so calls made before the
+	// clock reaches them (e.g. metadata at startup) are answered
+	reader, err := OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+	for {
+		ev, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if ev.Kind == KindFrame {
+			continue
+		}
+		if key := responseKey(ev.Kind, ev.Pair); r.first[key] == nil {
+			r.first[key] = ev.Data
+		}
+	}
+	return r, nil
+}
+
+func responseKey(kind, pair string) string {
+	return kind + "|" + pair
+}
+
+// Connect starts the playback on first call
+func (r *Replay) Connect() error {
+	r.started.Do(func() {
+		go r.run()
+	})
+	return nil
+}
+
+func (r *Replay) AddMessageHandler(handler func(channel string, data []byte)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.handlers = append(r.handlers, handler)
+}
+
+func (r *Replay) AddConnectHandler(handler func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.connHandlers = append(r.connHandlers, handler)
+}
+
+// Subscribe is a no-op, every recorded frame is delivered as it was received live
+func (r *Replay) Subscribe(channel string) {}
+
+// Unsubscribe is a no-op, see Subscribe
+func (r *Replay) Unsubscribe(channel string) {}
+
+// Done is closed when the playback reached the end of the recording
+func (r *Replay) Done() <-chan struct{} {
+	return r.done
+}
+
+// Clock returns the recorded time of the last played event
+func (r *Replay) Clock() time.Time {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.clock
+}
+
+func (r *Replay) run() {
+	defer close(r.done)
+
+	reader, err := OpenReader(r.path)
+	if err != nil {
+		r.log.Errorf("Replay: %v", err)
+		return
+	}
+	defer reader.Close()
+
+	r.mu.RLock()
+	connHandlers := r.connHandlers
+	r.mu.RUnlock()
+	for _, h := range connHandlers {
+		h()
+	}
+
+	r.log.Infof("Replay: playing %s (speed=%g)", r.path, r.speed)
+	var origin time.Time
+	start := time.Now()
+	for {
+		ev, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			r.log.Errorf("Replay stopped: %v", err)
+			return
+		}
+
+		// Keep the recorded pace
+		if origin.IsZero() {
+			origin = ev.Time
+		}
+		if r.speed > 0 {
+			due := start.Add(time.Duration(float64(ev.Time.Sub(origin)) / r.speed))
+			if wait := time.Until(due); wait > 0 {
+				time.Sleep(wait)
+			}
+		}
+
+		r.play(ev)
+	}
+	r.log.Infof("Replay finished: %d events up to %s", r.events, r.Clock().Format(time.RFC3339))
+}
+
+// play applies one event
+func (r *Replay) play(ev *Event) {
+	r.mu.Lock()
+	r.clock = ev.Time
+	r.events++
+	if ev.Kind != KindFrame {
+		r.latest[responseKey(ev.Kind, ev.Pair)] = ev.Data
+		r.mu.Unlock()
+		return
+	}
+	handlers := r.handlers
+	r.mu.Unlock()
+
+	for _, h := range handlers {
+		h(ev.Channel, ev.Data)
+	}
+}
+
+// response decodes the REST response of a kind and pair at the replay clock
+func (r *Replay) response(kind, pair string, dest any) error {
+	key := responseKey(kind, pair)
+	r.mu.RLock()
+	data, ok := r.latest[key]
+	if !ok {
+		data, ok = r.first[key]
+	}
+	r.mu.RUnlock()
+
+	if !ok {
+		if pair != "" {
+			return fmt.Errorf("no %s of %s recorded", kind, pair)
+		}
+		return fmt.Errorf("no %s recorded", kind)
+	}
+	return json.Unmarshal(data, dest)
+}
+
+func (r *Replay) GetPairs(ctx context.Context) ([]exchange.Pair, error) {
+	var pairs []exchange.Pair
+	return pairs, r.response(KindPairs, "", &pairs)
+}
+
+func (r *Replay) GetPriceIncrements(ctx context.Context) (map[string]float64, error) {
+	var increments map[string]float64
+	return increments, r.response(KindIncrements, "", &increments)
+}
+
+func (r *Replay) GetTickers(ctx context.Context) ([]exchange.Ticker, error) {
+	var tickers []exchange.Ticker
+	return tickers, r.response(KindTickers, "", &tickers)
+}
+
+func (r *Replay) GetTrades(ctx context.Context, pair string) ([]exchange.Trade, error) {
+	var trades []exchange.Trade
+	return trades, r.response(KindTrades, pair, &trades)
+}
+
+func (r *Replay) GetOrderBook(ctx context.Context, pair string) (*exchange.OrderBook, error) {
+	var book exchange.OrderBook
+	if err := r.response(KindOrderBook, pair, &book); err != nil {
+		return nil, err
+	}
+	return &book, nil
+}
@@ -0,0 +1,307 @@
+// Package shadow simulates the fills of orders that are never sent to the venue.
+// The orders rest in the shadow of the venue order book: they keep their queue
+// position behind the venue volume at their price and fill from the public
+// trade prints and book snapshots, without moving the venue market.
+package shadow
+
+import (
+	"math"
+	"sort"
+
+	"tuyul/backend/internal/exchange"
+)
+
+// Order is a limit order resting in the shadow of a venue book
+type Order struct {
+	ID     string
+	Side   string // buy or sell
+	Price  float64
+	Amount float64 // Base currency
+	Filled float64
+
+	// QueueAhead is the venue volume resting before the order at its price,
+	// it has to trade or be cancelled before the order fills
+	QueueAhead float64
+
+	seq int64 // Time priority among the shadow orders
+}
+
+// Remaining returns the unfilled amount
+func (o *Order) Remaining() float64 {
+	return math.Max(o.Amount-o.Filled, 0)
+}
+
+// Fill is an executed part of an order
+type Fill struct {
+	Order  *Order
+	Price  float64
+	Amount float64
+	Maker  bool // Rested in the book, false when the order crossed the spread on placement
+}
+
+// Book matches the shadow orders of one pair against the venue data.
+// It is not safe for concurrent use.
+type Book struct {
+	venue       *exchange.OrderBook // Last venue snapshot
+	orders      []*Order
+	taken       map[float64]float64 // Snapshot volume crossing orders took, by price
+	lastTradeID int64
+	seq         int64
+}
+
+// NewBook creates an empty book
+func NewBook() *Book {
+	return &Book{taken: make(map[float64]float64)}
+}
+
+// Venue returns the last venue snapshot, nil before the first one
+func (b *Book) Venue() *exchange.OrderBook {
+	return b.venue
+}
+
+// Orders returns the resting orders
+func (b *Book) Orders() []*Order {
+	return b.orders
+}
+
+// Order returns a resting order, nil if there is none with this ID
+func (b *Book) Order(id string) *Order {
+	for _, o := range b.orders {
+		if o.ID == id {
+			return o
+		}
+	}
+	return nil
+}
+
+// Place adds a limit order. The part crossing the venue book fills at once,
+// level by level as a taker; the rest joins the queue behind the venue volume at its price.
+func (b *Book) Place(o *Order) []Fill {
+	b.seq++
+	o.seq = b.seq
+
+	var fills []Fill
+	if b.venue != nil {
+		levels := b.venue.Asks
+		if o.Side == "sell" {
+			levels = b.venue.Bids
+		}
+		for _, level := range levels {
+			if o.Remaining() <= 0 || !crosses(o.Side, o.Price, level.Price) {
+				break
+			}
+			qty := math.Min(o.Remaining(), level.BaseVolume-b.taken[level.Price])
+			if qty <= 0 {
+				continue
+			}
+			b.taken[level.Price] += qty
+			fills = append(fills, b.fill(o, level.Price, qty, false))
+		}
+		o.QueueAhead = levelVolume(b.sameSide(o.Side), o.Price)
+	}
+
+	if o.Remaining() > 0 {
+		b.orders = append(b.orders, o)
+	}
+	return fills
+}
+
+// Cancel removes a resting order and returns it, nil if there is none with this ID
+func (b *Book) Cancel(id string) *Order {
+	for i, o := range b.orders {
+		if o.ID == id {
+			b.orders = append(b.orders[:i], b.orders[i+1:]...)
+			return o
+		}
+	}
+	return nil
+}
+
+// UpdateOrderBook applies a venue snapshot. Orders the opposite side moved
+// through fill from its volume at their price or better, the queue ahead of
+// the others shrinks to the venue volume left at their price.
+func (b *Book) UpdateOrderBook(book *exchange.OrderBook) []Fill {
+	b.venue = book
+	clear(b.taken)
+
+	var fills []Fill
+	for _, o := range b.priority() {
+		opposite := book.Asks
+		if o.Side == "sell" {
+			opposite = book.Bids
+		}
+		available := 0.0
+		for _, level := range opposite {
+			if !crosses(o.Side, o.Price, level.Price) {
+				break
+			}
+			available += level.BaseVolume - b.taken[level.Price]
+		}
+		if qty := math.Min(o.Remaining(), available); qty > 0 {
+			b.takeVolume(opposite, o.Side, o.Price, qty)
+			fills = append(fills, b.fill(o, o.Price, qty, true))
+			o.QueueAhead = 0
+		}
+		o.QueueAhead = math.Min(o.QueueAhead, levelVolume(b.sameSide(o.Side), o.Price))
+	}
+	b.removeFilled()
+	return fills
+}
+
+// AddTrades applies venue trade prints. A taker trading at an order's price
+// consumes the queue ahead before filling the order, one trading through the
+// price fills it first. Prints already applied are skipped.
+func (b *Book) AddTrades(trades []exchange.Trade) []Fill {
+	sorted := append([]exchange.Trade(nil), trades...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].ID < sorted[j].ID })
+
+	var fills []Fill
+	for _, t := range sorted {
+		if t.ID != 0 && t.ID <= b.lastTradeID {
+			continue
+		}
+		b.lastTradeID = max(b.lastTradeID, t.ID)
+
+		left := t.Amount
+		for _, o := range b.priority() {
+			if left <= 0 {
+				break
+			}
+			// A taker sell hits resting buys, a taker buy lifts resting sells
+			if o.Side == t.Side || o.Remaining() <= 0 || !crosses(t.Side, t.Price, o.Price) {
+				continue
+			}
+			if samePrice(t.Price, o.Price) {
+				ahead := math.Min(o.QueueAhead, left)
+				o.QueueAhead -= ahead
+				left -= ahead
+			}
+			if qty := math.Min(o.Remaining(), left); qty > 0 {
+				left -= qty
+				fills = append(fills, b.fill(o, o.Price, qty, true))
+			}
+		}
+	}
+	b.removeFilled()
+	return fills
+}
+
+// OrderBook returns the venue snapshot with the resting orders merged in,
+// as the venue would show it. Nil before the first snapshot.
+func (b *Book) OrderBook() *exchange.OrderBook {
+	if b.venue == nil {
+		return nil
+	}
+	book := &exchange.OrderBook{
+		Pair:     b.venue.Pair,
+		Sequence: b.venue.Sequence,
+		Bids:     append([]exchange.OrderBookLevel(nil), b.venue.Bids...),
+		Asks:     append([]exchange.OrderBookLevel(nil), b.venue.Asks...),
+	}
+	for _, o := range b.orders {
+		if o.Side == "buy" {
+			book.Bids = mergeLevel(book.Bids, o, func(a, b float64) bool { return a > b })
+		} else {
+			book.Asks = mergeLevel(book.Asks, o, func(a, b float64) bool { return a < b })
+		}
+	}
+	return book
+}
+
+func (b *Book) fill(o *Order, price, qty float64, maker bool) Fill {
+	if qty >= o.Remaining() {
+		o.Filled = o.Amount // No float dust left resting
+	} else {
+		o.Filled += qty
+	}
+	return Fill{Order: o, Price: price, Amount: qty, Maker: maker}
+}
+
+// takeVolume books snapshot volume an order filled against, best levels first
+func (b *Book) takeVolume(levels []exchange.OrderBookLevel, side string, price, qty float64) {
+	for _, level := range levels {
+		if qty <= 0 || !crosses(side, price, level.Price) {
+			return
+		}
+		take := math.Min(qty, level.BaseVolume-b.taken[level.Price])
+		if take > 0 {
+			b.taken[level.Price] += take
+			qty -= take
+		}
+	}
+}
+
+// priority returns the resting orders best price first, then oldest first
+func (b *Book) priority() []*Order {
+	orders := append([]*Order(nil), b.orders...)
+	sort.SliceStable(orders, func(i, j int) bool {
+		oi, oj := orders[i], orders[j]
+		if oi.Side == oj.Side && !samePrice(oi.Price, oj.Price) {
+			if oi.Side == "buy" {
+				return oi.Price > oj.Price
+			}
+			return oi.Price < oj.Price
+		}
+		return oi.seq < oj.seq
+	})
+	return orders
+}
+
+func (b *Book) removeFilled() {
+	kept := b.orders[:0]
+	for _, o := range b.orders {
+		if o.Remaining() > 0 {
+			kept = append(kept, o)
+		}
+	}
+	clear(b.orders[len(kept):])
+	b.orders = kept
+}
+
+// sameSide returns the venue levels an order of this side rests among
+func (b *Book) sameSide(side string) []exchange.OrderBookLevel {
+	if side == "buy" {
+		return b.venue.Bids
+	}
+	return b.venue.Asks
+}
+
+// crosses reports whether an order of side at price trades with a counter price
+func crosses(side string, price, counter float64) bool {
+	if side == "buy" {
+		return counter <= price || samePrice(counter, price)
+	}
+	return counter >= price || samePrice(counter, price)
+}
+
+// samePrice compares prices parsed and computed separately
+func samePrice(a, b float64) bool {
+	return math.Abs(a-b) <= 1e-9*math.Max(math.Abs(a), math.Abs(b))
+}
+
+func levelVolume(levels []exchange.OrderBookLevel, price float64) float64 {
+	for _, level := range levels {
+		if samePrice(level.Price, price) {
+			return level.BaseVolume
+		}
+	}
+	return 0
+}
+
+// mergeLevel adds the remaining amount of an order to its level, better reports
+// whether a price sorts before another on this side
+func mergeLevel(levels []exchange.OrderBookLevel, o *Order, better func(a, b float64) bool) []exchange.OrderBookLevel {
+	qty := o.Remaining()
+	for i, level := range levels {
+		if samePrice(level.Price, o.Price) {
+			levels[i].BaseVolume += qty
+			levels[i].QuoteVolume += qty * o.Price
+			return levels
+		}
+		if better(o.Price, level.Price) {
+			levels = append(levels[:i], append([]exchange.OrderBookLevel{{Price: o.Price, BaseVolume: qty, QuoteVolume: qty * o.Price}}, levels[i:]...)...)
+			return levels
+		}
+	}
+	return append(levels, exchange.OrderBookLevel{Price: o.Price, BaseVolume: qty, QuoteVolume: qty * o.Price})
+}
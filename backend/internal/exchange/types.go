@@ -0,0 +1,125 @@
+package exchange
+
+import "time"
+
+// Order statuses reported by adapters in Order and OrderUpdate
+const (
+	OrderStatusOpen            = "open"
+	OrderStatusPartiallyFilled = "partially_filled"
+	OrderStatusFilled          = "filled"
+	OrderStatusCancelled       = "cancelled"
+)
+
+// Pair describes a tradable market.
+// Base is the traded asset (e.g. "btc"), quote is the pricing asset (e.g. "idr").
+type Pair struct {
+	ID              string  `json:"id"`     // Internal pair ID, e.g. "btcidr"
+	Symbol          string  `json:"symbol"` // Venue symbol, e.g. "BTCIDR"
+	BaseCurrency    string  `json:"base_currency"`
+	QuoteCurrency   string  `json:"quote_currency"`
+	Description     string  `json:"description"`
+	VolumePrecision int     `json:"volume_precision"`
+	PricePrecision  int     `json:"price_precision"`
+	PriceRound      int     `json:"price_round"`
+	MinBaseAmount   float64 `json:"min_base_amount"`  // Minimum order size in base currency
+	MinQuoteAmount  float64 `json:"min_quote_amount"` // Minimum order value in quote currency
+}
+
+// Ticker is a 24h summary of a pair. Fields a source does not provide are zero.
+type Ticker struct {
+	Pair        string  `json:"pair"`
+	Last        float64 `json:"last"`
+	High        float64 `json:"high"`
+	Low         float64 `json:"low"`
+	Open        float64 `json:"open"`
+	Bid         float64 `json:"bid"`
+	Ask         float64 `json:"ask"`
+	BaseVolume  float64 `json:"base_volume"`
+	QuoteVolume float64 `json:"quote_volume"`
+}
+
+// Trade is a public trade print
+type Trade struct {
+	Pair   string    `json:"pair"`
+	ID     int64     `json:"id"`   // Venue trade ID, increasing per pair
+	Side   string    `json:"side"` // Taker side: "buy" or "sell"
+	Price  float64   `json:"price"`
+	Amount float64   `json:"amount"` // Base currency
+	Time   time.Time `json:"time"`
+}
+
+// OrderBookLevel is a single price level
+type OrderBookLevel struct {
+	Price       float64 `json:"price"`
+	BaseVolume  float64 `json:"base_volume"`
+	QuoteVolume float64 `json:"quote_volume"`
+}
+
+// OrderBook is a depth snapshot of a pair
+type OrderBook struct {
+	Pair     string           `json:"pair"`
+	Sequence int64            `json:"sequence"` // Venue sequence number, 0 if not provided
+	Bids     []OrderBookLevel `json:"bids"`     // Highest first
+	Asks     []OrderBookLevel `json:"asks"`     // Lowest first
+}
+
+// AccountInfo holds account identity and balances keyed by lowercase asset
+type AccountInfo struct {
+	UserID     string             `json:"user_id"`
+	Name       string             `json:"name"`
+	Email      string             `json:"email"`
+	ServerTime int64              `json:"server_time"`
+	Balances   map[string]float64 `json:"balances"` // Available
+	Held       map[string]float64 `json:"held"`     // Locked in open orders
+}
+
+// OrderResult is returned when an order is accepted
+type OrderResult struct {
+	OrderID       string `json:"order_id"`
+	ClientOrderID string `json:"client_order_id"`
+}
+
+// Order is a snapshot of an order.
+// Amount and Remaining are in base currency (quote currency for market buys).
+type Order struct {
+	OrderID       string  `json:"order_id"`
+	ClientOrderID string  `json:"client_order_id"`
+	Pair          string  `json:"pair"`
+	Side          string  `json:"side"` // buy or sell
+	Type          string  `json:"type"` // limit or market
+	Price         float64 `json:"price"`
+	Amount        float64 `json:"amount"`
+	Remaining     float64 `json:"remaining"`
+	Status        string  `json:"status"`
+	Fee           float64 `json:"fee"` // Paid so far in quote currency, 0 if the venue does not report it
+}
+
+// OrderUpdate is a private stream event for an order
+type OrderUpdate struct {
+	OrderID         string  `json:"order_id"`
+	ClientOrderID   string  `json:"client_order_id"`
+	Pair            string  `json:"pair"`
+	Side            string  `json:"side"` // buy or sell
+	Price           float64 `json:"price"`
+	OrigQty         float64 `json:"orig_qty"`
+	ExecutedQty     float64 `json:"executed_qty"` // Cumulative
+	UnfilledQty     float64 `json:"unfilled_qty"`
+	Status          string  `json:"status"`
+	TransactionTime int64   `json:"transaction_time"` // Unix milliseconds
+
+	// Fee of the quantity executed since the previous update in quote currency,
+	// 0 if the venue does not report it
+	Fee float64 `json:"fee"`
+}
+
+// IsFill reports whether the update carries an executed quantity
+func (u *OrderUpdate) IsFill() bool {
+	return u.Status == OrderStatusPartiallyFilled || u.Status == OrderStatusFilled
+}
+
+// KeyPermissions are the scopes granted to API credentials
+type KeyPermissions struct {
+	View     bool `json:"view"`     // Balances and orders
+	Trade    bool `json:"trade"`    // Place and cancel orders
+	Withdraw bool `json:"withdraw"` // Move funds off the venue
+}
@@ -0,0 +1,142 @@
+package handler
+
+import (
+	"strconv"
+
+	"tuyul/backend/internal/model"
+	"tuyul/backend/internal/service"
+	"tuyul/backend/internal/util"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AlertHandler handles price and signal alert endpoints
+type AlertHandler struct {
+	alertService *service.AlertService
+}
+
+// NewAlertHandler creates a new alert handler
+func NewAlertHandler(alertService *service.AlertService) *AlertHandler {
+	return &AlertHandler{
+		alertService: alertService,
+	}
+}
+
+// ListAlerts returns the alerts of the user
+// GET /api/v1/alerts
+func (h *AlertHandler) ListAlerts(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		util.SendError(c, util.ErrUnauthorized("User not authenticated"))
+		return
+	}
+
+	alerts, err := h.alertService.ListAlerts(c.Request.Context(), userID.(string))
+	if err != nil {
+		util.SendError(c, err)
+		return
+	}
+
+	util.SendSuccess(c, alerts)
+}
+
+// CreateAlert creates an alert
+// POST /api/v1/alerts
+func (h *AlertHandler) CreateAlert(c *gin.Context) {
+	var req model.AlertRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.SendValidationError(c, err.Error())
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		util.SendError(c, util.ErrUnauthorized("User not authenticated"))
+		return
+	}
+
+	alert, err := h.alertService.CreateAlert(c.Request.Context(), userID.(string), &req)
+	if err != nil {
+		util.SendError(c, err)
+		return
+	}
+
+	util.SendCreated(c, alert, "Alert created successfully")
+}
+
+// GetAlert returns an alert
+// GET /api/v1/alerts/:id
+func (h *AlertHandler) GetAlert(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		util.SendError(c, util.ErrUnauthorized("User not authenticated"))
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		util.SendError(c, util.ErrBadRequest("Invalid alert ID"))
+		return
+	}
+
+	alert, err := h.alertService.GetAlert(c.Request.Context(), userID.(string), id)
+	if err != nil {
+		util.SendError(c, err)
+		return
+	}
+
+	util.SendSuccess(c, alert)
+}
+
+// UpdateAlert replaces an alert
+// PUT /api/v1/alerts/:id
+func (h *AlertHandler) UpdateAlert(c *gin.Context) {
+	var req model.AlertRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.SendValidationError(c, err.Error())
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		util.SendError(c, util.ErrUnauthorized("User not authenticated"))
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		util.SendError(c, util.ErrBadRequest("Invalid alert ID"))
+		return
+	}
+
+	alert, err := h.alertService.UpdateAlert(c.Request.Context(), userID.(string), id, &req)
+	if err != nil {
+		util.SendError(c, err)
+		return
+	}
+
+	util.SendSuccess(c, alert)
+}
+
+// DeleteAlert removes an alert
+// DELETE /api/v1/alerts/:id
+func (h *AlertHandler) DeleteAlert(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		util.SendError(c, util.ErrUnauthorized("User not authenticated"))
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		util.SendError(c, util.ErrBadRequest("Invalid alert ID"))
+		return
+	}
+
+	if err := h.alertService.DeleteAlert(c.Request.Context(), userID.(string), id); err != nil {
+		util.SendError(c, err)
+		return
+	}
+
+	util.SendSuccessWithMessage(c, nil, "Alert deleted successfully")
+}
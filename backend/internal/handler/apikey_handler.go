@@ -1,6 +1,8 @@
 package handler
 
 import (
+	"strconv"
+
 	"tuyul/backend/internal/model"
 	"tuyul/backend/internal/service"
 	"tuyul/backend/internal/util"
@@ -40,7 +42,7 @@ func (h *APIKeyHandler) Create(c *gin.Context) {
 	util.SendCreated(c, apiKey, "API key saved and validated successfully")
 }
 
-// Get gets the API key status
+// Get gets the status of the default API key
 // GET /api/v1/api-keys
 func (h *APIKeyHandler) Get(c *gin.Context) {
 	userID, _ := c.Get("user_id")
@@ -54,7 +56,7 @@ func (h *APIKeyHandler) Get(c *gin.Context) {
 	util.SendSuccess(c, apiKey)
 }
 
-// Delete deletes the API key
+// Delete deletes the default API key
 // DELETE /api/v1/api-keys
 func (h *APIKeyHandler) Delete(c *gin.Context) {
 	userID, _ := c.Get("user_id")
@@ -67,7 +69,7 @@ func (h *APIKeyHandler) Delete(c *gin.Context) {
 	util.SendSuccessWithMessage(c, nil, "API key deleted successfully")
 }
 
-// Validate validates the API key with Indodax
+// Validate validates the default API key with Indodax
 // POST /api/v1/api-keys/validate
 func (h *APIKeyHandler) Validate(c *gin.Context) {
 	userID, _ := c.Get("user_id")
@@ -81,12 +83,12 @@ func (h *APIKeyHandler) Validate(c *gin.Context) {
 	util.SendSuccessWithMessage(c, apiKey, "API key validated successfully")
 }
 
-// GetAccountInfo gets account information from Indodax
+// GetAccountInfo gets account information of the default API key from Indodax
 // GET /api/v1/api-keys/account-info
 func (h *APIKeyHandler) GetAccountInfo(c *gin.Context) {
 	userID, _ := c.Get("user_id")
 	
-	info, err := h.apiKeyService.GetAccountInfo(c.Request.Context(), userID.(string))
+	info, err := h.apiKeyService.GetAccountInfo(c.Request.Context(), userID.(string), nil)
 	if err != nil {
 		util.SendError(c, err)
 		return
@@ -95,5 +97,115 @@ func (h *APIKeyHandler) GetAccountInfo(c *gin.Context) {
 	util.SendSuccess(c, info)
 }
 
+// List lists all API keys of the user
+// GET /api/v1/api-keys/list
+func (h *APIKeyHandler) List(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	apiKeys, err := h.apiKeyService.List(c.Request.Context(), userID.(string))
+	if err != nil {
+		util.SendError(c, err)
+		return
+	}
+
+	util.SendSuccess(c, apiKeys)
+}
+
+// GetByID gets the status of an API key
+// GET /api/v1/api-keys/:id
+func (h *APIKeyHandler) GetByID(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	keyID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		util.SendError(c, util.ErrBadRequest("Invalid API key ID"))
+		return
+	}
+
+	apiKey, err := h.apiKeyService.GetByID(c.Request.Context(), userID.(string), keyID)
+	if err != nil {
+		util.SendError(c, err)
+		return
+	}
+
+	util.SendSuccess(c, apiKey)
+}
+
+// DeleteByID deletes an API key
+// DELETE /api/v1/api-keys/:id
+func (h *APIKeyHandler) DeleteByID(c *gin.Context) {
+	userID, _ := c.Get("user_id")
 
+	keyID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		util.SendError(c, util.ErrBadRequest("Invalid API key ID"))
+		return
+	}
 
+	if err := h.apiKeyService.DeleteByID(c.Request.Context(), userID.(string), keyID); err != nil {
+		util.SendError(c, err)
+		return
+	}
+
+	util.SendSuccessWithMessage(c, nil, "API key deleted successfully")
+}
+
+// ValidateByID validates an API key with Indodax
+// POST /api/v1/api-keys/:id/validate
+func (h *APIKeyHandler) ValidateByID(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	keyID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		util.SendError(c, util.ErrBadRequest("Invalid API key ID"))
+		return
+	}
+
+	apiKey, err := h.apiKeyService.ValidateByID(c.Request.Context(), userID.(string), keyID)
+	if err != nil {
+		util.SendError(c, err)
+		return
+	}
+
+	util.SendSuccessWithMessage(c, apiKey, "API key validated successfully")
+}
+
+// GetAccountInfoByID gets account information of an API key from Indodax
+// GET /api/v1/api-keys/:id/account-info
+func (h *APIKeyHandler) GetAccountInfoByID(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	keyID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		util.SendError(c, util.ErrBadRequest("Invalid API key ID"))
+		return
+	}
+
+	info, err := h.apiKeyService.GetAccountInfo(c.Request.Context(), userID.(string), &keyID)
+	if err != nil {
+		util.SendError(c, err)
+		return
+	}
+
+	util.SendSuccess(c, info)
+}
+
+// SetDefault makes an API key the default key of the user
+// POST /api/v1/api-keys/:id/default
+func (h *APIKeyHandler) SetDefault(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+
+	keyID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		util.SendError(c, util.ErrBadRequest("Invalid API key ID"))
+		return
+	}
+
+	apiKey, err := h.apiKeyService.SetDefault(c.Request.Context(), userID.(string), keyID)
+	if err != nil {
+		util.SendError(c, err)
+		return
+	}
+
+	util.SendSuccessWithMessage(c, apiKey, "Default API key updated successfully")
+}
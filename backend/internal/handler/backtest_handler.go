@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"strconv"
+
+	"tuyul/backend/internal/model"
+	"tuyul/backend/internal/service"
+	"tuyul/backend/internal/util"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BacktestHandler handles strategy backtest endpoints
+type BacktestHandler struct {
+	backtestService *service.BacktestService
+}
+
+// NewBacktestHandler creates a new backtest handler
+func NewBacktestHandler(backtestService *service.BacktestService) *BacktestHandler {
+	return &BacktestHandler{
+		backtestService: backtestService,
+	}
+}
+
+// ListBacktests returns the backtests of the user, without trades and equity curves
+// GET /api/v1/backtests
+func (h *BacktestHandler) ListBacktests(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		util.SendError(c, util.ErrUnauthorized("User not authenticated"))
+		return
+	}
+
+	backtests, err := h.backtestService.ListBacktests(c.Request.Context(), userID.(string))
+	if err != nil {
+		util.SendError(c, err)
+		return
+	}
+
+	util.SendSuccess(c, backtests)
+}
+
+// CreateBacktest queues a backtest
+// POST /api/v1/backtests
+func (h *BacktestHandler) CreateBacktest(c *gin.Context) {
+	var req model.BacktestRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.SendValidationError(c, err.Error())
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		util.SendError(c, util.ErrUnauthorized("User not authenticated"))
+		return
+	}
+
+	backtest, err := h.backtestService.CreateBacktest(c.Request.Context(), userID.(string), &req)
+	if err != nil {
+		util.SendError(c, err)
+		return
+	}
+
+	util.SendCreated(c, backtest, "Backtest queued")
+}
+
+// GetBacktest returns a backtest with its result
+// GET /api/v1/backtests/:id
+func (h *BacktestHandler) GetBacktest(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		util.SendError(c, util.ErrUnauthorized("User not authenticated"))
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		util.SendError(c, util.ErrBadRequest("Invalid backtest ID"))
+		return
+	}
+
+	backtest, err := h.backtestService.GetBacktest(c.Request.Context(), userID.(string), id)
+	if err != nil {
+		util.SendError(c, err)
+		return
+	}
+
+	util.SendSuccess(c, backtest)
+}
+
+// DeleteBacktest cancels and removes a backtest
+// DELETE /api/v1/backtests/:id
+func (h *BacktestHandler) DeleteBacktest(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		util.SendError(c, util.ErrUnauthorized("User not authenticated"))
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		util.SendError(c, util.ErrBadRequest("Invalid backtest ID"))
+		return
+	}
+
+	if err := h.backtestService.DeleteBacktest(c.Request.Context(), userID.(string), id); err != nil {
+		util.SendError(c, err)
+		return
+	}
+
+	util.SendSuccessWithMessage(c, nil, "Backtest deleted successfully")
+}
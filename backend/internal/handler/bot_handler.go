@@ -18,18 +18,22 @@ import (
 )
 
 type BotHandler struct {
-	botRepo   *repository.BotRepository
-	orderRepo *repository.OrderRepository
-	mmService *service.MarketMakerService
-	phService *service.PumpHunterService
+	botRepo     *repository.BotRepository
+	orderRepo   *repository.OrderRepository
+	mmService   *service.MarketMakerService
+	phService   *service.PumpHunterService
+	gridService *service.GridService
+	dcaService  *service.DCAService
 }
 
-func NewBotHandler(botRepo *repository.BotRepository, orderRepo *repository.OrderRepository, mmService *service.MarketMakerService, phService *service.PumpHunterService) *BotHandler {
+func NewBotHandler(botRepo *repository.BotRepository, orderRepo *repository.OrderRepository, mmService *service.MarketMakerService, phService *service.PumpHunterService, gridService *service.GridService, dcaService *service.DCAService) *BotHandler {
 	return &BotHandler{
-		botRepo:   botRepo,
-		orderRepo: orderRepo,
-		mmService: mmService,
-		phService: phService,
+		botRepo:     botRepo,
+		orderRepo:   orderRepo,
+		mmService:   mmService,
+		phService:   phService,
+		gridService: gridService,
+		dcaService:  dcaService,
 	}
 }
 
@@ -55,6 +59,10 @@ func (h *BotHandler) CreateBot(c *gin.Context) {
 		bot, err = h.mmService.CreateBot(c.Request.Context(), userID.(string), &req)
 	case model.BotTypePumpHunter:
 		bot, err = h.phService.CreateBot(c.Request.Context(), userID.(string), &req)
+	case model.BotTypeGrid:
+		bot, err = h.gridService.CreateBot(c.Request.Context(), userID.(string), &req)
+	case model.BotTypeDCA:
+		bot, err = h.dcaService.CreateBot(c.Request.Context(), userID.(string), &req)
 	default:
 		err = util.ErrBadRequest("Unsupported bot type")
 	}
@@ -96,6 +104,10 @@ func (h *BotHandler) UpdateBot(c *gin.Context) {
 		bot, updateErr = h.mmService.UpdateBot(c.Request.Context(), userID.(string), id, &req)
 	case model.BotTypePumpHunter:
 		bot, updateErr = h.phService.UpdateBot(c.Request.Context(), userID.(string), id, &req)
+	case model.BotTypeGrid:
+		bot, updateErr = h.gridService.UpdateBot(c.Request.Context(), userID.(string), id, &req)
+	case model.BotTypeDCA:
+		bot, updateErr = h.dcaService.UpdateBot(c.Request.Context(), userID.(string), id, &req)
 	default:
 		updateErr = util.ErrBadRequest("Unsupported bot type")
 	}
@@ -203,7 +215,8 @@ func (h *BotHandler) GetBotSummary(c *gin.Context) {
 
 	// Get current market prices (bid/ask) and spread
 	// For Market Maker bots: try to get from running instance first, then fallback to market data
-	if bot.Type == model.BotTypeMarketMaker && bot.Pair != "" {
+	// (Grid and DCA bots use market data only)
+	if (bot.Type == model.BotTypeMarketMaker || bot.Type == model.BotTypeGrid || bot.Type == model.BotTypeDCA) && bot.Pair != "" {
 		var buyPrice, sellPrice float64
 
 		// Try to get from running bot instance (most up-to-date)
@@ -265,6 +278,10 @@ func (h *BotHandler) DeleteBot(c *gin.Context) {
 		deleteErr = h.mmService.DeleteBot(c.Request.Context(), userID.(string), id)
 	case model.BotTypePumpHunter:
 		deleteErr = h.phService.DeleteBot(c.Request.Context(), userID.(string), id)
+	case model.BotTypeGrid:
+		deleteErr = h.gridService.DeleteBot(c.Request.Context(), userID.(string), id)
+	case model.BotTypeDCA:
+		deleteErr = h.dcaService.DeleteBot(c.Request.Context(), userID.(string), id)
 	default:
 		deleteErr = h.botRepo.Delete(c.Request.Context(), id)
 	}
@@ -307,6 +324,10 @@ func (h *BotHandler) StartBot(c *gin.Context) {
 		startErr = h.mmService.StartBot(ctx, userID.(string), id)
 	case model.BotTypePumpHunter:
 		startErr = h.phService.StartBot(ctx, userID.(string), id)
+	case model.BotTypeGrid:
+		startErr = h.gridService.StartBot(ctx, userID.(string), id)
+	case model.BotTypeDCA:
+		startErr = h.dcaService.StartBot(ctx, userID.(string), id)
 	default:
 		startErr = util.ErrBadRequest("Unsupported bot type")
 	}
@@ -355,6 +376,10 @@ func (h *BotHandler) StopBot(c *gin.Context) {
 		stopErr = h.mmService.StopBot(ctx, userID.(string), id)
 	case model.BotTypePumpHunter:
 		stopErr = h.phService.StopBot(ctx, userID.(string), id)
+	case model.BotTypeGrid:
+		stopErr = h.gridService.StopBot(ctx, userID.(string), id)
+	case model.BotTypeDCA:
+		stopErr = h.dcaService.StopBot(ctx, userID.(string), id)
 	default:
 		stopErr = util.ErrBadRequest("Unsupported bot type")
 	}
@@ -429,11 +454,11 @@ func (h *BotHandler) ListOrders(c *gin.Context) {
 		return
 	}
 
-	// For Market Maker bots: get orders with ParentType="bot"
+	// For Market Maker, Grid and DCA bots: get orders with ParentType="bot"
 	// For Pump Hunter bots: get orders with ParentType="position" for all positions
 	var orders []*model.Order
 
-	if bot.Type == model.BotTypeMarketMaker {
+	if bot.Type == model.BotTypeMarketMaker || bot.Type == model.BotTypeGrid || bot.Type == model.BotTypeDCA {
 		// Direct bot orders - fetch 50 most recent (to ensure we see partial/filled orders even with many cancelled)
 		orders, err = h.orderRepo.ListByParentAndUser(c.Request.Context(), userID.(string), "bot", id, 50)
 	} else if bot.Type == model.BotTypePumpHunter {
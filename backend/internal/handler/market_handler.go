@@ -3,6 +3,7 @@ package handler
 import (
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"tuyul/backend/internal/service/market"
@@ -57,7 +58,13 @@ func (h *MarketHandler) GetPumpScores(c *gin.Context) {
 	minPumpScoreStr := c.DefaultQuery("min_pump_score", "0")
 	minPumpScore, _ := strconv.ParseFloat(minPumpScoreStr, 64)
 
-	coins, err := h.marketService.GetSortedCoins(c.Request.Context(), redis.PumpScoreRankKey(), limit, minVol, minPumpScore)
+	profile := c.Query("profile")
+	if profile != "" && !h.marketService.HasScoreProfile(profile) {
+		util.SendCustomError(c, http.StatusNotFound, util.ErrCodeNotFound, "Pump score profile not found")
+		return
+	}
+
+	coins, err := h.marketService.GetProfileRanking(c.Request.Context(), profile, limit, minVol, minPumpScore)
 	if err != nil {
 		util.SendError(c, err)
 		return
@@ -102,6 +109,115 @@ func (h *MarketHandler) GetPairDetail(c *gin.Context) {
 	util.SendSuccess(c, coin)
 }
 
+// GetRecentTrades returns the latest public trades of a pair (newest first)
+func (h *MarketHandler) GetRecentTrades(c *gin.Context) {
+	pairID := c.Param("pair")
+	if _, ok := h.marketService.GetPairInfo(pairID); !ok {
+		util.SendCustomError(c, http.StatusNotFound, util.ErrCodeNotFound, "Pair not found")
+		return
+	}
+
+	limitStr := c.DefaultQuery("limit", "50")
+	limit, _ := strconv.Atoi(limitStr)
+
+	trades, err := h.marketService.GetRecentTrades(c.Request.Context(), pairID, limit)
+	if err != nil {
+		util.SendError(c, util.ErrInternalServer("Failed to get trades: "+err.Error()))
+		return
+	}
+
+	util.SendSuccess(c, gin.H{
+		"pair":   pairID,
+		"trades": trades,
+		"count":  len(trades),
+	})
+}
+
+// GetCandles returns the closed OHLCV candles of a pair (oldest first).
+// from and to accept unix seconds or RFC3339 and filter on the candle open time.
+func (h *MarketHandler) GetCandles(c *gin.Context) {
+	pairID := c.Param("pair")
+	if _, ok := h.marketService.GetPairInfo(pairID); !ok {
+		util.SendCustomError(c, http.StatusNotFound, util.ErrCodeNotFound, "Pair not found")
+		return
+	}
+
+	timeframes := h.marketService.Timeframes()
+	tf := c.Query("tf")
+	if tf == "" && len(timeframes) > 0 {
+		tf = timeframes[0].Name
+	}
+	if !h.marketService.HasTimeframe(tf) {
+		names := make([]string, 0, len(timeframes))
+		for _, spec := range timeframes {
+			names = append(names, spec.Name)
+		}
+		util.SendError(c, util.ErrBadRequest("Invalid timeframe, expected one of: "+strings.Join(names, ", ")))
+		return
+	}
+
+	from, err := parseTimeQuery(c.Query("from"))
+	if err != nil {
+		util.SendError(c, util.ErrBadRequest("Invalid from: "+err.Error()))
+		return
+	}
+	to, err := parseTimeQuery(c.Query("to"))
+	if err != nil {
+		util.SendError(c, util.ErrBadRequest("Invalid to: "+err.Error()))
+		return
+	}
+
+	limitStr := c.DefaultQuery("limit", "500")
+	limit, _ := strconv.Atoi(limitStr)
+	if limit <= 0 || limit > 5000 {
+		limit = 5000
+	}
+
+	candles, err := h.marketService.GetCandles(c.Request.Context(), pairID, tf, from, to, limit)
+	if err != nil {
+		util.SendError(c, util.ErrInternalServer("Failed to get candles: "+err.Error()))
+		return
+	}
+
+	util.SendSuccess(c, gin.H{
+		"pair":      pairID,
+		"timeframe": tf,
+		"candles":   candles,
+		"count":     len(candles),
+	})
+}
+
+// GetDepth returns the order book depth metrics of a pair
+func (h *MarketHandler) GetDepth(c *gin.Context) {
+	pairID := c.Param("pair")
+	if _, ok := h.marketService.GetPairInfo(pairID); !ok {
+		util.SendCustomError(c, http.StatusNotFound, util.ErrCodeNotFound, "Pair not found")
+		return
+	}
+
+	depth, ok := h.marketService.GetDepthMetrics(pairID)
+	if !ok {
+		util.SendCustomError(c, http.StatusNotFound, util.ErrCodeNotFound, "Depth metrics not available yet")
+		return
+	}
+
+	util.SendSuccess(c, gin.H{
+		"pair":  pairID,
+		"depth": depth,
+	})
+}
+
+// parseTimeQuery parses a unix seconds or RFC3339 query value, empty means unset
+func parseTimeQuery(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if sec, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(sec, 0), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
 // SyncMetadata manually triggers a metadata refresh from Indodax
 func (h *MarketHandler) SyncMetadata(c *gin.Context) {
 	if err := h.marketService.RefreshMetadata(); err != nil {
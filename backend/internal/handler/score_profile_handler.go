@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"tuyul/backend/internal/model"
+	"tuyul/backend/internal/service/market"
+	"tuyul/backend/internal/util"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ScoreProfileHandler handles pump score profile endpoints
+type ScoreProfileHandler struct {
+	profileService *market.ScoreProfileService
+}
+
+// NewScoreProfileHandler creates a new score profile handler
+func NewScoreProfileHandler(profileService *market.ScoreProfileService) *ScoreProfileHandler {
+	return &ScoreProfileHandler{
+		profileService: profileService,
+	}
+}
+
+// List returns the active pump score profiles
+// GET /api/v1/market/score-profiles
+func (h *ScoreProfileHandler) List(c *gin.Context) {
+	profiles := h.profileService.List()
+	util.SendSuccess(c, gin.H{
+		"profiles": profiles,
+		"count":    len(profiles),
+	})
+}
+
+// Create creates or replaces a profile (admin only)
+// POST /api/v1/market/score-profiles
+func (h *ScoreProfileHandler) Create(c *gin.Context) {
+	var req model.PumpScoreProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.SendValidationError(c, err.Error())
+		return
+	}
+
+	profile, err := h.profileService.Save(c.Request.Context(), &req)
+	if err != nil {
+		util.SendError(c, err)
+		return
+	}
+
+	util.SendCreated(c, profile, "Pump score profile saved successfully")
+}
+
+// Update replaces the profile named in the path (admin only)
+// PUT /api/v1/market/score-profiles/:name
+func (h *ScoreProfileHandler) Update(c *gin.Context) {
+	var req model.PumpScoreProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.SendValidationError(c, err.Error())
+		return
+	}
+	req.Name = c.Param("name")
+
+	profile, err := h.profileService.Save(c.Request.Context(), &req)
+	if err != nil {
+		util.SendError(c, err)
+		return
+	}
+
+	util.SendSuccessWithMessage(c, profile, "Pump score profile updated successfully")
+}
+
+// Delete removes a profile (admin only)
+// DELETE /api/v1/market/score-profiles/:name
+func (h *ScoreProfileHandler) Delete(c *gin.Context) {
+	if err := h.profileService.Delete(c.Request.Context(), c.Param("name")); err != nil {
+		util.SendError(c, err)
+		return
+	}
+
+	util.SendSuccessWithMessage(c, nil, "Pump score profile deleted successfully")
+}
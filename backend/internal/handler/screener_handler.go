@@ -0,0 +1,169 @@
+package handler
+
+import (
+	"strconv"
+
+	"tuyul/backend/internal/model"
+	"tuyul/backend/internal/service"
+	"tuyul/backend/internal/util"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Maximum coins returned by an ad-hoc screen
+const maxScreenLimit = 500
+
+// ScreenerHandler handles market screener endpoints
+type ScreenerHandler struct {
+	screenerService *service.ScreenerService
+}
+
+// NewScreenerHandler creates a new screener handler
+func NewScreenerHandler(screenerService *service.ScreenerService) *ScreenerHandler {
+	return &ScreenerHandler{
+		screenerService: screenerService,
+	}
+}
+
+// Screen runs a filter expression over the market
+// GET /api/v1/market/screener?filter=&sort=&limit=
+func (h *ScreenerHandler) Screen(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if limit <= 0 || limit > maxScreenLimit {
+		limit = maxScreenLimit
+	}
+
+	coins, err := h.screenerService.Run(c.Query("filter"), c.Query("sort"), limit)
+	if err != nil {
+		util.SendError(c, err)
+		return
+	}
+
+	util.SendSuccess(c, gin.H{
+		"coins": coins,
+		"count": len(coins),
+	})
+}
+
+// ListScreens returns the saved screens of the user
+// GET /api/v1/market/screens
+func (h *ScreenerHandler) ListScreens(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		util.SendError(c, util.ErrUnauthorized("User not authenticated"))
+		return
+	}
+
+	screens, err := h.screenerService.ListScreens(c.Request.Context(), userID.(string))
+	if err != nil {
+		util.SendError(c, err)
+		return
+	}
+
+	util.SendSuccess(c, screens)
+}
+
+// CreateScreen saves a screen
+// POST /api/v1/market/screens
+func (h *ScreenerHandler) CreateScreen(c *gin.Context) {
+	var req model.ScreenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.SendValidationError(c, err.Error())
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		util.SendError(c, util.ErrUnauthorized("User not authenticated"))
+		return
+	}
+
+	screen, err := h.screenerService.CreateScreen(c.Request.Context(), userID.(string), &req)
+	if err != nil {
+		util.SendError(c, err)
+		return
+	}
+
+	util.SendCreated(c, screen, "Screen saved successfully")
+}
+
+// UpdateScreen replaces a saved screen
+// PUT /api/v1/market/screens/:id
+func (h *ScreenerHandler) UpdateScreen(c *gin.Context) {
+	var req model.ScreenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		util.SendValidationError(c, err.Error())
+		return
+	}
+
+	userID, exists := c.Get("user_id")
+	if !exists {
+		util.SendError(c, util.ErrUnauthorized("User not authenticated"))
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		util.SendError(c, util.ErrBadRequest("Invalid screen ID"))
+		return
+	}
+
+	screen, err := h.screenerService.UpdateScreen(c.Request.Context(), userID.(string), id, &req)
+	if err != nil {
+		util.SendError(c, err)
+		return
+	}
+
+	util.SendSuccess(c, screen)
+}
+
+// DeleteScreen removes a saved screen
+// DELETE /api/v1/market/screens/:id
+func (h *ScreenerHandler) DeleteScreen(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		util.SendError(c, util.ErrUnauthorized("User not authenticated"))
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		util.SendError(c, util.ErrBadRequest("Invalid screen ID"))
+		return
+	}
+
+	if err := h.screenerService.DeleteScreen(c.Request.Context(), userID.(string), id); err != nil {
+		util.SendError(c, err)
+		return
+	}
+
+	util.SendSuccessWithMessage(c, nil, "Screen deleted successfully")
+}
+
+// RunScreen returns the current matches of a saved screen
+// GET /api/v1/market/screens/:id/results
+func (h *ScreenerHandler) RunScreen(c *gin.Context) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		util.SendError(c, util.ErrUnauthorized("User not authenticated"))
+		return
+	}
+
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		util.SendError(c, util.ErrBadRequest("Invalid screen ID"))
+		return
+	}
+
+	screen, coins, err := h.screenerService.RunScreen(c.Request.Context(), userID.(string), id)
+	if err != nil {
+		util.SendError(c, err)
+		return
+	}
+
+	util.SendSuccess(c, gin.H{
+		"screen": screen,
+		"coins":  coins,
+		"count":  len(coins),
+	})
+}
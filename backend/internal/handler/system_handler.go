@@ -0,0 +1,31 @@
+package handler
+
+import (
+	"time"
+
+	"tuyul/backend/internal/util"
+	"tuyul/backend/pkg/indodax"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SystemHandler handles admin system endpoints
+type SystemHandler struct {
+	indodaxClient *indodax.Client
+}
+
+// NewSystemHandler creates a new system handler
+func NewSystemHandler(indodaxClient *indodax.Client) *SystemHandler {
+	return &SystemHandler{
+		indodaxClient: indodaxClient,
+	}
+}
+
+// GetExchangeMetrics returns the private API retry counters (rate limit / nonce rejections)
+// GET /api/v1/system/exchange-metrics
+func (h *SystemHandler) GetExchangeMetrics(c *gin.Context) {
+	util.SendSuccess(c, gin.H{
+		"indodax": h.indodaxClient.Metrics(),
+		"time":    time.Now().Unix(),
+	})
+}
@@ -0,0 +1,52 @@
+package model
+
+import "time"
+
+// Alert types
+const (
+	AlertTypePriceAbove  = "price_above"  // Price crosses above Threshold
+	AlertTypePriceBelow  = "price_below"  // Price crosses below Threshold
+	AlertTypeMove        = "move"         // Change % over the Timeframe window reaches Threshold (negative for drops)
+	AlertTypePumpScore   = "pump_score"   // Pump score at or above Threshold
+	AlertTypeGap         = "gap"          // Bid-ask gap % at or above Threshold
+	AlertTypeVolumeSpike = "volume_spike" // IDR traded over the Timeframe window is Threshold times the 24h pace
+)
+
+// Alert modes
+const (
+	AlertModeOnce   = "once"   // Deactivated after the first trigger
+	AlertModeRepeat = "repeat" // Re-armed once the condition clears and the cooldown passed
+)
+
+// Alert is a user's price or signal alert.
+// Price alerts need a pair, the others watch every pair when Pair is empty.
+type Alert struct {
+	ID        int64   `json:"id"`
+	UserID    string  `json:"user_id"`
+	Pair      string  `json:"pair,omitempty"`
+	Type      string  `json:"type"`
+	Threshold float64 `json:"threshold"`
+	Timeframe string  `json:"timeframe,omitempty"` // Window of move and volume_spike alerts
+	Mode      string  `json:"mode"`
+	Cooldown  int     `json:"cooldown_seconds"` // Minimum delay between two triggers of a repeating alert
+	Note      string  `json:"note,omitempty"`
+	Active    bool    `json:"active"`
+
+	TriggerCount    int        `json:"trigger_count"`
+	LastTriggeredAt *time.Time `json:"last_triggered_at,omitempty"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// AlertRequest creates or updates an alert
+type AlertRequest struct {
+	Pair      string  `json:"pair"`
+	Type      string  `json:"type" binding:"required,oneof=price_above price_below move pump_score gap volume_spike"`
+	Threshold float64 `json:"threshold"`
+	Timeframe string  `json:"timeframe"`
+	Mode      string  `json:"mode" binding:"omitempty,oneof=once repeat"`
+	Cooldown  int     `json:"cooldown_seconds" binding:"gte=0"`
+	Note      string  `json:"note" binding:"max=200"`
+	Active    *bool   `json:"active"` // Defaults to true
+}
@@ -2,17 +2,55 @@ package model
 
 import "time"
 
-// APIKey represents an Indodax API key
+// APIKey represents an Indodax API key. A user can register several keys
+// (e.g. one per sub-account), bots and trades are bound to one of them.
 type APIKey struct {
+	ID              int64      `json:"id"`
 	UserID          string     `json:"user_id"`
+	Label           string     `json:"label"`
+	IsDefault       bool       `json:"is_default"`       // Used when no key is chosen explicitly
 	EncryptedKey    string     `json:"encrypted_key"`    // Stored in Redis, never exposed in API responses
 	EncryptedSecret string     `json:"encrypted_secret"` // Stored in Redis, never exposed in API responses
 	IsValid         bool       `json:"is_valid"`
+	Permissions     []string   `json:"permissions,omitempty"` // Scopes detected on the exchange, empty if never checked
 	LastValidatedAt *time.Time `json:"last_validated_at,omitempty"`
 	CreatedAt       time.Time  `json:"created_at"`
 	UpdatedAt       time.Time  `json:"updated_at"`
 }
 
+// API key permission scopes
+const (
+	APIKeyPermissionView     = "view"
+	APIKeyPermissionTrade    = "trade"
+	APIKeyPermissionWithdraw = "withdraw"
+)
+
+// HasPermission reports whether the key was detected with a scope
+func (k *APIKey) HasPermission(scope string) bool {
+	for _, p := range k.Permissions {
+		if p == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// Warnings describes what the detected permissions mean for trading
+func (k *APIKey) Warnings() []string {
+	if len(k.Permissions) == 0 {
+		return []string{"Permissions have not been checked yet, validate the key to detect them"}
+	}
+
+	var warnings []string
+	if k.HasPermission(APIKeyPermissionWithdraw) {
+		warnings = append(warnings, "Key has withdraw permission and is disabled, replace it with a key without withdraw permission")
+	}
+	if !k.HasPermission(APIKeyPermissionTrade) {
+		warnings = append(warnings, "Key has no trade permission, live bots and trades using it will fail")
+	}
+	return warnings
+}
+
 // APIKeyRequest represents API key creation/update request
 type APIKeyRequest struct {
 	Key    string `json:"api_key" binding:"required"`
@@ -22,8 +60,13 @@ type APIKeyRequest struct {
 
 // APIKeyResponse represents API key response (without secret)
 type APIKeyResponse struct {
+	ID              int64      `json:"id"`
 	UserID          string     `json:"user_id"`
+	Label           string     `json:"label"`
+	IsDefault       bool       `json:"is_default"`
 	IsValid         bool       `json:"is_valid"`
+	Permissions     []string   `json:"permissions"`
+	Warnings        []string   `json:"warnings,omitempty"`
 	LastValidatedAt *time.Time `json:"last_validated_at,omitempty"`
 	CreatedAt       time.Time  `json:"created_at"`
 	UpdatedAt       time.Time  `json:"updated_at"`
@@ -32,19 +75,32 @@ type APIKeyResponse struct {
 // ToResponse converts APIKey to APIKeyResponse
 func (k *APIKey) ToResponse() *APIKeyResponse {
 	return &APIKeyResponse{
+		ID:              k.ID,
 		UserID:          k.UserID,
+		Label:           k.Label,
+		IsDefault:       k.IsDefault,
 		IsValid:         k.IsValid,
+		Permissions:     k.Permissions,
+		Warnings:        k.Warnings(),
 		LastValidatedAt: k.LastValidatedAt,
 		CreatedAt:       k.CreatedAt,
 		UpdatedAt:       k.UpdatedAt,
 	}
 }
 
+// AccountInfoResponse represents exchange account info with non-zero balances only
+type AccountInfoResponse struct {
+	ServerTime  int64             `json:"server_time"`
+	Balance     map[string]string `json:"balance"`
+	BalanceHold map[string]string `json:"balance_hold"`
+	UserID      string            `json:"user_id"`
+	Name        string            `json:"name"`
+	Email       string            `json:"email"`
+}
+
 // DecryptedAPIKey holds decrypted API credentials (in-memory only)
 type DecryptedAPIKey struct {
+	ID     int64
 	Key    string
 	Secret string
 }
-
-
-
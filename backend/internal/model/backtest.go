@@ -0,0 +1,205 @@
+package model
+
+import "time"
+
+// Backtest status constants
+const (
+	BacktestStatusQueued    = "queued"
+	BacktestStatusRunning   = "running"
+	BacktestStatusCompleted = "completed"
+	BacktestStatusFailed    = "failed"
+)
+
+// Backtest defaults and limits
+const (
+	DefaultBacktestTakerFeePercent = 0.3 // Market fills
+	DefaultBacktestMakerFeePercent = 0.2 // Resting limit fills (take profit)
+	MaxBacktestPairs               = 50
+	MaxBacktestConfigs             = 20 // Market Maker configurations compared in one run
+)
+
+// Backtest is an asynchronous strategy simulation of a user
+type Backtest struct {
+	ID     int64  `json:"id"`
+	UserID string `json:"user_id"`
+	Name   string `json:"name"`
+	Type   string `json:"type"` // Bot type simulated, pump_hunter
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+
+	Request *BacktestRequest `json:"request"`
+	Result  *BacktestResult  `json:"result,omitempty"` // Set once completed
+
+	CreatedAt   time.Time  `json:"created_at"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// BacktestRequest describes a Pump Hunter backtest
+type BacktestRequest struct {
+	Name string `json:"name" binding:"max=64"`
+
+	// Pairs simulated, the entry rules' allowed pairs when empty
+	Pairs []string  `json:"pairs"`
+	From  time.Time `json:"from" binding:"required"`
+	To    time.Time `json:"to" binding:"required"`
+
+	InitialBalanceIDR float64 `json:"initial_balance_idr" binding:"required,gt=0"`
+
+	// Fees in percent of the notional, defaults when unset
+	TakerFeePercent *float64 `json:"taker_fee_percent" binding:"omitempty,gte=0"`
+	MakerFeePercent *float64 `json:"maker_fee_percent" binding:"omitempty,gte=0"`
+	// Price concession of market fills in percent of the candle close
+	SlippagePercent float64 `json:"slippage_percent" binding:"gte=0"`
+
+	// Strategy, same fields as a Pump Hunter bot
+	EntryRules     *PumpHunterEntryRules     `json:"entry_rules" binding:"required"`
+	ExitRules      *PumpHunterExitRules      `json:"exit_rules" binding:"required"`
+	RiskManagement *PumpHunterRiskManagement `json:"risk_management" binding:"required"`
+}
+
+// BacktestPairs returns the pairs to simulate
+func (r *BacktestRequest) BacktestPairs() []string {
+	if len(r.Pairs) > 0 || r.EntryRules == nil {
+		return r.Pairs
+	}
+	return r.EntryRules.AllowedPairs
+}
+
+// Fees returns the taker and maker fee rates in percent
+func (r *BacktestRequest) Fees() (taker, maker float64) {
+	return backtestFees(r.TakerFeePercent, r.MakerFeePercent)
+}
+
+func backtestFees(takerPercent, makerPercent *float64) (taker, maker float64) {
+	taker, maker = DefaultBacktestTakerFeePercent, DefaultBacktestMakerFeePercent
+	if takerPercent != nil {
+		taker = *takerPercent
+	}
+	if makerPercent != nil {
+		maker = *makerPercent
+	}
+	return taker, maker
+}
+
+// BacktestResult is the outcome of a backtest
+type BacktestResult struct {
+	Timeframe string `json:"timeframe"` // Candle resolution simulated
+	Candles   int    `json:"candles"`   // Candles replayed, warm-up excluded
+
+	InitialBalanceIDR float64 `json:"initial_balance_idr"`
+	FinalBalanceIDR   float64 `json:"final_balance_idr"`
+	ProfitIDR         float64 `json:"profit_idr"`
+	ReturnPercent     float64 `json:"return_percent"`
+	FeesIDR           float64 `json:"fees_idr"`
+
+	MaxDrawdownPercent float64 `json:"max_drawdown_percent"`
+	MaxDrawdownIDR     float64 `json:"max_drawdown_idr"`
+
+	TotalTrades   int     `json:"total_trades"`
+	WinningTrades int     `json:"winning_trades"`
+	WinRate       float64 `json:"win_rate"` // Percentage
+
+	// Set when the total loss limit stopped new entries, as it stops a live bot
+	StoppedAt *time.Time `json:"stopped_at,omitempty"`
+
+	Trades []BacktestTrade `json:"trades"`
+	Equity []EquityPoint   `json:"equity"` // Downsampled to at most 1000 points
+}
+
+// BacktestTrade is a simulated round trip
+type BacktestTrade struct {
+	Pair           string    `json:"pair"`
+	EntryAt        time.Time `json:"entry_at"`
+	EntryPrice     float64   `json:"entry_price"`
+	EntryPumpScore float64   `json:"entry_pump_score"`
+	Quantity       float64   `json:"quantity"`
+	ExitAt         time.Time `json:"exit_at"`
+	ExitPrice      float64   `json:"exit_price"`
+	CloseReason    string    `json:"close_reason"`
+	FeesIDR        float64   `json:"fees_idr"`
+	ProfitIDR      float64   `json:"profit_idr"` // After fees
+	ProfitPercent  float64   `json:"profit_percent"`
+}
+
+// EquityPoint is the balance plus open positions at market value
+type EquityPoint struct {
+	Time      time.Time `json:"time"`
+	EquityIDR float64   `json:"equity_idr"`
+}
+
+// MarketMakerBacktestRequest describes a Market Maker simulation over recorded
+// order books and trade prints, comparing several configurations
+type MarketMakerBacktestRequest struct {
+	// Simulated period, the whole recording when unset
+	From *time.Time `json:"from"`
+	To   *time.Time `json:"to"`
+
+	// Fees in percent of the notional, defaults when unset
+	TakerFeePercent *float64 `json:"taker_fee_percent"`
+	MakerFeePercent *float64 `json:"maker_fee_percent"`
+
+	Configs []MarketMakerBacktestConfig `json:"configs"`
+}
+
+// Fees returns the taker and maker fee rates in percent
+func (r *MarketMakerBacktestRequest) Fees() (taker, maker float64) {
+	return backtestFees(r.TakerFeePercent, r.MakerFeePercent)
+}
+
+// MarketMakerBacktestConfig is one simulated Market Maker bot, same fields as the bot
+type MarketMakerBacktestConfig struct {
+	Name              string  `json:"name"`
+	Pair              string  `json:"pair"`
+	InitialBalanceIDR float64 `json:"initial_balance_idr"`
+	OrderSizeIDR      float64 `json:"order_size_idr"`
+	MinGapPercent     float64 `json:"min_gap_percent"`
+	MaxLossIDR        float64 `json:"max_loss_idr"`
+}
+
+// MarketMakerBacktestResult is the outcome of a Market Maker backtest, one entry per configuration
+type MarketMakerBacktestResult struct {
+	From    time.Time                 `json:"from"`
+	To      time.Time                 `json:"to"`
+	Configs []MarketMakerConfigResult `json:"configs"`
+}
+
+// MarketMakerConfigResult is the outcome of one simulated configuration
+type MarketMakerConfigResult struct {
+	Name string `json:"name"`
+	Pair string `json:"pair"`
+
+	// PnL. Equity values the coins at the venue mid price.
+	InitialBalanceIDR  float64 `json:"initial_balance_idr"`
+	FinalEquityIDR     float64 `json:"final_equity_idr"`
+	RealizedProfitIDR  float64 `json:"realized_profit_idr"` // Before fees, as the bot accounts it
+	FeesIDR            float64 `json:"fees_idr"`
+	NetProfitIDR       float64 `json:"net_profit_idr"` // Final equity minus initial balance and fees
+	ReturnPercent      float64 `json:"return_percent"`
+	MaxDrawdownPercent float64 `json:"max_drawdown_percent"`
+
+	// Order flow
+	OrdersPlaced    int     `json:"orders_placed"`
+	OrdersCancelled int     `json:"orders_cancelled"` // Repositions and spread cancels
+	Fills           int     `json:"fills"`
+	BuyVolumeIDR    float64 `json:"buy_volume_idr"`
+	SellVolumeIDR   float64 `json:"sell_volume_idr"`
+	TotalTrades     int     `json:"total_trades"` // Completed sell orders
+	WinningTrades   int     `json:"winning_trades"`
+	WinRate         float64 `json:"win_rate"` // Percentage
+
+	// Spread capture: edge of the fills against the venue mid price when they happened
+	SpreadCaptureIDR     float64 `json:"spread_capture_idr"`
+	SpreadCapturePercent float64 `json:"spread_capture_percent"` // Of the filled notional
+
+	// Inventory risk, coins held or locked in the sell order at the mid price
+	MaxInventoryIDR      float64 `json:"max_inventory_idr"`
+	AvgInventoryIDR      float64 `json:"avg_inventory_idr"`      // Time-weighted
+	InventoryTimePercent float64 `json:"inventory_time_percent"` // Share of the period holding coins
+	MaxUnrealizedLossIDR float64 `json:"max_unrealized_loss_idr"`
+	EndInventory         float64 `json:"end_inventory"` // Base currency
+	EndInventoryIDR      float64 `json:"end_inventory_idr"`
+
+	// Set when the max loss stopped the bot, as it stops a live bot
+	StoppedAt *time.Time `json:"stopped_at,omitempty"`
+}
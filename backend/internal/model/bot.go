@@ -2,6 +2,7 @@ package model
 
 import (
 	"encoding/json"
+	"math"
 	"time"
 )
 
@@ -17,6 +18,8 @@ const (
 const (
 	BotTypeMarketMaker = "market_maker"
 	BotTypePumpHunter  = "pump_hunter"
+	BotTypeGrid        = "grid"
+	BotTypeDCA         = "dca"
 )
 
 // BotConfig represents a trading bot configuration
@@ -24,12 +27,12 @@ type BotConfig struct {
 	ID     int64  `json:"id"`
 	UserID string `json:"user_id"`
 	Name   string `json:"name"`
-	Type   string `json:"type"` // market_maker, pump_hunter
+	Type   string `json:"type"` // market_maker, pump_hunter, grid, dca
 	Pair   string `json:"pair"`
 
 	// Trading mode
 	IsPaperTrading bool   `json:"is_paper_trading"`
-	APIKeyID       *int64 `json:"api_key_id,omitempty"` // null for paper trading
+	APIKeyID       *int64 `json:"api_key_id,omitempty"` // null for paper trading, user's default key if unset
 
 	// Market Maker parameters
 	InitialBalanceIDR          float64 `json:"initial_balance_idr"`
@@ -46,6 +49,19 @@ type BotConfig struct {
 	ExitRules      *PumpHunterExitRules      `json:"exit_rules,omitempty"`
 	RiskManagement *PumpHunterRiskManagement `json:"risk_management,omitempty"`
 
+	// Grid parameters (OrderSizeIDR is the value of each grid's buy order)
+	Grid *GridConfig `json:"grid,omitempty"`
+	// Grid state, one level per interval from the lowest up (built on first start)
+	GridLevels []*GridLevel `json:"grid_levels,omitempty"`
+
+	// DCA parameters (OrderSizeIDR is the value of each scheduled or dip buy)
+	DCA *DCAConfig `json:"dca,omitempty"`
+	// DCA state, the position itself is tracked by TotalCoinBought and TotalCostIDR
+	DCAState *DCAState `json:"dca_state,omitempty"`
+
+	// Deadman switch (live trading only, nil = enabled with defaults)
+	Deadman *DeadmanConfig `json:"deadman,omitempty"`
+
 	// Statistics
 	TotalTrades    int     `json:"total_trades"`
 	WinningTrades  int     `json:"winning_trades"`
@@ -68,8 +84,8 @@ type BotConfig struct {
 // BotConfigRequest represents the request to create/update a bot
 type BotConfigRequest struct {
 	Name           string `json:"name" binding:"required"`
-	Type           string `json:"type" binding:"required,oneof=market_maker pump_hunter"`
-	Pair           string `json:"pair" binding:"required_if=Type market_maker"` // Only required for market_maker
+	Type           string `json:"type" binding:"required,oneof=market_maker pump_hunter grid dca"`
+	Pair           string `json:"pair" binding:"required_if=Type market_maker,required_if=Type grid,required_if=Type dca"` // Not used by pump_hunter
 	IsPaperTrading bool   `json:"is_paper_trading"`
 	APIKeyID       *int64 `json:"api_key_id"`
 
@@ -84,6 +100,15 @@ type BotConfigRequest struct {
 	EntryRules     *PumpHunterEntryRules     `json:"entry_rules"`
 	ExitRules      *PumpHunterExitRules      `json:"exit_rules"`
 	RiskManagement *PumpHunterRiskManagement `json:"risk_management"`
+
+	// Grid parameters
+	Grid *GridConfig `json:"grid"`
+
+	// DCA parameters
+	DCA *DCAConfig `json:"dca"`
+
+	// Deadman switch
+	Deadman *DeadmanConfig `json:"deadman"`
 }
 
 type PumpHunterEntryRules struct {
@@ -93,6 +118,30 @@ type PumpHunterEntryRules struct {
 	MinPriceIDR           float64  `json:"min_price_idr"`
 	ExcludedPairs         []string `json:"excluded_pairs"`
 	AllowedPairs          []string `json:"allowed_pairs"`
+
+	// Evaluate pump score and positive timeframes on sliding windows instead of
+	// clock-reset buckets (also applies to pump score exits and false pump checks)
+	UseRollingWindows bool `json:"use_rolling_windows"`
+
+	// Pump score profile the bot trades on (the default profile if empty).
+	// A profile scores buckets or windows on its own, UseRollingWindows then
+	// only applies to the positive timeframes count.
+	ScoreProfile string `json:"score_profile"`
+
+	// Indicator filters on IndicatorTimeframe (shortest timeframe if empty), zero disables.
+	// Entries are refused while an enabled filter lacks the candles to compute it.
+	IndicatorTimeframe string  `json:"indicator_timeframe"`
+	MinRSI             float64 `json:"min_rsi"`
+	MaxRSI             float64 `json:"max_rsi"`
+	RequireEMATrend    bool    `json:"require_ema_trend"`  // EMA 9 above EMA 21
+	RequireAboveVWAP   bool    `json:"require_above_vwap"` // Price above VWAP
+
+	// Order book filters on the pair's depth metrics, zero disables.
+	// Entries are refused while an enabled filter has no metrics for the pair.
+	MinDepthImbalance  float64 `json:"min_depth_imbalance"`   // -1 to 1, bids heavier when positive
+	MinBidDepthIDR     float64 `json:"min_bid_depth_idr"`     // Bids within the depth band
+	RejectAskWall      bool    `json:"reject_ask_wall"`       // Refuse pairs with an ask wall in the band
+	MaxDepthAgeSeconds int     `json:"max_depth_age_seconds"` // Refuse metrics older than this
 }
 
 type PumpHunterExitRules struct {
@@ -103,6 +152,10 @@ type PumpHunterExitRules struct {
 	MaxHoldMinutes         int     `json:"max_hold_minutes"`
 	ExitOnPumpScoreDrop    bool    `json:"exit_on_pump_score_drop"`
 	PumpScoreDropThreshold float64 `json:"pump_score_drop_threshold"`
+
+	// Indicator exits on the entry rules' IndicatorTimeframe, zero disables
+	ExitRSIAbove       float64 `json:"exit_rsi_above"`         // RSI overbought level
+	ExitOnEMACrossDown bool    `json:"exit_on_ema_cross_down"` // EMA 9 below EMA 21
 }
 
 type PumpHunterRiskManagement struct {
@@ -113,6 +166,162 @@ type PumpHunterRiskManagement struct {
 	MinBalanceIDR            float64 `json:"min_balance_idr"`
 }
 
+// Grid spacing constants
+const (
+	GridSpacingArithmetic = "arithmetic" // Equal price steps
+	GridSpacingGeometric  = "geometric"  // Equal percentage steps
+)
+
+// GridConfig is the price range a grid bot trades in
+type GridConfig struct {
+	LowerPrice float64 `json:"lower_price"`
+	UpperPrice float64 `json:"upper_price"`
+	GridCount  int     `json:"grid_count"` // Intervals between the bounds, each buys at its low and sells at its high
+	Spacing    string  `json:"spacing"`    // arithmetic (default) or geometric
+}
+
+// Prices returns the GridCount+1 prices of the ladder, from LowerPrice to UpperPrice
+func (g *GridConfig) Prices() []float64 {
+	prices := make([]float64, g.GridCount+1)
+	step := (g.UpperPrice - g.LowerPrice) / float64(g.GridCount)
+	ratio := math.Pow(g.UpperPrice/g.LowerPrice, 1/float64(g.GridCount))
+	for i := range prices {
+		if g.Spacing == GridSpacingGeometric {
+			prices[i] = g.LowerPrice * math.Pow(ratio, float64(i))
+		} else {
+			prices[i] = g.LowerPrice + step*float64(i)
+		}
+	}
+	prices[g.GridCount] = g.UpperPrice
+	return prices
+}
+
+// GridLevel is one interval of a grid bot. It buys Amount at BuyPrice, sells it at
+// SellPrice, then buys again, so a single order rests per level at any time.
+type GridLevel struct {
+	BuyPrice  float64 `json:"buy_price"`
+	SellPrice float64 `json:"sell_price"`
+	Amount    float64 `json:"amount"` // Base amount of the level's buy order
+
+	Side        string  `json:"side"`                    // Side of the next order: buy or sell
+	OrderID     string  `json:"order_id,omitempty"`      // Client order ID of the resting order, empty if none
+	OrderPrice  float64 `json:"order_price,omitempty"`   // Limit price of the resting order
+	OrderAmount float64 `json:"order_amount,omitempty"`  // Amount of the resting order
+	Filled      float64 `json:"filled,omitempty"`        // Executed amount of the resting order
+	OrderFeeIDR float64 `json:"order_fee_idr,omitempty"` // Fee charged so far for the resting order
+
+	Held        float64 `json:"held"`                   // Base amount bought and not sold yet
+	CostIDR     float64 `json:"cost_idr"`               // IDR paid for the current round trip, fees included
+	ProceedsIDR float64 `json:"proceeds_idr,omitempty"` // IDR received so far from the current round trip, net of fees
+
+	Trades    int     `json:"trades"`     // Completed buy and sell round trips
+	ProfitIDR float64 `json:"profit_idr"` // Realized profit of the completed round trips
+}
+
+// DCAConfig is the buying schedule of a DCA bot. Buys are market orders of OrderSizeIDR,
+// made every IntervalMinutes and/or whenever the price fell DipPercent below the last buy.
+type DCAConfig struct {
+	IntervalMinutes int     `json:"interval_minutes"` // 0 = no scheduled buys
+	DipPercent      float64 `json:"dip_percent"`      // 0 = no dip buys
+
+	// Safety orders buy more each time the price falls another step below the first buy of the position
+	SafetyOrderCount       int     `json:"safety_order_count"`        // 0 = no safety orders
+	SafetyOrderStepPercent float64 `json:"safety_order_step_percent"` // Price deviation between safety orders
+	SafetyOrderSizeIDR     float64 `json:"safety_order_size_idr"`     // 0 = OrderSizeIDR
+
+	// Sell the whole position with a limit order this far above its average price, 0 = accumulate only
+	TakeProfitPercent float64 `json:"take_profit_percent"`
+}
+
+// DCA order reasons
+const (
+	DCAReasonScheduled  = "scheduled"
+	DCAReasonDip        = "dip"
+	DCAReasonSafety     = "safety"
+	DCAReasonTakeProfit = "take_profit"
+)
+
+// DCAState is the runtime state of a DCA bot. At most one order is in flight:
+// a market buy or the resting take-profit sell of the position.
+type DCAState struct {
+	NextBuyAt      time.Time `json:"next_buy_at"`      // Next scheduled buy, zero = right away
+	LastFillPrice  float64   `json:"last_fill_price"`  // Average price of the last buy (or take-profit), dip reference
+	DealStartPrice float64   `json:"deal_start_price"` // Average price of the first buy of the position, safety order reference
+	SafetyOrders   int       `json:"safety_orders"`    // Safety orders bought in the position
+	DealProfitIDR  float64   `json:"deal_profit_idr"`  // Profit realized so far by the take-profit of the position
+
+	OrderID         string    `json:"order_id,omitempty"`          // Client order ID of the order in flight, empty if none
+	ExchangeOrderID string    `json:"exchange_order_id,omitempty"` // Exchange order ID, live market updates may only carry this one
+	OrderSide       string    `json:"order_side,omitempty"`
+	OrderReason     string    `json:"order_reason,omitempty"`    // scheduled, dip, safety or take_profit
+	OrderPrice      float64   `json:"order_price,omitempty"`     // Limit price of a sell, ask at placement of a buy
+	OrderAmount     float64   `json:"order_amount,omitempty"`    // IDR of a buy, coins of a sell
+	OrderPlacedAt   time.Time `json:"order_placed_at,omitempty"` // When the order was placed
+	Filled          float64   `json:"filled,omitempty"`          // Executed coins of the order
+	FilledIDR       float64   `json:"filled_idr,omitempty"`      // Executed value of the order, fees excluded
+	OrderFeeIDR     float64   `json:"order_fee_idr,omitempty"`   // Fee charged so far for the order
+}
+
+// SetOrder records a new order in flight
+func (s *DCAState) SetOrder(orderID, side, reason string, price, amount float64, placedAt time.Time) {
+	s.ClearOrder()
+	s.OrderID = orderID
+	s.OrderSide = side
+	s.OrderReason = reason
+	s.OrderPrice = price
+	s.OrderAmount = amount
+	s.OrderPlacedAt = placedAt
+}
+
+// ClearOrder forgets the order in flight
+func (s *DCAState) ClearOrder() {
+	s.OrderID = ""
+	s.ExchangeOrderID = ""
+	s.OrderSide = ""
+	s.OrderReason = ""
+	s.OrderPrice = 0
+	s.OrderAmount = 0
+	s.OrderPlacedAt = time.Time{}
+	s.Filled = 0
+	s.FilledIDR = 0
+	s.OrderFeeIDR = 0
+}
+
+// Deadman switch defaults
+const (
+	DefaultDeadmanCountdownSeconds = 120
+	MinDeadmanCountdownSeconds     = 10
+)
+
+// DeadmanConfig controls the exchange-side countdownCancelAll heartbeat for a live bot.
+// While the bot runs, the countdown is refreshed periodically. If the heartbeat stops
+// (crash, network loss), Indodax cancels all open orders of the bot's pairs.
+type DeadmanConfig struct {
+	Enabled          bool `json:"enabled"`
+	CountdownSeconds int  `json:"countdown_seconds"` // Orders are cancelled if no heartbeat within this window
+}
+
+// DeadmanSettings returns whether the deadman switch is enabled and its countdown
+func (b *BotConfig) DeadmanSettings() (bool, time.Duration) {
+	if b.IsPaperTrading {
+		return false, 0
+	}
+	if b.Deadman == nil {
+		return true, DefaultDeadmanCountdownSeconds * time.Second
+	}
+	if !b.Deadman.Enabled {
+		return false, 0
+	}
+	seconds := b.Deadman.CountdownSeconds
+	if seconds <= 0 {
+		seconds = DefaultDeadmanCountdownSeconds
+	}
+	if seconds < MinDeadmanCountdownSeconds {
+		seconds = MinDeadmanCountdownSeconds
+	}
+	return true, time.Duration(seconds) * time.Second
+}
+
 // Order represents a trading order
 type Order struct {
 	ID           int64   `json:"id"`
@@ -127,6 +336,7 @@ type Order struct {
 	Amount       float64 `json:"amount"`
 	FilledAmount float64 `json:"filled_amount"`
 	IsPaperTrade bool    `json:"is_paper_trade"`
+	APIKeyID     int64   `json:"api_key_id,omitempty"` // Key the order was placed with, 0 = user's default key
 
 	// Timestamps
 	CreatedAt time.Time  `json:"created_at"`
@@ -134,6 +344,14 @@ type Order struct {
 	FilledAt  *time.Time `json:"filled_at,omitempty"`
 }
 
+// BoundAPIKeyID returns the API key the bot trades with, 0 if it uses the default key
+func (b *BotConfig) BoundAPIKeyID() int64 {
+	if b.APIKeyID == nil {
+		return 0
+	}
+	return *b.APIKeyID
+}
+
 // ToJSON converts balances map to JSON string
 func (b *BotConfig) ToJSON() (string, error) {
 	data, err := json.Marshal(b.Balances)
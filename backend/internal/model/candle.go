@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// Candle is a closed OHLCV bucket of a pair
+type Candle struct {
+	Pair      string    `json:"pair"`
+	Timeframe string    `json:"timeframe"`
+	OpenTime  time.Time `json:"open_time"`
+	CloseTime time.Time `json:"close_time"`
+
+	Open  float64 `json:"open"`
+	High  float64 `json:"high"`
+	Low   float64 `json:"low"`
+	Close float64 `json:"close"`
+
+	Volume      float64 `json:"volume"`       // Traded base currency
+	QuoteVolume float64 `json:"quote_volume"` // Traded IDR
+	BuyVolume   float64 `json:"buy_volume"`   // Taker buy volume in IDR
+	SellVolume  float64 `json:"sell_volume"`  // Taker sell volume in IDR
+	VWAP        float64 `json:"vwap"`
+	Trades      int     `json:"trades"`
+}
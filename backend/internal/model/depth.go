@@ -0,0 +1,35 @@
+package model
+
+import "time"
+
+// Depth metric sources
+const (
+	DepthSourceLive    = "live"    // Order book stream of a subscribed pair
+	DepthSourceSampled = "sampled" // Periodic REST snapshot
+)
+
+// DepthWall is a resting level far larger than the other levels of its side
+type DepthWall struct {
+	Price       float64 `json:"price"`
+	IDRVolume   float64 `json:"idr_volume"`
+	DistancePct float64 `json:"distance_pct"` // From the mid price
+}
+
+// DepthMetrics are derived from the order book levels within BandPct of the mid price
+type DepthMetrics struct {
+	Mid        float64 `json:"mid"`
+	Microprice float64 `json:"microprice"` // Mid weighted by the top-of-book sizes
+	BandPct    float64 `json:"band_pct"`
+
+	BidDepthIDR float64 `json:"bid_depth_idr"` // Cumulative bids within the band
+	AskDepthIDR float64 `json:"ask_depth_idr"` // Cumulative asks within the band
+	Imbalance   float64 `json:"imbalance"`     // (bid - ask) / (bid + ask) within the band, -1 to 1
+	BidAskRatio float64 `json:"bid_ask_ratio"` // Bid depth / ask depth, 0 without asks
+
+	// Largest wall of each side within the band, nil when none
+	BidWall *DepthWall `json:"bid_wall,omitempty"`
+	AskWall *DepthWall `json:"ask_wall,omitempty"`
+
+	Source    string    `json:"source"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,22 @@
+package model
+
+import "time"
+
+// Feed health statuses
+const (
+	FeedStatusOK       = "ok"       // Stream delivering, every pair fresh
+	FeedStatusDegraded = "degraded" // Stream delivering, some pairs stale
+	FeedStatusStale    = "stale"    // Stream silent, every pair is stale
+)
+
+// FeedHealth reports the freshness of the public market data feed
+type FeedHealth struct {
+	Status           string     `json:"status"`
+	StreamLagSeconds float64    `json:"stream_lag_seconds"`    // Since the last stream message
+	RESTLagSeconds   float64    `json:"rest_lag_seconds"`      // Since the last successful ticker poll, -1 before the first
+	StaleSince       *time.Time `json:"stale_since,omitempty"` // When the stream went silent
+	Reconnects       int        `json:"reconnects"`            // Forced reconnects since the stream went silent
+	StalePairs       int        `json:"stale_pairs"`
+	TotalPairs       int        `json:"total_pairs"`
+	LastStreamAt     *time.Time `json:"last_stream_at,omitempty"` // Nil before the first stream message
+}
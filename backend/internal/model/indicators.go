@@ -0,0 +1,20 @@
+package model
+
+// Indicators are the technical indicators of a pair on one timeframe,
+// computed over its closed candles plus the in-progress one.
+// A value of 0 means there are not enough candles yet.
+type Indicators struct {
+	RSI14 float64 `json:"rsi_14"`
+	EMA9  float64 `json:"ema_9"`
+	EMA21 float64 `json:"ema_21"`
+	SMA20 float64 `json:"sma_20"`
+	VWAP  float64 `json:"vwap"` // Over the last 20 candles
+	ATR14 float64 `json:"atr_14"`
+
+	// Bollinger bands (20 periods, 2 standard deviations)
+	BBUpper  float64 `json:"bb_upper"`
+	BBMiddle float64 `json:"bb_middle"`
+	BBLower  float64 `json:"bb_lower"`
+
+	Candles int `json:"candles"` // Candles the values are based on
+}
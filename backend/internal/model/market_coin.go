@@ -29,37 +29,86 @@ type Coin struct {
 	BidVolume float64 `json:"bid_volume"`
 	AskVolume float64 `json:"ask_volume"`
 
+	// Depth metrics, live for subscribed pairs and sampled for the others (nil until known)
+	Depth *DepthMetrics `json:"depth,omitempty"`
+
 	// Gap Analysis (calculated)
 	GapPercentage float64 `json:"gap_percentage"` // ((Ask - Bid) / Bid) * 100
 	Spread        float64 `json:"spread"`         // Ask - Bid (absolute)
 
-	// Timeframe Data (OHLC + Transaction Count)
+	// Timeframe Data (OHLC + Transaction Count), one entry per configured timeframe
 	Timeframes Timeframes           `json:"timeframes"`
 	LastReset  map[string]time.Time `json:"last_reset"`
 
+	// Sliding windows over the last duration of every configured timeframe
+	Windows map[string]WindowStats `json:"windows"`
+
+	// Technical indicators per timeframe
+	Indicators map[string]Indicators `json:"indicators"`
+
 	// Pump Score (calculated)
-	PumpScore float64 `json:"pump_score"` // 0 to infinity
+	PumpScore        float64 `json:"pump_score"`         // 0 to infinity, from the timeframe buckets
+	RollingPumpScore float64 `json:"rolling_pump_score"` // Same formula over the sliding windows
+
+	// Scores of the admin-defined profiles, keyed by profile name
+	ProfileScores map[string]float64 `json:"profile_scores,omitempty"`
 
 	// Volatility (calculated)
-	Volatility1m float64 `json:"volatility_1m"` // Percentage
+	Volatility1m float64 `json:"volatility_1m"` // Percentage, over the shortest timeframe
 
 	// Metadata
 	LastUpdate time.Time `json:"last_update"`
+	Stale      bool      `json:"stale"` // Market stream silent or pair not confirmed recently, do not trade on it
 }
 
-// Timeframes Structure
-type Timeframes struct {
-	OneMinute  TimeframeData `json:"1m"`
-	FiveMinute TimeframeData `json:"5m"`
-	FifteenMin TimeframeData `json:"15m"`
-	ThirtyMin  TimeframeData `json:"30m"`
+// Timeframes holds the live bucket of every configured timeframe, keyed by name (e.g. "1m")
+type Timeframes map[string]*TimeframeData
+
+// Get returns a copy of the bucket of a timeframe, zero when it is not tracked
+func (t Timeframes) Get(tf string) TimeframeData {
+	if data, ok := t[tf]; ok && data != nil {
+		return *data
+	}
+	return TimeframeData{}
 }
 
 type TimeframeData struct {
 	Open float64 `json:"open"` // Opening price when timeframe started
 	High float64 `json:"high"` // Highest price in timeframe
 	Low  float64 `json:"low"`  // Lowest price in timeframe
-	Trx  int     `json:"trx"`  // Transaction count in timeframe
+	Trx  int     `json:"trx"`  // Transaction count in timeframe (from the trade feed)
+
+	// Trade flow in timeframe (from the trade feed)
+	BaseVolume float64 `json:"base_volume"` // Traded base currency
+	BuyVolume  float64 `json:"buy_volume"`  // Taker buy volume in IDR
+	SellVolume float64 `json:"sell_volume"` // Taker sell volume in IDR
+	VWAP       float64 `json:"vwap"`        // Volume weighted average price
+}
+
+// WindowStats are the statistics of a sliding window ending now.
+// Unlike TimeframeData they do not reset, Open is the price at the window start.
+type WindowStats struct {
+	Open      float64 `json:"open"`
+	High      float64 `json:"high"`
+	Low       float64 `json:"low"`
+	Close     float64 `json:"close"`
+	ChangePct float64 `json:"change_pct"`
+	Trx       int     `json:"trx"`
+
+	BaseVolume float64 `json:"base_volume"` // Traded base currency
+	BuyVolume  float64 `json:"buy_volume"`  // Taker buy volume in IDR
+	SellVolume float64 `json:"sell_volume"` // Taker sell volume in IDR
+}
+
+// MarketTrade is a public trade print
+type MarketTrade struct {
+	ID     int64     `json:"id"`
+	Pair   string    `json:"pair"`
+	Side   string    `json:"side"` // Taker side: buy or sell
+	Price  float64   `json:"price"`
+	Amount float64   `json:"amount"` // Base currency
+	Total  float64   `json:"total"`  // IDR
+	Time   time.Time `json:"time"`
 }
 
 // Helper to Marshal Coin to Map for Redis
@@ -72,29 +121,51 @@ func (c *Coin) ToMap() (map[string]interface{}, error) {
 	if err != nil {
 		return nil, err
 	}
+	windowsJSON, err := json.Marshal(c.Windows)
+	if err != nil {
+		return nil, err
+	}
+	indicatorsJSON, err := json.Marshal(c.Indicators)
+	if err != nil {
+		return nil, err
+	}
+	profileScoresJSON, err := json.Marshal(c.ProfileScores)
+	if err != nil {
+		return nil, err
+	}
+	depthJSON, err := json.Marshal(c.Depth)
+	if err != nil {
+		return nil, err
+	}
 
 	return map[string]interface{}{
-		"pair_id":        c.PairID,
-		"base_currency":  c.BaseCurrency,
-		"quote_currency": c.QuoteCurrency,
-		"current_price":  c.CurrentPrice,
-		"high_24h":       c.High24h,
-		"low_24h":        c.Low24h,
-		"open_24h":       c.Open24h,
-		"volume_24h":     c.Volume24h,
-		"volume_idr":     c.VolumeIDR,
-		"change_24h":     c.Change24h,
-		"best_bid":       c.BestBid,
-		"best_ask":       c.BestAsk,
-		"bid_volume":     c.BidVolume,
-		"ask_volume":     c.AskVolume,
-		"gap_percentage": c.GapPercentage,
-		"spread":         c.Spread,
-		"timeframes":     string(timeframesJSON),
-		"last_reset":     string(lastResetJSON),
-		"pump_score":     c.PumpScore,
-		"volatility_1m":  c.Volatility1m,
-		"last_update":    c.LastUpdate.UnixMilli(),
+		"pair_id":            c.PairID,
+		"base_currency":      c.BaseCurrency,
+		"quote_currency":     c.QuoteCurrency,
+		"current_price":      c.CurrentPrice,
+		"high_24h":           c.High24h,
+		"low_24h":            c.Low24h,
+		"open_24h":           c.Open24h,
+		"volume_24h":         c.Volume24h,
+		"volume_idr":         c.VolumeIDR,
+		"change_24h":         c.Change24h,
+		"best_bid":           c.BestBid,
+		"best_ask":           c.BestAsk,
+		"bid_volume":         c.BidVolume,
+		"ask_volume":         c.AskVolume,
+		"depth":              string(depthJSON),
+		"gap_percentage":     c.GapPercentage,
+		"spread":             c.Spread,
+		"timeframes":         string(timeframesJSON),
+		"last_reset":         string(lastResetJSON),
+		"windows":            string(windowsJSON),
+		"indicators":         string(indicatorsJSON),
+		"profile_scores":     string(profileScoresJSON),
+		"pump_score":         c.PumpScore,
+		"rolling_pump_score": c.RollingPumpScore,
+		"volatility_1m":      c.Volatility1m,
+		"last_update":        c.LastUpdate.UnixMilli(),
+		"stale":              c.Stale,
 	}, nil
 }
 
@@ -121,6 +192,7 @@ func CoinFromMap(data map[string]string) (*Coin, error) {
 	c.GapPercentage, _ = strconv.ParseFloat(data["gap_percentage"], 64)
 	c.Spread, _ = strconv.ParseFloat(data["spread"], 64)
 	c.PumpScore, _ = strconv.ParseFloat(data["pump_score"], 64)
+	c.RollingPumpScore, _ = strconv.ParseFloat(data["rolling_pump_score"], 64)
 	c.Volatility1m, _ = strconv.ParseFloat(data["volatility_1m"], 64)
 
 	if lu, ok := data["last_update"]; ok {
@@ -128,6 +200,8 @@ func CoinFromMap(data map[string]string) (*Coin, error) {
 		c.LastUpdate = time.UnixMilli(ms)
 	}
 
+	c.Stale, _ = strconv.ParseBool(data["stale"])
+
 	if tf, ok := data["timeframes"]; ok {
 		json.Unmarshal([]byte(tf), &c.Timeframes)
 	}
@@ -136,6 +210,22 @@ func CoinFromMap(data map[string]string) (*Coin, error) {
 		json.Unmarshal([]byte(lr), &c.LastReset)
 	}
 
+	if w, ok := data["windows"]; ok {
+		json.Unmarshal([]byte(w), &c.Windows)
+	}
+
+	if ind, ok := data["indicators"]; ok {
+		json.Unmarshal([]byte(ind), &c.Indicators)
+	}
+
+	if ps, ok := data["profile_scores"]; ok {
+		json.Unmarshal([]byte(ps), &c.ProfileScores)
+	}
+
+	if d, ok := data["depth"]; ok {
+		json.Unmarshal([]byte(d), &c.Depth)
+	}
+
 	return c, nil
 }
 
@@ -0,0 +1,54 @@
+package model
+
+import "time"
+
+// DefaultPumpScoreProfile is the built-in profile behind Coin.PumpScore
+const DefaultPumpScoreProfile = "default"
+
+// DefaultPumpSignalThreshold is the pump_signal broadcast threshold of the built-in profile
+const DefaultPumpSignalThreshold = 1000
+
+// Trade count dampening modes
+const (
+	TrxDampeningNone = "none" // change × trx
+	TrxDampeningSqrt = "sqrt" // change × √trx
+	TrxDampeningLog  = "log"  // change × ln(1 + trx)
+)
+
+// PumpScoreProfile is a named pump score formula.
+// Score = Σ change% × dampen(trx) × weight [× volume factor] over the timeframes.
+type PumpScoreProfile struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+
+	// Weight per timeframe name, the configured timeframe weights when empty
+	Weights map[string]float64 `json:"weights,omitempty"`
+
+	TrxDampening string `json:"trx_dampening"` // none, sqrt, log
+
+	// Scale each timeframe by its traded IDR relative to the pace of the 24h volume
+	VolumeNormalization bool `json:"volume_normalization"`
+
+	// Score the sliding windows instead of the clock-reset buckets
+	UseRollingWindows bool `json:"use_rolling_windows"`
+
+	// Thresholds
+	MinVolumeIDR    float64 `json:"min_volume_idr"`   // Coins below score 0
+	SignalThreshold float64 `json:"signal_threshold"` // pump_signal broadcast (0 disables)
+
+	BuiltIn   bool      `json:"built_in"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// PumpScoreProfileRequest creates or updates a profile
+type PumpScoreProfileRequest struct {
+	Name                string             `json:"name"`
+	Description         string             `json:"description"`
+	Weights             map[string]float64 `json:"weights"`
+	TrxDampening        string             `json:"trx_dampening" binding:"omitempty,oneof=none sqrt log"`
+	VolumeNormalization bool               `json:"volume_normalization"`
+	UseRollingWindows   bool               `json:"use_rolling_windows"`
+	MinVolumeIDR        float64            `json:"min_volume_idr" binding:"gte=0"`
+	SignalThreshold     float64            `json:"signal_threshold" binding:"gte=0"`
+}
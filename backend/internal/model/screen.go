@@ -0,0 +1,28 @@
+package model
+
+import "time"
+
+// Screen is a saved market screener query of a user
+type Screen struct {
+	ID     int64  `json:"id"`
+	UserID string `json:"user_id"`
+	Name   string `json:"name"`
+	Filter string `json:"filter"` // e.g. "pump_score > 300 and volume_idr > 5e9"
+	Sort   string `json:"sort"`   // "field [asc|desc]"
+	Limit  int    `json:"limit"`  // 0 returns every match
+
+	// Push coins entering and leaving the screen over the WebSocket
+	Stream bool `json:"stream"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// ScreenRequest creates or updates a saved screen
+type ScreenRequest struct {
+	Name   string `json:"name" binding:"required,max=64"`
+	Filter string `json:"filter"`
+	Sort   string `json:"sort"`
+	Limit  int    `json:"limit" binding:"gte=0"`
+	Stream bool   `json:"stream"`
+}
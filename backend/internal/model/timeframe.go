@@ -0,0 +1,61 @@
+package model
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TimeframeSpec defines a tracked timeframe
+type TimeframeSpec struct {
+	Name     string        `json:"name"`     // e.g. "5m", also the key in Coin.Timeframes
+	Duration time.Duration `json:"duration"` // Bucket length
+	Weight   float64       `json:"weight"`   // Pump score weight (0 excludes it from the score)
+}
+
+// ParseTimeframes parses a comma-separated list of name[:weight] entries
+// (e.g. "1m:0.2,5m:0.4,1h"), sorted from shortest to longest.
+// Names are Go durations, so hours are written as "24h" rather than "1d".
+func ParseTimeframes(spec string) ([]TimeframeSpec, error) {
+	var specs []TimeframeSpec
+	seen := make(map[string]bool)
+
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		name, weightStr, hasWeight := strings.Cut(entry, ":")
+		name = strings.TrimSpace(name)
+		d, err := time.ParseDuration(name)
+		if err != nil || d < time.Minute {
+			return nil, fmt.Errorf("invalid timeframe %q: must be a duration of at least 1m", name)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate timeframe %q", name)
+		}
+		seen[name] = true
+
+		var weight float64
+		if hasWeight {
+			weight, err = strconv.ParseFloat(strings.TrimSpace(weightStr), 64)
+			if err != nil || weight < 0 {
+				return nil, fmt.Errorf("invalid weight for timeframe %q", name)
+			}
+		}
+
+		specs = append(specs, TimeframeSpec{Name: name, Duration: d, Weight: weight})
+	}
+
+	if len(specs) == 0 {
+		return nil, fmt.Errorf("no timeframes configured")
+	}
+
+	sort.SliceStable(specs, func(i, j int) bool {
+		return specs[i].Duration < specs[j].Duration
+	})
+	return specs, nil
+}
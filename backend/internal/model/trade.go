@@ -53,6 +53,7 @@ type Trade struct {
 	Status       string `json:"status"` // pending, filled, completed, cancelled, stopped, error
 	ErrorMessage string `json:"error_message,omitempty"`
 	IsPaperTrade bool   `json:"is_paper_trade"`
+	APIKeyID     *int64 `json:"api_key_id,omitempty"` // Key used for live trades
 
 	// Timestamps
 	CreatedAt   time.Time  `json:"created_at"`
@@ -68,4 +69,13 @@ type TradeRequest struct {
 	TargetProfit float64 `json:"target_profit" binding:"required,gt=0"`
 	StopLoss     float64 `json:"stop_loss" binding:"required,gt=0"`
 	IsPaperTrade bool    `json:"is_paper_trade"`
+	APIKeyID     *int64  `json:"api_key_id"` // Live only, user's default key if omitted
+}
+
+// BoundAPIKeyID returns the API key the trade was placed with, 0 if it predates key binding
+func (t *Trade) BoundAPIKeyID() int64 {
+	if t.APIKeyID == nil {
+		return 0
+	}
+	return *t.APIKeyID
 }
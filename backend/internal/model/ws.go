@@ -1,5 +1,7 @@
 package model
 
+import "time"
+
 // WSMessageType represents the type of WebSocket message
 type WSMessageType string
 
@@ -10,6 +12,9 @@ const (
 	MessageTypePositionUpdate WSMessageType = "position_update"
 	MessageTypeBalanceUpdate  WSMessageType = "balance_update"
 	MessageTypePumpSignal     WSMessageType = "pump_signal"
+	MessageTypeDeadmanAlert   WSMessageType = "deadman_alert"
+	MessageTypeScreenMatch    WSMessageType = "screen_match"
+	MessageTypeAlert          WSMessageType = "alert"
 	MessageTypeError          WSMessageType = "error"
 	MessageTypeAuthSuccess    WSMessageType = "auth_success"
 	MessageTypePong           WSMessageType = "pong"
@@ -41,3 +46,36 @@ type WSBotUpdatePayload struct {
 	SellPrice     float64 `json:"sell_price,omitempty"`     // Current ask price
 	SpreadPercent float64 `json:"spread_percent,omitempty"` // Current spread percentage
 }
+
+// WSDeadmanAlertPayload reports deadman switch heartbeat failures/recoveries
+type WSDeadmanAlertPayload struct {
+	Pair                string  `json:"pair"`
+	BotIDs              []int64 `json:"bot_ids"`
+	Status              string  `json:"status"` // failing, recovered
+	ConsecutiveFailures int     `json:"consecutive_failures"`
+	CountdownSeconds    int     `json:"countdown_seconds"`
+	Error               string  `json:"error,omitempty"`
+}
+
+// WSScreenMatchPayload reports a coin entering or leaving a streamed screen
+type WSScreenMatchPayload struct {
+	ScreenID int64  `json:"screen_id"`
+	Name     string `json:"name"`
+	Pair     string `json:"pair"`
+	Matched  bool   `json:"matched"` // false when the coin left the screen
+	Coin     *Coin  `json:"coin"`
+}
+
+// WSAlertPayload reports a triggered alert
+type WSAlertPayload struct {
+	AlertID     int64     `json:"alert_id"`
+	Pair        string    `json:"pair"`
+	Type        string    `json:"type"`
+	Threshold   float64   `json:"threshold"`
+	Value       float64   `json:"value"` // Value that triggered the alert
+	Price       float64   `json:"price"`
+	Message     string    `json:"message"`
+	Note        string    `json:"note,omitempty"`
+	Repeating   bool      `json:"repeating"`
+	TriggeredAt time.Time `json:"triggered_at"`
+}
@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"tuyul/backend/internal/model"
+	"tuyul/backend/pkg/redis"
+
+	redislib "github.com/redis/go-redis/v9"
+)
+
+// AlertRepository stores the price and signal alerts of users
+type AlertRepository struct {
+	redis *redis.Client
+}
+
+func NewAlertRepository(redisClient *redis.Client) *AlertRepository {
+	return &AlertRepository{
+		redis: redisClient,
+	}
+}
+
+// Create stores a new alert
+func (r *AlertRepository) Create(ctx context.Context, alert *model.Alert) error {
+	id, err := r.redis.Incr(ctx, "sequences:alert_id")
+	if err != nil {
+		return err
+	}
+	alert.ID = id
+	alert.CreatedAt = time.Now()
+	alert.UpdatedAt = alert.CreatedAt
+
+	if err := r.redis.SetJSON(ctx, redis.AlertKey(alert.ID), alert, 0); err != nil {
+		return err
+	}
+	if err := r.redis.SAdd(ctx, redis.UserAlertsKey(alert.UserID), alert.ID); err != nil {
+		return err
+	}
+	return r.updateActiveIndex(ctx, alert)
+}
+
+// Update replaces an alert
+func (r *AlertRepository) Update(ctx context.Context, alert *model.Alert) error {
+	alert.UpdatedAt = time.Now()
+	if err := r.redis.SetJSON(ctx, redis.AlertKey(alert.ID), alert, 0); err != nil {
+		return err
+	}
+	return r.updateActiveIndex(ctx, alert)
+}
+
+func (r *AlertRepository) updateActiveIndex(ctx context.Context, alert *model.Alert) error {
+	if alert.Active {
+		return r.redis.SAdd(ctx, redis.ActiveAlertsKey(), alert.ID)
+	}
+	return r.redis.SRem(ctx, redis.ActiveAlertsKey(), alert.ID)
+}
+
+// GetByID retrieves an alert
+func (r *AlertRepository) GetByID(ctx context.Context, alertID int64) (*model.Alert, error) {
+	var alert model.Alert
+	if err := r.redis.GetJSON(ctx, redis.AlertKey(alertID), &alert); err != nil {
+		if err == redislib.Nil {
+			return nil, fmt.Errorf("alert not found")
+		}
+		return nil, err
+	}
+	return &alert, nil
+}
+
+// ListByUser retrieves the alerts of a user
+func (r *AlertRepository) ListByUser(ctx context.Context, userID string) ([]*model.Alert, error) {
+	ids, err := r.redis.SMembers(ctx, redis.UserAlertsKey(userID))
+	if err != nil {
+		return nil, err
+	}
+	return r.getMany(ctx, ids), nil
+}
+
+// ListActive retrieves the alerts evaluated on market updates
+func (r *AlertRepository) ListActive(ctx context.Context) ([]*model.Alert, error) {
+	ids, err := r.redis.SMembers(ctx, redis.ActiveAlertsKey())
+	if err != nil {
+		return nil, err
+	}
+	return r.getMany(ctx, ids), nil
+}
+
+func (r *AlertRepository) getMany(ctx context.Context, ids []string) []*model.Alert {
+	alerts := make([]*model.Alert, 0, len(ids))
+	for _, idStr := range ids {
+		id, _ := strconv.ParseInt(idStr, 10, 64)
+		alert, err := r.GetByID(ctx, id)
+		if err == nil {
+			alerts = append(alerts, alert)
+		}
+	}
+	return alerts
+}
+
+// Delete removes an alert and its index entries
+func (r *AlertRepository) Delete(ctx context.Context, alert *model.Alert) error {
+	if err := r.redis.Del(ctx, redis.AlertKey(alert.ID)); err != nil {
+		return err
+	}
+	r.redis.SRem(ctx, redis.UserAlertsKey(alert.UserID), alert.ID)
+	r.redis.SRem(ctx, redis.ActiveAlertsKey(), alert.ID)
+	return nil
+}
@@ -4,6 +4,8 @@ package repository
 import (
 	"context"
 	"errors"
+	"sort"
+	"strconv"
 
 	"tuyul/backend/internal/model"
 	"tuyul/backend/pkg/redis"
@@ -23,15 +25,28 @@ func NewAPIKeyRepository(redisClient *redis.Client) *APIKeyRepository {
 	}
 }
 
-// Create creates or updates an API key
+// Create creates a new API key
 func (r *APIKeyRepository) Create(ctx context.Context, apiKey *model.APIKey) error {
-	key := redis.APIKeyKey(apiKey.UserID)
-	return r.redis.SetJSON(ctx, key, apiKey, 0)
+	if apiKey.ID == 0 {
+		id, err := r.redis.Incr(ctx, "sequences:api_key_id")
+		if err != nil {
+			return err
+		}
+		apiKey.ID = id
+	}
+
+	keyIDStr := strconv.FormatInt(apiKey.ID, 10)
+	if err := r.redis.SetJSON(ctx, redis.APIKeyKey(keyIDStr), apiKey, 0); err != nil {
+		return err
+	}
+
+	// Add to user's keys set
+	return r.redis.SAdd(ctx, redis.UserAPIKeysKey(apiKey.UserID), keyIDStr)
 }
 
-// Get gets an API key by user ID
-func (r *APIKeyRepository) Get(ctx context.Context, userID string) (*model.APIKey, error) {
-	key := redis.APIKeyKey(userID)
+// GetByID gets an API key by ID
+func (r *APIKeyRepository) GetByID(ctx context.Context, keyID int64) (*model.APIKey, error) {
+	key := redis.APIKeyKey(strconv.FormatInt(keyID, 10))
 
 	var apiKey model.APIKey
 	if err := r.redis.GetJSON(ctx, key, &apiKey); err != nil {
@@ -44,46 +59,114 @@ func (r *APIKeyRepository) Get(ctx context.Context, userID string) (*model.APIKe
 	return &apiKey, nil
 }
 
+// Get gets an API key of a user (keys of other users are reported as not found)
+func (r *APIKeyRepository) Get(ctx context.Context, userID string, keyID int64) (*model.APIKey, error) {
+	apiKey, err := r.GetByID(ctx, keyID)
+	if err != nil {
+		return nil, err
+	}
+	if apiKey.UserID != userID {
+		return nil, errors.New("API key not found")
+	}
+	return apiKey, nil
+}
+
+// ListByUser returns the API keys of a user, oldest first
+func (r *APIKeyRepository) ListByUser(ctx context.Context, userID string) ([]*model.APIKey, error) {
+	keyIDs, err := r.redis.SMembers(ctx, redis.UserAPIKeysKey(userID))
+	if err != nil {
+		return nil, err
+	}
+
+	apiKeys := make([]*model.APIKey, 0, len(keyIDs))
+	for _, idStr := range keyIDs {
+		id, _ := strconv.ParseInt(idStr, 10, 64)
+		apiKey, err := r.GetByID(ctx, id)
+		if err == nil {
+			apiKeys = append(apiKeys, apiKey)
+		}
+	}
+
+	sort.Slice(apiKeys, func(i, j int) bool { return apiKeys[i].ID < apiKeys[j].ID })
+	return apiKeys, nil
+}
+
+// GetDefault gets the default API key of a user (the oldest key if none is flagged)
+func (r *APIKeyRepository) GetDefault(ctx context.Context, userID string) (*model.APIKey, error) {
+	apiKeys, err := r.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(apiKeys) == 0 {
+		return nil, errors.New("API key not found")
+	}
+
+	for _, apiKey := range apiKeys {
+		if apiKey.IsDefault {
+			return apiKey, nil
+		}
+	}
+	return apiKeys[0], nil
+}
+
 // Update updates an API key
 func (r *APIKeyRepository) Update(ctx context.Context, apiKey *model.APIKey) error {
 	return r.Create(ctx, apiKey) // Same as create in Redis
 }
 
-// Delete deletes an API key
-func (r *APIKeyRepository) Delete(ctx context.Context, userID string) error {
-	key := redis.APIKeyKey(userID)
-	return r.redis.Del(ctx, key)
+// Delete deletes an API key of a user
+func (r *APIKeyRepository) Delete(ctx context.Context, userID string, keyID int64) error {
+	keyIDStr := strconv.FormatInt(keyID, 10)
+	if err := r.redis.Del(ctx, redis.APIKeyKey(keyIDStr)); err != nil {
+		return err
+	}
+	return r.redis.SRem(ctx, redis.UserAPIKeysKey(userID), keyIDStr)
 }
 
-// Exists checks if a user has an API key
-func (r *APIKeyRepository) Exists(ctx context.Context, userID string) (bool, error) {
-	key := redis.APIKeyKey(userID)
-	return r.redis.Exists(ctx, key)
+// ListAll returns the API keys of all users
+func (r *APIKeyRepository) ListAll(ctx context.Context) ([]*model.APIKey, error) {
+	keys, err := r.redis.Keys(ctx, redis.APIKeyKey("*"))
+	if err != nil {
+		return nil, err
+	}
+
+	apiKeys := make([]*model.APIKey, 0, len(keys))
+	for _, key := range keys {
+		var apiKey model.APIKey
+		if err := r.redis.GetJSON(ctx, key, &apiKey); err == nil {
+			apiKeys = append(apiKeys, &apiKey)
+		}
+	}
+	return apiKeys, nil
 }
 
-// GetAllUserIDs returns all user IDs that have API keys
-func (r *APIKeyRepository) GetAllUserIDs(ctx context.Context) ([]string, error) {
-	// Get the pattern for API key keys (e.g., "tuyul:api_key:*")
-	pattern := redis.APIKeyKey("*")
-	keys, err := r.redis.Keys(ctx, pattern)
+// MigrateLegacyKeys moves the single per-user keys of older versions to the
+// multi-key layout as the user's default key. Returns the new key ID per user.
+func (r *APIKeyRepository) MigrateLegacyKeys(ctx context.Context) (map[string]int64, error) {
+	// Key format: "tuyul:api_key:{userID}"
+	keys, err := r.redis.Keys(ctx, redis.LegacyAPIKeyKey("*"))
 	if err != nil {
 		return nil, err
 	}
 
-	// Extract user IDs from keys
-	// Key format: "tuyul:api_key:{userID}"
-	userIDs := make([]string, 0, len(keys))
-	prefix := redis.APIKeyKey("")
-	
+	migrated := make(map[string]int64, len(keys))
 	for _, key := range keys {
-		// Remove prefix to get userID
-		if len(key) > len(prefix) {
-			userID := key[len(prefix):]
-			userIDs = append(userIDs, userID)
+		var apiKey model.APIKey
+		if err := r.redis.GetJSON(ctx, key, &apiKey); err != nil {
+			return migrated, err
 		}
+
+		apiKey.ID = 0
+		apiKey.Label = "Default"
+		apiKey.IsDefault = true
+		if err := r.Create(ctx, &apiKey); err != nil {
+			return migrated, err
+		}
+		if err := r.redis.Del(ctx, key); err != nil {
+			return migrated, err
+		}
+		migrated[apiKey.UserID] = apiKey.ID
 	}
 
-	return userIDs, nil
+	return migrated, nil
 }
-
-
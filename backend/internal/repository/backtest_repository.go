@@ -0,0 +1,112 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"tuyul/backend/internal/model"
+	"tuyul/backend/pkg/redis"
+
+	redislib "github.com/redis/go-redis/v9"
+)
+
+// BacktestRepository stores the backtest jobs of users and their results
+type BacktestRepository struct {
+	redis *redis.Client
+}
+
+func NewBacktestRepository(redisClient *redis.Client) *BacktestRepository {
+	return &BacktestRepository{
+		redis: redisClient,
+	}
+}
+
+// Create stores a new backtest
+func (r *BacktestRepository) Create(ctx context.Context, backtest *model.Backtest) error {
+	id, err := r.redis.Incr(ctx, "sequences:backtest_id")
+	if err != nil {
+		return err
+	}
+	backtest.ID = id
+	backtest.CreatedAt = time.Now()
+
+	if err := r.redis.SetJSON(ctx, redis.BacktestKey(backtest.ID), backtest, 0); err != nil {
+		return err
+	}
+	if err := r.redis.SAdd(ctx, redis.UserBacktestsKey(backtest.UserID), backtest.ID); err != nil {
+		return err
+	}
+	return r.updateActiveIndex(ctx, backtest)
+}
+
+// Update replaces a backtest
+func (r *BacktestRepository) Update(ctx context.Context, backtest *model.Backtest) error {
+	if err := r.redis.SetJSON(ctx, redis.BacktestKey(backtest.ID), backtest, 0); err != nil {
+		return err
+	}
+	return r.updateActiveIndex(ctx, backtest)
+}
+
+func (r *BacktestRepository) updateActiveIndex(ctx context.Context, backtest *model.Backtest) error {
+	if backtest.Status == model.BacktestStatusQueued || backtest.Status == model.BacktestStatusRunning {
+		return r.redis.SAdd(ctx, redis.ActiveBacktestsKey(), backtest.ID)
+	}
+	return r.redis.SRem(ctx, redis.ActiveBacktestsKey(), backtest.ID)
+}
+
+// GetByID retrieves a backtest
+func (r *BacktestRepository) GetByID(ctx context.Context, backtestID int64) (*model.Backtest, error) {
+	var backtest model.Backtest
+	if err := r.redis.GetJSON(ctx, redis.BacktestKey(backtestID), &backtest); err != nil {
+		if err == redislib.Nil {
+			return nil, fmt.Errorf("backtest not found")
+		}
+		return nil, err
+	}
+	return &backtest, nil
+}
+
+// ListByUser retrieves the backtests of a user, newest first
+func (r *BacktestRepository) ListByUser(ctx context.Context, userID string) ([]*model.Backtest, error) {
+	ids, err := r.redis.SMembers(ctx, redis.UserBacktestsKey(userID))
+	if err != nil {
+		return nil, err
+	}
+	backtests := r.getMany(ctx, ids)
+	sort.Slice(backtests, func(i, j int) bool { return backtests[i].ID > backtests[j].ID })
+	return backtests, nil
+}
+
+// ListActive retrieves the queued and running backtests
+func (r *BacktestRepository) ListActive(ctx context.Context) ([]*model.Backtest, error) {
+	ids, err := r.redis.SMembers(ctx, redis.ActiveBacktestsKey())
+	if err != nil {
+		return nil, err
+	}
+	return r.getMany(ctx, ids), nil
+}
+
+func (r *BacktestRepository) getMany(ctx context.Context, ids []string) []*model.Backtest {
+	backtests := make([]*model.Backtest, 0, len(ids))
+	for _, idStr := range ids {
+		id, _ := strconv.ParseInt(idStr, 10, 64)
+		backtest, err := r.GetByID(ctx, id)
+		if err == nil {
+			backtests = append(backtests, backtest)
+		}
+	}
+	return backtests
+}
+
+// Delete removes a backtest and its index entries
+func (r *BacktestRepository) Delete(ctx context.Context, backtest *model.Backtest) error {
+	if err := r.redis.Del(ctx, redis.BacktestKey(backtest.ID)); err != nil {
+		return err
+	}
+	r.redis.SRem(ctx, redis.UserBacktestsKey(backtest.UserID), backtest.ID)
+	r.redis.SRem(ctx, redis.ActiveBacktestsKey(), backtest.ID)
+	return nil
+}
@@ -236,6 +236,27 @@ func (r *BotRepository) ListByStatus(ctx context.Context, status string) ([]*mod
 	return bots, nil
 }
 
+// ListByType retrieves all bots of a type
+func (r *BotRepository) ListByType(ctx context.Context, botType string) ([]*model.BotConfig, error) {
+	typeKey := redis.BotsByTypeKey(botType)
+
+	botIDs, err := r.redis.SMembers(ctx, typeKey)
+	if err != nil {
+		return nil, err
+	}
+
+	bots := make([]*model.BotConfig, 0, len(botIDs))
+	for _, idStr := range botIDs {
+		id, _ := strconv.ParseInt(idStr, 10, 64)
+		bot, err := r.GetByID(ctx, id)
+		if err == nil {
+			bots = append(bots, bot)
+		}
+	}
+
+	return bots, nil
+}
+
 // UpdateBalance updates the bot's balance
 func (r *BotRepository) UpdateBalance(ctx context.Context, botID int64, balances map[string]float64) error {
 	bot, err := r.GetByID(ctx, botID)
@@ -279,6 +300,32 @@ func (r *BotRepository) UpdateTracking(ctx context.Context, botID int64, totalCo
 	return r.Update(ctx, bot, "")
 }
 
+// UpdateGridLevels updates the level state of a grid bot
+func (r *BotRepository) UpdateGridLevels(ctx context.Context, botID int64, levels []*model.GridLevel) error {
+	bot, err := r.GetByID(ctx, botID)
+	if err != nil {
+		return err
+	}
+
+	bot.GridLevels = levels
+	bot.UpdatedAt = time.Now()
+
+	return r.Update(ctx, bot, "")
+}
+
+// UpdateDCAState updates the runtime state of a DCA bot
+func (r *BotRepository) UpdateDCAState(ctx context.Context, botID int64, state *model.DCAState) error {
+	bot, err := r.GetByID(ctx, botID)
+	if err != nil {
+		return err
+	}
+
+	bot.DCAState = state
+	bot.UpdatedAt = time.Now()
+
+	return r.Update(ctx, bot, "")
+}
+
 // UpdateStatus updates bot status
 func (r *BotRepository) UpdateStatus(ctx context.Context, botID int64, status string, errorMsg *string) error {
 	bot, err := r.GetByID(ctx, botID)
@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"tuyul/backend/internal/model"
+	"tuyul/backend/pkg/redis"
+)
+
+// CandleRepository stores closed candles per pair and timeframe in sorted sets
+// scored by open time (unix ms)
+type CandleRepository struct {
+	redis     *redis.Client
+	retention int
+}
+
+// NewCandleRepository creates a candle repository keeping at most retention
+// candles per pair and timeframe (0 keeps everything)
+func NewCandleRepository(redisClient *redis.Client, retention int) *CandleRepository {
+	return &CandleRepository{
+		redis:     redisClient,
+		retention: retention,
+	}
+}
+
+// Save stores a closed candle, replacing any candle with the same open time,
+// and trims the series to the retention limit
+func (r *CandleRepository) Save(ctx context.Context, candle *model.Candle) error {
+	data, err := json.Marshal(candle)
+	if err != nil {
+		return err
+	}
+
+	key := redis.CandlesKey(candle.Pair, candle.Timeframe)
+	score := strconv.FormatInt(candle.OpenTime.UnixMilli(), 10)
+
+	pipe := r.redis.TxPipeline()
+	pipe.ZRemRangeByScore(ctx, key, score, score)
+	pipe.ZAdd(ctx, key, redis.Z{Score: float64(candle.OpenTime.UnixMilli()), Member: string(data)})
+	if r.retention > 0 {
+		// Ranks are ascending by open time, drop everything but the newest
+		pipe.ZRemRangeByRank(ctx, key, 0, int64(-r.retention-1))
+	}
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// List returns the newest candles opened within [from, to], oldest first.
+// Zero times leave that side of the range open, limit <= 0 returns all.
+func (r *CandleRepository) List(ctx context.Context, pair, timeframe string, from, to time.Time, limit int) ([]model.Candle, error) {
+	min, max := "-inf", "+inf"
+	if !from.IsZero() {
+		min = strconv.FormatInt(from.UnixMilli(), 10)
+	}
+	if !to.IsZero() {
+		max = strconv.FormatInt(to.UnixMilli(), 10)
+	}
+
+	members, err := r.redis.ZRevRangeByScore(ctx, redis.CandlesKey(pair, timeframe), max, min, 0, int64(limit))
+	if err != nil {
+		return nil, err
+	}
+
+	candles := make([]model.Candle, 0, len(members))
+	for i := len(members) - 1; i >= 0; i-- {
+		var c model.Candle
+		if err := json.Unmarshal([]byte(members[i]), &c); err != nil {
+			continue
+		}
+		candles = append(candles, c)
+	}
+	return candles, nil
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"tuyul/backend/internal/model"
+	"tuyul/backend/pkg/redis"
+
+	redislib "github.com/redis/go-redis/v9"
+)
+
+// PumpScoreProfileRepository stores the admin-defined pump score profiles in one hash
+type PumpScoreProfileRepository struct {
+	redis *redis.Client
+}
+
+func NewPumpScoreProfileRepository(redisClient *redis.Client) *PumpScoreProfileRepository {
+	return &PumpScoreProfileRepository{
+		redis: redisClient,
+	}
+}
+
+// Save creates or replaces a profile
+func (r *PumpScoreProfileRepository) Save(ctx context.Context, profile *model.PumpScoreProfile) error {
+	now := time.Now()
+	if profile.CreatedAt.IsZero() {
+		profile.CreatedAt = now
+	}
+	profile.UpdatedAt = now
+
+	data, err := json.Marshal(profile)
+	if err != nil {
+		return err
+	}
+	return r.redis.HSet(ctx, redis.PumpScoreProfilesKey(), profile.Name, string(data))
+}
+
+// GetByName retrieves a profile
+func (r *PumpScoreProfileRepository) GetByName(ctx context.Context, name string) (*model.PumpScoreProfile, error) {
+	data, err := r.redis.HGet(ctx, redis.PumpScoreProfilesKey(), name)
+	if err != nil {
+		if err == redislib.Nil {
+			return nil, fmt.Errorf("pump score profile not found")
+		}
+		return nil, err
+	}
+
+	var profile model.PumpScoreProfile
+	if err := json.Unmarshal([]byte(data), &profile); err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// List returns every stored profile sorted by name
+func (r *PumpScoreProfileRepository) List(ctx context.Context) ([]*model.PumpScoreProfile, error) {
+	all, err := r.redis.HGetAll(ctx, redis.PumpScoreProfilesKey())
+	if err != nil {
+		return nil, err
+	}
+
+	profiles := make([]*model.PumpScoreProfile, 0, len(all))
+	for _, data := range all {
+		var profile model.PumpScoreProfile
+		if err := json.Unmarshal([]byte(data), &profile); err != nil {
+			continue
+		}
+		profiles = append(profiles, &profile)
+	}
+	sort.Slice(profiles, func(i, j int) bool {
+		return profiles[i].Name < profiles[j].Name
+	})
+	return profiles, nil
+}
+
+// Delete removes a profile and its ranking
+func (r *PumpScoreProfileRepository) Delete(ctx context.Context, name string) error {
+	if err := r.redis.HDel(ctx, redis.PumpScoreProfilesKey(), name); err != nil {
+		return err
+	}
+	return r.redis.Del(ctx, redis.PumpScoreProfileRankKey(name))
+}
@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"tuyul/backend/internal/model"
+	"tuyul/backend/pkg/redis"
+
+	redislib "github.com/redis/go-redis/v9"
+)
+
+// ScreenRepository stores the saved screener queries of users
+type ScreenRepository struct {
+	redis *redis.Client
+}
+
+func NewScreenRepository(redisClient *redis.Client) *ScreenRepository {
+	return &ScreenRepository{
+		redis: redisClient,
+	}
+}
+
+// Create stores a new screen
+func (r *ScreenRepository) Create(ctx context.Context, screen *model.Screen) error {
+	id, err := r.redis.Incr(ctx, "sequences:screen_id")
+	if err != nil {
+		return err
+	}
+	screen.ID = id
+	screen.CreatedAt = time.Now()
+	screen.UpdatedAt = screen.CreatedAt
+
+	if err := r.redis.SetJSON(ctx, redis.ScreenKey(screen.ID), screen, 0); err != nil {
+		return err
+	}
+	if err := r.redis.SAdd(ctx, redis.UserScreensKey(screen.UserID), screen.ID); err != nil {
+		return err
+	}
+	return r.updateStreamingIndex(ctx, screen)
+}
+
+// Update replaces a screen
+func (r *ScreenRepository) Update(ctx context.Context, screen *model.Screen) error {
+	screen.UpdatedAt = time.Now()
+	if err := r.redis.SetJSON(ctx, redis.ScreenKey(screen.ID), screen, 0); err != nil {
+		return err
+	}
+	return r.updateStreamingIndex(ctx, screen)
+}
+
+func (r *ScreenRepository) updateStreamingIndex(ctx context.Context, screen *model.Screen) error {
+	if screen.Stream {
+		return r.redis.SAdd(ctx, redis.StreamingScreensKey(), screen.ID)
+	}
+	return r.redis.SRem(ctx, redis.StreamingScreensKey(), screen.ID)
+}
+
+// GetByID retrieves a screen
+func (r *ScreenRepository) GetByID(ctx context.Context, screenID int64) (*model.Screen, error) {
+	var screen model.Screen
+	if err := r.redis.GetJSON(ctx, redis.ScreenKey(screenID), &screen); err != nil {
+		if err == redislib.Nil {
+			return nil, fmt.Errorf("screen not found")
+		}
+		return nil, err
+	}
+	return &screen, nil
+}
+
+// ListByUser retrieves the screens of a user
+func (r *ScreenRepository) ListByUser(ctx context.Context, userID string) ([]*model.Screen, error) {
+	ids, err := r.redis.SMembers(ctx, redis.UserScreensKey(userID))
+	if err != nil {
+		return nil, err
+	}
+	return r.getMany(ctx, ids), nil
+}
+
+// ListStreaming retrieves the screens streamed over the WebSocket
+func (r *ScreenRepository) ListStreaming(ctx context.Context) ([]*model.Screen, error) {
+	ids, err := r.redis.SMembers(ctx, redis.StreamingScreensKey())
+	if err != nil {
+		return nil, err
+	}
+	return r.getMany(ctx, ids), nil
+}
+
+func (r *ScreenRepository) getMany(ctx context.Context, ids []string) []*model.Screen {
+	screens := make([]*model.Screen, 0, len(ids))
+	for _, idStr := range ids {
+		id, _ := strconv.ParseInt(idStr, 10, 64)
+		screen, err := r.GetByID(ctx, id)
+		if err == nil {
+			screens = append(screens, screen)
+		}
+	}
+	return screens
+}
+
+// Delete removes a screen and its index entries
+func (r *ScreenRepository) Delete(ctx context.Context, screen *model.Screen) error {
+	if err := r.redis.Del(ctx, redis.ScreenKey(screen.ID)); err != nil {
+		return err
+	}
+	r.redis.SRem(ctx, redis.UserScreensKey(screen.UserID), screen.ID)
+	r.redis.SRem(ctx, redis.StreamingScreensKey(), screen.ID)
+	return nil
+}
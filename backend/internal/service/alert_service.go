@@ -0,0 +1,349 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"tuyul/backend/internal/model"
+	"tuyul/backend/internal/repository"
+	"tuyul/backend/internal/service/market"
+	"tuyul/backend/internal/util"
+	"tuyul/backend/pkg/logger"
+)
+
+// Alerts allowed per user
+const maxAlertsPerUser = 50
+
+// AlertService evaluates the active user alerts on every market update and
+// delivers triggers over the WebSocket
+type AlertService struct {
+	repo                *repository.AlertRepository
+	marketService       *market.MarketDataService
+	notificationService *NotificationService
+	log                 *logger.Logger
+
+	// Active alerts, key: alert ID.
+	// mu also serializes alert writes, so triggers never overwrite edits or deletions.
+	alerts map[int64]*alertState
+	mu     sync.Mutex
+}
+
+// alertState is an active alert and its trigger state per pair
+type alertState struct {
+	alert  *model.Alert
+	window time.Duration // Timeframe duration of move and volume_spike alerts
+
+	// Per pair, whether the next evaluation meeting the condition triggers.
+	// Disarmed by a trigger, re-armed when the condition clears.
+	armed map[string]bool
+}
+
+func NewAlertService(repo *repository.AlertRepository, marketService *market.MarketDataService, notificationService *NotificationService) *AlertService {
+	s := &AlertService{
+		repo:                repo,
+		marketService:       marketService,
+		notificationService: notificationService,
+		log:                 logger.GetLogger(),
+		alerts:              make(map[int64]*alertState),
+	}
+	marketService.OnUpdate(s.handleCoinUpdate)
+	return s
+}
+
+// LoadAlerts starts evaluating the stored active alerts
+func (s *AlertService) LoadAlerts(ctx context.Context) error {
+	alerts, err := s.repo.ListActive(ctx)
+	if err != nil {
+		return err
+	}
+	for _, alert := range alerts {
+		s.track(alert)
+	}
+	s.log.Infof("Loaded %d active alerts", len(alerts))
+	return nil
+}
+
+// CreateAlert creates an alert for a user
+func (s *AlertService) CreateAlert(ctx context.Context, userID string, req *model.AlertRequest) (*model.Alert, error) {
+	existing, err := s.repo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) >= maxAlertsPerUser {
+		return nil, util.ErrBadRequest(fmt.Sprintf("A user can have at most %d alerts", maxAlertsPerUser))
+	}
+
+	alert := &model.Alert{UserID: userID, Active: true}
+	if err := s.applyRequest(alert, req); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Create(ctx, alert); err != nil {
+		return nil, err
+	}
+	s.track(alert)
+	return alert, nil
+}
+
+// ListAlerts returns the alerts of a user
+func (s *AlertService) ListAlerts(ctx context.Context, userID string) ([]*model.Alert, error) {
+	return s.repo.ListByUser(ctx, userID)
+}
+
+// GetAlert returns an alert owned by the user
+func (s *AlertService) GetAlert(ctx context.Context, userID string, alertID int64) (*model.Alert, error) {
+	alert, err := s.repo.GetByID(ctx, alertID)
+	if err != nil {
+		return nil, util.ErrNotFound("Alert not found")
+	}
+	if alert.UserID != userID {
+		return nil, util.ErrForbidden("Access denied")
+	}
+	return alert, nil
+}
+
+// UpdateAlert replaces an alert, resetting its trigger state
+func (s *AlertService) UpdateAlert(ctx context.Context, userID string, alertID int64, req *model.AlertRequest) (*model.Alert, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	alert, err := s.GetAlert(ctx, userID, alertID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.applyRequest(alert, req); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Update(ctx, alert); err != nil {
+		return nil, err
+	}
+	s.trackLocked(alert)
+	return alert, nil
+}
+
+// DeleteAlert removes an alert
+func (s *AlertService) DeleteAlert(ctx context.Context, userID string, alertID int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	alert, err := s.GetAlert(ctx, userID, alertID)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.Delete(ctx, alert); err != nil {
+		return err
+	}
+	delete(s.alerts, alert.ID)
+	return nil
+}
+
+// applyRequest validates a request and copies it onto an alert
+func (s *AlertService) applyRequest(alert *model.Alert, req *model.AlertRequest) error {
+	pair := model.NormalizePairID(strings.TrimSpace(req.Pair))
+	if pair != "" {
+		if _, ok := s.marketService.GetPairInfo(pair); !ok {
+			return util.ErrBadRequest(fmt.Sprintf("pair %s does not exist", pair))
+		}
+	}
+
+	timeframe := ""
+	switch req.Type {
+	case model.AlertTypePriceAbove, model.AlertTypePriceBelow:
+		if pair == "" {
+			return util.ErrBadRequest("price alerts need a pair")
+		}
+		if req.Threshold <= 0 {
+			return util.ErrBadRequest("threshold must be a price greater than 0")
+		}
+	case model.AlertTypeMove, model.AlertTypeVolumeSpike:
+		timeframe = req.Timeframe
+		if timeframe == "" {
+			timeframe = s.marketService.ShortestTimeframe()
+		}
+		if !s.marketService.HasTimeframe(timeframe) {
+			return util.ErrBadRequest(fmt.Sprintf("timeframe %s is not a tracked timeframe", timeframe))
+		}
+		if req.Type == model.AlertTypeMove && req.Threshold == 0 {
+			return util.ErrBadRequest("threshold must be a non-zero percentage (negative for drops)")
+		}
+		if req.Type == model.AlertTypeVolumeSpike && req.Threshold <= 0 {
+			return util.ErrBadRequest("threshold must be a volume multiple greater than 0")
+		}
+	default:
+		if req.Threshold <= 0 {
+			return util.ErrBadRequest("threshold must be greater than 0")
+		}
+	}
+
+	alert.Pair = pair
+	alert.Type = req.Type
+	alert.Threshold = req.Threshold
+	alert.Timeframe = timeframe
+	alert.Mode = req.Mode
+	if alert.Mode == "" {
+		alert.Mode = model.AlertModeOnce
+	}
+	alert.Cooldown = req.Cooldown
+	alert.Note = req.Note
+	if req.Active != nil {
+		alert.Active = *req.Active
+	}
+	return nil
+}
+
+// track starts or stops evaluating an alert with a fresh trigger state
+func (s *AlertService) track(alert *model.Alert) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.trackLocked(alert)
+}
+
+// trackLocked is track for callers holding s.mu
+func (s *AlertService) trackLocked(alert *model.Alert) {
+	if !alert.Active {
+		delete(s.alerts, alert.ID)
+		return
+	}
+
+	// Keep a copy, the caller's alert is returned to the API
+	a := *alert
+	state := &alertState{alert: &a, armed: make(map[string]bool)}
+	for _, tf := range s.marketService.Timeframes() {
+		if tf.Name == a.Timeframe {
+			state.window = tf.Duration
+		}
+	}
+	s.alerts[a.ID] = state
+}
+
+// evaluate returns the watched value of a coin and whether the alert condition is met.
+// ok is false when the value is not available yet.
+func (st *alertState) evaluate(coin *model.Coin) (value float64, met bool, ok bool) {
+	a := st.alert
+	switch a.Type {
+	case model.AlertTypePriceAbove:
+		return coin.CurrentPrice, coin.CurrentPrice >= a.Threshold, coin.CurrentPrice > 0
+	case model.AlertTypePriceBelow:
+		return coin.CurrentPrice, coin.CurrentPrice <= a.Threshold, coin.CurrentPrice > 0
+	case model.AlertTypeMove:
+		w, found := coin.Windows[a.Timeframe]
+		if !found || w.Open <= 0 {
+			return 0, false, false
+		}
+		if a.Threshold < 0 {
+			return w.ChangePct, w.ChangePct <= a.Threshold, true
+		}
+		return w.ChangePct, w.ChangePct >= a.Threshold, true
+	case model.AlertTypePumpScore:
+		return coin.PumpScore, coin.PumpScore >= a.Threshold, true
+	case model.AlertTypeGap:
+		return coin.GapPercentage, coin.GapPercentage >= a.Threshold, true
+	case model.AlertTypeVolumeSpike:
+		w, found := coin.Windows[a.Timeframe]
+		pace := coin.VolumeIDR * st.window.Hours() / 24
+		if !found || pace <= 0 {
+			return 0, false, false
+		}
+		ratio := (w.BuyVolume + w.SellVolume) / pace
+		return ratio, ratio >= a.Threshold, true
+	}
+	return 0, false, false
+}
+
+// handleCoinUpdate evaluates the active alerts watching a coin
+func (s *AlertService) handleCoinUpdate(coin *model.Coin) {
+	now := time.Now()
+	var triggered []model.Alert
+	var payloads []model.WSAlertPayload
+
+	s.mu.Lock()
+	for id, st := range s.alerts {
+		a := st.alert
+		if a.Pair != "" && a.Pair != coin.PairID {
+			continue
+		}
+		value, met, ok := st.evaluate(coin)
+		if !ok {
+			continue
+		}
+
+		armed, seen := st.armed[coin.PairID]
+		if !seen {
+			// Price alerts fire on a cross, so they arm on the side opposite to the level.
+			// Signal alerts fire right away if their condition already holds.
+			armed = !met || (a.Type != model.AlertTypePriceAbove && a.Type != model.AlertTypePriceBelow)
+		}
+		if !met {
+			st.armed[coin.PairID] = true
+			continue
+		}
+		if !armed {
+			st.armed[coin.PairID] = false
+			continue
+		}
+		if a.LastTriggeredAt != nil && now.Sub(*a.LastTriggeredAt) < time.Duration(a.Cooldown)*time.Second {
+			// Still cooling down, the condition has to clear again before the next trigger
+			st.armed[coin.PairID] = false
+			continue
+		}
+
+		// Trigger
+		st.armed[coin.PairID] = false
+		triggeredAt := now
+		a.TriggerCount++
+		a.LastTriggeredAt = &triggeredAt
+		if a.Mode == model.AlertModeOnce {
+			a.Active = false
+			delete(s.alerts, id)
+		}
+		triggered = append(triggered, *a)
+		payloads = append(payloads, model.WSAlertPayload{
+			AlertID:     a.ID,
+			Pair:        coin.PairID,
+			Type:        a.Type,
+			Threshold:   a.Threshold,
+			Value:       value,
+			Price:       coin.CurrentPrice,
+			Message:     alertMessage(a, coin.PairID, value),
+			Note:        a.Note,
+			Repeating:   a.Mode == model.AlertModeRepeat,
+			TriggeredAt: now,
+		})
+	}
+
+	// Saved before releasing the lock, edits and deletions can't be overwritten
+	ctx := context.Background()
+	for i := range triggered {
+		if err := s.repo.Update(ctx, &triggered[i]); err != nil {
+			s.log.Errorf("Failed to save triggered alert %d: %v", triggered[i].ID, err)
+		}
+	}
+	s.mu.Unlock()
+
+	for i := range triggered {
+		s.notificationService.NotifyAlert(ctx, triggered[i].UserID, payloads[i])
+		s.log.Debugf("Alert %d triggered for %s: %s", triggered[i].ID, payloads[i].Pair, payloads[i].Message)
+	}
+}
+
+// alertMessage describes a trigger for the user
+func alertMessage(a *model.Alert, pair string, value float64) string {
+	pair = strings.ToUpper(pair)
+	switch a.Type {
+	case model.AlertTypePriceAbove:
+		return fmt.Sprintf("%s price crossed above %.8g (now %.8g)", pair, a.Threshold, value)
+	case model.AlertTypePriceBelow:
+		return fmt.Sprintf("%s price crossed below %.8g (now %.8g)", pair, a.Threshold, value)
+	case model.AlertTypeMove:
+		return fmt.Sprintf("%s moved %.2f%% over %s", pair, value, a.Timeframe)
+	case model.AlertTypePumpScore:
+		return fmt.Sprintf("%s pump score %.2f reached %.2f", pair, value, a.Threshold)
+	case model.AlertTypeGap:
+		return fmt.Sprintf("%s gap %.2f%% reached %.2f%%", pair, value, a.Threshold)
+	case model.AlertTypeVolumeSpike:
+		return fmt.Sprintf("%s traded %.1fx its usual volume over %s", pair, value, a.Timeframe)
+	}
+	return pair
+}
@@ -3,14 +3,15 @@ package service
 import (
 	"context"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 
+	"tuyul/backend/internal/exchange"
 	"tuyul/backend/internal/model"
 	"tuyul/backend/internal/repository"
 	"tuyul/backend/internal/util"
 	"tuyul/backend/pkg/crypto"
-	"tuyul/backend/pkg/indodax"
 	"tuyul/backend/pkg/logger"
 )
 
@@ -18,7 +19,8 @@ import (
 type APIKeyService struct {
 	apiKeyRepo          *repository.APIKeyRepository
 	userRepo            *repository.UserRepository
-	indodaxClient       *indodax.Client
+	botRepo             *repository.BotRepository
+	exchange            exchange.Exchange
 	notificationService *NotificationService
 	orderMonitor        *OrderMonitor // For subscribing to order updates when API key is created/updated
 	encryptionKey       string
@@ -28,14 +30,16 @@ type APIKeyService struct {
 func NewAPIKeyService(
 	apiKeyRepo *repository.APIKeyRepository,
 	userRepo *repository.UserRepository,
-	indodaxClient *indodax.Client,
+	botRepo *repository.BotRepository,
+	ex exchange.Exchange,
 	notificationService *NotificationService,
 	encryptionKey string,
 ) *APIKeyService {
 	return &APIKeyService{
 		apiKeyRepo:          apiKeyRepo,
 		userRepo:            userRepo,
-		indodaxClient:       indodaxClient,
+		botRepo:             botRepo,
+		exchange:            ex,
 		notificationService: notificationService,
 		orderMonitor:        nil, // Will be set via SetOrderMonitor
 		encryptionKey:       encryptionKey,
@@ -47,7 +51,8 @@ func (s *APIKeyService) SetOrderMonitor(orderMonitor *OrderMonitor) {
 	s.orderMonitor = orderMonitor
 }
 
-// Create creates or updates an API key with validation
+// Create adds an API key with validation. Registering a key the user already
+// has updates it instead. The first key of a user becomes the default.
 func (s *APIKeyService) Create(ctx context.Context, userID string, req *model.APIKeyRequest) (*model.APIKeyResponse, error) {
 	// Trim whitespace from key and secret (common issue from copy-paste)
 	key := strings.TrimSpace(req.Key)
@@ -75,11 +80,10 @@ func (s *APIKeyService) Create(ctx context.Context, userID string, req *model.AP
 	log.Infof("Validating API key for user %s: key=%s (len=%d), secret=%s (len=%d)", userID, maskString(key), len(key), maskString(secret), len(secret))
 
 	// Validate API key with Indodax
-	isValid, err := s.indodaxClient.ValidateAPIKey(key, secret)
+	isValid, err := s.exchange.ValidateCredentials(ctx, key, secret)
 	if err != nil {
 		log.Errorf("API key validation failed for user %s: %v", userID, err)
-		return nil, util.NewAppErrorWithDetails(400, util.ErrCodeIndodaxAPI,
-			"Failed to validate API key", err.Error())
+		return nil, util.NewExchangeAppError("Failed to validate API key", err)
 	}
 
 	if !isValid {
@@ -87,6 +91,17 @@ func (s *APIKeyService) Create(ctx context.Context, userID string, req *model.AP
 			"Invalid API key or secret. Please check your credentials from Indodax.")
 	}
 
+	// Detect permissions and refuse keys that can withdraw, so stored credentials can never move funds
+	permissions, err := s.detectPermissions(ctx, key, secret)
+	if err != nil {
+		log.Errorf("API key permission check failed for user %s: %v", userID, err)
+		return nil, util.NewExchangeAppError("Failed to check API key permissions", err)
+	}
+	if hasScope(permissions, model.APIKeyPermissionWithdraw) {
+		log.Warnf("Refused API key with withdraw permission for user %s", userID)
+		return nil, errWithdrawPermission()
+	}
+
 	// Encrypt API key and secret (use trimmed values)
 	encryptedKey, err := crypto.Encrypt(key, s.encryptionKey)
 	if err != nil {
@@ -99,21 +114,41 @@ func (s *APIKeyService) Create(ctx context.Context, userID string, req *model.AP
 	}
 
 	// Create API key
+	userKeys, err := s.apiKeyRepo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, util.ErrInternalServer("Failed to load API keys")
+	}
+
 	now := time.Now()
 	apiKey := &model.APIKey{
 		UserID:          userID,
+		Label:           strings.TrimSpace(req.Label),
+		IsDefault:       len(userKeys) == 0,
 		EncryptedKey:    encryptedKey,
 		EncryptedSecret: encryptedSecret,
 		IsValid:         true,
+		Permissions:     permissions,
 		LastValidatedAt: &now,
 		CreatedAt:       now,
 		UpdatedAt:       now,
 	}
 
-	// Check if user already has an API key
-	existingKey, _ := s.apiKeyRepo.Get(ctx, userID)
-	if existingKey != nil {
+	// Check if the user already registered this key
+	for _, existingKey := range userKeys {
+		existing, err := crypto.Decrypt(existingKey.EncryptedKey, s.encryptionKey)
+		if err != nil || existing != key {
+			continue
+		}
+		apiKey.ID = existingKey.ID
+		apiKey.IsDefault = existingKey.IsDefault
 		apiKey.CreatedAt = existingKey.CreatedAt // Keep original creation time
+		if apiKey.Label == "" {
+			apiKey.Label = existingKey.Label
+		}
+		break
+	}
+	if apiKey.Label == "" {
+		apiKey.Label = fmt.Sprintf("Key %d", len(userKeys)+1)
 	}
 
 	// Save API key
@@ -133,12 +168,12 @@ func (s *APIKeyService) Create(ctx context.Context, userID string, req *model.AP
 			subscribeCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 			defer cancel()
 			
-			if err := s.orderMonitor.SubscribeUserOrders(subscribeCtx, userID); err != nil {
+			if err := s.orderMonitor.SubscribeKeyOrders(subscribeCtx, userID, apiKey.ID); err != nil {
 				log := logger.GetLogger()
-				log.Warnf("Failed to subscribe user %s to order updates after API key creation: %v", userID, err)
+				log.Warnf("Failed to subscribe API key %d of user %s to order updates after creation: %v", apiKey.ID, userID, err)
 			} else {
 				log := logger.GetLogger()
-				log.Infof("Subscribed user %s to order updates after API key creation", userID)
+				log.Infof("Subscribed API key %d of user %s to order updates after creation", apiKey.ID, userID)
 			}
 		}()
 	}
@@ -146,23 +181,112 @@ func (s *APIKeyService) Create(ctx context.Context, userID string, req *model.AP
 	return apiKey.ToResponse(), nil
 }
 
-// Get gets the API key status (without exposing credentials)
+// Get gets the default API key status (without exposing credentials)
 func (s *APIKeyService) Get(ctx context.Context, userID string) (*model.APIKeyResponse, error) {
-	apiKey, err := s.apiKeyRepo.Get(ctx, userID)
+	apiKey, err := s.apiKeyRepo.GetDefault(ctx, userID)
+	if err != nil {
+		return nil, util.ErrAPIKeyNotFound()
+	}
+
+	return apiKey.ToResponse(), nil
+}
+
+// GetByID gets the status of one API key of the user
+func (s *APIKeyService) GetByID(ctx context.Context, userID string, keyID int64) (*model.APIKeyResponse, error) {
+	apiKey, err := s.apiKeyRepo.Get(ctx, userID, keyID)
 	if err != nil {
-		return nil, util.ErrNotFound("API key not found")
+		return nil, util.ErrAPIKeyNotFound()
 	}
 
 	return apiKey.ToResponse(), nil
 }
 
-// GetDecrypted gets decrypted API credentials (for internal use only)
+// List lists the API keys of the user, oldest first
+func (s *APIKeyService) List(ctx context.Context, userID string) ([]*model.APIKeyResponse, error) {
+	apiKeys, err := s.apiKeyRepo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, util.ErrInternalServer("Failed to load API keys")
+	}
+
+	resp := make([]*model.APIKeyResponse, 0, len(apiKeys))
+	for _, apiKey := range apiKeys {
+		resp = append(resp, apiKey.ToResponse())
+	}
+	return resp, nil
+}
+
+// GetDecrypted gets decrypted credentials of the default API key (for internal use only)
 func (s *APIKeyService) GetDecrypted(ctx context.Context, userID string) (*model.DecryptedAPIKey, error) {
-	apiKey, err := s.apiKeyRepo.Get(ctx, userID)
+	apiKey, err := s.apiKeyRepo.GetDefault(ctx, userID)
+	if err != nil {
+		return nil, util.ErrAPIKeyNotFound()
+	}
+
+	return s.decrypt(userID, apiKey)
+}
+
+// GetDecryptedByID gets decrypted credentials of one API key of the user (for internal use only)
+func (s *APIKeyService) GetDecryptedByID(ctx context.Context, userID string, keyID int64) (*model.DecryptedAPIKey, error) {
+	apiKey, err := s.apiKeyRepo.Get(ctx, userID, keyID)
 	if err != nil {
-		return nil, util.ErrNotFound("API key not found")
+		return nil, util.ErrAPIKeyNotFound()
+	}
+
+	return s.decrypt(userID, apiKey)
+}
+
+// ResolveDecrypted gets decrypted credentials of the chosen API key,
+// or of the default key when none is chosen (nil or 0)
+func (s *APIKeyService) ResolveDecrypted(ctx context.Context, userID string, keyID *int64) (*model.DecryptedAPIKey, error) {
+	if keyID == nil || *keyID == 0 {
+		return s.GetDecrypted(ctx, userID)
 	}
+	return s.GetDecryptedByID(ctx, userID, *keyID)
+}
 
+// detectPermissions returns the scopes of credentials, nil if the exchange cannot report them
+func (s *APIKeyService) detectPermissions(ctx context.Context, key, secret string) ([]string, error) {
+	inspector, ok := s.exchange.(exchange.PermissionInspector)
+	if !ok {
+		return nil, nil
+	}
+
+	perms, err := inspector.GetKeyPermissions(ctx, key, secret)
+	if err != nil {
+		return nil, err
+	}
+
+	scopes := []string{}
+	if perms.View {
+		scopes = append(scopes, model.APIKeyPermissionView)
+	}
+	if perms.Trade {
+		scopes = append(scopes, model.APIKeyPermissionTrade)
+	}
+	if perms.Withdraw {
+		scopes = append(scopes, model.APIKeyPermissionWithdraw)
+	}
+	return scopes, nil
+}
+
+// hasScope reports whether a permission list contains a scope
+func hasScope(permissions []string, scope string) bool {
+	for _, p := range permissions {
+		if p == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// errWithdrawPermission is returned for keys that are able to withdraw funds
+func errWithdrawPermission() *util.AppError {
+	return util.NewAppError(400, util.ErrCodeAPIKeyWithdraw,
+		"This API key has withdraw permission. For your safety, create a new key on Indodax with only view and trade permissions.")
+}
+
+// decrypt decrypts the credentials of a stored API key
+func (s *APIKeyService) decrypt(userID string, apiKey *model.APIKey) (*model.DecryptedAPIKey, error) {
 	if !apiKey.IsValid {
 		return nil, util.NewAppError(400, util.ErrCodeAPIKeyInvalid, "API key is invalid")
 	}
@@ -218,45 +342,132 @@ func (s *APIKeyService) GetDecrypted(ctx context.Context, userID string) (*model
 	log.Infof("Successfully decrypted API key for user %s: key=%s (len=%d), secret=%s (len=%d)", userID, maskString(key), len(key), maskString(secret), len(secret))
 
 	return &model.DecryptedAPIKey{
+		ID:     apiKey.ID,
 		Key:    key,
 		Secret: secret,
 	}, nil
 }
 
-// Delete deletes an API key
+// Delete deletes the default API key
 func (s *APIKeyService) Delete(ctx context.Context, userID string) error {
+	apiKey, err := s.apiKeyRepo.GetDefault(ctx, userID)
+	if err != nil {
+		return util.ErrAPIKeyNotFound()
+	}
+
+	return s.DeleteByID(ctx, userID, apiKey.ID)
+}
+
+// DeleteByID deletes one API key of the user.
+// Keys used by running live bots cannot be deleted.
+func (s *APIKeyService) DeleteByID(ctx context.Context, userID string, keyID int64) error {
 	// Check if API key exists
-	_, err := s.apiKeyRepo.Get(ctx, userID)
+	apiKey, err := s.apiKeyRepo.Get(ctx, userID, keyID)
+	if err != nil {
+		return util.ErrAPIKeyNotFound()
+	}
+
+	// Check running bots bound to this key
+	bots, err := s.botRepo.ListByUser(ctx, userID)
 	if err != nil {
-		return util.ErrNotFound("API key not found")
+		return util.ErrInternalServer("Failed to check bots using the API key")
+	}
+	for _, bot := range bots {
+		if bot.IsPaperTrading || bot.Status != model.BotStatusRunning {
+			continue
+		}
+		// Bots without a binding trade with the default key
+		if bot.BoundAPIKeyID() == keyID || (bot.APIKeyID == nil && apiKey.IsDefault) {
+			return util.ErrConflict(fmt.Sprintf("API key is used by running bot %q. Stop the bot first.", bot.Name))
+		}
 	}
 
 	// Delete API key
-	if err := s.apiKeyRepo.Delete(ctx, userID); err != nil {
+	if err := s.apiKeyRepo.Delete(ctx, userID, keyID); err != nil {
 		return util.ErrInternalServer("Failed to delete API key")
 	}
 
+	// Unsubscribe from order updates for this key (if orderMonitor is available)
+	log := logger.GetLogger()
+	if s.orderMonitor != nil {
+		s.orderMonitor.UnsubscribeKeyOrders(keyID)
+		log.Infof("Unsubscribed API key %d of user %s from order updates after deletion", keyID, userID)
+	}
+
+	remaining, err := s.apiKeyRepo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil
+	}
+
 	// Update user's API key status
-	if err := s.userRepo.UpdateAPIKeyStatus(ctx, userID, false); err != nil {
-		// Log error but don't fail
+	if len(remaining) == 0 {
+		if err := s.userRepo.UpdateAPIKeyStatus(ctx, userID, false); err != nil {
+			// Log error but don't fail
+		}
+		return nil
 	}
 
-	// Unsubscribe from order updates for this user (if orderMonitor is available)
-	if s.orderMonitor != nil {
-		s.orderMonitor.UnsubscribeUserOrders(userID)
-		log := logger.GetLogger()
-		log.Infof("Unsubscribed user %s from order updates after API key deletion", userID)
+	// Promote the oldest remaining key if the default was deleted
+	if apiKey.IsDefault {
+		remaining[0].IsDefault = true
+		remaining[0].UpdatedAt = time.Now()
+		if err := s.apiKeyRepo.Update(ctx, remaining[0]); err != nil {
+			log.Warnf("Failed to promote API key %d to default for user %s: %v", remaining[0].ID, userID, err)
+		}
 	}
 
 	return nil
 }
 
-// ValidateAndUpdate validates the existing API key with Indodax and updates status
+// SetDefault makes an API key the default of the user
+func (s *APIKeyService) SetDefault(ctx context.Context, userID string, keyID int64) (*model.APIKeyResponse, error) {
+	apiKeys, err := s.apiKeyRepo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, util.ErrInternalServer("Failed to load API keys")
+	}
+
+	var result *model.APIKey
+	for _, apiKey := range apiKeys {
+		if apiKey.ID == keyID {
+			result = apiKey
+		}
+	}
+	if result == nil {
+		return nil, util.ErrAPIKeyNotFound()
+	}
+
+	now := time.Now()
+	for _, apiKey := range apiKeys {
+		isDefault := apiKey.ID == keyID
+		if apiKey.IsDefault == isDefault {
+			continue
+		}
+		apiKey.IsDefault = isDefault
+		apiKey.UpdatedAt = now
+		if err := s.apiKeyRepo.Update(ctx, apiKey); err != nil {
+			return nil, util.ErrInternalServer("Failed to update API key")
+		}
+	}
+
+	return result.ToResponse(), nil
+}
+
+// ValidateAndUpdate validates the default API key with Indodax and updates status
 func (s *APIKeyService) ValidateAndUpdate(ctx context.Context, userID string) (*model.APIKeyResponse, error) {
+	apiKey, err := s.apiKeyRepo.GetDefault(ctx, userID)
+	if err != nil {
+		return nil, util.ErrAPIKeyNotFound()
+	}
+
+	return s.ValidateByID(ctx, userID, apiKey.ID)
+}
+
+// ValidateByID validates one API key of the user with Indodax and updates status
+func (s *APIKeyService) ValidateByID(ctx context.Context, userID string, keyID int64) (*model.APIKeyResponse, error) {
 	// Get API key
-	apiKey, err := s.apiKeyRepo.Get(ctx, userID)
+	apiKey, err := s.apiKeyRepo.Get(ctx, userID, keyID)
 	if err != nil {
-		return nil, util.ErrNotFound("API key not found")
+		return nil, util.ErrAPIKeyNotFound()
 	}
 
 	// Validate encryption key length
@@ -279,15 +490,27 @@ func (s *APIKeyService) ValidateAndUpdate(ctx context.Context, userID string) (*
 	}
 
 	// Validate with Indodax
-	isValid, err := s.indodaxClient.ValidateAPIKey(key, secret)
+	isValid, err := s.exchange.ValidateCredentials(ctx, key, secret)
 	if err != nil {
-		return nil, util.NewAppErrorWithDetails(400, util.ErrCodeIndodaxAPI,
-			"Failed to validate API key", err.Error())
+		return nil, util.NewExchangeAppError("Failed to validate API key", err)
+	}
+
+	// Re-detect permissions (keys stored before detection have none)
+	var permissions []string
+	if isValid {
+		permissions, err = s.detectPermissions(ctx, key, secret)
+		if err != nil {
+			return nil, util.NewExchangeAppError("Failed to check API key permissions", err)
+		}
 	}
+	canWithdraw := hasScope(permissions, model.APIKeyPermissionWithdraw)
 
-	// Update validation status
+	// Update validation status (keys that can withdraw are disabled)
 	now := time.Now()
-	apiKey.IsValid = isValid
+	apiKey.IsValid = isValid && !canWithdraw
+	if permissions != nil {
+		apiKey.Permissions = permissions
+	}
 	apiKey.LastValidatedAt = &now
 	apiKey.UpdatedAt = now
 
@@ -295,6 +518,14 @@ func (s *APIKeyService) ValidateAndUpdate(ctx context.Context, userID string) (*
 		return nil, util.ErrInternalServer("Failed to update API key status")
 	}
 
+	if canWithdraw {
+		logger.GetLogger().Errorf("API key %d of user %s has withdraw permission, key disabled", keyID, userID)
+		if s.orderMonitor != nil {
+			s.orderMonitor.UnsubscribeKeyOrders(keyID)
+		}
+		return nil, errWithdrawPermission()
+	}
+
 	// Subscribe to order updates for this user (if orderMonitor is available and API key is valid)
 	if s.orderMonitor != nil && apiKey.IsValid {
 		go func() {
@@ -302,12 +533,12 @@ func (s *APIKeyService) ValidateAndUpdate(ctx context.Context, userID string) (*
 			subscribeCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 			defer cancel()
 			
-			if err := s.orderMonitor.SubscribeUserOrders(subscribeCtx, userID); err != nil {
+			if err := s.orderMonitor.SubscribeKeyOrders(subscribeCtx, userID, keyID); err != nil {
 				log := logger.GetLogger()
-				log.Warnf("Failed to subscribe user %s to order updates after API key update: %v", userID, err)
+				log.Warnf("Failed to subscribe API key %d of user %s to order updates after validation: %v", keyID, userID, err)
 			} else {
 				log := logger.GetLogger()
-				log.Infof("Subscribed user %s to order updates after API key update", userID)
+				log.Infof("Subscribed API key %d of user %s to order updates after validation", keyID, userID)
 			}
 		}()
 	}
@@ -315,52 +546,137 @@ func (s *APIKeyService) ValidateAndUpdate(ctx context.Context, userID string) (*
 	return apiKey.ToResponse(), nil
 }
 
-// filterNonZeroBalances filters out zero balances from a map
-func filterNonZeroBalances(balances map[string]indodax.BalanceValue) map[string]indodax.BalanceValue {
-	filtered := make(map[string]indodax.BalanceValue)
+// formatNonZeroBalances filters out zero balances and formats the rest as strings
+func formatNonZeroBalances(balances map[string]float64) map[string]string {
+	filtered := make(map[string]string)
 	for k, v := range balances {
-		// Parse the balance value to check if it's non-zero
-		balanceStr := v.String()
-		var balance float64
-		if _, err := fmt.Sscanf(balanceStr, "%f", &balance); err == nil {
-			if balance > 0 {
-				filtered[k] = v
-			}
-		} else {
-			// If parsing fails, include it (might be a special format)
-			filtered[k] = v
+		if v > 0 {
+			filtered[k] = strconv.FormatFloat(v, 'f', -1, 64)
 		}
 	}
 	return filtered
 }
 
-// GetAccountInfo gets account information from Indodax
-func (s *APIKeyService) GetAccountInfo(ctx context.Context, userID string) (*indodax.GetInfoReturn, error) {
+// GetAccountInfo gets account information of an API key from the exchange (default key if keyID is nil)
+func (s *APIKeyService) GetAccountInfo(ctx context.Context, userID string, keyID *int64) (*model.AccountInfoResponse, error) {
 	// Get decrypted credentials
-	credentials, err := s.GetDecrypted(ctx, userID)
+	credentials, err := s.ResolveDecrypted(ctx, userID, keyID)
 	if err != nil {
 		return nil, err
 	}
 
-	// Get account info from Indodax
-	info, err := s.indodaxClient.GetInfo(ctx, credentials.Key, credentials.Secret)
+	// Get account info from the exchange
+	info, err := s.exchange.NewTrader(credentials.Key, credentials.Secret).GetInfo(ctx)
 	if err != nil {
-		return nil, util.NewAppErrorWithDetails(400, util.ErrCodeIndodaxAPI,
-			"Failed to get account info", err.Error())
+		return nil, util.NewExchangeAppError("Failed to get account info", err)
 	}
 
 	// Filter out zero balances
-	info.Balance = filterNonZeroBalances(info.Balance)
-	info.BalanceHold = filterNonZeroBalances(info.BalanceHold)
+	resp := &model.AccountInfoResponse{
+		ServerTime:  info.ServerTime,
+		Balance:     formatNonZeroBalances(info.Balances),
+		BalanceHold: formatNonZeroBalances(info.Held),
+		UserID:      info.UserID,
+		Name:        info.Name,
+		Email:       info.Email,
+	}
+
+	// Notify via WebSocket (only non-zero)
+	s.notificationService.NotifyUser(ctx, userID, model.MessageTypeBalanceUpdate, resp.Balance)
+
+	return resp, nil
+}
+
+// MigrateLegacyKeys moves single per-user keys of older versions to the multi-key
+// layout and binds the user's live bots to the migrated key
+func (s *APIKeyService) MigrateLegacyKeys(ctx context.Context) error {
+	migrated, err := s.apiKeyRepo.MigrateLegacyKeys(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to migrate API keys: %w", err)
+	}
+	if len(migrated) == 0 {
+		return nil
+	}
 
-	// Notify via WebSocket - convert BalanceValue map to string map (only non-zero)
-	balanceMap := make(map[string]string)
-	for k, v := range info.Balance {
-		balanceMap[k] = v.String()
+	log := logger.GetLogger()
+	for userID, keyID := range migrated {
+		bots, err := s.botRepo.ListByUser(ctx, userID)
+		if err != nil {
+			log.Warnf("Failed to list bots of user %s for API key migration: %v", userID, err)
+			continue
+		}
+		for _, bot := range bots {
+			if bot.IsPaperTrading || bot.APIKeyID != nil {
+				continue
+			}
+			id := keyID
+			bot.APIKeyID = &id
+			if err := s.botRepo.Update(ctx, bot, ""); err != nil {
+				log.Warnf("Failed to bind bot %d to API key %d: %v", bot.ID, keyID, err)
+			}
+		}
 	}
-	s.notificationService.NotifyUser(ctx, userID, model.MessageTypeBalanceUpdate, balanceMap)
 
-	return info, nil
+	log.Infof("Migrated %d API keys to multi-key storage", len(migrated))
+	return nil
 }
 
+// CheckStoredKeyPermissions detects the permissions of valid keys stored before permission
+// detection. Keys that can withdraw or that the exchange rejects are disabled, keys that
+// cannot be checked right now are left unchanged and checked again on the next start.
+func (s *APIKeyService) CheckStoredKeyPermissions(ctx context.Context) error {
+	apiKeys, err := s.apiKeyRepo.ListAll(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list API keys: %w", err)
+	}
+
+	log := logger.GetLogger()
+	checked := 0
+	for _, apiKey := range apiKeys {
+		if !apiKey.IsValid || apiKey.Permissions != nil {
+			continue
+		}
+
+		credentials, err := s.decrypt(apiKey.UserID, apiKey)
+		if err != nil {
+			log.Warnf("API key %d of user %s: failed to decrypt for the permission check: %v", apiKey.ID, apiKey.UserID, err)
+			continue
+		}
+		permissions, err := s.detectPermissions(ctx, credentials.Key, credentials.Secret)
+		if err != nil && !util.IsAPIKeyError(err) {
+			// Transient or unknown failure, don't disable keys over an exchange outage
+			log.Warnf("API key %d of user %s: failed to check permissions, will retry on next start: %v", apiKey.ID, apiKey.UserID, err)
+			continue
+		}
+		if err == nil && permissions == nil {
+			// The exchange cannot report permissions
+			continue
+		}
+
+		now := time.Now()
+		switch {
+		case err != nil:
+			log.Warnf("API key %d of user %s rejected by the exchange, disabled until validated again: %v", apiKey.ID, apiKey.UserID, err)
+			apiKey.IsValid = false
+		case hasScope(permissions, model.APIKeyPermissionWithdraw):
+			log.Errorf("API key %d of user %s has withdraw permission, key disabled", apiKey.ID, apiKey.UserID)
+			apiKey.IsValid = false
+			apiKey.Permissions = permissions
+			apiKey.LastValidatedAt = &now
+		default:
+			apiKey.Permissions = permissions
+			apiKey.LastValidatedAt = &now
+		}
+		apiKey.UpdatedAt = now
+
+		if err := s.apiKeyRepo.Update(ctx, apiKey); err != nil {
+			log.Warnf("Failed to save permissions of API key %d: %v", apiKey.ID, err)
+		}
+		checked++
+	}
 
+	if checked > 0 {
+		log.Infof("Checked permissions of %d stored API keys", checked)
+	}
+	return nil
+}
@@ -0,0 +1,222 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"tuyul/backend/internal/model"
+	"tuyul/backend/internal/repository"
+	"tuyul/backend/internal/service/market"
+	"tuyul/backend/internal/util"
+	"tuyul/backend/pkg/logger"
+)
+
+// Backtests a user can have queued or running at once
+const maxActiveBacktestsPerUser = 3
+
+// BacktestService runs the backtests of users as background jobs over the
+// candle store, a limited number at a time
+type BacktestService struct {
+	repo          *repository.BacktestRepository
+	marketService *market.MarketDataService
+	log           *logger.Logger
+
+	slots   chan struct{}                // One token per running job
+	cancels map[int64]context.CancelFunc // Queued and running jobs, key: backtest ID
+	mu      sync.Mutex
+}
+
+func NewBacktestService(repo *repository.BacktestRepository, marketService *market.MarketDataService, maxConcurrent int) *BacktestService {
+	if maxConcurrent <= 0 {
+		maxConcurrent = 1
+	}
+	return &BacktestService{
+		repo:          repo,
+		marketService: marketService,
+		log:           logger.GetLogger(),
+		slots:         make(chan struct{}, maxConcurrent),
+		cancels:       make(map[int64]context.CancelFunc),
+	}
+}
+
+// FailInterrupted marks the backtests a restart interrupted as failed
+func (s *BacktestService) FailInterrupted(ctx context.Context) error {
+	backtests, err := s.repo.ListActive(ctx)
+	if err != nil {
+		return err
+	}
+	for _, backtest := range backtests {
+		markFinished(backtest, nil, fmt.Errorf("interrupted by a server restart"))
+		if err := s.repo.Update(ctx, backtest); err != nil {
+			return err
+		}
+	}
+	if len(backtests) > 0 {
+		s.log.Warnf("Marked %d interrupted backtests as failed", len(backtests))
+	}
+	return nil
+}
+
+// CreateBacktest validates a request and queues the backtest
+func (s *BacktestService) CreateBacktest(ctx context.Context, userID string, req *model.BacktestRequest) (*model.Backtest, error) {
+	runner, err := NewPumpHunterBacktest(req, s.marketService.Timeframes(), s.marketService.ScoreProfiles())
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.repo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	active := 0
+	for _, backtest := range existing {
+		if backtest.Status == model.BacktestStatusQueued || backtest.Status == model.BacktestStatusRunning {
+			active++
+		}
+	}
+	if active >= maxActiveBacktestsPerUser {
+		return nil, util.ErrBadRequest(fmt.Sprintf("A user can have at most %d backtests queued or running", maxActiveBacktestsPerUser))
+	}
+
+	backtest := &model.Backtest{
+		UserID:  userID,
+		Name:    req.Name,
+		Type:    model.BotTypePumpHunter,
+		Status:  model.BacktestStatusQueued,
+		Request: req,
+	}
+	if err := s.repo.Create(ctx, backtest); err != nil {
+		return nil, err
+	}
+
+	jobCtx, cancel := context.WithCancel(context.Background())
+	s.mu.Lock()
+	s.cancels[backtest.ID] = cancel
+	s.mu.Unlock()
+	job := *backtest // The job updates its own copy
+	go s.run(jobCtx, &job, runner)
+
+	return backtest, nil
+}
+
+// ListBacktests returns the backtests of a user without trades and equity curves
+func (s *BacktestService) ListBacktests(ctx context.Context, userID string) ([]*model.Backtest, error) {
+	backtests, err := s.repo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, backtest := range backtests {
+		if backtest.Result != nil {
+			backtest.Result.Trades = nil
+			backtest.Result.Equity = nil
+		}
+	}
+	return backtests, nil
+}
+
+// GetBacktest returns a backtest owned by the user
+func (s *BacktestService) GetBacktest(ctx context.Context, userID string, backtestID int64) (*model.Backtest, error) {
+	backtest, err := s.repo.GetByID(ctx, backtestID)
+	if err != nil {
+		return nil, util.ErrNotFound("Backtest not found")
+	}
+	if backtest.UserID != userID {
+		return nil, util.ErrForbidden("Access denied")
+	}
+	return backtest, nil
+}
+
+// DeleteBacktest cancels a queued or running backtest and removes it
+func (s *BacktestService) DeleteBacktest(ctx context.Context, userID string, backtestID int64) error {
+	backtest, err := s.GetBacktest(ctx, userID, backtestID)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	if cancel, ok := s.cancels[backtest.ID]; ok {
+		cancel()
+		delete(s.cancels, backtest.ID)
+	}
+	s.mu.Unlock()
+
+	return s.repo.Delete(ctx, backtest)
+}
+
+// run waits for a free slot, loads the candles and runs a backtest
+func (s *BacktestService) run(ctx context.Context, backtest *model.Backtest, runner *PumpHunterBacktest) {
+	select {
+	case s.slots <- struct{}{}:
+		defer func() { <-s.slots }()
+	case <-ctx.Done():
+		return // Deleted while queued
+	}
+
+	now := time.Now()
+	backtest.Status = model.BacktestStatusRunning
+	backtest.StartedAt = &now
+	if !s.save(backtest, false) {
+		return
+	}
+	s.log.Infof("Backtest %d started for user %s", backtest.ID, backtest.UserID)
+
+	result, err := s.execute(ctx, backtest.Request, runner)
+	if ctx.Err() != nil {
+		return // Deleted while running
+	}
+	markFinished(backtest, result, err)
+	if err != nil {
+		s.log.Warnf("Backtest %d failed: %v", backtest.ID, err)
+	} else {
+		s.log.Infof("Backtest %d completed: %d trades, return %.2f%%", backtest.ID, result.TotalTrades, result.ReturnPercent)
+	}
+	s.save(backtest, true)
+}
+
+// execute loads the candles of the requested pairs, warm-up included, and runs the backtest
+func (s *BacktestService) execute(ctx context.Context, req *model.BacktestRequest, runner *PumpHunterBacktest) (*model.BacktestResult, error) {
+	tf := runner.BaseTimeframe().Name
+	candles := make(map[string][]model.Candle)
+	for _, pair := range req.BacktestPairs() {
+		series, err := s.marketService.GetCandles(ctx, pair, tf, req.From.Add(-BacktestWarmup), req.To, 0)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load %s candles of %s: %w", tf, pair, err)
+		}
+		candles[pair] = series
+	}
+	return runner.Run(ctx, candles)
+}
+
+// markFinished sets the outcome of a backtest
+func markFinished(backtest *model.Backtest, result *model.BacktestResult, err error) {
+	now := time.Now()
+	backtest.CompletedAt = &now
+	backtest.Result = result
+	if err != nil {
+		backtest.Status = model.BacktestStatusFailed
+		backtest.Error = err.Error()
+		return
+	}
+	backtest.Status = model.BacktestStatusCompleted
+}
+
+// save stores the backtest of a job unless it was deleted meanwhile, done
+// releases the job. Returns false if the backtest was deleted.
+func (s *BacktestService) save(backtest *model.Backtest, done bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cancel, ok := s.cancels[backtest.ID]
+	if !ok {
+		return false
+	}
+	if err := s.repo.Update(context.Background(), backtest); err != nil {
+		s.log.Errorf("Failed to save backtest %d: %v", backtest.ID, err)
+	}
+	if done {
+		cancel()
+		delete(s.cancels, backtest.ID)
+	}
+	return true
+}
@@ -4,42 +4,87 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"sync"
 	"time"
 
+	"tuyul/backend/internal/exchange"
 	"tuyul/backend/internal/model"
 	"tuyul/backend/internal/repository"
 	"tuyul/backend/internal/util"
-	"tuyul/backend/pkg/indodax"
 	"tuyul/backend/pkg/logger"
 )
 
+// Pauses applied by bots when the exchange is unavailable
+const (
+	networkErrorPause = 30 * time.Second
+	maintenancePause  = 2 * time.Minute
+)
+
 // GenerateClientOrderID generates a unique client order ID for a bot order
 func GenerateClientOrderID(botID int64, pair, side string) string {
 	return fmt.Sprintf("bot%d-%s-%s-%d", botID, pair, strings.ToLower(side), time.Now().UnixMilli())
 }
 
-// CreateTradeClient creates a trade client based on trading mode
+// CreateTradeClient creates a trade client based on trading mode.
+// Live bots without a bound key are pinned to the key resolved now, so their orders record it.
 // Returns the trade client and any error
 func CreateTradeClient(
 	ctx context.Context,
-	isPaperTrading bool,
-	balances map[string]float64,
+	bot *model.BotConfig,
 	apiKeyService *APIKeyService,
-	indodaxClient *indodax.Client,
-	userID string,
-	onFilled func(order *model.Order),
+	ex exchange.Exchange,
+	paperExchange *PaperExchange,
+	onUpdate func(update *exchange.OrderUpdate),
 ) (TradeClient, error) {
-	if isPaperTrading {
-		return NewPaperTradeClient(balances, onFilled), nil
+	if bot.IsPaperTrading {
+		return NewPaperTradeClient(paperExchange, bot.UserID, bot.Balances, onUpdate), nil
 	}
 
-	// Get API key for live trading
-	key, err := apiKeyService.GetDecrypted(ctx, userID)
+	// Get API key for live trading (the user's default key if none is bound)
+	key, err := apiKeyService.ResolveDecrypted(ctx, bot.UserID, bot.APIKeyID)
 	if err != nil {
 		return nil, util.ErrBadRequest("Valid API key not found")
 	}
+	bot.APIKeyID = &key.ID
+
+	return ex.NewTrader(key.Key, key.Secret), nil
+}
+
+// resolveBotAPIKeyID returns the API key a live bot binds to (the user's default key if none
+// is requested), nil for paper bots
+func resolveBotAPIKeyID(ctx context.Context, apiKeyService *APIKeyService, userID string, req *model.BotConfigRequest) (*int64, error) {
+	if req.IsPaperTrading {
+		return nil, nil
+	}
+	key, err := apiKeyService.ResolveDecrypted(ctx, userID, req.APIKeyID)
+	if err != nil {
+		return nil, util.NewAppError(400, util.ErrCodeAPIKeyInvalid, "Valid API key is required for live trading. Please add your API key in Settings.")
+	}
+	return &key.ID, nil
+}
+
+// checkDuplicateBot refuses a second bot of a type on the same pair and mode
+func checkDuplicateBot(ctx context.Context, botRepo *repository.BotRepository, userID, botType, label string, req *model.BotConfigRequest, excludeBotID int64) error {
+	exists, err := botRepo.ExistsByTypePairMode(ctx, userID, botType, req.Pair, req.IsPaperTrading, excludeBotID)
+	if err != nil {
+		return err
+	}
+	if exists {
+		modeStr := "paper"
+		if !req.IsPaperTrading {
+			modeStr = "live"
+		}
+		return util.ErrBadRequest(fmt.Sprintf("A %s %s bot for pair %s already exists. Each pair can only have one %s bot per mode (paper/live).", modeStr, label, req.Pair, label))
+	}
+	return nil
+}
 
-	return NewLiveTradeClient(indodaxClient, key.Key, key.Secret), nil
+// baseCurrencyOf derives the base currency from the pair ID (e.g. "btc" in "btcidr")
+func baseCurrencyOf(pair string, pairInfo exchange.Pair) string {
+	if strings.HasSuffix(pair, "idr") {
+		return strings.TrimSuffix(pair, "idr")
+	}
+	return pairInfo.BaseCurrency
 }
 
 // StopBotWithError stops a bot and sets error status (for live bots only)
@@ -95,3 +140,229 @@ func StopBotWithError(
 		}
 	}()
 }
+
+// botInstance is the runtime state of a bot trading from its own event loop (grid and DCA).
+// Only the loop places, cancels and checks orders, so mu guards the state against order
+// updates and is never held across an exchange request.
+type botInstance struct {
+	Config       *model.BotConfig
+	TradeClient  TradeClient
+	StopChan     chan struct{}
+	WakeChan     chan struct{}  // Signalled when the loop should run before the next tick
+	BaseCurrency string         // e.g. "btc" in "btcidr"
+	PairInfo     *exchange.Pair // Cached pair info to avoid repeated lookups
+	PausedUntil  time.Time      // Orders paused until then after the exchange was unavailable
+
+	mu sync.Mutex // Protects Config (state, balances, position) and the bot's orders
+}
+
+// runningBot is a bot instance built on botInstance
+type runningBot interface {
+	instance() *botInstance
+}
+
+func (inst *botInstance) instance() *botInstance {
+	return inst
+}
+
+// stopped reports whether the bot was stopped
+func (inst *botInstance) stopped() bool {
+	select {
+	case <-inst.StopChan:
+		return true
+	default:
+		return false
+	}
+}
+
+// wake makes the bot loop run without waiting for the next tick
+func (inst *botInstance) wake() {
+	select {
+	case inst.WakeChan <- struct{}{}:
+	default:
+	}
+}
+
+// tradable reports whether an order meets the pair's minimums
+func (inst *botInstance) tradable(amount, price float64) bool {
+	return amount > 0 && amount >= inst.PairInfo.MinBaseAmount && amount*price >= inst.PairInfo.MinQuoteAmount
+}
+
+// botRunner holds what the grid and DCA services share to run their bots
+type botRunner struct {
+	name                string // Prefixes log lines and client order IDs, e.g. "Grid"
+	botRepo             *repository.BotRepository
+	orderRepo           *repository.OrderRepository
+	notificationService *NotificationService
+	deadmanService      *DeadmanService
+	liveFeeRate         float64 // Fraction of the notional booked on live fills reported without a fee
+	log                 *logger.Logger
+}
+
+func newBotRunner(
+	name string,
+	botRepo *repository.BotRepository,
+	orderRepo *repository.OrderRepository,
+	notificationService *NotificationService,
+	deadmanService *DeadmanService,
+	liveFeeFallbackPct float64,
+) botRunner {
+	return botRunner{
+		name:                name,
+		botRepo:             botRepo,
+		orderRepo:           orderRepo,
+		notificationService: notificationService,
+		deadmanService:      deadmanService,
+		liveFeeRate:         liveFeeFallbackPct / 100,
+		log:                 logger.GetLogger(),
+	}
+}
+
+// clientOrderID generates a unique client order ID for a bot order, tag tells its orders apart
+func (r *botRunner) clientOrderID(botID int64, tag string) string {
+	return fmt.Sprintf("%s%d-%s-%d", strings.ToLower(r.name), botID, tag, time.Now().UnixMilli())
+}
+
+// markError flags a bot removed from its service as stopped on error, its event loop
+// cancels the orders on exit
+func (r *botRunner) markError(bot runningBot, errorMsg string) {
+	inst := bot.instance()
+	botID := inst.Config.ID
+
+	r.log.Errorf("%s bot %d: Stopping on error: %s", r.name, botID, errorMsg)
+	close(inst.StopChan)
+
+	errMsg := errorMsg
+	if err := r.botRepo.UpdateStatus(context.Background(), botID, model.BotStatusError, &errMsg); err != nil {
+		r.log.Errorf("Failed to update %s bot %d status to error: %v", strings.ToLower(r.name), botID, err)
+	}
+
+	inst.mu.Lock()
+	inst.Config.Status = model.BotStatusError
+	inst.Config.ErrorMessage = &errMsg
+	inst.mu.Unlock()
+}
+
+// handleAPIError handles a failed order request (caller must hold inst.mu). stop is run for
+// errors retrying will not fix. Returns true if placement should stop for this tick.
+func (r *botRunner) handleAPIError(bot runningBot, err error, stop func(errorMsg string)) bool {
+	inst := bot.instance()
+	switch exchange.KindOf(err) {
+	case exchange.ErrorKindRateLimited, exchange.ErrorKindInvalidNonce:
+		r.log.Warnf("%s bot %d: Rate limited - will retry on next tick", r.name, inst.Config.ID)
+		return true
+
+	case exchange.ErrorKindMaintenance, exchange.ErrorKindNetwork:
+		pause := networkErrorPause
+		if exchange.IsKind(err, exchange.ErrorKindMaintenance) {
+			pause = maintenancePause
+		}
+		inst.PausedUntil = time.Now().Add(pause)
+		r.log.Warnf("%s bot %d: Exchange unavailable (%v) - pausing orders for %s", r.name, inst.Config.ID, err, pause)
+		return true
+
+	case exchange.ErrorKindInsufficientBalance:
+		// The exchange account holds less than the bot's allocation
+		r.log.Warnf("%s bot %d: Insufficient balance on exchange - will retry on next tick", r.name, inst.Config.ID)
+		return true
+
+	case exchange.ErrorKindOrderBelowMinimum:
+		// Order sizes are fixed, they will not grow by retrying
+		go stop(fmt.Sprintf("%s order size below exchange minimum: %v", r.name, err))
+		return true
+	}
+
+	// Handle critical trading errors (API key or invalid pair)
+	if util.IsCriticalTradingError(err) {
+		go stop(fmt.Sprintf("Trading error: %v", err))
+		return true
+	}
+
+	return false
+}
+
+// createOrderRecord stores the record of a bot order before it is placed, so its updates
+// find it (caller must hold inst.mu)
+func (r *botRunner) createOrderRecord(ctx context.Context, bot runningBot, orderID, side string, price, amount float64) {
+	inst := bot.instance()
+	order := &model.Order{
+		UserID:       inst.Config.UserID,
+		ParentID:     inst.Config.ID,
+		ParentType:   "bot",
+		OrderID:      orderID,
+		Pair:         inst.Config.Pair,
+		Side:         side,
+		Status:       "open",
+		Price:        price,
+		Amount:       amount,
+		IsPaperTrade: inst.Config.IsPaperTrading,
+		APIKeyID:     inst.Config.BoundAPIKeyID(),
+	}
+	if err := r.orderRepo.Create(ctx, order); err != nil {
+		r.log.Errorf("%s bot %d: Failed to save order %s: %v", r.name, inst.Config.ID, orderID, err)
+		return
+	}
+	r.notificationService.NotifyOrderUpdate(ctx, inst.Config.UserID, order)
+}
+
+// updateOrderRecord updates the stored record of a bot order, a positive price replaces the
+// estimated price of a market buy by its average fill price
+func (r *botRunner) updateOrderRecord(bot runningBot, orderID, status string, filled, price float64) {
+	inst := bot.instance()
+	ctx := context.Background()
+	order, err := r.orderRepo.GetByOrderID(ctx, orderID)
+	if err != nil {
+		r.log.Warnf("%s bot %d: Order record %s not found: %v", r.name, inst.Config.ID, orderID, err)
+		return
+	}
+	if order.Status == status && order.FilledAmount == filled {
+		return
+	}
+
+	oldStatus := order.Status
+	order.Status = status
+	order.FilledAmount = filled
+	if price > 0 && order.Side == "buy" {
+		order.Price = price
+		order.Amount = filled
+	}
+	if status == exchange.OrderStatusFilled {
+		now := time.Now()
+		order.FilledAt = &now
+	}
+	if err := r.orderRepo.Update(ctx, order, oldStatus); err != nil {
+		r.log.Warnf("%s bot %d: Failed to update order record %s: %v", r.name, inst.Config.ID, orderID, err)
+		return
+	}
+	r.notificationService.NotifyOrderUpdate(ctx, inst.Config.UserID, order)
+}
+
+// saveBalances persists the balances of a bot (caller must hold inst.mu)
+func (r *botRunner) saveBalances(ctx context.Context, bot runningBot) {
+	inst := bot.instance()
+	if err := r.botRepo.UpdateBalance(ctx, inst.Config.ID, inst.Config.Balances); err != nil {
+		r.log.Warnf("%s bot %d: Failed to save balances: %v", r.name, inst.Config.ID, err)
+	}
+}
+
+// saveStats persists the trade stats of a bot (caller must hold inst.mu)
+func (r *botRunner) saveStats(bot runningBot) {
+	inst := bot.instance()
+	if err := r.botRepo.UpdateStats(context.Background(), inst.Config.ID, inst.Config.TotalTrades, inst.Config.WinningTrades, inst.Config.TotalProfitIDR); err != nil {
+		r.log.Warnf("%s bot %d: Failed to save stats: %v", r.name, inst.Config.ID, err)
+	}
+}
+
+// notifyBot sends the bot's stats and balances via WebSocket (caller must hold inst.mu)
+func (r *botRunner) notifyBot(bot runningBot) {
+	inst := bot.instance()
+	r.notificationService.NotifyBotUpdate(context.Background(), inst.Config.UserID, model.WSBotUpdatePayload{
+		BotID:          inst.Config.ID,
+		Status:         inst.Config.Status,
+		TotalTrades:    inst.Config.TotalTrades,
+		WinningTrades:  inst.Config.WinningTrades,
+		WinRate:        inst.Config.WinRate(),
+		TotalProfitIDR: inst.Config.TotalProfitIDR,
+		Balances:       inst.Config.Balances,
+	})
+}
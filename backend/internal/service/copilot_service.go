@@ -6,14 +6,23 @@ import (
 	"strings"
 	"time"
 
+	"tuyul/backend/internal/exchange"
 	"tuyul/backend/internal/model"
 	"tuyul/backend/internal/repository"
 	"tuyul/backend/internal/service/market"
 	"tuyul/backend/internal/util"
-	"tuyul/backend/pkg/indodax"
 	"tuyul/backend/pkg/logger"
 )
 
+const (
+	// Attempts to place an auto-sell when the exchange fails transiently
+	autoSellAttempts = 3
+	// Delay before the first auto-sell retry, grows linearly
+	autoSellRetryDelay = 2 * time.Second
+	// A paper order can fill before its record is saved, its update waits this long at most
+	paperOrderRecordWait = 5 * time.Second
+)
+
 type CopilotService struct {
 	tradeRepo         *repository.TradeRepository
 	orderRepo         *repository.OrderRepository
@@ -21,7 +30,8 @@ type CopilotService struct {
 	apiKeyService     *APIKeyService
 	marketDataService *market.MarketDataService
 	orderMonitor      *OrderMonitor
-	indodaxClient     *indodax.Client
+	paperExchange     *PaperExchange
+	exchange          exchange.Exchange
 	log               *logger.Logger
 }
 
@@ -32,7 +42,8 @@ func NewCopilotService(
 	apiKeyService *APIKeyService,
 	marketDataService *market.MarketDataService,
 	orderMonitor *OrderMonitor,
-	indodaxClient *indodax.Client,
+	paperExchange *PaperExchange,
+	ex exchange.Exchange,
 ) *CopilotService {
 	s := &CopilotService{
 		tradeRepo:         tradeRepo,
@@ -41,7 +52,8 @@ func NewCopilotService(
 		apiKeyService:     apiKeyService,
 		marketDataService: marketDataService,
 		orderMonitor:      orderMonitor,
-		indodaxClient:     indodaxClient,
+		paperExchange:     paperExchange,
+		exchange:          ex,
 		log:               logger.GetLogger(),
 	}
 
@@ -61,27 +73,28 @@ func (s *CopilotService) PlaceBuyOrder(ctx context.Context, userID string, req *
 
 	// 2. Setup Trade Client
 	var tradeClient TradeClient
+	var apiKeyID *int64
 	if req.IsPaperTrade {
 		balances, _ := s.getPaperBalances(ctx, userID)
-		tradeClient = NewPaperTradeClient(balances, func(order *model.Order) {
-			s.handleOrderFilled(order)
-		})
+		tradeClient = NewPaperTradeClient(s.paperExchange, userID, balances, s.paperOrderHandler(userID))
 	} else {
-		credentials, err := s.apiKeyService.GetDecrypted(ctx, userID)
+		// Use the requested API key, or the user's default key if none is given
+		credentials, err := s.apiKeyService.ResolveDecrypted(ctx, userID, req.APIKeyID)
 		if err != nil {
 			return nil, util.NewAppError(400, util.ErrCodeAPIKeyInvalid, "API key not found or invalid")
 		}
-		tradeClient = NewLiveTradeClient(s.indodaxClient, credentials.Key, credentials.Secret)
+		tradeClient = s.exchange.NewTrader(credentials.Key, credentials.Secret)
+		apiKeyID = &credentials.ID
 	}
 
 	// 3. Check balance
 	accountInfo, err := tradeClient.GetInfo(ctx)
 	if err != nil {
-		return nil, util.NewAppErrorWithDetails(400, util.ErrCodeIndodaxAPI, "Failed to get account info", err.Error())
+		return nil, util.NewExchangeAppError("Failed to get account info", err)
 	}
 
 	// Parse IDR balance
-	idrBalance := s.parseBalance(accountInfo.Balance["idr"].String())
+	idrBalance := accountInfo.Balances["idr"]
 	if idrBalance < req.VolumeIDR {
 		return nil, util.NewAppError(400, util.ErrCodeInsufficientBalance,
 			fmt.Sprintf("Insufficient IDR balance. Available: %.2f, Required: %.2f", idrBalance, req.VolumeIDR))
@@ -104,7 +117,7 @@ func (s *CopilotService) PlaceBuyOrder(ctx context.Context, userID string, req *
 	
 	result, err := tradeClient.Trade(ctx, "buy", req.Pair, req.BuyingPrice, amount, "limit", clientOrderID)
 	if err != nil {
-		return nil, util.NewAppErrorWithDetails(400, util.ErrCodeIndodaxAPI, "Failed to place buy order", err.Error())
+		return nil, util.NewExchangeAppError("Failed to place buy order", err)
 	}
 
 	// 7. Create trade record (get ID first)
@@ -119,6 +132,7 @@ func (s *CopilotService) PlaceBuyOrder(ctx context.Context, userID string, req *
 		StopLoss:     req.StopLoss,
 		Status:       model.TradeStatusPending,
 		IsPaperTrade: req.IsPaperTrade,
+		APIKeyID:     apiKeyID,
 		CreatedAt:    now,
 		UpdatedAt:    now,
 	}
@@ -133,13 +147,14 @@ func (s *CopilotService) PlaceBuyOrder(ctx context.Context, userID string, req *
 		UserID:       userID,
 		ParentID:     trade.ID,
 		ParentType:   "trade",
-		OrderID:      fmt.Sprintf("%d", result.OrderID),
+		OrderID:      result.OrderID,
 		Pair:         req.Pair,
 		Side:         "buy",
 		Status:       "open",
 		Price:        req.BuyingPrice,
 		Amount:       amount,
 		IsPaperTrade: req.IsPaperTrade,
+		APIKeyID:     trade.BoundAPIKeyID(),
 	}
 
 	if err := s.orderRepo.Create(ctx, order); err != nil {
@@ -161,7 +176,7 @@ func (s *CopilotService) PlaceBuyOrder(ctx context.Context, userID string, req *
 
 	// 11. Subscribe to order updates for live trading
 	if !req.IsPaperTrade {
-		s.orderMonitor.SubscribeUserOrders(ctx, userID)
+		s.orderMonitor.SubscribeKeyOrders(ctx, userID, *apiKeyID)
 	}
 
 	s.log.Infof("Buy order placed (%s): TradeID=%d, Pair=%s, Price=%.2f, Amount=%.8f",
@@ -211,7 +226,7 @@ func (s *CopilotService) CancelBuyOrder(ctx context.Context, userID string, trad
 	}
 
 	// 3. Get Trade Client
-	tradeClient, err := s.getTradeClient(ctx, userID, trade.IsPaperTrade)
+	tradeClient, err := s.getTradeClient(ctx, trade)
 	if err != nil {
 		return err
 	}
@@ -219,7 +234,7 @@ func (s *CopilotService) CancelBuyOrder(ctx context.Context, userID string, trad
 	// 4. Cancel on Indodax
 	err = tradeClient.CancelOrder(ctx, trade.Pair, trade.BuyOrderID, "buy")
 	if err != nil {
-		return util.NewAppErrorWithDetails(400, util.ErrCodeIndodaxAPI, "Failed to cancel order", err.Error())
+		return util.NewExchangeAppError("Failed to cancel order", err)
 	}
 
 	// 5. Update trade status
@@ -275,13 +290,6 @@ func (s *CopilotService) validateTradeRequest(req *model.TradeRequest) error {
 	return nil
 }
 
-// parseBalance parses balance string to float64
-func (s *CopilotService) parseBalance(balanceStr string) float64 {
-	var balance float64
-	fmt.Sscanf(balanceStr, "%f", &balance)
-	return balance
-}
-
 // extractCoinSymbol extracts the coin symbol from pair (e.g., "btcidr" -> "btc")
 func (s *CopilotService) extractCoinSymbol(pair string) string {
 	pair = strings.ToLower(pair)
@@ -302,7 +310,7 @@ func (s *CopilotService) PlaceAutoSell(ctx context.Context, trade *model.Trade,
 	s.log.Infof("Placing auto-sell for TradeID=%d", trade.ID)
 
 	// 1. Get Trade Client
-	tradeClient, err := s.getTradeClient(ctx, trade.UserID, trade.IsPaperTrade)
+	tradeClient, err := s.getTradeClient(ctx, trade)
 	if err != nil {
 		return err
 	}
@@ -326,9 +334,7 @@ func (s *CopilotService) PlaceAutoSell(ctx context.Context, trade *model.Trade,
 	} else {
 		// Extract coin symbol and get balance
 		coinSymbol := s.extractCoinSymbol(trade.Pair)
-		if balanceVal, ok := accountInfo.Balance[coinSymbol]; ok {
-			balanceStr := balanceVal.String()
-			balance := s.parseBalance(balanceStr)
+		if balance, ok := accountInfo.Balances[coinSymbol]; ok {
 			if balance > 0 {
 				filledAmount = balance
 			}
@@ -339,7 +345,13 @@ func (s *CopilotService) PlaceAutoSell(ctx context.Context, trade *model.Trade,
 	// Generate unique client order ID for auto-sell
 	clientOrderID := fmt.Sprintf("copilot-%s-sell-%d", trade.Pair, time.Now().UnixMilli())
 	
+	// Transient exchange errors are retried, the buy is already filled and must not be left unhedged
 	result, err := tradeClient.Trade(ctx, "sell", trade.Pair, sellPrice, filledAmount, "limit", clientOrderID)
+	for attempt := 1; err != nil && exchange.IsTransient(err) && attempt < autoSellAttempts; attempt++ {
+		s.log.Warnf("Auto-sell for TradeID=%d failed (%v), retrying (%d/%d)", trade.ID, err, attempt, autoSellAttempts-1)
+		time.Sleep(time.Duration(attempt) * autoSellRetryDelay)
+		result, err = tradeClient.Trade(ctx, "sell", trade.Pair, sellPrice, filledAmount, "limit", clientOrderID)
+	}
 	if err != nil {
 		s.log.Errorf("Failed to place auto-sell order: %v", err)
 		trade.Status = model.TradeStatusError
@@ -353,13 +365,14 @@ func (s *CopilotService) PlaceAutoSell(ctx context.Context, trade *model.Trade,
 		UserID:       trade.UserID,
 		ParentID:     trade.ID,
 		ParentType:   "trade",
-		OrderID:      fmt.Sprintf("%d", result.OrderID),
+		OrderID:      result.OrderID,
 		Pair:         trade.Pair,
 		Side:         "sell",
 		Status:       "open",
 		Price:        sellPrice,
 		Amount:       filledAmount,
 		IsPaperTrade: trade.IsPaperTrade,
+		APIKeyID:     trade.BoundAPIKeyID(),
 	}
 
 	if err := s.orderRepo.Create(ctx, order); err != nil {
@@ -402,7 +415,7 @@ func (s *CopilotService) ManualSell(ctx context.Context, userID string, tradeID
 	}
 
 	// 3. Get Trade Client
-	tradeClient, err := s.getTradeClient(ctx, userID, trade.IsPaperTrade)
+	tradeClient, err := s.getTradeClient(ctx, trade)
 	if err != nil {
 		return err
 	}
@@ -418,11 +431,11 @@ func (s *CopilotService) ManualSell(ctx context.Context, userID string, tradeID
 	// 5. Get current balance
 	accountInfo, err := tradeClient.GetInfo(ctx)
 	if err != nil {
-		return util.NewAppErrorWithDetails(400, util.ErrCodeIndodaxAPI, "Failed to get account info", err.Error())
+		return util.NewExchangeAppError("Failed to get account info", err)
 	}
 
 	coinSymbol := s.extractCoinSymbol(trade.Pair)
-	sellAmount := s.parseBalance(accountInfo.Balance[coinSymbol].String())
+	sellAmount := accountInfo.Balances[coinSymbol]
 
 	if sellAmount <= 0 {
 		return util.NewAppError(400, util.ErrCodeInsufficientBalance, "No coins available to sell")
@@ -437,11 +450,11 @@ func (s *CopilotService) ManualSell(ctx context.Context, userID string, tradeID
 
 	result, err := tradeClient.Trade(ctx, "sell", trade.Pair, marketPrice, sellAmount, "market", clientOrderID)
 	if err != nil {
-		return util.NewAppErrorWithDetails(400, util.ErrCodeIndodaxAPI, "Failed to place manual sell", err.Error())
+		return util.NewExchangeAppError("Failed to place manual sell", err)
 	}
 
 	// 7. Update trade
-	trade.SellOrderID = fmt.Sprintf("%d", result.OrderID)
+	trade.SellOrderID = result.OrderID
 	trade.SellPrice = marketPrice
 	trade.SellAmount = sellAmount
 	trade.ManualSell = true
@@ -459,22 +472,67 @@ func (s *CopilotService) ManualSell(ctx context.Context, userID string, tradeID
 
 // Internal methodology helpers
 
-func (s *CopilotService) getTradeClient(ctx context.Context, userID string, isPaperTrade bool) (TradeClient, error) {
-	if isPaperTrade {
-		balances, _ := s.getPaperBalances(ctx, userID)
-		return NewPaperTradeClient(balances, func(order *model.Order) {
-			s.handleOrderFilled(order)
-		}), nil
+// getTradeClient returns the client of a trade, using the API key the trade was placed with
+func (s *CopilotService) getTradeClient(ctx context.Context, trade *model.Trade) (TradeClient, error) {
+	if trade.IsPaperTrade {
+		balances, _ := s.getPaperBalances(ctx, trade.UserID)
+		return NewPaperTradeClient(s.paperExchange, trade.UserID, balances, s.paperOrderHandler(trade.UserID)), nil
 	}
 
-	credentials, err := s.apiKeyService.GetDecrypted(ctx, userID)
+	credentials, err := s.apiKeyService.ResolveDecrypted(ctx, trade.UserID, trade.APIKeyID)
 	if err != nil {
 		return nil, util.NewAppError(400, util.ErrCodeAPIKeyInvalid, "API key not found")
 	}
-	return NewLiveTradeClient(s.indodaxClient, credentials.Key, credentials.Secret), nil
+	// Trades predating key binding are pinned to the key resolved now, so their orders record it
+	if trade.APIKeyID == nil {
+		trade.APIKeyID = &credentials.ID
+	}
+	return s.exchange.NewTrader(credentials.Key, credentials.Secret), nil
+}
+
+// paperOrderHandler returns the order update handler of a user's paper orders.
+// Fees are taken from the IDR balance, complete fills settle the trade.
+func (s *CopilotService) paperOrderHandler(userID string) func(update *exchange.OrderUpdate) {
+	return func(update *exchange.OrderUpdate) {
+		ctx := context.Background()
+		if update.Fee > 0 {
+			balances, _ := s.getPaperBalances(ctx, userID)
+			balances["idr"] -= update.Fee
+			s.savePaperBalances(ctx, userID, balances)
+		}
+		if update.Status != exchange.OrderStatusFilled {
+			return
+		}
+
+		filled := &model.Order{
+			OrderID: update.OrderID,
+			Side:    update.Side,
+			Price:   update.Price,
+			Amount:  update.ExecutedQty,
+			Status:  "filled",
+		}
+		if _, err := s.orderRepo.GetByOrderID(ctx, update.OrderID); err == nil {
+			s.handleOrderFilled(filled)
+			return
+		}
+
+		// The order record is saved after the exchange accepted the order, so marketable
+		// orders fill before it exists. Wait for it without holding up the account's
+		// other order updates.
+		go func() {
+			deadline := time.Now().Add(paperOrderRecordWait)
+			for time.Now().Before(deadline) {
+				time.Sleep(200 * time.Millisecond)
+				if _, err := s.orderRepo.GetByOrderID(ctx, update.OrderID); err == nil {
+					break
+				}
+			}
+			s.handleOrderFilled(filled)
+		}()
+	}
 }
 
-// handleOrderFilled is the callback for PaperTradeClient
+// handleOrderFilled settles a filled paper order on its trade
 func (s *CopilotService) handleOrderFilled(order *model.Order) {
 	ctx := context.Background()
 	// Find trade
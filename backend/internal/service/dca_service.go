@@ -0,0 +1,946 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"tuyul/backend/internal/exchange"
+	"tuyul/backend/internal/model"
+	"tuyul/backend/internal/repository"
+	"tuyul/backend/internal/service/market"
+	"tuyul/backend/internal/util"
+)
+
+// DCA bot limits and pacing
+const (
+	dcaMaxIntervalMinutes = 30 * 24 * 60 // Scheduled buys at least monthly
+	dcaMaxSafetyOrders    = 20
+	dcaTickInterval       = 5 * time.Second  // Buy triggers and the take-profit are checked at this pace
+	dcaBuyTimeout         = time.Minute      // A market buy without a final update is checked on the exchange after this
+	dcaOrderTimeout       = 10 * time.Second // Per cancel or order check
+)
+
+// DCAService runs DCA (dollar-cost averaging) bots: market buys of a fixed IDR amount on a
+// schedule, on dips and as safety orders, with an optional take-profit on the averaged position
+type DCAService struct {
+	botRunner
+	apiKeyService     *APIKeyService
+	marketDataService *market.MarketDataService
+	orderMonitor      *OrderMonitor
+	paperExchange     *PaperExchange
+	exchange          exchange.Exchange
+
+	// Runtime bots
+	instances map[int64]*DCAInstance
+	mu        sync.RWMutex
+}
+
+// DCAInstance represents a running DCA bot in memory
+type DCAInstance struct {
+	botInstance // WakeChan is signalled when an order finished
+}
+
+func NewDCAService(
+	botRepo *repository.BotRepository,
+	orderRepo *repository.OrderRepository,
+	apiKeyService *APIKeyService,
+	marketDataService *market.MarketDataService,
+	orderMonitor *OrderMonitor,
+	notificationService *NotificationService,
+	deadmanService *DeadmanService,
+	paperExchange *PaperExchange,
+	ex exchange.Exchange,
+	liveFeeFallbackPct float64,
+) *DCAService {
+	s := &DCAService{
+		botRunner:         newBotRunner("DCA", botRepo, orderRepo, notificationService, deadmanService, liveFeeFallbackPct),
+		apiKeyService:     apiKeyService,
+		marketDataService: marketDataService,
+		orderMonitor:      orderMonitor,
+		paperExchange:     paperExchange,
+		exchange:          ex,
+		instances:         make(map[int64]*DCAInstance),
+	}
+
+	// Register order update handler for live bots
+	orderMonitor.AddOrderHandler(s.handleOrderUpdate)
+
+	return s
+}
+
+// CreateBot creates a new DCA bot configuration
+func (s *DCAService) CreateBot(ctx context.Context, userID string, req *model.BotConfigRequest) (*model.BotConfig, error) {
+	// 1. Validate parameters
+	pairInfo, err := s.validateBotConfig(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. Check for duplicate bot (same type, pair, and mode)
+	if err := checkDuplicateBot(ctx, s.botRepo, userID, model.BotTypeDCA, "DCA", req, 0); err != nil {
+		return nil, err
+	}
+
+	// 3. Bind the requested API key of live bots, or the user's default key if none is given
+	apiKeyID, err := resolveBotAPIKeyID(ctx, s.apiKeyService, userID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// 4. Create bot config, the first scheduled buy is made on start
+	dca := *req.DCA
+	bot := &model.BotConfig{
+		UserID:            userID,
+		Name:              req.Name,
+		Type:              model.BotTypeDCA,
+		Pair:              req.Pair,
+		IsPaperTrading:    req.IsPaperTrading,
+		APIKeyID:          apiKeyID,
+		InitialBalanceIDR: req.InitialBalanceIDR,
+		OrderSizeIDR:      req.OrderSizeIDR,
+		DCA:               &dca,
+		DCAState:          &model.DCAState{},
+		Deadman:           req.Deadman,
+		Balances: map[string]float64{
+			"idr":                              req.InitialBalanceIDR,
+			baseCurrencyOf(req.Pair, pairInfo): 0,
+		},
+		Status:    model.BotStatusStopped,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	// 5. Save to repository
+	if err := s.botRepo.Create(ctx, bot); err != nil {
+		s.log.Errorf("Failed to create DCA bot: %v", err)
+		return nil, util.ErrInternalServer("Failed to create bot")
+	}
+
+	s.log.Infof("DCA bot created: ID=%d, Pair=%s, Size=%.0f IDR, Interval=%dm, Dip=%.2f%%, SafetyOrders=%d, TakeProfit=%.2f%%, PaperTrading=%v",
+		bot.ID, bot.Pair, bot.OrderSizeIDR, dca.IntervalMinutes, dca.DipPercent, dca.SafetyOrderCount, dca.TakeProfitPercent, bot.IsPaperTrading)
+
+	return bot, nil
+}
+
+// GetBot retrieves a DCA bot by ID and verifies ownership
+func (s *DCAService) GetBot(ctx context.Context, userID string, botID int64) (*model.BotConfig, error) {
+	bot, err := s.botRepo.GetByID(ctx, botID)
+	if err != nil {
+		return nil, util.ErrNotFound("Bot not found")
+	}
+
+	if bot.UserID != userID {
+		return nil, util.ErrForbidden("Access denied")
+	}
+	if bot.Type != model.BotTypeDCA {
+		return nil, util.ErrBadRequest("Not a DCA bot")
+	}
+
+	return bot, nil
+}
+
+// UpdateBot updates a DCA bot configuration. The pair and mode can only change while the
+// bot holds no position and no order.
+func (s *DCAService) UpdateBot(ctx context.Context, userID string, botID int64, req *model.BotConfigRequest) (*model.BotConfig, error) {
+	bot, err := s.GetBot(ctx, userID, botID)
+	if err != nil {
+		return nil, err
+	}
+
+	if bot.Status == model.BotStatusRunning {
+		return nil, util.ErrBadRequest("Cannot update a running bot. Stop it first.")
+	}
+
+	pairInfo, err := s.validateBotConfig(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check for duplicate bot if pair or mode is being changed (exclude current bot)
+	if bot.Pair != req.Pair || bot.IsPaperTrading != req.IsPaperTrading {
+		if err := checkDuplicateBot(ctx, s.botRepo, userID, model.BotTypeDCA, "DCA", req, botID); err != nil {
+			return nil, err
+		}
+		if bot.TotalCoinBought > 0 || (bot.DCAState != nil && bot.DCAState.OrderID != "") {
+			return nil, util.ErrBadRequest("Cannot change the pair or mode while the bot holds a position or an order. Create a new bot instead.")
+		}
+		bot.DCAState = &model.DCAState{}
+		bot.TotalCoinBought = 0
+		bot.TotalCostIDR = 0
+		bot.LastBuyPrice = 0
+	}
+
+	apiKeyID, err := resolveBotAPIKeyID(ctx, s.apiKeyService, userID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Update fields
+	dca := *req.DCA
+	bot.Name = req.Name
+	bot.Pair = req.Pair
+	bot.IsPaperTrading = req.IsPaperTrading
+	bot.APIKeyID = apiKeyID
+	bot.InitialBalanceIDR = req.InitialBalanceIDR
+	bot.OrderSizeIDR = req.OrderSizeIDR
+	bot.DCA = &dca
+	if req.Deadman != nil {
+		bot.Deadman = req.Deadman
+	}
+	if bot.DCAState == nil {
+		bot.DCAState = &model.DCAState{}
+	}
+	if bot.Balances == nil {
+		bot.Balances = map[string]float64{"idr": req.InitialBalanceIDR}
+	}
+	base := baseCurrencyOf(req.Pair, pairInfo)
+	if _, ok := bot.Balances[base]; !ok {
+		bot.Balances[base] = 0
+	}
+
+	if err := s.botRepo.Update(ctx, bot, ""); err != nil {
+		return nil, err
+	}
+
+	return bot, nil
+}
+
+// DeleteBot deletes a DCA bot and its orders
+func (s *DCAService) DeleteBot(ctx context.Context, userID string, botID int64) error {
+	bot, err := s.GetBot(ctx, userID, botID)
+	if err != nil {
+		return err
+	}
+
+	if bot.Status == model.BotStatusRunning {
+		return util.ErrBadRequest("Cannot delete a running bot. Stop it first.")
+	}
+
+	orders, err := s.orderRepo.ListByParentAndUser(ctx, userID, "bot", botID, 0) // 0 = no limit, get all
+	if err != nil {
+		s.log.Warnf("Failed to list orders for DCA bot %d: %v", botID, err)
+	} else {
+		for _, order := range orders {
+			if err := s.orderRepo.Delete(ctx, order.ID); err != nil {
+				s.log.Warnf("Failed to delete order %d for DCA bot %d: %v", order.ID, botID, err)
+			}
+		}
+	}
+
+	return s.botRepo.Delete(ctx, botID)
+}
+
+// StartBot starts a DCA bot instance
+func (s *DCAService) StartBot(ctx context.Context, userID string, botID int64) error {
+	bot, err := s.GetBot(ctx, userID, botID)
+	if err != nil {
+		return err
+	}
+
+	if bot.Status == model.BotStatusRunning {
+		return util.ErrBadRequest("Bot is already running")
+	}
+	if bot.DCA == nil {
+		return util.ErrBadRequest("DCA parameters are missing")
+	}
+
+	s.mu.RLock()
+	_, exists := s.instances[botID]
+	s.mu.RUnlock()
+	if exists {
+		return util.ErrBadRequest("Bot instance already exists")
+	}
+
+	// 1. Create instance
+	pairInfo, ok := s.marketDataService.GetPairInfo(bot.Pair)
+	if !ok {
+		return util.ErrBadRequest("Invalid pair")
+	}
+	inst := &DCAInstance{botInstance{
+		Config:       bot,
+		StopChan:     make(chan struct{}),
+		WakeChan:     make(chan struct{}, 1),
+		BaseCurrency: baseCurrencyOf(bot.Pair, pairInfo),
+		PairInfo:     &pairInfo,
+	}}
+	if bot.Balances == nil {
+		bot.Balances = map[string]float64{"idr": bot.InitialBalanceIDR}
+	}
+	if _, ok := bot.Balances[inst.BaseCurrency]; !ok {
+		bot.Balances[inst.BaseCurrency] = 0
+	}
+	if bot.DCAState == nil {
+		bot.DCAState = &model.DCAState{}
+	}
+
+	// 2. Setup Trade Client (paper fills arrive like live order updates)
+	inst.TradeClient, err = CreateTradeClient(ctx, bot, s.apiKeyService, s.exchange, s.paperExchange,
+		func(update *exchange.OrderUpdate) {
+			s.handleOrderUpdate(userID, update)
+		})
+	if err != nil {
+		return err
+	}
+	if !bot.IsPaperTrading && !s.orderMonitor.IsSubscribed(bot.BoundAPIKeyID()) {
+		s.log.Errorf("API key %d of user %s is not subscribed to order updates. Subscription is required for live trading.", bot.BoundAPIKeyID(), userID)
+		return fmt.Errorf("cannot start live bot: user is not subscribed to order updates. Please ensure your API key is configured correctly")
+	}
+
+	// 3. Update status in database
+	if err := s.botRepo.UpdateStatus(ctx, botID, model.BotStatusRunning, nil); err != nil {
+		return err
+	}
+	bot.Status = model.BotStatusRunning
+	bot.ErrorMessage = nil
+
+	// 4. Store instance
+	s.mu.Lock()
+	if _, exists := s.instances[botID]; exists {
+		s.mu.Unlock()
+		return util.ErrBadRequest("Bot instance already exists")
+	}
+	s.instances[botID] = inst
+	s.mu.Unlock()
+
+	// 5. Arm deadman switch (live only) so the take-profit is cancelled if we go down
+	if enabled, countdown := bot.DeadmanSettings(); enabled {
+		if err := s.deadmanService.Arm(ctx, userID, bot.BoundAPIKeyID(), bot.Pair, botID, countdown); err != nil {
+			s.log.Warnf("DCA bot %d: Failed to arm deadman switch for %s: %v", botID, bot.Pair, err)
+		}
+	}
+
+	// 6. Start event loop, it settles the order left by the previous run first
+	go s.runBot(inst)
+
+	s.log.Infof("DCA bot %d started for pair %s holding %.8f %s", botID, bot.Pair, bot.TotalCoinBought, inst.BaseCurrency)
+
+	inst.mu.Lock()
+	s.notifyBot(inst)
+	inst.mu.Unlock()
+
+	return nil
+}
+
+// StopBot stops a DCA bot. The take-profit is cancelled in the background, the position is kept.
+func (s *DCAService) StopBot(ctx context.Context, userID string, botID int64) error {
+	bot, err := s.GetBot(ctx, userID, botID)
+	if err != nil {
+		return err
+	}
+
+	if bot.Status != model.BotStatusRunning {
+		return nil
+	}
+
+	s.mu.Lock()
+	inst, ok := s.instances[botID]
+	delete(s.instances, botID)
+	s.mu.Unlock()
+
+	if err := s.botRepo.UpdateStatus(ctx, botID, model.BotStatusStopped, nil); err != nil {
+		s.log.Errorf("Failed to update status for DCA bot %d: %v", botID, err)
+		return err
+	}
+	if !ok {
+		s.log.Warnf("DCA bot %d not found in instances map, status updated anyway", botID)
+		return nil
+	}
+
+	close(inst.StopChan)
+
+	inst.mu.Lock()
+	inst.Config.Status = model.BotStatusStopped
+	inst.mu.Unlock()
+
+	s.log.Infof("DCA bot %d stopped", botID)
+
+	// The event loop cancels the take-profit on exit (don't block the response),
+	// the deadman switch stays armed until it is
+	return nil
+}
+
+// stopBotWithError stops a live bot and sets error status
+func (s *DCAService) stopBotWithError(inst *DCAInstance, errorMsg string) {
+	if inst.Config.IsPaperTrading {
+		return
+	}
+
+	botID := inst.Config.ID
+	s.mu.Lock()
+	if s.instances[botID] != inst {
+		s.mu.Unlock()
+		return
+	}
+	delete(s.instances, botID)
+	s.mu.Unlock()
+
+	s.markError(inst, errorMsg)
+}
+
+func (s *DCAService) runBot(inst *DCAInstance) {
+	s.log.Infof("Starting event loop for DCA bot %d", inst.Config.ID)
+	defer s.log.Infof("Event loop stopped for DCA bot %d", inst.Config.ID)
+
+	ticker := time.NewTicker(dcaTickInterval)
+	defer ticker.Stop()
+
+	// The order left by the previous run is settled before new ones are placed
+	s.settleOrder(inst, false)
+	s.tick(inst)
+	for {
+		select {
+		case <-inst.StopChan:
+			// Cancelled here so no placement is in flight
+			s.cancelOpenOrder(inst)
+			return
+		case <-ticker.C:
+			s.tick(inst)
+		case <-inst.WakeChan:
+			s.tick(inst)
+		}
+	}
+}
+
+// tick buys when a trigger is due and keeps a take-profit resting on the position
+func (s *DCAService) tick(inst *DCAInstance) {
+	if time.Now().Before(inst.PausedUntil) {
+		return
+	}
+	// No new orders on stale market data (silent stream), the resting take-profit is still managed
+	if s.marketDataService.IsStale(inst.Config.Pair, time.Now()) {
+		s.log.Debugf("DCA bot %d: Market data for %s is stale, not placing new orders", inst.Config.ID, inst.Config.Pair)
+		return
+	}
+	ctx := context.Background()
+	coin, err := s.marketDataService.GetCoin(ctx, inst.Config.Pair)
+	if err != nil || coin.BestBid <= 0 || coin.BestAsk <= 0 {
+		s.log.Debugf("DCA bot %d: No order book for %s yet", inst.Config.ID, inst.Config.Pair)
+		return
+	}
+
+	inst.mu.Lock()
+	// The bot may have been stopped while waiting for the lock
+	if inst.stopped() {
+		inst.mu.Unlock()
+		return
+	}
+
+	state := inst.Config.DCAState
+	now := time.Now()
+
+	// 1. A buy in flight is checked on the exchange once its final update is overdue
+	if state.OrderID != "" && state.OrderSide == "buy" {
+		overdue := now.Sub(state.OrderPlacedAt) >= dcaBuyTimeout
+		inst.mu.Unlock()
+		if overdue {
+			s.settleOrder(inst, false)
+		}
+		return
+	}
+
+	// 2. Dips are measured from the first price seen until the first buy
+	if inst.Config.DCA.DipPercent > 0 && state.LastFillPrice <= 0 {
+		state.LastFillPrice = coin.BestAsk
+		s.saveState(ctx, inst)
+	}
+
+	// 3. Buy on the first due trigger the balance covers, an unaffordable safety order does not
+	// hold back smaller buys
+	var buy *dcaBuy
+	for _, due := range inst.dueBuys(coin.BestAsk, now) {
+		if inst.Config.Balances["idr"] < due.sizeIDR {
+			s.log.Debugf("DCA bot %d: Not enough IDR for a %s buy (%.2f < %.2f)", inst.Config.ID, due.reason,
+				inst.Config.Balances["idr"], due.sizeIDR)
+			continue
+		}
+		buy = &due
+		break
+	}
+	takeProfit := state.OrderID != ""
+	wantTakeProfit := !takeProfit && inst.Config.DCA.TakeProfitPercent > 0 && inst.Config.TotalCoinBought > 0
+	inst.mu.Unlock()
+
+	switch {
+	case buy != nil:
+		// The take-profit is cancelled first and re-placed for the grown position
+		if !takeProfit || s.cancelTakeProfit(inst) {
+			s.placeBuy(ctx, inst, *buy, coin.BestAsk)
+		}
+	case wantTakeProfit:
+		// 4. Place the take-profit of a position without one
+		s.placeTakeProfit(ctx, inst, coin.BestBid)
+	}
+}
+
+// dcaBuy is a buy trigger that is due
+type dcaBuy struct {
+	reason  string
+	sizeIDR float64
+}
+
+// dueBuys returns the buys due at the given ask, safety order first (caller must hold inst.mu)
+func (inst *DCAInstance) dueBuys(ask float64, now time.Time) []dcaBuy {
+	cfg, state := inst.Config.DCA, inst.Config.DCAState
+
+	var buys []dcaBuy
+	if cfg.SafetyOrderCount > state.SafetyOrders && state.DealStartPrice > 0 && inst.Config.TotalCoinBought > 0 &&
+		ask <= state.DealStartPrice*(1-float64(state.SafetyOrders+1)*cfg.SafetyOrderStepPercent/100) {
+		size := inst.Config.OrderSizeIDR
+		if cfg.SafetyOrderSizeIDR > 0 {
+			size = cfg.SafetyOrderSizeIDR
+		}
+		buys = append(buys, dcaBuy{reason: model.DCAReasonSafety, sizeIDR: size})
+	}
+	if cfg.DipPercent > 0 && state.LastFillPrice > 0 && ask <= state.LastFillPrice*(1-cfg.DipPercent/100) {
+		buys = append(buys, dcaBuy{reason: model.DCAReasonDip, sizeIDR: inst.Config.OrderSizeIDR})
+	}
+	if cfg.IntervalMinutes > 0 && !now.Before(state.NextBuyAt) {
+		buys = append(buys, dcaBuy{reason: model.DCAReasonScheduled, sizeIDR: inst.Config.OrderSizeIDR})
+	}
+	return buys
+}
+
+// placeBuy places a market buy, recorded and funded under inst.mu and sent without holding it
+func (s *DCAService) placeBuy(ctx context.Context, inst *DCAInstance, buy dcaBuy, ask float64) {
+	inst.mu.Lock()
+	state := inst.Config.DCAState
+	// An order update or the cancelled take-profit may have changed the position meanwhile
+	if inst.stopped() || state.OrderID != "" || inst.Config.Balances["idr"] < buy.sizeIDR {
+		inst.mu.Unlock()
+		return
+	}
+
+	// Record the order BEFORE the API call, updates are matched against it
+	now := time.Now()
+	clientOrderID := s.clientOrderID(inst.Config.ID, "buy")
+	state.SetOrder(clientOrderID, "buy", buy.reason, ask, buy.sizeIDR, now)
+
+	// Lock the IDR of the order, what it does not spend is released when it closes
+	inst.Config.Balances["idr"] -= buy.sizeIDR
+	amount := util.FloorToPrecision(buy.sizeIDR/ask, util.GetVolumePrecision(*inst.PairInfo)) // Estimated until filled
+	s.createOrderRecord(ctx, inst, clientOrderID, "buy", ask, amount)
+	inst.mu.Unlock()
+
+	// Market buys are sized in IDR
+	res, err := inst.TradeClient.Trade(ctx, "buy", inst.Config.Pair, 0, buy.sizeIDR, "market", clientOrderID)
+
+	inst.mu.Lock()
+	defer inst.mu.Unlock()
+
+	if err != nil {
+		s.log.Errorf("DCA bot %d: Failed to place %s buy of %.0f IDR: %v", inst.Config.ID, buy.reason, buy.sizeIDR, err)
+		if state.OrderID == clientOrderID {
+			state.ClearOrder()
+			inst.Config.Balances["idr"] += buy.sizeIDR
+		}
+		s.updateOrderRecord(inst, clientOrderID, exchange.OrderStatusCancelled, 0, 0)
+		s.handleAPIError(inst, err, func(errorMsg string) {
+			s.stopBotWithError(inst, errorMsg)
+		})
+		return
+	}
+
+	if state.OrderID == clientOrderID {
+		state.ExchangeOrderID = res.OrderID
+	}
+	if buy.reason == model.DCAReasonScheduled {
+		state.NextBuyAt = now.Add(time.Duration(inst.Config.DCA.IntervalMinutes) * time.Minute)
+	}
+	s.saveState(ctx, inst)
+	s.notifyBot(inst)
+
+	s.log.Infof("DCA bot %d: Placed %s buy of %.0f IDR near %.8g (OrderID: %s)", inst.Config.ID, buy.reason, buy.sizeIDR, ask, clientOrderID)
+}
+
+// placeTakeProfit places a limit sell of the position above its average price, recorded and
+// funded under inst.mu and sent without holding it
+func (s *DCAService) placeTakeProfit(ctx context.Context, inst *DCAInstance, bid float64) {
+	inst.mu.Lock()
+	state := inst.Config.DCAState
+	if inst.stopped() || state.OrderID != "" || inst.Config.TotalCoinBought <= 0 {
+		inst.mu.Unlock()
+		return
+	}
+	avg := inst.Config.TotalCostIDR / inst.Config.TotalCoinBought
+
+	// Never sell below the target, at the bid when the market already is above it
+	tick := util.GetTickSize(*inst.PairInfo, s.marketDataService)
+	price := math.Max(math.Ceil(avg*(1+inst.Config.DCA.TakeProfitPercent/100)/tick)*tick, bid)
+	amount := util.FloorToPrecision(math.Min(inst.Config.TotalCoinBought, inst.Config.Balances[inst.BaseCurrency]),
+		util.GetVolumePrecision(*inst.PairInfo))
+	if !inst.tradable(amount, price) {
+		s.log.Debugf("DCA bot %d: Position of %.8f %s is below the pair's minimum order", inst.Config.ID, amount, inst.BaseCurrency)
+		inst.mu.Unlock()
+		return
+	}
+
+	clientOrderID := s.clientOrderID(inst.Config.ID, "sell")
+	state.SetOrder(clientOrderID, "sell", model.DCAReasonTakeProfit, price, amount, time.Now())
+
+	// Lock the coins of the order
+	inst.Config.Balances[inst.BaseCurrency] -= amount
+	s.createOrderRecord(ctx, inst, clientOrderID, "sell", price, amount)
+	inst.mu.Unlock()
+
+	res, err := inst.TradeClient.Trade(ctx, "sell", inst.Config.Pair, price, amount, "limit", clientOrderID)
+
+	inst.mu.Lock()
+	defer inst.mu.Unlock()
+
+	if err != nil {
+		s.log.Errorf("DCA bot %d: Failed to place take-profit %.8f @ %.8g: %v", inst.Config.ID, amount, price, err)
+		if state.OrderID == clientOrderID {
+			state.ClearOrder()
+			inst.Config.Balances[inst.BaseCurrency] += amount
+		}
+		s.updateOrderRecord(inst, clientOrderID, exchange.OrderStatusCancelled, 0, 0)
+		s.handleAPIError(inst, err, func(errorMsg string) {
+			s.stopBotWithError(inst, errorMsg)
+		})
+		return
+	}
+
+	if state.OrderID == clientOrderID {
+		state.ExchangeOrderID = res.OrderID
+	}
+	s.saveState(ctx, inst)
+	s.notifyBot(inst)
+
+	s.log.Infof("DCA bot %d: Placed take-profit %.8f @ %.8g, average %.8g (OrderID: %s)", inst.Config.ID, amount, price, avg, clientOrderID)
+}
+
+// cancelTakeProfit cancels the resting take-profit and settles what it sold, without holding
+// inst.mu. Returns whether no order is left.
+func (s *DCAService) cancelTakeProfit(inst *DCAInstance) bool {
+	inst.mu.Lock()
+	orderID, side := inst.Config.DCAState.OrderID, inst.Config.DCAState.OrderSide
+	inst.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), dcaOrderTimeout)
+	err := inst.TradeClient.CancelOrder(ctx, inst.Config.Pair, orderID, side)
+	cancel()
+	if err != nil && !util.IsOrderNotFoundError(err) {
+		s.log.Warnf("DCA bot %d: Failed to cancel take-profit %s: %v", inst.Config.ID, orderID, err)
+		inst.mu.Lock()
+		s.handleAPIError(inst, err, func(errorMsg string) {
+			s.stopBotWithError(inst, errorMsg)
+		})
+		inst.mu.Unlock()
+		return false
+	}
+	s.settleOrder(inst, true)
+
+	inst.mu.Lock()
+	defer inst.mu.Unlock()
+	return inst.Config.DCAState.OrderID == ""
+}
+
+// handleOrderUpdate applies an order update to the DCA bot the order belongs to
+func (s *DCAService) handleOrderUpdate(userID string, update *exchange.OrderUpdate) {
+	if !update.IsFill() && update.Status != exchange.OrderStatusCancelled {
+		return
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, inst := range s.instances {
+		if inst.Config.UserID != userID {
+			continue
+		}
+
+		inst.mu.Lock()
+		owned := inst.ownsOrder(update)
+		if owned {
+			state := inst.Config.DCAState
+			s.log.Debugf("DCA bot %d: Order %s %s, executed %.8f/%.8f", inst.Config.ID, state.OrderID,
+				update.Status, update.ExecutedQty, update.OrigQty)
+			s.applyExecution(inst, update.Status, update.ExecutedQty, update.Price, update.Fee)
+			s.saveState(context.Background(), inst)
+			s.notifyBot(inst)
+		}
+		inst.mu.Unlock()
+
+		if owned {
+			return
+		}
+	}
+}
+
+// applyExecution brings the bot up to date with its order in flight (caller must hold inst.mu).
+// executed is cumulative in coins at an average price, fee covers the quantity executed since the previous update.
+// Live fills the exchange reports without a fee are charged the fallback rate.
+func (s *DCAService) applyExecution(inst *DCAInstance, status string, executed, price, fee float64) {
+	state := inst.Config.DCAState
+	if price <= 0 {
+		price = state.OrderPrice
+	}
+
+	if delta := executed - state.Filled; delta > 0 {
+		value := executed*price - state.FilledIDR
+		if fee <= 0 && !inst.Config.IsPaperTrading {
+			fee = value * s.liveFeeRate
+		}
+		state.Filled = executed
+		state.FilledIDR += value
+		if state.OrderSide == "sell" {
+			var cost float64
+			if inst.Config.TotalCoinBought > 0 {
+				cost = delta * inst.Config.TotalCostIDR / inst.Config.TotalCoinBought
+			}
+			inst.Config.Balances["idr"] += value
+			inst.Config.TotalCoinBought = math.Max(inst.Config.TotalCoinBought-delta, 0)
+			inst.Config.TotalCostIDR = math.Max(inst.Config.TotalCostIDR-cost, 0)
+			s.bookProfit(inst, value-cost)
+		} else {
+			inst.Config.Balances[inst.BaseCurrency] += delta
+			inst.Config.TotalCoinBought += delta
+			inst.Config.TotalCostIDR += value
+		}
+	}
+
+	if fee > 0 {
+		inst.Config.Balances["idr"] -= fee
+		state.OrderFeeIDR += fee
+		if state.OrderSide == "sell" {
+			s.bookProfit(inst, -fee)
+		} else {
+			inst.Config.TotalCostIDR += fee
+		}
+	}
+
+	if inst.Config.TotalCoinBought > 0 {
+		inst.Config.LastBuyPrice = inst.Config.TotalCostIDR / inst.Config.TotalCoinBought
+	}
+
+	if status == exchange.OrderStatusFilled || status == exchange.OrderStatusCancelled {
+		s.closeOrder(inst, status)
+	} else {
+		s.updateOrderRecord(inst, state.OrderID, "open", state.Filled, 0)
+	}
+}
+
+// closeOrder releases what a finished order did not use and moves the position on
+func (s *DCAService) closeOrder(inst *DCAInstance, status string) {
+	state := inst.Config.DCAState
+	if state.OrderSide == "sell" {
+		inst.Config.Balances[inst.BaseCurrency] += math.Max(state.OrderAmount-state.Filled, 0)
+	} else {
+		inst.Config.Balances["idr"] += math.Max(state.OrderAmount-state.FilledIDR, 0)
+	}
+
+	var avgPrice float64
+	if state.Filled > 0 {
+		avgPrice = state.FilledIDR / state.Filled
+	}
+	recordStatus := status
+	if status == exchange.OrderStatusCancelled && state.Filled > 0 {
+		recordStatus = "partial"
+	}
+	s.updateOrderRecord(inst, state.OrderID, recordStatus, state.Filled, avgPrice)
+
+	side, reason, filled := state.OrderSide, state.OrderReason, state.Filled
+	state.ClearOrder()
+
+	switch {
+	case side == "buy" && filled > 0:
+		state.LastFillPrice = avgPrice
+		if state.DealStartPrice <= 0 {
+			state.DealStartPrice = avgPrice
+		}
+		if reason == model.DCAReasonSafety {
+			state.SafetyOrders++
+		}
+		s.log.Infof("DCA bot %d: %s buy of %.8f %s @ %.8g, position %.8f at average %.8g", inst.Config.ID, reason,
+			filled, inst.BaseCurrency, avgPrice, inst.Config.TotalCoinBought, inst.Config.LastBuyPrice)
+	case side == "sell" && status == exchange.OrderStatusFilled:
+		s.completeDeal(inst, avgPrice)
+	}
+
+	inst.wake()
+}
+
+// completeDeal books the position closed by the take-profit as one trade and starts a new one
+func (s *DCAService) completeDeal(inst *DCAInstance, sellPrice float64) {
+	state := inst.Config.DCAState
+	profit := state.DealProfitIDR
+
+	inst.Config.TotalTrades++
+	if profit > 0 {
+		inst.Config.WinningTrades++
+	}
+	s.saveStats(inst)
+
+	// Coins left after the take-profit are dust below the pair's minimum, they stay in the balance
+	inst.Config.TotalCoinBought = 0
+	inst.Config.TotalCostIDR = 0
+	inst.Config.LastBuyPrice = 0
+	state.DealStartPrice = 0
+	state.SafetyOrders = 0
+	state.DealProfitIDR = 0
+	state.LastFillPrice = sellPrice
+
+	s.log.Infof("DCA bot %d: Take-profit filled @ %.8g, deal profit %.2f IDR", inst.Config.ID, sellPrice, profit)
+}
+
+// bookProfit adds realized profit of the take-profit to the deal and the bot's stats
+func (s *DCAService) bookProfit(inst *DCAInstance, profit float64) {
+	inst.Config.DCAState.DealProfitIDR += profit
+	inst.Config.TotalProfitIDR += profit
+	s.saveStats(inst)
+}
+
+// settleOrder applies the state of the order in flight on the exchange, checked without
+// holding inst.mu. cancelled forces an open order to be closed, after the bot cancelled it.
+func (s *DCAService) settleOrder(inst *DCAInstance, cancelled bool) {
+	inst.mu.Lock()
+	orderID := inst.Config.DCAState.OrderID
+	inst.mu.Unlock()
+	if orderID == "" {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), dcaOrderTimeout)
+	order, err := inst.TradeClient.GetOrder(ctx, inst.Config.Pair, orderID)
+	cancel()
+
+	inst.mu.Lock()
+	defer inst.mu.Unlock()
+
+	// Skip an order an order update settled meanwhile
+	state := inst.Config.DCAState
+	if state.OrderID != orderID {
+		return
+	}
+	switch {
+	case err == nil:
+		status := order.Status
+		if cancelled && status != exchange.OrderStatusFilled {
+			status = exchange.OrderStatusCancelled
+		}
+		executed := order.Amount - order.Remaining
+		if state.OrderSide == "buy" {
+			// Market buys are sized and report what is left in IDR
+			executed = state.Filled
+			if order.Price > 0 {
+				executed = (order.Amount - order.Remaining) / order.Price
+			}
+		}
+		s.applyExecution(inst, status, executed, order.Price, order.Fee-state.OrderFeeIDR)
+	case util.IsOrderNotFoundError(err):
+		// Unknown to the exchange (paper orders do not survive a restart), keep what was seen
+		s.log.Warnf("DCA bot %d: Order %s not found, closing it with %.8f executed", inst.Config.ID, state.OrderID, state.Filled)
+		s.applyExecution(inst, exchange.OrderStatusCancelled, state.Filled, 0, 0)
+	default:
+		s.log.Warnf("DCA bot %d: Failed to check order %s, keeping it: %v", inst.Config.ID, state.OrderID, err)
+		return
+	}
+
+	s.saveState(context.Background(), inst)
+	s.notifyBot(inst)
+}
+
+// cancelOpenOrder cancels the take-profit of a stopped bot and settles the order in flight.
+// The deadman switch of the pair is disarmed once nothing rests on the exchange, otherwise
+// it is left to expire so the exchange cancels the take-profit.
+func (s *DCAService) cancelOpenOrder(inst *DCAInstance) {
+	config := inst.Config
+	inst.mu.Lock()
+	orderID, side := config.DCAState.OrderID, config.DCAState.OrderSide
+	inst.mu.Unlock()
+
+	if orderID != "" && side == "sell" {
+		ctx, cancel := context.WithTimeout(context.Background(), dcaOrderTimeout)
+		err := inst.TradeClient.CancelOrder(ctx, config.Pair, orderID, side)
+		cancel()
+		if err != nil && !util.IsOrderNotFoundError(err) {
+			// Left on the bot, the next start settles it
+			s.log.Warnf("DCA bot %d: Failed to cancel take-profit %s: %v", config.ID, orderID, err)
+			s.deadmanService.Expire(config.UserID, config.Pair, config.ID)
+			return
+		}
+	}
+	s.deadmanService.Disarm(config.UserID, config.Pair, config.ID)
+
+	// Market buys are not cancelled, they are settled as far as they executed
+	s.settleOrder(inst, side == "sell")
+}
+
+// validateBotConfig validates a DCA bot request and returns the pair info
+func (s *DCAService) validateBotConfig(req *model.BotConfigRequest) (exchange.Pair, error) {
+	if req.Pair == "" {
+		return exchange.Pair{}, util.ErrBadRequest("Pair is required")
+	}
+	pairInfo, ok := s.marketDataService.GetPairInfo(req.Pair)
+	if !ok {
+		return exchange.Pair{}, util.ErrBadRequest(fmt.Sprintf("Invalid or unsupported pair: %s", req.Pair))
+	}
+
+	dca := req.DCA
+	if dca == nil {
+		return pairInfo, util.ErrBadRequest("DCA parameters are required")
+	}
+	if dca.IntervalMinutes < 0 || dca.IntervalMinutes > dcaMaxIntervalMinutes {
+		return pairInfo, util.ErrBadRequest(fmt.Sprintf("Interval must be between 0 and %d minutes", dcaMaxIntervalMinutes))
+	}
+	if dca.DipPercent < 0 || dca.DipPercent >= 100 {
+		return pairInfo, util.ErrBadRequest("Dip percent must be between 0 and 100")
+	}
+	if dca.IntervalMinutes == 0 && dca.DipPercent == 0 {
+		return pairInfo, util.ErrBadRequest("Set an interval, a dip percent or both")
+	}
+	if dca.SafetyOrderCount < 0 || dca.SafetyOrderCount > dcaMaxSafetyOrders {
+		return pairInfo, util.ErrBadRequest(fmt.Sprintf("Safety order count must be between 0 and %d", dcaMaxSafetyOrders))
+	}
+	if dca.SafetyOrderCount > 0 && (dca.SafetyOrderStepPercent <= 0 || dca.SafetyOrderStepPercent*float64(dca.SafetyOrderCount) >= 100) {
+		return pairInfo, util.ErrBadRequest("Safety order step must be positive and all steps together below 100%")
+	}
+	if dca.TakeProfitPercent < 0 {
+		return pairInfo, util.ErrBadRequest("Take profit percent cannot be negative")
+	}
+
+	if req.InitialBalanceIDR < util.MinInitialBalanceIDR {
+		return pairInfo, util.ErrBadRequest(fmt.Sprintf("Initial balance must be at least %.0f IDR", util.MinInitialBalanceIDR))
+	}
+	minOrder := math.Max(util.MinOrderValueIDR, pairInfo.MinQuoteAmount)
+	if req.OrderSizeIDR < minOrder {
+		return pairInfo, util.ErrBadRequest(fmt.Sprintf("Order size must be at least %.0f IDR", minOrder))
+	}
+	if dca.SafetyOrderSizeIDR != 0 && dca.SafetyOrderSizeIDR < minOrder {
+		return pairInfo, util.ErrBadRequest(fmt.Sprintf("Safety order size must be at least %.0f IDR", minOrder))
+	}
+	if req.OrderSizeIDR > req.InitialBalanceIDR {
+		return pairInfo, util.ErrBadRequest("Initial balance must cover at least one buy")
+	}
+
+	return pairInfo, nil
+}
+
+// saveState persists the balances, position and state of a bot (caller must hold inst.mu)
+func (s *DCAService) saveState(ctx context.Context, inst *DCAInstance) {
+	s.saveBalances(ctx, inst)
+	if err := s.botRepo.UpdateTracking(ctx, inst.Config.ID, inst.Config.TotalCoinBought, inst.Config.TotalCostIDR, inst.Config.LastBuyPrice); err != nil {
+		s.log.Warnf("DCA bot %d: Failed to save position: %v", inst.Config.ID, err)
+	}
+	if err := s.botRepo.UpdateDCAState(ctx, inst.Config.ID, inst.Config.DCAState); err != nil {
+		s.log.Warnf("DCA bot %d: Failed to save DCA state: %v", inst.Config.ID, err)
+	}
+}
+
+// ownsOrder reports whether an update belongs to the bot's order in flight
+func (inst *DCAInstance) ownsOrder(update *exchange.OrderUpdate) bool {
+	state := inst.Config.DCAState
+	if state == nil || state.OrderID == "" {
+		return false
+	}
+	if update.ClientOrderID == state.OrderID || update.OrderID == state.OrderID {
+		return true
+	}
+	// Live market order updates may only carry the exchange ID, as "{pair}-{type}-{numericId}"
+	return state.ExchangeOrderID != "" &&
+		(update.OrderID == state.ExchangeOrderID || strings.HasSuffix(update.OrderID, "-"+state.ExchangeOrderID))
+}
@@ -0,0 +1,401 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"tuyul/backend/internal/exchange"
+	"tuyul/backend/internal/model"
+	"tuyul/backend/pkg/logger"
+)
+
+// DeadmanService keeps exchange-side deadman timers (Indodax countdownCancelAll)
+// alive for live bots. One switch exists per API key and covers every pair traded by
+// the bots of that exchange account, so a single call refreshes all of them. If this
+// process dies or loses connectivity, the heartbeats stop and the exchange cancels all
+// open orders of the pairs once the countdown expires.
+type DeadmanService struct {
+	apiKeyService       *APIKeyService
+	notificationService *NotificationService
+	exchangeSwitch      exchange.DeadmanSwitch
+	log                 *logger.Logger
+
+	// Key: API key ID
+	switches map[int64]*deadmanSwitch
+	mu       sync.Mutex
+}
+
+type deadmanSwitch struct {
+	userID    string
+	apiKeyID  int64
+	apiKey    string
+	apiSecret string
+
+	// Pairs armed on the account, with the bots trading them and their requested countdown
+	pairs     map[string]map[int64]time.Duration
+	countdown time.Duration
+	failures  int
+
+	resetChan chan struct{}
+	stopChan  chan struct{}
+}
+
+func NewDeadmanService(
+	apiKeyService *APIKeyService,
+	notificationService *NotificationService,
+	exchangeSwitch exchange.DeadmanSwitch,
+) *DeadmanService {
+	return &DeadmanService{
+		apiKeyService:       apiKeyService,
+		notificationService: notificationService,
+		exchangeSwitch:      exchangeSwitch,
+		log:                 logger.GetLogger(),
+		switches:            make(map[int64]*deadmanSwitch),
+	}
+}
+
+// Arm registers a bot's pair on the switch of its API key and starts heartbeats if needed.
+// All pairs of a key share the longest countdown of its bots.
+// An apiKeyID of 0 selects the user's default key.
+func (s *DeadmanService) Arm(ctx context.Context, userID string, apiKeyID int64, pair string, botID int64, countdown time.Duration) error {
+	if apiKeyID == 0 {
+		apiKey, err := s.apiKeyService.GetDecrypted(ctx, userID)
+		if err != nil {
+			return fmt.Errorf("failed to get API key for deadman switch: %w", err)
+		}
+		apiKeyID = apiKey.ID
+	}
+
+	// 1. Already armed - register the bot
+	s.mu.Lock()
+	if sw, ok := s.switches[apiKeyID]; ok {
+		s.register(sw, pair, botID, countdown)
+		s.mu.Unlock()
+		return nil
+	}
+	s.mu.Unlock()
+
+	// 2. Load credentials (outside lock, hits Redis)
+	apiKey, err := s.apiKeyService.GetDecryptedByID(ctx, userID, apiKeyID)
+	if err != nil {
+		return fmt.Errorf("failed to get API key for deadman switch: %w", err)
+	}
+
+	// 3. Create switch (double-check in case of concurrent Arm)
+	s.mu.Lock()
+	sw, ok := s.switches[apiKeyID]
+	if ok {
+		s.register(sw, pair, botID, countdown)
+		s.mu.Unlock()
+		return nil
+	}
+	sw = &deadmanSwitch{
+		userID:    userID,
+		apiKeyID:  apiKeyID,
+		apiKey:    apiKey.Key,
+		apiSecret: apiKey.Secret,
+		pairs:     map[string]map[int64]time.Duration{pair: {botID: countdown}},
+		countdown: countdown,
+		resetChan: make(chan struct{}, 1),
+		stopChan:  make(chan struct{}),
+	}
+	s.switches[apiKeyID] = sw
+	s.mu.Unlock()
+
+	s.log.Infof("Deadman switch armed for user %s API key %d pair %s (bot %d, countdown %s)", userID, apiKeyID, pair, botID, countdown)
+
+	// 4. First heartbeat synchronously so the caller sees immediate failures
+	err = s.heartbeat(sw)
+
+	go s.run(sw)
+
+	return err
+}
+
+// Disarm removes a bot from a pair it is registered on. When the last bot leaves
+// the pair, its exchange timer is cancelled; when the last pair leaves, heartbeats stop.
+func (s *DeadmanService) Disarm(userID, pair string, botID int64) {
+	sw := s.unregister(userID, pair, botID)
+	if sw == nil {
+		return
+	}
+
+	// Stop the exchange-side timer of the pair (countdownTime=0)
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := s.exchangeSwitch.CountdownCancelAll(ctx, sw.apiKey, sw.apiSecret, []string{pair}, 0); err != nil {
+			s.log.Warnf("Failed to disarm deadman switch for user %s pair %s: %v", userID, pair, err)
+			return
+		}
+		s.log.Infof("Deadman switch disarmed for user %s pair %s", userID, pair)
+	}()
+}
+
+// Expire removes a bot from a pair like Disarm but leaves the exchange timer running.
+// Used when the bot could not cancel its orders: once no bot refreshes the pair, the
+// exchange cancels them when the countdown runs out.
+func (s *DeadmanService) Expire(userID, pair string, botID int64) {
+	if sw := s.unregister(userID, pair, botID); sw != nil {
+		s.log.Warnf("Deadman switch of user %s pair %s left to expire (bot %d)", userID, pair, botID)
+	}
+}
+
+// unregister removes a bot from a pair and stops the heartbeats of a switch without pairs.
+// Returns the switch when the pair has no bots left.
+func (s *DeadmanService) unregister(userID, pair string, botID int64) *deadmanSwitch {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	sw := s.findSwitch(userID, pair, botID)
+	if sw == nil {
+		return nil
+	}
+	bots := sw.pairs[pair]
+	delete(bots, botID)
+	if len(bots) > 0 {
+		s.recomputeCountdown(sw)
+		return nil
+	}
+	delete(sw.pairs, pair)
+	if len(sw.pairs) > 0 {
+		s.recomputeCountdown(sw)
+	} else {
+		delete(s.switches, sw.apiKeyID)
+		close(sw.stopChan)
+	}
+	return sw
+}
+
+// findSwitch returns the switch a bot's pair is registered on (caller must hold s.mu)
+func (s *DeadmanService) findSwitch(userID, pair string, botID int64) *deadmanSwitch {
+	for _, sw := range s.switches {
+		if sw.userID != userID {
+			continue
+		}
+		if _, ok := sw.pairs[pair][botID]; ok {
+			return sw
+		}
+	}
+	return nil
+}
+
+// DisarmBot removes a bot from every pair it is registered on
+func (s *DeadmanService) DisarmBot(userID string, botID int64) {
+	for _, pair := range s.botPairs(userID, botID) {
+		s.Disarm(userID, pair, botID)
+	}
+}
+
+// ExpireBot removes a bot from every pair it is registered on like DisarmBot,
+// leaving the exchange timers running
+func (s *DeadmanService) ExpireBot(userID string, botID int64) {
+	for _, pair := range s.botPairs(userID, botID) {
+		s.Expire(userID, pair, botID)
+	}
+}
+
+// SyncBotPairs makes the set of pairs armed for a bot match the given list.
+// Used by bots that trade multiple pairs (Pump Hunter).
+func (s *DeadmanService) SyncBotPairs(ctx context.Context, userID string, apiKeyID, botID int64, pairs []string, countdown time.Duration) {
+	wanted := make(map[string]bool, len(pairs))
+	for _, pair := range pairs {
+		wanted[pair] = true
+	}
+
+	// Disarm pairs no longer needed
+	for _, pair := range s.botPairs(userID, botID) {
+		if !wanted[pair] {
+			s.Disarm(userID, pair, botID)
+		}
+	}
+
+	// Arm new pairs
+	for pair := range wanted {
+		if err := s.Arm(ctx, userID, apiKeyID, pair, botID, countdown); err != nil {
+			s.log.Warnf("Bot %d: Failed to arm deadman switch for %s: %v", botID, pair, err)
+		}
+	}
+}
+
+// botPairs returns the pairs a bot is currently registered on
+func (s *DeadmanService) botPairs(userID string, botID int64) []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var pairs []string
+	for _, sw := range s.switches {
+		if sw.userID != userID {
+			continue
+		}
+		for pair, bots := range sw.pairs {
+			if _, ok := bots[botID]; ok {
+				pairs = append(pairs, pair)
+			}
+		}
+	}
+	return pairs
+}
+
+// register adds a bot to a pair of an armed switch (caller must hold s.mu).
+// A new pair is armed by the next heartbeat, which is sent right away.
+func (s *DeadmanService) register(sw *deadmanSwitch, pair string, botID int64, countdown time.Duration) {
+	bots, ok := sw.pairs[pair]
+	if !ok {
+		bots = make(map[int64]time.Duration)
+		sw.pairs[pair] = bots
+		s.log.Infof("Deadman switch of user %s API key %d extended to pair %s (bot %d)", sw.userID, sw.apiKeyID, pair, botID)
+	}
+	bots[botID] = countdown
+	s.recomputeCountdown(sw)
+	if !ok {
+		sw.reset()
+	}
+}
+
+// recomputeCountdown picks the longest countdown among bots (caller must hold s.mu)
+func (s *DeadmanService) recomputeCountdown(sw *deadmanSwitch) {
+	var longest time.Duration
+	for _, bots := range sw.pairs {
+		for _, d := range bots {
+			if d > longest {
+				longest = d
+			}
+		}
+	}
+	if longest == sw.countdown {
+		return
+	}
+	sw.countdown = longest
+	sw.reset()
+}
+
+// heartbeatInterval refreshes 4 times per countdown window so a few failures are tolerated
+func heartbeatInterval(countdown time.Duration) time.Duration {
+	return countdown / 4
+}
+
+func (s *DeadmanService) run(sw *deadmanSwitch) {
+	s.mu.Lock()
+	interval := heartbeatInterval(sw.countdown)
+	s.mu.Unlock()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-sw.stopChan:
+			return
+		case <-sw.resetChan:
+			s.mu.Lock()
+			interval = heartbeatInterval(sw.countdown)
+			s.mu.Unlock()
+			ticker.Reset(interval)
+			s.heartbeat(sw)
+		case <-ticker.C:
+			s.heartbeat(sw)
+		}
+	}
+}
+
+// heartbeat refreshes the exchange countdown of all pairs of the switch in one call
+// and reports failures to the user
+func (s *DeadmanService) heartbeat(sw *deadmanSwitch) error {
+	// Don't re-arm a switch that was disarmed meanwhile
+	select {
+	case <-sw.stopChan:
+		return nil
+	default:
+	}
+
+	s.mu.Lock()
+	countdown := sw.countdown
+	pairs := sw.pairList()
+	s.mu.Unlock()
+	if len(pairs) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	err := s.exchangeSwitch.CountdownCancelAll(ctx, sw.apiKey, sw.apiSecret, pairs, countdown)
+
+	s.mu.Lock()
+	if err == nil {
+		recovered := sw.failures > 0
+		failures := sw.failures
+		sw.failures = 0
+		botIDs := sw.botIDs()
+		s.mu.Unlock()
+
+		if recovered {
+			s.log.Infof("Deadman heartbeat recovered for user %s API key %d after %d failures", sw.userID, sw.apiKeyID, failures)
+			s.notificationService.NotifyDeadmanAlert(context.Background(), sw.userID, model.WSDeadmanAlertPayload{
+				Pair:             strings.Join(pairs, ","),
+				BotIDs:           botIDs,
+				Status:           "recovered",
+				CountdownSeconds: int(countdown.Seconds()),
+			})
+		}
+		return nil
+	}
+	sw.failures++
+	failures := sw.failures
+	botIDs := sw.botIDs()
+	s.mu.Unlock()
+
+	s.log.Warnf("Deadman heartbeat failed for user %s API key %d pairs %s (%d consecutive): %v",
+		sw.userID, sw.apiKeyID, strings.Join(pairs, ","), failures, err)
+
+	// Notify on first failure and then every 3rd to avoid flooding
+	if failures == 1 || failures%3 == 0 {
+		s.notificationService.NotifyDeadmanAlert(context.Background(), sw.userID, model.WSDeadmanAlertPayload{
+			Pair:                strings.Join(pairs, ","),
+			BotIDs:              botIDs,
+			Status:              "failing",
+			ConsecutiveFailures: failures,
+			CountdownSeconds:    int(countdown.Seconds()),
+			Error:               err.Error(),
+		})
+	}
+	return err
+}
+
+// reset makes the heartbeat loop refresh the exchange timers right away
+func (sw *deadmanSwitch) reset() {
+	select {
+	case sw.resetChan <- struct{}{}:
+	default:
+	}
+}
+
+// pairList returns the pairs armed on the switch, sorted (caller must hold s.mu)
+func (sw *deadmanSwitch) pairList() []string {
+	pairs := make([]string, 0, len(sw.pairs))
+	for pair := range sw.pairs {
+		pairs = append(pairs, pair)
+	}
+	sort.Strings(pairs)
+	return pairs
+}
+
+// botIDs returns the bot IDs registered on the switch (caller must hold s.mu)
+func (sw *deadmanSwitch) botIDs() []int64 {
+	seen := make(map[int64]bool)
+	ids := make([]int64, 0)
+	for _, bots := range sw.pairs {
+		for id := range bots {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids
+}
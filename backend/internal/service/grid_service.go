@@ -0,0 +1,888 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"tuyul/backend/internal/exchange"
+	"tuyul/backend/internal/model"
+	"tuyul/backend/internal/repository"
+	"tuyul/backend/internal/service/market"
+	"tuyul/backend/internal/util"
+)
+
+// Grid bot limits and pacing
+const (
+	gridMinCount      = 2
+	gridMaxCount      = 100
+	gridTickInterval  = 3 * time.Second  // Levels without a resting order are armed at this pace
+	gridOrdersPerTick = 5                // Orders placed per tick, large ladders are armed over several ticks
+	gridOrderTimeout  = 10 * time.Second // Per cancel or order check, so one slow call does not starve the rest
+)
+
+// GridService runs grid bots: a ladder of limit orders across a price range where
+// every level buys at its lower price, sells at its upper price and buys again
+type GridService struct {
+	botRunner
+	apiKeyService     *APIKeyService
+	marketDataService *market.MarketDataService
+	orderMonitor      *OrderMonitor
+	paperExchange     *PaperExchange
+	exchange          exchange.Exchange
+
+	// Runtime bots
+	instances map[int64]*GridInstance
+	mu        sync.RWMutex
+}
+
+// GridInstance represents a running grid bot in memory
+type GridInstance struct {
+	botInstance // WakeChan is signalled when a level needs a new order
+}
+
+func NewGridService(
+	botRepo *repository.BotRepository,
+	orderRepo *repository.OrderRepository,
+	apiKeyService *APIKeyService,
+	marketDataService *market.MarketDataService,
+	orderMonitor *OrderMonitor,
+	notificationService *NotificationService,
+	deadmanService *DeadmanService,
+	paperExchange *PaperExchange,
+	ex exchange.Exchange,
+	liveFeeFallbackPct float64,
+) *GridService {
+	s := &GridService{
+		botRunner:         newBotRunner("Grid", botRepo, orderRepo, notificationService, deadmanService, liveFeeFallbackPct),
+		apiKeyService:     apiKeyService,
+		marketDataService: marketDataService,
+		orderMonitor:      orderMonitor,
+		paperExchange:     paperExchange,
+		exchange:          ex,
+		instances:         make(map[int64]*GridInstance),
+	}
+
+	// Register order update handler for live bots
+	orderMonitor.AddOrderHandler(s.handleOrderUpdate)
+
+	return s
+}
+
+// CreateBot creates a new grid bot configuration
+func (s *GridService) CreateBot(ctx context.Context, userID string, req *model.BotConfigRequest) (*model.BotConfig, error) {
+	// 1. Validate parameters
+	pairInfo, err := s.validateBotConfig(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2. Check for duplicate bot (same type, pair, and mode)
+	if err := checkDuplicateBot(ctx, s.botRepo, userID, model.BotTypeGrid, "grid", req, 0); err != nil {
+		return nil, err
+	}
+
+	// 3. Bind the requested API key of live bots, or the user's default key if none is given
+	apiKeyID, err := resolveBotAPIKeyID(ctx, s.apiKeyService, userID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// 4. Create bot config, the ladder is built on first start
+	grid := *req.Grid
+	bot := &model.BotConfig{
+		UserID:            userID,
+		Name:              req.Name,
+		Type:              model.BotTypeGrid,
+		Pair:              req.Pair,
+		IsPaperTrading:    req.IsPaperTrading,
+		APIKeyID:          apiKeyID,
+		InitialBalanceIDR: req.InitialBalanceIDR,
+		OrderSizeIDR:      req.OrderSizeIDR,
+		Grid:              &grid,
+		Deadman:           req.Deadman,
+		Balances: map[string]float64{
+			"idr":                              req.InitialBalanceIDR,
+			baseCurrencyOf(req.Pair, pairInfo): 0,
+		},
+		Status:    model.BotStatusStopped,
+		CreatedAt: time.Now(),
+		UpdatedAt: time.Now(),
+	}
+
+	// 5. Save to repository
+	if err := s.botRepo.Create(ctx, bot); err != nil {
+		s.log.Errorf("Failed to create grid bot: %v", err)
+		return nil, util.ErrInternalServer("Failed to create bot")
+	}
+
+	s.log.Infof("Grid bot created: ID=%d, Pair=%s, Range=%.8g-%.8g, Grids=%d (%s), PaperTrading=%v",
+		bot.ID, bot.Pair, grid.LowerPrice, grid.UpperPrice, grid.GridCount, grid.Spacing, bot.IsPaperTrading)
+
+	return bot, nil
+}
+
+// GetBot retrieves a grid bot by ID and verifies ownership
+func (s *GridService) GetBot(ctx context.Context, userID string, botID int64) (*model.BotConfig, error) {
+	bot, err := s.botRepo.GetByID(ctx, botID)
+	if err != nil {
+		return nil, util.ErrNotFound("Bot not found")
+	}
+
+	if bot.UserID != userID {
+		return nil, util.ErrForbidden("Access denied")
+	}
+	if bot.Type != model.BotTypeGrid {
+		return nil, util.ErrBadRequest("Not a grid bot")
+	}
+
+	return bot, nil
+}
+
+// UpdateBot updates a grid bot configuration. A changed ladder is rebuilt on the next start,
+// which is only allowed while no level holds coins or an order.
+func (s *GridService) UpdateBot(ctx context.Context, userID string, botID int64, req *model.BotConfigRequest) (*model.BotConfig, error) {
+	bot, err := s.GetBot(ctx, userID, botID)
+	if err != nil {
+		return nil, err
+	}
+
+	if bot.Status == model.BotStatusRunning {
+		return nil, util.ErrBadRequest("Cannot update a running bot. Stop it first.")
+	}
+
+	pairInfo, err := s.validateBotConfig(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Check for duplicate bot if pair or mode is being changed (exclude current bot)
+	if bot.Pair != req.Pair || bot.IsPaperTrading != req.IsPaperTrading {
+		if err := checkDuplicateBot(ctx, s.botRepo, userID, model.BotTypeGrid, "grid", req, botID); err != nil {
+			return nil, err
+		}
+	}
+
+	ladderChanged := bot.Pair != req.Pair || bot.IsPaperTrading != req.IsPaperTrading ||
+		bot.OrderSizeIDR != req.OrderSizeIDR || bot.Grid == nil || *bot.Grid != *req.Grid
+	if ladderChanged {
+		for _, level := range bot.GridLevels {
+			if level.Held > 0 || level.OrderID != "" {
+				return nil, util.ErrBadRequest("Cannot change the grid while its levels hold coins or orders. Start the bot until they are sold, or create a new bot.")
+			}
+		}
+		bot.GridLevels = nil
+	}
+
+	apiKeyID, err := resolveBotAPIKeyID(ctx, s.apiKeyService, userID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Update fields
+	grid := *req.Grid
+	bot.Name = req.Name
+	bot.Pair = req.Pair
+	bot.IsPaperTrading = req.IsPaperTrading
+	bot.APIKeyID = apiKeyID
+	bot.InitialBalanceIDR = req.InitialBalanceIDR
+	bot.OrderSizeIDR = req.OrderSizeIDR
+	bot.Grid = &grid
+	if req.Deadman != nil {
+		bot.Deadman = req.Deadman
+	}
+	if bot.Balances == nil {
+		bot.Balances = map[string]float64{"idr": req.InitialBalanceIDR}
+	}
+	base := baseCurrencyOf(req.Pair, pairInfo)
+	if _, ok := bot.Balances[base]; !ok {
+		bot.Balances[base] = 0
+	}
+
+	if err := s.botRepo.Update(ctx, bot, ""); err != nil {
+		return nil, err
+	}
+
+	return bot, nil
+}
+
+// DeleteBot deletes a grid bot and its orders
+func (s *GridService) DeleteBot(ctx context.Context, userID string, botID int64) error {
+	bot, err := s.GetBot(ctx, userID, botID)
+	if err != nil {
+		return err
+	}
+
+	if bot.Status == model.BotStatusRunning {
+		return util.ErrBadRequest("Cannot delete a running bot. Stop it first.")
+	}
+
+	orders, err := s.orderRepo.ListByParentAndUser(ctx, userID, "bot", botID, 0) // 0 = no limit, get all
+	if err != nil {
+		s.log.Warnf("Failed to list orders for grid bot %d: %v", botID, err)
+	} else {
+		for _, order := range orders {
+			if err := s.orderRepo.Delete(ctx, order.ID); err != nil {
+				s.log.Warnf("Failed to delete order %d for grid bot %d: %v", order.ID, botID, err)
+			}
+		}
+	}
+
+	return s.botRepo.Delete(ctx, botID)
+}
+
+// StartBot starts a grid bot instance
+func (s *GridService) StartBot(ctx context.Context, userID string, botID int64) error {
+	bot, err := s.GetBot(ctx, userID, botID)
+	if err != nil {
+		return err
+	}
+
+	if bot.Status == model.BotStatusRunning {
+		return util.ErrBadRequest("Bot is already running")
+	}
+	if bot.Grid == nil {
+		return util.ErrBadRequest("Grid parameters are missing")
+	}
+
+	s.mu.RLock()
+	_, exists := s.instances[botID]
+	s.mu.RUnlock()
+	if exists {
+		return util.ErrBadRequest("Bot instance already exists")
+	}
+
+	// 1. Create instance
+	pairInfo, ok := s.marketDataService.GetPairInfo(bot.Pair)
+	if !ok {
+		return util.ErrBadRequest("Invalid pair")
+	}
+	inst := &GridInstance{botInstance{
+		Config:       bot,
+		StopChan:     make(chan struct{}),
+		WakeChan:     make(chan struct{}, 1),
+		BaseCurrency: baseCurrencyOf(bot.Pair, pairInfo),
+		PairInfo:     &pairInfo,
+	}}
+	if bot.Balances == nil {
+		bot.Balances = map[string]float64{"idr": bot.InitialBalanceIDR}
+	}
+	if _, ok := bot.Balances[inst.BaseCurrency]; !ok {
+		bot.Balances[inst.BaseCurrency] = 0
+	}
+
+	// 2. Setup Trade Client (paper fills arrive like live order updates)
+	inst.TradeClient, err = CreateTradeClient(ctx, bot, s.apiKeyService, s.exchange, s.paperExchange,
+		func(update *exchange.OrderUpdate) {
+			s.handleOrderUpdate(userID, update)
+		})
+	if err != nil {
+		return err
+	}
+	if !bot.IsPaperTrading && !s.orderMonitor.IsSubscribed(bot.BoundAPIKeyID()) {
+		s.log.Errorf("API key %d of user %s is not subscribed to order updates. Subscription is required for live trading.", bot.BoundAPIKeyID(), userID)
+		return fmt.Errorf("cannot start live bot: user is not subscribed to order updates. Please ensure your API key is configured correctly")
+	}
+
+	// 3. Build the ladder on first start, the event loop settles the orders left by the previous run
+	if len(bot.GridLevels) == 0 {
+		levels, err := s.buildLevels(bot.Grid, bot.OrderSizeIDR, pairInfo)
+		if err != nil {
+			return err
+		}
+		inst.mu.Lock()
+		bot.GridLevels = levels
+		s.saveLevels(ctx, inst)
+		inst.mu.Unlock()
+	}
+
+	// 4. Update status in database
+	if err := s.botRepo.UpdateStatus(ctx, botID, model.BotStatusRunning, nil); err != nil {
+		return err
+	}
+	bot.Status = model.BotStatusRunning
+	bot.ErrorMessage = nil
+
+	// 5. Store instance
+	s.mu.Lock()
+	if _, exists := s.instances[botID]; exists {
+		s.mu.Unlock()
+		return util.ErrBadRequest("Bot instance already exists")
+	}
+	s.instances[botID] = inst
+	s.mu.Unlock()
+
+	// 6. Arm deadman switch (live only) so resting orders are cancelled if we go down
+	if enabled, countdown := bot.DeadmanSettings(); enabled {
+		if err := s.deadmanService.Arm(ctx, userID, bot.BoundAPIKeyID(), bot.Pair, botID, countdown); err != nil {
+			s.log.Warnf("Grid bot %d: Failed to arm deadman switch for %s: %v", botID, bot.Pair, err)
+		}
+	}
+
+	// 7. Start event loop
+	go s.runBot(inst)
+
+	s.log.Infof("Grid bot %d started for pair %s with %d levels", botID, bot.Pair, len(bot.GridLevels))
+
+	inst.mu.Lock()
+	s.notifyBot(inst)
+	inst.mu.Unlock()
+
+	return nil
+}
+
+// StopBot stops a grid bot. Resting orders are cancelled in the background.
+func (s *GridService) StopBot(ctx context.Context, userID string, botID int64) error {
+	bot, err := s.GetBot(ctx, userID, botID)
+	if err != nil {
+		return err
+	}
+
+	if bot.Status != model.BotStatusRunning {
+		return nil
+	}
+
+	s.mu.Lock()
+	inst, ok := s.instances[botID]
+	delete(s.instances, botID)
+	s.mu.Unlock()
+
+	if err := s.botRepo.UpdateStatus(ctx, botID, model.BotStatusStopped, nil); err != nil {
+		s.log.Errorf("Failed to update status for grid bot %d: %v", botID, err)
+		return err
+	}
+	if !ok {
+		s.log.Warnf("Grid bot %d not found in instances map, status updated anyway", botID)
+		return nil
+	}
+
+	close(inst.StopChan)
+
+	inst.mu.Lock()
+	inst.Config.Status = model.BotStatusStopped
+	inst.mu.Unlock()
+
+	s.log.Infof("Grid bot %d stopped", botID)
+
+	// The event loop cancels the orders on exit (don't block the response),
+	// the deadman switch stays armed until they are
+	return nil
+}
+
+// stopBotWithError stops a live bot and sets error status
+func (s *GridService) stopBotWithError(inst *GridInstance, errorMsg string) {
+	if inst.Config.IsPaperTrading {
+		return
+	}
+
+	botID := inst.Config.ID
+	s.mu.Lock()
+	if s.instances[botID] != inst {
+		s.mu.Unlock()
+		return
+	}
+	delete(s.instances, botID)
+	s.mu.Unlock()
+
+	s.markError(inst, errorMsg)
+}
+
+func (s *GridService) runBot(inst *GridInstance) {
+	s.log.Infof("Starting event loop for grid bot %d", inst.Config.ID)
+	defer s.log.Infof("Event loop stopped for grid bot %d", inst.Config.ID)
+
+	ticker := time.NewTicker(gridTickInterval)
+	defer ticker.Stop()
+
+	// Orders left by the previous run are settled before their levels get new ones
+	s.settleLevels(inst, false)
+	s.placeOrders(inst)
+	for {
+		select {
+		case <-inst.StopChan:
+			// Cancelled here so no placement is in flight
+			s.cancelLevelOrders(inst)
+			return
+		case <-ticker.C:
+			s.placeOrders(inst)
+		case <-inst.WakeChan:
+			s.placeOrders(inst)
+		}
+	}
+}
+
+// placeOrders places the next order of the levels without a resting order
+func (s *GridService) placeOrders(inst *GridInstance) {
+	if time.Now().Before(inst.PausedUntil) {
+		return
+	}
+	// No new orders on stale market data (silent stream), resting ones are still managed
+	if s.marketDataService.IsStale(inst.Config.Pair, time.Now()) {
+		s.log.Debugf("Grid bot %d: Market data for %s is stale, not placing new orders", inst.Config.ID, inst.Config.Pair)
+		return
+	}
+	ctx := context.Background()
+	coin, err := s.marketDataService.GetCoin(ctx, inst.Config.Pair)
+	if err != nil || coin.BestBid <= 0 || coin.BestAsk <= 0 {
+		s.log.Debugf("Grid bot %d: No order book for %s yet", inst.Config.ID, inst.Config.Pair)
+		return
+	}
+
+	placed, changed := 0, false
+	for i := range inst.Config.GridLevels {
+		inst.mu.Lock()
+		// The bot may have been stopped meanwhile
+		if inst.stopped() {
+			inst.mu.Unlock()
+			return
+		}
+		level := inst.Config.GridLevels[i]
+		if level.OrderID != "" {
+			inst.mu.Unlock()
+			continue
+		}
+		if placed == gridOrdersPerTick {
+			inst.wake() // Continue with the remaining levels right away
+			inst.mu.Unlock()
+			break
+		}
+
+		side, trades := level.Side, level.Trades
+		order := s.prepareLevelOrder(ctx, inst, i, coin.BestBid, coin.BestAsk)
+		// Side switches without an order are persisted too
+		changed = changed || level.Side != side || level.Trades != trades
+		inst.mu.Unlock()
+
+		if order == nil {
+			continue
+		}
+		ok, stop := s.placeLevelOrder(ctx, inst, order)
+		if ok {
+			placed++
+		}
+		changed = changed || ok
+		if stop {
+			break
+		}
+	}
+
+	if changed {
+		inst.mu.Lock()
+		s.saveLevels(ctx, inst)
+		s.notifyBot(inst)
+		inst.mu.Unlock()
+	}
+}
+
+// prepareLevelOrder records the next order of a level and locks its funds (caller must hold inst.mu).
+// Returns nil when the level has nothing to place.
+func (s *GridService) prepareLevelOrder(ctx context.Context, inst *GridInstance, index int, bid, ask float64) *gridLevelOrder {
+	level := inst.Config.GridLevels[index]
+	precision := util.GetVolumePrecision(*inst.PairInfo)
+
+	var price, amount float64
+	if level.Side == "sell" {
+		// Never sell below the level's price, above it when the market already is
+		price = math.Max(level.SellPrice, bid)
+		amount = util.FloorToPrecision(level.Held, precision)
+		if !inst.tradable(amount, price) {
+			// The remainder is too small to sell, it stays in the balance and the level buys again
+			s.completeRoundTrip(inst, level)
+			return nil
+		}
+		if inst.Config.Balances[inst.BaseCurrency] < amount {
+			s.log.Warnf("Grid bot %d: Level %d holds %.8f %s but the balance has %.8f", inst.Config.ID, index,
+				amount, inst.BaseCurrency, inst.Config.Balances[inst.BaseCurrency])
+			return nil
+		}
+	} else {
+		// Levels above the market buy at the ask, acquiring the coins they sell
+		price = math.Min(level.BuyPrice, ask)
+		amount = util.FloorToPrecision(level.Amount-level.Held, precision)
+		if !inst.tradable(amount, price) {
+			if level.Held > 0 {
+				level.Side = "sell"
+			}
+			return nil
+		}
+		if inst.Config.Balances["idr"] < amount*price {
+			s.log.Debugf("Grid bot %d: Not enough IDR for level %d (%.2f < %.2f)", inst.Config.ID, index,
+				inst.Config.Balances["idr"], amount*price)
+			return nil
+		}
+	}
+
+	// Record the order on the level BEFORE the API call, updates are matched against it
+	clientOrderID := s.clientOrderID(inst.Config.ID, fmt.Sprintf("%d-%s", index, level.Side))
+	level.OrderID = clientOrderID
+	level.OrderPrice = price
+	level.OrderAmount = amount
+	level.Filled = 0
+	level.OrderFeeIDR = 0
+	s.lockFunds(inst, level.Side, price, amount, 1)
+	s.createOrderRecord(ctx, inst, clientOrderID, level.Side, price, amount)
+
+	return &gridLevelOrder{level: level, index: index, orderID: clientOrderID, side: level.Side, price: price, amount: amount}
+}
+
+// placeLevelOrder places a prepared level order without holding inst.mu, a rejected order
+// releases its funds. Returns whether it was placed and whether placement should stop for this tick.
+func (s *GridService) placeLevelOrder(ctx context.Context, inst *GridInstance, o *gridLevelOrder) (bool, bool) {
+	_, err := inst.TradeClient.Trade(ctx, o.side, inst.Config.Pair, o.price, o.amount, "limit", o.orderID)
+	if err == nil {
+		s.log.Infof("Grid bot %d: Level %d placed %s %.8f @ %.8g (OrderID: %s)", inst.Config.ID, o.index, o.side, o.amount, o.price, o.orderID)
+		return true, false
+	}
+	s.log.Errorf("Grid bot %d: Failed to place %s order of level %d: %v", inst.Config.ID, o.side, o.index, err)
+
+	inst.mu.Lock()
+	defer inst.mu.Unlock()
+
+	if o.level.OrderID == o.orderID {
+		o.level.OrderID = ""
+		o.level.OrderPrice = 0
+		o.level.OrderAmount = 0
+		s.lockFunds(inst, o.side, o.price, o.amount, -1)
+	}
+	s.updateOrderRecord(inst, o.orderID, exchange.OrderStatusCancelled, 0, 0)
+	return false, s.handleAPIError(inst, err, func(errorMsg string) {
+		s.stopBotWithError(inst, errorMsg)
+	})
+}
+
+// lockFunds moves the funds of an order out of the balances, or back with sign -1 (caller must hold inst.mu)
+func (s *GridService) lockFunds(inst *GridInstance, side string, price, amount, sign float64) {
+	if side == "sell" {
+		inst.Config.Balances[inst.BaseCurrency] -= sign * amount
+	} else {
+		inst.Config.Balances["idr"] -= sign * amount * price
+	}
+}
+
+// handleOrderUpdate applies an order update to the grid level the order belongs to
+func (s *GridService) handleOrderUpdate(userID string, update *exchange.OrderUpdate) {
+	if !update.IsFill() && update.Status != exchange.OrderStatusCancelled {
+		return
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, inst := range s.instances {
+		if inst.Config.UserID != userID {
+			continue
+		}
+
+		inst.mu.Lock()
+		level := inst.levelByOrder(update.ClientOrderID, update.OrderID)
+		if level != nil {
+			s.log.Debugf("Grid bot %d: Order %s %s, executed %.8f/%.8f", inst.Config.ID, level.OrderID,
+				update.Status, update.ExecutedQty, update.OrigQty)
+			s.applyExecution(inst, level, update.Status, update.ExecutedQty, update.Price, update.Fee)
+			s.saveLevels(context.Background(), inst)
+			s.notifyBot(inst)
+		}
+		inst.mu.Unlock()
+
+		if level != nil {
+			return
+		}
+	}
+}
+
+// applyExecution brings a level up to date with its resting order (caller must hold inst.mu).
+// executed is cumulative, fee covers the quantity executed since the previous update.
+// Live fills the exchange reports without a fee are charged the fallback rate.
+func (s *GridService) applyExecution(inst *GridInstance, level *model.GridLevel, status string, executed, price, fee float64) {
+	if price <= 0 {
+		price = level.OrderPrice
+	}
+	delta := executed - level.Filled
+	if fee <= 0 && delta > 0 && !inst.Config.IsPaperTrading {
+		fee = delta * price * s.liveFeeRate
+	}
+
+	if delta > 0 {
+		value := delta * price
+		level.Filled = executed
+		if level.Side == "sell" {
+			inst.Config.Balances["idr"] += value
+			level.Held -= delta
+			level.ProceedsIDR += value
+		} else {
+			// IDR was locked at the limit price, a better price returns the difference
+			inst.Config.Balances[inst.BaseCurrency] += delta
+			inst.Config.Balances["idr"] += delta*level.OrderPrice - value
+			level.Held += delta
+			level.CostIDR += value
+		}
+	}
+
+	if fee > 0 {
+		inst.Config.Balances["idr"] -= fee
+		level.OrderFeeIDR += fee
+		if level.Side == "sell" {
+			level.ProceedsIDR -= fee
+		} else {
+			level.CostIDR += fee
+		}
+	}
+
+	if status == exchange.OrderStatusFilled || status == exchange.OrderStatusCancelled {
+		s.closeOrder(inst, level, status)
+	} else {
+		s.updateOrderRecord(inst, level.OrderID, "open", level.Filled, 0)
+	}
+}
+
+// closeOrder releases what a finished order did not use and picks the next side of its level
+func (s *GridService) closeOrder(inst *GridInstance, level *model.GridLevel, status string) {
+	if unfilled := level.OrderAmount - level.Filled; unfilled > 0 {
+		if level.Side == "sell" {
+			inst.Config.Balances[inst.BaseCurrency] += unfilled
+		} else {
+			inst.Config.Balances["idr"] += unfilled * level.OrderPrice
+		}
+	}
+
+	recordStatus := status
+	if status == exchange.OrderStatusCancelled && level.Filled > 0 {
+		recordStatus = "partial"
+	}
+	s.updateOrderRecord(inst, level.OrderID, recordStatus, level.Filled, 0)
+
+	filled := status == exchange.OrderStatusFilled
+	side := level.Side
+	level.OrderID = ""
+	level.OrderPrice = 0
+	level.OrderAmount = 0
+	level.Filled = 0
+	level.OrderFeeIDR = 0
+
+	switch {
+	case side == "buy" && level.Held > 0 && (filled || inst.tradable(level.Held, level.SellPrice)):
+		level.Side = "sell"
+	case side == "sell" && filled:
+		s.completeRoundTrip(inst, level)
+	}
+
+	inst.wake()
+}
+
+// completeRoundTrip books the profit of a level's buy and sell and re-arms its buy
+func (s *GridService) completeRoundTrip(inst *GridInstance, level *model.GridLevel) {
+	profit := level.ProceedsIDR - level.CostIDR
+	level.Trades++
+	level.ProfitIDR += profit
+	level.Held = 0
+	level.CostIDR = 0
+	level.ProceedsIDR = 0
+	level.Side = "buy"
+
+	inst.Config.TotalTrades++
+	if profit > 0 {
+		inst.Config.WinningTrades++
+	}
+	inst.Config.TotalProfitIDR += profit
+	s.saveStats(inst)
+
+	s.log.Infof("Grid bot %d: Round trip %.8g → %.8g completed, profit %.2f IDR", inst.Config.ID, level.BuyPrice, level.SellPrice, profit)
+}
+
+// settleOrder applies the state of a level's order on the exchange, as returned by
+// GetOrder (caller must hold inst.mu). cancelled forces an open order to be closed,
+// after the bot cancelled it.
+func (s *GridService) settleOrder(inst *GridInstance, level *model.GridLevel, order *exchange.Order, err error, cancelled bool) {
+	switch {
+	case err == nil:
+		status := order.Status
+		if cancelled && status != exchange.OrderStatusFilled {
+			status = exchange.OrderStatusCancelled
+		}
+		s.applyExecution(inst, level, status, order.Amount-order.Remaining, order.Price, order.Fee-level.OrderFeeIDR)
+	case util.IsOrderNotFoundError(err):
+		// Unknown to the exchange (paper orders do not survive a restart), keep what was seen
+		s.log.Warnf("Grid bot %d: Order %s not found, closing it with %.8f executed", inst.Config.ID, level.OrderID, level.Filled)
+		s.applyExecution(inst, level, exchange.OrderStatusCancelled, level.Filled, 0, 0)
+	default:
+		s.log.Warnf("Grid bot %d: Failed to check order %s, keeping it: %v", inst.Config.ID, level.OrderID, err)
+	}
+}
+
+// settleLevels settles the orders resting on the levels, checking each on the exchange
+// without holding inst.mu
+func (s *GridService) settleLevels(inst *GridInstance, cancelled bool) {
+	orders := inst.levelOrders()
+	if len(orders) == 0 {
+		return
+	}
+
+	for _, o := range orders {
+		ctx, cancel := context.WithTimeout(context.Background(), gridOrderTimeout)
+		order, err := inst.TradeClient.GetOrder(ctx, inst.Config.Pair, o.orderID)
+		cancel()
+
+		inst.mu.Lock()
+		// Skip levels an order update settled meanwhile
+		if o.level.OrderID == o.orderID {
+			s.settleOrder(inst, o.level, order, err, cancelled)
+		}
+		inst.mu.Unlock()
+	}
+
+	inst.mu.Lock()
+	s.saveLevels(context.Background(), inst)
+	s.notifyBot(inst)
+	inst.mu.Unlock()
+}
+
+// cancelLevelOrders cancels the resting orders of a stopped bot and settles what they executed.
+// Every order is cancelled before any is settled so a large ladder is not left half cancelled.
+// The deadman switch of the pair is disarmed once all of them are, otherwise it is left to
+// expire so the exchange cancels the rest.
+func (s *GridService) cancelLevelOrders(inst *GridInstance) {
+	config := inst.Config
+	failed := 0
+	for _, o := range inst.levelOrders() {
+		ctx, cancel := context.WithTimeout(context.Background(), gridOrderTimeout)
+		err := inst.TradeClient.CancelOrder(ctx, config.Pair, o.orderID, o.side)
+		cancel()
+		if err != nil && !util.IsOrderNotFoundError(err) {
+			// Left on the level, the next start settles it
+			s.log.Warnf("Grid bot %d: Failed to cancel order %s: %v", config.ID, o.orderID, err)
+			failed++
+		}
+	}
+
+	if failed == 0 {
+		s.deadmanService.Disarm(config.UserID, config.Pair, config.ID)
+	} else {
+		s.deadmanService.Expire(config.UserID, config.Pair, config.ID)
+	}
+
+	s.settleLevels(inst, true)
+}
+
+// validateBotConfig validates a grid bot request and returns the pair info
+func (s *GridService) validateBotConfig(req *model.BotConfigRequest) (exchange.Pair, error) {
+	if req.Pair == "" {
+		return exchange.Pair{}, util.ErrBadRequest("Pair is required")
+	}
+	pairInfo, ok := s.marketDataService.GetPairInfo(req.Pair)
+	if !ok {
+		return exchange.Pair{}, util.ErrBadRequest(fmt.Sprintf("Invalid or unsupported pair: %s", req.Pair))
+	}
+
+	grid := req.Grid
+	if grid == nil {
+		return pairInfo, util.ErrBadRequest("Grid parameters are required")
+	}
+	if grid.Spacing == "" {
+		grid.Spacing = model.GridSpacingArithmetic
+	}
+	if grid.Spacing != model.GridSpacingArithmetic && grid.Spacing != model.GridSpacingGeometric {
+		return pairInfo, util.ErrBadRequest("Grid spacing must be arithmetic or geometric")
+	}
+	if grid.LowerPrice <= 0 || grid.UpperPrice <= grid.LowerPrice {
+		return pairInfo, util.ErrBadRequest("Grid upper price must be above a positive lower price")
+	}
+	if grid.GridCount < gridMinCount || grid.GridCount > gridMaxCount {
+		return pairInfo, util.ErrBadRequest(fmt.Sprintf("Grid count must be between %d and %d", gridMinCount, gridMaxCount))
+	}
+
+	if req.InitialBalanceIDR < util.MinInitialBalanceIDR {
+		return pairInfo, util.ErrBadRequest(fmt.Sprintf("Initial balance must be at least %.0f IDR", util.MinInitialBalanceIDR))
+	}
+	if req.OrderSizeIDR < util.MinOrderValueIDR {
+		return pairInfo, util.ErrBadRequest(fmt.Sprintf("Order size must be at least %.0f IDR", util.MinOrderValueIDR))
+	}
+	if req.OrderSizeIDR*float64(grid.GridCount) > req.InitialBalanceIDR {
+		return pairInfo, util.ErrBadRequest(fmt.Sprintf("Initial balance must cover one order per grid (%d x %.0f IDR)", grid.GridCount, req.OrderSizeIDR))
+	}
+
+	if _, err := s.buildLevels(grid, req.OrderSizeIDR, pairInfo); err != nil {
+		return pairInfo, err
+	}
+	return pairInfo, nil
+}
+
+// buildLevels builds the levels of a ladder, prices rounded to the pair's tick size
+func (s *GridService) buildLevels(grid *model.GridConfig, orderSizeIDR float64, pairInfo exchange.Pair) ([]*model.GridLevel, error) {
+	tick := util.GetTickSize(pairInfo, s.marketDataService)
+	precision := util.GetVolumePrecision(pairInfo)
+
+	prices := grid.Prices()
+	for i := range prices {
+		prices[i] = util.RoundToNearestIncrement(prices[i], tick)
+	}
+
+	levels := make([]*model.GridLevel, 0, grid.GridCount)
+	for i := 0; i < grid.GridCount; i++ {
+		buyPrice, sellPrice := prices[i], prices[i+1]
+		if buyPrice <= 0 || sellPrice <= buyPrice {
+			return nil, util.ErrBadRequest(fmt.Sprintf("Grid step is below the pair's price tick (%.8g), use fewer grids or a wider range", tick))
+		}
+
+		amount := util.FloorToPrecision(orderSizeIDR/buyPrice, precision)
+		if amount <= 0 || amount < pairInfo.MinBaseAmount || amount*buyPrice < pairInfo.MinQuoteAmount {
+			return nil, util.ErrBadRequest(fmt.Sprintf("Order size is below the pair's minimum order at %.8g", buyPrice))
+		}
+
+		levels = append(levels, &model.GridLevel{
+			BuyPrice:  buyPrice,
+			SellPrice: sellPrice,
+			Amount:    amount,
+			Side:      "buy",
+		})
+	}
+	return levels, nil
+}
+
+// saveLevels persists the balances and levels of a bot (caller must hold inst.mu)
+func (s *GridService) saveLevels(ctx context.Context, inst *GridInstance) {
+	s.saveBalances(ctx, inst)
+	if err := s.botRepo.UpdateGridLevels(ctx, inst.Config.ID, inst.Config.GridLevels); err != nil {
+		s.log.Warnf("Grid bot %d: Failed to save grid levels: %v", inst.Config.ID, err)
+	}
+}
+
+// levelByOrder returns the level whose resting order has one of the given IDs
+func (inst *GridInstance) levelByOrder(ids ...string) *model.GridLevel {
+	for _, level := range inst.Config.GridLevels {
+		if level.OrderID == "" {
+			continue
+		}
+		for _, id := range ids {
+			if id == level.OrderID {
+				return level
+			}
+		}
+	}
+	return nil
+}
+
+// gridLevelOrder is an order of a level, as prepared or as resting when it was listed
+type gridLevelOrder struct {
+	level   *model.GridLevel
+	index   int
+	orderID string
+	side    string
+	price   float64
+	amount  float64
+}
+
+// levelOrders lists the orders resting on the levels, lowest level first
+func (inst *GridInstance) levelOrders() []gridLevelOrder {
+	inst.mu.Lock()
+	defer inst.mu.Unlock()
+
+	var orders []gridLevelOrder
+	for _, level := range inst.Config.GridLevels {
+		if level.OrderID != "" {
+			orders = append(orders, gridLevelOrder{level: level, orderID: level.OrderID, side: level.Side})
+		}
+	}
+	return orders
+}
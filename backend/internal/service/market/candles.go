@@ -0,0 +1,91 @@
+package market
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"tuyul/backend/internal/model"
+	"tuyul/backend/pkg/logger"
+)
+
+// buildCandle turns the live bucket of a coin into a candle ending at closeTime.
+// Buckets never reset open one timeframe before closeTime.
+func buildCandle(coin *model.Coin, tf model.TimeframeSpec, closeTime time.Time) (*model.Candle, bool) {
+	data := coin.Timeframes[tf.Name]
+	if data == nil || data.Open == 0 {
+		return nil, false
+	}
+
+	openTime := coin.LastReset[tf.Name]
+	if openTime.IsZero() {
+		openTime = closeTime.Add(-tf.Duration)
+	}
+
+	return &model.Candle{
+		Pair:        coin.PairID,
+		Timeframe:   tf.Name,
+		OpenTime:    openTime,
+		CloseTime:   closeTime,
+		Open:        data.Open,
+		High:        data.High,
+		Low:         data.Low,
+		Close:       coin.CurrentPrice,
+		Volume:      data.BaseVolume,
+		QuoteVolume: data.BuyVolume + data.SellVolume,
+		BuyVolume:   data.BuyVolume,
+		SellVolume:  data.SellVolume,
+		VWAP:        data.VWAP,
+		Trades:      data.Trx,
+	}, true
+}
+
+// saveClosedCandle persists the bucket of a timeframe that is about to be reset
+func (s *MarketDataService) saveClosedCandle(coin *model.Coin, tf model.TimeframeSpec, now time.Time) {
+	candle, ok := buildCandle(coin, tf, now)
+	if !ok {
+		return
+	}
+	s.appendCandleHistory(candle)
+
+	if s.candles == nil {
+		return
+	}
+	if err := s.candles.Save(context.Background(), candle); err != nil {
+		logger.Errorf("Failed to save %s candle for %s: %v", tf.Name, coin.PairID, err)
+	}
+}
+
+// GetCandles returns the closed candles of a pair opened within [from, to], oldest first.
+// Zero times leave that side of the range open, limit <= 0 returns the whole range.
+func (s *MarketDataService) GetCandles(ctx context.Context, pairID, tf string, from, to time.Time, limit int) ([]model.Candle, error) {
+	if !s.HasTimeframe(tf) {
+		return nil, fmt.Errorf("invalid timeframe: %s", tf)
+	}
+	if s.candles == nil {
+		return []model.Candle{}, nil
+	}
+	return s.candles.List(ctx, pairID, tf, from, to, limit)
+}
+
+// GetRecentCandles returns the last n closed candles of a pair, oldest first
+func (s *MarketDataService) GetRecentCandles(ctx context.Context, pairID, tf string, n int) ([]model.Candle, error) {
+	if n <= 0 {
+		return []model.Candle{}, nil
+	}
+	return s.GetCandles(ctx, pairID, tf, time.Time{}, time.Time{}, n)
+}
+
+// GetCurrentCandle returns the in-progress candle of a pair, closing at the
+// current time, or false when the bucket has no price yet
+func (s *MarketDataService) GetCurrentCandle(pairID, tf string) (*model.Candle, bool) {
+	spec, ok := s.timeframe(tf)
+	if !ok {
+		return nil, false
+	}
+	val, ok := s.coinCache.Load(pairID)
+	if !ok {
+		return nil, false
+	}
+	return buildCandle(val.(*model.Coin), spec, time.Now())
+}
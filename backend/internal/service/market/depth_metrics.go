@@ -0,0 +1,235 @@
+package market
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"tuyul/backend/internal/model"
+	"tuyul/backend/pkg/logger"
+)
+
+const (
+	// Timeout of one REST depth sample
+	depthSampleTimeout = 10 * time.Second
+	// Minimum delay between two REST depth samples, leaves room in the public rate limit
+	depthSamplePace = 500 * time.Millisecond
+	// Levels a side needs within the band before walls are detected
+	minWallLevels = 3
+)
+
+// DepthConfig controls the order book depth metrics
+type DepthConfig struct {
+	SampleInterval time.Duration // Between REST samples of all pairs, 0 disables sampling
+	BandPct        float64       // Depth is summed within this percentage of the mid price
+	WallMultiplier float64       // A level is a wall at this multiple of the median level in the band
+}
+
+// DefaultDepthConfig returns the depth settings used when none are configured
+func DefaultDepthConfig() DepthConfig {
+	return DepthConfig{
+		SampleInterval: 5 * time.Minute,
+		BandPct:        2,
+		WallMultiplier: 5,
+	}
+}
+
+// SetDepthConfig replaces the depth settings, call before Start
+func (s *MarketDataService) SetDepthConfig(cfg DepthConfig) {
+	s.depth = cfg
+}
+
+// CalculateDepthMetrics derives the depth metrics of a book, nil if a side is empty.
+// Levels are best first on both sides.
+func CalculateDepthMetrics(bids, asks []OrderBookLevel, bandPct, wallMultiplier float64) *model.DepthMetrics {
+	if len(bids) == 0 || len(asks) == 0 {
+		return nil
+	}
+	bestBid, bestAsk := bids[0], asks[0]
+	mid := (bestBid.Price + bestAsk.Price) / 2
+	if mid <= 0 {
+		return nil
+	}
+
+	m := &model.DepthMetrics{Mid: mid, Microprice: mid, BandPct: bandPct}
+	if size := bestBid.BaseVolume + bestAsk.BaseVolume; size > 0 {
+		m.Microprice = (bestBid.Price*bestAsk.BaseVolume + bestAsk.Price*bestBid.BaseVolume) / size
+	}
+
+	// 1. Levels within the band around the mid price
+	low, high := mid*(1-bandPct/100), mid*(1+bandPct/100)
+	bandBids := bids
+	for i, l := range bids {
+		if l.Price < low {
+			bandBids = bids[:i]
+			break
+		}
+	}
+	bandAsks := asks
+	for i, l := range asks {
+		if l.Price > high {
+			bandAsks = asks[:i]
+			break
+		}
+	}
+
+	// 2. Cumulative depth and imbalance
+	for _, l := range bandBids {
+		m.BidDepthIDR += levelIDR(l)
+	}
+	for _, l := range bandAsks {
+		m.AskDepthIDR += levelIDR(l)
+	}
+	if total := m.BidDepthIDR + m.AskDepthIDR; total > 0 {
+		m.Imbalance = (m.BidDepthIDR - m.AskDepthIDR) / total
+	}
+	if m.AskDepthIDR > 0 {
+		m.BidAskRatio = m.BidDepthIDR / m.AskDepthIDR
+	}
+
+	// 3. Walls
+	m.BidWall = findWall(bandBids, mid, wallMultiplier)
+	m.AskWall = findWall(bandAsks, mid, wallMultiplier)
+	return m
+}
+
+// levelIDR returns the IDR volume of a level, derived from the base volume if missing
+func levelIDR(l OrderBookLevel) float64 {
+	if l.IDRVolume > 0 {
+		return l.IDRVolume
+	}
+	return l.Price * l.BaseVolume
+}
+
+// findWall returns the largest level if it is at least multiplier times the median level
+func findWall(levels []OrderBookLevel, mid, multiplier float64) *model.DepthWall {
+	if multiplier <= 0 || len(levels) < minWallLevels {
+		return nil
+	}
+
+	volumes := make([]float64, len(levels))
+	largest := 0
+	for i, l := range levels {
+		volumes[i] = levelIDR(l)
+		if volumes[i] > volumes[largest] {
+			largest = i
+		}
+	}
+	wallIDR := volumes[largest]
+
+	sort.Float64s(volumes)
+	median := volumes[len(volumes)/2]
+	if len(volumes)%2 == 0 {
+		median = (volumes[len(volumes)/2-1] + median) / 2
+	}
+	if median <= 0 || wallIDR < multiplier*median {
+		return nil
+	}
+
+	return &model.DepthWall{
+		Price:       levels[largest].Price,
+		IDRVolume:   wallIDR,
+		DistancePct: math.Abs(levels[largest].Price-mid) / mid * 100,
+	}
+}
+
+// ApplyOrderBook updates the depth metrics of a subscribed pair from its live book.
+// Registered on the SubscriptionManager; stale books are ignored until resynced.
+func (s *MarketDataService) ApplyOrderBook(ticker OrderBookTicker) {
+	if ticker.Stale {
+		return
+	}
+	val, ok := s.coinCache.Load(ticker.Pair)
+	if !ok {
+		return
+	}
+	s.markStream(time.Now(), ticker.Pair)
+
+	m := CalculateDepthMetrics(ticker.Bids, ticker.Asks, s.depth.BandPct, s.depth.WallMultiplier)
+	if m == nil {
+		return
+	}
+	m.Source = model.DepthSourceLive
+	m.UpdatedAt = time.Now()
+	val.(*model.Coin).Depth = m
+}
+
+// hasLiveDepth reports whether a coin got live depth metrics within the last sample interval
+func (s *MarketDataService) hasLiveDepth(coin *model.Coin, now time.Time) bool {
+	d := coin.Depth
+	return d != nil && d.Source == model.DepthSourceLive && now.Sub(d.UpdatedAt) < s.depth.SampleInterval
+}
+
+// sampleDepth periodically samples the REST depth of every traded pair
+func (s *MarketDataService) sampleDepth() {
+	ticker := time.NewTicker(s.depth.SampleInterval)
+	defer ticker.Stop()
+
+	for {
+		s.sampleAllDepth()
+		<-ticker.C
+	}
+}
+
+// sampleAllDepth refreshes the depth metrics of every traded pair without a live book
+func (s *MarketDataService) sampleAllDepth() {
+	var coins []*model.Coin
+	now := time.Now()
+	s.coinCache.Range(func(_, val any) bool {
+		coin := val.(*model.Coin)
+		if coin.VolumeIDR > 0 && !s.hasLiveDepth(coin, now) {
+			coins = append(coins, coin)
+		}
+		return true
+	})
+
+	sampled := 0
+	for _, coin := range coins {
+		if s.sampleCoinDepth(coin) {
+			sampled++
+		}
+		time.Sleep(depthSamplePace)
+	}
+	logger.Debugf("Sampled order book depth of %d/%d pairs", sampled, len(coins))
+}
+
+// sampleCoinDepth fetches the REST depth of one pair and stores its metrics
+func (s *MarketDataService) sampleCoinDepth(coin *model.Coin) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), depthSampleTimeout)
+	defer cancel()
+
+	book, err := s.marketData.GetOrderBook(ctx, coin.PairID)
+	if err != nil {
+		logger.Warnf("Failed to sample depth of %s: %v", coin.PairID, err)
+		return false
+	}
+	ticker, ok := ToOrderBookTicker(book)
+	if !ok {
+		return false
+	}
+	m := CalculateDepthMetrics(ticker.Bids, ticker.Asks, s.depth.BandPct, s.depth.WallMultiplier)
+	if m == nil {
+		return false
+	}
+	m.Source = model.DepthSourceSampled
+	m.UpdatedAt = time.Now()
+
+	// A live book may have arrived while the sample was in flight
+	if s.hasLiveDepth(coin, m.UpdatedAt) {
+		return false
+	}
+	coin.Depth = m
+	s.saveCoinToRedis(coin)
+	return true
+}
+
+// GetDepthMetrics returns the latest depth metrics of a pair
+func (s *MarketDataService) GetDepthMetrics(pairID string) (*model.DepthMetrics, bool) {
+	val, ok := s.coinCache.Load(pairID)
+	if !ok {
+		return nil, false
+	}
+	d := val.(*model.Coin).Depth
+	return d, d != nil
+}
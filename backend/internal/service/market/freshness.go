@@ -0,0 +1,223 @@
+package market
+
+import (
+	"sync"
+	"time"
+
+	"tuyul/backend/internal/exchange"
+	"tuyul/backend/internal/model"
+	"tuyul/backend/pkg/logger"
+)
+
+const (
+	// Freshness of every pair is re-evaluated this often
+	freshnessCheckInterval = 5 * time.Second
+	// Longest delay between two forced reconnects of a silent stream
+	maxReconnectBackoff = 5 * time.Minute
+)
+
+// FreshnessConfig holds the market data staleness thresholds
+type FreshnessConfig struct {
+	FeedStaleAfter time.Duration // Stream silent this long marks every pair stale and forces a reconnect
+	PairStaleAfter time.Duration // A pair neither streamed nor polled this long is stale
+}
+
+// DefaultFreshnessConfig returns the default staleness thresholds
+func DefaultFreshnessConfig() FreshnessConfig {
+	return FreshnessConfig{
+		FeedStaleAfter: 30 * time.Second,
+		PairStaleAfter: 3 * time.Minute, // Three missed ticker polls
+	}
+}
+
+// SetFreshnessConfig overrides the staleness thresholds, call before Start
+func (s *MarketDataService) SetFreshnessConfig(cfg FreshnessConfig) {
+	s.freshness = cfg
+}
+
+// feedState tracks when market data was last received
+type feedState struct {
+	started    time.Time
+	lastStream time.Time            // Last stream message (summary, trades)
+	lastREST   time.Time            // Last successful ticker poll
+	seen       map[string]time.Time // Last confirmation of every pair, streamed or polled
+
+	// Silent stream handling
+	stale         bool
+	staleSince    time.Time
+	reconnects    int
+	nextReconnect time.Time
+
+	mu sync.Mutex
+}
+
+// markStream records stream data for some pairs
+func (s *MarketDataService) markStream(now time.Time, pairs ...string) {
+	s.feed.mu.Lock()
+	defer s.feed.mu.Unlock()
+	s.feed.lastStream = now
+	for _, pair := range pairs {
+		s.feed.seen[pair] = now
+	}
+}
+
+// markSeen records that the data of a pair was confirmed, e.g. by a REST poll
+func (s *MarketDataService) markSeen(pair string, now time.Time) {
+	s.feed.mu.Lock()
+	defer s.feed.mu.Unlock()
+	s.feed.seen[pair] = now
+}
+
+// markPolled records a successful ticker poll
+func (s *MarketDataService) markPolled(now time.Time) {
+	s.feed.mu.Lock()
+	defer s.feed.mu.Unlock()
+	s.feed.lastREST = now
+}
+
+// refreshStale sets the stale flag of a coin, returns whether it changed
+func (s *MarketDataService) refreshStale(coin *model.Coin, now time.Time) bool {
+	stale := s.IsStale(coin.PairID, now)
+	changed := coin.Stale != stale
+	coin.Stale = stale
+	return changed
+}
+
+// IsStale reports whether the data of a pair is too old to trade on
+func (s *MarketDataService) IsStale(pair string, now time.Time) bool {
+	s.feed.mu.Lock()
+	defer s.feed.mu.Unlock()
+	if s.feed.stale {
+		return true
+	}
+	seen, ok := s.feed.seen[pair]
+	if !ok {
+		// Never confirmed since startup
+		seen = s.feed.started
+	}
+	return now.Sub(seen) > s.freshness.PairStaleAfter
+}
+
+// IsFeedStale reports whether the market stream went silent
+func (s *MarketDataService) IsFeedStale() bool {
+	s.feed.mu.Lock()
+	defer s.feed.mu.Unlock()
+	return s.feed.stale
+}
+
+// monitorFreshness detects a silent stream and stale pairs
+func (s *MarketDataService) monitorFreshness() {
+	ticker := time.NewTicker(freshnessCheckInterval)
+	defer ticker.Stop()
+
+	for now := range ticker.C {
+		s.checkStream(now)
+
+		// Publish stale flag changes so bots and watchers react without new ticks
+		for _, coin := range s.Coins() {
+			if s.refreshStale(coin, now) {
+				go s.saveCoinToRedis(coin)
+				s.notify(coin)
+			}
+		}
+	}
+}
+
+// checkStream marks the feed stale when the stream is silent and escalates
+// forced reconnects with a growing backoff until data flows again
+func (s *MarketDataService) checkStream(now time.Time) {
+	s.feed.mu.Lock()
+	last := s.feed.lastStream
+	if last.IsZero() {
+		last = s.feed.started
+	}
+	lag := now.Sub(last)
+
+	if lag <= s.freshness.FeedStaleAfter {
+		if s.feed.stale {
+			logger.Infof("Market stream recovered after %s (%d forced reconnects)", now.Sub(s.feed.staleSince).Round(time.Second), s.feed.reconnects)
+		}
+		s.feed.stale = false
+		s.feed.reconnects = 0
+		s.feed.nextReconnect = time.Time{}
+		s.feed.mu.Unlock()
+		return
+	}
+
+	if !s.feed.stale {
+		s.feed.stale = true
+		s.feed.staleSince = now
+		logger.Warnf("Market stream silent for %s, marking all pairs stale", lag.Round(time.Second))
+	}
+	if now.Before(s.feed.nextReconnect) {
+		s.feed.mu.Unlock()
+		return
+	}
+
+	// Escalate: reconnect again after FeedStaleAfter, then double the wait each time
+	s.feed.reconnects++
+	attempt := s.feed.reconnects
+	backoff := s.freshness.FeedStaleAfter
+	for i := 1; i < attempt && backoff < maxReconnectBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxReconnectBackoff {
+		backoff = maxReconnectBackoff
+	}
+	s.feed.nextReconnect = now.Add(backoff)
+	s.feed.mu.Unlock()
+
+	reconnector, ok := s.stream.(exchange.StreamReconnector)
+	if !ok {
+		return
+	}
+	if attempt > 3 {
+		logger.Errorf("Market stream still silent after %d forced reconnects (lag %s), reconnecting again", attempt-1, lag.Round(time.Second))
+	} else {
+		logger.Warnf("Forcing market stream reconnect (attempt %d, lag %s)", attempt, lag.Round(time.Second))
+	}
+	reconnector.Reconnect()
+}
+
+// FeedHealth returns the freshness of the market data feed
+func (s *MarketDataService) FeedHealth() model.FeedHealth {
+	now := time.Now()
+	coins := s.Coins()
+	stalePairs := 0
+	for _, coin := range coins {
+		if s.IsStale(coin.PairID, now) {
+			stalePairs++
+		}
+	}
+
+	s.feed.mu.Lock()
+	defer s.feed.mu.Unlock()
+
+	health := model.FeedHealth{
+		Status:         model.FeedStatusOK,
+		RESTLagSeconds: -1,
+		Reconnects:     s.feed.reconnects,
+		StalePairs:     stalePairs,
+		TotalPairs:     len(coins),
+	}
+	last := s.feed.started
+	if !s.feed.lastStream.IsZero() {
+		last = s.feed.lastStream
+		lastStream := s.feed.lastStream
+		health.LastStreamAt = &lastStream
+	}
+	health.StreamLagSeconds = now.Sub(last).Seconds()
+	if !s.feed.lastREST.IsZero() {
+		health.RESTLagSeconds = now.Sub(s.feed.lastREST).Seconds()
+	}
+
+	switch {
+	case s.feed.stale:
+		health.Status = model.FeedStatusStale
+		staleSince := s.feed.staleSince
+		health.StaleSince = &staleSince
+	case stalePairs > 0:
+		health.Status = model.FeedStatusDegraded
+	}
+	return health
+}
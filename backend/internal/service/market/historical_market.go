@@ -0,0 +1,193 @@
+package market
+
+import (
+	"time"
+
+	"tuyul/backend/internal/model"
+	"tuyul/backend/internal/service/market/indicators"
+)
+
+// HistoricalMarket rebuilds the coin state the live market data service would
+// have computed from a series of closed candles of the shortest timeframe.
+// Buckets, sliding windows, 24h stats, indicators and scores use the same
+// formulas as live data, at the resolution of the candles.
+type HistoricalMarket struct {
+	timeframes []model.TimeframeSpec
+	profiles   []*model.PumpScoreProfile // Default profile first
+	pairs      map[string]*historicalPair
+}
+
+// historicalPair is the state of one pair
+type historicalPair struct {
+	coin    *model.Coin
+	recent  []model.Candle            // Base candles of the last 24h
+	live    map[string]*model.Candle  // In-progress bucket per timeframe
+	history map[string][]model.Candle // Closed buckets per timeframe, for indicators
+}
+
+// NewHistoricalMarket creates a historical market scoring with the given
+// active profiles (default first, see ActiveProfiles), the built-in formula when empty
+func NewHistoricalMarket(timeframes []model.TimeframeSpec, profiles []*model.PumpScoreProfile) *HistoricalMarket {
+	if len(profiles) == 0 {
+		profiles = ActiveProfiles(nil)
+	}
+	return &HistoricalMarket{
+		timeframes: timeframes,
+		profiles:   profiles,
+		pairs:      make(map[string]*historicalPair),
+	}
+}
+
+// BaseTimeframe returns the timeframe of the candles Step expects
+func (m *HistoricalMarket) BaseTimeframe() model.TimeframeSpec {
+	return m.timeframes[0]
+}
+
+// Step applies the next closed base candle of a pair and returns the coin state
+// at its close. Candles of a pair must be given oldest first.
+func (m *HistoricalMarket) Step(candle model.Candle) *model.Coin {
+	p, ok := m.pairs[candle.Pair]
+	if !ok {
+		p = &historicalPair{
+			coin: &model.Coin{
+				PairID:        candle.Pair,
+				QuoteCurrency: "idr",
+			},
+			live:    make(map[string]*model.Candle),
+			history: make(map[string][]model.Candle),
+		}
+		m.pairs[candle.Pair] = p
+	}
+	now := candle.CloseTime
+
+	// 1. Base candles of the last 24h
+	p.recent = append(p.recent, candle)
+	cut := 0
+	for cut < len(p.recent) && !p.recent[cut].OpenTime.After(now.Add(-24*time.Hour)) {
+		cut++
+	}
+	p.recent = p.recent[cut:]
+
+	// 2. Clock-reset buckets, closing the ones the candle is past
+	coin := &model.Coin{
+		PairID:        p.coin.PairID,
+		BaseCurrency:  p.coin.BaseCurrency,
+		QuoteCurrency: p.coin.QuoteCurrency,
+		CurrentPrice:  candle.Close,
+		BestBid:       candle.Close,
+		BestAsk:       candle.Close,
+		Timeframes:    make(model.Timeframes, len(m.timeframes)),
+		LastReset:     make(map[string]time.Time, len(m.timeframes)),
+		Windows:       make(map[string]model.WindowStats, len(m.timeframes)),
+		Indicators:    make(map[string]model.Indicators, len(m.timeframes)),
+		LastUpdate:    now,
+	}
+	for _, tf := range m.timeframes {
+		start := candle.OpenTime.Truncate(tf.Duration)
+		bucket := p.live[tf.Name]
+		if bucket != nil && !bucket.OpenTime.Equal(start) {
+			p.closeBucket(tf.Name)
+			bucket = nil
+		}
+		if bucket == nil {
+			bucket = &model.Candle{Pair: candle.Pair, Timeframe: tf.Name, OpenTime: start, Open: candle.Open, High: candle.High, Low: candle.Low}
+			p.live[tf.Name] = bucket
+		}
+		mergeCandle(bucket, candle)
+
+		coin.Timeframes[tf.Name] = &model.TimeframeData{
+			Open:       bucket.Open,
+			High:       bucket.High,
+			Low:        bucket.Low,
+			Trx:        bucket.Trades,
+			BaseVolume: bucket.Volume,
+			BuyVolume:  bucket.BuyVolume,
+			SellVolume: bucket.SellVolume,
+			VWAP:       bucket.VWAP,
+		}
+		coin.LastReset[tf.Name] = start
+
+		// 3. Sliding window ending at the candle close
+		coin.Windows[tf.Name] = windowStats(p.recent, now.Add(-tf.Duration))
+
+		// 4. Indicators over the closed buckets plus the in-progress one
+		series := append(append([]model.Candle(nil), p.history[tf.Name]...), *bucket)
+		series[len(series)-1].CloseTime = now
+		series[len(series)-1].Close = candle.Close
+		coin.Indicators[tf.Name] = indicators.Compute(series)
+	}
+
+	// 5. 24h summary
+	day := windowStats(p.recent, now.Add(-24*time.Hour))
+	coin.Open24h, coin.High24h, coin.Low24h = day.Open, day.High, day.Low
+	coin.Change24h = day.ChangePct
+	coin.Volume24h = day.BaseVolume
+	for _, c := range p.recent {
+		coin.VolumeIDR += c.QuoteVolume // Traded IDR, underestimated until a day of candles is seen
+	}
+
+	// 6. Scores
+	coin.PumpScore = CalculateProfileScore(coin, m.timeframes, m.profiles[0])
+	if len(m.profiles) > 1 {
+		coin.ProfileScores = make(map[string]float64, len(m.profiles)-1)
+		for _, profile := range m.profiles[1:] {
+			coin.ProfileScores[profile.Name] = CalculateProfileScore(coin, m.timeframes, profile)
+		}
+	}
+	coin.RollingPumpScore = CalculateRollingPumpScore(coin, m.timeframes)
+	coin.Volatility1m = CalculateVolatility(coin, m.timeframes[0].Name)
+
+	p.coin = coin
+	return coin
+}
+
+// closeBucket moves the in-progress bucket of a timeframe to the closed history
+func (p *historicalPair) closeBucket(tf string) {
+	bucket := p.live[tf]
+	delete(p.live, tf)
+	history := append(p.history[tf], *bucket)
+	if len(history) > maxIndicatorCandles {
+		history = append([]model.Candle(nil), history[len(history)-maxIndicatorCandles:]...)
+	}
+	p.history[tf] = history
+}
+
+// mergeCandle adds a base candle to a longer bucket
+func mergeCandle(bucket *model.Candle, c model.Candle) {
+	bucket.High = max(bucket.High, c.High)
+	bucket.Low = min(bucket.Low, c.Low)
+	bucket.Close = c.Close
+	bucket.CloseTime = c.CloseTime
+	bucket.Volume += c.Volume
+	bucket.QuoteVolume += c.QuoteVolume
+	bucket.BuyVolume += c.BuyVolume
+	bucket.SellVolume += c.SellVolume
+	bucket.Trades += c.Trades
+	if bucket.Volume > 0 {
+		bucket.VWAP = bucket.QuoteVolume / bucket.Volume
+	}
+}
+
+// windowStats summarizes the candles opened after since, oldest first
+func windowStats(candles []model.Candle, since time.Time) model.WindowStats {
+	var w model.WindowStats
+	for _, c := range candles {
+		if c.OpenTime.Before(since) {
+			continue
+		}
+		if w.Open == 0 {
+			w.Open, w.High, w.Low = c.Open, c.High, c.Low
+		}
+		w.High = max(w.High, c.High)
+		w.Low = min(w.Low, c.Low)
+		w.Close = c.Close
+		w.Trx += c.Trades
+		w.BaseVolume += c.Volume
+		w.BuyVolume += c.BuyVolume
+		w.SellVolume += c.SellVolume
+	}
+	if w.Open > 0 {
+		w.ChangePct = (w.Close - w.Open) / w.Open * 100
+	}
+	return w
+}
@@ -0,0 +1,93 @@
+package market
+
+import (
+	"context"
+	"time"
+
+	"tuyul/backend/internal/model"
+	"tuyul/backend/internal/service/market/indicators"
+	"tuyul/backend/pkg/logger"
+)
+
+// Closed candles kept in memory per pair and timeframe for indicators
+const maxIndicatorCandles = 200
+
+// candleHistory holds the recent closed candles of a pair and timeframe (oldest first)
+type candleHistory struct {
+	candles []model.Candle
+	seeded  bool // Loaded from the candle store
+}
+
+func candleHistoryKey(pairID, tf string) string {
+	return pairID + "|" + tf
+}
+
+// appendCandleHistory adds a closed candle to the in-memory history.
+// Histories not seeded yet pick it up from the store when they are.
+func (s *MarketDataService) appendCandleHistory(candle *model.Candle) {
+	s.historyMu.Lock()
+	defer s.historyMu.Unlock()
+
+	h, ok := s.candleHistory[candleHistoryKey(candle.Pair, candle.Timeframe)]
+	if !ok || !h.seeded {
+		return
+	}
+	h.candles = append(h.candles, *candle)
+	if len(h.candles) > maxIndicatorCandles {
+		h.candles = append([]model.Candle(nil), h.candles[len(h.candles)-maxIndicatorCandles:]...)
+	}
+}
+
+// closedCandles returns a copy of the recent closed candles of a pair,
+// seeding the history from the candle store on first use
+func (s *MarketDataService) closedCandles(pairID, tf string) []model.Candle {
+	key := candleHistoryKey(pairID, tf)
+
+	s.historyMu.Lock()
+	h, ok := s.candleHistory[key]
+	if ok && h.seeded {
+		candles := append([]model.Candle(nil), h.candles...)
+		s.historyMu.Unlock()
+		return candles
+	}
+	s.historyMu.Unlock()
+
+	var seed []model.Candle
+	if s.candles != nil {
+		var err error
+		seed, err = s.candles.List(context.Background(), pairID, tf, time.Time{}, time.Time{}, maxIndicatorCandles)
+		if err != nil {
+			logger.Warnf("Failed to load %s candles of %s for indicators: %v", tf, pairID, err)
+			return nil // Retry on the next refresh
+		}
+	}
+
+	s.historyMu.Lock()
+	defer s.historyMu.Unlock()
+	s.candleHistory[key] = &candleHistory{candles: seed, seeded: true}
+	return append([]model.Candle(nil), seed...)
+}
+
+// refreshIndicators recomputes the indicators of every timeframe of a coin over
+// its closed candles plus the in-progress one
+func (s *MarketDataService) refreshIndicators(coin *model.Coin, now time.Time) {
+	result := make(map[string]model.Indicators, len(s.timeframes))
+	for _, tf := range s.timeframes {
+		series := s.closedCandles(coin.PairID, tf.Name)
+		if live, ok := buildCandle(coin, tf, now); ok {
+			series = append(series, *live)
+		}
+		result[tf.Name] = indicators.Compute(series)
+	}
+	coin.Indicators = result
+}
+
+// GetIndicators returns the latest indicators of a pair on a timeframe
+func (s *MarketDataService) GetIndicators(pairID, tf string) (model.Indicators, bool) {
+	val, ok := s.coinCache.Load(pairID)
+	if !ok {
+		return model.Indicators{}, false
+	}
+	ind, ok := val.(*model.Coin).Indicators[tf]
+	return ind, ok
+}
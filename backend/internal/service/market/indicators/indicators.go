@@ -0,0 +1,174 @@
+// Package indicators computes technical indicators over candle series.
+// Every function takes values oldest first and returns 0 when there is not
+// enough data for the requested period.
+package indicators
+
+import (
+	"math"
+
+	"tuyul/backend/internal/model"
+)
+
+// Default periods used by Compute
+const (
+	RSIPeriod       = 14
+	EMAFastPeriod   = 9
+	EMASlowPeriod   = 21
+	SMAPeriod       = 20
+	ATRPeriod       = 14
+	VWAPPeriod      = 20
+	BollingerPeriod = 20
+	BollingerStdDev = 2.0
+)
+
+// Compute returns the default indicator set of a candle series
+func Compute(candles []model.Candle) model.Indicators {
+	closes := Closes(candles)
+	upper, middle, lower := Bollinger(closes, BollingerPeriod, BollingerStdDev)
+
+	return model.Indicators{
+		RSI14:    RSI(closes, RSIPeriod),
+		EMA9:     EMA(closes, EMAFastPeriod),
+		EMA21:    EMA(closes, EMASlowPeriod),
+		SMA20:    SMA(closes, SMAPeriod),
+		VWAP:     VWAP(candles, VWAPPeriod),
+		ATR14:    ATR(candles, ATRPeriod),
+		BBUpper:  upper,
+		BBMiddle: middle,
+		BBLower:  lower,
+		Candles:  len(candles),
+	}
+}
+
+// Closes returns the close prices of a candle series
+func Closes(candles []model.Candle) []float64 {
+	closes := make([]float64, len(candles))
+	for i, c := range candles {
+		closes[i] = c.Close
+	}
+	return closes
+}
+
+// SMA is the simple moving average of the last period values
+func SMA(values []float64, period int) float64 {
+	if period <= 0 || len(values) < period {
+		return 0
+	}
+	sum := 0.0
+	for _, v := range values[len(values)-period:] {
+		sum += v
+	}
+	return sum / float64(period)
+}
+
+// EMA is the exponential moving average, seeded with the SMA of the first period values
+func EMA(values []float64, period int) float64 {
+	if period <= 0 || len(values) < period {
+		return 0
+	}
+	k := 2.0 / float64(period+1)
+	ema := SMA(values[:period], period)
+	for _, v := range values[period:] {
+		ema = v*k + ema*(1-k)
+	}
+	return ema
+}
+
+// RSI is the relative strength index with Wilder smoothing
+func RSI(values []float64, period int) float64 {
+	if period <= 0 || len(values) <= period {
+		return 0
+	}
+
+	// 1. Average gain/loss of the first period changes
+	var gain, loss float64
+	for i := 1; i <= period; i++ {
+		change := values[i] - values[i-1]
+		if change > 0 {
+			gain += change
+		} else {
+			loss -= change
+		}
+	}
+	avgGain := gain / float64(period)
+	avgLoss := loss / float64(period)
+
+	// 2. Wilder smoothing over the rest
+	for i := period + 1; i < len(values); i++ {
+		change := values[i] - values[i-1]
+		var g, l float64
+		if change > 0 {
+			g = change
+		} else {
+			l = -change
+		}
+		avgGain = (avgGain*float64(period-1) + g) / float64(period)
+		avgLoss = (avgLoss*float64(period-1) + l) / float64(period)
+	}
+
+	if avgLoss == 0 {
+		if avgGain == 0 {
+			return 50
+		}
+		return 100
+	}
+	rs := avgGain / avgLoss
+	return 100 - 100/(1+rs)
+}
+
+// ATR is the average true range with Wilder smoothing
+func ATR(candles []model.Candle, period int) float64 {
+	if period <= 0 || len(candles) <= period {
+		return 0
+	}
+
+	trueRange := func(i int) float64 {
+		c, prevClose := candles[i], candles[i-1].Close
+		return math.Max(c.High-c.Low, math.Max(math.Abs(c.High-prevClose), math.Abs(c.Low-prevClose)))
+	}
+
+	atr := 0.0
+	for i := 1; i <= period; i++ {
+		atr += trueRange(i)
+	}
+	atr /= float64(period)
+
+	for i := period + 1; i < len(candles); i++ {
+		atr = (atr*float64(period-1) + trueRange(i)) / float64(period)
+	}
+	return atr
+}
+
+// Bollinger returns the bands at k standard deviations around the SMA of the last period values
+func Bollinger(values []float64, period int, k float64) (upper, middle, lower float64) {
+	middle = SMA(values, period)
+	if middle == 0 {
+		return 0, 0, 0
+	}
+
+	variance := 0.0
+	for _, v := range values[len(values)-period:] {
+		variance += (v - middle) * (v - middle)
+	}
+	stdDev := math.Sqrt(variance / float64(period))
+
+	return middle + k*stdDev, middle, middle - k*stdDev
+}
+
+// VWAP is the volume weighted average price of the last period candles.
+// Candles without trades are skipped, 0 when none traded.
+func VWAP(candles []model.Candle, period int) float64 {
+	if period <= 0 || len(candles) < period {
+		return 0
+	}
+
+	var quote, base float64
+	for _, c := range candles[len(candles)-period:] {
+		quote += c.QuoteVolume
+		base += c.Volume
+	}
+	if base == 0 {
+		return 0
+	}
+	return quote / base
+}
@@ -2,8 +2,8 @@ package market
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+	"maps"
 	"strconv"
 	"strings"
 	"sync"
@@ -11,22 +11,36 @@ import (
 
 	"tuyul/backend/pkg/logger"
 
+	"tuyul/backend/internal/exchange"
 	"tuyul/backend/internal/model"
-	"tuyul/backend/pkg/indodax"
+	"tuyul/backend/internal/repository"
 	"tuyul/backend/pkg/redis"
 )
 
 type MarketDataService struct {
 	redisClient *redis.Client
-	wsClient    *indodax.WSClient
-	restClient  *indodax.Client
+	marketData  exchange.MarketData
+	stream      exchange.MarketStream
+	candles     *repository.CandleRepository
+	timeframes  []model.TimeframeSpec // Sorted from shortest to longest
 
 	// Cache for coins to avoid frequent unmarshal from Redis during updates
 	// Key: pairID
 	coinCache sync.Map
 
+	// Serializes coin updates from the stream with REST backfills and Redis snapshots
+	coinMu sync.Mutex
+
+	// Sliding windows per pair, fed by ticks and trades
+	// Key: pairID, Value: *pairWindows
+	windows sync.Map
+
+	// Recent closed candles for indicators, key: pairID|timeframe
+	candleHistory map[string]*candleHistory
+	historyMu     sync.Mutex
+
 	// Metadata cache
-	pairs      sync.Map // Key: pairID, Value: indodax.Pair
+	pairs      sync.Map // Key: pairID, Value: exchange.Pair
 	increments sync.Map // Key: pairID, Value: float64
 
 	updateChan chan *model.Coin
@@ -34,35 +48,71 @@ type MarketDataService struct {
 	// Subscriptions
 	subscribers []func(coin *model.Coin)
 	mu          sync.RWMutex
+
+	// Order book depth metrics settings
+	depth DepthConfig
+
+	// Market data freshness
+	freshness FreshnessConfig
+	feed      feedState
+
+	// Pump score profiles, the default one first
+	profiles   []*model.PumpScoreProfile
+	profilesMu sync.RWMutex
+
+	// Trade feed: recent prints per pair and pairs subscribed on the stream
+	trades    map[string]*tradeBuffer
+	tradeSubs map[string]bool
+	tradesMu  sync.Mutex
 }
 
-func NewMarketDataService(redisClient *redis.Client, wsClient *indodax.WSClient, restClient *indodax.Client) *MarketDataService {
-	return &MarketDataService{
+func NewMarketDataService(redisClient *redis.Client, marketData exchange.MarketData, stream exchange.MarketStream, candles *repository.CandleRepository, timeframes []model.TimeframeSpec) *MarketDataService {
+	s := &MarketDataService{
 		redisClient: redisClient,
-		wsClient:    wsClient,
-		restClient:  restClient,
+		marketData:  marketData,
+		stream:      stream,
+		candles:     candles,
+		timeframes:  timeframes,
 		updateChan:  make(chan *model.Coin, 100), // Buffer updates
+		trades:      make(map[string]*tradeBuffer),
+		tradeSubs:   make(map[string]bool),
+		depth:       DefaultDepthConfig(),
+		freshness:   DefaultFreshnessConfig(),
+		feed:        feedState{seen: make(map[string]time.Time)},
+
+		candleHistory: make(map[string]*candleHistory),
 	}
+	s.SetScoreProfiles(nil)
+	return s
 }
 
 // Start begins listening to market data
 func (s *MarketDataService) Start() {
-	// Register WS handlers (add, don't replace existing handlers)
-	s.wsClient.AddMessageHandler(s.handleWSMessage)
+	// Register stream handlers (add, don't replace existing handlers)
+	s.stream.AddTickerHandler(s.processSummaryUpdate)
+	s.stream.AddTradeHandler(s.processTrades)
+	s.stream.AddConnectHandler(s.backfillActivePairs)
+
+	s.feed.mu.Lock()
+	s.feed.started = time.Now()
+	s.feed.mu.Unlock()
 
 	// Connect to WS
-	if err := s.wsClient.Connect(); err != nil {
+	if err := s.stream.Connect(); err != nil {
 		logger.Errorf("Failed to connect to public WS: %v", err)
 	}
 
 	// Subscribe to market summaries (all pairs)
-	s.wsClient.Subscribe("market:summary-24h")
+	s.stream.SubscribeTickers()
 
 	// Load existing metadata from Redis
 	// If empty, sync from exchange automatically
 	if !s.LoadMetadata() {
 		logger.Infof("Metadata cache empty, performing initial sync from Indodax...")
 		go s.RefreshMetadata()
+	} else {
+		// Subscribe to the trade feed of every known pair
+		s.subscribeTrades()
 	}
 
 	// Initial gap update (synchronous - must complete before server starts)
@@ -71,16 +121,24 @@ func (s *MarketDataService) Start() {
 	logger.Infof("Initial gap/spread update completed")
 
 	// Start periodic REST poller for Best Bid / Best Ask and Gap Analysis
-	// (WebSocket market:summary-24h doesn't provide Bid/Ask)
+	// (the summary stream doesn't provide Bid/Ask)
 	go s.pollGapData()
+
+	// Start periodic REST depth sampling (subscribed pairs get live metrics)
+	if s.depth.SampleInterval > 0 {
+		go s.sampleDepth()
+	}
+
+	// Watch for a silent stream and stale pairs
+	go s.monitorFreshness()
 }
 
-// RefreshMetadata fetches pairs and price increments from Indodax and saves to Redis
+// RefreshMetadata fetches pairs and price increments from the exchange and saves to Redis
 func (s *MarketDataService) RefreshMetadata() error {
 	ctx := context.Background()
 
 	// Fetch pairs
-	pairs, err := s.restClient.GetPairs(ctx)
+	pairs, err := s.marketData.GetPairs(ctx)
 	if err == nil {
 		for _, p := range pairs {
 			s.pairs.Store(p.ID, p)
@@ -92,26 +150,25 @@ func (s *MarketDataService) RefreshMetadata() error {
 		}
 		// Save to Redis
 		s.redisClient.SetJSON(ctx, redis.CachePairsKey(), pairs, 0)
-		logger.Infof("Successfully refreshed %d pairs from Indodax and initialized coins", len(pairs))
+		logger.Infof("Successfully refreshed %d pairs from exchange and initialized coins", len(pairs))
+		s.subscribeTrades()
 	} else {
 		logger.Errorf("Failed to refresh pairs: %v", err)
 		return err
 	}
 
 	// Fetch increments
-	increments, err := s.restClient.GetPriceIncrements(ctx)
+	// Keys are already internal pair IDs (cstidr)
+	increments, err := s.marketData.GetPriceIncrements(ctx)
 	if err == nil {
-		// Normalize keys: remove underscores to match pair IDs (cst_idr -> cstidr)
-		normalizedIncrements := make(map[string]string)
+		cachedIncrements := make(map[string]string, len(increments))
 		for pair, inc := range increments {
-			f, _ := strconv.ParseFloat(inc, 64)
-			normalizedPair := strings.ReplaceAll(pair, "_", "")
-			s.increments.Store(normalizedPair, f)
-			normalizedIncrements[normalizedPair] = inc
+			s.increments.Store(pair, inc)
+			cachedIncrements[pair] = strconv.FormatFloat(inc, 'f', -1, 64)
 		}
-		// Save normalized increments to Redis
-		s.redisClient.SetJSON(ctx, redis.CachePriceIncrementsKey(), normalizedIncrements, 0)
-		logger.Infof("Successfully refreshed %d price increments from Indodax (normalized keys)", len(increments))
+		// Save increments to Redis
+		s.redisClient.SetJSON(ctx, redis.CachePriceIncrementsKey(), cachedIncrements, 0)
+		logger.Infof("Successfully refreshed %d price increments from exchange", len(increments))
 	} else {
 		logger.Errorf("Failed to refresh increments: %v", err)
 		return err
@@ -128,7 +185,7 @@ func (s *MarketDataService) LoadMetadata() bool {
 	loaded := false
 
 	// Load pairs
-	var pairs []indodax.Pair
+	var pairs []exchange.Pair
 	if err := s.redisClient.GetJSON(ctx, redis.CachePairsKey(), &pairs); err == nil && len(pairs) > 0 {
 		for _, p := range pairs {
 			s.pairs.Store(p.ID, p)
@@ -158,12 +215,12 @@ func (s *MarketDataService) LoadMetadata() bool {
 }
 
 // GetPairInfo returns metadata for a pair
-func (s *MarketDataService) GetPairInfo(pairID string) (indodax.Pair, bool) {
+func (s *MarketDataService) GetPairInfo(pairID string) (exchange.Pair, bool) {
 	val, ok := s.pairs.Load(pairID)
 	if !ok {
-		return indodax.Pair{}, false
+		return exchange.Pair{}, false
 	}
-	return val.(indodax.Pair), true
+	return val.(exchange.Pair), true
 }
 
 // GetPriceIncrement returns the price increment for a pair
@@ -182,46 +239,32 @@ func (s *MarketDataService) OnUpdate(handler func(coin *model.Coin)) {
 	s.subscribers = append(s.subscribers, handler)
 }
 
-func (s *MarketDataService) handleWSMessage(channel string, data []byte) {
-	if channel == "market:summary-24h" {
-		s.processSummaryUpdate(data)
+// notify runs the update handlers for a coin
+func (s *MarketDataService) notify(coin *model.Coin) {
+	s.mu.RLock()
+	handlers := s.subscribers
+	s.mu.RUnlock()
+	for _, h := range handlers {
+		h(coin)
 	}
 }
 
-func (s *MarketDataService) processSummaryUpdate(data []byte) {
+func (s *MarketDataService) processSummaryUpdate(tickers []exchange.Ticker) {
 	// Silent processing - no logging for market summary updates
-	var summaryData struct {
-		Data [][]interface{} `json:"data"`
+	pairs := make([]string, len(tickers))
+	for i, t := range tickers {
+		pairs[i] = t.Pair
 	}
+	s.markStream(time.Now(), pairs...)
 
-	if err := json.Unmarshal(data, &summaryData); err != nil {
-		logger.Errorf("Failed to parse summary data: %v", err)
-		return
-	}
-
-	for _, item := range summaryData.Data {
-		if len(item) < 8 {
-			continue
-		}
-
-		pairID, ok := item[0].(string)
-		if !ok {
-			continue
-		}
-
-		lastPrice := parseFloat(item[2])
-		high24h := parseFloat(item[4])
-		low24h := parseFloat(item[3])
-		open24h := parseFloat(item[5])
-		volIDR := parseStringFloat(item[6])
-		volBase := parseStringFloat(item[7])
-
-		// Update Coin
-		s.updateCoin(pairID, lastPrice, high24h, low24h, open24h, volBase, volIDR)
+	for _, t := range tickers {
+		s.updateCoin(t.Pair, t.Last, t.High, t.Low, t.Open, t.BaseVolume, t.QuoteVolume)
 	}
 }
 
 func (s *MarketDataService) updateCoin(pairID string, price, high, low, open, volBase, volIDR float64) {
+	s.coinMu.Lock()
+
 	// Get from cache or create
 	coin, _ := s.getOrCreateCoin(pairID)
 
@@ -240,27 +283,26 @@ func (s *MarketDataService) updateCoin(pairID string, price, high, low, open, vo
 
 	coin.LastUpdate = time.Now()
 
-	// Update Timeframes (OHLC + Trx)
+	// Update Timeframes (OHLC, Trx comes from the trade feed)
 	s.updateTimeframes(coin, price)
+	s.recordWindowPrice(pairID, coin.LastUpdate, price)
 
 	// Calculate Pump Score
-	coin.PumpScore = CalculatePumpScore(coin)
+	s.refreshWindows(coin, coin.LastUpdate)
+	s.refreshScores(coin)
 
 	// Calculate Volatility
-	coin.Volatility1m = CalculateVolatility(coin)
+	coin.Volatility1m = CalculateVolatility(coin, s.ShortestTimeframe())
 
 	// Calculate Gap (If we had Bid/Ask. Summary WS lacks it, but we'll call anyway)
 	CalculateGap(coin)
+	s.refreshStale(coin, coin.LastUpdate)
 
 	// Save to Cache & Redis
 	s.coinCache.Store(pairID, coin)
+	s.coinMu.Unlock()
 	go s.saveCoinToRedis(coin)
 
-	// Sample log every 100 updates per pair to see it's working
-	if coin.Timeframes.OneMinute.Trx%100 == 0 {
-		logger.Infof("Updated coin %s: Price=%.2f, PumpScore=%.2f", pairID, price, coin.PumpScore)
-	}
-
 	// Notify subscribers
 	s.mu.RLock()
 	handlers := s.subscribers
@@ -275,83 +317,64 @@ func (s *MarketDataService) getOrCreateCoin(pairID string) (*model.Coin, bool) {
 		return v.(*model.Coin), true
 	}
 
-	now := time.Now()
 	coin := &model.Coin{
 		PairID:        pairID,
 		BaseCurrency:  strings.TrimSuffix(pairID, "idr"),
 		QuoteCurrency: "idr",
-		LastReset: map[string]time.Time{
-			"1m":  now,
-			"5m":  now,
-			"15m": now,
-			"30m": now,
-		},
-	}
-	// Initial Timeframes
-	coin.Timeframes.OneMinute.Open = coin.CurrentPrice
-	coin.Timeframes.FiveMinute.Open = coin.CurrentPrice
-	coin.Timeframes.FifteenMin.Open = coin.CurrentPrice
-	coin.Timeframes.ThirtyMin.Open = coin.CurrentPrice
+	}
+	s.ensureTimeframes(coin, time.Now())
 
 	s.coinCache.Store(pairID, coin)
 	return coin, false
 }
 
-func (s *MarketDataService) updateTimeframes(coin *model.Coin, price float64) {
-	// Update 1m
-	if coin.Timeframes.OneMinute.Open == 0 {
-		coin.Timeframes.OneMinute.Open = price
-	}
-	coin.Timeframes.OneMinute.High = max(coin.Timeframes.OneMinute.High, price)
-	if coin.Timeframes.OneMinute.Low == 0 {
-		coin.Timeframes.OneMinute.Low = price
-	} else {
-		coin.Timeframes.OneMinute.Low = min(coin.Timeframes.OneMinute.Low, price)
-	}
-	coin.Timeframes.OneMinute.Trx++
-
-	// Update 5m
-	if coin.Timeframes.FiveMinute.Open == 0 {
-		coin.Timeframes.FiveMinute.Open = price
+// ensureTimeframes adds a bucket for every configured timeframe the coin lacks.
+// Called before a coin is cached, so buckets are never added while it is shared.
+func (s *MarketDataService) ensureTimeframes(coin *model.Coin, now time.Time) {
+	if coin.Timeframes == nil {
+		coin.Timeframes = make(model.Timeframes, len(s.timeframes))
 	}
-	coin.Timeframes.FiveMinute.High = max(coin.Timeframes.FiveMinute.High, price)
-	if coin.Timeframes.FiveMinute.Low == 0 {
-		coin.Timeframes.FiveMinute.Low = price
-	} else {
-		coin.Timeframes.FiveMinute.Low = min(coin.Timeframes.FiveMinute.Low, price)
-	}
-	coin.Timeframes.FiveMinute.Trx++
-
-	// Update 15m
-	if coin.Timeframes.FifteenMin.Open == 0 {
-		coin.Timeframes.FifteenMin.Open = price
+	if coin.LastReset == nil {
+		coin.LastReset = make(map[string]time.Time, len(s.timeframes))
 	}
-	coin.Timeframes.FifteenMin.High = max(coin.Timeframes.FifteenMin.High, price)
-	if coin.Timeframes.FifteenMin.Low == 0 {
-		coin.Timeframes.FifteenMin.Low = price
-	} else {
-		coin.Timeframes.FifteenMin.Low = min(coin.Timeframes.FifteenMin.Low, price)
+	for _, tf := range s.timeframes {
+		if coin.Timeframes[tf.Name] == nil {
+			coin.Timeframes[tf.Name] = &model.TimeframeData{Open: coin.CurrentPrice}
+		}
+		if _, ok := coin.LastReset[tf.Name]; !ok {
+			coin.LastReset[tf.Name] = now
+		}
 	}
-	coin.Timeframes.FifteenMin.Trx++
+}
 
-	// Update 30m
-	if coin.Timeframes.ThirtyMin.Open == 0 {
-		coin.Timeframes.ThirtyMin.Open = price
-	}
-	coin.Timeframes.ThirtyMin.High = max(coin.Timeframes.ThirtyMin.High, price)
-	if coin.Timeframes.ThirtyMin.Low == 0 {
-		coin.Timeframes.ThirtyMin.Low = price
-	} else {
-		coin.Timeframes.ThirtyMin.Low = min(coin.Timeframes.ThirtyMin.Low, price)
+func (s *MarketDataService) updateTimeframes(coin *model.Coin, price float64) {
+	for _, tf := range s.timeframes {
+		data := coin.Timeframes[tf.Name]
+		if data == nil {
+			continue
+		}
+		if data.Open == 0 {
+			data.Open = price
+		}
+		data.High = max(data.High, price)
+		if data.Low == 0 {
+			data.Low = price
+		} else {
+			data.Low = min(data.Low, price)
+		}
 	}
-	coin.Timeframes.ThirtyMin.Trx++
 }
 
 func (s *MarketDataService) saveCoinToRedis(coin *model.Coin) {
 	ctx := context.Background()
 	key := redis.CoinKey(coin.PairID)
 
+	// Snapshot under the update lock, the coin may be changing meanwhile
+	s.coinMu.Lock()
 	data, err := coin.ToMap()
+	pumpScore, gap, change, volume := coin.PumpScore, coin.GapPercentage, coin.Change24h, coin.VolumeIDR
+	profileScores := maps.Clone(coin.ProfileScores)
+	s.coinMu.Unlock()
 	if err != nil {
 		logger.Errorf("Failed to map coin: %v", err)
 		return
@@ -363,10 +386,13 @@ func (s *MarketDataService) saveCoinToRedis(coin *model.Coin) {
 	}
 
 	// Update Sorted Sets using exposed types
-	s.redisClient.ZAdd(ctx, redis.PumpScoreRankKey(), redis.Z{Score: coin.PumpScore, Member: coin.PairID})
-	s.redisClient.ZAdd(ctx, redis.GapRankKey(), redis.Z{Score: coin.GapPercentage, Member: coin.PairID})
-	s.redisClient.ZAdd(ctx, redis.ChangeRankKey(), redis.Z{Score: coin.Change24h, Member: coin.PairID})
-	s.redisClient.ZAdd(ctx, redis.VolumeRankKey(), redis.Z{Score: coin.VolumeIDR, Member: coin.PairID})
+	s.redisClient.ZAdd(ctx, redis.PumpScoreRankKey(), redis.Z{Score: pumpScore, Member: coin.PairID})
+	s.redisClient.ZAdd(ctx, redis.GapRankKey(), redis.Z{Score: gap, Member: coin.PairID})
+	s.redisClient.ZAdd(ctx, redis.ChangeRankKey(), redis.Z{Score: change, Member: coin.PairID})
+	s.redisClient.ZAdd(ctx, redis.VolumeRankKey(), redis.Z{Score: volume, Member: coin.PairID})
+	for profile, score := range profileScores {
+		s.redisClient.ZAdd(ctx, redis.PumpScoreProfileRankKey(profile), redis.Z{Score: score, Member: coin.PairID})
+	}
 
 	// Add to active pairs set
 	s.redisClient.SAdd(ctx, redis.ActivePairsKey(), coin.PairID)
@@ -379,31 +405,37 @@ func (s *MarketDataService) PerformTimeframeReset(pairID string, now time.Time)
 	}
 	coin := val.(*model.Coin)
 
+	s.coinMu.Lock()
 	if coin.LastReset == nil {
+		s.coinMu.Unlock()
 		logger.Warnf("Pair %s: LastReset map is nil", pairID)
 		return
 	}
 
 	updated := false
-	if s.shouldReset("1m", coin.LastReset["1m"], now) {
-		s.resetTimeframe(coin, "1m", now)
-		updated = true
-	}
-	if s.shouldReset("5m", coin.LastReset["5m"], now) {
-		s.resetTimeframe(coin, "5m", now)
-		updated = true
+	for _, tf := range s.timeframes {
+		if now.Sub(coin.LastReset[tf.Name]) >= tf.Duration {
+			s.resetTimeframe(coin, tf.Name, now)
+			updated = true
+		}
 	}
-	if s.shouldReset("15m", coin.LastReset["15m"], now) {
-		s.resetTimeframe(coin, "15m", now)
+
+	// Windows slide even without new ticks
+	pumpScore, rollingScore, profileScores := coin.PumpScore, coin.RollingPumpScore, coin.ProfileScores
+	s.refreshWindows(coin, now)
+	s.refreshScores(coin)
+	if coin.PumpScore != pumpScore || coin.RollingPumpScore != rollingScore || !maps.Equal(coin.ProfileScores, profileScores) {
 		updated = true
 	}
-	if s.shouldReset("30m", coin.LastReset["30m"], now) {
-		s.resetTimeframe(coin, "30m", now)
+
+	indicators := coin.Indicators
+	s.refreshIndicators(coin, now)
+	if !maps.Equal(coin.Indicators, indicators) {
 		updated = true
 	}
+	s.coinMu.Unlock()
 
 	if updated {
-		coin.PumpScore = CalculatePumpScore(coin)
 		s.saveCoinToRedis(coin)
 
 		// Notify subscribers about timeframe reset
@@ -416,59 +448,49 @@ func (s *MarketDataService) PerformTimeframeReset(pairID string, now time.Time)
 	}
 }
 
-func (s *MarketDataService) shouldReset(tf string, lastReset, now time.Time) bool {
-	switch tf {
-	case "1m":
-		return now.Sub(lastReset) >= 1*time.Minute
-	case "5m":
-		return now.Sub(lastReset) >= 5*time.Minute
-	case "15m":
-		return now.Sub(lastReset) >= 15*time.Minute
-	case "30m":
-		return now.Sub(lastReset) >= 30*time.Minute
-	}
-	return false
-}
-
 func (s *MarketDataService) resetTimeframe(coin *model.Coin, tf string, now time.Time) {
-	price := coin.CurrentPrice
-	zeroTF := model.TimeframeData{Open: price, High: price, Low: price, Trx: 0}
-	switch tf {
-	case "1m":
-		coin.Timeframes.OneMinute = zeroTF
-		coin.LastReset["1m"] = now
-	case "5m":
-		coin.Timeframes.FiveMinute = zeroTF
-		coin.LastReset["5m"] = now
-	case "15m":
-		coin.Timeframes.FifteenMin = zeroTF
-		coin.LastReset["15m"] = now
-	case "30m":
-		coin.Timeframes.ThirtyMin = zeroTF
-		coin.LastReset["30m"] = now
+	// Persist the bucket being closed before it is thrown away
+	if spec, ok := s.timeframe(tf); ok {
+		s.saveClosedCandle(coin, spec, now)
 	}
+
+	data := coin.Timeframes[tf]
+	if data == nil {
+		return
+	}
+	price := coin.CurrentPrice
+	*data = model.TimeframeData{Open: price, High: price, Low: price, Trx: 0}
+	coin.LastReset[tf] = now
+}
+
+// Timeframes returns the configured timeframes, shortest first
+func (s *MarketDataService) Timeframes() []model.TimeframeSpec {
+	return s.timeframes
+}
+
+// HasTimeframe reports whether tf is a configured timeframe
+func (s *MarketDataService) HasTimeframe(tf string) bool {
+	_, ok := s.timeframe(tf)
+	return ok
 }
 
-func parseFloat(v interface{}) float64 {
-	switch val := v.(type) {
-	case float64:
-		return val
-	case int:
-		return float64(val)
-	case string:
-		f, _ := strconv.ParseFloat(val, 64)
-		return f
-	default:
-		return 0
+// timeframe returns the spec of a configured timeframe
+func (s *MarketDataService) timeframe(tf string) (model.TimeframeSpec, bool) {
+	for _, spec := range s.timeframes {
+		if spec.Name == tf {
+			return spec, true
+		}
 	}
+	return model.TimeframeSpec{}, false
 }
 
-func parseStringFloat(v interface{}) float64 {
-	if s, ok := v.(string); ok {
-		f, _ := strconv.ParseFloat(s, 64)
-		return f
+// ShortestTimeframe returns the name of the shortest configured timeframe,
+// the one volatility and trade-count signals are measured over
+func (s *MarketDataService) ShortestTimeframe() string {
+	if len(s.timeframes) == 0 {
+		return ""
 	}
-	return parseFloat(v)
+	return s.timeframes[0].Name
 }
 
 func max(a, b float64) float64 {
@@ -508,10 +530,21 @@ func (s *MarketDataService) GetCoin(ctx context.Context, pairID string) (*model.
 	}
 
 	// Store in cache
+	s.ensureTimeframes(coin, time.Now())
 	s.coinCache.Store(pairID, coin)
 	return coin, nil
 }
 
+// Coins returns every cached coin
+func (s *MarketDataService) Coins() []*model.Coin {
+	var coins []*model.Coin
+	s.coinCache.Range(func(_, val any) bool {
+		coins = append(coins, val.(*model.Coin))
+		return true
+	})
+	return coins
+}
+
 // GetSortedCoins retrieves a list of coins from a sorted set
 func (s *MarketDataService) GetSortedCoins(ctx context.Context, sortKey string, limit int, minVolume float64, minPumpScore float64) ([]*model.Coin, error) {
 	// Get pair IDs from sorted set
@@ -555,28 +588,31 @@ func (s *MarketDataService) pollGapData() {
 
 func (s *MarketDataService) updateGapsFromREST() {
 	ctx := context.Background()
-	summaries, err := s.restClient.GetSummaries(ctx)
+	tickers, err := s.marketData.GetTickers(ctx)
 	if err != nil {
 		logger.Errorf("Failed to poll summaries for gaps: %v", err)
 		return
 	}
+	now := time.Now()
+	s.markPolled(now)
 
 	updatedCount := 0
 
-	for tickerID, detail := range summaries.Tickers {
-		// Indodax summaries use btc_idr format, we use btcidr
-		pairID := strings.ReplaceAll(tickerID, "_", "")
+	for _, t := range tickers {
+		pairID := t.Pair
 
 		// Optimization: only update coins we already know about or initialize if needed
+		s.coinMu.Lock()
 		coin, _ := s.getOrCreateCoin(pairID)
+		s.markSeen(pairID, now)
 
 		// Update bid/ask
-		bestBid, _ := strconv.ParseFloat(detail.Buy, 64)
-		bestAsk, _ := strconv.ParseFloat(detail.Sell, 64)
-		lastPrice, _ := strconv.ParseFloat(detail.Last, 64)
-		volIDR, _ := strconv.ParseFloat(detail.VolIDR, 64)
-		high, _ := strconv.ParseFloat(detail.High, 64)
-		low, _ := strconv.ParseFloat(detail.Low, 64)
+		bestBid := t.Bid
+		bestAsk := t.Ask
+		lastPrice := t.Last
+		volIDR := t.QuoteVolume
+		high := t.High
+		low := t.Low
 
 		changed := false
 
@@ -612,12 +648,19 @@ func (s *MarketDataService) updateGapsFromREST() {
 			coin.LastUpdate = time.Now()
 			// Update Timeframes slightly (just to ensure price is tracked)
 			s.updateTimeframes(coin, coin.CurrentPrice)
+			s.recordWindowPrice(pairID, coin.LastUpdate, coin.CurrentPrice)
 
 			// Recalculate Pump Score if volume/price changed
-			coin.PumpScore = CalculatePumpScore(coin)
+			s.refreshWindows(coin, coin.LastUpdate)
+			s.refreshScores(coin)
+			s.refreshStale(coin, coin.LastUpdate)
 
 			// Save to Cache & Redis
 			s.coinCache.Store(pairID, coin)
+		}
+		s.coinMu.Unlock()
+
+		if changed {
 			go s.saveCoinToRedis(coin)
 
 			// Notify subscribers about gap/bid/ask updates
@@ -1,33 +1,115 @@
 package market
 
 import (
+	"math"
+	"time"
+
 	"tuyul/backend/internal/model"
 )
 
 // CalculatePumpScore calculates the pump score based on multi-timeframe weighted average
-func CalculatePumpScore(coin *model.Coin) float64 {
+func CalculatePumpScore(coin *model.Coin, timeframes []model.TimeframeSpec) float64 {
 	// Formula:
-	// Pump Score = (1m_pct × 1m_trx × 0.20) +
-	//              (5m_pct × 5m_trx × 0.40) +
-	//              (15m_pct × 15m_trx × 0.30) +
-	//              (30m_pct × 30m_trx × 0.10)
+	// Pump Score = Σ (tf_pct × tf_trx × tf_weight) over the configured timeframes
+	// Default weights: 1m 0.20, 5m 0.40, 15m 0.30, 30m 0.10
 
-	score1m := calculateTimeframeScore(coin.CurrentPrice, coin.Timeframes.OneMinute, 0.20)
-	score5m := calculateTimeframeScore(coin.CurrentPrice, coin.Timeframes.FiveMinute, 0.40)
-	score15m := calculateTimeframeScore(coin.CurrentPrice, coin.Timeframes.FifteenMin, 0.30)
-	score30m := calculateTimeframeScore(coin.CurrentPrice, coin.Timeframes.ThirtyMin, 0.10)
+	score := 0.0
+	for _, tf := range timeframes {
+		if tf.Weight == 0 {
+			continue
+		}
+		data := coin.Timeframes.Get(tf.Name)
+		score += calculateTimeframeScore(coin.CurrentPrice, data.Open, data.Trx, tf.Weight)
+	}
+	return score
+}
+
+// CalculateRollingPumpScore applies the pump score formula to the sliding windows,
+// which unlike the buckets keep their full length right after a reset boundary
+func CalculateRollingPumpScore(coin *model.Coin, timeframes []model.TimeframeSpec) float64 {
+	score := 0.0
+	for _, tf := range timeframes {
+		if tf.Weight == 0 {
+			continue
+		}
+		window, ok := coin.Windows[tf.Name]
+		if !ok {
+			continue
+		}
+		score += calculateTimeframeScore(coin.CurrentPrice, window.Open, window.Trx, tf.Weight)
+	}
+	return score
+}
+
+// CalculateProfileScore applies a scoring profile to a coin
+func CalculateProfileScore(coin *model.Coin, timeframes []model.TimeframeSpec, profile *model.PumpScoreProfile) float64 {
+	if profile.MinVolumeIDR > 0 && coin.VolumeIDR < profile.MinVolumeIDR {
+		return 0
+	}
 
-	return score1m + score5m + score15m + score30m
+	score := 0.0
+	for _, tf := range timeframes {
+		weight := tf.Weight
+		if len(profile.Weights) > 0 {
+			weight = profile.Weights[tf.Name]
+		}
+		if weight == 0 {
+			continue
+		}
+
+		// 1. Open, trade count and traded IDR of the bucket or window
+		var open, quote float64
+		var trx int
+		if profile.UseRollingWindows {
+			window, ok := coin.Windows[tf.Name]
+			if !ok {
+				continue
+			}
+			open, trx, quote = window.Open, window.Trx, window.BuyVolume+window.SellVolume
+		} else {
+			data := coin.Timeframes.Get(tf.Name)
+			open, trx, quote = data.Open, data.Trx, data.BuyVolume+data.SellVolume
+		}
+		if open == 0 {
+			continue
+		}
+
+		// 2. change% × dampened trx × weight
+		changePct := ((coin.CurrentPrice - open) / open) * 100
+		term := changePct * dampenTrx(trx, profile.TrxDampening) * weight
+
+		// 3. Volume relative to the 24h pace (1 = average activity)
+		if profile.VolumeNormalization {
+			expected := coin.VolumeIDR * float64(tf.Duration) / float64(24*time.Hour)
+			if expected > 0 {
+				term *= quote / expected
+			}
+		}
+
+		score += term
+	}
+	return score
+}
+
+func dampenTrx(trx int, mode string) float64 {
+	switch mode {
+	case model.TrxDampeningSqrt:
+		return math.Sqrt(float64(trx))
+	case model.TrxDampeningLog:
+		return math.Log1p(float64(trx))
+	default:
+		return float64(trx)
+	}
 }
 
-func calculateTimeframeScore(currentPrice float64, tf model.TimeframeData, weight float64) float64 {
-	if tf.Open == 0 {
+func calculateTimeframeScore(currentPrice, open float64, trx int, weight float64) float64 {
+	if open == 0 {
 		return 0
 	}
 
 	// Price Change %
-	changePct := ((currentPrice - tf.Open) / tf.Open) * 100
+	changePct := ((currentPrice - open) / open) * 100
 
 	// Score
-	return changePct * float64(tf.Trx) * weight
+	return changePct * float64(trx) * weight
 }
@@ -0,0 +1,180 @@
+package market
+
+import (
+	"sync"
+	"time"
+
+	"tuyul/backend/internal/exchange"
+	"tuyul/backend/internal/model"
+)
+
+// Slots per rolling window, the window slides in steps of duration/windowSlots
+const windowSlots = 60
+
+// windowSlot aggregates the ticks and trades of one step of a window
+type windowSlot struct {
+	index     int64   // Slot number since the epoch
+	prevClose float64 // Last price before the slot started
+
+	open, high, low, close float64
+	trx                    int
+	baseVolume             float64
+	buyVolume, sellVolume  float64
+}
+
+// rollingWindow keeps the last duration of prices and trades in a ring of slots
+type rollingWindow struct {
+	slot  time.Duration
+	slots [windowSlots]windowSlot
+	last  float64 // Last recorded price
+}
+
+func newRollingWindow(d time.Duration) *rollingWindow {
+	slot := d / windowSlots
+	if slot <= 0 {
+		slot = time.Second
+	}
+	return &rollingWindow{slot: slot}
+}
+
+// slotAt returns the slot covering t, or nil when t is older than the ring
+func (w *rollingWindow) slotAt(t time.Time) *windowSlot {
+	idx := t.UnixNano() / int64(w.slot)
+	if idx <= 0 {
+		return nil
+	}
+	sl := &w.slots[idx%windowSlots]
+	if sl.index == idx {
+		return sl
+	}
+	if sl.index > idx {
+		return nil
+	}
+	*sl = windowSlot{index: idx, prevClose: w.last}
+	return sl
+}
+
+func (w *rollingWindow) addPrice(t time.Time, price float64) *windowSlot {
+	if price <= 0 {
+		return nil
+	}
+	sl := w.slotAt(t)
+	if sl == nil {
+		return nil
+	}
+	if sl.open == 0 {
+		sl.open, sl.high, sl.low = price, price, price
+	} else {
+		sl.high = max(sl.high, price)
+		sl.low = min(sl.low, price)
+	}
+	sl.close = price
+	w.last = price
+	return sl
+}
+
+func (w *rollingWindow) addTrade(t exchange.Trade) {
+	sl := w.addPrice(t.Time, t.Price)
+	if sl == nil {
+		return
+	}
+	sl.trx++
+	sl.baseVolume += t.Amount
+	if t.Side == "buy" {
+		sl.buyVolume += t.Price * t.Amount
+	} else {
+		sl.sellVolume += t.Price * t.Amount
+	}
+}
+
+// stats aggregates the slots of the window ending at now
+func (w *rollingWindow) stats(now time.Time) model.WindowStats {
+	nowIdx := now.UnixNano() / int64(w.slot)
+	first := nowIdx - windowSlots + 1
+
+	var st model.WindowStats
+	for i := first; i <= nowIdx; i++ {
+		sl := &w.slots[i%windowSlots]
+		if sl.index != i || sl.open == 0 {
+			continue
+		}
+		if st.Open == 0 {
+			// Price at the window start, else the first print inside it
+			st.Open = sl.prevClose
+			if st.Open == 0 {
+				st.Open = sl.open
+			}
+			st.High, st.Low = st.Open, st.Open
+		}
+		st.High = max(st.High, sl.high)
+		st.Low = min(st.Low, sl.low)
+		st.Close = sl.close
+		st.Trx += sl.trx
+		st.BaseVolume += sl.baseVolume
+		st.BuyVolume += sl.buyVolume
+		st.SellVolume += sl.sellVolume
+	}
+
+	if st.Open == 0 {
+		// Nothing happened within the window, the price is flat
+		st.Open, st.High, st.Low, st.Close = w.last, w.last, w.last, w.last
+	}
+	if st.Open > 0 {
+		st.ChangePct = (st.Close - st.Open) / st.Open * 100
+	}
+	return st
+}
+
+// pairWindows holds the rolling windows of one pair, one per configured timeframe
+type pairWindows struct {
+	mu      sync.Mutex
+	windows map[string]*rollingWindow
+}
+
+// pairWindowsFor returns the windows of a pair, creating them on first use
+func (s *MarketDataService) pairWindowsFor(pairID string) *pairWindows {
+	if v, ok := s.windows.Load(pairID); ok {
+		return v.(*pairWindows)
+	}
+	pw := &pairWindows{windows: make(map[string]*rollingWindow, len(s.timeframes))}
+	for _, tf := range s.timeframes {
+		pw.windows[tf.Name] = newRollingWindow(tf.Duration)
+	}
+	v, _ := s.windows.LoadOrStore(pairID, pw)
+	return v.(*pairWindows)
+}
+
+// recordWindowPrice adds a price tick to every window of a pair
+func (s *MarketDataService) recordWindowPrice(pairID string, t time.Time, price float64) {
+	pw := s.pairWindowsFor(pairID)
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+	for _, w := range pw.windows {
+		w.addPrice(t, price)
+	}
+}
+
+// recordWindowTrade adds a trade print to every window of a pair
+func (s *MarketDataService) recordWindowTrade(pairID string, t exchange.Trade) {
+	pw := s.pairWindowsFor(pairID)
+	pw.mu.Lock()
+	defer pw.mu.Unlock()
+	for _, w := range pw.windows {
+		w.addTrade(t)
+	}
+}
+
+// refreshWindows recomputes the sliding windows and the rolling pump score of a coin.
+// A new map is assigned so readers of the previous one are not affected.
+func (s *MarketDataService) refreshWindows(coin *model.Coin, now time.Time) {
+	pw := s.pairWindowsFor(coin.PairID)
+	pw.mu.Lock()
+	windows := make(map[string]model.WindowStats, len(pw.windows))
+	for name, w := range pw.windows {
+		windows[name] = w.stats(now)
+	}
+	pw.mu.Unlock()
+
+	coin.Windows = windows
+	coin.RollingPumpScore = CalculateRollingPumpScore(coin, s.timeframes)
+}
@@ -0,0 +1,218 @@
+package market
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"tuyul/backend/internal/model"
+	"tuyul/backend/internal/repository"
+	"tuyul/backend/internal/util"
+	"tuyul/backend/pkg/logger"
+	"tuyul/backend/pkg/redis"
+)
+
+var profileNamePattern = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+
+// builtInProfile is the original formula: configured weights, raw trade count
+func builtInProfile() *model.PumpScoreProfile {
+	return &model.PumpScoreProfile{
+		Name:            model.DefaultPumpScoreProfile,
+		Description:     "Configured timeframe weights, change × trade count",
+		TrxDampening:    model.TrxDampeningNone,
+		SignalThreshold: model.DefaultPumpSignalThreshold,
+		BuiltIn:         true,
+	}
+}
+
+// ActiveProfiles returns the profiles scored for a set of stored profiles, the
+// default one first. A profile named "default" overrides the built-in formula.
+func ActiveProfiles(profiles []*model.PumpScoreProfile) []*model.PumpScoreProfile {
+	active := []*model.PumpScoreProfile{builtInProfile()}
+	for _, p := range profiles {
+		if p.Name == model.DefaultPumpScoreProfile {
+			active[0] = p
+			continue
+		}
+		active = append(active, p)
+	}
+	return active
+}
+
+// SetScoreProfiles replaces the profiles scored on every coin update.
+// A profile named "default" overrides the built-in formula behind Coin.PumpScore.
+func (s *MarketDataService) SetScoreProfiles(profiles []*model.PumpScoreProfile) {
+	active := ActiveProfiles(profiles)
+
+	s.profilesMu.Lock()
+	s.profiles = active
+	s.profilesMu.Unlock()
+}
+
+// ScoreProfiles returns the active profiles, the default one first
+func (s *MarketDataService) ScoreProfiles() []*model.PumpScoreProfile {
+	s.profilesMu.RLock()
+	defer s.profilesMu.RUnlock()
+	return s.profiles
+}
+
+// HasScoreProfile reports whether a profile is active
+func (s *MarketDataService) HasScoreProfile(name string) bool {
+	for _, p := range s.ScoreProfiles() {
+		if p.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// refreshScores recomputes Coin.PumpScore (default profile) and the score of every other profile.
+// A new map is assigned so readers of the previous one are not affected.
+func (s *MarketDataService) refreshScores(coin *model.Coin) {
+	profiles := s.ScoreProfiles()
+	coin.PumpScore = CalculateProfileScore(coin, s.timeframes, profiles[0])
+
+	if len(profiles) == 1 {
+		coin.ProfileScores = nil
+		return
+	}
+	scores := make(map[string]float64, len(profiles)-1)
+	for _, p := range profiles[1:] {
+		scores[p.Name] = CalculateProfileScore(coin, s.timeframes, p)
+	}
+	coin.ProfileScores = scores
+}
+
+// ProfileScore returns the score of a coin under a profile, empty meaning the default
+func ProfileScore(coin *model.Coin, profile string) float64 {
+	if profile == "" || profile == model.DefaultPumpScoreProfile {
+		return coin.PumpScore
+	}
+	return coin.ProfileScores[profile]
+}
+
+// IsPumpSignal reports whether a coin reaches the signal threshold of any profile
+func (s *MarketDataService) IsPumpSignal(coin *model.Coin) bool {
+	for _, p := range s.ScoreProfiles() {
+		if p.SignalThreshold > 0 && ProfileScore(coin, p.Name) >= p.SignalThreshold {
+			return true
+		}
+	}
+	return false
+}
+
+// GetProfileRanking returns coins sorted by the score of a profile (highest first)
+func (s *MarketDataService) GetProfileRanking(ctx context.Context, profile string, limit int, minVolume, minScore float64) ([]*model.Coin, error) {
+	if profile == "" || profile == model.DefaultPumpScoreProfile {
+		return s.GetSortedCoins(ctx, redis.PumpScoreRankKey(), limit, minVolume, minScore)
+	}
+
+	coins, err := s.GetSortedCoins(ctx, redis.PumpScoreProfileRankKey(profile), limit, minVolume, 0)
+	if err != nil {
+		return nil, err
+	}
+	if minScore <= 0 {
+		return coins, nil
+	}
+	filtered := coins[:0]
+	for _, coin := range coins {
+		if ProfileScore(coin, profile) >= minScore {
+			filtered = append(filtered, coin)
+		}
+	}
+	return filtered, nil
+}
+
+// ScoreProfileService manages the admin-defined pump score profiles
+type ScoreProfileService struct {
+	repo          *repository.PumpScoreProfileRepository
+	botRepo       *repository.BotRepository
+	marketService *MarketDataService
+}
+
+func NewScoreProfileService(repo *repository.PumpScoreProfileRepository, botRepo *repository.BotRepository, marketService *MarketDataService) *ScoreProfileService {
+	return &ScoreProfileService{
+		repo:          repo,
+		botRepo:       botRepo,
+		marketService: marketService,
+	}
+}
+
+// Load activates the stored profiles
+func (s *ScoreProfileService) Load(ctx context.Context) error {
+	profiles, err := s.repo.List(ctx)
+	if err != nil {
+		return err
+	}
+	s.marketService.SetScoreProfiles(profiles)
+	logger.Infof("Loaded %d pump score profiles", len(profiles))
+	return nil
+}
+
+// List returns the active profiles, the default one first
+func (s *ScoreProfileService) List() []*model.PumpScoreProfile {
+	return s.marketService.ScoreProfiles()
+}
+
+// Save creates or replaces a profile and activates it
+func (s *ScoreProfileService) Save(ctx context.Context, req *model.PumpScoreProfileRequest) (*model.PumpScoreProfile, error) {
+	if !profileNamePattern.MatchString(req.Name) {
+		return nil, util.ErrBadRequest("name must be 1-32 characters of a-z, 0-9, _ or -")
+	}
+	for tf, weight := range req.Weights {
+		if !s.marketService.HasTimeframe(tf) {
+			return nil, util.ErrBadRequest(fmt.Sprintf("weights: %s is not a tracked timeframe", tf))
+		}
+		if weight < 0 {
+			return nil, util.ErrBadRequest(fmt.Sprintf("weights: %s must not be negative", tf))
+		}
+	}
+
+	profile := &model.PumpScoreProfile{
+		Name:                req.Name,
+		Description:         req.Description,
+		Weights:             req.Weights,
+		TrxDampening:        req.TrxDampening,
+		VolumeNormalization: req.VolumeNormalization,
+		UseRollingWindows:   req.UseRollingWindows,
+		MinVolumeIDR:        req.MinVolumeIDR,
+		SignalThreshold:     req.SignalThreshold,
+	}
+	if profile.TrxDampening == "" {
+		profile.TrxDampening = model.TrxDampeningNone
+	}
+	if existing, err := s.repo.GetByName(ctx, req.Name); err == nil {
+		profile.CreatedAt = existing.CreatedAt
+	}
+
+	if err := s.repo.Save(ctx, profile); err != nil {
+		return nil, err
+	}
+	if err := s.Load(ctx); err != nil {
+		return nil, err
+	}
+	return profile, nil
+}
+
+// Delete removes a profile. Deleting "default" restores the built-in formula.
+// Profiles that Pump Hunter bots enter on cannot be deleted.
+func (s *ScoreProfileService) Delete(ctx context.Context, name string) error {
+	if _, err := s.repo.GetByName(ctx, name); err != nil {
+		return util.ErrNotFound("Pump score profile not found")
+	}
+	if name != model.DefaultPumpScoreProfile {
+		bots, err := s.botRepo.ListByType(ctx, model.BotTypePumpHunter)
+		if err != nil {
+			return util.ErrInternalServer("Failed to check bots using the profile")
+		}
+		for _, bot := range bots {
+			if bot.EntryRules != nil && bot.EntryRules.ScoreProfile == name {
+				return util.ErrConflict(fmt.Sprintf("Pump score profile is used by bot %q", bot.Name))
+			}
+		}
+	}
+	if err := s.repo.Delete(ctx, name); err != nil {
+		return err
+	}
+	return s.Load(ctx)
+}
@@ -0,0 +1,387 @@
+// Package screener parses and evaluates filter and sort expressions over
+// market coins, e.g. `pump_score > 300 and volume_idr > 5e9 and change_24h < 20`.
+//
+// Fields are the JSON names of model.Coin, nested ones joined with dots
+// (indicators.5m.rsi_14, windows.1m.change_pct, depth.imbalance).
+// Comparisons take fields, numbers, 'strings' or true/false on either side
+// and combine with and, or, not and parentheses.
+package screener
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"tuyul/backend/internal/model"
+)
+
+// Maximum length of an expression
+const maxExprLength = 1000
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokNumber
+	tokString
+	tokOp
+	tokLParen
+	tokRParen
+	tokAnd
+	tokOr
+	tokNot
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+// tokenize splits an expression into tokens
+func tokenize(src string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{tokLParen, "(", i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{tokRParen, ")", i})
+			i++
+		case c == '&' || c == '|':
+			if i+1 >= len(src) || src[i+1] != c {
+				return nil, fmt.Errorf("unexpected %q at %d", c, i)
+			}
+			kind := tokAnd
+			if c == '|' {
+				kind = tokOr
+			}
+			tokens = append(tokens, token{kind, src[i : i+2], i})
+			i += 2
+		case strings.IndexByte("<>=!", c) >= 0:
+			op := string(c)
+			if i+1 < len(src) && src[i+1] == '=' {
+				op += "="
+			}
+			switch op {
+			case "!":
+				tokens = append(tokens, token{tokNot, op, i})
+			case "=":
+				tokens = append(tokens, token{tokOp, "==", i})
+			default:
+				tokens = append(tokens, token{tokOp, op, i})
+			}
+			i += len(op)
+		case c == '\'' || c == '"':
+			end := strings.IndexByte(src[i+1:], c)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string at %d", i)
+			}
+			tokens = append(tokens, token{tokString, src[i+1 : i+1+end], i})
+			i += end + 2
+		case c >= '0' && c <= '9' || c == '.' || c == '-':
+			start := i
+			i++
+			for i < len(src) && (isNumberChar(src[i]) || (src[i] == '-' || src[i] == '+') && (src[i-1] == 'e' || src[i-1] == 'E')) {
+				i++
+			}
+			tokens = append(tokens, token{tokNumber, src[start:i], start})
+		case c == '_' || unicode.IsLetter(rune(c)):
+			start := i
+			for i < len(src) && (src[i] == '_' || src[i] == '.' || unicode.IsLetter(rune(src[i])) || unicode.IsDigit(rune(src[i]))) {
+				i++
+			}
+			text := src[start:i]
+			switch strings.ToLower(text) {
+			case "and":
+				tokens = append(tokens, token{tokAnd, text, start})
+			case "or":
+				tokens = append(tokens, token{tokOr, text, start})
+			case "not":
+				tokens = append(tokens, token{tokNot, text, start})
+			default:
+				tokens = append(tokens, token{tokIdent, text, start})
+			}
+		default:
+			return nil, fmt.Errorf("unexpected %q at %d", c, i)
+		}
+	}
+	return append(tokens, token{tokEOF, "", len(src)}), nil
+}
+
+func isNumberChar(c byte) bool {
+	return c >= '0' && c <= '9' || c == '.' || c == 'e' || c == 'E'
+}
+
+// node is a boolean expression over a coin
+type node interface {
+	eval(coin *model.Coin) bool
+}
+
+type andNode struct{ left, right node }
+
+func (n andNode) eval(coin *model.Coin) bool { return n.left.eval(coin) && n.right.eval(coin) }
+
+type orNode struct{ left, right node }
+
+func (n orNode) eval(coin *model.Coin) bool { return n.left.eval(coin) || n.right.eval(coin) }
+
+type notNode struct{ inner node }
+
+func (n notNode) eval(coin *model.Coin) bool { return !n.inner.eval(coin) }
+
+// operand is a field path or a literal (float64, string or bool)
+type operand struct {
+	path    []string
+	literal any
+}
+
+func (o operand) value(coin *model.Coin) (any, bool) {
+	if o.path == nil {
+		return o.literal, true
+	}
+	return resolve(coin, o.path)
+}
+
+type compareNode struct {
+	left, right operand
+	op          string
+}
+
+// eval compares two values of the same type, false when a field is missing
+func (n compareNode) eval(coin *model.Coin) bool {
+	left, ok := n.left.value(coin)
+	if !ok {
+		return false
+	}
+	right, ok := n.right.value(coin)
+	if !ok {
+		return false
+	}
+
+	var cmp int
+	switch l := left.(type) {
+	case float64:
+		r, ok := right.(float64)
+		if !ok {
+			return false
+		}
+		cmp = compareFloat(l, r)
+	case string:
+		r, ok := right.(string)
+		if !ok {
+			return false
+		}
+		cmp = strings.Compare(l, r)
+	case bool:
+		r, ok := right.(bool)
+		if !ok || (n.op != "==" && n.op != "!=") {
+			return false
+		}
+		if l != r {
+			cmp = 1
+		}
+	default:
+		return false
+	}
+
+	switch n.op {
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case "==":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	}
+	return false
+}
+
+func compareFloat(a, b float64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// Filter is a parsed filter expression
+type Filter struct {
+	root   node
+	source string
+}
+
+// ParseFilter parses a filter expression, an empty one matches every coin
+func ParseFilter(expr string) (*Filter, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return &Filter{}, nil
+	}
+	if len(expr) > maxExprLength {
+		return nil, fmt.Errorf("expression longer than %d characters", maxExprLength)
+	}
+
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %q at %d", tok.text, tok.pos)
+	}
+	return &Filter{root: root, source: expr}, nil
+}
+
+// Match reports whether a coin passes the filter
+func (f *Filter) Match(coin *model.Coin) bool {
+	return f.root == nil || f.root.eval(coin)
+}
+
+// String returns the source expression
+func (f *Filter) String() string {
+	return f.source
+}
+
+// parser is a recursive descent parser:
+//
+//	or      = and { "or" and }
+//	and     = not { "and" not }
+//	not     = "not" not | primary
+//	primary = "(" or ")" | operand op operand
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokOr {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokAnd {
+		p.next()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseNot() (node, error) {
+	if p.peek().kind == tokNot {
+		p.next()
+		inner, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{inner}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	if p.peek().kind == tokLParen {
+		p.next()
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if tok := p.next(); tok.kind != tokRParen {
+			return nil, fmt.Errorf("expected ) at %d", tok.pos)
+		}
+		return inner, nil
+	}
+
+	left, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	op := p.next()
+	if op.kind != tokOp {
+		return nil, fmt.Errorf("expected comparison operator at %d", op.pos)
+	}
+	right, err := p.parseOperand()
+	if err != nil {
+		return nil, err
+	}
+	if left.path == nil && right.path == nil {
+		return nil, fmt.Errorf("comparison at %d has no field", op.pos)
+	}
+	return compareNode{left: left, right: right, op: op.text}, nil
+}
+
+func (p *parser) parseOperand() (operand, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokNumber:
+		v, err := strconv.ParseFloat(tok.text, 64)
+		if err != nil {
+			return operand{}, fmt.Errorf("invalid number %q at %d", tok.text, tok.pos)
+		}
+		return operand{literal: v}, nil
+	case tokString:
+		return operand{literal: tok.text}, nil
+	case tokIdent:
+		switch strings.ToLower(tok.text) {
+		case "true":
+			return operand{literal: true}, nil
+		case "false":
+			return operand{literal: false}, nil
+		}
+		path, err := parsePath(tok.text)
+		if err != nil {
+			return operand{}, fmt.Errorf("%v at %d", err, tok.pos)
+		}
+		return operand{path: path}, nil
+	case tokEOF:
+		return operand{}, fmt.Errorf("unexpected end of expression")
+	}
+	return operand{}, fmt.Errorf("unexpected %q at %d", tok.text, tok.pos)
+}
@@ -0,0 +1,231 @@
+package screener
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"tuyul/backend/internal/model"
+)
+
+var (
+	coinType = reflect.TypeOf(model.Coin{})
+	timeType = reflect.TypeOf(time.Time{})
+
+	// Struct type -> JSON field name -> field index
+	jsonFieldCache sync.Map
+)
+
+// jsonFields maps the JSON names of a struct type to its field indexes
+func jsonFields(t reflect.Type) map[string]int {
+	if cached, ok := jsonFieldCache.Load(t); ok {
+		return cached.(map[string]int)
+	}
+	fields := make(map[string]int, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if !f.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = i
+	}
+	jsonFieldCache.Store(t, fields)
+	return fields
+}
+
+// parsePath splits a dotted field name and checks it against model.Coin.
+// Map keys (timeframes, profiles) are free, the path must end on a scalar.
+func parsePath(name string) ([]string, error) {
+	path := strings.Split(name, ".")
+	t := coinType
+	for i, seg := range path {
+		if seg == "" {
+			return nil, fmt.Errorf("invalid field %q", name)
+		}
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		switch {
+		case t.Kind() == reflect.Struct && t != timeType:
+			idx, ok := jsonFields(t)[seg]
+			if !ok {
+				return nil, fmt.Errorf("unknown field %q", strings.Join(path[:i+1], "."))
+			}
+			t = t.Field(idx).Type
+		case t.Kind() == reflect.Map && t.Key().Kind() == reflect.String:
+			t = t.Elem()
+		default:
+			return nil, fmt.Errorf("unknown field %q", strings.Join(path[:i+1], "."))
+		}
+	}
+
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if !isScalar(t) {
+		return nil, fmt.Errorf("field %q is not a number, string or bool", name)
+	}
+	return path, nil
+}
+
+func isScalar(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.String, reflect.Bool:
+		return true
+	}
+	return t == timeType
+}
+
+// resolve returns the value of a field as float64, string or bool.
+// Times resolve to unix seconds. ok is false when the field is missing (e.g. nil depth).
+func resolve(coin *model.Coin, path []string) (any, bool) {
+	v := reflect.ValueOf(coin)
+	for _, seg := range path {
+		for v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				return nil, false
+			}
+			v = v.Elem()
+		}
+		switch v.Kind() {
+		case reflect.Struct:
+			idx, ok := jsonFields(v.Type())[seg]
+			if !ok {
+				return nil, false
+			}
+			v = v.Field(idx)
+		case reflect.Map:
+			v = v.MapIndex(reflect.ValueOf(seg).Convert(v.Type().Key()))
+			if !v.IsValid() {
+				return nil, false
+			}
+		default:
+			return nil, false
+		}
+	}
+
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil, false
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.String:
+		return v.String(), true
+	case reflect.Bool:
+		return v.Bool(), true
+	}
+	if v.Type() == timeType {
+		return float64(v.Interface().(time.Time).Unix()), true
+	}
+	return nil, false
+}
+
+// DefaultSort orders screen results when no sort is given
+const DefaultSort = "pump_score desc"
+
+// Sort is a parsed sort expression: a field and a direction
+type Sort struct {
+	Field string
+	Desc  bool
+	path  []string
+}
+
+// ParseSort parses "field [asc|desc]" or "-field" (descending), empty meaning DefaultSort
+func ParseSort(expr string) (*Sort, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		expr = DefaultSort
+	}
+
+	s := &Sort{}
+	parts := strings.Fields(expr)
+	switch {
+	case len(parts) == 1 && strings.HasPrefix(parts[0], "-"):
+		s.Field, s.Desc = parts[0][1:], true
+	case len(parts) == 1:
+		s.Field = parts[0]
+	case len(parts) == 2 && strings.EqualFold(parts[1], "asc"):
+		s.Field = parts[0]
+	case len(parts) == 2 && strings.EqualFold(parts[1], "desc"):
+		s.Field, s.Desc = parts[0], true
+	default:
+		return nil, fmt.Errorf("sort must be \"field [asc|desc]\"")
+	}
+
+	path, err := parsePath(s.Field)
+	if err != nil {
+		return nil, err
+	}
+	s.path = path
+	return s, nil
+}
+
+// Apply sorts coins in place, coins missing the field last
+func (s *Sort) Apply(coins []*model.Coin) {
+	type keyed struct {
+		coin  *model.Coin
+		value any
+		ok    bool
+	}
+	items := make([]keyed, len(coins))
+	for i, coin := range coins {
+		v, ok := resolve(coin, s.path)
+		items[i] = keyed{coin, v, ok}
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		a, b := items[i], items[j]
+		if !a.ok || !b.ok {
+			return a.ok && !b.ok
+		}
+		cmp := compareValues(a.value, b.value)
+		if s.Desc {
+			return cmp > 0
+		}
+		return cmp < 0
+	})
+
+	for i, item := range items {
+		coins[i] = item.coin
+	}
+}
+
+// compareValues orders two resolved values, false before true
+func compareValues(a, b any) int {
+	switch av := a.(type) {
+	case float64:
+		if bv, ok := b.(float64); ok {
+			return compareFloat(av, bv)
+		}
+	case string:
+		if bv, ok := b.(string); ok {
+			return strings.Compare(av, bv)
+		}
+	case bool:
+		if bv, ok := b.(bool); ok && av != bv {
+			if bv {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
@@ -1,12 +1,12 @@
 package market
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"strings"
 	"sync"
+	"time"
 
-	"tuyul/backend/pkg/indodax"
+	"tuyul/backend/internal/exchange"
 	"tuyul/backend/pkg/logger"
 )
 
@@ -24,32 +24,63 @@ type OrderBookTicker struct {
 	BestAsk float64         `json:"best_ask"` // For backward compatibility
 	Bids    []OrderBookLevel `json:"bids"`     // All bid levels (sorted: highest first)
 	Asks    []OrderBookLevel `json:"asks"`     // All ask levels (sorted: lowest first)
+
+	// Sequence is the stream sequence of the last applied update (0 if unknown)
+	Sequence int64 `json:"sequence"`
+	// Stale is set while a sequence gap was detected and the book is being resynced
+	Stale bool `json:"stale"`
 }
 
 // TickerHandler is a callback function for ticker updates
 type TickerHandler func(ticker OrderBookTicker)
 
+const (
+	// Timeout of one REST snapshot request
+	resyncTimeout = 10 * time.Second
+	// Minimum delay between resync attempts of the same pair
+	resyncRetryDelay = 2 * time.Second
+)
+
+// bookState tracks stream consistency of one subscribed pair
+type bookState struct {
+	lastSeq    int64
+	stale      bool
+	gaps       int64 // Incremented on every detected gap, so a resync can tell if it is outdated
+	resyncing  bool
+	lastResync time.Time
+}
+
 type SubscriptionManager struct {
-	wsClient *indodax.WSClient
-	log      *logger.Logger
+	stream     exchange.MarketStream
+	marketData exchange.MarketData
+	log        *logger.Logger
 
 	// pair -> list of subscribers
 	subscribers map[string][]TickerHandler
 	// pair -> reference count
 	refCount map[string]int
 	mu       sync.RWMutex
+
+	// pair -> sequence tracking state
+	books  map[string]*bookState
+	bookMu sync.Mutex
+
+	// Called with the books of every subscribed pair
+	observers []TickerHandler
 }
 
-func NewSubscriptionManager(wsClient *indodax.WSClient) *SubscriptionManager {
+func NewSubscriptionManager(stream exchange.MarketStream, marketData exchange.MarketData) *SubscriptionManager {
 	sm := &SubscriptionManager{
-		wsClient:    wsClient,
+		stream:      stream,
+		marketData:  marketData,
 		log:         logger.GetLogger(),
 		subscribers: make(map[string][]TickerHandler),
 		refCount:    make(map[string]int),
+		books:       make(map[string]*bookState),
 	}
 
-	// Add message handler on WSClient (don't replace existing handlers)
-	wsClient.AddMessageHandler(sm.handleWSMessage)
+	// Add order book handler on the stream (don't replace existing handlers)
+	stream.AddOrderBookHandler(sm.handleOrderBook)
 
 	return sm
 }
@@ -59,16 +90,16 @@ func (sm *SubscriptionManager) Subscribe(pair string, handler TickerHandler) err
 	sm.mu.Lock()
 	defer sm.mu.Unlock()
 
-	channel := fmt.Sprintf("market:order-book-%s", pair)
-
-	// If first subscriber, subscribe via WSClient
+	// If first subscriber, subscribe via the market stream
 	if sm.refCount[pair] == 0 {
-		if err := sm.wsClient.Connect(); err != nil {
+		if err := sm.stream.Connect(); err != nil {
 			return fmt.Errorf("failed to connect ws: %w", err)
 		}
-		sm.log.Infof("SubscriptionManager: Subscribing to order-book channel: %s for pair: %s", channel, pair)
-		sm.wsClient.Subscribe(channel)
-		sm.log.Infof("SubscriptionManager: Subscribe() called for channel: %s", channel)
+		sm.log.Infof("SubscriptionManager: Subscribing to order book for pair: %s", pair)
+		sm.bookMu.Lock()
+		sm.books[pair] = &bookState{}
+		sm.bookMu.Unlock()
+		sm.stream.SubscribeOrderBook(pair)
 	} else {
 		sm.log.Debugf("SubscriptionManager: Pair %s already has %d subscribers, skipping subscription", pair, sm.refCount[pair])
 	}
@@ -97,149 +128,174 @@ func (sm *SubscriptionManager) Unsubscribe(pair string, handler TickerHandler) {
 	}
 
 	if sm.refCount[pair] == 0 {
-		channel := fmt.Sprintf("market:order-book-%s", pair)
-		sm.wsClient.Unsubscribe(channel)
+		sm.stream.UnsubscribeOrderBook(pair)
 		delete(sm.subscribers, pair)
 		delete(sm.refCount, pair)
-		sm.log.Infof("Unsubscribed from WebSocket channel: %s", channel)
+		sm.bookMu.Lock()
+		delete(sm.books, pair)
+		sm.bookMu.Unlock()
+		sm.log.Infof("Unsubscribed from order book for pair: %s", pair)
 	}
 }
 
-func (sm *SubscriptionManager) handleWSMessage(channel string, data []byte) {
-	// We only care about order-book channels
-	// Format: market:order-book-btcidr
-	// Early return for non-order-book channels to avoid unnecessary processing
-	if !strings.HasPrefix(channel, "market:order-book-") {
-		return
+// OnBook registers a handler called with the book of every subscribed pair
+func (sm *SubscriptionManager) OnBook(handler TickerHandler) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.observers = append(sm.observers, handler)
+}
+
+// IsStale reports whether the book of a subscribed pair is known to be inconsistent
+func (sm *SubscriptionManager) IsStale(pair string) bool {
+	sm.bookMu.Lock()
+	defer sm.bookMu.Unlock()
+
+	if state, ok := sm.books[pair]; ok {
+		return state.stale
 	}
+	return false
+}
 
-	sm.log.Infof("SubscriptionManager: Received order-book message on channel: %s (data length: %d bytes)", channel, len(data))
+func (sm *SubscriptionManager) handleOrderBook(book *exchange.OrderBook) {
+	pair := book.Pair
 
-	var pair string
-	if n, err := fmt.Sscanf(channel, "market:order-book-%s", &pair); err != nil || n != 1 {
-		sm.log.Errorf("SubscriptionManager: Failed to extract pair from channel %s: %v", channel, err)
+	// 1. Track sequence and detect gaps
+	sm.bookMu.Lock()
+	state, ok := sm.books[pair]
+	if !ok {
+		sm.bookMu.Unlock()
 		return
 	}
-	sm.log.Infof("SubscriptionManager: Processing orderbook for pair: %s", pair)
-
-	// Parse orderbook data
-	// According to Indodax docs, the structure is:
-	// {
-	//   "result": {
-	//     "channel": "market:order-book-btcidr",
-	//     "data": {
-	//       "data": {
-	//         "pair": "btcidr",
-	//         "ask": [...],
-	//         "bid": [...]
-	//       },
-	//       "offset": 67409
-	//     }
-	//   }
-	// }
-	// The ws_client already extracts result.data, so we receive:
-	// {
-	//   "data": {
-	//     "pair": "btcidr",
-	//     "ask": [...],
-	//     "bid": [...]
-	//   },
-	//   "offset": 67409
-	// }
-	var obData struct {
-		Data struct {
-			Pair string `json:"pair"`
-			Ask  []struct {
-				Price      string `json:"price"`
-				BaseVolume string `json:"btc_volume"` // Note: field name varies by pair (btc_volume, eth_volume, etc.)
-				IDRVolume  string `json:"idr_volume"`
-			} `json:"ask"`
-			Bid []struct {
-				Price      string `json:"price"`
-				BaseVolume string `json:"btc_volume"` // Note: field name varies by pair
-				IDRVolume  string `json:"idr_volume"`
-			} `json:"bid"`
-		} `json:"data"`
-		Offset int64 `json:"offset"`
-	}
-
-	if err := json.Unmarshal(data, &obData); err != nil {
-		sm.log.Errorf("Failed to unmarshal orderbook data for %s: %v. Raw data: %s", pair, err, string(data))
-		return
+
+	if book.Sequence > 0 && state.lastSeq > 0 {
+		switch {
+		case book.Sequence == state.lastSeq:
+			// Duplicate (e.g. snapshot re-sent on resubscribe)
+			sm.bookMu.Unlock()
+			return
+		case book.Sequence < state.lastSeq:
+			// Out-of-order frame or stream reset, rebase on it and resync
+			sm.log.Warnf("SubscriptionManager: Out-of-order order book for %s (got %d after %d), resyncing", pair, book.Sequence, state.lastSeq)
+			state.stale = true
+			state.gaps++
+		case book.Sequence > state.lastSeq+1:
+			sm.log.Warnf("SubscriptionManager: Order book gap for %s (expected %d, got %d), resyncing", pair, state.lastSeq+1, book.Sequence)
+			state.stale = true
+			state.gaps++
+		}
+	}
+	if book.Sequence > 0 {
+		state.lastSeq = book.Sequence
 	}
 
-	if len(obData.Data.Ask) == 0 || len(obData.Data.Bid) == 0 {
-		sm.log.Debugf("SubscriptionManager: Empty orderbook for %s (ask=%d bid=%d)", pair, len(obData.Data.Ask), len(obData.Data.Bid))
-		return
+	startResync := state.stale && !state.resyncing && time.Since(state.lastResync) >= resyncRetryDelay
+	if startResync {
+		state.resyncing = true
+		state.lastResync = time.Now()
 	}
+	stale := state.stale
+	gaps := state.gaps
+	sm.bookMu.Unlock()
 
-	// Parse all bid levels
-	bids := make([]OrderBookLevel, 0, len(obData.Data.Bid))
-	for _, bid := range obData.Data.Bid {
-		var price, baseVol, idrVol float64
-		if _, err := fmt.Sscanf(bid.Price, "%f", &price); err != nil {
-			sm.log.Warnf("Failed to parse bid price for %s: %v (skipping level)", pair, err)
-			continue
-		}
-		if _, err := fmt.Sscanf(bid.BaseVolume, "%f", &baseVol); err != nil {
-			// Base volume might be missing or empty, set to 0
-			baseVol = 0
-		}
-		if _, err := fmt.Sscanf(bid.IDRVolume, "%f", &idrVol); err != nil {
-			// IDR volume might be missing or empty, set to 0
-			idrVol = 0
-		}
-		bids = append(bids, OrderBookLevel{
-			Price:      price,
-			BaseVolume: baseVol,
-			IDRVolume:  idrVol,
-		})
+	if startResync {
+		go sm.resync(pair, gaps)
 	}
 
-	// Parse all ask levels
-	asks := make([]OrderBookLevel, 0, len(obData.Data.Ask))
-	for _, ask := range obData.Data.Ask {
-		var price, baseVol, idrVol float64
-		if _, err := fmt.Sscanf(ask.Price, "%f", &price); err != nil {
-			sm.log.Warnf("Failed to parse ask price for %s: %v (skipping level)", pair, err)
-			continue
+	// 2. Notify subscribers (stale books are still delivered, flagged)
+	ticker, ok := ToOrderBookTicker(book)
+	if !ok {
+		sm.log.Debugf("SubscriptionManager: Empty orderbook for %s (ask=%d bid=%d)", pair, len(book.Asks), len(book.Bids))
+		return
 	}
-		if _, err := fmt.Sscanf(ask.BaseVolume, "%f", &baseVol); err != nil {
-			baseVol = 0
-		}
-		if _, err := fmt.Sscanf(ask.IDRVolume, "%f", &idrVol); err != nil {
-			idrVol = 0
-		}
-		asks = append(asks, OrderBookLevel{
-			Price:      price,
-			BaseVolume: baseVol,
-			IDRVolume:  idrVol,
-		})
+	ticker.Stale = stale
+	sm.notify(ticker)
+}
+
+// resync replaces a stale book with a REST snapshot.
+// The book stays stale if another gap was detected while the snapshot was in flight.
+func (sm *SubscriptionManager) resync(pair string, gaps int64) {
+	ctx, cancel := context.WithTimeout(context.Background(), resyncTimeout)
+	defer cancel()
+
+	book, err := sm.marketData.GetOrderBook(ctx, pair)
+	if err == nil && (len(book.Bids) == 0 || len(book.Asks) == 0) {
+		err = fmt.Errorf("empty snapshot")
 	}
 
-	// Extract best prices (first element is best)
-	var bestBid, bestAsk float64
-	if len(bids) > 0 {
-		bestBid = bids[0].Price
+	sm.bookMu.Lock()
+	state, ok := sm.books[pair]
+	if !ok {
+		// Unsubscribed meanwhile
+		sm.bookMu.Unlock()
+		return
+	}
+	state.resyncing = false
+	if err != nil {
+		sm.bookMu.Unlock()
+		sm.log.Warnf("SubscriptionManager: Failed to resync order book for %s: %v", pair, err)
+		return
 	}
-	if len(asks) > 0 {
-		bestAsk = asks[0].Price
+	if state.gaps != gaps {
+		sm.bookMu.Unlock()
+		sm.log.Debugf("SubscriptionManager: Order book for %s changed during resync, will retry", pair)
+		return
+	}
+	state.stale = false
+	seq := state.lastSeq
+	sm.bookMu.Unlock()
+
+	sm.log.Infof("SubscriptionManager: Resynced order book for %s from snapshot (seq=%d)", pair, seq)
+
+	ticker, _ := ToOrderBookTicker(book)
+	ticker.Sequence = seq
+	sm.notify(ticker)
+}
+
+// ToOrderBookTicker converts an exchange book; ok is false if a side is empty
+func ToOrderBookTicker(book *exchange.OrderBook) (OrderBookTicker, bool) {
+	if len(book.Asks) == 0 || len(book.Bids) == 0 {
+		return OrderBookTicker{}, false
 	}
 
-	ticker := OrderBookTicker{
-		Pair:    pair,
-		BestBid: bestBid,
-		BestAsk: bestAsk,
-		Bids:    bids,
-		Asks:    asks,
+	// Convert levels (first element is best)
+	bids := make([]OrderBookLevel, 0, len(book.Bids))
+	for _, bid := range book.Bids {
+		bids = append(bids, OrderBookLevel{
+			Price:      bid.Price,
+			BaseVolume: bid.BaseVolume,
+			IDRVolume:  bid.QuoteVolume,
+		})
 	}
+	asks := make([]OrderBookLevel, 0, len(book.Asks))
+	for _, ask := range book.Asks {
+		asks = append(asks, OrderBookLevel{
+			Price:      ask.Price,
+			BaseVolume: ask.BaseVolume,
+			IDRVolume:  ask.QuoteVolume,
+		})
+	}
+
+	return OrderBookTicker{
+		Pair:     book.Pair,
+		BestBid:  bids[0].Price,
+		BestAsk:  asks[0].Price,
+		Bids:     bids,
+		Asks:     asks,
+		Sequence: book.Sequence,
+	}, true
+}
 
-	// Notify subscribers
+func (sm *SubscriptionManager) notify(ticker OrderBookTicker) {
 	sm.mu.RLock()
-	handlers := sm.subscribers[pair]
+	handlers := sm.subscribers[ticker.Pair]
+	observers := sm.observers
 	sm.mu.RUnlock()
 
-	sm.log.Debugf("SubscriptionManager: Notifying %d handlers for pair %s (bid=%.2f ask=%.2f)", len(handlers), pair, bestBid, bestAsk)
+	for _, observer := range observers {
+		observer(ticker)
+	}
+
+	sm.log.Debugf("SubscriptionManager: Notifying %d handlers for pair %s (bid=%.2f ask=%.2f stale=%v)", len(handlers), ticker.Pair, ticker.BestBid, ticker.BestAsk, ticker.Stale)
 	for _, handler := range handlers {
 		handler(ticker)
 	}
@@ -0,0 +1,254 @@
+package market
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"tuyul/backend/internal/exchange"
+	"tuyul/backend/internal/model"
+	"tuyul/backend/pkg/logger"
+)
+
+// Recent trades kept in memory per pair
+const maxRecentTrades = 200
+
+// tradeBuffer holds the most recent prints of a pair (oldest first)
+type tradeBuffer struct {
+	trades []model.MarketTrade
+	lastID int64
+}
+
+// subscribeTrades subscribes to the trade feed of every known pair not yet subscribed
+func (s *MarketDataService) subscribeTrades() {
+	var pairs []string
+	s.tradesMu.Lock()
+	s.pairs.Range(func(key, _ interface{}) bool {
+		pairID := key.(string)
+		if !s.tradeSubs[pairID] {
+			s.tradeSubs[pairID] = true
+			pairs = append(pairs, pairID)
+		}
+		return true
+	})
+	s.tradesMu.Unlock()
+
+	for _, pairID := range pairs {
+		s.stream.SubscribeTrades(pairID)
+	}
+	if len(pairs) > 0 {
+		logger.Infof("Subscribed to trade feed of %d pairs", len(pairs))
+	}
+}
+
+// processTrades handles trade prints from the stream
+func (s *MarketDataService) processTrades(trades []exchange.Trade) {
+	byPair := make(map[string][]exchange.Trade)
+	for _, t := range trades {
+		byPair[t.Pair] = append(byPair[t.Pair], t)
+	}
+	pairs := make([]string, 0, len(byPair))
+	for pairID := range byPair {
+		pairs = append(pairs, pairID)
+	}
+	s.markStream(time.Now(), pairs...)
+
+	for pairID, pairTrades := range byPair {
+		s.recordTrades(pairID, pairTrades, true)
+	}
+}
+
+// recordTrades stores new prints (by trade ID) and folds them into the coin timeframes.
+// Backfilled trades only count towards timeframes that started before them.
+func (s *MarketDataService) recordTrades(pairID string, trades []exchange.Trade, live bool) int {
+	// 1. Keep only trades newer than the last recorded one (batches are not ordered)
+	trades = append([]exchange.Trade(nil), trades...)
+	sort.Slice(trades, func(i, j int) bool { return trades[i].ID < trades[j].ID })
+
+	s.tradesMu.Lock()
+	buf, ok := s.trades[pairID]
+	if !ok {
+		buf = &tradeBuffer{}
+		s.trades[pairID] = buf
+	}
+
+	fresh := make([]exchange.Trade, 0, len(trades))
+	for _, t := range trades {
+		if t.ID <= buf.lastID {
+			continue
+		}
+		buf.lastID = t.ID
+		buf.trades = append(buf.trades, model.MarketTrade{
+			ID:     t.ID,
+			Pair:   pairID,
+			Side:   t.Side,
+			Price:  t.Price,
+			Amount: t.Amount,
+			Total:  t.Price * t.Amount,
+			Time:   t.Time,
+		})
+		fresh = append(fresh, t)
+	}
+	if len(buf.trades) > maxRecentTrades {
+		buf.trades = append([]model.MarketTrade(nil), buf.trades[len(buf.trades)-maxRecentTrades:]...)
+	}
+	s.tradesMu.Unlock()
+
+	if len(fresh) == 0 {
+		return 0
+	}
+
+	// 2. Update coin (backfills run outside the stream goroutine)
+	s.coinMu.Lock()
+	coin, _ := s.getOrCreateCoin(pairID)
+	for _, t := range fresh {
+		applyTrade(coin, t, live)
+		s.recordWindowTrade(pairID, t)
+	}
+	if live {
+		coin.CurrentPrice = fresh[len(fresh)-1].Price
+	}
+	coin.LastUpdate = time.Now()
+	s.refreshWindows(coin, coin.LastUpdate)
+	s.refreshScores(coin)
+	coin.Volatility1m = CalculateVolatility(coin, s.ShortestTimeframe())
+	s.refreshStale(coin, coin.LastUpdate)
+
+	s.coinCache.Store(pairID, coin)
+
+	// Sample log every 100 trades per pair to see it's working
+	shortest := s.ShortestTimeframe()
+	if trx := coin.Timeframes.Get(shortest).Trx; trx > 0 && trx%100 == 0 {
+		logger.Infof("Updated coin %s: Price=%.2f, Trx%s=%d, PumpScore=%.2f", pairID, coin.CurrentPrice, shortest, trx, coin.PumpScore)
+	}
+	s.coinMu.Unlock()
+	go s.saveCoinToRedis(coin)
+
+	// 3. Notify subscribers
+	s.mu.RLock()
+	handlers := s.subscribers
+	s.mu.RUnlock()
+	for _, h := range handlers {
+		h(coin)
+	}
+
+	return len(fresh)
+}
+
+// applyTrade adds one print to every timeframe
+func applyTrade(coin *model.Coin, t exchange.Trade, live bool) {
+	total := t.Price * t.Amount
+
+	apply := func(tf *model.TimeframeData, since time.Time) {
+		if !live && t.Time.Before(since) {
+			return
+		}
+		if tf.Open == 0 {
+			tf.Open = t.Price
+		}
+		tf.High = max(tf.High, t.Price)
+		if tf.Low == 0 {
+			tf.Low = t.Price
+		} else {
+			tf.Low = min(tf.Low, t.Price)
+		}
+		tf.Trx++
+		tf.BaseVolume += t.Amount
+		if t.Side == "buy" {
+			tf.BuyVolume += total
+		} else {
+			tf.SellVolume += total
+		}
+		if tf.BaseVolume > 0 {
+			tf.VWAP = (tf.BuyVolume + tf.SellVolume) / tf.BaseVolume
+		}
+	}
+
+	for name, data := range coin.Timeframes {
+		if data != nil {
+			apply(data, coin.LastReset[name])
+		}
+	}
+}
+
+// backfillTrades fetches recent trades over REST (fallback when the stream missed prints)
+func (s *MarketDataService) backfillTrades(ctx context.Context, pairID string) (int, error) {
+	trades, err := s.marketData.GetTrades(ctx, pairID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch trades for %s: %w", pairID, err)
+	}
+	return s.recordTrades(pairID, trades, false), nil
+}
+
+// backfillActivePairs recovers prints missed while the stream was disconnected.
+// Only pairs that already had trades are backfilled to keep REST usage low.
+func (s *MarketDataService) backfillActivePairs() {
+	s.tradesMu.Lock()
+	pairs := make([]string, 0, len(s.trades))
+	for pairID, buf := range s.trades {
+		if len(buf.trades) > 0 {
+			pairs = append(pairs, pairID)
+		}
+	}
+	s.tradesMu.Unlock()
+
+	if len(pairs) == 0 {
+		return
+	}
+
+	recovered := 0
+	for _, pairID := range pairs {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		n, err := s.backfillTrades(ctx, pairID)
+		cancel()
+		if err != nil {
+			logger.Warnf("Trade backfill failed: %v", err)
+			continue
+		}
+		recovered += n
+	}
+	logger.Infof("Trade backfill after reconnect: %d trades recovered across %d pairs", recovered, len(pairs))
+}
+
+// GetRecentTrades returns the latest prints of a pair, newest first.
+// Falls back to REST when nothing was recorded yet.
+func (s *MarketDataService) GetRecentTrades(ctx context.Context, pairID string, limit int) ([]model.MarketTrade, error) {
+	if limit <= 0 || limit > maxRecentTrades {
+		limit = maxRecentTrades
+	}
+
+	if s.recentTradeCount(pairID) == 0 {
+		if _, err := s.backfillTrades(ctx, pairID); err != nil {
+			return nil, err
+		}
+	}
+
+	s.tradesMu.Lock()
+	defer s.tradesMu.Unlock()
+
+	buf, ok := s.trades[pairID]
+	if !ok {
+		return []model.MarketTrade{}, nil
+	}
+
+	n := len(buf.trades)
+	if n > limit {
+		n = limit
+	}
+	result := make([]model.MarketTrade, 0, n)
+	for i := len(buf.trades) - 1; i >= len(buf.trades)-n; i-- {
+		result = append(result, buf.trades[i])
+	}
+	return result, nil
+}
+
+func (s *MarketDataService) recentTradeCount(pairID string) int {
+	s.tradesMu.Lock()
+	defer s.tradesMu.Unlock()
+
+	if buf, ok := s.trades[pairID]; ok {
+		return len(buf.trades)
+	}
+	return 0
+}
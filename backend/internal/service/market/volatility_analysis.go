@@ -2,9 +2,9 @@ package market
 
 import "tuyul/backend/internal/model"
 
-// CalculateVolatility calculates the 1-minute volatility percentage
-func CalculateVolatility(coin *model.Coin) float64 {
-	tf := coin.Timeframes.OneMinute
+// CalculateVolatility calculates the volatility percentage of a timeframe
+func CalculateVolatility(coin *model.Coin, timeframe string) float64 {
+	tf := coin.Timeframes.Get(timeframe)
 	if tf.Open == 0 {
 		return 0
 	}
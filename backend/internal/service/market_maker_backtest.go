@@ -0,0 +1,557 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"sync"
+	"time"
+
+	"tuyul/backend/internal/exchange"
+	"tuyul/backend/internal/exchange/shadow"
+	"tuyul/backend/internal/model"
+	"tuyul/backend/internal/service/market"
+	"tuyul/backend/internal/util"
+	"tuyul/backend/pkg/logger"
+)
+
+// Minimum time between two order actions of a bot, as the live debounce
+const marketMakerDebounce = 2 * time.Second
+
+// MarketReplay is recorded market data played back through a market stream,
+// e.g. recorder.Replay
+type MarketReplay interface {
+	GetPairs(ctx context.Context) ([]exchange.Pair, error)
+	GetPriceIncrements(ctx context.Context) (map[string]float64, error)
+
+	// Clock returns the recorded time of the last played event
+	Clock() time.Time
+	// Done is closed when the playback reached the end of the recording
+	Done() <-chan struct{}
+}
+
+// MarketMakerBacktest replays recorded order books and trade prints through the
+// quoting rules of Market Maker configurations. Each configuration quotes with
+// its own shadow order, which keeps its queue position behind the recorded volume.
+type MarketMakerBacktest struct {
+	req *model.MarketMakerBacktestRequest
+	log *logger.Logger
+}
+
+// NewMarketMakerBacktest validates a request
+func NewMarketMakerBacktest(req *model.MarketMakerBacktestRequest) (*MarketMakerBacktest, error) {
+	if len(req.Configs) == 0 {
+		return nil, util.ErrBadRequest("At least one configuration is required")
+	}
+	if len(req.Configs) > model.MaxBacktestConfigs {
+		return nil, util.ErrBadRequest(fmt.Sprintf("At most %d configurations can be compared", model.MaxBacktestConfigs))
+	}
+	if req.From != nil && req.To != nil && !req.To.After(*req.From) {
+		return nil, util.ErrBadRequest("To must be after from")
+	}
+	taker, maker := req.Fees()
+	if taker < 0 || maker < 0 {
+		return nil, util.ErrBadRequest("Fees cannot be negative")
+	}
+
+	for i, cfg := range req.Configs {
+		if cfg.Pair == "" {
+			return nil, util.ErrBadRequest(fmt.Sprintf("Configuration %d: pair is required", i+1))
+		}
+		if cfg.InitialBalanceIDR < util.MinInitialBalanceIDR {
+			return nil, util.ErrBadRequest(fmt.Sprintf("Configuration %d: initial balance must be at least %.0f IDR", i+1, util.MinInitialBalanceIDR))
+		}
+		if cfg.OrderSizeIDR < util.MinOrderValueIDR || cfg.OrderSizeIDR > cfg.InitialBalanceIDR {
+			return nil, util.ErrBadRequest(fmt.Sprintf("Configuration %d: order size must be between %.0f IDR and the initial balance", i+1, util.MinOrderValueIDR))
+		}
+		if cfg.MinGapPercent < 0 || cfg.MinGapPercent > 10 {
+			return nil, util.ErrBadRequest(fmt.Sprintf("Configuration %d: min gap percent must be between 0 and 10", i+1))
+		}
+		if cfg.MaxLossIDR <= 0 {
+			return nil, util.ErrBadRequest(fmt.Sprintf("Configuration %d: max loss IDR must be greater than 0", i+1))
+		}
+	}
+
+	return &MarketMakerBacktest{req: req, log: logger.GetLogger()}, nil
+}
+
+// Run plays the replay through stream, the venue market stream decoding its
+// frames, and returns the outcome of every configuration
+func (b *MarketMakerBacktest) Run(ctx context.Context, replay MarketReplay, stream exchange.MarketStream) (*model.MarketMakerBacktestResult, error) {
+	// 1. Pair metadata of the recording
+	pairs, err := replay.GetPairs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("recording has no pair metadata: %w", err)
+	}
+	increments, _ := replay.GetPriceIncrements(ctx) // Precision fallback when missing
+
+	run := &marketMakerRun{
+		req:     b.req,
+		markets: make(map[string]*mmMarket),
+	}
+	run.takerFee, run.makerFee = b.req.Fees()
+	for i, cfg := range b.req.Configs {
+		var pairInfo *exchange.Pair
+		for j := range pairs {
+			if pairs[j].ID == cfg.Pair {
+				pairInfo = &pairs[j]
+				break
+			}
+		}
+		if pairInfo == nil {
+			return nil, util.ErrBadRequest(fmt.Sprintf("Configuration %d: pair %s is not in the recording", i+1, cfg.Pair))
+		}
+		tickSize := increments[pairInfo.ID]
+		if tickSize <= 0 {
+			tickSize = 1.0 / util.Pow10(pairInfo.PricePrecision)
+		}
+
+		m := run.markets[cfg.Pair]
+		if m == nil {
+			m = &mmMarket{}
+			run.markets[cfg.Pair] = m
+		}
+		bot := newMarketMakerSim(int64(i+1), cfg, pairInfo, marketMakerRules{tickSize: tickSize, log: b.log})
+		m.bots = append(m.bots, bot)
+		run.bots = append(run.bots, bot)
+	}
+
+	// 2. Play the recording
+	stream.AddOrderBookHandler(func(book *exchange.OrderBook) {
+		if ctx.Err() == nil {
+			run.onOrderBook(replay.Clock(), book)
+		}
+	})
+	stream.AddTradeHandler(func(trades []exchange.Trade) {
+		if ctx.Err() == nil {
+			run.onTrades(replay.Clock(), trades)
+		}
+	})
+	for pair := range run.markets {
+		stream.SubscribeOrderBook(pair)
+		stream.SubscribeTrades(pair)
+	}
+	if err := stream.Connect(); err != nil {
+		return nil, err
+	}
+	select {
+	case <-replay.Done():
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	// 3. Outcome
+	return run.finish(), nil
+}
+
+// marketMakerRun is the state of one backtest
+type marketMakerRun struct {
+	req      *model.MarketMakerBacktestRequest
+	takerFee float64 // Percent
+	makerFee float64
+	markets  map[string]*mmMarket
+	bots     []*marketMakerSim // In request order
+
+	from, to time.Time // Simulated period seen so far
+	mu       sync.Mutex
+}
+
+// mmMarket is the recorded market of a pair and the bots quoting it
+type mmMarket struct {
+	bots []*marketMakerSim
+
+	// Shortest timeframe bucket of the trade prints, for the volatility filter
+	bucketStart           time.Time
+	bucketOpen, high, low float64
+}
+
+func (m *mmMarket) volatility() float64 {
+	if m.bucketOpen == 0 {
+		return 0
+	}
+	return (m.high - m.low) / m.bucketOpen * 100
+}
+
+func (m *mmMarket) addTrades(trades []exchange.Trade) {
+	for _, t := range trades {
+		start := t.Time.Truncate(time.Minute)
+		if start.After(m.bucketStart) {
+			m.bucketStart, m.bucketOpen, m.high, m.low = start, t.Price, t.Price, t.Price
+			continue
+		}
+		m.high = max(m.high, t.Price)
+		m.low = min(m.low, t.Price)
+	}
+}
+
+// started reports whether bots quote at this time, earlier events only build the book state
+func (r *marketMakerRun) started(now time.Time) bool {
+	return r.req.From == nil || !now.Before(*r.req.From)
+}
+
+// ended reports whether the simulated period is over, later events are ignored
+func (r *marketMakerRun) ended(now time.Time) bool {
+	return r.req.To != nil && now.After(*r.req.To)
+}
+
+func (r *marketMakerRun) onOrderBook(now time.Time, book *exchange.OrderBook) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	m := r.markets[book.Pair]
+	if m == nil || r.ended(now) {
+		return
+	}
+	quoting := r.started(now)
+	if quoting {
+		if r.from.IsZero() {
+			r.from = now
+		}
+		r.to = now
+	}
+	for _, bot := range m.bots {
+		r.applyFills(bot, bot.book.UpdateOrderBook(book), now)
+		if quoting {
+			bot.track(now)
+			r.quote(bot, m.volatility(), now)
+		}
+	}
+}
+
+func (r *marketMakerRun) onTrades(now time.Time, trades []exchange.Trade) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(trades) == 0 || r.ended(now) {
+		return
+	}
+	m := r.markets[trades[0].Pair]
+	if m == nil {
+		return
+	}
+	m.addTrades(trades)
+	for _, bot := range m.bots {
+		r.applyFills(bot, bot.book.AddTrades(trades), now)
+	}
+}
+
+// quote runs the live ticker handling of a bot on the book with its own order merged in
+func (r *marketMakerRun) quote(bot *marketMakerSim, volatility float64, now time.Time) {
+	inst := bot.inst
+	if bot.result.StoppedAt != nil {
+		return
+	}
+	book := bot.book.OrderBook()
+	if book == nil {
+		return
+	}
+	ticker, ok := market.ToOrderBookTicker(book)
+	if !ok {
+		return
+	}
+
+	// 1. Prices and balances
+	inst.CurrentBid = ticker.BestBid
+	inst.CurrentAsk = ticker.BestAsk
+	bot.rules.validateAndNormalizeBalances(inst)
+
+	// 2. Gap and volatility
+	if bot.rules.quoteSkipped(inst, ticker, volatility) {
+		return
+	}
+
+	// 3. Debounce order actions
+	if now.Sub(inst.LastOrderTime) < marketMakerDebounce {
+		return
+	}
+
+	// 4. Place, or reposition the active order
+	if inst.ActiveOrder == nil {
+		side, price, amount, ok := bot.rules.nextOrder(inst, ticker)
+		if !ok {
+			return
+		}
+		r.placeOrder(bot, side, price, amount, now)
+		return
+	}
+	if reason := bot.rules.repositionReason(inst, ticker); reason != "" {
+		bot.cancelOrder()
+		inst.LastOrderTime = now
+	}
+}
+
+// placeOrder rests a shadow order and locks its funds like a live bot
+func (r *marketMakerRun) placeOrder(bot *marketMakerSim, side string, price, amount float64, now time.Time) {
+	inst := bot.inst
+	bot.orderSeq++
+	order := &model.Order{
+		ParentID: inst.Config.ID,
+		OrderID:  strconv.Itoa(bot.orderSeq),
+		Pair:     inst.Config.Pair,
+		Side:     side,
+		Status:   "open",
+		Price:    price,
+		Amount:   amount,
+	}
+	inst.ActiveOrder = order
+	inst.LastOrderTime = now
+	bot.result.OrdersPlaced++
+
+	if side == "buy" {
+		inst.Config.Balances["idr"] = math.Max(inst.Config.Balances["idr"]-amount*price, 0)
+	} else {
+		inst.Config.Balances[inst.BaseCurrency] = math.Max(inst.Config.Balances[inst.BaseCurrency]-amount, 0)
+	}
+
+	fills := bot.book.Place(&shadow.Order{ID: order.OrderID, Side: side, Price: price, Amount: amount})
+	r.applyFills(bot, fills, now)
+}
+
+// applyFills accounts for executed shadow orders like the live fill handlers
+func (r *marketMakerRun) applyFills(bot *marketMakerSim, fills []shadow.Fill, now time.Time) {
+	inst := bot.inst
+	for _, fill := range fills {
+		order := inst.ActiveOrder
+		if order == nil || order.OrderID != fill.Order.ID {
+			continue
+		}
+		value := fill.Amount * fill.Price
+		feeRate := r.makerFee
+		if !fill.Maker {
+			feeRate = r.takerFee
+		}
+		bot.result.FeesIDR += value * feeRate / 100
+		bot.result.Fills++
+		if venue := bot.book.Venue(); venue != nil {
+			bot.recordEdge(order.Side, fill.Price, fill.Amount, venueMid(venue))
+		}
+
+		if order.Side == "buy" {
+			// IDR was locked at the order price, return what a better taker price saved
+			inst.Config.Balances["idr"] += fill.Amount * (order.Price - fill.Price)
+			inst.Config.Balances[inst.BaseCurrency] += fill.Amount
+			inst.TotalCoinBought += fill.Amount
+			inst.TotalCostIDR += value
+			inst.LastBuyPrice = inst.TotalCostIDR / inst.TotalCoinBought
+			bot.result.BuyVolumeIDR += value
+		} else {
+			inst.Config.Balances["idr"] += value
+			bot.result.SellVolumeIDR += value
+			if inst.TotalCoinBought > 0 {
+				profit := (fill.Price - inst.TotalCostIDR/inst.TotalCoinBought) * fill.Amount
+				inst.Config.TotalProfitIDR += profit
+				bot.orderProfit += profit
+
+				sellRatio := math.Min(fill.Amount/inst.TotalCoinBought, 1)
+				inst.TotalCostIDR -= inst.TotalCostIDR * sellRatio
+				inst.TotalCoinBought = math.Max(inst.TotalCoinBought-fill.Amount, 0)
+				if inst.TotalCoinBought > 0 {
+					inst.LastBuyPrice = inst.TotalCostIDR / inst.TotalCoinBought
+				} else {
+					inst.LastBuyPrice = 0
+					inst.TotalCostIDR = 0
+				}
+			}
+		}
+
+		order.FilledAmount += fill.Amount
+		if fill.Order.Remaining() > 0 {
+			continue
+		}
+
+		// Completely filled
+		if order.Side == "sell" {
+			inst.Config.TotalTrades++
+			if bot.orderProfit > 0 {
+				inst.Config.WinningTrades++
+			}
+			bot.orderProfit = 0
+		}
+		inst.ActiveOrder = nil
+
+		// Circuit breaker
+		if order.Side == "sell" && inst.Config.TotalProfitIDR < -inst.Config.MaxLossIDR {
+			stoppedAt := now
+			bot.result.StoppedAt = &stoppedAt
+		}
+	}
+}
+
+func venueMid(book *exchange.OrderBook) float64 {
+	if len(book.Bids) == 0 || len(book.Asks) == 0 {
+		return 0
+	}
+	return (book.Bids[0].Price + book.Asks[0].Price) / 2
+}
+
+func (r *marketMakerRun) finish() *model.MarketMakerBacktestResult {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	result := &model.MarketMakerBacktestResult{From: r.from, To: r.to}
+	for _, bot := range r.bots {
+		result.Configs = append(result.Configs, bot.finish())
+	}
+	return result
+}
+
+// marketMakerSim is one simulated Market Maker bot
+type marketMakerSim struct {
+	cfg      model.MarketMakerBacktestConfig
+	inst     *BotInstance
+	rules    marketMakerRules
+	book     *shadow.Book
+	orderSeq int
+
+	orderProfit float64 // Realized profit of the active sell order so far
+	result      model.MarketMakerConfigResult
+
+	// Tracking
+	lastTrack      time.Time
+	trackedTime    time.Duration
+	inventoryTime  time.Duration
+	inventoryArea  float64 // IDR × seconds
+	peakEquity     float64
+	filledNotional float64
+}
+
+func newMarketMakerSim(id int64, cfg model.MarketMakerBacktestConfig, pairInfo *exchange.Pair, rules marketMakerRules) *marketMakerSim {
+	config := &model.BotConfig{
+		ID:                id,
+		Name:              cfg.Name,
+		Type:              model.BotTypeMarketMaker,
+		Pair:              cfg.Pair,
+		IsPaperTrading:    true,
+		InitialBalanceIDR: cfg.InitialBalanceIDR,
+		OrderSizeIDR:      cfg.OrderSizeIDR,
+		MinGapPercent:     cfg.MinGapPercent,
+		MaxLossIDR:        cfg.MaxLossIDR,
+		Balances:          map[string]float64{"idr": cfg.InitialBalanceIDR, pairInfo.BaseCurrency: 0},
+		Status:            model.BotStatusRunning,
+	}
+	return &marketMakerSim{
+		cfg: cfg,
+		inst: &BotInstance{
+			Config:       config,
+			BaseCurrency: pairInfo.BaseCurrency,
+			PairInfo:     pairInfo,
+		},
+		rules:      rules,
+		book:       shadow.NewBook(),
+		peakEquity: cfg.InitialBalanceIDR,
+	}
+}
+
+// cancelOrder cancels the active order and unlocks its unfilled funds like a live cancellation
+func (s *marketMakerSim) cancelOrder() {
+	order := s.inst.ActiveOrder
+	s.inst.ActiveOrder = nil
+	if order == nil {
+		return
+	}
+	cancelled := s.book.Cancel(order.OrderID)
+	if cancelled == nil {
+		return
+	}
+	s.result.OrdersCancelled++
+	if order.Side == "buy" {
+		s.inst.Config.Balances["idr"] += cancelled.Remaining() * order.Price
+	} else {
+		s.inst.Config.Balances[s.inst.BaseCurrency] += cancelled.Remaining()
+	}
+}
+
+// inventory returns the coins held, the unfilled part of a resting sell included
+func (s *marketMakerSim) inventory() float64 {
+	coins := s.inst.Config.Balances[s.inst.BaseCurrency]
+	if order := s.inst.ActiveOrder; order != nil && order.Side == "sell" {
+		coins += order.Amount - order.FilledAmount
+	}
+	return coins
+}
+
+// equity returns the IDR balance, the unfilled part of a resting buy included, plus the inventory at mid
+func (s *marketMakerSim) equity(mid float64) float64 {
+	idr := s.inst.Config.Balances["idr"]
+	if order := s.inst.ActiveOrder; order != nil && order.Side == "buy" {
+		idr += (order.Amount - order.FilledAmount) * order.Price
+	}
+	return idr + s.inventory()*mid
+}
+
+// recordEdge adds the edge of a fill against the venue mid price to the spread capture
+func (s *marketMakerSim) recordEdge(side string, price, amount, mid float64) {
+	if mid <= 0 {
+		return
+	}
+	edge := (mid - price) * amount
+	if side == "sell" {
+		edge = -edge
+	}
+	s.result.SpreadCaptureIDR += edge
+	s.filledNotional += price * amount
+}
+
+// track updates the inventory and drawdown statistics at a venue book event
+func (s *marketMakerSim) track(now time.Time) {
+	venue := s.book.Venue()
+	if venue == nil {
+		return
+	}
+	mid := venueMid(venue)
+	if mid <= 0 {
+		return
+	}
+
+	coins := s.inventory()
+	inventoryIDR := coins * mid
+	if !s.lastTrack.IsZero() {
+		dt := now.Sub(s.lastTrack)
+		s.trackedTime += dt
+		s.inventoryArea += inventoryIDR * dt.Seconds()
+		if coins > 0 {
+			s.inventoryTime += dt
+		}
+	}
+	s.lastTrack = now
+
+	s.result.MaxInventoryIDR = max(s.result.MaxInventoryIDR, inventoryIDR)
+	if s.inst.TotalCoinBought > 0 {
+		unrealized := s.inst.TotalCostIDR/s.inst.TotalCoinBought*coins - inventoryIDR
+		s.result.MaxUnrealizedLossIDR = max(s.result.MaxUnrealizedLossIDR, unrealized)
+	}
+
+	equity := s.equity(mid)
+	s.peakEquity = max(s.peakEquity, equity)
+	if s.peakEquity > 0 {
+		s.result.MaxDrawdownPercent = max(s.result.MaxDrawdownPercent, (s.peakEquity-equity)/s.peakEquity*100)
+	}
+	s.result.FinalEquityIDR = equity
+	s.result.EndInventory = coins
+	s.result.EndInventoryIDR = inventoryIDR
+}
+
+func (s *marketMakerSim) finish() model.MarketMakerConfigResult {
+	res := s.result
+	res.Name = s.cfg.Name
+	res.Pair = s.cfg.Pair
+	res.InitialBalanceIDR = s.cfg.InitialBalanceIDR
+	if res.FinalEquityIDR == 0 {
+		res.FinalEquityIDR = s.cfg.InitialBalanceIDR // Never quoted
+	}
+	res.RealizedProfitIDR = s.inst.Config.TotalProfitIDR
+	res.NetProfitIDR = res.FinalEquityIDR - res.InitialBalanceIDR - res.FeesIDR
+	res.ReturnPercent = res.NetProfitIDR / res.InitialBalanceIDR * 100
+	res.TotalTrades = s.inst.Config.TotalTrades
+	res.WinningTrades = s.inst.Config.WinningTrades
+	res.WinRate = s.inst.Config.WinRate()
+	if s.filledNotional > 0 {
+		res.SpreadCapturePercent = res.SpreadCaptureIDR / s.filledNotional * 100
+	}
+	if s.trackedTime > 0 {
+		res.AvgInventoryIDR = s.inventoryArea / s.trackedTime.Seconds()
+		res.InventoryTimePercent = float64(s.inventoryTime) / float64(s.trackedTime) * 100
+	}
+	return res
+}
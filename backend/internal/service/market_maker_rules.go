@@ -0,0 +1,638 @@
+package service
+
+import (
+	"fmt"
+
+	"tuyul/backend/internal/service/market"
+	"tuyul/backend/internal/util"
+	"tuyul/backend/pkg/logger"
+)
+
+// marketMakerRules takes the quoting decisions of a Market Maker bot from its
+// instance state and an order book. It has no side effects, so live bots and
+// backtests quote the same way; callers place, cancel and account for orders.
+type marketMakerRules struct {
+	tickSize float64 // Price increment of the bot's pair
+	log      *logger.Logger
+}
+
+// quoteSkipped reports whether the bot stays out of the market on this book:
+// spread below the minimum gap, or a volatile market while holding a losing inventory
+func (r marketMakerRules) quoteSkipped(inst *BotInstance, ticker market.OrderBookTicker, volatility float64) bool {
+	spreadPercent := (ticker.BestAsk - ticker.BestBid) / ticker.BestBid * 100
+
+	// 1. Check minimum gap
+	if spreadPercent < inst.Config.MinGapPercent {
+		r.log.Debugf("Bot %d: Spread too tight (%.4f%% < %.4f%%), skipping", inst.Config.ID, spreadPercent, inst.Config.MinGapPercent)
+		return true // Spread too tight
+	}
+
+	r.log.Debugf("Bot %d: Gap OK (%.4f%% >= %.4f%%), processing orders", inst.Config.ID, spreadPercent, inst.Config.MinGapPercent)
+
+	// 2. Check volatility - only skip SELL if volatile AND we're at a loss
+	if volatility > 2.0 {
+		// Determine if we would be selling (use virtual balance)
+		coinBalance := inst.Config.Balances[inst.BaseCurrency]
+		if coinBalance > 0 {
+			// We have coins, so we would be selling
+			// Check if we're at profit or loss
+			if inst.LastBuyPrice > 0 {
+				// Compare current ask price (what we'd sell at) vs buy price
+				currentSellPrice := ticker.BestAsk
+				profitPercent := ((currentSellPrice - inst.LastBuyPrice) / inst.LastBuyPrice) * 100
+
+				if profitPercent < 0 {
+					// We're at a loss - skip selling during high volatility
+					r.log.Debugf("Bot %d: Too volatile (%.2f%%) and at loss (%.2f%%), skipping SELL",
+						inst.Config.ID, volatility, profitPercent)
+					return true
+				} else {
+					// We're at profit - volatility is fine, proceed with selling
+					r.log.Debugf("Bot %d: Volatile (%.2f%%) but at profit (%.2f%%), proceeding with SELL",
+						inst.Config.ID, volatility, profitPercent)
+				}
+			} else {
+				// No buy price tracked yet - skip selling during high volatility to be safe
+				r.log.Debugf("Bot %d: Too volatile (%.2f%%) for SELL (no buy price tracked), skipping",
+					inst.Config.ID, volatility)
+				return true
+			}
+		} else {
+			// We would be buying - volatility is OK, proceed
+			r.log.Debugf("Bot %d: Volatile (%.2f%%) but buying is OK, proceeding", inst.Config.ID, volatility)
+		}
+	}
+
+	return false
+}
+
+// nextOrder decides the order to place when the bot has none: sell the whole
+// coin balance, or buy OrderSizeIDR worth. ok is false when no order should be placed.
+func (r marketMakerRules) nextOrder(inst *BotInstance, ticker market.OrderBookTicker) (side string, price, amount float64, ok bool) {
+	// Validate and normalize balances
+	r.validateAndNormalizeBalances(inst)
+
+	// Get balances after validation
+	idrBalance := inst.Config.Balances["idr"]
+	coinBalance := inst.Config.Balances[inst.BaseCurrency]
+
+	r.log.Debugf("Bot %d: Balance check - IDR=%.2f, %s=%.8f (BaseCurrency=%s, all balances: %+v)",
+		inst.Config.ID, idrBalance, inst.BaseCurrency, coinBalance, inst.BaseCurrency, inst.Config.Balances)
+
+	// Ensure pair info is available
+	if inst.PairInfo == nil {
+		r.log.Warnf("Bot %d: Pair info not available for %s", inst.Config.ID, inst.Config.Pair)
+		return "", 0, 0, false
+	}
+	pairInfo := inst.PairInfo
+
+	// Get volume precision (using shared utility)
+	volumePrecision := util.GetVolumePrecision(*pairInfo)
+
+	r.log.Debugf("Bot %d: Pair info - VolumePrecision=%d, MinQuoteAmount=%.0f, MinBaseAmount=%.8f",
+		inst.Config.ID, volumePrecision, pairInfo.MinQuoteAmount, pairInfo.MinBaseAmount)
+
+	// Note: Balance corruption check is now in validateAndNormalizeBalances
+
+	// Decision logic:
+	// - If we have coins: SELL ALL available coin balance (with stop-loss check)
+	// - If we have IDR: BUY using OrderSizeIDR / price
+
+	// First check if coin balance is tradeable (not dust)
+	hasTradableCoins := false
+	if coinBalance > 0 {
+		roundedCoinBalance := util.FloorToPrecision(coinBalance, volumePrecision)
+		if roundedCoinBalance > 0 && roundedCoinBalance >= pairInfo.MinBaseAmount {
+			hasTradableCoins = true
+		} else {
+			r.log.Debugf("Bot %d: Coin balance %.8f %s is dust (below minimum %.8f) - will place BUY order instead",
+				inst.Config.ID, coinBalance, inst.BaseCurrency, pairInfo.MinBaseAmount)
+		}
+	}
+
+	if hasTradableCoins {
+		// Have coins -> SELL ALL available balance
+		// Check orderbook depth before placing sell order
+		estimatedSellValueIDR := coinBalance * inst.CurrentAsk
+		if !r.checkOrderbookDepth(ticker, "sell", estimatedSellValueIDR, inst.Config.MinGapPercent) {
+			r.log.Debugf("Bot %d: Skipping SELL - insufficient orderbook depth", inst.Config.ID)
+			return "", 0, 0, false
+		}
+
+		// Calculate competitive sell price
+		sellPrice, err := r.calculateCompetitivePrice(inst, ticker, "sell")
+		if err != nil {
+			r.log.Warnf("Bot %d: Failed to calculate competitive price for SELL: %v", inst.Config.ID, err)
+			return "", 0, 0, false
+		}
+
+		// Validate profit before selling
+		shouldSkip, reason := r.validateSellProfit(inst, sellPrice)
+		if shouldSkip {
+			r.log.Debugf("Bot %d: Skipping SELL - %s", inst.Config.ID, reason)
+			return "", 0, 0, false
+		}
+
+		if inst.LastBuyPrice > 0 {
+			profitPercent := ((sellPrice - inst.LastBuyPrice) / inst.LastBuyPrice) * 100
+			r.log.Debugf("Bot %d: SELL check - buyPrice=%.2f, sellPrice=%.2f, profit=%.2f%%",
+				inst.Config.ID, inst.LastBuyPrice, sellPrice, profitPercent)
+		}
+
+		// Round coin balance for order placement
+		roundedCoinBalance := util.FloorToPrecision(coinBalance, volumePrecision)
+
+		side = "sell"
+		price = sellPrice
+
+		amount = roundedCoinBalance // Use rounded amount (already validated > 0)
+
+		// Safety check: don't sell if amount is unreasonably large (corruption protection)
+		if amount > util.MaxReasonableCoinAmount {
+			r.log.Errorf("Bot %d: Refusing to place SELL order - amount %.8f is unreasonably large", inst.Config.ID, amount)
+			return "", 0, 0, false
+		}
+
+		r.log.Debugf("Bot %d: Placing SELL order - price=%.2f amount=%.8f (all available)", inst.Config.ID, price, amount)
+	} else if idrBalance >= inst.Config.OrderSizeIDR {
+		// Have IDR -> BUY
+		// Check orderbook depth before placing buy order
+		if !r.checkOrderbookDepth(ticker, "buy", inst.Config.OrderSizeIDR, inst.Config.MinGapPercent) {
+			r.log.Debugf("Bot %d: Skipping BUY - insufficient orderbook depth or bid gap too large", inst.Config.ID)
+			return "", 0, 0, false
+		}
+
+		side = "buy"
+
+		// Calculate competitive buy price
+		buyPrice, err := r.calculateCompetitivePrice(inst, ticker, "buy")
+		if err != nil {
+			r.log.Warnf("Bot %d: Failed to calculate competitive price for BUY: %v", inst.Config.ID, err)
+			return "", 0, 0, false
+		}
+		price = buyPrice
+
+		amount = inst.Config.OrderSizeIDR / price
+
+		// Safety check: don't buy more than we can afford
+		maxAffordable := idrBalance / price
+		if amount > maxAffordable {
+			r.log.Warnf("Bot %d: Attempted to buy %.8f but can only afford %.8f, capping", inst.Config.ID, amount, maxAffordable)
+			amount = maxAffordable
+		}
+
+		r.log.Debugf("Bot %d: Placing BUY order - price=%.2f amount=%.8f (size=%.2f IDR)", inst.Config.ID, price, amount, inst.Config.OrderSizeIDR)
+	} else {
+		// Insufficient balance
+		r.log.Debugf("Bot %d: Insufficient balance - IDR=%.2f < %.2f, %s=%.8f",
+			inst.Config.ID, idrBalance, inst.Config.OrderSizeIDR, inst.BaseCurrency, coinBalance)
+		return "", 0, 0, false
+	}
+
+	// Validate order amount (using shared utility)
+	r.log.Debugf("Bot %d: Before validation - amount=%.8f, volumePrecision=%d", inst.Config.ID, amount, volumePrecision)
+
+	validation := util.ValidateOrderAmount(
+		inst.Config.ID,
+		amount,
+		price,
+		*pairInfo,
+		volumePrecision,
+		inst.BaseCurrency,
+		r.log,
+	)
+
+	if !validation.Valid {
+		r.log.Debugf("Bot %d: Order validation failed - %s", inst.Config.ID, validation.Reason)
+		return "", 0, 0, false
+	}
+
+	// Use validated amount
+	amount = validation.Amount
+	r.log.Debugf("Bot %d: After validation - amount=%.8f, orderValue=%.2f IDR", inst.Config.ID, amount, validation.OrderValue)
+
+	return side, price, amount, true
+}
+
+// repositionReason returns why the active order should be cancelled on this book, empty to keep it
+func (r marketMakerRules) repositionReason(inst *BotInstance, ticker market.OrderBookTicker) string {
+	order := inst.ActiveOrder
+	var shouldCancel bool
+	var reason string
+	var expectedPrice float64
+
+	// Calculate current spread
+	spreadPercent := (inst.CurrentAsk - inst.CurrentBid) / inst.CurrentBid * 100
+
+	if order.Side == "buy" {
+		// For BUY: If spread is below gap target, cancel immediately (even if partially filled)
+		// We can't profitably sell later if spread is too tight
+		if spreadPercent < inst.Config.MinGapPercent {
+			r.log.Debugf("Bot %d: Spread too tight (%.4f%% < %.4f%%), cancelling BUY order immediately",
+				inst.Config.ID, spreadPercent, inst.Config.MinGapPercent)
+			shouldCancel = true
+			reason = fmt.Sprintf("Spread too tight (%.4f%% < %.4f%%) - cancelling to avoid unprofitable cycle",
+				spreadPercent, inst.Config.MinGapPercent)
+			// Proceed to cancellation logic below
+		} else {
+			// Spread is OK - proceed with normal BUY repositioning logic
+			// Calculate expected competitive price
+			calculatedPrice, err := r.calculateCompetitivePrice(inst, ticker, "buy")
+			if err != nil {
+				r.log.Warnf("Bot %d: Failed to calculate competitive price for BUY in checkReposition: %v", inst.Config.ID, err)
+				return ""
+			}
+			expectedPrice = calculatedPrice
+
+			// Allow small tolerance for price matching (handles floating point precision)
+			priceDiff := order.Price - expectedPrice
+			if priceDiff < 0 {
+				priceDiff = -priceDiff
+			}
+
+			if priceDiff > 0.01 { // 0.01 IDR tolerance
+				// Price doesn't match - cancel to reposition
+				shouldCancel = true
+				reason = fmt.Sprintf("Our bid (%.2f) != expected price (%.2f) - should match market",
+					order.Price, expectedPrice)
+			} else {
+				// Price matches - but check if depth is still sufficient
+				// If depth became insufficient, cancel the order (risky to keep in thin market)
+				if !r.checkOrderbookDepth(ticker, "buy", inst.Config.OrderSizeIDR, inst.Config.MinGapPercent) {
+					shouldCancel = true
+					reason = fmt.Sprintf("Our bid (%.2f) matches expected price, but orderbook depth insufficient - cancelling risky order",
+						order.Price)
+				}
+				// If price matches AND depth is sufficient, keep the order (shouldCancel stays false)
+			}
+		}
+	} else {
+		// For SELL: If spread is below gap target, check profit before deciding
+		if spreadPercent < inst.Config.MinGapPercent {
+			// Check profit before deciding
+			shouldSkip, skipReason := r.validateSellProfit(inst, order.Price)
+			if shouldSkip {
+				// Profit doesn't meet requirements - cancel
+				r.log.Debugf("Bot %d: Spread too tight (%.4f%% < %.4f%%) and %s, cancelling SELL order",
+					inst.Config.ID, spreadPercent, inst.Config.MinGapPercent, skipReason)
+				shouldCancel = true
+				reason = fmt.Sprintf("Spread too tight (%.4f%% < %.4f%%) and %s - cancelling",
+					spreadPercent, inst.Config.MinGapPercent, skipReason)
+			} else if inst.LastBuyPrice > 0 {
+				// Profit meets requirements - keep the order to lock in profit
+				profitPercent := ((order.Price - inst.LastBuyPrice) / inst.LastBuyPrice) * 100
+				r.log.Debugf("Bot %d: Spread too tight (%.4f%% < %.4f%%) but profit (%.2f%%) > MinGap (%.4f%%), keeping SELL order",
+					inst.Config.ID, spreadPercent, inst.Config.MinGapPercent, profitPercent, inst.Config.MinGapPercent)
+				return "" // Don't cancel - we want to lock in profit
+			} else {
+				// No buy price tracked - cancel to be safe
+				r.log.Debugf("Bot %d: Spread too tight (%.4f%% < %.4f%%) and no buy price tracked, cancelling SELL order",
+					inst.Config.ID, spreadPercent, inst.Config.MinGapPercent)
+				shouldCancel = true
+				reason = fmt.Sprintf("Spread too tight (%.4f%% < %.4f%%) and no buy price tracked - cancelling",
+					spreadPercent, inst.Config.MinGapPercent)
+			}
+		} else {
+			// Spread is OK - proceed with normal SELL repositioning logic
+			// Calculate expected competitive price
+			calculatedPrice, err := r.calculateCompetitivePrice(inst, ticker, "sell")
+			if err != nil {
+				r.log.Warnf("Bot %d: Failed to calculate competitive price for SELL in checkReposition: %v", inst.Config.ID, err)
+				return ""
+			}
+			expectedPrice = calculatedPrice
+
+			// Allow small tolerance for price matching (handles floating point precision)
+			priceDiff := order.Price - expectedPrice
+			if priceDiff < 0 {
+				priceDiff = -priceDiff
+			}
+
+			if priceDiff > 0.01 {
+				// Price doesn't match - cancel to reposition
+				shouldCancel = true
+				reason = fmt.Sprintf("Our ask (%.2f) != expected price (%.2f) - should match market",
+					order.Price, expectedPrice)
+			}
+			// If price matches, keep the order (no depth check - we need liquidity fast for selling)
+		}
+	}
+
+	if !shouldCancel {
+		r.log.Debugf("Bot %d: Order still at competitive price - %s order price=%.2f matches expected price=%.2f",
+			inst.Config.ID, order.Side, order.Price, expectedPrice)
+		return ""
+	}
+
+	return reason
+}
+
+// validateAndNormalizeBalances ensures balances are initialized and valid
+// Uses shared balance validation utility
+func (r marketMakerRules) validateAndNormalizeBalances(inst *BotInstance) {
+	requiredCurrencies := []string{"idr", inst.BaseCurrency}
+	inst.Config.Balances = util.ValidateAndNormalizeBalances(
+		inst.Config.Balances,
+		requiredCurrencies,
+		inst.Config.InitialBalanceIDR,
+		r.log,
+	)
+}
+
+// calculateCompetitivePrice calculates the competitive price for placing or checking an order
+// Returns the price that should be used based on whether we're the only buyer/seller
+func (r marketMakerRules) calculateCompetitivePrice(
+	inst *BotInstance,
+	ticker market.OrderBookTicker,
+	side string, // "buy" or "sell"
+) (float64, error) {
+	if inst.PairInfo == nil {
+		return 0, fmt.Errorf("pair info not available for bot %d", inst.Config.ID)
+	}
+
+	tickSize := r.tickSize
+	var price float64
+	var noCompetition bool
+
+	if side == "buy" {
+		noCompetition = r.isOnlyBuyer(inst, ticker)
+		if noCompetition {
+			price = inst.CurrentBid // Match best bid if no other buyers
+			r.log.Debugf("Bot %d: calculateCompetitivePrice BUY - no other buyers → price = best bid (%.2f)",
+				inst.Config.ID, price)
+		} else {
+			price = inst.CurrentBid + tickSize // Add tick to outbid other buyers
+			r.log.Debugf("Bot %d: calculateCompetitivePrice BUY - other buyers competing → price = best bid (%.2f) + tick (%.2f) = %.2f",
+				inst.Config.ID, inst.CurrentBid, tickSize, price)
+		}
+	} else if side == "sell" {
+		noCompetition = r.isOnlySeller(inst, ticker)
+		if noCompetition {
+			price = inst.CurrentAsk // Match best ask if no other sellers
+			r.log.Debugf("Bot %d: calculateCompetitivePrice SELL - no other sellers → price = best ask (%.2f)",
+				inst.Config.ID, price)
+		} else {
+			price = inst.CurrentAsk - tickSize // Subtract tick to undercut other sellers
+			r.log.Debugf("Bot %d: calculateCompetitivePrice SELL - other sellers competing → price = best ask (%.2f) - tick (%.2f) = %.2f",
+				inst.Config.ID, inst.CurrentAsk, tickSize, price)
+		}
+	} else {
+		return 0, fmt.Errorf("invalid side: %s (must be 'buy' or 'sell')", side)
+	}
+
+	// Round price to pair's price precision (critical for Indodax API)
+	if tickSize >= 1.0 {
+		// For IDR pairs (whole number prices), round to nearest increment then cast to int
+		price = util.RoundToNearestIncrement(price, tickSize)
+		price = float64(int64(price)) // Force to exact integer for IDR
+		r.log.Debugf("Bot %d: calculateCompetitivePrice %s - after rounding to increment %.0f: %.0f",
+			inst.Config.ID, side, tickSize, price)
+	} else {
+		price = util.RoundToPrecision(price, inst.PairInfo.PricePrecision)
+		r.log.Debugf("Bot %d: calculateCompetitivePrice %s - after rounding to precision %d: %.2f",
+			inst.Config.ID, side, inst.PairInfo.PricePrecision, price)
+	}
+
+	return price, nil
+}
+
+// validateSellProfit checks if a sell order should be placed based on profit requirements
+// Returns (shouldSkip, reason) - if shouldSkip is true, the order should not be placed
+func (r marketMakerRules) validateSellProfit(inst *BotInstance, sellPrice float64) (bool, string) {
+	if inst.LastBuyPrice <= 0 {
+		// No buy price tracked - allow selling (let user decide)
+		return false, ""
+	}
+
+	// Calculate current profit/loss percentage
+	profitPercent := ((sellPrice - inst.LastBuyPrice) / inst.LastBuyPrice) * 100
+
+	// If loss exceeds 5%, skip selling to avoid realizing large losses
+	if profitPercent < -5.0 {
+		return true, fmt.Sprintf("at loss (%.2f%%) and price may recover, holding", profitPercent)
+	}
+
+	// Also check if profit meets minimum gap requirement
+	if profitPercent <= inst.Config.MinGapPercent {
+		return true, fmt.Sprintf("profit (%.2f%%) <= MinGap (%.4f%%), not profitable enough",
+			profitPercent, inst.Config.MinGapPercent)
+	}
+
+	return false, ""
+}
+
+// isOnlyBuyer checks if there are other BUYERS competing when we want to place a BUY order
+// This is used when placing BUY orders to decide if we need to add tick to compete with other buyers
+// Returns TRUE if: there are NO other buyers (we can just match current best bid)
+// Returns FALSE if: there are other buyers competing (we should add tick to outbid them)
+func (r marketMakerRules) isOnlyBuyer(inst *BotInstance, ticker market.OrderBookTicker) bool {
+	// Check the BID side (buyers) to see if there's competition
+	if len(ticker.Bids) == 0 {
+		r.log.Debugf("Bot %d: No buyers in orderbook → no competition, can place at current best bid", inst.Config.ID)
+		return true
+	}
+
+	// If we don't have an active buy order, check if there are multiple buyers
+	if inst.ActiveOrder == nil || inst.ActiveOrder.Side != "buy" {
+		if len(ticker.Bids) >= 2 {
+			bestBidPrice := ticker.Bids[0].Price
+			secondBidPrice := ticker.Bids[1].Price
+			// If there are 2+ different bid levels, there's buyer competition
+			if bestBidPrice > secondBidPrice {
+				r.log.Debugf("Bot %d: Multiple buyers competing (best bid=%.2f, 2nd bid=%.2f) → ADD TICK to outbid them",
+					inst.Config.ID, bestBidPrice, secondBidPrice)
+				return false // Multiple buyers, need to add tick
+			}
+		}
+		// Only one buyer level
+		r.log.Debugf("Bot %d: Only one buyer level → no competition", inst.Config.ID)
+		return true
+	}
+
+	// We have an active buy order - check if it's the only one at best bid
+	bestBid := ticker.Bids[0]
+	bestBidPrice := bestBid.Price
+	bestBidVolumeIDR := bestBid.IDRVolume
+
+	// Check if our buy order price matches best bid
+	priceDiff := inst.ActiveOrder.Price - bestBidPrice
+	if priceDiff < 0 {
+		priceDiff = -priceDiff
+	}
+
+	if priceDiff > 0.01 {
+		// Our buy is not at best bid, there are other buyers ahead
+		r.log.Debugf("Bot %d: Our buy (%.2f) NOT at best bid (%.2f) → other buyers ahead → ADD TICK",
+			inst.Config.ID, inst.ActiveOrder.Price, bestBidPrice)
+		return false
+	}
+
+	// Our buy is at best bid - check if it's the only one (volume match)
+	ourOrderValueIDR := inst.ActiveOrder.Amount * inst.ActiveOrder.Price
+	volumeDiff := ourOrderValueIDR - bestBidVolumeIDR
+	if volumeDiff < 0 {
+		volumeDiff = -volumeDiff
+	}
+
+	tolerance := ourOrderValueIDR * 0.01
+	r.log.Debugf("Bot %d: Our buy at best bid - volume check: our=%.2f IDR, bestBid=%.2f IDR, diff=%.2f",
+		inst.Config.ID, ourOrderValueIDR, bestBidVolumeIDR, volumeDiff)
+
+	if volumeDiff <= tolerance {
+		r.log.Debugf("Bot %d: ✓ Our buy is the ONLY buyer at best bid → no need to add tick",
+			inst.Config.ID)
+		return true // We're the only buyer, no need to be aggressive
+	}
+
+	r.log.Debugf("Bot %d: Other buyers at best bid (volume mismatch) → ADD TICK to outbid them",
+		inst.Config.ID)
+	return false // Other buyers at same price
+}
+
+// isOnlySeller checks if there are other SELLERS competing when we want to place a SELL order
+// This is used when placing SELL orders to decide if we need to subtract tick to compete with other sellers
+// Returns TRUE if: there are NO other sellers (we can just match current best ask)
+// Returns FALSE if: there are other sellers competing (we should subtract tick to undercut them)
+func (r marketMakerRules) isOnlySeller(inst *BotInstance, ticker market.OrderBookTicker) bool {
+	// Check the ASK side (sellers) to see if there's competition
+	if len(ticker.Asks) == 0 {
+		r.log.Debugf("Bot %d: No sellers in orderbook → no competition, can place at current best ask", inst.Config.ID)
+		return true
+	}
+
+	// If we don't have an active sell order, check if there are multiple sellers
+	if inst.ActiveOrder == nil || inst.ActiveOrder.Side != "sell" {
+		if len(ticker.Asks) >= 2 {
+			bestAskPrice := ticker.Asks[0].Price
+			secondAskPrice := ticker.Asks[1].Price
+			// If there are 2+ different ask levels, there's seller competition
+			if secondAskPrice > bestAskPrice {
+				r.log.Debugf("Bot %d: Multiple sellers competing (best ask=%.2f, 2nd ask=%.2f) → SUBTRACT TICK to undercut them",
+					inst.Config.ID, bestAskPrice, secondAskPrice)
+				return false // Multiple sellers, need to subtract tick
+			}
+		}
+		// Only one seller level
+		r.log.Debugf("Bot %d: Only one seller level → no competition", inst.Config.ID)
+		return true
+	}
+
+	// We have an active sell order - check if it's the only one at best ask
+	bestAsk := ticker.Asks[0]
+	bestAskPrice := bestAsk.Price
+	bestAskVolume := bestAsk.BaseVolume // Coin volume for asks
+
+	// Check if our sell order price matches best ask
+	priceDiff := inst.ActiveOrder.Price - bestAskPrice
+	if priceDiff < 0 {
+		priceDiff = -priceDiff
+	}
+
+	if priceDiff > 0.01 {
+		// Our sell is not at best ask, there are other sellers ahead
+		r.log.Debugf("Bot %d: Our sell (%.2f) NOT at best ask (%.2f) → other sellers ahead → SUBTRACT TICK",
+			inst.Config.ID, inst.ActiveOrder.Price, bestAskPrice)
+		return false
+	}
+
+	// Our sell is at best ask - check if it's the only one (volume match)
+	ourOrderVolume := inst.ActiveOrder.Amount // Coin amount
+
+	// If bestAskVolume is 0 or very small, it means our order is the only one at this price
+	if bestAskVolume < util.TinyBalanceThreshold {
+		r.log.Debugf("Bot %d: ✓ Best ask volume is 0 → our sell is the ONLY seller at best ask → no need to subtract tick",
+			inst.Config.ID)
+		return true
+	}
+
+	volumeDiff := ourOrderVolume - bestAskVolume
+	if volumeDiff < 0 {
+		volumeDiff = -volumeDiff
+	}
+
+	tolerance := ourOrderVolume * 0.01
+	r.log.Debugf("Bot %d: Our sell at best ask - volume check: our=%.8f coins, bestAsk=%.8f coins, diff=%.8f",
+		inst.Config.ID, ourOrderVolume, bestAskVolume, volumeDiff)
+
+	if volumeDiff <= tolerance {
+		r.log.Debugf("Bot %d: ✓ Our sell is the ONLY seller at best ask → no need to subtract tick",
+			inst.Config.ID)
+		return true // We're the only seller, no need to be aggressive
+	}
+
+	r.log.Debugf("Bot %d: Other sellers at best ask (volume mismatch) → SUBTRACT TICK to undercut them",
+		inst.Config.ID)
+	return false // Other sellers at same price
+}
+
+// checkOrderbookDepth checks if there's sufficient depth in the orderbook
+// Returns true if depth is sufficient, false if market is too thin
+func (r marketMakerRules) checkOrderbookDepth(ticker market.OrderBookTicker, side string, orderSizeIDR float64, minGapPercent float64) bool {
+	const MIN_DEPTH_LEVELS = 3       // Need at least 3 price levels
+	const MIN_DEPTH_MULTIPLIER = 2.0 // Depth should be at least 2x our order size
+	const MAX_BID_GAP_PERCENT = 0.5  // Max 0.5% gap between bid[0] and bid[1]
+
+	if len(ticker.Bids) < MIN_DEPTH_LEVELS || len(ticker.Asks) < MIN_DEPTH_LEVELS {
+		r.log.Debugf("Orderbook depth too thin: bids=%d asks=%d (need at least %d levels)",
+			len(ticker.Bids), len(ticker.Asks), MIN_DEPTH_LEVELS)
+		return false
+	}
+
+	if side == "buy" {
+		// Check if gap between bid[0] and bid[1] is too large (thin market)
+		if len(ticker.Bids) >= 2 {
+			bidGap := ticker.Bids[0].Price - ticker.Bids[1].Price
+			bidGapPercent := (bidGap / ticker.Bids[0].Price) * 100
+			if bidGapPercent > MAX_BID_GAP_PERCENT {
+				r.log.Debugf("Bid gap too large: bid[0]=%.2f, bid[1]=%.2f, gap=%.2f (%.4f%%) > threshold (%.2f%%)",
+					ticker.Bids[0].Price, ticker.Bids[1].Price, bidGap, bidGapPercent, MAX_BID_GAP_PERCENT)
+				return false
+			}
+		}
+		// For buy orders, check depth below our bid (we'll be placing at best bid)
+		// Sum up IDR volume at the first few bid levels
+		totalDepthIDR := 0.0
+		levelsToCheck := MIN_DEPTH_LEVELS
+		if levelsToCheck > len(ticker.Bids) {
+			levelsToCheck = len(ticker.Bids)
+		}
+
+		for i := 0; i < levelsToCheck; i++ {
+			totalDepthIDR += ticker.Bids[i].IDRVolume
+		}
+
+		// Check if depth is sufficient (at least 2x our order size)
+		minRequiredDepth := orderSizeIDR * MIN_DEPTH_MULTIPLIER
+		if totalDepthIDR < minRequiredDepth {
+			r.log.Debugf("Insufficient buy depth: total=%.2f IDR, required=%.2f IDR (order size=%.2f IDR)",
+				totalDepthIDR, minRequiredDepth, orderSizeIDR)
+			return false
+		}
+
+		r.log.Debugf("Buy depth OK: total=%.2f IDR across %d levels (order size=%.2f IDR)",
+			totalDepthIDR, levelsToCheck, orderSizeIDR)
+		return true
+	} else {
+		// For sell orders, check depth above our ask (we'll be placing at best ask)
+		totalDepthIDR := 0.0
+		levelsToCheck := MIN_DEPTH_LEVELS
+		if levelsToCheck > len(ticker.Asks) {
+			levelsToCheck = len(ticker.Asks)
+		}
+
+		for i := 0; i < levelsToCheck; i++ {
+			totalDepthIDR += ticker.Asks[i].IDRVolume
+		}
+
+		minRequiredDepth := orderSizeIDR * MIN_DEPTH_MULTIPLIER
+		if totalDepthIDR < minRequiredDepth {
+			r.log.Debugf("Insufficient sell depth: total=%.2f IDR, required=%.2f IDR (order size=%.2f IDR)",
+				totalDepthIDR, minRequiredDepth, orderSizeIDR)
+			return false
+		}
+
+		r.log.Debugf("Sell depth OK: total=%.2f IDR across %d levels (order size=%.2f IDR)",
+			totalDepthIDR, levelsToCheck, orderSizeIDR)
+		return true
+	}
+}
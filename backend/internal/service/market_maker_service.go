@@ -5,16 +5,15 @@ import (
 	"encoding/json"
 	"fmt"
 	"sort"
-	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"tuyul/backend/internal/exchange"
 	"tuyul/backend/internal/model"
 	"tuyul/backend/internal/repository"
 	"tuyul/backend/internal/service/market"
 	"tuyul/backend/internal/util"
-	"tuyul/backend/pkg/indodax"
 	"tuyul/backend/pkg/logger"
 )
 
@@ -26,7 +25,9 @@ type MarketMakerService struct {
 	subManager          *market.SubscriptionManager
 	orderMonitor        *OrderMonitor
 	notificationService *NotificationService
-	indodaxClient       *indodax.Client
+	deadmanService      *DeadmanService
+	paperExchange       *PaperExchange
+	exchange            exchange.Exchange
 	log                 *logger.Logger
 
 	// Runtime bots
@@ -59,12 +60,13 @@ type BotInstance struct {
 	ActiveOrder     *model.Order
 	CurrentBid      float64
 	CurrentAsk      float64
-	BaseCurrency    string        // e.g. "btc" in "btcidr"
-	LastBuyPrice    float64       // Track last buy price for profit calculation
-	TotalCoinBought float64       // Track total coins bought (for average price calculation)
-	TotalCostIDR    float64       // Track total cost in IDR (for average price calculation)
-	LastOrderTime   time.Time     // Track last order placement/cancellation for rate limiting
-	PairInfo        *indodax.Pair // Cached pair info to avoid repeated lookups
+	BaseCurrency    string         // e.g. "btc" in "btcidr"
+	LastBuyPrice    float64        // Track last buy price for profit calculation
+	TotalCoinBought float64        // Track total coins bought (for average price calculation)
+	TotalCostIDR    float64        // Track total cost in IDR (for average price calculation)
+	LastOrderTime   time.Time      // Track last order placement/cancellation for rate limiting
+	PausedUntil     time.Time      // Quoting paused until then after the exchange was unavailable
+	PairInfo        *exchange.Pair // Cached pair info to avoid repeated lookups
 
 	mu sync.Mutex // Protects ActiveOrder and order operations to prevent race conditions
 }
@@ -77,7 +79,9 @@ func NewMarketMakerService(
 	subManager *market.SubscriptionManager,
 	orderMonitor *OrderMonitor,
 	notificationService *NotificationService,
-	indodaxClient *indodax.Client,
+	deadmanService *DeadmanService,
+	paperExchange *PaperExchange,
+	ex exchange.Exchange,
 ) *MarketMakerService {
 	s := &MarketMakerService{
 		botRepo:             botRepo,
@@ -87,7 +91,9 @@ func NewMarketMakerService(
 		subManager:          subManager,
 		orderMonitor:        orderMonitor,
 		notificationService: notificationService,
-		indodaxClient:       indodaxClient,
+		deadmanService:      deadmanService,
+		paperExchange:       paperExchange,
+		exchange:            ex,
 		log:                 logger.GetLogger(),
 		instances:           make(map[int64]*BotInstance),
 		cleanupStopChan:     make(chan struct{}),
@@ -125,20 +131,12 @@ func (s *MarketMakerService) CreateBot(ctx context.Context, userID string, req *
 	// 2. Separate logic for paper vs live
 	var apiKeyID *int64
 	if !req.IsPaperTrading {
-		// Verify user has a valid API key (single API key per user model)
-		_, err := s.apiKeyService.GetDecrypted(ctx, userID)
+		// Bind the requested API key, or the user's default key if none is given
+		key, err := s.apiKeyService.ResolveDecrypted(ctx, userID, req.APIKeyID)
 		if err != nil {
 			return nil, util.NewAppError(400, util.ErrCodeAPIKeyInvalid, "Valid API key is required for live trading. Please add your API key in Settings.")
 		}
-		// For backward compatibility, set apiKeyID to 1 if not provided
-		// In the future, we can remove apiKeyID entirely since we have one key per user
-		if req.APIKeyID != nil {
-			apiKeyID = req.APIKeyID
-		} else {
-			// Default to 1 for single API key per user
-			apiKeyIDValue := int64(1)
-			apiKeyID = &apiKeyIDValue
-		}
+		apiKeyID = &key.ID
 	}
 
 	// 3. Get pair info to determine base currency
@@ -160,6 +158,7 @@ func (s *MarketMakerService) CreateBot(ctx context.Context, userID string, req *
 		MinGapPercent:              req.MinGapPercent,
 		RepositionThresholdPercent: req.RepositionThresholdPercent,
 		MaxLossIDR:                 req.MaxLossIDR,
+		Deadman:                    req.Deadman,
 		Status:                     model.BotStatusStopped,
 		CreatedAt:                  time.Now(),
 		UpdatedAt:                  time.Now(),
@@ -248,8 +247,18 @@ func (s *MarketMakerService) UpdateBot(ctx context.Context, userID string, botID
 	bot.RepositionThresholdPercent = req.RepositionThresholdPercent
 	bot.MaxLossIDR = req.MaxLossIDR
 	bot.IsPaperTrading = req.IsPaperTrading
-	bot.APIKeyID = req.APIKeyID
+	bot.APIKeyID = nil
+	if !req.IsPaperTrading {
+		key, err := s.apiKeyService.ResolveDecrypted(ctx, userID, req.APIKeyID)
+		if err != nil {
+			return nil, util.NewAppError(400, util.ErrCodeAPIKeyInvalid, "Valid API key is required for live trading. Please add your API key in Settings.")
+		}
+		bot.APIKeyID = &key.ID
+	}
 	bot.InitialBalanceIDR = req.InitialBalanceIDR
+	if req.Deadman != nil {
+		bot.Deadman = req.Deadman
+	}
 
 	if err := s.botRepo.Update(ctx, bot, ""); err != nil {
 		return nil, err
@@ -319,21 +328,23 @@ func (s *MarketMakerService) StartBot(ctx context.Context, userID string, botID
 
 	// 2. Setup Trade Client
 	if bot.IsPaperTrading {
-		inst.TradeClient = NewPaperTradeClient(bot.Balances, func(order *model.Order) {
-			// For paper trading, the full amount is always filled at once
-			s.handleFilled(inst, order, order.Amount)
+		// Paper fills arrive like live order updates, the fee is taken from the IDR balance
+		inst.TradeClient = NewPaperTradeClient(s.paperExchange, userID, bot.Balances, func(update *exchange.OrderUpdate) {
+			s.chargePaperFee(inst, update.Fee)
+			s.handleLiveOrderUpdate(userID, update)
 		})
 	} else {
-		// Get API keys
-		key, err := s.apiKeyService.GetDecrypted(ctx, userID)
+		// Get the API key bound to the bot (bots without a binding use the default key)
+		key, err := s.apiKeyService.ResolveDecrypted(ctx, userID, bot.APIKeyID)
 		if err != nil {
 			return util.ErrBadRequest("Valid API key not found")
 		}
-		inst.TradeClient = NewLiveTradeClient(s.indodaxClient, key.Key, key.Secret)
+		bot.APIKeyID = &key.ID
+		inst.TradeClient = s.exchange.NewTrader(key.Key, key.Secret)
 		// Verify subscription exists - REQUIRED for live trading
 		// Subscription should be established on API boot or when API key is created
-		if !s.orderMonitor.IsSubscribed(userID) {
-			s.log.Errorf("User %s is not subscribed to order updates. Subscription is required for live trading.", userID)
+		if !s.orderMonitor.IsSubscribed(key.ID) {
+			s.log.Errorf("API key %d of user %s is not subscribed to order updates. Subscription is required for live trading.", key.ID, userID)
 			return fmt.Errorf("cannot start live bot: user is not subscribed to order updates. Please ensure your API key is configured correctly")
 		}
 	}
@@ -453,25 +464,25 @@ func (s *MarketMakerService) StartBot(ctx context.Context, userID string, botID
 			inst.ActiveOrder = nil
 		} else {
 			// Check order status from Indodax
-			indodaxStatus := strings.ToLower(orderInfo.Status)
-			s.log.Debugf("Bot %d: Order %s status on Indodax: %s (RemainCoin=%s, OrderCoin=%s)",
-				botID, inst.ActiveOrder.OrderID, indodaxStatus, orderInfo.RemainCoin, orderInfo.OrderCoin)
+			orderStatus := orderInfo.Status
+			s.log.Debugf("Bot %d: Order %s status on Indodax: %s (Remaining=%.8f, Amount=%.8f)",
+				botID, inst.ActiveOrder.OrderID, orderStatus, orderInfo.Remaining, orderInfo.Amount)
 
-			if indodaxStatus == "filled" || indodaxStatus == "done" {
+			if orderStatus == exchange.OrderStatusFilled {
 				// Order was filled - process the fill
 				s.log.Debugf("Bot %d: Order %s was filled while bot was stopped, processing fill...", botID, inst.ActiveOrder.OrderID)
 				// Use the order amount since we don't have executed qty from GetOrder
 				s.handleFilled(inst, inst.ActiveOrder, inst.ActiveOrder.Amount)
-			} else if indodaxStatus == "cancelled" {
+			} else if orderStatus == exchange.OrderStatusCancelled {
 				// Order was cancelled
 				s.log.Debugf("Bot %d: Order %s was cancelled, clearing active order", botID, inst.ActiveOrder.OrderID)
 				s.orderRepo.UpdateStatus(ctx, inst.ActiveOrder.ID, "cancelled")
 				s.log.Debugf("Bot %d: Cancelled order kept in database for history", botID)
 				inst.ActiveOrder = nil
-			} else if indodaxStatus == "open" {
-				// Order is still open - parse remaining amount to check for partial fills
-				remainCoin, _ := strconv.ParseFloat(orderInfo.RemainCoin, 64)
-				orderCoin, _ := strconv.ParseFloat(orderInfo.OrderCoin, 64)
+			} else if orderStatus == exchange.OrderStatusOpen || orderStatus == exchange.OrderStatusPartiallyFilled {
+				// Order is still open - check remaining amount for partial fills
+				remainCoin := orderInfo.Remaining
+				orderCoin := orderInfo.Amount
 
 				if orderCoin > 0 && remainCoin < orderCoin {
 					// Partial fill detected
@@ -487,16 +498,25 @@ func (s *MarketMakerService) StartBot(ctx context.Context, userID string, botID
 				s.log.Debugf("Bot %d: Successfully verified and restored active order %s", botID, inst.ActiveOrder.OrderID)
 			} else {
 				// Unknown status - clear to be safe
-				s.log.Warnf("Bot %d: Order %s has unknown status '%s', clearing", botID, inst.ActiveOrder.OrderID, indodaxStatus)
+				s.log.Warnf("Bot %d: Order %s has unknown status '%s', clearing", botID, inst.ActiveOrder.OrderID, orderStatus)
 				inst.ActiveOrder = nil
 			}
 		}
 	} else if bot.IsPaperTrading && inst.ActiveOrder != nil {
-		// For paper trading, we can't verify with Indodax, so we trust the database
-		// But check if the order is stale (created more than 1 hour ago)
-		if inst.ActiveOrder.CreatedAt.Before(time.Now().Add(-1 * time.Hour)) {
-			s.log.Warnf("Bot %d: Paper trading order %s is stale (created %v ago), clearing",
-				botID, inst.ActiveOrder.OrderID, time.Since(inst.ActiveOrder.CreatedAt))
+		// Paper orders only live in memory, an order missing from the paper exchange
+		// did not survive a restart: release its unfilled funds and let the bot quote again
+		if _, err := inst.TradeClient.GetOrder(ctx, inst.ActiveOrder.Pair, inst.ActiveOrder.OrderID); err != nil {
+			s.log.Warnf("Bot %d: Paper trading order %s is gone from the paper exchange, clearing", botID, inst.ActiveOrder.OrderID)
+			unfilled := inst.ActiveOrder.Amount - inst.ActiveOrder.FilledAmount
+			if inst.ActiveOrder.Side == "sell" {
+				bot.Balances[inst.BaseCurrency] += unfilled
+			} else {
+				bot.Balances["idr"] += unfilled * inst.ActiveOrder.Price
+			}
+			if err := s.botRepo.UpdateBalance(ctx, botID, bot.Balances); err != nil {
+				s.log.Warnf("Bot %d: Failed to save balance after releasing paper order: %v", botID, err)
+			}
+			s.orderRepo.UpdateStatus(ctx, inst.ActiveOrder.ID, "cancelled")
 			inst.ActiveOrder = nil
 		} else {
 			s.log.Debugf("Bot %d: Restored paper trading order %s", botID, inst.ActiveOrder.OrderID)
@@ -555,7 +575,14 @@ func (s *MarketMakerService) StartBot(ctx context.Context, userID string, botID
 	s.mu.Unlock()
 	s.log.Debugf("Bot %d: Instance stored in map (total instances: %d)", botID, len(s.instances))
 
-	// 8. Start event loop (goroutine will continue running even if StartBot returns)
+	// 8. Arm deadman switch (live only) so resting orders are cancelled if we go down
+	if enabled, countdown := bot.DeadmanSettings(); enabled {
+		if err := s.deadmanService.Arm(ctx, userID, bot.BoundAPIKeyID(), bot.Pair, botID, countdown); err != nil {
+			s.log.Warnf("Bot %d: Failed to arm deadman switch for %s: %v", botID, bot.Pair, err)
+		}
+	}
+
+	// 9. Start event loop (goroutine will continue running even if StartBot returns)
 	go s.runBot(inst)
 
 	s.log.Infof("Bot %d started successfully for pair %s", botID, bot.Pair)
@@ -738,11 +765,21 @@ func (s *MarketMakerService) StopBot(ctx context.Context, userID string, botID i
 		}
 	}()
 
-	// 6. Cancel orders asynchronously in background (don't block the response)
+	// 6. Cancel orders asynchronously in background (don't block the response).
+	// The deadman switch stays armed until they are, and is left to expire if a cancel fails.
 	go func() {
 		cancelCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 		defer cancel()
 
+		failed := 0
+		defer func() {
+			if failed == 0 {
+				s.deadmanService.Disarm(userID, bot.Pair, botID)
+			} else {
+				s.deadmanService.Expire(userID, bot.Pair, botID)
+			}
+		}()
+
 		// Cancel any active/open orders and restore locked funds
 		if targetInst.ActiveOrder != nil && targetInst.ActiveOrder.Status == "open" {
 			s.log.Debugf("Bot %d: Cancelling active order %s (ID: %s)", botID, targetInst.ActiveOrder.Side, targetInst.ActiveOrder.OrderID)
@@ -758,6 +795,7 @@ func (s *MarketMakerService) StopBot(ctx context.Context, userID string, botID i
 					s.log.Debugf("Bot %d: Active order %s already filled/cancelled (not found)", botID, targetInst.ActiveOrder.OrderID)
 				} else {
 					s.log.Warnf("Bot %d: Failed to cancel active order %s: %v", botID, targetInst.ActiveOrder.OrderID, err)
+					failed++
 				}
 			} else {
 				// Order successfully cancelled - restore locked funds
@@ -790,7 +828,10 @@ func (s *MarketMakerService) StopBot(ctx context.Context, userID string, botID i
 
 		// Also check for any other open orders for this bot
 		openOrders, err := s.orderRepo.ListByParentAndUser(cancelCtx, userID, "bot", botID, 0)
-		if err == nil {
+		if err != nil {
+			s.log.Warnf("Bot %d: Failed to list open orders on stop: %v", botID, err)
+			failed++
+		} else {
 			for _, order := range openOrders {
 				if order.Status == "open" {
 					s.log.Debugf("Bot %d: Cancelling open order %d (ID: %s)", botID, order.ID, order.OrderID)
@@ -800,6 +841,7 @@ func (s *MarketMakerService) StopBot(ctx context.Context, userID string, botID i
 							s.log.Debugf("Bot %d: Order %d (ID: %s) already filled/cancelled (not found)", botID, order.ID, order.OrderID)
 						} else {
 							s.log.Warnf("Bot %d: Failed to cancel order %d: %v", botID, order.ID, err)
+							failed++
 						}
 					} else {
 						// Order successfully cancelled - restore locked funds
@@ -888,6 +930,9 @@ func (s *MarketMakerService) stopBotWithError(botID int64, userID string, errorM
 		s.subManager.Unsubscribe(inst.Config.Pair, inst.TickerHandler)
 	}
 
+	// Orders are not cancelled here, let the deadman switch cancel them on the exchange
+	s.deadmanService.Expire(userID, inst.Config.Pair, botID)
+
 	// Remove from instances map
 	s.mu.Lock()
 	delete(s.instances, botID)
@@ -931,12 +976,22 @@ func (s *MarketMakerService) runBot(inst *BotInstance) {
 func (s *MarketMakerService) handleTicker(inst *BotInstance, ticker market.OrderBookTicker) {
 	s.log.Debugf("Bot %d received ticker: bid=%.2f ask=%.2f", inst.Config.ID, ticker.BestBid, ticker.BestAsk)
 
+	// 0. Refuse to quote against a book known to be inconsistent (resync pending)
+	if ticker.Stale {
+		s.log.Debugf("Bot %d: Order book for %s is stale, skipping", inst.Config.ID, ticker.Pair)
+		return
+	}
+	if time.Now().Before(inst.PausedUntil) {
+		s.log.Debugf("Bot %d: Quoting paused until %s, skipping", inst.Config.ID, inst.PausedUntil.Format(time.RFC3339))
+		return
+	}
+
 	// 1. Update prices first (needed for decision making)
 	inst.CurrentBid = ticker.BestBid
 	inst.CurrentAsk = ticker.BestAsk
 
 	// 2. Check and initialize virtual balance first (before any order decisions)
-	s.validateAndNormalizeBalances(inst)
+	s.marketMakerRules(inst).validateAndNormalizeBalances(inst)
 
 	// 3. Calculate spread (will be used for both update and decision)
 	spreadPercent := (ticker.BestAsk - ticker.BestBid) / ticker.BestBid * 100
@@ -956,51 +1011,22 @@ func (s *MarketMakerService) handleTicker(inst *BotInstance, ticker market.Order
 		SpreadPercent:  spreadPercent,
 	})
 
-	// 4. Check minimum gap
-	if spreadPercent < inst.Config.MinGapPercent {
-		s.log.Debugf("Bot %d: Spread too tight (%.4f%% < %.4f%%), skipping", inst.Config.ID, spreadPercent, inst.Config.MinGapPercent)
-		return // Spread too tight
-	}
-
-	s.log.Debugf("Bot %d: Gap OK (%.4f%% >= %.4f%%), processing orders", inst.Config.ID, spreadPercent, inst.Config.MinGapPercent)
-
-	// 4. Check volatility - only skip SELL if volatile AND we're at a loss
-	coin, err := s.marketDataService.GetCoin(context.Background(), inst.Config.Pair)
-	if err == nil && coin.Volatility1m > 2.0 {
-		// Determine if we would be selling (use virtual balance)
-		coinBalance := inst.Config.Balances[inst.BaseCurrency]
-		if coinBalance > 0 {
-			// We have coins, so we would be selling
-			// Check if we're at profit or loss
-			if inst.LastBuyPrice > 0 {
-				// Compare current ask price (what we'd sell at) vs buy price
-				currentSellPrice := ticker.BestAsk
-				profitPercent := ((currentSellPrice - inst.LastBuyPrice) / inst.LastBuyPrice) * 100
-
-				if profitPercent < 0 {
-					// We're at a loss - skip selling during high volatility
-					s.log.Debugf("Bot %d: Too volatile (%.2f%%) and at loss (%.2f%%), skipping SELL",
-						inst.Config.ID, coin.Volatility1m, profitPercent)
-					return
-				} else {
-					// We're at profit - volatility is fine, proceed with selling
-					s.log.Debugf("Bot %d: Volatile (%.2f%%) but at profit (%.2f%%), proceeding with SELL",
-						inst.Config.ID, coin.Volatility1m, profitPercent)
-				}
-			} else {
-				// No buy price tracked yet - skip selling during high volatility to be safe
-				s.log.Debugf("Bot %d: Too volatile (%.2f%%) for SELL (no buy price tracked), skipping",
-					inst.Config.ID, coin.Volatility1m)
-				return
-			}
-		} else {
-			// We would be buying - volatility is OK, proceed
-			s.log.Debugf("Bot %d: Volatile (%.2f%%) but buying is OK, proceeding", inst.Config.ID, coin.Volatility1m)
-		}
+	// 4. Check minimum gap and volatility
+	volatility := 0.0
+	if coin, err := s.marketDataService.GetCoin(context.Background(), inst.Config.Pair); err == nil {
+		volatility = coin.Volatility1m
+	}
+	if s.marketMakerRules(inst).quoteSkipped(inst, ticker, volatility) {
+		return
 	}
 
 	// 5. Process orders
 	if inst.ActiveOrder == nil {
+		// No new orders on stale market data (silent stream), resting ones are still managed
+		if s.marketDataService.IsStale(inst.Config.Pair, time.Now()) {
+			s.log.Debugf("Bot %d: Market data for %s is stale, not placing new orders", inst.Config.ID, inst.Config.Pair)
+			return
+		}
 		s.placeNewOrder(inst, ticker)
 	} else {
 		s.checkReposition(inst, ticker)
@@ -1036,151 +1062,12 @@ func (s *MarketMakerService) placeNewOrder(inst *BotInstance, ticker market.Orde
 		inst.ActiveOrder = nil
 	}
 
-	// Validate and normalize balances
-	s.validateAndNormalizeBalances(inst)
-
-	// Get balances after validation
-	idrBalance := inst.Config.Balances["idr"]
-	coinBalance := inst.Config.Balances[inst.BaseCurrency]
-
-	s.log.Debugf("Bot %d: Balance check - IDR=%.2f, %s=%.8f (BaseCurrency=%s, all balances: %+v)",
-		inst.Config.ID, idrBalance, inst.BaseCurrency, coinBalance, inst.BaseCurrency, inst.Config.Balances)
-
-	// Ensure pair info is available
-	if inst.PairInfo == nil {
-		s.log.Warnf("Bot %d: Pair info not available for %s", inst.Config.ID, inst.Config.Pair)
-		return
-	}
-	pairInfo := inst.PairInfo
-
-	// Get volume precision (using shared utility)
-	volumePrecision := util.GetVolumePrecision(*pairInfo)
-
-	s.log.Debugf("Bot %d: Pair info - VolumePrecision=%d, TradeMinBaseCurrency=%d, TradeMinTradedCurrency=%.2f",
-		inst.Config.ID, volumePrecision, pairInfo.TradeMinBaseCurrency, pairInfo.TradeMinTradedCurrency)
-
-	var side string
-	var price, amount float64
-
-	// Note: Balance corruption check is now in validateAndNormalizeBalances
-
-	// Decision logic:
-	// - If we have coins: SELL ALL available coin balance (with stop-loss check)
-	// - If we have IDR: BUY using OrderSizeIDR / price
-
-	// First check if coin balance is tradeable (not dust)
-	hasTradableCoins := false
-	if coinBalance > 0 {
-		roundedCoinBalance := util.FloorToPrecision(coinBalance, volumePrecision)
-		if roundedCoinBalance > 0 && roundedCoinBalance >= pairInfo.TradeMinTradedCurrency {
-			hasTradableCoins = true
-		} else {
-			s.log.Debugf("Bot %d: Coin balance %.8f %s is dust (below minimum %.8f) - will place BUY order instead",
-				inst.Config.ID, coinBalance, inst.BaseCurrency, pairInfo.TradeMinTradedCurrency)
-		}
-	}
-
-	if hasTradableCoins {
-		// Have coins -> SELL ALL available balance
-		// Check orderbook depth before placing sell order
-		estimatedSellValueIDR := coinBalance * inst.CurrentAsk
-		if !s.checkOrderbookDepth(ticker, "sell", estimatedSellValueIDR, inst.Config.MinGapPercent) {
-			s.log.Debugf("Bot %d: Skipping SELL - insufficient orderbook depth", inst.Config.ID)
-			return
-		}
-
-		// Calculate competitive sell price
-		sellPrice, err := s.calculateCompetitivePrice(inst, ticker, "sell")
-		if err != nil {
-			s.log.Warnf("Bot %d: Failed to calculate competitive price for SELL: %v", inst.Config.ID, err)
-			return
-		}
-
-		// Validate profit before selling
-		shouldSkip, reason := s.validateSellProfit(inst, sellPrice)
-		if shouldSkip {
-			s.log.Debugf("Bot %d: Skipping SELL - %s", inst.Config.ID, reason)
-			return
-		}
-
-		if inst.LastBuyPrice > 0 {
-			profitPercent := ((sellPrice - inst.LastBuyPrice) / inst.LastBuyPrice) * 100
-			s.log.Debugf("Bot %d: SELL check - buyPrice=%.2f, sellPrice=%.2f, profit=%.2f%%",
-				inst.Config.ID, inst.LastBuyPrice, sellPrice, profitPercent)
-		}
-
-		// Round coin balance for order placement
-		roundedCoinBalance := util.FloorToPrecision(coinBalance, volumePrecision)
-
-		side = "sell"
-		price = sellPrice
-
-		amount = roundedCoinBalance // Use rounded amount (already validated > 0)
-
-		// Safety check: don't sell if amount is unreasonably large (corruption protection)
-		if amount > util.MaxReasonableCoinAmount {
-			s.log.Errorf("Bot %d: Refusing to place SELL order - amount %.8f is unreasonably large", inst.Config.ID, amount)
-			return
-		}
-
-		s.log.Debugf("Bot %d: Placing SELL order - price=%.2f amount=%.8f (all available)", inst.Config.ID, price, amount)
-	} else if idrBalance >= inst.Config.OrderSizeIDR {
-		// Have IDR -> BUY
-		// Check orderbook depth before placing buy order
-		if !s.checkOrderbookDepth(ticker, "buy", inst.Config.OrderSizeIDR, inst.Config.MinGapPercent) {
-			s.log.Debugf("Bot %d: Skipping BUY - insufficient orderbook depth or bid gap too large", inst.Config.ID)
-			return
-		}
-
-		side = "buy"
-
-		// Calculate competitive buy price
-		buyPrice, err := s.calculateCompetitivePrice(inst, ticker, "buy")
-		if err != nil {
-			s.log.Warnf("Bot %d: Failed to calculate competitive price for BUY: %v", inst.Config.ID, err)
-			return
-		}
-		price = buyPrice
-
-		amount = inst.Config.OrderSizeIDR / price
-
-		// Safety check: don't buy more than we can afford
-		maxAffordable := idrBalance / price
-		if amount > maxAffordable {
-			s.log.Warnf("Bot %d: Attempted to buy %.8f but can only afford %.8f, capping", inst.Config.ID, amount, maxAffordable)
-			amount = maxAffordable
-		}
-
-		s.log.Debugf("Bot %d: Placing BUY order - price=%.2f amount=%.8f (size=%.2f IDR)", inst.Config.ID, price, amount, inst.Config.OrderSizeIDR)
-	} else {
-		// Insufficient balance
-		s.log.Debugf("Bot %d: Insufficient balance - IDR=%.2f < %.2f, %s=%.8f",
-			inst.Config.ID, idrBalance, inst.Config.OrderSizeIDR, inst.BaseCurrency, coinBalance)
-		return
-	}
-
-	// Validate order amount (using shared utility)
-	s.log.Debugf("Bot %d: Before validation - amount=%.8f, volumePrecision=%d", inst.Config.ID, amount, volumePrecision)
-
-	validation := util.ValidateOrderAmount(
-		inst.Config.ID,
-		amount,
-		price,
-		*pairInfo,
-		volumePrecision,
-		inst.BaseCurrency,
-		s.log,
-	)
-
-	if !validation.Valid {
-		s.log.Debugf("Bot %d: Order validation failed - %s", inst.Config.ID, validation.Reason)
+	// Decide side, price and amount from the balances and the book
+	side, price, amount, ok := s.marketMakerRules(inst).nextOrder(inst, ticker)
+	if !ok {
 		return
 	}
 
-	// Use validated amount
-	amount = validation.Amount
-	s.log.Debugf("Bot %d: After validation - amount=%.8f, orderValue=%.2f IDR", inst.Config.ID, amount, validation.OrderValue)
-
 	// Double-check: Don't place if active order exists (defense against race conditions)
 	if inst.ActiveOrder != nil && inst.ActiveOrder.Status == "open" {
 		s.log.Warnf("Bot %d: Aborting placeNewOrder - active order detected during order placement (ID: %s, side: %s, price: %.2f)",
@@ -1207,6 +1094,7 @@ func (s *MarketMakerService) placeNewOrder(inst *BotInstance, ticker market.Orde
 		Price:        price,
 		Amount:       amount,
 		IsPaperTrade: inst.Config.IsPaperTrading,
+		APIKeyID:     inst.Config.BoundAPIKeyID(),
 	}
 	inst.ActiveOrder = placeholderOrder
 	s.log.Debugf("Bot %d: Set ActiveOrder to pending BEFORE API call to prevent race", inst.Config.ID)
@@ -1231,7 +1119,7 @@ func (s *MarketMakerService) placeNewOrder(inst *BotInstance, ticker market.Orde
 	// If ClientOrderID is empty (shouldn't happen), fallback to numeric ID
 	orderID := res.ClientOrderID
 	if orderID == "" {
-		orderID = fmt.Sprintf("%d", res.OrderID)
+		orderID = res.OrderID
 	}
 
 	// Update placeholder order with actual response data
@@ -1308,110 +1196,8 @@ func (s *MarketMakerService) checkReposition(inst *BotInstance, ticker market.Or
 	}
 
 	ctx := context.Background()
-	var shouldCancel bool
-	var reason string
-	var expectedPrice float64
-
-	// Calculate current spread
-	spreadPercent := (inst.CurrentAsk - inst.CurrentBid) / inst.CurrentBid * 100
-
-	if order.Side == "buy" {
-		// For BUY: If spread is below gap target, cancel immediately (even if partially filled)
-		// We can't profitably sell later if spread is too tight
-		if spreadPercent < inst.Config.MinGapPercent {
-			s.log.Debugf("Bot %d: Spread too tight (%.4f%% < %.4f%%), cancelling BUY order immediately",
-				inst.Config.ID, spreadPercent, inst.Config.MinGapPercent)
-			shouldCancel = true
-			reason = fmt.Sprintf("Spread too tight (%.4f%% < %.4f%%) - cancelling to avoid unprofitable cycle",
-				spreadPercent, inst.Config.MinGapPercent)
-			// Proceed to cancellation logic below
-		} else {
-			// Spread is OK - proceed with normal BUY repositioning logic
-			// Calculate expected competitive price
-			calculatedPrice, err := s.calculateCompetitivePrice(inst, ticker, "buy")
-			if err != nil {
-				s.log.Warnf("Bot %d: Failed to calculate competitive price for BUY in checkReposition: %v", inst.Config.ID, err)
-				return
-			}
-			expectedPrice = calculatedPrice
-
-			// Allow small tolerance for price matching (handles floating point precision)
-			priceDiff := order.Price - expectedPrice
-			if priceDiff < 0 {
-				priceDiff = -priceDiff
-			}
-
-			if priceDiff > 0.01 { // 0.01 IDR tolerance
-				// Price doesn't match - cancel to reposition
-				shouldCancel = true
-				reason = fmt.Sprintf("Our bid (%.2f) != expected price (%.2f) - should match market",
-					order.Price, expectedPrice)
-			} else {
-				// Price matches - but check if depth is still sufficient
-				// If depth became insufficient, cancel the order (risky to keep in thin market)
-				if !s.checkOrderbookDepth(ticker, "buy", inst.Config.OrderSizeIDR, inst.Config.MinGapPercent) {
-					shouldCancel = true
-					reason = fmt.Sprintf("Our bid (%.2f) matches expected price, but orderbook depth insufficient - cancelling risky order",
-						order.Price)
-				}
-				// If price matches AND depth is sufficient, keep the order (shouldCancel stays false)
-			}
-		}
-	} else {
-		// For SELL: If spread is below gap target, check profit before deciding
-		if spreadPercent < inst.Config.MinGapPercent {
-			// Check profit before deciding
-			shouldSkip, skipReason := s.validateSellProfit(inst, order.Price)
-			if shouldSkip {
-				// Profit doesn't meet requirements - cancel
-				s.log.Debugf("Bot %d: Spread too tight (%.4f%% < %.4f%%) and %s, cancelling SELL order",
-					inst.Config.ID, spreadPercent, inst.Config.MinGapPercent, skipReason)
-				shouldCancel = true
-				reason = fmt.Sprintf("Spread too tight (%.4f%% < %.4f%%) and %s - cancelling",
-					spreadPercent, inst.Config.MinGapPercent, skipReason)
-			} else if inst.LastBuyPrice > 0 {
-				// Profit meets requirements - keep the order to lock in profit
-				profitPercent := ((order.Price - inst.LastBuyPrice) / inst.LastBuyPrice) * 100
-				s.log.Debugf("Bot %d: Spread too tight (%.4f%% < %.4f%%) but profit (%.2f%%) > MinGap (%.4f%%), keeping SELL order",
-					inst.Config.ID, spreadPercent, inst.Config.MinGapPercent, profitPercent, inst.Config.MinGapPercent)
-				return // Don't cancel - we want to lock in profit
-			} else {
-				// No buy price tracked - cancel to be safe
-				s.log.Debugf("Bot %d: Spread too tight (%.4f%% < %.4f%%) and no buy price tracked, cancelling SELL order",
-					inst.Config.ID, spreadPercent, inst.Config.MinGapPercent)
-				shouldCancel = true
-				reason = fmt.Sprintf("Spread too tight (%.4f%% < %.4f%%) and no buy price tracked - cancelling",
-					spreadPercent, inst.Config.MinGapPercent)
-			}
-		} else {
-			// Spread is OK - proceed with normal SELL repositioning logic
-			// Calculate expected competitive price
-			calculatedPrice, err := s.calculateCompetitivePrice(inst, ticker, "sell")
-			if err != nil {
-				s.log.Warnf("Bot %d: Failed to calculate competitive price for SELL in checkReposition: %v", inst.Config.ID, err)
-				return
-			}
-			expectedPrice = calculatedPrice
-
-			// Allow small tolerance for price matching (handles floating point precision)
-			priceDiff := order.Price - expectedPrice
-			if priceDiff < 0 {
-				priceDiff = -priceDiff
-			}
-
-			if priceDiff > 0.01 {
-				// Price doesn't match - cancel to reposition
-				shouldCancel = true
-				reason = fmt.Sprintf("Our ask (%.2f) != expected price (%.2f) - should match market",
-					order.Price, expectedPrice)
-			}
-			// If price matches, keep the order (no depth check - we need liquidity fast for selling)
-		}
-	}
-
-	if !shouldCancel {
-		s.log.Debugf("Bot %d: Order still at competitive price - %s order price=%.2f matches expected price=%.2f",
-			inst.Config.ID, order.Side, order.Price, expectedPrice)
+	reason := s.marketMakerRules(inst).repositionReason(inst, ticker)
+	if reason == "" {
 		return
 	}
 
@@ -1858,25 +1644,25 @@ func (s *MarketMakerService) handlePartialFill(inst *BotInstance, order *model.O
 	})
 }
 
-func (s *MarketMakerService) handleLiveOrderUpdate(userID string, order *indodax.OrderUpdate) {
-	status := strings.ToLower(order.Status)
+func (s *MarketMakerService) handleLiveOrderUpdate(userID string, order *exchange.OrderUpdate) {
+	status := order.Status
 
 	// Handle CANCELLED orders - restore balance if we deducted pessimistically
-	if status == "cancelled" || status == "canceled" {
+	if status == exchange.OrderStatusCancelled {
 		s.handleCancelledOrder(userID, order)
 		return
 	}
 
-	// Indodax sends "FILL" or "DONE" for filled orders
-	if status != "filled" && status != "fill" && status != "done" {
+	// Partial and complete fills (Indodax "FILL" / "DONE")
+	if !order.IsFill() {
 		return
 	}
 
-	// Parse quantities from WebSocket
-	executedQty, _ := strconv.ParseFloat(order.ExecutedQty, 64)
-	unfilledQty, _ := strconv.ParseFloat(order.UnfilledQty, 64)
-	origQty, _ := strconv.ParseFloat(order.OrigQty, 64)
-	price, _ := strconv.ParseFloat(order.Price, 64)
+	// Quantities from WebSocket
+	executedQty := order.ExecutedQty
+	unfilledQty := order.UnfilledQty
+	origQty := order.OrigQty
+	price := order.Price
 
 	// Check if order is completely filled
 	isCompletelyFilled := (unfilledQty == 0) || (executedQty >= origQty)
@@ -1961,10 +1747,10 @@ func (s *MarketMakerService) handleLiveOrderUpdate(userID string, order *indodax
 	}
 }
 
-func (s *MarketMakerService) handleCancelledOrder(userID string, order *indodax.OrderUpdate) {
-	// Parse quantities
-	executedQty, _ := strconv.ParseFloat(order.ExecutedQty, 64)
-	origQty, _ := strconv.ParseFloat(order.OrigQty, 64)
+func (s *MarketMakerService) handleCancelledOrder(userID string, order *exchange.OrderUpdate) {
+	// Quantities
+	executedQty := order.ExecutedQty
+	origQty := order.OrigQty
 	unfilledQty := origQty - executedQty
 
 	wsClientOrderID := order.ClientOrderID
@@ -2104,9 +1890,9 @@ func (s *MarketMakerService) handleCancelledOrder(userID string, order *indodax.
 		ctx := context.Background()
 		dbOrder, err := s.orderRepo.GetByOrderID(ctx, wsClientOrderID)
 		if err == nil && dbOrder.ParentType == "bot" {
-			// Found the order in database - it belongs to a stopped bot
+			// Found the order in database - it belongs to a stopped bot (grid bots settle their own orders)
 			bot, err := s.botRepo.GetByID(ctx, dbOrder.ParentID)
-			if err == nil {
+			if err == nil && bot.Type == model.BotTypeMarketMaker {
 				s.log.Infof("[WS_ORDER_UPDATE] MarketMaker: ✅ MATCHED cancelled order %s to STOPPED bot %d via database",
 					wsClientOrderID, bot.ID)
 
@@ -2177,100 +1963,6 @@ func (s *MarketMakerService) handleCancelledOrder(userID string, order *indodax.
 	}
 }
 
-// calculateCompetitivePrice calculates the competitive price for placing or checking an order
-// Returns the price that should be used based on whether we're the only buyer/seller
-func (s *MarketMakerService) calculateCompetitivePrice(
-	inst *BotInstance,
-	ticker market.OrderBookTicker,
-	side string, // "buy" or "sell"
-) (float64, error) {
-	if inst.PairInfo == nil {
-		return 0, fmt.Errorf("pair info not available for bot %d", inst.Config.ID)
-	}
-
-	tickSize := s.getTickSize(*inst.PairInfo)
-	var price float64
-	var noCompetition bool
-
-	if side == "buy" {
-		noCompetition = s.isOnlyBuyer(inst, ticker)
-		if noCompetition {
-			price = inst.CurrentBid // Match best bid if no other buyers
-			s.log.Debugf("Bot %d: calculateCompetitivePrice BUY - no other buyers → price = best bid (%.2f)",
-				inst.Config.ID, price)
-		} else {
-			price = inst.CurrentBid + tickSize // Add tick to outbid other buyers
-			s.log.Debugf("Bot %d: calculateCompetitivePrice BUY - other buyers competing → price = best bid (%.2f) + tick (%.2f) = %.2f",
-				inst.Config.ID, inst.CurrentBid, tickSize, price)
-		}
-	} else if side == "sell" {
-		noCompetition = s.isOnlySeller(inst, ticker)
-		if noCompetition {
-			price = inst.CurrentAsk // Match best ask if no other sellers
-			s.log.Debugf("Bot %d: calculateCompetitivePrice SELL - no other sellers → price = best ask (%.2f)",
-				inst.Config.ID, price)
-		} else {
-			price = inst.CurrentAsk - tickSize // Subtract tick to undercut other sellers
-			s.log.Debugf("Bot %d: calculateCompetitivePrice SELL - other sellers competing → price = best ask (%.2f) - tick (%.2f) = %.2f",
-				inst.Config.ID, inst.CurrentAsk, tickSize, price)
-		}
-	} else {
-		return 0, fmt.Errorf("invalid side: %s (must be 'buy' or 'sell')", side)
-	}
-
-	// Round price to pair's price precision (critical for Indodax API)
-	if tickSize >= 1.0 {
-		// For IDR pairs (whole number prices), round to nearest increment then cast to int
-		price = util.RoundToNearestIncrement(price, tickSize)
-		price = float64(int64(price)) // Force to exact integer for IDR
-		s.log.Debugf("Bot %d: calculateCompetitivePrice %s - after rounding to increment %.0f: %.0f",
-			inst.Config.ID, side, tickSize, price)
-	} else {
-		price = util.RoundToPrecision(price, inst.PairInfo.PricePrecision)
-		s.log.Debugf("Bot %d: calculateCompetitivePrice %s - after rounding to precision %d: %.2f",
-			inst.Config.ID, side, inst.PairInfo.PricePrecision, price)
-	}
-
-	return price, nil
-}
-
-// validateSellProfit checks if a sell order should be placed based on profit requirements
-// Returns (shouldSkip, reason) - if shouldSkip is true, the order should not be placed
-func (s *MarketMakerService) validateSellProfit(inst *BotInstance, sellPrice float64) (bool, string) {
-	if inst.LastBuyPrice <= 0 {
-		// No buy price tracked - allow selling (let user decide)
-		return false, ""
-	}
-
-	// Calculate current profit/loss percentage
-	profitPercent := ((sellPrice - inst.LastBuyPrice) / inst.LastBuyPrice) * 100
-
-	// If loss exceeds 5%, skip selling to avoid realizing large losses
-	if profitPercent < -5.0 {
-		return true, fmt.Sprintf("at loss (%.2f%%) and price may recover, holding", profitPercent)
-	}
-
-	// Also check if profit meets minimum gap requirement
-	if profitPercent <= inst.Config.MinGapPercent {
-		return true, fmt.Sprintf("profit (%.2f%%) <= MinGap (%.4f%%), not profitable enough",
-			profitPercent, inst.Config.MinGapPercent)
-	}
-
-	return false, ""
-}
-
-// validateAndNormalizeBalances ensures balances are initialized and valid
-// Uses shared balance validation utility
-func (s *MarketMakerService) validateAndNormalizeBalances(inst *BotInstance) {
-	requiredCurrencies := []string{"idr", inst.BaseCurrency}
-	inst.Config.Balances = util.ValidateAndNormalizeBalances(
-		inst.Config.Balances,
-		requiredCurrencies,
-		inst.Config.InitialBalanceIDR,
-		s.log,
-	)
-}
-
 // handleAPIError handles API errors consistently across Trade and CancelOrder calls
 // Returns true if the error was handled and operation should retry, false otherwise
 func (s *MarketMakerService) handleAPIError(inst *BotInstance, err error, operation string) bool {
@@ -2278,19 +1970,43 @@ func (s *MarketMakerService) handleAPIError(inst *BotInstance, err error, operat
 		return false
 	}
 
-	// Handle rate limiting errors
-	if strings.Contains(strings.ToLower(err.Error()), "too_many_requests") ||
-		strings.Contains(strings.ToLower(err.Error()), "rate limit") {
+	switch exchange.KindOf(err) {
+	case exchange.ErrorKindRateLimited, exchange.ErrorKindInvalidNonce:
+		// The client already retried with backoff, debounce at bot level too
 		s.log.Warnf("Bot %d: Rate limited during %s - will retry after backoff", inst.Config.ID, operation)
 		inst.LastOrderTime = time.Now() // Update to enforce debounce
 		return true                     // Should retry
-	}
 
-	// Handle order not found errors (expected if order was already filled/cancelled)
-	if util.IsOrderNotFoundError(err) {
+	case exchange.ErrorKindMaintenance, exchange.ErrorKindNetwork:
+		pause := networkErrorPause
+		if exchange.IsKind(err, exchange.ErrorKindMaintenance) {
+			pause = maintenancePause
+		}
+		inst.PausedUntil = time.Now().Add(pause)
+		s.log.Warnf("Bot %d: Exchange unavailable during %s (%v) - pausing quotes for %s", inst.Config.ID, operation, err, pause)
+		return true
+
+	case exchange.ErrorKindOrderNotFound:
+		// Expected if order was already filled/cancelled
 		s.log.Infof("Bot %d: Order not found on exchange during %s - likely filled or cancelled, waiting for WebSocket confirmation",
 			inst.Config.ID, operation)
 		return false // Don't retry, wait for WebSocket
+
+	case exchange.ErrorKindInsufficientBalance:
+		// Virtual balance drifted from the exchange, lower the IDR allocation before quoting again.
+		// Async: callers hold inst.mu, which reconcileIDR takes.
+		s.log.Warnf("Bot %d: Insufficient balance during %s - resyncing balance", inst.Config.ID, operation)
+		if !inst.Config.IsPaperTrading {
+			go s.reconcileIDR(inst)
+		}
+		return false
+
+	case exchange.ErrorKindOrderBelowMinimum:
+		// Order size is derived from the bot balance, it will not grow by retrying
+		if !inst.Config.IsPaperTrading {
+			s.stopBotWithError(inst.Config.ID, inst.Config.UserID, fmt.Sprintf("Order size below exchange minimum during %s: %v", operation, err))
+		}
+		return false
 	}
 
 	// Handle critical trading errors (API key or invalid pair)
@@ -2304,7 +2020,16 @@ func (s *MarketMakerService) handleAPIError(inst *BotInstance, err error, operat
 	return false
 }
 
-func (s *MarketMakerService) getTickSize(pair indodax.Pair) float64 {
+// marketMakerRules returns the quoting rules of a bot
+func (s *MarketMakerService) marketMakerRules(inst *BotInstance) marketMakerRules {
+	rules := marketMakerRules{log: s.log}
+	if inst.PairInfo != nil {
+		rules.tickSize = s.getTickSize(*inst.PairInfo)
+	}
+	return rules
+}
+
+func (s *MarketMakerService) getTickSize(pair exchange.Pair) float64 {
 	// Try to get actual price increment from market data service first
 	if increment, ok := s.marketDataService.GetPriceIncrement(pair.ID); ok && increment > 0 {
 		s.log.Debugf("getTickSize for %s: Using price increment from API = %.10f", pair.ID, increment)
@@ -2318,219 +2043,32 @@ func (s *MarketMakerService) getTickSize(pair indodax.Pair) float64 {
 	return fallback
 }
 
-// isOnlyBuyer checks if there are other BUYERS competing when we want to place a BUY order
-// This is used when placing BUY orders to decide if we need to add tick to compete with other buyers
-// Returns TRUE if: there are NO other buyers (we can just match current best bid)
-// Returns FALSE if: there are other buyers competing (we should add tick to outbid them)
-func (s *MarketMakerService) isOnlyBuyer(inst *BotInstance, ticker market.OrderBookTicker) bool {
-	// Check the BID side (buyers) to see if there's competition
-	if len(ticker.Bids) == 0 {
-		s.log.Debugf("Bot %d: No buyers in orderbook → no competition, can place at current best bid", inst.Config.ID)
-		return true
-	}
-
-	// If we don't have an active buy order, check if there are multiple buyers
-	if inst.ActiveOrder == nil || inst.ActiveOrder.Side != "buy" {
-		if len(ticker.Bids) >= 2 {
-			bestBidPrice := ticker.Bids[0].Price
-			secondBidPrice := ticker.Bids[1].Price
-			// If there are 2+ different bid levels, there's buyer competition
-			if bestBidPrice > secondBidPrice {
-				s.log.Debugf("Bot %d: Multiple buyers competing (best bid=%.2f, 2nd bid=%.2f) → ADD TICK to outbid them",
-					inst.Config.ID, bestBidPrice, secondBidPrice)
-				return false // Multiple buyers, need to add tick
-			}
-		}
-		// Only one buyer level
-		s.log.Debugf("Bot %d: Only one buyer level → no competition", inst.Config.ID)
-		return true
-	}
-
-	// We have an active buy order - check if it's the only one at best bid
-	bestBid := ticker.Bids[0]
-	bestBidPrice := bestBid.Price
-	bestBidVolumeIDR := bestBid.IDRVolume
-
-	// Check if our buy order price matches best bid
-	priceDiff := inst.ActiveOrder.Price - bestBidPrice
-	if priceDiff < 0 {
-		priceDiff = -priceDiff
-	}
-
-	if priceDiff > 0.01 {
-		// Our buy is not at best bid, there are other buyers ahead
-		s.log.Debugf("Bot %d: Our buy (%.2f) NOT at best bid (%.2f) → other buyers ahead → ADD TICK",
-			inst.Config.ID, inst.ActiveOrder.Price, bestBidPrice)
-		return false
-	}
-
-	// Our buy is at best bid - check if it's the only one (volume match)
-	ourOrderValueIDR := inst.ActiveOrder.Amount * inst.ActiveOrder.Price
-	volumeDiff := ourOrderValueIDR - bestBidVolumeIDR
-	if volumeDiff < 0 {
-		volumeDiff = -volumeDiff
-	}
-
-	tolerance := ourOrderValueIDR * 0.01
-	s.log.Debugf("Bot %d: Our buy at best bid - volume check: our=%.2f IDR, bestBid=%.2f IDR, diff=%.2f",
-		inst.Config.ID, ourOrderValueIDR, bestBidVolumeIDR, volumeDiff)
-
-	if volumeDiff <= tolerance {
-		s.log.Debugf("Bot %d: ✓ Our buy is the ONLY buyer at best bid → no need to add tick",
-			inst.Config.ID)
-		return true // We're the only buyer, no need to be aggressive
-	}
-
-	s.log.Debugf("Bot %d: Other buyers at best bid (volume mismatch) → ADD TICK to outbid them",
-		inst.Config.ID)
-	return false // Other buyers at same price
-}
-
-// isOnlySeller checks if there are other SELLERS competing when we want to place a SELL order
-// This is used when placing SELL orders to decide if we need to subtract tick to compete with other sellers
-// Returns TRUE if: there are NO other sellers (we can just match current best ask)
-// Returns FALSE if: there are other sellers competing (we should subtract tick to undercut them)
-func (s *MarketMakerService) isOnlySeller(inst *BotInstance, ticker market.OrderBookTicker) bool {
-	// Check the ASK side (sellers) to see if there's competition
-	if len(ticker.Asks) == 0 {
-		s.log.Debugf("Bot %d: No sellers in orderbook → no competition, can place at current best ask", inst.Config.ID)
-		return true
-	}
-
-	// If we don't have an active sell order, check if there are multiple sellers
-	if inst.ActiveOrder == nil || inst.ActiveOrder.Side != "sell" {
-		if len(ticker.Asks) >= 2 {
-			bestAskPrice := ticker.Asks[0].Price
-			secondAskPrice := ticker.Asks[1].Price
-			// If there are 2+ different ask levels, there's seller competition
-			if secondAskPrice > bestAskPrice {
-				s.log.Debugf("Bot %d: Multiple sellers competing (best ask=%.2f, 2nd ask=%.2f) → SUBTRACT TICK to undercut them",
-					inst.Config.ID, bestAskPrice, secondAskPrice)
-				return false // Multiple sellers, need to subtract tick
-			}
-		}
-		// Only one seller level
-		s.log.Debugf("Bot %d: Only one seller level → no competition", inst.Config.ID)
-		return true
-	}
-
-	// We have an active sell order - check if it's the only one at best ask
-	bestAsk := ticker.Asks[0]
-	bestAskPrice := bestAsk.Price
-	bestAskVolume := bestAsk.BaseVolume // Coin volume for asks
-
-	// Check if our sell order price matches best ask
-	priceDiff := inst.ActiveOrder.Price - bestAskPrice
-	if priceDiff < 0 {
-		priceDiff = -priceDiff
-	}
-
-	if priceDiff > 0.01 {
-		// Our sell is not at best ask, there are other sellers ahead
-		s.log.Debugf("Bot %d: Our sell (%.2f) NOT at best ask (%.2f) → other sellers ahead → SUBTRACT TICK",
-			inst.Config.ID, inst.ActiveOrder.Price, bestAskPrice)
-		return false
-	}
-
-	// Our sell is at best ask - check if it's the only one (volume match)
-	ourOrderVolume := inst.ActiveOrder.Amount // Coin amount
-
-	// If bestAskVolume is 0 or very small, it means our order is the only one at this price
-	if bestAskVolume < util.TinyBalanceThreshold {
-		s.log.Debugf("Bot %d: ✓ Best ask volume is 0 → our sell is the ONLY seller at best ask → no need to subtract tick",
-			inst.Config.ID)
-		return true
-	}
-
-	volumeDiff := ourOrderVolume - bestAskVolume
-	if volumeDiff < 0 {
-		volumeDiff = -volumeDiff
-	}
-
-	tolerance := ourOrderVolume * 0.01
-	s.log.Debugf("Bot %d: Our sell at best ask - volume check: our=%.8f coins, bestAsk=%.8f coins, diff=%.8f",
-		inst.Config.ID, ourOrderVolume, bestAskVolume, volumeDiff)
+// reconcileIDR lowers the bot's IDR balance to the IDR available on the exchange after an
+// order was rejected for insufficient balance. It never raises it, so IDR spent on the
+// bot's coins stays accounted for.
+func (s *MarketMakerService) reconcileIDR(inst *BotInstance) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
 
-	if volumeDiff <= tolerance {
-		s.log.Debugf("Bot %d: ✓ Our sell is the ONLY seller at best ask → no need to subtract tick",
-			inst.Config.ID)
-		return true // We're the only seller, no need to be aggressive
+	info, err := inst.TradeClient.GetInfo(ctx)
+	if err != nil {
+		s.log.Warnf("Bot %d: Failed to fetch live balance for resync: %v", inst.Config.ID, err)
+		return
 	}
+	available := info.Balances["idr"]
 
-	s.log.Debugf("Bot %d: Other sellers at best ask (volume mismatch) → SUBTRACT TICK to undercut them",
-		inst.Config.ID)
-	return false // Other sellers at same price
-}
-
-// checkOrderbookDepth checks if there's sufficient depth in the orderbook
-// Returns true if depth is sufficient, false if market is too thin
-func (s *MarketMakerService) checkOrderbookDepth(ticker market.OrderBookTicker, side string, orderSizeIDR float64, minGapPercent float64) bool {
-	const MIN_DEPTH_LEVELS = 3       // Need at least 3 price levels
-	const MIN_DEPTH_MULTIPLIER = 2.0 // Depth should be at least 2x our order size
-	const MAX_BID_GAP_PERCENT = 0.5  // Max 0.5% gap between bid[0] and bid[1]
+	inst.mu.Lock()
+	defer inst.mu.Unlock()
 
-	if len(ticker.Bids) < MIN_DEPTH_LEVELS || len(ticker.Asks) < MIN_DEPTH_LEVELS {
-		s.log.Debugf("Orderbook depth too thin: bids=%d asks=%d (need at least %d levels)",
-			len(ticker.Bids), len(ticker.Asks), MIN_DEPTH_LEVELS)
-		return false
+	current := inst.Config.Balances["idr"]
+	if current <= available {
+		return
 	}
+	inst.Config.Balances["idr"] = available
+	s.log.Warnf("Bot %d: IDR balance resynced to exchange: %.2f -> %.2f", inst.Config.ID, current, available)
 
-	if side == "buy" {
-		// Check if gap between bid[0] and bid[1] is too large (thin market)
-		if len(ticker.Bids) >= 2 {
-			bidGap := ticker.Bids[0].Price - ticker.Bids[1].Price
-			bidGapPercent := (bidGap / ticker.Bids[0].Price) * 100
-			if bidGapPercent > MAX_BID_GAP_PERCENT {
-				s.log.Debugf("Bid gap too large: bid[0]=%.2f, bid[1]=%.2f, gap=%.2f (%.4f%%) > threshold (%.2f%%)",
-					ticker.Bids[0].Price, ticker.Bids[1].Price, bidGap, bidGapPercent, MAX_BID_GAP_PERCENT)
-				return false
-			}
-		}
-		// For buy orders, check depth below our bid (we'll be placing at best bid)
-		// Sum up IDR volume at the first few bid levels
-		totalDepthIDR := 0.0
-		levelsToCheck := MIN_DEPTH_LEVELS
-		if levelsToCheck > len(ticker.Bids) {
-			levelsToCheck = len(ticker.Bids)
-		}
-
-		for i := 0; i < levelsToCheck; i++ {
-			totalDepthIDR += ticker.Bids[i].IDRVolume
-		}
-
-		// Check if depth is sufficient (at least 2x our order size)
-		minRequiredDepth := orderSizeIDR * MIN_DEPTH_MULTIPLIER
-		if totalDepthIDR < minRequiredDepth {
-			s.log.Debugf("Insufficient buy depth: total=%.2f IDR, required=%.2f IDR (order size=%.2f IDR)",
-				totalDepthIDR, minRequiredDepth, orderSizeIDR)
-			return false
-		}
-
-		s.log.Debugf("Buy depth OK: total=%.2f IDR across %d levels (order size=%.2f IDR)",
-			totalDepthIDR, levelsToCheck, orderSizeIDR)
-		return true
-	} else {
-		// For sell orders, check depth above our ask (we'll be placing at best ask)
-		totalDepthIDR := 0.0
-		levelsToCheck := MIN_DEPTH_LEVELS
-		if levelsToCheck > len(ticker.Asks) {
-			levelsToCheck = len(ticker.Asks)
-		}
-
-		for i := 0; i < levelsToCheck; i++ {
-			totalDepthIDR += ticker.Asks[i].IDRVolume
-		}
-
-		minRequiredDepth := orderSizeIDR * MIN_DEPTH_MULTIPLIER
-		if totalDepthIDR < minRequiredDepth {
-			s.log.Debugf("Insufficient sell depth: total=%.2f IDR, required=%.2f IDR (order size=%.2f IDR)",
-				totalDepthIDR, minRequiredDepth, orderSizeIDR)
-			return false
-		}
-
-		s.log.Debugf("Sell depth OK: total=%.2f IDR across %d levels (order size=%.2f IDR)",
-			totalDepthIDR, levelsToCheck, orderSizeIDR)
-		return true
+	if err := s.botRepo.UpdateBalance(ctx, inst.Config.ID, inst.Config.Balances); err != nil {
+		s.log.Warnf("Bot %d: Failed to save resynced balance: %v", inst.Config.ID, err)
 	}
 }
 
@@ -2546,7 +2084,7 @@ func (s *MarketMakerService) syncBalance(ctx context.Context, inst *BotInstance)
 			}
 			return fmt.Errorf("failed to fetch live balance: %w", err)
 		}
-		realIDR, _ = strconv.ParseFloat(info.Balance["idr"].String(), 64)
+		realIDR = info.Balances["idr"]
 	} else {
 		// For paper trading, we just use what's in our virtual balance or initial
 		if v, ok := inst.Config.Balances["idr"]; ok {
@@ -2584,6 +2122,21 @@ func (s *MarketMakerService) syncBalance(ctx context.Context, inst *BotInstance)
 	return s.botRepo.UpdateBalance(ctx, inst.Config.ID, inst.Config.Balances)
 }
 
+// chargePaperFee takes the exchange fee of a paper fill from the bot's IDR balance
+func (s *MarketMakerService) chargePaperFee(inst *BotInstance, fee float64) {
+	if fee <= 0 {
+		return
+	}
+
+	inst.mu.Lock()
+	defer inst.mu.Unlock()
+
+	inst.Config.Balances["idr"] -= fee
+	if err := s.botRepo.UpdateBalance(context.Background(), inst.Config.ID, inst.Config.Balances); err != nil {
+		s.log.Warnf("Bot %d: Failed to save balance after paper fee: %v", inst.Config.ID, err)
+	}
+}
+
 func (s *MarketMakerService) calculateProfit(inst *BotInstance, sellOrder *model.Order, sellAmount float64) float64 {
 	// Calculate profit: (SellPrice - BuyPrice) * Amount - Fees
 	// Fees: 0.1% on buy + 0.1% on sell = 0.2% total
@@ -2763,14 +2316,12 @@ func (s *MarketMakerService) cancelOrderForCleanup(ctx context.Context, inst *Bo
 	s.log.Debugf("Bot %d: Cancelling order %s (side=%s, price=%.2f, status=%s)",
 		botID, order.OrderID, order.Side, order.Price, order.Status)
 
-	// Cancel on Indodax if live trading
-	if !inst.Config.IsPaperTrading {
-		if err := inst.TradeClient.CancelOrder(ctx, order.Pair, order.OrderID, order.Side); err != nil {
-			s.log.Debugf("Bot %d: Failed to cancel order %s on Indodax: %v", botID, order.OrderID, err)
-			// Continue anyway - might already be cancelled
-		} else {
-			s.log.Debugf("Bot %d: Successfully cancelled order %s on Indodax", botID, order.OrderID)
-		}
+	// Cancel on the exchange (paper orders rest on the paper exchange)
+	if err := inst.TradeClient.CancelOrder(ctx, order.Pair, order.OrderID, order.Side); err != nil {
+		s.log.Debugf("Bot %d: Failed to cancel order %s on exchange: %v", botID, order.OrderID, err)
+		// Continue anyway - might already be cancelled
+	} else {
+		s.log.Debugf("Bot %d: Successfully cancelled order %s on exchange", botID, order.OrderID)
 	}
 
 	// Update status in database
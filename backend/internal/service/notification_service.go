@@ -91,6 +91,16 @@ func (s *NotificationService) NotifyPositionUpdate(ctx context.Context, userID s
 	s.NotifyUser(ctx, userID, model.MessageTypePositionUpdate, position)
 }
 
+// NotifyDeadmanAlert sends a deadman switch heartbeat alert to a user
+func (s *NotificationService) NotifyDeadmanAlert(ctx context.Context, userID string, payload model.WSDeadmanAlertPayload) {
+	s.NotifyUser(ctx, userID, model.MessageTypeDeadmanAlert, payload)
+}
+
+// NotifyAlert sends a triggered alert to its owner
+func (s *NotificationService) NotifyAlert(ctx context.Context, userID string, payload model.WSAlertPayload) {
+	s.NotifyUser(ctx, userID, model.MessageTypeAlert, payload)
+}
+
 // NotifyPumpSignal sends a pump signal to all users
 func (s *NotificationService) NotifyPumpSignal(ctx context.Context, payload interface{}) {
 	s.Broadcast(ctx, model.MessageTypePumpSignal, payload)
@@ -3,15 +3,14 @@ package service
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"strconv"
-	"strings"
 	"sync"
 	"time"
 
+	"tuyul/backend/internal/exchange"
 	"tuyul/backend/internal/model"
 	"tuyul/backend/internal/repository"
-	"tuyul/backend/pkg/indodax"
 	"tuyul/backend/pkg/logger"
 )
 
@@ -22,11 +21,12 @@ type OrderMonitor struct {
 	apiKeyRepo          *repository.APIKeyRepository
 	apiKeyService       *APIKeyService
 	notificationService *NotificationService
-	indodaxClient       *indodax.Client
+	exchange            exchange.Exchange
+	liveFeeRate         float64 // Fraction of the notional charged on reconciled live fills without a fee
 	log                 *logger.Logger
 
-	// User-specific WebSocket clients
-	wsClients map[string]*indodax.PrivateWSClient
+	// Private order streams, Key: API key ID
+	wsClients map[int64]*orderSubscription
 	mu        sync.RWMutex
 
 	// Callbacks for Copilot
@@ -34,18 +34,29 @@ type OrderMonitor struct {
 	onSellFilled func(trade *model.Trade, filledAmount float64, avgPrice float64)
 
 	// Generic handlers for bots
-	orderHandlers []func(userID string, order *indodax.OrderUpdate)
+	orderHandlers []func(userID string, order *exchange.OrderUpdate)
+
+	// API keys with a reconciliation run in progress
+	reconciling map[int64]bool
+	reconcileMu sync.Mutex
 
 	done chan struct{}
 }
 
+// orderSubscription is the private order stream of one API key
+type orderSubscription struct {
+	userID string
+	stream exchange.OrderStream
+}
+
 func NewOrderMonitor(
 	tradeRepo *repository.TradeRepository,
 	orderRepo *repository.OrderRepository,
 	apiKeyRepo *repository.APIKeyRepository,
 	apiKeyService *APIKeyService,
 	notificationService *NotificationService,
-	indodaxClient *indodax.Client,
+	ex exchange.Exchange,
+	liveFeeFallbackPct float64,
 ) *OrderMonitor {
 	return &OrderMonitor{
 		tradeRepo:           tradeRepo,
@@ -53,9 +64,11 @@ func NewOrderMonitor(
 		apiKeyRepo:          apiKeyRepo,
 		apiKeyService:       apiKeyService,
 		notificationService: notificationService,
-		indodaxClient:       indodaxClient,
+		exchange:            ex,
+		liveFeeRate:         liveFeeFallbackPct / 100,
 		log:                 logger.GetLogger(),
-		wsClients:           make(map[string]*indodax.PrivateWSClient),
+		wsClients:           make(map[int64]*orderSubscription),
+		reconciling:         make(map[int64]bool),
 		done:                make(chan struct{}),
 	}
 }
@@ -71,39 +84,63 @@ func (m *OrderMonitor) SetSellFilledCallback(cb func(trade *model.Trade, filledA
 }
 
 // AddOrderHandler adds a generic order update handler
-func (m *OrderMonitor) AddOrderHandler(handler func(userID string, order *indodax.OrderUpdate)) {
+func (m *OrderMonitor) AddOrderHandler(handler func(userID string, order *exchange.OrderUpdate)) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.orderHandlers = append(m.orderHandlers, handler)
 }
 
-// SubscribeUserOrders subscribes to order updates for a specific user
+// SubscribeUserOrders subscribes to order updates for every valid API key of a user
 func (m *OrderMonitor) SubscribeUserOrders(ctx context.Context, userID string) error {
+	apiKeys, err := m.apiKeyRepo.ListByUser(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get API keys: %w", err)
+	}
+
+	var errs []error
+	for _, apiKey := range apiKeys {
+		if !apiKey.IsValid {
+			continue
+		}
+		if err := m.SubscribeKeyOrders(ctx, userID, apiKey.ID); err != nil {
+			errs = append(errs, fmt.Errorf("API key %d: %w", apiKey.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SubscribeKeyOrders subscribes to order updates for one API key of a user
+func (m *OrderMonitor) SubscribeKeyOrders(ctx context.Context, userID string, keyID int64) error {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	// Check if already subscribed
-	if _, exists := m.wsClients[userID]; exists {
+	if _, exists := m.wsClients[keyID]; exists {
 		return nil
 	}
 
-	// Get user's API credentials
-	credentials, err := m.apiKeyService.GetDecrypted(ctx, userID)
+	// Get API credentials
+	credentials, err := m.apiKeyService.GetDecryptedByID(ctx, userID, keyID)
 	if err != nil {
 		return fmt.Errorf("failed to get API credentials: %w", err)
 	}
 
-	// Create Private WebSocket client
-	wsClient := indodax.NewPrivateWSClient(m.indodaxClient, credentials.Key, credentials.Secret)
+	// Create private order stream
+	wsClient := m.exchange.NewOrderStream(credentials.Key, credentials.Secret)
 
 	// Set order update handler
-	wsClient.SetOrderUpdateHandler(func(order *indodax.OrderUpdate) {
+	wsClient.SetOrderUpdateHandler(func(order *exchange.OrderUpdate) {
 		m.handleOrderUpdate(userID, order)
 	})
 
 	// Set error handler
 	wsClient.SetErrorHandler(func(err error) {
-		m.log.Errorf("Private WS error for user %s: %v", userID, err)
+		m.log.Errorf("Private WS error for user %s (API key %d): %v", userID, keyID, err)
+	})
+
+	// Reconcile fills missed while the stream was reconnecting
+	wsClient.SetReconnectHandler(func() {
+		m.reconcileAfterReconnect(userID, keyID, wsClient)
 	})
 
 	// Connect (this will authenticate and subscribe)
@@ -115,61 +152,78 @@ func (m *OrderMonitor) SubscribeUserOrders(ctx context.Context, userID string) e
 	// This ensures the connection is fully established before allowing bot to start
 	subCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
-	
+
 	if err := wsClient.WaitForSubscription(subCtx, 5*time.Second); err != nil {
 		wsClient.Close()
 		return fmt.Errorf("failed to verify private websocket subscription: %w", err)
 	}
 
-	m.wsClients[userID] = wsClient
-	m.log.Infof("Subscribed to order updates for user %s (verified)", userID)
+	m.wsClients[keyID] = &orderSubscription{userID: userID, stream: wsClient}
+	m.log.Infof("Subscribed to order updates for user %s, API key %d (verified)", userID, keyID)
 
 	return nil
 }
 
-// UnsubscribeUserOrders unsubscribes from order updates for a user
+// UnsubscribeUserOrders unsubscribes from order updates for every API key of a user
 func (m *OrderMonitor) UnsubscribeUserOrders(userID string) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	if wsClient, exists := m.wsClients[userID]; exists {
-		wsClient.Close()
-		delete(m.wsClients, userID)
-		m.log.Infof("Unsubscribed from order updates for user %s", userID)
+	for keyID, sub := range m.wsClients {
+		if sub.userID != userID {
+			continue
+		}
+		sub.stream.Close()
+		delete(m.wsClients, keyID)
+		m.log.Infof("Unsubscribed from order updates for user %s, API key %d", userID, keyID)
 	}
 }
 
-// IsSubscribed checks if a user is already subscribed to order updates
-func (m *OrderMonitor) IsSubscribed(userID string) bool {
+// UnsubscribeKeyOrders unsubscribes from order updates for one API key
+func (m *OrderMonitor) UnsubscribeKeyOrders(keyID int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if sub, exists := m.wsClients[keyID]; exists {
+		sub.stream.Close()
+		delete(m.wsClients, keyID)
+		m.log.Infof("Unsubscribed from order updates for user %s, API key %d", sub.userID, keyID)
+	}
+}
+
+// IsSubscribed checks if an API key is already subscribed to order updates
+func (m *OrderMonitor) IsSubscribed(keyID int64) bool {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
-	_, exists := m.wsClients[userID]
+	_, exists := m.wsClients[keyID]
 	return exists
 }
 
-// SubscribeAllUsersWithAPIKeys subscribes to order updates for all users with API keys
+// SubscribeAllUsersWithAPIKeys subscribes to order updates for every valid API key
 // This is called on API boot to establish connections proactively
 func (m *OrderMonitor) SubscribeAllUsersWithAPIKeys(ctx context.Context) error {
-	// Get all users with API keys
-	userIDs, err := m.apiKeyRepo.GetAllUserIDs(ctx)
+	apiKeys, err := m.apiKeyRepo.ListAll(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to get users with API keys: %w", err)
+		return fmt.Errorf("failed to get API keys: %w", err)
 	}
 
-	if len(userIDs) == 0 {
-		m.log.Info("No users with API keys found, skipping subscription")
+	if len(apiKeys) == 0 {
+		m.log.Info("No API keys found, skipping subscription")
 		return nil
 	}
 
-	m.log.Infof("Found %d users with API keys, subscribing to order updates...", len(userIDs))
+	m.log.Infof("Found %d API keys, subscribing to order updates...", len(apiKeys))
 
 	successCount := 0
 	failureCount := 0
 
-	for _, userID := range userIDs {
+	for _, apiKey := range apiKeys {
+		if !apiKey.IsValid {
+			continue
+		}
 		// Subscribe (errors are logged but don't stop the process)
-		if err := m.SubscribeUserOrders(ctx, userID); err != nil {
-			m.log.Warnf("Failed to subscribe user %s: %v", userID, err)
+		if err := m.SubscribeKeyOrders(ctx, apiKey.UserID, apiKey.ID); err != nil {
+			m.log.Warnf("Failed to subscribe API key %d of user %s: %v", apiKey.ID, apiKey.UserID, err)
 			failureCount++
 		} else {
 			successCount++
@@ -181,18 +235,16 @@ func (m *OrderMonitor) SubscribeAllUsersWithAPIKeys(ctx context.Context) error {
 }
 
 // handleOrderUpdate processes order update events from WebSocket
-func (m *OrderMonitor) handleOrderUpdate(userID string, order *indodax.OrderUpdate) {
+func (m *OrderMonitor) handleOrderUpdate(userID string, order *exchange.OrderUpdate) {
 	ctx := context.Background()
 
 	// Log FULL order update data for debugging
 	orderJSON, _ := json.Marshal(order)
-	status := strings.ToLower(order.Status)
+	status := order.Status
 	
-	if status == "filled" {
-		executedQty, _ := strconv.ParseFloat(order.ExecutedQty, 64)
-		price, _ := strconv.ParseFloat(order.Price, 64)
+	if status == exchange.OrderStatusFilled {
 		m.log.Infof("[WS_ORDER_UPDATE] OrderMonitor: Received FILLED order update - Full Data: %s, UserID=%s, ExecutedQty=%.8f, Price=%.2f",
-			string(orderJSON), userID, executedQty, price)
+			string(orderJSON), userID, order.ExecutedQty, order.Price)
 	} else {
 		m.log.Infof("[WS_ORDER_UPDATE] OrderMonitor: Received order update - Full Data: %s, UserID=%s, Status=%s",
 			string(orderJSON), userID, status)
@@ -211,17 +263,21 @@ func (m *OrderMonitor) handleOrderUpdate(userID string, order *indodax.OrderUpda
 
 	// Find internal order record
 	internalOrder, err := m.orderRepo.GetByOrderID(ctx, indodaxOrderID)
+	if err != nil && order.ClientOrderID != "" {
+		// Bots store the client order ID
+		internalOrder, err = m.orderRepo.GetByOrderID(ctx, order.ClientOrderID)
+	}
 	if err != nil {
 		// Optimization: if not found, it might be an order placed before this system restart
 		// or placed externally. We'll ignore it.
-		if status == "filled" {
+		if status == exchange.OrderStatusFilled {
 			m.log.Debugf("OrderMonitor: Filled order %s not found in database (may be external order or from before restart)", indodaxOrderID)
 		}
 		return
 	}
 
 	// Update order status in repository
-	m.orderRepo.UpdateStatus(ctx, internalOrder.ID, strings.ToLower(order.Status))
+	m.orderRepo.UpdateStatus(ctx, internalOrder.ID, status)
 
 	// Notify via WebSocket
 	m.notificationService.NotifyOrderUpdate(ctx, userID, internalOrder)
@@ -238,16 +294,15 @@ func (m *OrderMonitor) handleOrderUpdate(userID string, order *indodax.OrderUpda
 	// via AddOrderHandler will take care of it.
 }
 
-func (m *OrderMonitor) handleCopilotOrder(trade *model.Trade, internalOrder *model.Order, update *indodax.OrderUpdate) {
-	filledAmount, _ := strconv.ParseFloat(update.ExecutedQty, 64)
+func (m *OrderMonitor) handleCopilotOrder(trade *model.Trade, internalOrder *model.Order, update *exchange.OrderUpdate) {
+	filledAmount := update.ExecutedQty
 
-	switch strings.ToLower(update.Status) {
-	case "filled":
+	switch update.Status {
+	case exchange.OrderStatusFilled:
 		if trade.BuyOrderID == update.OrderID {
 			m.handleBuyOrderFilled(trade, filledAmount)
 		} else if trade.SellOrderID == update.OrderID {
-			avgPrice, _ := strconv.ParseFloat(update.Price, 64)
-			m.handleSellOrderFilled(trade, filledAmount, avgPrice)
+			m.handleSellOrderFilled(trade, filledAmount, update.Price)
 		}
 	}
 }
@@ -324,10 +379,10 @@ func (m *OrderMonitor) Stop() {
 	defer m.mu.Unlock()
 
 	// Close all WebSocket connections
-	for userID, wsClient := range m.wsClients {
-		wsClient.Close()
-		m.log.Infof("Closed WebSocket for user %s", userID)
+	for keyID, sub := range m.wsClients {
+		sub.stream.Close()
+		m.log.Infof("Closed WebSocket for user %s, API key %d", sub.userID, keyID)
 	}
 
-	m.wsClients = make(map[string]*indodax.PrivateWSClient)
+	m.wsClients = make(map[int64]*orderSubscription)
 }
@@ -0,0 +1,352 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"tuyul/backend/internal/exchange"
+	"tuyul/backend/internal/model"
+)
+
+const (
+	// Interval of the periodic reconciliation of open orders
+	reconcileInterval = 2 * time.Minute
+	// Timeout of one reconciliation run for a user
+	reconcileTimeout = 30 * time.Second
+	// Orders younger than this are left to the stream to avoid racing their first updates
+	reconcileMinAge = 10 * time.Second
+	// Recent orders fetched per pair when an order can no longer be looked up directly
+	reconcileHistoryLimit = 100
+)
+
+// StartReconciliation reconciles the open orders of subscribed API keys now and then periodically.
+// The private stream does not replay updates, so fills during a disconnect are otherwise lost.
+func (m *OrderMonitor) StartReconciliation() {
+	go func() {
+		m.reconcileAll()
+
+		ticker := time.NewTicker(reconcileInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				m.reconcileAll()
+			case <-m.done:
+				return
+			}
+		}
+	}()
+	m.log.Info("Order reconciliation started")
+}
+
+// reconcileAll reconciles every subscribed API key
+func (m *OrderMonitor) reconcileAll() {
+	m.mu.RLock()
+	owners := make(map[int64]string, len(m.wsClients))
+	for keyID, sub := range m.wsClients {
+		owners[keyID] = sub.userID
+	}
+	m.mu.RUnlock()
+
+	if len(owners) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), reconcileTimeout)
+	byKey, err := m.openOrdersByKey(ctx)
+	cancel()
+	if err != nil {
+		m.log.Warnf("Order reconciliation skipped: %v", err)
+		return
+	}
+
+	for keyID, orders := range byKey {
+		userID, ok := owners[keyID]
+		if !ok {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), reconcileTimeout)
+		if _, err := m.reconcileKey(ctx, userID, keyID, orders); err != nil {
+			m.log.Warnf("Order reconciliation failed for user %s, API key %d: %v", userID, keyID, err)
+		}
+		cancel()
+	}
+}
+
+// reconcileAfterReconnect reconciles an API key once its reconnected stream is subscribed again,
+// so updates after that point are delivered by the stream itself
+func (m *OrderMonitor) reconcileAfterReconnect(userID string, keyID int64, stream exchange.OrderStream) {
+	ctx, cancel := context.WithTimeout(context.Background(), reconcileTimeout)
+	defer cancel()
+
+	if err := stream.WaitForSubscription(ctx, 5*time.Second); err != nil {
+		m.log.Warnf("Private WS for user %s, API key %d not confirmed after reconnect, reconciling anyway: %v", userID, keyID, err)
+	}
+
+	byKey, err := m.openOrdersByKey(ctx)
+	if err != nil {
+		m.log.Warnf("Order reconciliation after reconnect skipped for user %s, API key %d: %v", userID, keyID, err)
+		return
+	}
+
+	replayed, err := m.reconcileKey(ctx, userID, keyID, byKey[keyID])
+	if err != nil {
+		m.log.Warnf("Order reconciliation after reconnect failed for user %s, API key %d: %v", userID, keyID, err)
+		return
+	}
+	m.log.Infof("Order reconciliation after reconnect for user %s, API key %d: %d missed updates replayed", userID, keyID, replayed)
+}
+
+// openOrdersByKey returns the live orders still believed to be open, keyed by the API key
+// they were placed with (orders without a key belong to the user's default key)
+func (m *OrderMonitor) openOrdersByKey(ctx context.Context) (map[int64][]*model.Order, error) {
+	cutoff := time.Now().Add(-reconcileMinAge)
+	byKey := make(map[int64][]*model.Order)
+	defaultKeys := make(map[string]int64)
+
+	for _, status := range []string{exchange.OrderStatusOpen, exchange.OrderStatusPartiallyFilled} {
+		orders, err := m.orderRepo.ListByStatus(ctx, status)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s orders: %w", status, err)
+		}
+		for _, o := range orders {
+			if o.IsPaperTrade || o.OrderID == "" || o.CreatedAt.After(cutoff) {
+				continue
+			}
+
+			keyID := o.APIKeyID
+			if keyID == 0 {
+				id, ok := defaultKeys[o.UserID]
+				if !ok {
+					if apiKey, err := m.apiKeyRepo.GetDefault(ctx, o.UserID); err == nil {
+						id = apiKey.ID
+					}
+					defaultKeys[o.UserID] = id
+				}
+				if id == 0 {
+					continue
+				}
+				keyID = id
+			}
+			byKey[keyID] = append(byKey[keyID], o)
+		}
+	}
+	return byKey, nil
+}
+
+// reconcileKey compares the given orders with the exchange account of an API key
+// and replays missed transitions. Returns the number of replayed updates.
+func (m *OrderMonitor) reconcileKey(ctx context.Context, userID string, keyID int64, orders []*model.Order) (int, error) {
+	if len(orders) == 0 {
+		return 0, nil
+	}
+
+	// Skip if a run for this key is already in progress (reconnect and ticker may overlap)
+	m.reconcileMu.Lock()
+	if m.reconciling[keyID] {
+		m.reconcileMu.Unlock()
+		return 0, nil
+	}
+	m.reconciling[keyID] = true
+	m.reconcileMu.Unlock()
+
+	defer func() {
+		m.reconcileMu.Lock()
+		delete(m.reconciling, keyID)
+		m.reconcileMu.Unlock()
+	}()
+
+	credentials, err := m.apiKeyService.GetDecryptedByID(ctx, userID, keyID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get API credentials: %w", err)
+	}
+	trader := m.exchange.NewTrader(credentials.Key, credentials.Secret)
+
+	byPair := make(map[string][]*model.Order)
+	for _, o := range orders {
+		byPair[o.Pair] = append(byPair[o.Pair], o)
+	}
+
+	replayed := 0
+	for pair, pairOrders := range byPair {
+		n, err := m.reconcilePair(ctx, userID, trader, pair, pairOrders)
+		replayed += n
+		if err != nil {
+			m.log.Warnf("Order reconciliation of %s for user %s incomplete: %v", pair, userID, err)
+		}
+	}
+
+	if replayed > 0 {
+		m.log.Infof("Order reconciliation for user %s, API key %d: %d missed updates replayed", userID, keyID, replayed)
+	}
+	return replayed, nil
+}
+
+// reconcilePair reconciles the orders of one pair
+func (m *OrderMonitor) reconcilePair(ctx context.Context, userID string, trader exchange.Trader, pair string, orders []*model.Order) (int, error) {
+	// 1. Orders still open on the exchange (may have been partially filled)
+	open, err := trader.GetOpenOrders(ctx, pair)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get open orders: %w", err)
+	}
+	openByID := indexOrders(open)
+
+	var history map[string]*exchange.Order
+	replayed := 0
+	for _, internal := range orders {
+		venue, stillOpen := openByID[internal.OrderID]
+
+		// 2. Orders closed while disconnected: look up directly, then in the history
+		if !stillOpen {
+			venue, err = trader.GetOrder(ctx, pair, internal.OrderID)
+			if exchange.IsKind(err, exchange.ErrorKindOrderNotFound) {
+				if history == nil {
+					closed, err := trader.GetOrderHistory(ctx, pair, reconcileHistoryLimit)
+					if err != nil {
+						return replayed, fmt.Errorf("failed to get order history: %w", err)
+					}
+					history = indexOrders(closed)
+				}
+				venue, err = history[internal.OrderID], nil
+			}
+			if err != nil {
+				m.log.Warnf("Order reconciliation: failed to get order %s for user %s: %v", internal.OrderID, userID, err)
+				continue
+			}
+			if venue == nil {
+				m.log.Warnf("Order reconciliation: order %s for user %s not found on exchange, leaving it %s",
+					internal.OrderID, userID, internal.Status)
+				continue
+			}
+		}
+
+		// 3. Replay what the stream would have sent
+		update := missedUpdate(internal, venue, m.liveFeeRate)
+		if update == nil {
+			continue
+		}
+		m.replayOrderUpdate(ctx, userID, internal, update)
+		replayed++
+	}
+
+	return replayed, nil
+}
+
+// replayOrderUpdate feeds a reconstructed update through the stream path,
+// then records it on the order so it is not replayed again
+func (m *OrderMonitor) replayOrderUpdate(ctx context.Context, userID string, internal *model.Order, update *exchange.OrderUpdate) {
+	m.log.Infof("[RECONCILE] Replaying missed update for order %s (user %s): %s -> %s, ExecutedQty=%.8f",
+		internal.OrderID, userID, internal.Status, update.Status, update.ExecutedQty)
+
+	m.handleOrderUpdate(userID, update)
+
+	// Handlers may have updated the order already, reload before writing
+	current, err := m.orderRepo.GetByID(ctx, internal.ID)
+	if err != nil {
+		m.log.Errorf("Order reconciliation: failed to reload order %d: %v", internal.ID, err)
+		return
+	}
+
+	oldStatus := current.Status
+	current.FilledAmount = max(current.FilledAmount, update.ExecutedQty)
+	if update.Status == exchange.OrderStatusFilled || update.Status == exchange.OrderStatusCancelled {
+		current.Status = update.Status
+	}
+	if update.Status == exchange.OrderStatusFilled && current.FilledAt == nil {
+		now := time.Now()
+		current.FilledAt = &now
+	}
+
+	if err := m.orderRepo.Update(ctx, current, oldStatus); err != nil {
+		m.log.Errorf("Order reconciliation: failed to update order %d: %v", internal.ID, err)
+	}
+}
+
+// missedUpdate builds the update the stream would have sent for the exchange state of an order,
+// or nil if nothing changed since it was last seen. Live fills the exchange reports without a
+// fee are charged liveFeeRate of the notional.
+func missedUpdate(internal *model.Order, venue *exchange.Order, liveFeeRate float64) *exchange.OrderUpdate {
+	amount := venue.Amount
+	if amount == 0 {
+		amount = internal.Amount
+	}
+	executed := amount - venue.Remaining
+	unfilled := venue.Remaining
+
+	price := venue.Price
+	if price <= 0 {
+		price = internal.Price
+	}
+
+	// Market buys are sized in IDR, the stream reports coins
+	side := venue.Side
+	if side == "" {
+		side = internal.Side
+	}
+	if venue.Type == "market" && side == "buy" && venue.Amount > 0 {
+		if price <= 0 {
+			return nil
+		}
+		amount, executed, unfilled = amount/price, executed/price, unfilled/price
+	}
+
+	status := venue.Status
+	switch status {
+	case exchange.OrderStatusFilled:
+		executed, unfilled = amount, 0
+	case exchange.OrderStatusCancelled:
+	case exchange.OrderStatusOpen, exchange.OrderStatusPartiallyFilled:
+		if executed <= 0 || executed <= internal.FilledAmount {
+			return nil
+		}
+		status = exchange.OrderStatusPartiallyFilled
+	default:
+		return nil
+	}
+
+	// Fee of the quantity executed since the order was last seen
+	var fee float64
+	if delta := executed - internal.FilledAmount; delta > 0 {
+		if venue.Fee > 0 && executed > 0 {
+			fee = venue.Fee * delta / executed
+		} else if !internal.IsPaperTrade {
+			fee = delta * price * liveFeeRate
+		}
+	}
+
+	// Bots match on the client order ID they stored
+	clientOrderID := venue.ClientOrderID
+	if clientOrderID == "" && internal.OrderID != venue.OrderID {
+		clientOrderID = internal.OrderID
+	}
+
+	return &exchange.OrderUpdate{
+		OrderID:         venue.OrderID,
+		ClientOrderID:   clientOrderID,
+		Pair:            internal.Pair,
+		Side:            side,
+		Price:           price,
+		OrigQty:         amount,
+		ExecutedQty:     executed,
+		UnfilledQty:     unfilled,
+		Status:          status,
+		TransactionTime: time.Now().UnixMilli(),
+		Fee:             fee,
+	}
+}
+
+// indexOrders maps orders by exchange order ID and client order ID
+func indexOrders(orders []exchange.Order) map[string]*exchange.Order {
+	index := make(map[string]*exchange.Order, len(orders)*2)
+	for i := range orders {
+		o := &orders[i]
+		if o.OrderID != "" {
+			index[o.OrderID] = o
+		}
+		if o.ClientOrderID != "" {
+			index[o.ClientOrderID] = o
+		}
+	}
+	return index
+}
@@ -0,0 +1,518 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"tuyul/backend/internal/exchange"
+	"tuyul/backend/internal/exchange/shadow"
+	"tuyul/backend/internal/service/market"
+	"tuyul/backend/pkg/logger"
+)
+
+// Closed paper orders are kept this long for GetOrder and the order history
+const paperOrderRetention = 24 * time.Hour
+
+// paperVenue names the paper exchange in exchange errors
+const paperVenue = "paper"
+
+// PaperExchange matches the orders of paper accounts against the live public
+// market data. Limit orders rest in the shadow of the venue book and fill only
+// when the trade prints or book snapshots of their pair reach them, keeping
+// their place in the queue; market orders walk the book. Order updates are
+// delivered like the private order stream of a live account: in order per
+// account and never while the exchange or the caller of Trade/CancelOrder holds a lock.
+type PaperExchange struct {
+	marketData exchange.MarketData
+	subManager *market.SubscriptionManager
+	makerFee   float64 // Fraction of the notional
+	takerFee   float64
+	log        *logger.Logger
+
+	mu        sync.Mutex
+	books     map[string]*shadow.Book // Pairs with resting orders, followed by followBook
+	orders    map[string]*paperOrder  // By paper order ID
+	clientIDs map[string]string       // Client order ID -> paper order ID
+	lastID    int64
+
+	queueMu sync.Mutex
+	queues  map[string][]paperNotice // Undelivered updates by account, drained by one goroutine each
+
+	subMu      sync.Mutex
+	subscribed map[string]bool // Pairs whose book handleBook is subscribed to
+}
+
+// paperOrder is an order of a paper account
+type paperOrder struct {
+	exchange.Order
+	account  string
+	seq      int64         // Placement order
+	shadow   *shadow.Order // Base amounts, market buys are converted at placement
+	cost     float64       // Quote value executed
+	newFee   float64       // Fee not reported in an update yet
+	onUpdate func(update *exchange.OrderUpdate)
+	closedAt time.Time
+}
+
+type paperNotice struct {
+	onUpdate func(update *exchange.OrderUpdate)
+	update   *exchange.OrderUpdate
+}
+
+// NewPaperExchange creates the paper exchange. Fees are in percent of the notional,
+// the maker fee applies to fills of resting orders, the taker fee to the rest.
+func NewPaperExchange(stream exchange.MarketStream, marketData exchange.MarketData, subManager *market.SubscriptionManager, makerFeePct, takerFeePct float64) *PaperExchange {
+	e := &PaperExchange{
+		marketData: marketData,
+		subManager: subManager,
+		makerFee:   makerFeePct / 100,
+		takerFee:   takerFeePct / 100,
+		log:        logger.GetLogger(),
+		books:      make(map[string]*shadow.Book),
+		orders:     make(map[string]*paperOrder),
+		clientIDs:  make(map[string]string),
+		queues:     make(map[string][]paperNotice),
+		subscribed: make(map[string]bool),
+	}
+
+	stream.AddTradeHandler(e.handleTrades)
+
+	return e
+}
+
+// Trade places an order of a paper account, see exchange.Trader.
+// onUpdate receives the fills and the cancellation of the order.
+func (e *PaperExchange) Trade(ctx context.Context, account, side, pair string, price, amount float64, type_, clientOrderID string, onUpdate func(update *exchange.OrderUpdate)) (*exchange.OrderResult, error) {
+	// 1. Validate
+	isMarket := type_ == "market"
+	if side != "buy" && side != "sell" {
+		return nil, fmt.Errorf("invalid order side %q", side)
+	}
+	if amount <= 0 || (!isMarket && price <= 0) {
+		return nil, fmt.Errorf("invalid order price %.8f or amount %.8f", price, amount)
+	}
+
+	// 2. Venue book from a REST snapshot until the stream delivers one
+	var snapshot *exchange.OrderBook
+	if !e.hasVenueBook(pair) {
+		book, err := e.marketData.GetOrderBook(ctx, pair)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get order book of %s: %w", pair, err)
+		}
+		snapshot = book
+	}
+
+	// Deferred first to run once e.mu is released
+	defer e.followBook(pair)
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if o, ok := e.orders[e.clientIDs[clientOrderID]]; ok && o.closedAt.IsZero() {
+		return nil, fmt.Errorf("duplicate client order ID %s", clientOrderID)
+	}
+	e.prune(time.Now())
+
+	book, resting := e.books[pair]
+	if !resting {
+		book = shadow.NewBook()
+	}
+	var fills []shadow.Fill
+	if snapshot != nil && book.Venue() == nil {
+		fills = book.UpdateOrderBook(snapshot)
+	}
+
+	// 3. Match
+	e.lastID = max(e.lastID+1, time.Now().UnixNano())
+	id := strconv.FormatInt(e.lastID, 10)
+	o := &paperOrder{
+		Order: exchange.Order{
+			OrderID:       id,
+			ClientOrderID: clientOrderID,
+			Pair:          pair,
+			Side:          side,
+			Type:          type_,
+			Price:         price,
+			Amount:        amount,
+			Remaining:     amount,
+			Status:        exchange.OrderStatusOpen,
+		},
+		account:  account,
+		seq:      e.lastID,
+		shadow:   &shadow.Order{ID: id, Side: side, Price: price, Amount: amount},
+		onUpdate: onUpdate,
+	}
+	if isMarket {
+		o.shadow = marketOrder(book.Venue(), id, side, amount)
+	}
+	e.orders[id] = o
+	if clientOrderID != "" {
+		e.clientIDs[clientOrderID] = id
+	}
+
+	fills = append(fills, book.Place(o.shadow)...)
+	if isMarket {
+		// What the book could not fill does not rest
+		book.Cancel(id)
+		if o.shadow.Filled <= 0 {
+			delete(e.orders, id)
+			delete(e.clientIDs, clientOrderID)
+			e.applyFills(fills)
+			return nil, fmt.Errorf("no liquidity to fill market %s on %s", side, pair)
+		}
+	}
+	e.applyFills(fills)
+	if isMarket && o.closedAt.IsZero() {
+		e.close(o, exchange.OrderStatusCancelled)
+		e.notify(o)
+	}
+
+	// 4. Follow the book of the pair while orders rest on it
+	if !resting && len(book.Orders()) > 0 {
+		e.books[pair] = book
+	}
+	e.release(pair)
+
+	e.log.Debugf("PaperExchange: %s %s order %s on %s: %.8f @ %.8f, %s (filled %.8f)",
+		type_, side, id, pair, amount, price, o.Status, o.shadow.Filled)
+
+	return &exchange.OrderResult{
+		OrderID:       id,
+		ClientOrderID: clientOrderID,
+	}, nil
+}
+
+// CancelOrder cancels a resting order of a paper account by paper or client order ID
+func (e *PaperExchange) CancelOrder(account, orderID string) error {
+	e.mu.Lock()
+	o := e.lookup(account, orderID)
+	if o == nil || !o.closedAt.IsZero() {
+		e.mu.Unlock()
+		return paperOrderNotFound(orderID)
+	}
+	if book, ok := e.books[o.Pair]; ok {
+		book.Cancel(o.OrderID)
+	}
+	e.close(o, exchange.OrderStatusCancelled)
+	e.notify(o)
+	e.release(o.Pair)
+	e.mu.Unlock()
+
+	e.followBook(o.Pair)
+	return nil
+}
+
+// GetOrder returns an order of a paper account by paper or client order ID
+func (e *PaperExchange) GetOrder(account, orderID string) (*exchange.Order, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	o := e.lookup(account, orderID)
+	if o == nil {
+		return nil, paperOrderNotFound(orderID)
+	}
+	order := o.Order
+	return &order, nil
+}
+
+// OpenOrders returns the resting orders of a paper account on a pair, oldest first
+func (e *PaperExchange) OpenOrders(account, pair string) []exchange.Order {
+	return e.listOrders(account, pair, true, 0)
+}
+
+// OrderHistory returns up to limit recent orders of a paper account on a pair
+// including closed ones, newest first. A limit of 0 returns all of them.
+func (e *PaperExchange) OrderHistory(account, pair string, limit int) []exchange.Order {
+	return e.listOrders(account, pair, false, limit)
+}
+
+func (e *PaperExchange) listOrders(account, pair string, openOnly bool, limit int) []exchange.Order {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var matched []*paperOrder
+	for _, o := range e.orders {
+		if o.account == account && o.Pair == pair && (!openOnly || o.closedAt.IsZero()) {
+			matched = append(matched, o)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool { return matched[i].seq < matched[j].seq })
+	if !openOnly {
+		for i, j := 0, len(matched)-1; i < j; i, j = i+1, j-1 {
+			matched[i], matched[j] = matched[j], matched[i]
+		}
+	}
+	if limit > 0 && len(matched) > limit {
+		matched = matched[:limit]
+	}
+
+	orders := make([]exchange.Order, 0, len(matched))
+	for _, o := range matched {
+		orders = append(orders, o.Order)
+	}
+	return orders
+}
+
+// handleBook matches the resting orders of a pair against a streamed book
+func (e *PaperExchange) handleBook(ticker market.OrderBookTicker) {
+	if ticker.Stale {
+		// Wait for the resynced snapshot instead of filling from a broken book
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	book, ok := e.books[ticker.Pair]
+	if !ok {
+		return
+	}
+	e.applyFills(book.UpdateOrderBook(venueOrderBook(ticker)))
+	if e.release(ticker.Pair) {
+		// Not from the stream goroutine delivering this book
+		go e.followBook(ticker.Pair)
+	}
+}
+
+// handleTrades matches the resting orders against streamed trade prints
+func (e *PaperExchange) handleTrades(trades []exchange.Trade) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if len(e.books) == 0 {
+		return
+	}
+	byPair := make(map[string][]exchange.Trade)
+	for _, t := range trades {
+		if _, ok := e.books[t.Pair]; ok {
+			byPair[t.Pair] = append(byPair[t.Pair], t)
+		}
+	}
+	for pair, pairTrades := range byPair {
+		e.applyFills(e.books[pair].AddTrades(pairTrades))
+		if e.release(pair) {
+			go e.followBook(pair)
+		}
+	}
+}
+
+// applyFills books fills on their orders, with fees, and queues one update per order.
+// Callers hold e.mu.
+func (e *PaperExchange) applyFills(fills []shadow.Fill) {
+	var touched []*paperOrder
+	for _, f := range fills {
+		o, ok := e.orders[f.Order.ID]
+		if !ok {
+			continue
+		}
+		rate := e.takerFee
+		if f.Maker {
+			rate = e.makerFee
+		}
+		notional := f.Price * f.Amount
+		o.cost += notional
+		o.Fee += notional * rate
+		o.newFee += notional * rate
+		if !slices.Contains(touched, o) {
+			touched = append(touched, o)
+		}
+	}
+
+	for _, o := range touched {
+		if o.shadow.Remaining() <= 0 {
+			e.close(o, exchange.OrderStatusFilled)
+		} else {
+			o.Status = exchange.OrderStatusPartiallyFilled
+			o.Remaining = o.shadow.Remaining()
+		}
+		e.notify(o)
+	}
+}
+
+// close ends an order. Market orders report their average fill price.
+func (e *PaperExchange) close(o *paperOrder, status string) {
+	o.Status = status
+	o.closedAt = time.Now()
+	switch {
+	case status == exchange.OrderStatusFilled:
+		o.Remaining = 0
+	case o.Type == "market" && o.Side == "buy":
+		o.Remaining = math.Max(o.Amount-o.cost, 0)
+	default:
+		o.Remaining = o.shadow.Remaining()
+	}
+	if o.Type == "market" && o.shadow.Filled > 0 {
+		o.Price = o.cost / o.shadow.Filled
+	}
+}
+
+// notify queues the current state of an order for its update handler
+func (e *PaperExchange) notify(o *paperOrder) {
+	update := &exchange.OrderUpdate{
+		OrderID:         o.OrderID,
+		ClientOrderID:   o.ClientOrderID,
+		Pair:            o.Pair,
+		Side:            o.Side,
+		Price:           o.Price,
+		OrigQty:         o.shadow.Amount,
+		ExecutedQty:     o.shadow.Filled,
+		UnfilledQty:     o.shadow.Remaining(),
+		Status:          o.Status,
+		TransactionTime: time.Now().UnixMilli(),
+		Fee:             o.newFee,
+	}
+	if o.shadow.Filled > 0 {
+		update.Price = o.cost / o.shadow.Filled
+	}
+	o.newFee = 0
+	if o.onUpdate == nil {
+		return
+	}
+
+	e.queueMu.Lock()
+	pending, draining := e.queues[o.account]
+	e.queues[o.account] = append(pending, paperNotice{onUpdate: o.onUpdate, update: update})
+	e.queueMu.Unlock()
+	if !draining {
+		go e.dispatch(o.account)
+	}
+}
+
+// dispatch delivers the queued order updates of an account one at a time
+func (e *PaperExchange) dispatch(account string) {
+	for {
+		e.queueMu.Lock()
+		pending := e.queues[account]
+		if len(pending) == 0 {
+			delete(e.queues, account)
+			e.queueMu.Unlock()
+			return
+		}
+		notice := pending[0]
+		pending[0] = paperNotice{}
+		e.queues[account] = pending[1:]
+		e.queueMu.Unlock()
+
+		notice.onUpdate(notice.update)
+	}
+}
+
+// hasVenueBook reports whether the book of a pair is already followed
+func (e *PaperExchange) hasVenueBook(pair string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	book, ok := e.books[pair]
+	return ok && book.Venue() != nil
+}
+
+// release drops the book of a pair without resting orders and reports whether it did.
+// Callers hold e.mu and call followBook once they released it.
+func (e *PaperExchange) release(pair string) bool {
+	book, ok := e.books[pair]
+	if !ok || len(book.Orders()) > 0 {
+		return false
+	}
+	delete(e.books, pair)
+	return true
+}
+
+// followBook subscribes to or unsubscribes from the book of a pair so it matches
+// whether orders rest on it. Callers must not hold e.mu: subscribing may connect the
+// stream, and the book handler takes e.mu.
+func (e *PaperExchange) followBook(pair string) {
+	e.subMu.Lock()
+	defer e.subMu.Unlock()
+
+	e.mu.Lock()
+	_, resting := e.books[pair]
+	e.mu.Unlock()
+
+	switch {
+	case resting && !e.subscribed[pair]:
+		if err := e.subManager.Subscribe(pair, e.handleBook); err != nil {
+			e.log.Warnf("PaperExchange: Failed to subscribe to order book of %s, paper orders fill from trades only: %v", pair, err)
+			return
+		}
+		e.subscribed[pair] = true
+	case !resting && e.subscribed[pair]:
+		e.subManager.Unsubscribe(pair, e.handleBook)
+		delete(e.subscribed, pair)
+	}
+}
+
+// lookup finds an order of an account by paper or client order ID. Callers hold e.mu.
+func (e *PaperExchange) lookup(account, orderID string) *paperOrder {
+	o, ok := e.orders[orderID]
+	if !ok {
+		o, ok = e.orders[e.clientIDs[orderID]]
+	}
+	if !ok || o.account != account {
+		return nil
+	}
+	return o
+}
+
+// prune forgets orders closed before the retention. Callers hold e.mu.
+func (e *PaperExchange) prune(now time.Time) {
+	for id, o := range e.orders {
+		if !o.closedAt.IsZero() && now.Sub(o.closedAt) > paperOrderRetention {
+			delete(e.orders, id)
+			if e.clientIDs[o.ClientOrderID] == id {
+				delete(e.clientIDs, o.ClientOrderID)
+			}
+		}
+	}
+}
+
+// marketOrder converts a market order to a shadow order crossing the whole book.
+// Market buys are sized in quote currency: they take the base amount the budget
+// buys at the venue asks.
+func marketOrder(venue *exchange.OrderBook, id, side string, amount float64) *shadow.Order {
+	if side == "sell" {
+		return &shadow.Order{ID: id, Side: side, Price: 0, Amount: amount}
+	}
+
+	base, budget := 0.0, amount
+	if venue != nil {
+		for _, level := range venue.Asks {
+			if budget <= 0 {
+				break
+			}
+			qty := math.Min(level.BaseVolume, budget/level.Price)
+			base += qty
+			budget -= qty * level.Price
+		}
+	}
+	return &shadow.Order{ID: id, Side: side, Price: math.MaxFloat64, Amount: base}
+}
+
+// venueOrderBook converts a streamed book back to the exchange format
+func venueOrderBook(ticker market.OrderBookTicker) *exchange.OrderBook {
+	book := &exchange.OrderBook{
+		Pair:     ticker.Pair,
+		Sequence: ticker.Sequence,
+		Bids:     make([]exchange.OrderBookLevel, 0, len(ticker.Bids)),
+		Asks:     make([]exchange.OrderBookLevel, 0, len(ticker.Asks)),
+	}
+	for _, level := range ticker.Bids {
+		book.Bids = append(book.Bids, exchange.OrderBookLevel{Price: level.Price, BaseVolume: level.BaseVolume, QuoteVolume: level.IDRVolume})
+	}
+	for _, level := range ticker.Asks {
+		book.Asks = append(book.Asks, exchange.OrderBookLevel{Price: level.Price, BaseVolume: level.BaseVolume, QuoteVolume: level.IDRVolume})
+	}
+	return book
+}
+
+func paperOrderNotFound(orderID string) error {
+	return &exchange.Error{
+		Kind:  exchange.ErrorKindOrderNotFound,
+		Venue: paperVenue,
+		Err:   fmt.Errorf("paper order %s not found", orderID),
+	}
+}
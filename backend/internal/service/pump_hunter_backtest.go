@@ -0,0 +1,390 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"tuyul/backend/internal/model"
+	"tuyul/backend/internal/service/market"
+	"tuyul/backend/internal/util"
+	"tuyul/backend/pkg/logger"
+)
+
+const (
+	// Candles before the start of a backtest that only build the market state
+	// (24h volume, sliding windows, indicators)
+	BacktestWarmup = 24 * time.Hour
+	// Equity curves are downsampled to this many points
+	maxEquityPoints = 1000
+	// Close reason of positions still open at the end of a backtest
+	closeReasonEndOfTest = "end_of_test"
+)
+
+// PumpHunterBacktest replays historical candles through the Pump Hunter entry
+// and exit rules with simulated fills and fees.
+//
+// Entries fill at the candle close plus slippage, without the pending order and
+// false pump stage of live bots. Positions with a target above 1% rest a limit
+// sell at the target, like live bots, and leave only when a candle high reaches
+// it; the others are checked once per candle by the exit rules and sold at the
+// close minus slippage.
+type PumpHunterBacktest struct {
+	req        *model.BacktestRequest
+	timeframes []model.TimeframeSpec
+	profiles   []*model.PumpScoreProfile
+	log        *logger.Logger
+}
+
+// NewPumpHunterBacktest validates a backtest request against the tracked
+// timeframes and the score profiles (default first, built-in when empty)
+func NewPumpHunterBacktest(req *model.BacktestRequest, timeframes []model.TimeframeSpec, profiles []*model.PumpScoreProfile) (*PumpHunterBacktest, error) {
+	if req.EntryRules == nil || req.ExitRules == nil || req.RiskManagement == nil {
+		return nil, util.ErrBadRequest("Pump Hunter rules and risk management are required")
+	}
+	if !req.To.After(req.From) {
+		return nil, util.ErrBadRequest("to must be after from")
+	}
+	if req.InitialBalanceIDR <= 0 {
+		return nil, util.ErrBadRequest("initial_balance_idr must be greater than 0")
+	}
+	if req.SlippagePercent < 0 || (req.TakerFeePercent != nil && *req.TakerFeePercent < 0) || (req.MakerFeePercent != nil && *req.MakerFeePercent < 0) {
+		return nil, util.ErrBadRequest("fees and slippage must not be negative")
+	}
+	if len(req.BacktestPairs()) == 0 {
+		return nil, util.ErrBadRequest("pairs or entry_rules.allowed_pairs are required")
+	}
+	if len(req.BacktestPairs()) > model.MaxBacktestPairs {
+		return nil, util.ErrBadRequest(fmt.Sprintf("at most %d pairs can be backtested", model.MaxBacktestPairs))
+	}
+	if req.RiskManagement.MaxConcurrentPositions <= 0 {
+		return nil, util.ErrBadRequest("max_concurrent_positions must be greater than 0")
+	}
+
+	if profile := req.EntryRules.ScoreProfile; profile != "" && profile != model.DefaultPumpScoreProfile {
+		found := false
+		for _, p := range profiles {
+			found = found || p.Name == profile
+		}
+		if !found {
+			return nil, util.ErrBadRequest(fmt.Sprintf("score_profile %s does not exist", profile))
+		}
+	}
+	if tf := req.EntryRules.IndicatorTimeframe; tf != "" {
+		found := false
+		for _, spec := range timeframes {
+			found = found || spec.Name == tf
+		}
+		if !found {
+			return nil, util.ErrBadRequest(fmt.Sprintf("indicator_timeframe %s is not a tracked timeframe", tf))
+		}
+	}
+	if err := validateRuleRanges(req.EntryRules, req.ExitRules); err != nil {
+		return nil, err
+	}
+
+	return &PumpHunterBacktest{
+		req:        req,
+		timeframes: timeframes,
+		profiles:   profiles,
+		log:        logger.GetLogger(),
+	}, nil
+}
+
+// BaseTimeframe returns the timeframe of the candles Run expects
+func (b *PumpHunterBacktest) BaseTimeframe() model.TimeframeSpec {
+	return b.timeframes[0]
+}
+
+// backtestRun is the state of one run
+type backtestRun struct {
+	req          *model.BacktestRequest
+	config       *model.BotConfig
+	rules        pumpHunterRules
+	book         pumpHunterBook
+	takerFee     float64 // Percent
+	makerFee     float64 // Percent
+	nextID       int64
+	coins        map[string]*model.Coin  // Latest state per pair
+	lastCandles  map[string]model.Candle // Candle of the current step per pair
+	result       *model.BacktestResult
+	equity       []model.EquityPoint
+	peakEquity   float64
+	entryStopped bool
+}
+
+// Run replays the candles of every pair (base timeframe, oldest first), the
+// ones before the request start only warm the market state up
+func (b *PumpHunterBacktest) Run(ctx context.Context, candles map[string][]model.Candle) (*model.BacktestResult, error) {
+	req := b.req
+	config := &model.BotConfig{
+		Name:              req.Name,
+		Type:              model.BotTypePumpHunter,
+		Pair:              "ALL",
+		IsPaperTrading:    true,
+		InitialBalanceIDR: req.InitialBalanceIDR,
+		Balances:          map[string]float64{"idr": req.InitialBalanceIDR},
+		EntryRules:        req.EntryRules,
+		ExitRules:         req.ExitRules,
+		RiskManagement:    req.RiskManagement,
+	}
+	taker, maker := req.Fees()
+	run := &backtestRun{
+		req:    req,
+		config: config,
+		rules:  newPumpHunterRules(config, b.timeframes[0].Name, b.log),
+		book: pumpHunterBook{
+			OpenPositions: make(map[int64]*model.Position),
+			PendingOrders: make(map[int64]*model.Position),
+		},
+		takerFee:    taker,
+		makerFee:    maker,
+		coins:       make(map[string]*model.Coin),
+		lastCandles: make(map[string]model.Candle),
+		result: &model.BacktestResult{
+			Timeframe:         b.timeframes[0].Name,
+			InitialBalanceIDR: req.InitialBalanceIDR,
+			Trades:            []model.BacktestTrade{},
+		},
+		peakEquity: req.InitialBalanceIDR,
+	}
+
+	// 1. Merge the series of every pair into one timeline
+	var timeline []model.Candle
+	for _, series := range candles {
+		timeline = append(timeline, series...)
+	}
+	if len(timeline) == 0 {
+		return nil, util.ErrBadRequest("no candles in the requested range")
+	}
+	sort.SliceStable(timeline, func(i, j int) bool {
+		return timeline[i].CloseTime.Before(timeline[j].CloseTime)
+	})
+
+	// 2. Step through the timeline one close time at a time
+	hist := market.NewHistoricalMarket(b.timeframes, b.profiles)
+	var now time.Time
+	for i := 0; i < len(timeline); {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		now = timeline[i].CloseTime
+		if now.After(req.To) {
+			break
+		}
+
+		var stepped []*model.Coin
+		for ; i < len(timeline) && timeline[i].CloseTime.Equal(now); i++ {
+			coin := hist.Step(timeline[i])
+			run.coins[coin.PairID] = coin
+			run.lastCandles[coin.PairID] = timeline[i]
+			stepped = append(stepped, coin)
+		}
+		if now.Before(req.From) {
+			continue // Warm-up
+		}
+		run.result.Candles += len(stepped)
+
+		run.checkExits(now)
+		run.checkEntries(stepped, now)
+		run.recordEquity(now)
+	}
+
+	// 3. Sell what is left at the last close
+	for _, pos := range run.sortedPositions() {
+		run.closePosition(pos, run.coins[pos.Pair].CurrentPrice, now, closeReasonEndOfTest)
+	}
+	run.recordEquity(now)
+
+	return run.finish(), nil
+}
+
+// checkExits fills take profit orders and runs the exit rules on open positions
+func (r *backtestRun) checkExits(now time.Time) {
+	target := r.config.ExitRules.TargetProfitPercent
+	for _, pos := range r.sortedPositions() {
+		coin := r.coins[pos.Pair]
+
+		// Resting limit sell at the target
+		if pos.Status == model.PositionStatusSelling {
+			sellPrice := pos.EntryPrice * (1 + target/100)
+			if candle := r.lastCandles[pos.Pair]; candle.CloseTime.Equal(now) && candle.High >= sellPrice {
+				r.closePosition(pos, sellPrice, now, "take_profit")
+			}
+			continue
+		}
+
+		r.rules.trackPrice(pos, coin.CurrentPrice)
+		if reason, _ := r.rules.exitSignal(pos, coin, now); reason != "" {
+			r.closePosition(pos, coin.CurrentPrice*(1-r.req.SlippagePercent/100), now, reason)
+		}
+	}
+}
+
+// checkEntries opens positions on the coins passing the entry rules, highest score first
+func (r *backtestRun) checkEntries(coins []*model.Coin, now time.Time) {
+	if r.entryStopped {
+		return
+	}
+	if r.rules.maxLossReached() {
+		// A live bot stops here
+		r.entryStopped = true
+		stoppedAt := now
+		r.result.StoppedAt = &stoppedAt
+		return
+	}
+
+	// Daily loss counter restarts on a new day
+	risk := r.config.RiskManagement
+	if risk.DailyLossLimitIDR > 0 && r.book.DailyLoss >= risk.DailyLossLimitIDR && now.Sub(r.book.LastLossTime) > 24*time.Hour {
+		r.book.DailyLoss = 0
+	}
+
+	sort.SliceStable(coins, func(i, j int) bool {
+		return signalPumpScore(r.config, coins[i]) > signalPumpScore(r.config, coins[j])
+	})
+	for _, coin := range coins {
+		if r.rules.entryRejection(&r.book, coin, now) == "" {
+			r.openPosition(coin, now)
+		}
+	}
+}
+
+// openPosition buys a coin at the close plus slippage, sized like a live bot
+func (r *backtestRun) openPosition(coin *model.Coin, now time.Time) {
+	risk := r.config.RiskManagement
+	availableBalance, hasEnough := util.CalculateAvailableBalance(r.config.Balances["idr"], risk.MinBalanceIDR)
+	if !hasEnough {
+		return
+	}
+	maxPositionSize := risk.MaxPositionIDR
+	if maxPositionSize <= 0 {
+		maxPositionSize = availableBalance
+	}
+	sizeIDR, valid := util.CalculatePositionSize(maxPositionSize, availableBalance)
+	if !valid || sizeIDR <= 0 {
+		return
+	}
+
+	price := coin.CurrentPrice * (1 + r.req.SlippagePercent/100)
+	fee := sizeIDR * r.takerFee / 100
+	r.nextID++
+	pos := &model.Position{
+		ID:             r.nextID,
+		Pair:           coin.PairID,
+		Status:         model.PositionStatusOpen,
+		EntryPrice:     price,
+		EntryQuantity:  (sizeIDR - fee) / price,
+		EntryAmountIDR: sizeIDR,
+		EntryOrderType: "market",
+		EntryPumpScore: signalPumpScore(r.config, coin),
+		EntryAt:        now,
+		HighestPrice:   price,
+		LowestPrice:    price,
+		LastPriceCheck: now,
+		IsPaperTrade:   true,
+	}
+	if target := r.config.ExitRules.TargetProfitPercent; target > 1.0 {
+		pos.Status = model.PositionStatusSelling
+	}
+
+	r.config.Balances["idr"] -= sizeIDR
+	r.result.FeesIDR += fee
+	r.book.OpenPositions[pos.ID] = pos
+}
+
+// closePosition sells a position, books the trade and updates the loss tracking
+func (r *backtestRun) closePosition(pos *model.Position, price float64, now time.Time, reason string) {
+	feeRate := r.takerFee
+	if reason == "take_profit" && pos.Status == model.PositionStatusSelling {
+		feeRate = r.makerFee
+	}
+	gross := price * pos.EntryQuantity
+	exitFee := gross * feeRate / 100
+	net := gross - exitFee
+	profitIDR := net - pos.EntryAmountIDR
+	entryFee := pos.EntryAmountIDR * r.takerFee / 100
+
+	r.config.Balances["idr"] += net
+	r.config.TotalTrades++
+	r.config.TotalProfitIDR += profitIDR
+	if profitIDR > 0 {
+		r.config.WinningTrades++
+	} else {
+		r.book.DailyLoss += math.Abs(profitIDR)
+		r.book.LastLossTime = now
+	}
+	r.result.FeesIDR += exitFee
+	delete(r.book.OpenPositions, pos.ID)
+
+	r.result.Trades = append(r.result.Trades, model.BacktestTrade{
+		Pair:           pos.Pair,
+		EntryAt:        pos.EntryAt,
+		EntryPrice:     pos.EntryPrice,
+		EntryPumpScore: pos.EntryPumpScore,
+		Quantity:       pos.EntryQuantity,
+		ExitAt:         now,
+		ExitPrice:      price,
+		CloseReason:    reason,
+		FeesIDR:        entryFee + exitFee,
+		ProfitIDR:      profitIDR,
+		ProfitPercent:  profitIDR / pos.EntryAmountIDR * 100,
+	})
+}
+
+// recordEquity values the balance plus open positions and tracks the drawdown
+func (r *backtestRun) recordEquity(now time.Time) {
+	equity := r.config.Balances["idr"]
+	for _, pos := range r.book.OpenPositions {
+		equity += pos.EntryQuantity * r.coins[pos.Pair].CurrentPrice
+	}
+	r.equity = append(r.equity, model.EquityPoint{Time: now, EquityIDR: equity})
+
+	if equity > r.peakEquity {
+		r.peakEquity = equity
+	}
+	if drawdown := r.peakEquity - equity; drawdown > r.result.MaxDrawdownIDR {
+		r.result.MaxDrawdownIDR = drawdown
+	}
+	if drawdownPct := (r.peakEquity - equity) / r.peakEquity * 100; drawdownPct > r.result.MaxDrawdownPercent {
+		r.result.MaxDrawdownPercent = drawdownPct
+	}
+}
+
+// sortedPositions returns the open positions in opening order
+func (r *backtestRun) sortedPositions() []*model.Position {
+	positions := make([]*model.Position, 0, len(r.book.OpenPositions))
+	for _, pos := range r.book.OpenPositions {
+		positions = append(positions, pos)
+	}
+	sort.Slice(positions, func(i, j int) bool { return positions[i].ID < positions[j].ID })
+	return positions
+}
+
+// finish computes the summary statistics
+func (r *backtestRun) finish() *model.BacktestResult {
+	result := r.result
+	result.FinalBalanceIDR = r.config.Balances["idr"]
+	result.ProfitIDR = result.FinalBalanceIDR - result.InitialBalanceIDR
+	result.ReturnPercent = result.ProfitIDR / result.InitialBalanceIDR * 100
+	result.TotalTrades = r.config.TotalTrades
+	result.WinningTrades = r.config.WinningTrades
+	result.WinRate = r.config.WinRate()
+	result.Equity = downsampleEquity(r.equity, maxEquityPoints)
+	return result
+}
+
+// downsampleEquity keeps at most n evenly spaced points, always keeping the last one
+func downsampleEquity(points []model.EquityPoint, n int) []model.EquityPoint {
+	if len(points) <= n {
+		return points
+	}
+	step := float64(len(points)-1) / float64(n-1)
+	sampled := make([]model.EquityPoint, 0, n)
+	for i := 0; i < n; i++ {
+		sampled = append(sampled, points[int(math.Round(float64(i)*step))])
+	}
+	return sampled
+}
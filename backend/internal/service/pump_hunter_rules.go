@@ -0,0 +1,453 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"tuyul/backend/internal/model"
+	"tuyul/backend/internal/service/market"
+	"tuyul/backend/pkg/logger"
+)
+
+// Entry rejection reason that is not logged, it is by far the most frequent
+const reasonPumpScoreTooLow = "Pump score too low"
+
+// pumpHunterRules evaluates the entry and exit rules of a Pump Hunter bot at a
+// given time. Apart from the tracking fields of positions it has no side effects,
+// so live bots and backtests take the same decisions; callers persist, notify and trade.
+type pumpHunterRules struct {
+	config      *model.BotConfig
+	indicatorTF string // Timeframe of the indicator filters and exits
+	log         *logger.Logger
+}
+
+func newPumpHunterRules(config *model.BotConfig, shortestTimeframe string, log *logger.Logger) pumpHunterRules {
+	tf := shortestTimeframe
+	if config.EntryRules != nil && config.EntryRules.IndicatorTimeframe != "" {
+		tf = config.EntryRules.IndicatorTimeframe
+	}
+	return pumpHunterRules{config: config, indicatorTF: tf, log: log}
+}
+
+// pumpHunterBook is the position state the entry risk checks run against
+type pumpHunterBook struct {
+	DailyLoss     float64
+	LastLossTime  time.Time
+	OpenPositions map[int64]*model.Position
+	PendingOrders map[int64]*model.Position // Entry orders not filled yet
+}
+
+// activeCount counts pending, buying and open positions (selling ones are exiting)
+func (b *pumpHunterBook) activeCount() int {
+	count := len(b.PendingOrders)
+	for _, pos := range b.OpenPositions {
+		if pos.Status == model.PositionStatusOpen || pos.Status == model.PositionStatusBuying {
+			count++
+		}
+	}
+	return count
+}
+
+// maxLossReached reports whether the total loss limit stops the bot
+// (DailyLossLimitIDR is the Pump Hunter limit, MaxLossIDR is for Market Maker)
+func (r pumpHunterRules) maxLossReached() bool {
+	limit := r.config.RiskManagement.DailyLossLimitIDR
+	return limit > 0 && r.config.TotalProfitIDR <= -limit
+}
+
+// entryRejection returns why a coin may not be entered now, empty when every check passes
+func (r pumpHunterRules) entryRejection(book *pumpHunterBook, coin *model.Coin, now time.Time) string {
+	reason := r.entryReason(book, coin, now)
+	if reason != "" && reason != reasonPumpScoreTooLow {
+		r.log.Debugf("Bot %d: Entry FAILED for %s - %s", r.config.ID, coin.PairID, reason)
+	}
+	return reason
+}
+
+func (r pumpHunterRules) entryReason(book *pumpHunterBook, coin *model.Coin, now time.Time) string {
+	config := r.config
+	risk := config.RiskManagement
+	rules := config.EntryRules
+
+	// 0. Risk Management Checks
+	// 0.2 Daily Loss Limit (the loss counter restarts 24h after the last loss)
+	if risk.DailyLossLimitIDR > 0 && book.DailyLoss >= risk.DailyLossLimitIDR && now.Sub(book.LastLossTime) <= 24*time.Hour {
+		return fmt.Sprintf("Daily loss limit reached (%.2f >= %.2f)", book.DailyLoss, risk.DailyLossLimitIDR)
+	}
+
+	// 0.3 Max Concurrent Positions (count: pending, buying, open - don't count: selling, closed)
+	if active := book.activeCount(); active >= risk.MaxConcurrentPositions {
+		return fmt.Sprintf("Max concurrent positions reached (%d >= %d) [PendingOrders=%d]",
+			active, risk.MaxConcurrentPositions, len(book.PendingOrders))
+	}
+
+	// 0.4 Cooldown after loss
+	if risk.CooldownAfterLossMinutes > 0 && !book.LastLossTime.IsZero() {
+		cooldown := time.Duration(risk.CooldownAfterLossMinutes) * time.Minute
+		if elapsed := now.Sub(book.LastLossTime); elapsed < cooldown {
+			return fmt.Sprintf("Still in cooldown period (%.0f minutes remaining)", (cooldown - elapsed).Minutes())
+		}
+	}
+
+	// 0.5 Excluded/Allowed Pairs
+	if len(rules.AllowedPairs) > 0 {
+		allowed := false
+		for _, p := range rules.AllowedPairs {
+			if p == coin.PairID {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return "Pair not in allowed list"
+		}
+	}
+	for _, p := range rules.ExcludedPairs {
+		if p == coin.PairID {
+			return "Pair excluded"
+		}
+	}
+
+	// 0.6 Already have position (check both open and pending)
+	for _, pos := range book.OpenPositions {
+		if pos.Pair == coin.PairID {
+			return "Already have open position"
+		}
+	}
+	for _, pos := range book.PendingOrders {
+		if pos.Pair == coin.PairID {
+			return "Already have pending order"
+		}
+	}
+
+	// 0.7 Stale market data (silent stream or pair not confirmed recently)
+	if coin.Stale {
+		return "Market data is stale"
+	}
+
+	// 1. Entry Rules
+	// 1.1 Pump Score
+	if signalPumpScore(config, coin) < rules.MinPumpScore {
+		return reasonPumpScoreTooLow
+	}
+
+	// 1.2 24h Volume
+	if coin.VolumeIDR < rules.Min24hVolumeIDR {
+		return fmt.Sprintf("Volume too low (%.2f < %.2f)", coin.VolumeIDR, rules.Min24hVolumeIDR)
+	}
+
+	// 1.3 Min Price
+	if coin.CurrentPrice < rules.MinPriceIDR {
+		return fmt.Sprintf("Price too low (%.2f < %.2f)", coin.CurrentPrice, rules.MinPriceIDR)
+	}
+
+	// 1.4 Indicator filters
+	if reason := r.indicatorEntryRejection(coin); reason != "" {
+		return reason
+	}
+
+	// 1.5 Order book filters
+	if reason := checkDepthEntry(config, coin, now); reason != "" {
+		return reason
+	}
+
+	// 1.6 Positive Timeframes (last check)
+	if positive := positiveTimeframes(config, coin); positive < rules.MinTimeframesPositive {
+		return fmt.Sprintf("Not enough positive timeframes (%d < %d)", positive, rules.MinTimeframesPositive)
+	}
+
+	return ""
+}
+
+// trackPrice updates the highest and lowest price of a position, returns whether a new ATH was set
+func (r pumpHunterRules) trackPrice(pos *model.Position, price float64) bool {
+	athUpdated := false
+	if price > pos.HighestPrice {
+		oldATH := pos.HighestPrice
+		pos.HighestPrice = price
+		pos.MinutesBelowATH = 0 // Reset counter on new ATH
+		athUpdated = true
+		r.log.Debugf("Bot %d: Position %s - New ATH: %.2f (was %.2f)",
+			r.config.ID, pos.Pair, price, oldATH)
+	} else if price >= pos.HighestPrice && pos.MinutesBelowATH > 0 {
+		// Price recovered to ATH (or above) - reset counter immediately
+		// This ensures we catch recovery in real-time (every 10s) not just at 1-minute checks
+		oldCounter := pos.MinutesBelowATH
+		pos.MinutesBelowATH = 0
+		r.log.Debugf("Bot %d: Position %s - Price recovered to ATH %.2f, resetting counter (was %d)",
+			r.config.ID, pos.Pair, pos.HighestPrice, oldCounter)
+	}
+	if price < pos.LowestPrice {
+		pos.LowestPrice = price
+	}
+	return athUpdated
+}
+
+// exitCheck tells the caller of exitSignal what changed on the position
+type exitCheck int
+
+const (
+	exitCheckSkipped exitCheck = iota // Less than a minute since the last check, position unchanged
+	exitCheckDone                     // Checked, tracking fields updated
+	exitCheckSignal                   // Exit signal raised, confirmed or cleared
+)
+
+// exitSignal checks the exit rules of a position once per minute. Every exit
+// but the ATH decline needs the same signal on two consecutive checks; the
+// confirmed exit reason is returned, empty while the position stays open.
+func (r pumpHunterRules) exitSignal(pos *model.Position, coin *model.Coin, now time.Time) (string, exitCheck) {
+	config := r.config.ExitRules
+	currentMinute := now.Minute()
+
+	// Check if 1 minute has passed since last check
+	timeSinceLastCheck := now.Sub(pos.LastPriceCheck)
+	if timeSinceLastCheck < 1*time.Minute {
+		// Not time to check yet, but return existing signal if confirmed
+		if pos.ExitConfirmCount >= 2 && pos.ExitSignalReason != "" {
+			return pos.ExitSignalReason, exitCheckSkipped // Already confirmed, sell
+		}
+		return "", exitCheckSkipped // Wait for next minute
+	}
+
+	// Update last check time
+	pos.LastPriceCheck = now
+	r.log.Debugf("Bot %d: Checking exit conditions for %s (target=%.2f%%, price=%.2f, ATH=%.2f, timeSinceLastCheck=%.0fs)",
+		r.config.ID, pos.Pair, config.TargetProfitPercent, coin.CurrentPrice, pos.HighestPrice, timeSinceLastCheck.Seconds())
+
+	profitPct := (coin.CurrentPrice - pos.EntryPrice) / pos.EntryPrice * 100
+
+	// Check all exit conditions (priority order)
+	var exitReason string
+
+	// 1. Stop Loss (highest priority)
+	if profitPct <= -config.StopLossPercent {
+		r.log.Debugf("Bot %d: Position %s - Stop loss triggered: profit %.2f%% <= -%.2f%% (entry: %.2f, current: %.2f)",
+			r.config.ID, pos.Pair, profitPct, config.StopLossPercent, pos.EntryPrice, coin.CurrentPrice)
+		exitReason = "stop_loss"
+	} else if config.MaxHoldMinutes > 0 && now.Sub(pos.EntryAt) > time.Duration(config.MaxHoldMinutes)*time.Minute {
+		// 2. Max Hold Time
+		exitReason = "max_hold_time"
+	} else if config.TargetProfitPercent > 1.0 && profitPct >= config.TargetProfitPercent {
+		// 3. Take Profit (for target > 1%, immediate limit order already placed, but check if filled)
+		// Limit order should already be placed, but if somehow not, this is a fallback
+		exitReason = "take_profit"
+	} else if config.TrailingStopEnabled && pos.HighestPrice > pos.EntryPrice {
+		// 4. Trailing Stop
+		dropPct := (pos.HighestPrice - coin.CurrentPrice) / pos.HighestPrice * 100
+		if dropPct >= config.TrailingStopPercent {
+			exitReason = "trailing_stop"
+		}
+	}
+
+	// Check pump score drop (only if no exit reason found yet)
+	if exitReason == "" && config.ExitOnPumpScoreDrop && signalPumpScore(r.config, coin) < config.PumpScoreDropThreshold {
+		// 5. Pump Score Drop
+		exitReason = "pump_score_drop"
+	}
+
+	// Check indicator exits (skipped while the indicators lack candles)
+	if exitReason == "" {
+		exitReason = r.indicatorExit(coin)
+	}
+
+	// Check ATH decline for target = 1% (always check if target = 1%, regardless of trailing stop)
+	if exitReason == "" && config.TargetProfitPercent == 1.0 {
+		// 6. ATH decline (for target = 1%)
+		r.log.Debugf("Bot %d: Checking ATH decline for %s (current=%.2f, ATH=%.2f, minutesBelowATH=%d)",
+			r.config.ID, pos.Pair, coin.CurrentPrice, pos.HighestPrice, pos.MinutesBelowATH)
+		// athDecline increments MinutesBelowATH and returns true only after 2 consecutive
+		// minutes below ATH, so the 2-minute confirmation below is not needed
+		if r.athDecline(pos, coin) {
+			return "ath_decline", exitCheckDone
+		}
+	}
+
+	// 2-minute confirmation logic
+	if exitReason != "" {
+		// First time seeing this exit signal
+		if pos.ExitSignalReason != exitReason {
+			pos.ExitSignalReason = exitReason
+			pos.ExitSignalMinute = currentMinute
+			pos.ExitConfirmCount = 1
+			r.log.Debugf("Bot %d: Exit signal detected: %s (1st confirmation, waiting for 2nd)",
+				r.config.ID, exitReason)
+			return "", exitCheckSignal // Wait for next minute confirmation
+		}
+
+		// Same signal, next minute
+		pos.ExitConfirmCount++
+		if pos.ExitConfirmCount >= 2 {
+			return exitReason, exitCheckSignal // Confirmed, sell now
+		}
+		r.log.Debugf("Bot %d: Exit signal %s confirmed %d/2",
+			r.config.ID, exitReason, pos.ExitConfirmCount)
+		return "", exitCheckSignal // Wait for 2nd confirmation
+	}
+
+	// No exit signal - reset confirmation counter
+	if pos.ExitSignalReason != "" {
+		r.log.Debugf("Bot %d: Exit signal %s cleared (price recovered)",
+			r.config.ID, pos.ExitSignalReason)
+		pos.ExitSignalReason = ""
+		pos.ExitConfirmCount = 0
+		return "", exitCheckSignal
+	}
+	return "", exitCheckDone
+}
+
+// athDecline checks if price has been below ATH for 2 consecutive minutes
+func (r pumpHunterRules) athDecline(pos *model.Position, coin *model.Coin) bool {
+	currentPrice := coin.CurrentPrice
+
+	// Update ATH
+	if currentPrice > pos.HighestPrice {
+		oldATH := pos.HighestPrice
+		pos.HighestPrice = currentPrice
+		pos.MinutesBelowATH = 0 // Reset counter
+		r.log.Debugf("Bot %d: Position %s - New ATH: %.2f (was %.2f), resetting below-ATH counter",
+			pos.BotConfigID, pos.Pair, currentPrice, oldATH)
+		return false // New ATH, wait
+	}
+
+	// Check if below ATH
+	if currentPrice < pos.HighestPrice {
+		pos.MinutesBelowATH++
+		dropPct := (pos.HighestPrice - currentPrice) / pos.HighestPrice * 100
+		// Return true only when we've had 2 consecutive minutes below ATH
+		if pos.MinutesBelowATH >= 2 {
+			r.log.Debugf("Bot %d: Position %s - ATH decline confirmed: %.2f consecutive minutes below ATH %.2f (current: %.2f, drop: %.2f%%)",
+				pos.BotConfigID, pos.Pair, float64(pos.MinutesBelowATH), pos.HighestPrice, currentPrice, dropPct)
+			return true // 2 consecutive minutes below ATH confirmed
+		}
+		r.log.Debugf("Bot %d: Position %s - Price below ATH: %.2f < %.2f (%.2f%% drop), minute %d/2",
+			pos.BotConfigID, pos.Pair, currentPrice, pos.HighestPrice, dropPct, pos.MinutesBelowATH)
+		return false // Wait for 2nd minute
+	}
+
+	// Price equals ATH - reset counter
+	if pos.MinutesBelowATH > 0 {
+		r.log.Debugf("Bot %d: Position %s - Price recovered to ATH: %.2f, resetting below-ATH counter",
+			pos.BotConfigID, pos.Pair, currentPrice)
+	}
+	pos.MinutesBelowATH = 0
+	return false
+}
+
+// indicators returns the indicators of a coin on the bot's indicator timeframe
+func (r pumpHunterRules) indicators(coin *model.Coin) model.Indicators {
+	return coin.Indicators[r.indicatorTF]
+}
+
+// indicatorEntryRejection returns why the indicator filters refuse an entry, empty when they pass
+func (r pumpHunterRules) indicatorEntryRejection(coin *model.Coin) string {
+	rules := r.config.EntryRules
+	if rules.MinRSI == 0 && rules.MaxRSI == 0 && !rules.RequireEMATrend && !rules.RequireAboveVWAP {
+		return ""
+	}
+
+	ind := r.indicators(coin)
+	if rules.MinRSI > 0 || rules.MaxRSI > 0 {
+		if ind.RSI14 == 0 {
+			return "RSI not available yet"
+		}
+		if rules.MinRSI > 0 && ind.RSI14 < rules.MinRSI {
+			return fmt.Sprintf("RSI too low (%.2f < %.2f)", ind.RSI14, rules.MinRSI)
+		}
+		if rules.MaxRSI > 0 && ind.RSI14 > rules.MaxRSI {
+			return fmt.Sprintf("RSI too high (%.2f > %.2f)", ind.RSI14, rules.MaxRSI)
+		}
+	}
+	if rules.RequireEMATrend {
+		if ind.EMA21 == 0 {
+			return "EMA not available yet"
+		}
+		if ind.EMA9 <= ind.EMA21 {
+			return fmt.Sprintf("EMA trend down (EMA9 %.2f <= EMA21 %.2f)", ind.EMA9, ind.EMA21)
+		}
+	}
+	if rules.RequireAboveVWAP {
+		if ind.VWAP == 0 {
+			return "VWAP not available yet"
+		}
+		if coin.CurrentPrice <= ind.VWAP {
+			return fmt.Sprintf("Price below VWAP (%.2f <= %.2f)", coin.CurrentPrice, ind.VWAP)
+		}
+	}
+	return ""
+}
+
+// indicatorExit returns the indicator exit reason of a position, empty when none triggers
+func (r pumpHunterRules) indicatorExit(coin *model.Coin) string {
+	rules := r.config.ExitRules
+	if rules == nil || (rules.ExitRSIAbove == 0 && !rules.ExitOnEMACrossDown) {
+		return ""
+	}
+
+	ind := r.indicators(coin)
+	if rules.ExitRSIAbove > 0 && ind.RSI14 > rules.ExitRSIAbove {
+		return "rsi_overbought"
+	}
+	if rules.ExitOnEMACrossDown && ind.EMA21 > 0 && ind.EMA9 < ind.EMA21 {
+		return "ema_cross_down"
+	}
+	return ""
+}
+
+// checkDepthEntry returns why the order book filters refuse an entry, empty when they pass
+func checkDepthEntry(config *model.BotConfig, coin *model.Coin, now time.Time) string {
+	rules := config.EntryRules
+	if rules.MinDepthImbalance == 0 && rules.MinBidDepthIDR == 0 && !rules.RejectAskWall {
+		return ""
+	}
+
+	depth := coin.Depth
+	if depth == nil {
+		return "Depth metrics not available yet"
+	}
+	if rules.MaxDepthAgeSeconds > 0 {
+		if age := now.Sub(depth.UpdatedAt); age > time.Duration(rules.MaxDepthAgeSeconds)*time.Second {
+			return fmt.Sprintf("Depth metrics too old (%s)", age.Round(time.Second))
+		}
+	}
+	if rules.MinDepthImbalance != 0 && depth.Imbalance < rules.MinDepthImbalance {
+		return fmt.Sprintf("Depth imbalance too low (%.2f < %.2f)", depth.Imbalance, rules.MinDepthImbalance)
+	}
+	if rules.MinBidDepthIDR > 0 && depth.BidDepthIDR < rules.MinBidDepthIDR {
+		return fmt.Sprintf("Bid depth too low (%.2f < %.2f)", depth.BidDepthIDR, rules.MinBidDepthIDR)
+	}
+	if rules.RejectAskWall && depth.AskWall != nil {
+		return fmt.Sprintf("Ask wall at %.2f (%.2f IDR)", depth.AskWall.Price, depth.AskWall.IDRVolume)
+	}
+	return ""
+}
+
+// signalPumpScore returns the pump score a bot trades on
+func signalPumpScore(config *model.BotConfig, coin *model.Coin) float64 {
+	if config.EntryRules != nil && config.EntryRules.ScoreProfile != "" {
+		return market.ProfileScore(coin, config.EntryRules.ScoreProfile)
+	}
+	if config.EntryRules != nil && config.EntryRules.UseRollingWindows {
+		return coin.RollingPumpScore
+	}
+	return coin.PumpScore
+}
+
+// positiveTimeframes counts the timeframes the price is up over, using the
+// sliding windows or the clock-reset buckets depending on the bot config
+func positiveTimeframes(config *model.BotConfig, coin *model.Coin) int {
+	count := 0
+	if config.EntryRules != nil && config.EntryRules.UseRollingWindows {
+		for _, w := range coin.Windows {
+			if w.Open > 0 && coin.CurrentPrice > w.Open {
+				count++
+			}
+		}
+		return count
+	}
+	for _, tf := range coin.Timeframes {
+		if tf != nil && tf.Open > 0 && coin.CurrentPrice > tf.Open {
+			count++
+		}
+	}
+	return count
+}
@@ -4,16 +4,15 @@ import (
 	"context"
 	"fmt"
 	"math"
-	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"tuyul/backend/internal/exchange"
 	"tuyul/backend/internal/model"
 	"tuyul/backend/internal/repository"
 	"tuyul/backend/internal/service/market"
 	"tuyul/backend/internal/util"
-	"tuyul/backend/pkg/indodax"
 	"tuyul/backend/pkg/logger"
 )
 
@@ -31,6 +30,7 @@ type PumpHunterInstance struct {
 	SignalBuffer  map[string]*PumpSignal
 	DailyLoss     float64
 	LastLossTime  time.Time
+	PausedUntil   time.Time // New entries paused until then after the exchange was unavailable
 	StopChan      chan struct{}
 	mu            sync.RWMutex
 	signalMu      sync.Mutex
@@ -44,7 +44,9 @@ type PumpHunterService struct {
 	marketDataService   *market.MarketDataService
 	orderMonitor        *OrderMonitor
 	notificationService *NotificationService
-	indodaxClient       *indodax.Client
+	deadmanService      *DeadmanService
+	paperExchange       *PaperExchange
+	exchange            exchange.Exchange
 	log                 *logger.Logger
 
 	instances map[int64]*PumpHunterInstance
@@ -59,7 +61,9 @@ func NewPumpHunterService(
 	marketDataService *market.MarketDataService,
 	orderMonitor *OrderMonitor,
 	notificationService *NotificationService,
-	indodaxClient *indodax.Client,
+	deadmanService *DeadmanService,
+	paperExchange *PaperExchange,
+	ex exchange.Exchange,
 ) *PumpHunterService {
 	s := &PumpHunterService{
 		botRepo:             botRepo,
@@ -69,7 +73,9 @@ func NewPumpHunterService(
 		marketDataService:   marketDataService,
 		orderMonitor:        orderMonitor,
 		notificationService: notificationService,
-		indodaxClient:       indodaxClient,
+		deadmanService:      deadmanService,
+		paperExchange:       paperExchange,
+		exchange:            ex,
 		log:                 logger.GetLogger(),
 		instances:           make(map[int64]*PumpHunterInstance),
 	}
@@ -113,20 +119,24 @@ func (s *PumpHunterService) StartBot(ctx context.Context, userID string, botID i
 
 	// 2. Setup Trade Client
 	if bot.IsPaperTrading {
-		inst.TradeClient = NewPaperTradeClient(bot.Balances, func(order *model.Order) {
-			s.handleOrderFilled(inst, order)
+		// Paper fills arrive like live order updates, the fee is taken from the IDR balance
+		inst.TradeClient = NewPaperTradeClient(s.paperExchange, userID, bot.Balances, func(update *exchange.OrderUpdate) {
+			s.chargePaperFee(inst, update.Fee)
+			s.handleOrderUpdate(userID, update)
 		})
 	} else {
-		key, err := s.apiKeyService.GetDecrypted(ctx, userID)
+		// Get the API key bound to the bot (bots without a binding use the default key)
+		key, err := s.apiKeyService.ResolveDecrypted(ctx, userID, bot.APIKeyID)
 		if err != nil {
 			return util.ErrBadRequest("Valid API key not found")
 		}
-		inst.TradeClient = NewLiveTradeClient(s.indodaxClient, key.Key, key.Secret)
+		bot.APIKeyID = &key.ID
+		inst.TradeClient = s.exchange.NewTrader(key.Key, key.Secret)
 
 		// Verify subscription exists - REQUIRED for live trading
 		// Subscription should be established on API boot or when API key is created
-		if !s.orderMonitor.IsSubscribed(userID) {
-			s.log.Errorf("User %s is not subscribed to order updates. Subscription is required for live trading.", userID)
+		if !s.orderMonitor.IsSubscribed(key.ID) {
+			s.log.Errorf("API key %d of user %s is not subscribed to order updates. Subscription is required for live trading.", key.ID, userID)
 			return fmt.Errorf("cannot start live bot: user is not subscribed to order updates. Please ensure your API key is configured correctly")
 		}
 	}
@@ -146,6 +156,13 @@ func (s *PumpHunterService) StartBot(ctx context.Context, userID string, botID i
 						s.cancelPendingOrder(inst, pos, "stale_on_restore")
 						continue
 					}
+					// Paper orders don't survive a restart, verify it is still on the paper exchange
+					if _, err := inst.TradeClient.GetOrder(ctx, pos.Pair, pos.EntryOrderID); util.IsOrderNotFoundError(err) {
+						s.log.Warnf("Bot %d: Pending paper order %s for position %d no longer exists, cancelling",
+							botID, pos.EntryOrderID, pos.ID)
+						s.cancelPendingOrder(inst, pos, "missing_on_restore")
+						continue
+					}
 					s.log.Debugf("Bot %d: Restored pending paper trading position %d (OrderID=%s)",
 						botID, pos.ID, pos.EntryOrderID)
 				} else {
@@ -166,8 +183,8 @@ func (s *PumpHunterService) StartBot(ctx context.Context, userID string, botID i
 						}
 
 						// Check order status
-						indodaxStatus := strings.ToLower(orderInfo.Status)
-						if indodaxStatus == "filled" || indodaxStatus == "done" {
+						orderStatus := orderInfo.Status
+						if orderStatus == exchange.OrderStatusFilled {
 							// Order was filled while API was down - process the fill
 							s.log.Infof("Bot %d: Pending order %s was filled while API was down, processing fill...",
 								botID, pos.EntryOrderID)
@@ -218,21 +235,21 @@ func (s *PumpHunterService) StartBot(ctx context.Context, userID string, botID i
 							// Process fill (this will move position from pending to open)
 							go s.handleOrderFilled(inst, filledOrder)
 							continue
-						} else if indodaxStatus == "cancelled" {
+						} else if orderStatus == exchange.OrderStatusCancelled {
 							// Order was cancelled externally
 							s.log.Warnf("Bot %d: Pending order %s was cancelled externally, cancelling position",
 								botID, pos.EntryOrderID)
 							// Cancel pending order (cancelPendingOrder handles its own mutex)
 							s.cancelPendingOrder(inst, pos, "order_cancelled_externally")
 							continue
-						} else if indodaxStatus == "open" {
+						} else if orderStatus == exchange.OrderStatusOpen || orderStatus == exchange.OrderStatusPartiallyFilled {
 							// Order still open - restore it
 							s.log.Debugf("Bot %d: Verified and restored pending order %s (status: %s)",
-								botID, pos.EntryOrderID, indodaxStatus)
+								botID, pos.EntryOrderID, orderStatus)
 						} else {
 							// Unknown status - cancel to be safe
 							s.log.Warnf("Bot %d: Pending order %s has unknown status '%s', cancelling position",
-								botID, pos.EntryOrderID, indodaxStatus)
+								botID, pos.EntryOrderID, orderStatus)
 							// Cancel pending order (cancelPendingOrder handles its own mutex)
 							s.cancelPendingOrder(inst, pos, "unknown_order_status")
 							continue
@@ -387,6 +404,22 @@ func (s *PumpHunterService) StartBot(ctx context.Context, userID string, botID i
 							s.notificationService.NotifyPositionUpdate(ctx, bot.UserID, pos)
 						}
 						inst.mu.Unlock()
+					} else if _, err := inst.TradeClient.GetOrder(ctx, pos.Pair, pos.ExitOrderID); util.IsOrderNotFoundError(err) {
+						// Paper orders don't survive a restart - place new one
+						s.log.Warnf("Bot %d: Sell order %s for position %d no longer exists on the paper exchange, placing new sell order",
+							botID, pos.ExitOrderID, pos.ID)
+						inst.mu.Lock()
+						targetProfit := bot.ExitRules.TargetProfitPercent
+						if targetProfit > 1.0 {
+							sellPrice := pos.EntryPrice * (1 + targetProfit/100)
+							s.placeLimitSellOrder(inst, pos, sellPrice)
+						} else {
+							pos.Status = model.PositionStatusOpen
+							s.posRepo.Update(ctx, pos)
+							s.log.Infof("Bot %d: Position %d changed back to 'open' for ATH monitoring", botID, pos.ID)
+							s.notificationService.NotifyPositionUpdate(ctx, bot.UserID, pos)
+						}
+						inst.mu.Unlock()
 					} else {
 						// Order exists and is still open - restore it
 						s.log.Debugf("Bot %d: Verified paper trading sell order %s for position %d (status: %s)",
@@ -413,26 +446,24 @@ func (s *PumpHunterService) StartBot(ctx context.Context, userID string, botID i
 						inst.mu.Unlock()
 					} else {
 						// Check order status from Indodax
-						indodaxStatus := strings.ToLower(orderInfo.Status)
-						if indodaxStatus == "filled" || indodaxStatus == "done" {
+						orderStatus := orderInfo.Status
+						if orderStatus == exchange.OrderStatusFilled {
 							// Order was filled - handle it
 							s.log.Infof("Bot %d: Sell order %s for position %d was already filled, handling completion",
 								botID, pos.ExitOrderID, pos.ID)
 							inst.mu.Lock()
-							price, _ := strconv.ParseFloat(orderInfo.Price, 64)
-							amount, _ := strconv.ParseFloat(orderInfo.OrderCoin, 64)
 							filledOrder := &model.Order{
 								OrderID: pos.ExitOrderID,
 								Side:    "sell",
 								Pair:    pos.Pair,
-								Price:   price,
-								Amount:  amount,
+								Price:   orderInfo.Price,
+								Amount:  orderInfo.Amount,
 								Status:  "filled",
 							}
 							inst.mu.Unlock()
 							go s.handleOrderFilled(inst, filledOrder)
 							continue
-						} else if indodaxStatus == "cancelled" {
+						} else if orderStatus == exchange.OrderStatusCancelled {
 							// Order was cancelled externally - place new one
 							s.log.Warnf("Bot %d: Sell order %s for position %d was cancelled externally, placing new sell order",
 								botID, pos.ExitOrderID, pos.ID)
@@ -448,14 +479,14 @@ func (s *PumpHunterService) StartBot(ctx context.Context, userID string, botID i
 								s.notificationService.NotifyPositionUpdate(ctx, bot.UserID, pos)
 							}
 							inst.mu.Unlock()
-						} else if indodaxStatus == "open" {
+						} else if orderStatus == exchange.OrderStatusOpen || orderStatus == exchange.OrderStatusPartiallyFilled {
 							// Order still open - restore it
 							s.log.Debugf("Bot %d: Verified and restored sell order %s for position %d (status: %s)",
-								botID, pos.ExitOrderID, pos.ID, indodaxStatus)
+								botID, pos.ExitOrderID, pos.ID, orderStatus)
 						} else {
 							// Unknown status - place new order to be safe
 							s.log.Warnf("Bot %d: Sell order %s for position %d has unknown status '%s', placing new sell order",
-								botID, pos.ExitOrderID, pos.ID, indodaxStatus)
+								botID, pos.ExitOrderID, pos.ID, orderStatus)
 							inst.mu.Lock()
 							targetProfit := bot.ExitRules.TargetProfitPercent
 							if targetProfit > 1.0 {
@@ -485,6 +516,9 @@ func (s *PumpHunterService) StartBot(ctx context.Context, userID string, botID i
 		botID, len(inst.OpenPositions), len(inst.PendingOrders))
 	go s.runBot(inst)
 
+	// Arm deadman switch for pairs with restored orders (async, we hold s.mu)
+	go s.syncDeadman(inst)
+
 	s.instances[botID] = inst
 	// 4. Initial balance sync (allocates IDR and ensures isolated state)
 	if err := s.syncBalance(ctx, inst); err != nil {
@@ -521,6 +555,10 @@ func (s *PumpHunterService) CreateBot(ctx context.Context, userID string, req *m
 		return nil, util.ErrBadRequest("Pump Hunter rules and risk management are required")
 	}
 
+	if err := s.validateIndicatorRules(req.EntryRules, req.ExitRules); err != nil {
+		return nil, err
+	}
+
 	// Validate RiskManagement fields
 	if req.RiskManagement.MaxPositionIDR <= 0 {
 		return nil, util.ErrBadRequest("max_position_idr must be greater than 0")
@@ -546,20 +584,12 @@ func (s *PumpHunterService) CreateBot(ctx context.Context, userID string, req *m
 	// 2. Separate logic for paper vs live
 	var apiKeyID *int64
 	if !req.IsPaperTrading {
-		// Verify user has a valid API key (single API key per user model)
-		_, err := s.apiKeyService.GetDecrypted(ctx, userID)
+		// Bind the requested API key, or the user's default key if none is given
+		key, err := s.apiKeyService.ResolveDecrypted(ctx, userID, req.APIKeyID)
 		if err != nil {
 			return nil, util.NewAppError(400, util.ErrCodeAPIKeyInvalid, "Valid API key is required for live trading. Please add your API key in Settings.")
 		}
-		// For backward compatibility, set apiKeyID to 1 if not provided
-		// In the future, we can remove apiKeyID entirely since we have one key per user
-		if req.APIKeyID != nil {
-			apiKeyID = req.APIKeyID
-		} else {
-			// Default to 1 for single API key per user
-			apiKeyIDValue := int64(1)
-			apiKeyID = &apiKeyIDValue
-		}
+		apiKeyID = &key.ID
 	}
 
 	// 3. Create bot config
@@ -573,6 +603,7 @@ func (s *PumpHunterService) CreateBot(ctx context.Context, userID string, req *m
 		EntryRules:        req.EntryRules,
 		ExitRules:         req.ExitRules,
 		RiskManagement:    req.RiskManagement,
+		Deadman:           req.Deadman,
 		InitialBalanceIDR: req.InitialBalanceIDR,
 		Balances:          make(map[string]float64),
 	}
@@ -664,7 +695,17 @@ func (s *PumpHunterService) UpdateBot(ctx context.Context, userID string, botID
 	// Update fields
 	bot.Name = req.Name
 	bot.IsPaperTrading = req.IsPaperTrading
-	bot.APIKeyID = req.APIKeyID
+	bot.APIKeyID = nil
+	if !req.IsPaperTrading {
+		key, err := s.apiKeyService.ResolveDecrypted(ctx, userID, req.APIKeyID)
+		if err != nil {
+			return nil, util.NewAppError(400, util.ErrCodeAPIKeyInvalid, "Valid API key is required for live trading. Please add your API key in Settings.")
+		}
+		bot.APIKeyID = &key.ID
+	}
+	if req.Deadman != nil {
+		bot.Deadman = req.Deadman
+	}
 
 	// Merge EntryRules (only update fields that are provided)
 	if req.EntryRules != nil {
@@ -689,6 +730,31 @@ func (s *PumpHunterService) UpdateBot(ctx context.Context, userID string, botID
 		if req.EntryRules.AllowedPairs != nil {
 			bot.EntryRules.AllowedPairs = req.EntryRules.AllowedPairs
 		}
+		bot.EntryRules.UseRollingWindows = req.EntryRules.UseRollingWindows
+		if req.EntryRules.ScoreProfile != "" {
+			bot.EntryRules.ScoreProfile = req.EntryRules.ScoreProfile
+		}
+		if req.EntryRules.IndicatorTimeframe != "" {
+			bot.EntryRules.IndicatorTimeframe = req.EntryRules.IndicatorTimeframe
+		}
+		if req.EntryRules.MinRSI > 0 {
+			bot.EntryRules.MinRSI = req.EntryRules.MinRSI
+		}
+		if req.EntryRules.MaxRSI > 0 {
+			bot.EntryRules.MaxRSI = req.EntryRules.MaxRSI
+		}
+		bot.EntryRules.RequireEMATrend = req.EntryRules.RequireEMATrend
+		bot.EntryRules.RequireAboveVWAP = req.EntryRules.RequireAboveVWAP
+		if req.EntryRules.MinDepthImbalance != 0 {
+			bot.EntryRules.MinDepthImbalance = req.EntryRules.MinDepthImbalance
+		}
+		if req.EntryRules.MinBidDepthIDR > 0 {
+			bot.EntryRules.MinBidDepthIDR = req.EntryRules.MinBidDepthIDR
+		}
+		bot.EntryRules.RejectAskWall = req.EntryRules.RejectAskWall
+		if req.EntryRules.MaxDepthAgeSeconds > 0 {
+			bot.EntryRules.MaxDepthAgeSeconds = req.EntryRules.MaxDepthAgeSeconds
+		}
 	}
 
 	// Merge ExitRules (only update fields that are provided)
@@ -713,6 +779,10 @@ func (s *PumpHunterService) UpdateBot(ctx context.Context, userID string, botID
 		if req.ExitRules.PumpScoreDropThreshold > 0 {
 			bot.ExitRules.PumpScoreDropThreshold = req.ExitRules.PumpScoreDropThreshold
 		}
+		if req.ExitRules.ExitRSIAbove > 0 {
+			bot.ExitRules.ExitRSIAbove = req.ExitRules.ExitRSIAbove
+		}
+		bot.ExitRules.ExitOnEMACrossDown = req.ExitRules.ExitOnEMACrossDown
 	}
 
 	// Merge RiskManagement (only update fields that are provided, and validate)
@@ -746,6 +816,10 @@ func (s *PumpHunterService) UpdateBot(ctx context.Context, userID string, botID
 		}
 	}
 
+	if err := s.validateIndicatorRules(bot.EntryRules, bot.ExitRules); err != nil {
+		return nil, err
+	}
+
 	if err := s.botRepo.Update(ctx, bot, ""); err != nil {
 		return nil, err
 	}
@@ -753,6 +827,41 @@ func (s *PumpHunterService) UpdateBot(ctx context.Context, userID string, botID
 	return bot, nil
 }
 
+// validateIndicatorRules checks the indicator filters of entry and exit rules
+func (s *PumpHunterService) validateIndicatorRules(entry *model.PumpHunterEntryRules, exit *model.PumpHunterExitRules) error {
+	if entry != nil {
+		if entry.ScoreProfile != "" && !s.marketDataService.HasScoreProfile(entry.ScoreProfile) {
+			return util.ErrBadRequest(fmt.Sprintf("score_profile %s does not exist", entry.ScoreProfile))
+		}
+		if entry.IndicatorTimeframe != "" && !s.marketDataService.HasTimeframe(entry.IndicatorTimeframe) {
+			return util.ErrBadRequest(fmt.Sprintf("indicator_timeframe %s is not a tracked timeframe", entry.IndicatorTimeframe))
+		}
+	}
+	return validateRuleRanges(entry, exit)
+}
+
+// validateRuleRanges checks the ranges of the indicator and order book filters
+func validateRuleRanges(entry *model.PumpHunterEntryRules, exit *model.PumpHunterExitRules) error {
+	if entry != nil {
+		if entry.MinRSI < 0 || entry.MinRSI > 100 || entry.MaxRSI < 0 || entry.MaxRSI > 100 {
+			return util.ErrBadRequest("min_rsi and max_rsi must be between 0 and 100")
+		}
+		if entry.MaxRSI > 0 && entry.MinRSI > entry.MaxRSI {
+			return util.ErrBadRequest("min_rsi must not be greater than max_rsi")
+		}
+		if entry.MinDepthImbalance < -1 || entry.MinDepthImbalance > 1 {
+			return util.ErrBadRequest("min_depth_imbalance must be between -1 and 1")
+		}
+		if entry.MinBidDepthIDR < 0 || entry.MaxDepthAgeSeconds < 0 {
+			return util.ErrBadRequest("min_bid_depth_idr and max_depth_age_seconds must not be negative")
+		}
+	}
+	if exit != nil && (exit.ExitRSIAbove < 0 || exit.ExitRSIAbove > 100) {
+		return util.ErrBadRequest("exit_rsi_above must be between 0 and 100")
+	}
+	return nil
+}
+
 // DeleteBot deletes a pump hunter bot
 func (s *PumpHunterService) DeleteBot(ctx context.Context, userID string, botID int64) error {
 	s.mu.Lock()
@@ -885,22 +994,41 @@ func (s *PumpHunterService) StopBot(ctx context.Context, userID string, botID in
 		}
 	}()
 
-	// Cancel orders asynchronously in background (don't block the response)
+	// Cancel orders asynchronously in background (don't block the response).
+	// The deadman switch stays armed until they are, and is left to expire if a cancel fails.
 	go func() {
 		cancelCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 		defer cancel()
 
+		failed := 0
+		defer func() {
+			if failed == 0 {
+				s.deadmanService.DisarmBot(userID, botID)
+			} else {
+				s.deadmanService.ExpireBot(userID, botID)
+			}
+		}()
+
 		// Cancel any open orders for this bot
 		positions, err := s.posRepo.ListByBot(cancelCtx, botID)
-		if err == nil {
+		if err != nil {
+			s.log.Warnf("Bot %d: Failed to list positions on stop: %v", botID, err)
+			failed++
+		} else {
 			for _, pos := range positions {
 				posOrders, err := s.orderRepo.ListByParentAndUser(cancelCtx, userID, "position", pos.ID, 0)
-				if err == nil {
+				if err != nil {
+					s.log.Warnf("Bot %d: Failed to list orders of position %d on stop: %v", botID, pos.ID, err)
+					failed++
+				} else {
 					for _, order := range posOrders {
 						if order.Status == "open" {
 							s.log.Infof("Bot %d: Cancelling open order %d (ID: %s) for position %d", botID, order.ID, order.OrderID, pos.ID)
 							if err := inst.TradeClient.CancelOrder(cancelCtx, order.Pair, order.OrderID, order.Side); err != nil {
 								s.log.Warnf("Bot %d: Failed to cancel order %d: indodax API error: %v", botID, order.ID, err)
+								if !util.IsOrderNotFoundError(err) {
+									failed++
+								}
 							} else {
 								s.orderRepo.UpdateStatus(cancelCtx, order.ID, "cancelled")
 								order.Status = "cancelled"
@@ -914,7 +1042,10 @@ func (s *PumpHunterService) StopBot(ctx context.Context, userID string, botID in
 
 		// Also check for any direct bot orders
 		botOrders, err := s.orderRepo.ListByParentAndUser(cancelCtx, userID, "bot", botID, 0)
-		if err == nil {
+		if err != nil {
+			s.log.Warnf("Bot %d: Failed to list open orders on stop: %v", botID, err)
+			failed++
+		} else {
 			for _, order := range botOrders {
 				if order.Status == "open" {
 					s.log.Infof("Bot %d: Cancelling open order %d (ID: %s)", botID, order.ID, order.OrderID)
@@ -924,6 +1055,7 @@ func (s *PumpHunterService) StopBot(ctx context.Context, userID string, botID in
 							s.log.Infof("Bot %d: Order %d (ID: %s) already filled/cancelled (not found)", botID, order.ID, order.OrderID)
 						} else {
 							s.log.Warnf("Bot %d: Failed to cancel order %d: indodax API error: %v", botID, order.ID, err)
+							failed++
 						}
 					} else {
 						s.orderRepo.UpdateStatus(cancelCtx, order.ID, "cancelled")
@@ -986,6 +1118,9 @@ func (s *PumpHunterService) stopBotWithError(botID int64, userID string, errorMs
 	}
 	s.mu.Unlock()
 
+	// Orders are not cancelled here, let the deadman switch cancel them on the exchange
+	s.deadmanService.ExpireBot(userID, botID)
+
 	// Notify via WebSocket
 	go func() {
 		notifyCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -1037,6 +1172,8 @@ func (s *PumpHunterService) runBot(inst *PumpHunterInstance) {
 		case <-pendingOrderTicker.C:
 			// Monitor pending orders for false pump detection and repositioning
 			s.monitorPendingOrders(inst)
+			// Keep deadman switch in sync with pairs that have orders
+			s.syncDeadman(inst)
 		case <-maxLossTicker.C:
 			// Periodic check for max loss limit
 			inst.mu.RLock()
@@ -1100,16 +1237,17 @@ func (s *PumpHunterService) bufferSignal(inst *PumpHunterInstance, coin *model.C
 
 	// Add or update signal in buffer
 	// If same coin exists, keep the one with higher score
+	score := signalPumpScore(inst.Config, coin)
 	if existing, ok := inst.SignalBuffer[coin.PairID]; ok {
-		if coin.PumpScore > existing.Score {
-			existing.Score = coin.PumpScore
+		if score > existing.Score {
+			existing.Score = score
 			existing.Coin = coin
 			existing.Timestamp = time.Now()
 		}
 	} else {
 		inst.SignalBuffer[coin.PairID] = &PumpSignal{
 			Coin:      coin,
-			Score:     coin.PumpScore,
+			Score:     score,
 			Timestamp: time.Now(),
 		}
 	}
@@ -1132,6 +1270,15 @@ func (s *PumpHunterService) processSignals(inst *PumpHunterInstance) {
 	inst.SignalBuffer = make(map[string]*PumpSignal)
 	inst.signalMu.Unlock()
 
+	// Signals are dropped while paused, they are stale by the time entries resume
+	inst.mu.RLock()
+	pausedUntil := inst.PausedUntil
+	inst.mu.RUnlock()
+	if time.Now().Before(pausedUntil) {
+		s.log.Debugf("Bot %d: Entries paused until %s, dropping %d signals", inst.Config.ID, pausedUntil.Format(time.RFC3339), len(signals))
+		return
+	}
+
 	// Sort signals by score descending (Priority Logic)
 	// Larger score = higher priority
 	for i := 0; i < len(signals); i++ {
@@ -1150,10 +1297,36 @@ func (s *PumpHunterService) processSignals(inst *PumpHunterInstance) {
 		if s.checkEntryConditions(inst, sig.Coin) {
 			s.log.Infof("Bot %d: Entry conditions PASSED for %s, opening position", inst.Config.ID, sig.Coin.PairID)
 			s.openPosition(inst, sig.Coin)
+			s.syncDeadman(inst)
 		}
 	}
 }
 
+// syncDeadman arms the deadman switch for every pair the bot holds positions/orders in (live only)
+func (s *PumpHunterService) syncDeadman(inst *PumpHunterInstance) {
+	enabled, countdown := inst.Config.DeadmanSettings()
+	if !enabled {
+		return
+	}
+
+	inst.mu.RLock()
+	pairSet := make(map[string]bool)
+	for _, pos := range inst.PendingOrders {
+		pairSet[pos.Pair] = true
+	}
+	for _, pos := range inst.OpenPositions {
+		pairSet[pos.Pair] = true
+	}
+	inst.mu.RUnlock()
+
+	pairs := make([]string, 0, len(pairSet))
+	for pair := range pairSet {
+		pairs = append(pairs, pair)
+	}
+
+	s.deadmanService.SyncBotPairs(context.Background(), inst.Config.UserID, inst.Config.BoundAPIKeyID(), inst.Config.ID, pairs, countdown)
+}
+
 func (s *PumpHunterService) checkEntryConditions(inst *PumpHunterInstance, coin *model.Coin) bool {
 	inst.mu.RLock()
 	defer inst.mu.RUnlock()
@@ -1166,11 +1339,10 @@ func (s *PumpHunterService) checkEntryConditions(inst *PumpHunterInstance, coin
 
 	// 0. Risk Management Checks
 	// 0.1 Circuit Breaker (Total Loss)
-	// For Pump Hunter, use DailyLossLimitIDR from RiskManagement (MaxLossIDR is 0 for Pump Hunter)
-	maxLossLimit := config.RiskManagement.DailyLossLimitIDR
-	if maxLossLimit > 0 && config.TotalProfitIDR <= -maxLossLimit {
+	rules := s.pumpHunterRules(config)
+	if rules.maxLossReached() {
 		s.log.Warnf("Bot %d reached total max loss limit (%.2f <= -%.2f), stopping bot",
-			config.ID, config.TotalProfitIDR, maxLossLimit)
+			config.ID, config.TotalProfitIDR, config.RiskManagement.DailyLossLimitIDR)
 		// Stop the bot asynchronously
 		ctx := context.Background()
 		go func() {
@@ -1181,128 +1353,21 @@ func (s *PumpHunterService) checkEntryConditions(inst *PumpHunterInstance, coin
 		return false
 	}
 
-	// 0.2 Daily Loss Limit
-	// For Pump Hunter, use DailyLossLimitIDR from RiskManagement
+	// 0.2 Daily Loss Limit - reset the counter on a new day
 	dailyLossLimit := config.RiskManagement.DailyLossLimitIDR
-	if dailyLossLimit > 0 && inst.DailyLoss >= dailyLossLimit {
-		// Check if we should reset daily loss (it's a new day)
-		if time.Since(inst.LastLossTime) > 24*time.Hour {
-			inst.DailyLoss = 0
-			s.log.Infof("Bot %d: Daily loss limit reset (new day)", config.ID)
-		} else {
-			s.log.Warnf("Bot %d: Daily loss limit reached (%.2f >= %.2f), skipping entry", config.ID, inst.DailyLoss, dailyLossLimit)
-			return false
-		}
+	if dailyLossLimit > 0 && inst.DailyLoss >= dailyLossLimit && time.Since(inst.LastLossTime) > 24*time.Hour {
+		inst.DailyLoss = 0
+		s.log.Infof("Bot %d: Daily loss limit reset (new day)", config.ID)
 	}
 
-	// 0.3 Max Concurrent Positions (count: pending, buying, open - don't count: selling, closed)
-	activeCount := 0
-	// Count pending orders (status: "pending")
-	activeCount += len(inst.PendingOrders)
-	// Count open positions with status "open" or "buying" (but NOT "selling" - those are exiting)
-	for _, pos := range inst.OpenPositions {
-		if pos.Status == model.PositionStatusOpen || pos.Status == model.PositionStatusBuying {
-			activeCount++
-		}
-		// Note: PositionStatusSelling is NOT counted - those positions are exiting, not active
+	// 0.2 - 1.6 Risk, pair and market rules
+	book := &pumpHunterBook{
+		DailyLoss:     inst.DailyLoss,
+		LastLossTime:  inst.LastLossTime,
+		OpenPositions: inst.OpenPositions,
+		PendingOrders: inst.PendingOrders,
 	}
-	if activeCount >= config.RiskManagement.MaxConcurrentPositions {
-		s.log.Debugf("Bot %d: Entry FAILED for %s - Max concurrent positions reached (%d >= %d) [PendingOrders=%d, OpenPositions(open/buying only)=%d]",
-			inst.Config.ID, coin.PairID, activeCount, config.RiskManagement.MaxConcurrentPositions,
-			len(inst.PendingOrders), func() int {
-				count := 0
-				for _, pos := range inst.OpenPositions {
-					if pos.Status == model.PositionStatusOpen || pos.Status == model.PositionStatusBuying {
-						count++
-					}
-				}
-				return count
-			}())
-		return false
-	}
-
-	// 0.4 Cooldown after loss
-	if config.RiskManagement.CooldownAfterLossMinutes > 0 && !inst.LastLossTime.IsZero() {
-		cooldownDuration := time.Duration(config.RiskManagement.CooldownAfterLossMinutes) * time.Minute
-		timeSinceLoss := time.Since(inst.LastLossTime)
-		if timeSinceLoss < cooldownDuration {
-			remaining := cooldownDuration - timeSinceLoss
-			s.log.Debugf("Bot %d: Entry FAILED for %s - Still in cooldown period (%.0f minutes remaining)",
-				inst.Config.ID, coin.PairID, remaining.Minutes())
-			return false
-		}
-	}
-
-	// 0.5 Excluded/Allowed Pairs
-	if len(config.EntryRules.AllowedPairs) > 0 {
-		allowed := false
-		for _, p := range config.EntryRules.AllowedPairs {
-			if p == coin.PairID {
-				allowed = true
-				break
-			}
-		}
-		if !allowed {
-			s.log.Debugf("Bot %d: Entry FAILED for %s - Pair not in allowed list", inst.Config.ID, coin.PairID)
-			return false
-		}
-	}
-	for _, p := range config.EntryRules.ExcludedPairs {
-		if p == coin.PairID {
-			return false
-		}
-	}
-
-	// 0.6 Already have position (check both open and pending)
-	for _, pos := range inst.OpenPositions {
-		if pos.Pair == coin.PairID {
-			s.log.Debugf("Bot %d: Entry FAILED for %s - Already have open position", inst.Config.ID, coin.PairID)
-			return false
-		}
-	}
-	// Also check pending orders
-	for _, pos := range inst.PendingOrders {
-		if pos.Pair == coin.PairID {
-			s.log.Debugf("Bot %d: Entry FAILED for %s - Already have pending order", inst.Config.ID, coin.PairID)
-			return false
-		}
-	}
-
-	// 1. Entry Rules
-	// 1.1 Pump Score
-	if coin.PumpScore < config.EntryRules.MinPumpScore {
-		// Silently fail - no log for pump score
-		return false
-	}
-
-	// 1.2 24h Volume
-	if coin.VolumeIDR < config.EntryRules.Min24hVolumeIDR {
-		s.log.Debugf("Bot %d: Entry FAILED for %s - Volume too low (%.2f < %.2f)", inst.Config.ID, coin.PairID, coin.VolumeIDR, config.EntryRules.Min24hVolumeIDR)
-		return false
-	}
-
-	// 1.3 Min Price
-	if coin.CurrentPrice < config.EntryRules.MinPriceIDR {
-		s.log.Debugf("Bot %d: Entry FAILED for %s - Price too low (%.2f < %.2f)", inst.Config.ID, coin.PairID, coin.CurrentPrice, config.EntryRules.MinPriceIDR)
-		return false
-	}
-
-	// 1.4 Positive Timeframes (last check)
-	positiveCount := 0
-	if coin.Timeframes.OneMinute.Open > 0 && coin.CurrentPrice > coin.Timeframes.OneMinute.Open {
-		positiveCount++
-	}
-	if coin.Timeframes.FiveMinute.Open > 0 && coin.CurrentPrice > coin.Timeframes.FiveMinute.Open {
-		positiveCount++
-	}
-	if coin.Timeframes.FifteenMin.Open > 0 && coin.CurrentPrice > coin.Timeframes.FifteenMin.Open {
-		positiveCount++
-	}
-	if coin.Timeframes.ThirtyMin.Open > 0 && coin.CurrentPrice > coin.Timeframes.ThirtyMin.Open {
-		positiveCount++
-	}
-	if positiveCount < config.EntryRules.MinTimeframesPositive {
-		s.log.Debugf("Bot %d: Entry FAILED for %s - Not enough positive timeframes (%d < %d)", inst.Config.ID, coin.PairID, positiveCount, config.EntryRules.MinTimeframesPositive)
+	if rules.entryRejection(book, coin, time.Now()) != "" {
 		return false
 	}
 
@@ -1375,8 +1440,8 @@ func (s *PumpHunterService) openPosition(inst *PumpHunterInstance, coin *model.C
 	// Get volume precision (using shared utility)
 	volumePrecision := util.GetVolumePrecision(pairInfo)
 
-	s.log.Debugf("Bot %d: Got pair info for %s - VolumePrecision=%d, TradeMinBaseCurrency=%d, TradeMinTradedCurrency=%.2f",
-		inst.Config.ID, coin.PairID, volumePrecision, pairInfo.TradeMinBaseCurrency, pairInfo.TradeMinTradedCurrency)
+	s.log.Debugf("Bot %d: Got pair info for %s - VolumePrecision=%d, MinQuoteAmount=%.0f, MinBaseAmount=%.8f",
+		inst.Config.ID, coin.PairID, volumePrecision, pairInfo.MinQuoteAmount, pairInfo.MinBaseAmount)
 
 	// Calculate available balance (using shared utility)
 	idrBalance := inst.Config.Balances["idr"]
@@ -1473,10 +1538,7 @@ func (s *PumpHunterService) openPosition(inst *PumpHunterInstance, coin *model.C
 	res, err := inst.TradeClient.Trade(ctx, "buy", coin.PairID, tradePrice, tradeAmount, orderType, clientOrderID)
 	if err != nil {
 		s.log.Errorf("Bot %d: Failed to open position on %s: indodax API error: %v", inst.Config.ID, coin.PairID, err)
-		// If critical trading error (API key or invalid pair) and live trading, stop the bot
-		if util.IsCriticalTradingError(err) && !inst.Config.IsPaperTrading {
-			s.stopBotWithError(inst.Config.ID, inst.Config.UserID, fmt.Sprintf("Trading error: %v", err))
-		}
+		s.handleAPIError(inst, err, "open position")
 		return
 	}
 
@@ -1485,7 +1547,7 @@ func (s *PumpHunterService) openPosition(inst *PumpHunterInstance, coin *model.C
 	orderIDStr := res.ClientOrderID
 	if orderIDStr == "" {
 		// Fallback to numeric ID (shouldn't happen)
-		orderIDStr = fmt.Sprintf("%d", res.OrderID)
+		orderIDStr = res.OrderID
 	}
 
 	s.log.Infof("Bot %d: Successfully placed BUY order for %s - OrderID=%s, Type=%s", inst.Config.ID, coin.PairID, orderIDStr, orderType)
@@ -1501,8 +1563,8 @@ func (s *PumpHunterService) openPosition(inst *PumpHunterInstance, coin *model.C
 		EntryAmountIDR:  sizeIDR,
 		EntryOrderID:    orderIDStr,
 		EntryOrderType:  orderType, // Track order type
-		EntryPumpScore:  coin.PumpScore,
-		EntryTrxCount1m: coin.Timeframes.OneMinute.Trx,
+		EntryPumpScore:  signalPumpScore(inst.Config, coin),
+		EntryTrxCount1m: coin.Timeframes.Get(s.marketDataService.ShortestTimeframe()).Trx,
 		EntryAt:         time.Now(),
 		OrderPlacedAt:   time.Now(), // Track when order was placed for false pump monitoring
 		HighestPrice:    buyPrice,   // Initialize ATH
@@ -1528,6 +1590,7 @@ func (s *PumpHunterService) openPosition(inst *PumpHunterInstance, coin *model.C
 		Price:        buyPrice,
 		Amount:       amount,
 		IsPaperTrade: inst.Config.IsPaperTrading,
+		APIKeyID:     inst.Config.BoundAPIKeyID(),
 	}
 
 	if err := s.orderRepo.Create(ctx, order); err != nil {
@@ -1592,25 +1655,7 @@ func (s *PumpHunterService) monitorExits(inst *PumpHunterInstance) {
 		}
 
 		// Update tracking
-		athUpdated := false
-		if coin.CurrentPrice > pos.HighestPrice {
-			oldATH := pos.HighestPrice
-			pos.HighestPrice = coin.CurrentPrice
-			pos.MinutesBelowATH = 0 // Reset counter on new ATH
-			athUpdated = true
-			s.log.Debugf("Bot %d: Position %s - New ATH: %.2f (was %.2f)",
-				inst.Config.ID, pos.Pair, coin.CurrentPrice, oldATH)
-		} else if coin.CurrentPrice >= pos.HighestPrice && pos.MinutesBelowATH > 0 {
-			// Price recovered to ATH (or above) - reset counter immediately
-			// This ensures we catch recovery in real-time (every 10s) not just at 1-minute checks
-			oldCounter := pos.MinutesBelowATH
-			pos.MinutesBelowATH = 0
-			s.log.Debugf("Bot %d: Position %s - Price recovered to ATH %.2f, resetting counter (was %d)",
-				inst.Config.ID, pos.Pair, pos.HighestPrice, oldCounter)
-		}
-		if coin.CurrentPrice < pos.LowestPrice {
-			pos.LowestPrice = coin.CurrentPrice
-		}
+		athUpdated := s.pumpHunterRules(inst.Config).trackPrice(pos, coin.CurrentPrice)
 
 		// Check conditions (this may update LastPriceCheck, HighestPrice, MinutesBelowATH, etc.)
 		reason := s.checkExitConditions(inst, pos, coin)
@@ -1629,165 +1674,26 @@ func (s *PumpHunterService) monitorExits(inst *PumpHunterInstance) {
 	}
 }
 
-func (s *PumpHunterService) checkExitConditions(inst *PumpHunterInstance, pos *model.Position, coin *model.Coin) string {
-	config := inst.Config.ExitRules
-	currentMinute := time.Now().Minute()
-
-	// Check if 1 minute has passed since last check
-	timeSinceLastCheck := time.Since(pos.LastPriceCheck)
-	if timeSinceLastCheck < 1*time.Minute {
-		// Not time to check yet, but return existing signal if confirmed
-		if pos.ExitConfirmCount >= 2 && pos.ExitSignalReason != "" {
-			return pos.ExitSignalReason // Already confirmed, sell
-		}
-		return "" // Wait for next minute
-	}
-
-	// Update last check time
-	pos.LastPriceCheck = time.Now()
-	s.log.Debugf("Bot %d: Checking exit conditions for %s (target=%.2f%%, price=%.2f, ATH=%.2f, timeSinceLastCheck=%.0fs)",
-		inst.Config.ID, pos.Pair, config.TargetProfitPercent, coin.CurrentPrice, pos.HighestPrice, timeSinceLastCheck.Seconds())
-	// Save LastPriceCheck update (will be saved again if exit signal detected, but ensure it's saved even if no signal)
-	ctx := context.Background()
-	s.posRepo.Update(ctx, pos)
+// pumpHunterRules returns the entry and exit rules of a bot
+func (s *PumpHunterService) pumpHunterRules(config *model.BotConfig) pumpHunterRules {
+	return newPumpHunterRules(config, s.marketDataService.ShortestTimeframe(), s.log)
+}
 
-	profitPct := (coin.CurrentPrice - pos.EntryPrice) / pos.EntryPrice * 100
-
-	// Check all exit conditions (priority order)
-	var exitReason string
-
-	// 1. Stop Loss (highest priority)
-	if profitPct <= -config.StopLossPercent {
-		s.log.Debugf("Bot %d: Position %s - Stop loss triggered: profit %.2f%% <= -%.2f%% (entry: %.2f, current: %.2f)",
-			inst.Config.ID, pos.Pair, profitPct, config.StopLossPercent, pos.EntryPrice, coin.CurrentPrice)
-		exitReason = "stop_loss"
-	} else if config.MaxHoldMinutes > 0 && time.Since(pos.EntryAt) > time.Duration(config.MaxHoldMinutes)*time.Minute {
-		// 2. Max Hold Time
-		exitReason = "max_hold_time"
-	} else if config.TargetProfitPercent > 1.0 && profitPct >= config.TargetProfitPercent {
-		// 3. Take Profit (for target > 1%, immediate limit order already placed, but check if filled)
-		// Limit order should already be placed, but if somehow not, this is a fallback
-		exitReason = "take_profit"
-	} else if config.TrailingStopEnabled && pos.HighestPrice > pos.EntryPrice {
-		// 4. Trailing Stop
-		dropPct := (pos.HighestPrice - coin.CurrentPrice) / pos.HighestPrice * 100
-		if dropPct >= config.TrailingStopPercent {
-			exitReason = "trailing_stop"
-		}
-	}
-
-	// Check pump score drop (only if no exit reason found yet)
-	if exitReason == "" && config.ExitOnPumpScoreDrop && coin.PumpScore < config.PumpScoreDropThreshold {
-		// 5. Pump Score Drop
-		exitReason = "pump_score_drop"
-	}
-
-	// Check ATH decline for target = 1% (always check if target = 1%, regardless of trailing stop)
-	if exitReason == "" && config.TargetProfitPercent == 1.0 {
-		// 6. ATH decline (for target = 1%)
-		s.log.Debugf("Bot %d: Checking ATH decline for %s (current=%.2f, ATH=%.2f, minutesBelowATH=%d)",
-			inst.Config.ID, pos.Pair, coin.CurrentPrice, pos.HighestPrice, pos.MinutesBelowATH)
-		// checkATHDecline increments MinutesBelowATH and returns true if price is below ATH
-		// We need 2 consecutive minutes below ATH - checkATHDecline already requires this (returns true only when >= 2)
-		// So when it returns true, we can immediately set exitReason and skip the 2-minute confirmation
-		if s.checkATHDecline(pos, coin) {
-			// checkATHDecline only returns true when MinutesBelowATH >= 2, so we've already confirmed 2 minutes
-			// Skip the 2-minute confirmation logic and sell immediately
-			s.log.Infof("Bot %d: Position %s - ATH decline confirmed: %.2f consecutive minutes below ATH %.2f (current: %.2f)",
-				inst.Config.ID, pos.Pair, float64(pos.MinutesBelowATH), pos.HighestPrice, coin.CurrentPrice)
-			return "ath_decline" // Already confirmed 2 minutes, sell immediately
-		}
-	}
-
-	// 2-minute confirmation logic
-	if exitReason != "" {
+// checkExitConditions runs the exit rules on a position, saving and broadcasting its tracking state
+func (s *PumpHunterService) checkExitConditions(inst *PumpHunterInstance, pos *model.Position, coin *model.Coin) string {
+	reason, check := s.pumpHunterRules(inst.Config).exitSignal(pos, coin, time.Now())
+	if check != exitCheckSkipped {
 		ctx := context.Background()
-		// First time seeing this exit signal
-		if pos.ExitSignalReason == "" || pos.ExitSignalReason != exitReason {
-			pos.ExitSignalReason = exitReason
-			pos.ExitSignalMinute = currentMinute
-			pos.ExitConfirmCount = 1
-			s.log.Debugf("Bot %d: Exit signal detected: %s (1st confirmation, waiting for 2nd)",
-				inst.Config.ID, exitReason)
-			s.posRepo.Update(ctx, pos) // Save state
-			// Broadcast exit signal detection
-			s.notificationService.NotifyPositionUpdate(ctx, inst.Config.UserID, pos)
-			return "" // Wait for next minute confirmation
-		}
-
-		// Same signal, same minute or next minute
-		if pos.ExitSignalReason == exitReason {
-			pos.ExitConfirmCount++
-			if pos.ExitConfirmCount >= 2 {
-				s.log.Infof("Bot %d: Exit signal confirmed (2nd): %s → SELLING",
-					inst.Config.ID, exitReason)
-				s.posRepo.Update(ctx, pos) // Save state
-				// Broadcast exit signal confirmation
-				s.notificationService.NotifyPositionUpdate(ctx, inst.Config.UserID, pos)
-				return exitReason // Confirmed, sell now
-			}
-			s.log.Debugf("Bot %d: Exit signal %s confirmed %d/2",
-				inst.Config.ID, exitReason, pos.ExitConfirmCount)
-			s.posRepo.Update(ctx, pos) // Save state
-			// Broadcast confirmation progress
-			s.notificationService.NotifyPositionUpdate(ctx, inst.Config.UserID, pos)
-			return "" // Wait for 2nd confirmation
-		}
-	} else {
-		// No exit signal - reset confirmation counter
-		if pos.ExitSignalReason != "" {
-			s.log.Debugf("Bot %d: Exit signal %s cleared (price recovered)",
-				inst.Config.ID, pos.ExitSignalReason)
-			pos.ExitSignalReason = ""
-			pos.ExitConfirmCount = 0
-			ctx := context.Background()
-			s.posRepo.Update(ctx, pos) // Save state
-			// Broadcast exit signal cleared
+		s.posRepo.Update(ctx, pos) // Save state
+		if check == exitCheckSignal {
+			// Broadcast exit signal detection, confirmation or clearing
 			s.notificationService.NotifyPositionUpdate(ctx, inst.Config.UserID, pos)
 		}
 	}
-
-	return ""
-}
-
-// checkATHDecline checks if price has been below ATH for 2 consecutive minutes
-func (s *PumpHunterService) checkATHDecline(pos *model.Position, coin *model.Coin) bool {
-	currentPrice := coin.CurrentPrice
-
-	// Update ATH
-	if currentPrice > pos.HighestPrice {
-		oldATH := pos.HighestPrice
-		pos.HighestPrice = currentPrice
-		pos.MinutesBelowATH = 0 // Reset counter
-		s.log.Debugf("Bot %d: Position %s - New ATH: %.2f (was %.2f), resetting below-ATH counter",
-			pos.BotConfigID, pos.Pair, currentPrice, oldATH)
-		// Note: ATH update broadcast is handled in monitorExits to avoid duplicate broadcasts
-		return false // New ATH, wait
-	}
-
-	// Check if below ATH
-	if currentPrice < pos.HighestPrice {
-		pos.MinutesBelowATH++
-		dropPct := (pos.HighestPrice - currentPrice) / pos.HighestPrice * 100
-		// Return true only when we've had 2 consecutive minutes below ATH
-		// This means checkExitConditions can skip the 2-minute confirmation for ATH decline
-		if pos.MinutesBelowATH >= 2 {
-			s.log.Debugf("Bot %d: Position %s - ATH decline confirmed: %.2f consecutive minutes below ATH %.2f (current: %.2f, drop: %.2f%%)",
-				pos.BotConfigID, pos.Pair, float64(pos.MinutesBelowATH), pos.HighestPrice, currentPrice, dropPct)
-			return true // 2 consecutive minutes below ATH confirmed
-		}
-		s.log.Debugf("Bot %d: Position %s - Price below ATH: %.2f < %.2f (%.2f%% drop), minute %d/2",
-			pos.BotConfigID, pos.Pair, currentPrice, pos.HighestPrice, dropPct, pos.MinutesBelowATH)
-		return false // Wait for 2nd minute
-	}
-
-	// Price equals ATH - reset counter
-	if pos.MinutesBelowATH > 0 {
-		s.log.Debugf("Bot %d: Position %s - Price recovered to ATH: %.2f, resetting below-ATH counter",
-			pos.BotConfigID, pos.Pair, currentPrice)
-	}
-	pos.MinutesBelowATH = 0
-	return false
+	if reason != "" {
+		s.log.Infof("Bot %d: Exit signal confirmed for %s: %s → SELLING", inst.Config.ID, pos.Pair, reason)
+	}
+	return reason
 }
 
 func (s *PumpHunterService) closePosition(inst *PumpHunterInstance, pos *model.Position, price float64, reason string) {
@@ -1803,10 +1709,8 @@ func (s *PumpHunterService) closePosition(inst *PumpHunterInstance, pos *model.P
 	res, err := inst.TradeClient.Trade(ctx, "sell", pos.Pair, tradePrice, pos.EntryQuantity, "market", clientOrderID)
 	if err != nil {
 		s.log.Errorf("Bot %d: Failed to close position on %s: indodax API error: %v", inst.Config.ID, pos.Pair, err)
-		// If critical trading error (API key or invalid pair) and live trading, stop the bot
-		if util.IsCriticalTradingError(err) && !inst.Config.IsPaperTrading {
-			s.stopBotWithError(inst.Config.ID, inst.Config.UserID, fmt.Sprintf("Trading error: %v", err))
-		}
+		// Position stays open, the exit is re-evaluated on the next monitor tick
+		s.handleAPIError(inst, err, "close position")
 		return
 	}
 
@@ -1816,11 +1720,11 @@ func (s *PumpHunterService) closePosition(inst *PumpHunterInstance, pos *model.P
 	orderIDStr := res.ClientOrderID
 	if orderIDStr == "" {
 		// Fallback to numeric ID if ClientOrderID is empty (shouldn't happen)
-		orderIDStr = fmt.Sprintf("%d", res.OrderID)
+		orderIDStr = res.OrderID
 		s.log.Warnf("Bot %d: ClientOrderID is empty for sell order, using numeric ID %s", inst.Config.ID, orderIDStr)
 	} else {
 		// Log both for debugging market order matching issues
-		s.log.Debugf("Bot %d: Stored ExitOrderID=%s (ClientOrderID from Trade response), Numeric OrderID=%d",
+		s.log.Debugf("Bot %d: Stored ExitOrderID=%s (ClientOrderID from Trade response), Numeric OrderID=%s",
 			inst.Config.ID, orderIDStr, res.OrderID)
 	}
 
@@ -1840,6 +1744,7 @@ func (s *PumpHunterService) closePosition(inst *PumpHunterInstance, pos *model.P
 		Price:        price,
 		Amount:       pos.EntryQuantity,
 		IsPaperTrade: inst.Config.IsPaperTrading,
+		APIKeyID:     inst.Config.BoundAPIKeyID(),
 	}
 
 	if err := s.orderRepo.Create(ctx, order); err != nil {
@@ -1870,7 +1775,10 @@ func (s *PumpHunterService) handleOrderFilled(inst *PumpHunterInstance, order *m
 		order.ID = dbOrder.ID
 		order.Pair = dbOrder.Pair
 		order.Side = dbOrder.Side
-		order.Price = dbOrder.Price
+		if order.Price == 0 {
+			// Keep the executed price when the update reports one
+			order.Price = dbOrder.Price
+		}
 		order.Amount = dbOrder.Amount
 		s.log.Debugf("Bot %d: Found order in database - ID=%d, OrderID=%s", inst.Config.ID, order.ID, order.OrderID)
 	}
@@ -2109,17 +2017,15 @@ func (s *PumpHunterService) finalizePositionClose(inst *PumpHunterInstance, pos
 	})
 }
 
-func (s *PumpHunterService) handleOrderUpdate(userID string, order *indodax.OrderUpdate) {
-	status := strings.ToLower(order.Status)
-
+func (s *PumpHunterService) handleOrderUpdate(userID string, order *exchange.OrderUpdate) {
 	// Handle CANCELLED orders - remove from pending orders and clean up
-	if status == "cancelled" || status == "canceled" {
+	if order.Status == exchange.OrderStatusCancelled {
 		s.handleCancelledOrder(userID, order)
 		return
 	}
 
-	// Indodax sends "FILL" or "DONE" for filled orders
-	if status != "filled" && status != "fill" && status != "done" {
+	// Partial and complete fills (Indodax "FILL" / "DONE")
+	if !order.IsFill() {
 		return
 	}
 
@@ -2158,16 +2064,14 @@ func (s *PumpHunterService) handleOrderUpdate(userID string, order *indodax.Orde
 						order.OrderID, order.ClientOrderID, pos.ID, order.Side, pos.EntryOrderID)
 					inst.mu.RUnlock()
 
-					// Convert indodax.OrderUpdate to model.Order for handleOrderFilled
-					price, _ := strconv.ParseFloat(order.Price, 64)
-					amount, _ := strconv.ParseFloat(order.ExecutedQty, 64)
+					// Convert exchange.OrderUpdate to model.Order for handleOrderFilled
 					fillTime := time.Unix(order.TransactionTime/1000, 0)
 
 					mOrder := &model.Order{
 						OrderID:      order.ClientOrderID, // Store ClientOrderID for matching
 						Side:         order.Side,
-						Price:        price,
-						Amount:       amount,
+						Price:        order.Price,
+						Amount:       order.ExecutedQty,
 						Status:       "filled",
 						FilledAt:     &fillTime,
 						IsPaperTrade: inst.Config.IsPaperTrading,
@@ -2205,7 +2109,7 @@ func (s *PumpHunterService) handleOrderUpdate(userID string, order *indodax.Orde
 
 				// If this is a BUY order matching EntryOrderID, it's likely a duplicate DONE update
 				// (the FILL update already moved it from PendingOrders to OpenPositions)
-				if matched && order.Side == "BUY" && (pos.EntryOrderID == order.OrderID || pos.EntryOrderID == order.ClientOrderID) {
+				if matched && order.Side == "buy" && (pos.EntryOrderID == order.OrderID || pos.EntryOrderID == order.ClientOrderID) {
 					s.log.Debugf("[WS_ORDER_UPDATE] PumpHunter: Buy order %s (ClientOrderID: %s) already processed - position %d is now open (duplicate DONE update)",
 						order.OrderID, order.ClientOrderID, pos.ID)
 					// Still process it to ensure order status is updated, but it won't change position state
@@ -2216,16 +2120,14 @@ func (s *PumpHunterService) handleOrderUpdate(userID string, order *indodax.Orde
 						order.OrderID, order.ClientOrderID, pos.ID, order.Side, pos.ExitOrderID)
 					inst.mu.RUnlock()
 
-					// Convert indodax.OrderUpdate to model.Order for handleOrderFilled
-					price, _ := strconv.ParseFloat(order.Price, 64)
-					amount, _ := strconv.ParseFloat(order.ExecutedQty, 64)
+					// Convert exchange.OrderUpdate to model.Order for handleOrderFilled
 					fillTime := time.Unix(order.TransactionTime/1000, 0)
 
 					mOrder := &model.Order{
 						OrderID:      order.ClientOrderID, // Store ClientOrderID for matching
 						Side:         order.Side,
-						Price:        price,
-						Amount:       amount,
+						Price:        order.Price,
+						Amount:       order.ExecutedQty,
 						Status:       "filled",
 						FilledAt:     &fillTime,
 						IsPaperTrade: inst.Config.IsPaperTrading,
@@ -2253,7 +2155,7 @@ func (s *PumpHunterService) handleOrderUpdate(userID string, order *indodax.Orde
 	}
 }
 
-func (s *PumpHunterService) handleCancelledOrder(userID string, order *indodax.OrderUpdate) {
+func (s *PumpHunterService) handleCancelledOrder(userID string, order *exchange.OrderUpdate) {
 	wsClientOrderID := order.ClientOrderID
 
 	s.log.Infof("[WS_ORDER_UPDATE] PumpHunter: Received CANCELLED order update - OrderID=%s, ClientOrderID=%s",
@@ -2276,7 +2178,22 @@ func (s *PumpHunterService) handleCancelledOrder(userID string, order *indodax.O
 	s.log.Debugf("[WS_ORDER_UPDATE] PumpHunter: Cancelled order %s processed", wsClientOrderID)
 }
 
-func (s *PumpHunterService) getTickSize(pair indodax.Pair) float64 {
+// chargePaperFee takes the exchange fee of a paper fill from the bot's IDR balance
+func (s *PumpHunterService) chargePaperFee(inst *PumpHunterInstance, fee float64) {
+	if fee <= 0 {
+		return
+	}
+
+	inst.mu.Lock()
+	defer inst.mu.Unlock()
+
+	inst.Config.Balances["idr"] -= fee
+	if err := s.botRepo.UpdateBalance(context.Background(), inst.Config.ID, inst.Config.Balances); err != nil {
+		s.log.Warnf("Bot %d: Failed to save balance after paper fee: %v", inst.Config.ID, err)
+	}
+}
+
+func (s *PumpHunterService) getTickSize(pair exchange.Pair) float64 {
 	val, ok := s.marketDataService.GetPriceIncrement(pair.ID)
 	if ok {
 		return val
@@ -2287,7 +2204,7 @@ func (s *PumpHunterService) getTickSize(pair indodax.Pair) float64 {
 
 // calculateBuyPrice calculates buy price with gap check
 // Returns: (price, orderType) where orderType is "market" or "limit"
-func (s *PumpHunterService) calculateBuyPrice(coin *model.Coin, pairInfo indodax.Pair) (float64, string) {
+func (s *PumpHunterService) calculateBuyPrice(coin *model.Coin, pairInfo exchange.Pair) (float64, string) {
 	bestBid := coin.BestBid
 	bestAsk := coin.BestAsk
 
@@ -2362,12 +2279,12 @@ func (s *PumpHunterService) monitorPendingOrders(inst *PumpHunterInstance) {
 		}
 
 		s.log.Debugf("Bot %d: Position %d (%s) - Checking false pump: PumpScore=%.2f, MinPumpScore=%.2f, TimeSinceOrder=%.1fs",
-			inst.Config.ID, pos.ID, pos.Pair, coin.PumpScore, inst.Config.EntryRules.MinPumpScore, timeSinceOrder.Seconds())
+			inst.Config.ID, pos.ID, pos.Pair, signalPumpScore(inst.Config, coin), inst.Config.EntryRules.MinPumpScore, timeSinceOrder.Seconds())
 
 		// Check 1: False pump detection (always check)
-		if coin.PumpScore < inst.Config.EntryRules.MinPumpScore {
+		if signalPumpScore(inst.Config, coin) < inst.Config.EntryRules.MinPumpScore {
 			s.log.Infof("Bot %d: Position %d (%s) - FALSE PUMP DETECTED! PumpScore=%.2f < MinPumpScore=%.2f, cancelling order",
-				inst.Config.ID, pos.ID, pos.Pair, coin.PumpScore, inst.Config.EntryRules.MinPumpScore)
+				inst.Config.ID, pos.ID, pos.Pair, signalPumpScore(inst.Config, coin), inst.Config.EntryRules.MinPumpScore)
 			s.cancelPendingOrder(inst, pos, "false_pump")
 			continue
 		}
@@ -2391,7 +2308,7 @@ func (s *PumpHunterService) monitorPendingOrders(inst *PumpHunterInstance) {
 		// Check 3: Time-based checks (after 2 minutes)
 		if timeSinceOrder > 2*time.Minute {
 			// 2 minutes passed - check if still valid
-			if coin.PumpScore < inst.Config.EntryRules.MinPumpScore {
+			if signalPumpScore(inst.Config, coin) < inst.Config.EntryRules.MinPumpScore {
 				s.cancelPendingOrder(inst, pos, "false_pump_timeout")
 			}
 			// Otherwise, continue monitoring (pump still valid)
@@ -2438,7 +2355,7 @@ func (s *PumpHunterService) repositionPendingOrder(
 	orderIDStr := res.ClientOrderID
 	if orderIDStr == "" {
 		// Fallback to numeric ID if ClientOrderID is empty (shouldn't happen)
-		orderIDStr = fmt.Sprintf("%d", res.OrderID)
+		orderIDStr = res.OrderID
 		s.log.Warnf("Bot %d: ClientOrderID is empty during reposition, using numeric ID %s", inst.Config.ID, orderIDStr)
 	}
 	pos.EntryOrderID = orderIDStr
@@ -2469,6 +2386,7 @@ func (s *PumpHunterService) repositionPendingOrder(
 				Price:        newPrice,
 				Amount:       pos.EntryQuantity,
 				IsPaperTrade: inst.Config.IsPaperTrading,
+				APIKeyID:     inst.Config.BoundAPIKeyID(),
 			}
 			s.orderRepo.Create(ctx, order)
 			pos.InternalEntryOrderID = order.ID
@@ -2486,6 +2404,7 @@ func (s *PumpHunterService) repositionPendingOrder(
 			Price:        newPrice,
 			Amount:       pos.EntryQuantity,
 			IsPaperTrade: inst.Config.IsPaperTrading,
+			APIKeyID:     inst.Config.BoundAPIKeyID(),
 		}
 		s.orderRepo.Create(ctx, order)
 		pos.InternalEntryOrderID = order.ID
@@ -2556,9 +2475,9 @@ func (s *PumpHunterService) placeLimitSellOrder(inst *PumpHunterInstance, pos *m
 	}
 
 	// Validate amount meets minimum trade requirement
-	if amount < pairInfo.TradeMinTradedCurrency {
+	if amount < pairInfo.MinBaseAmount {
 		s.log.Errorf("Bot %d: Amount %.8f is below minimum trade requirement %.8f for %s",
-			inst.Config.ID, amount, pairInfo.TradeMinTradedCurrency, pos.Pair)
+			inst.Config.ID, amount, pairInfo.MinBaseAmount, pos.Pair)
 		return
 	}
 
@@ -2575,10 +2494,9 @@ func (s *PumpHunterService) placeLimitSellOrder(inst *PumpHunterInstance, pos *m
 		s.log.Errorf("Bot %d: Failed to place limit sell order for position %d (%s): indodax API error: %v",
 			inst.Config.ID, pos.ID, pos.Pair, err)
 
-		errStr := strings.ToLower(err.Error())
 		// Check if error is "Insufficient balance" - this means position can't be sold
 		// (likely already sold or balance changed). Close the position.
-		if strings.Contains(errStr, "insufficient balance") {
+		if exchange.IsKind(err, exchange.ErrorKindInsufficientBalance) {
 			s.log.Warnf("Bot %d: Insufficient balance when placing sell order for position %d (%s) - closing position",
 				inst.Config.ID, pos.ID, pos.Pair)
 
@@ -2661,7 +2579,7 @@ func (s *PumpHunterService) placeLimitSellOrder(inst *PumpHunterInstance, pos *m
 	orderIDStr := res.ClientOrderID
 	if orderIDStr == "" {
 		// Fallback to numeric ID (shouldn't happen)
-		orderIDStr = fmt.Sprintf("%d", res.OrderID)
+		orderIDStr = res.OrderID
 	}
 
 	// Update position status
@@ -2680,6 +2598,7 @@ func (s *PumpHunterService) placeLimitSellOrder(inst *PumpHunterInstance, pos *m
 		Price:        sellPrice,
 		Amount:       amount, // Use rounded amount, not pos.EntryQuantity
 		IsPaperTrade: inst.Config.IsPaperTrading,
+		APIKeyID:     inst.Config.BoundAPIKeyID(),
 	}
 
 	s.orderRepo.Create(ctx, order)
@@ -2693,6 +2612,68 @@ func (s *PumpHunterService) placeLimitSellOrder(inst *PumpHunterInstance, pos *m
 	s.log.Infof("Bot %d: Placed limit sell order for %s at %.2f (target profit)", inst.Config.ID, pos.Pair, sellPrice)
 }
 
+// handleAPIError applies the recovery for a failed Trade call (caller holds inst.mu)
+func (s *PumpHunterService) handleAPIError(inst *PumpHunterInstance, err error, operation string) {
+	switch exchange.KindOf(err) {
+	case exchange.ErrorKindRateLimited, exchange.ErrorKindInvalidNonce, exchange.ErrorKindNetwork:
+		inst.PausedUntil = time.Now().Add(networkErrorPause)
+		s.log.Warnf("Bot %d: Transient error during %s - pausing entries for %s", inst.Config.ID, operation, networkErrorPause)
+		return
+
+	case exchange.ErrorKindMaintenance:
+		inst.PausedUntil = time.Now().Add(maintenancePause)
+		s.log.Warnf("Bot %d: Exchange under maintenance during %s - pausing entries for %s", inst.Config.ID, operation, maintenancePause)
+		return
+
+	case exchange.ErrorKindInsufficientBalance:
+		// Virtual balance drifted from the exchange, lower the IDR allocation.
+		// Async: callers hold inst.mu, which reconcileIDR takes.
+		if !inst.Config.IsPaperTrading {
+			go s.reconcileIDR(inst)
+		}
+		return
+
+	case exchange.ErrorKindOrderBelowMinimum:
+		// Depends on the pair and price, other signals may still be tradable
+		s.log.Warnf("Bot %d: Order below exchange minimum during %s, skipping", inst.Config.ID, operation)
+		return
+	}
+
+	// If critical trading error (API key or invalid pair) and live trading, stop the bot
+	if util.IsCriticalTradingError(err) && !inst.Config.IsPaperTrading {
+		s.stopBotWithError(inst.Config.ID, inst.Config.UserID, fmt.Sprintf("Trading error: %v", err))
+	}
+}
+
+// reconcileIDR lowers the bot's IDR balance to the IDR available on the exchange after an
+// order was rejected for insufficient balance. It never raises it, so capital tied up in
+// positions stays accounted for.
+func (s *PumpHunterService) reconcileIDR(inst *PumpHunterInstance) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	info, err := inst.TradeClient.GetInfo(ctx)
+	if err != nil {
+		s.log.Warnf("Bot %d: Failed to fetch live balance for resync: %v", inst.Config.ID, err)
+		return
+	}
+	available := info.Balances["idr"]
+
+	inst.mu.Lock()
+	defer inst.mu.Unlock()
+
+	current := inst.Config.Balances["idr"]
+	if current <= available {
+		return
+	}
+	inst.Config.Balances["idr"] = available
+	s.log.Warnf("Bot %d: IDR balance resynced to exchange: %.2f -> %.2f", inst.Config.ID, current, available)
+
+	if err := s.botRepo.UpdateBalance(ctx, inst.Config.ID, inst.Config.Balances); err != nil {
+		s.log.Warnf("Bot %d: Failed to save resynced balance: %v", inst.Config.ID, err)
+	}
+}
+
 func (s *PumpHunterService) syncBalance(ctx context.Context, inst *PumpHunterInstance) error {
 	var realIDR float64
 	if !inst.Config.IsPaperTrading {
@@ -2705,7 +2686,7 @@ func (s *PumpHunterService) syncBalance(ctx context.Context, inst *PumpHunterIns
 			}
 			return fmt.Errorf("failed to fetch live balance: %w", err)
 		}
-		realIDR, _ = strconv.ParseFloat(info.Balance["idr"].String(), 64)
+		realIDR = info.Balances["idr"]
 	} else {
 		// For paper trading, we just use what's in our virtual balance or initial
 		if v, ok := inst.Config.Balances["idr"]; ok {
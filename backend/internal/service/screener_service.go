@@ -0,0 +1,260 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"tuyul/backend/internal/model"
+	"tuyul/backend/internal/repository"
+	"tuyul/backend/internal/service/market"
+	"tuyul/backend/internal/service/market/screener"
+	"tuyul/backend/internal/util"
+	"tuyul/backend/pkg/logger"
+)
+
+// Saved screens allowed per user
+const maxScreensPerUser = 20
+
+// ScreenerService runs filter expressions over the market and streams saved
+// screens to their owners as coins enter and leave them
+type ScreenerService struct {
+	repo                *repository.ScreenRepository
+	marketService       *market.MarketDataService
+	notificationService *NotificationService
+	log                 *logger.Logger
+
+	// Streamed screens, key: screen ID
+	streams map[int64]*screenStream
+	mu      sync.Mutex
+}
+
+// screenStream tracks the pairs currently matching a streamed screen
+type screenStream struct {
+	screen  *model.Screen
+	filter  *screener.Filter
+	matches map[string]bool
+}
+
+func NewScreenerService(repo *repository.ScreenRepository, marketService *market.MarketDataService, notificationService *NotificationService) *ScreenerService {
+	s := &ScreenerService{
+		repo:                repo,
+		marketService:       marketService,
+		notificationService: notificationService,
+		log:                 logger.GetLogger(),
+		streams:             make(map[int64]*screenStream),
+	}
+	marketService.OnUpdate(s.handleCoinUpdate)
+	return s
+}
+
+// LoadStreams starts streaming the saved screens that have streaming enabled
+func (s *ScreenerService) LoadStreams(ctx context.Context) error {
+	screens, err := s.repo.ListStreaming(ctx)
+	if err != nil {
+		return err
+	}
+	for _, screen := range screens {
+		filter, err := screener.ParseFilter(screen.Filter)
+		if err != nil {
+			s.log.Warnf("Screen %d: invalid filter, not streaming: %v", screen.ID, err)
+			continue
+		}
+		s.setStream(screen, filter)
+	}
+	s.log.Infof("Streaming %d saved screens", len(screens))
+	return nil
+}
+
+// Run returns the coins matching a filter, sorted and limited (0 returns all)
+func (s *ScreenerService) Run(filterExpr, sortExpr string, limit int) ([]*model.Coin, error) {
+	filter, sorter, err := parseScreen(filterExpr, sortExpr)
+	if err != nil {
+		return nil, err
+	}
+
+	matches := make([]*model.Coin, 0)
+	for _, coin := range s.marketService.Coins() {
+		if filter.Match(coin) {
+			matches = append(matches, coin)
+		}
+	}
+	sorter.Apply(matches)
+
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches, nil
+}
+
+// parseScreen parses the filter and sort of a screen
+func parseScreen(filterExpr, sortExpr string) (*screener.Filter, *screener.Sort, error) {
+	filter, err := screener.ParseFilter(filterExpr)
+	if err != nil {
+		return nil, nil, util.ErrBadRequest(fmt.Sprintf("filter: %v", err))
+	}
+	sorter, err := screener.ParseSort(sortExpr)
+	if err != nil {
+		return nil, nil, util.ErrBadRequest(fmt.Sprintf("sort: %v", err))
+	}
+	return filter, sorter, nil
+}
+
+// CreateScreen saves a screen for a user
+func (s *ScreenerService) CreateScreen(ctx context.Context, userID string, req *model.ScreenRequest) (*model.Screen, error) {
+	filter, _, err := parseScreen(req.Filter, req.Sort)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.repo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) >= maxScreensPerUser {
+		return nil, util.ErrBadRequest(fmt.Sprintf("A user can save at most %d screens", maxScreensPerUser))
+	}
+
+	screen := &model.Screen{
+		UserID: userID,
+		Name:   req.Name,
+		Filter: req.Filter,
+		Sort:   req.Sort,
+		Limit:  req.Limit,
+		Stream: req.Stream,
+	}
+	if err := s.repo.Create(ctx, screen); err != nil {
+		return nil, err
+	}
+	s.setStream(screen, filter)
+	return screen, nil
+}
+
+// ListScreens returns the saved screens of a user
+func (s *ScreenerService) ListScreens(ctx context.Context, userID string) ([]*model.Screen, error) {
+	return s.repo.ListByUser(ctx, userID)
+}
+
+// GetScreen returns a saved screen owned by the user
+func (s *ScreenerService) GetScreen(ctx context.Context, userID string, screenID int64) (*model.Screen, error) {
+	screen, err := s.repo.GetByID(ctx, screenID)
+	if err != nil {
+		return nil, util.ErrNotFound("Screen not found")
+	}
+	if screen.UserID != userID {
+		return nil, util.ErrForbidden("Access denied")
+	}
+	return screen, nil
+}
+
+// UpdateScreen replaces a saved screen
+func (s *ScreenerService) UpdateScreen(ctx context.Context, userID string, screenID int64, req *model.ScreenRequest) (*model.Screen, error) {
+	screen, err := s.GetScreen(ctx, userID, screenID)
+	if err != nil {
+		return nil, err
+	}
+	filter, _, err := parseScreen(req.Filter, req.Sort)
+	if err != nil {
+		return nil, err
+	}
+
+	screen.Name = req.Name
+	screen.Filter = req.Filter
+	screen.Sort = req.Sort
+	screen.Limit = req.Limit
+	screen.Stream = req.Stream
+	if err := s.repo.Update(ctx, screen); err != nil {
+		return nil, err
+	}
+	s.setStream(screen, filter)
+	return screen, nil
+}
+
+// DeleteScreen removes a saved screen
+func (s *ScreenerService) DeleteScreen(ctx context.Context, userID string, screenID int64) error {
+	screen, err := s.GetScreen(ctx, userID, screenID)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.Delete(ctx, screen); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	delete(s.streams, screen.ID)
+	s.mu.Unlock()
+	return nil
+}
+
+// RunScreen returns the current matches of a saved screen
+func (s *ScreenerService) RunScreen(ctx context.Context, userID string, screenID int64) (*model.Screen, []*model.Coin, error) {
+	screen, err := s.GetScreen(ctx, userID, screenID)
+	if err != nil {
+		return nil, nil, err
+	}
+	coins, err := s.Run(screen.Filter, screen.Sort, screen.Limit)
+	if err != nil {
+		return nil, nil, err
+	}
+	return screen, coins, nil
+}
+
+// setStream starts, replaces or stops the stream of a screen.
+// Current matches are recorded silently, only later changes are pushed.
+func (s *ScreenerService) setStream(screen *model.Screen, filter *screener.Filter) {
+	if !screen.Stream {
+		s.mu.Lock()
+		delete(s.streams, screen.ID)
+		s.mu.Unlock()
+		return
+	}
+
+	stream := &screenStream{screen: screen, filter: filter, matches: make(map[string]bool)}
+	for _, coin := range s.marketService.Coins() {
+		if filter.Match(coin) {
+			stream.matches[coin.PairID] = true
+		}
+	}
+
+	s.mu.Lock()
+	s.streams[screen.ID] = stream
+	s.mu.Unlock()
+}
+
+// handleCoinUpdate re-evaluates the streamed screens on a coin update and
+// notifies owners of coins entering or leaving their screens
+func (s *ScreenerService) handleCoinUpdate(coin *model.Coin) {
+	type change struct {
+		userID  string
+		payload model.WSScreenMatchPayload
+	}
+	var changes []change
+
+	s.mu.Lock()
+	for _, stream := range s.streams {
+		matched := stream.filter.Match(coin)
+		if matched == stream.matches[coin.PairID] {
+			continue
+		}
+		if matched {
+			stream.matches[coin.PairID] = true
+		} else {
+			delete(stream.matches, coin.PairID)
+		}
+		changes = append(changes, change{
+			userID: stream.screen.UserID,
+			payload: model.WSScreenMatchPayload{
+				ScreenID: stream.screen.ID,
+				Name:     stream.screen.Name,
+				Pair:     coin.PairID,
+				Matched:  matched,
+				Coin:     coin,
+			},
+		})
+	}
+	s.mu.Unlock()
+
+	for _, c := range changes {
+		s.notificationService.NotifyUser(context.Background(), c.userID, model.MessageTypeScreenMatch, c.payload)
+	}
+}
@@ -6,11 +6,11 @@ import (
 	"sync"
 	"time"
 
+	"tuyul/backend/internal/exchange"
 	"tuyul/backend/internal/model"
 	"tuyul/backend/internal/repository"
 	"tuyul/backend/internal/service/market"
 	"tuyul/backend/internal/util"
-	"tuyul/backend/pkg/indodax"
 	"tuyul/backend/pkg/logger"
 )
 
@@ -18,10 +18,11 @@ import (
 type StopLossMonitor struct {
 	tradeRepo           *repository.TradeRepository
 	apiKeyService       *APIKeyService
-	indodaxClient       *indodax.Client
+	exchange            exchange.Exchange
 	marketDataService   *market.MarketDataService
 	notificationService *NotificationService
 	balanceRepo         *repository.BalanceRepository
+	paperExchange       *PaperExchange
 	log                 *logger.Logger
 
 	// Monitoring state
@@ -36,18 +37,20 @@ type StopLossMonitor struct {
 func NewStopLossMonitor(
 	tradeRepo *repository.TradeRepository,
 	apiKeyService *APIKeyService,
-	indodaxClient *indodax.Client,
+	ex exchange.Exchange,
 	marketDataService *market.MarketDataService,
 	notificationService *NotificationService,
 	balanceRepo *repository.BalanceRepository,
+	paperExchange *PaperExchange,
 ) *StopLossMonitor {
 	return &StopLossMonitor{
 		tradeRepo:           tradeRepo,
 		apiKeyService:       apiKeyService,
-		indodaxClient:       indodaxClient,
+		exchange:            ex,
 		marketDataService:   marketDataService,
 		notificationService: notificationService,
 		balanceRepo:         balanceRepo,
+		paperExchange:       paperExchange,
 		log:                 logger.GetLogger(),
 		activeTrades:        make(map[int64]*model.Trade),
 		done:                make(chan struct{}),
@@ -149,7 +152,7 @@ func (m *StopLossMonitor) checkTradeStopLoss(trade *model.Trade) {
 // triggerStopLoss executes the stop-loss by placing a market sell order
 func (m *StopLossMonitor) triggerStopLoss(ctx context.Context, trade *model.Trade, currentPrice float64) error {
 	// 1. Get Trade Client
-	tradeClient, err := m.getTradeClient(ctx, trade.UserID, trade.IsPaperTrade)
+	tradeClient, err := m.getTradeClient(ctx, trade)
 	if err != nil {
 		return err
 	}
@@ -169,7 +172,7 @@ func (m *StopLossMonitor) triggerStopLoss(ctx context.Context, trade *model.Trad
 	}
 
 	coinSymbol := m.extractCoinSymbol(trade.Pair)
-	sellAmount := m.parseBalance(accountInfo.Balance[coinSymbol].String())
+	sellAmount := accountInfo.Balances[coinSymbol]
 
 	if sellAmount <= 0 {
 		return fmt.Errorf("no coins available to sell")
@@ -188,13 +191,21 @@ func (m *StopLossMonitor) triggerStopLoss(ctx context.Context, trade *model.Trad
 		return fmt.Errorf("failed to place stop-loss sell order: %w", err)
 	}
 
+	// Paper market orders execute on placement, settle what they actually sold
+	soldAmount, sellPrice, fee := sellAmount, marketPrice, 0.0
+	if trade.IsPaperTrade {
+		if order, err := tradeClient.GetOrder(ctx, trade.Pair, result.OrderID); err == nil {
+			soldAmount, sellPrice, fee = order.Amount-order.Remaining, order.Price, order.Fee
+		}
+	}
+
 	// 5. Update trade status
 	oldStatus := trade.Status
 	trade.Status = model.TradeStatusStopped
 	trade.StopLossTriggered = true
-	trade.SellOrderID = fmt.Sprintf("%d", result.OrderID)
-	trade.SellPrice = marketPrice
-	trade.SellAmount = sellAmount
+	trade.SellOrderID = result.OrderID
+	trade.SellPrice = sellPrice
+	trade.SellAmount = soldAmount
 
 	if err := m.tradeRepo.Update(ctx, trade, oldStatus); err != nil {
 		m.log.Errorf("Failed to update trade after stop-loss: %v", err)
@@ -206,18 +217,18 @@ func (m *StopLossMonitor) triggerStopLoss(ctx context.Context, trade *model.Trad
 		balances, _ := m.getPaperBalances(ctx, trade.UserID)
 		// Remove coins
 		coinSymbol := m.extractCoinSymbol(trade.Pair)
-		balances[coinSymbol] -= sellAmount
+		balances[coinSymbol] -= soldAmount
 		if balances[coinSymbol] < 0 {
 			balances[coinSymbol] = 0
 		}
-		// Add IDR (at stop-loss price)
-		balances["idr"] += sellAmount * marketPrice
+		// Add IDR (at the executed price, net of the fee)
+		balances["idr"] += soldAmount*sellPrice - fee
 		m.savePaperBalances(ctx, trade.UserID, balances)
 	}
 
 	m.log.Warnf("Stop-loss executed (%s): TradeID=%d, SellPrice=%.2f, Amount=%.8f",
 		map[bool]string{true: "paper", false: "live"}[trade.IsPaperTrade],
-		trade.ID, marketPrice, sellAmount)
+		trade.ID, sellPrice, soldAmount)
 
 	// Send WebSocket alert to user
 	m.notificationService.NotifyUser(ctx, trade.UserID, model.MessageTypeOrderUpdate, trade)
@@ -225,18 +236,19 @@ func (m *StopLossMonitor) triggerStopLoss(ctx context.Context, trade *model.Trad
 	return nil
 }
 
-func (m *StopLossMonitor) getTradeClient(ctx context.Context, userID string, isPaperTrade bool) (TradeClient, error) {
-	if isPaperTrade {
-		balances, _ := m.getPaperBalances(ctx, userID)
-		// Note: We don't need a fill callback here because stop-loss is immediate market sell
-		return NewPaperTradeClient(balances, nil), nil
+// getTradeClient returns the client of a trade, using the API key the trade was placed with
+func (m *StopLossMonitor) getTradeClient(ctx context.Context, trade *model.Trade) (TradeClient, error) {
+	if trade.IsPaperTrade {
+		balances, _ := m.getPaperBalances(ctx, trade.UserID)
+		// Note: We don't need an update callback here because stop-loss is immediate market sell
+		return NewPaperTradeClient(m.paperExchange, trade.UserID, balances, nil), nil
 	}
 
-	credentials, err := m.apiKeyService.GetDecrypted(ctx, userID)
+	credentials, err := m.apiKeyService.ResolveDecrypted(ctx, trade.UserID, trade.APIKeyID)
 	if err != nil {
 		return nil, fmt.Errorf("valid API key not found")
 	}
-	return NewLiveTradeClient(m.indodaxClient, credentials.Key, credentials.Secret), nil
+	return m.exchange.NewTrader(credentials.Key, credentials.Secret), nil
 }
 
 func (m *StopLossMonitor) getPaperBalances(ctx context.Context, userID string) (map[string]float64, error) {
@@ -256,13 +268,6 @@ func (m *StopLossMonitor) extractCoinSymbol(pair string) string {
 	return pair
 }
 
-// parseBalance parses balance string to float64
-func (m *StopLossMonitor) parseBalance(balanceStr string) float64 {
-	var balance float64
-	fmt.Sscanf(balanceStr, "%f", &balance)
-	return balance
-}
-
 // LoadActiveTrades loads all filled trades into monitoring
 func (m *StopLossMonitor) LoadActiveTrades(ctx context.Context) error {
 	// This would require a method to get all filled trades from all users
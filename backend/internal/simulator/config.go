@@ -0,0 +1,266 @@
+// Package simulator implements a local fake Indodax exchange for end-to-end testing.
+// It serves the public REST API (/api), the private trade API (/tapi), the public
+// Centrifugo-style WebSocket and the private order WebSocket on a single port.
+package simulator
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// Config describes the simulated exchange
+type Config struct {
+	// Address to listen on (e.g. ":9090")
+	Listen string `json:"listen"`
+
+	// Price path tick interval in milliseconds
+	TickMs int `json:"tick_ms"`
+
+	// Random seed for price paths and synthetic flow (0 = time based)
+	Seed int64 `json:"seed"`
+
+	// Fees applied to simulated accounts
+	MakerFeeRate float64 `json:"maker_fee_rate"`
+	TakerFeeRate float64 `json:"taker_fee_rate"`
+
+	// Reject /tapi requests whose nonce is not strictly increasing per key
+	EnforceNonce bool `json:"enforce_nonce"`
+
+	Pairs    []PairConfig    `json:"pairs"`
+	Accounts []AccountConfig `json:"accounts"`
+}
+
+// PairConfig describes a tradable pair and how its price moves
+type PairConfig struct {
+	ID              string  `json:"id"`               // e.g. "btcidr"
+	BaseCurrency    string  `json:"base_currency"`    // e.g. "btc"
+	Description     string  `json:"description"`      // e.g. "BTC/IDR"
+	InitialPrice    float64 `json:"initial_price"`    // Starting mid price
+	PriceIncrement  float64 `json:"price_increment"`  // Tick size
+	VolumePrecision int     `json:"volume_precision"` // Coin decimals
+	MinOrderIDR     int     `json:"min_order_idr"`    // trade_min_base_currency
+	MinOrderCoin    float64 `json:"min_order_coin"`   // trade_min_traded_currency
+
+	// Synthetic liquidity quoted around the path price
+	Liquidity LiquidityConfig `json:"liquidity"`
+
+	// Price path driving the mid price
+	Path PathConfig `json:"path"`
+}
+
+// LiquidityConfig controls the synthetic order book and trade flow
+type LiquidityConfig struct {
+	SpreadPercent    float64 `json:"spread_percent"`     // Distance between best bid and best ask
+	Levels           int     `json:"levels"`             // Levels per side
+	LevelStepPercent float64 `json:"level_step_percent"` // Distance between levels
+	LevelSizeIDR     float64 `json:"level_size_idr"`     // IDR size per level
+	TradeProbability float64 `json:"trade_probability"`  // Chance of a synthetic taker trade per tick
+	TradeSizeIDR     float64 `json:"trade_size_idr"`     // Max IDR size of a synthetic taker trade
+}
+
+// Path modes
+const (
+	PathModeScript     = "script"
+	PathModeRandomWalk = "random_walk"
+	PathModeConstant   = "constant"
+)
+
+// PathConfig describes how the mid price evolves
+type PathConfig struct {
+	Mode string `json:"mode"` // script, random_walk, constant
+
+	// script: linear interpolation between points
+	Points []PathPoint `json:"points,omitempty"`
+	Loop   bool        `json:"loop,omitempty"`
+
+	// random_walk: per-tick percentage moves
+	VolatilityPercent float64 `json:"volatility_percent,omitempty"`
+	DriftPercent      float64 `json:"drift_percent,omitempty"`
+}
+
+// PathPoint is a scripted price at an offset from simulator start
+type PathPoint struct {
+	AtSec int     `json:"at_sec"`
+	Price float64 `json:"price"`
+}
+
+// AccountConfig describes a simulated API key and its balances
+type AccountConfig struct {
+	APIKey    string             `json:"api_key"`
+	APISecret string             `json:"api_secret"`
+	UserID    string             `json:"user_id"`
+	Name      string             `json:"name"`
+	Email     string             `json:"email"`
+	Balances  map[string]float64 `json:"balances"`
+
+	// Scopes of the key: view, trade, withdraw (empty = view and trade)
+	Permissions []string `json:"permissions,omitempty"`
+}
+
+// HasPermission reports whether the key was granted a scope
+func (a AccountConfig) HasPermission(scope string) bool {
+	if len(a.Permissions) == 0 {
+		return scope == "view" || scope == "trade"
+	}
+	for _, p := range a.Permissions {
+		if p == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// TickInterval returns the configured tick as a duration
+func (c *Config) TickInterval() time.Duration {
+	if c.TickMs <= 0 {
+		return time.Second
+	}
+	return time.Duration(c.TickMs) * time.Millisecond
+}
+
+// LoadConfig reads a JSON config file, falling back to DefaultConfig when path is empty
+func LoadConfig(path string) (*Config, error) {
+	if path == "" {
+		return DefaultConfig(), nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read simulator config: %w", err)
+	}
+
+	cfg := DefaultConfig()
+	cfg.Pairs = nil
+	cfg.Accounts = nil
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse simulator config: %w", err)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// Validate checks the config and fills per-pair defaults
+func (c *Config) Validate() error {
+	if len(c.Pairs) == 0 {
+		return fmt.Errorf("at least one pair is required")
+	}
+	for i := range c.Pairs {
+		p := &c.Pairs[i]
+		if p.ID == "" || p.BaseCurrency == "" {
+			return fmt.Errorf("pair %d: id and base_currency are required", i)
+		}
+		if p.InitialPrice <= 0 {
+			return fmt.Errorf("pair %s: initial_price must be greater than 0", p.ID)
+		}
+		if p.PriceIncrement <= 0 {
+			p.PriceIncrement = 1
+		}
+		if p.VolumePrecision <= 0 {
+			p.VolumePrecision = 8
+		}
+		if p.MinOrderIDR <= 0 {
+			p.MinOrderIDR = 10000
+		}
+		if p.Liquidity.Levels <= 0 {
+			p.Liquidity.Levels = 10
+		}
+		if p.Liquidity.SpreadPercent <= 0 {
+			p.Liquidity.SpreadPercent = 0.2
+		}
+		if p.Liquidity.LevelStepPercent <= 0 {
+			p.Liquidity.LevelStepPercent = 0.1
+		}
+		if p.Liquidity.LevelSizeIDR <= 0 {
+			p.Liquidity.LevelSizeIDR = 5_000_000
+		}
+		if p.Path.Mode == "" {
+			p.Path.Mode = PathModeConstant
+		}
+		if p.Path.Mode == PathModeScript && len(p.Path.Points) == 0 {
+			return fmt.Errorf("pair %s: script path requires points", p.ID)
+		}
+	}
+	for i, a := range c.Accounts {
+		if a.APIKey == "" || a.APISecret == "" {
+			return fmt.Errorf("account %d: api_key and api_secret are required", i)
+		}
+	}
+	return nil
+}
+
+// DefaultConfig returns a small two-pair market with one funded account
+func DefaultConfig() *Config {
+	return &Config{
+		Listen:       ":9090",
+		TickMs:       1000,
+		MakerFeeRate: 0.001,
+		TakerFeeRate: 0.002,
+		// The client keeps a monotonic nonce per key, so strict checking is safe
+		EnforceNonce: true,
+		Pairs: []PairConfig{
+			{
+				ID:              "btcidr",
+				BaseCurrency:    "btc",
+				Description:     "BTC/IDR",
+				InitialPrice:    1_500_000_000,
+				PriceIncrement:  1000,
+				VolumePrecision: 8,
+				MinOrderIDR:     10000,
+				MinOrderCoin:    0.00000001,
+				Liquidity: LiquidityConfig{
+					SpreadPercent:    0.1,
+					Levels:           10,
+					LevelStepPercent: 0.05,
+					LevelSizeIDR:     50_000_000,
+					TradeProbability: 0.5,
+					TradeSizeIDR:     5_000_000,
+				},
+				Path: PathConfig{Mode: PathModeRandomWalk, VolatilityPercent: 0.05},
+			},
+			{
+				ID:              "dogeidr",
+				BaseCurrency:    "doge",
+				Description:     "DOGE/IDR",
+				InitialPrice:    2500,
+				PriceIncrement:  1,
+				VolumePrecision: 8,
+				MinOrderIDR:     10000,
+				MinOrderCoin:    1,
+				Liquidity: LiquidityConfig{
+					SpreadPercent:    0.5,
+					Levels:           10,
+					LevelStepPercent: 0.2,
+					LevelSizeIDR:     10_000_000,
+					TradeProbability: 0.5,
+					TradeSizeIDR:     1_000_000,
+				},
+				Path: PathConfig{
+					Mode: PathModeScript,
+					Loop: true,
+					Points: []PathPoint{
+						{AtSec: 0, Price: 2500},
+						{AtSec: 300, Price: 2600},
+						{AtSec: 420, Price: 3000}, // Pump
+						{AtSec: 600, Price: 2550}, // Dump
+						{AtSec: 900, Price: 2500},
+					},
+				},
+			},
+		},
+		Accounts: []AccountConfig{
+			{
+				APIKey:    "SIM-KEY-1",
+				APISecret: "sim-secret-1",
+				UserID:    "100001",
+				Name:      "Simulator User",
+				Email:     "sim@example.com",
+				Balances:  map[string]float64{"idr": 100_000_000, "btc": 0.05, "doge": 10000},
+			},
+		},
+	}
+}
@@ -0,0 +1,929 @@
+package simulator
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Order statuses (REST format)
+const (
+	OrderStatusOpen      = "open"
+	OrderStatusFilled    = "filled"
+	OrderStatusCancelled = "cancelled"
+)
+
+// syntheticAccount owns the simulated liquidity; it has unlimited balance
+const syntheticAccount = "__liquidity__"
+
+// Engine errors use the same wording as Indodax so callers hit the same error paths
+var (
+	ErrInvalidPair         = fmt.Errorf("Invalid pair")
+	ErrInsufficientBalance = fmt.Errorf("Insufficient balance.")
+	ErrOrderNotFound       = fmt.Errorf("Order not found")
+	ErrDuplicateClientID   = fmt.Errorf("Duplicate client_order_id")
+)
+
+// Order is an order resting on (or removed from) a simulated book
+type Order struct {
+	ID            int64
+	Pair          string
+	Account       string
+	ClientOrderID string
+	Side          string // buy, sell
+	Type          string // limit, market
+	TimeInForce   string // GTC, MOC
+	Price         float64
+
+	// Coin amounts (for market buys by IDR, OrigCoin grows as fills happen)
+	OrigCoin   float64
+	RemainCoin float64
+	Executed   float64
+
+	// Cumulative IDR value of fills (for average price)
+	ExecutedIDR float64
+
+	// IDR budget for market buys
+	OrigIDR   float64
+	RemainIDR float64
+
+	// Funds locked for this order (IDR for buys, coin for sells)
+	Held float64
+
+	Status     string
+	SubmitTime time.Time
+	FinishTime time.Time
+}
+
+// Trade is a single match on a simulated book
+type Trade struct {
+	ID        int64
+	Pair      string
+	Price     float64
+	Amount    float64
+	Side      string // taker side
+	Timestamp time.Time
+}
+
+// OrderEvent is emitted for every state change of an account order
+type OrderEvent struct {
+	Account   string
+	Order     Order
+	Status    string // NEW, FILL, DONE, CANCELLED
+	FillPrice float64
+	TradeID   int64
+}
+
+// TradeResult summarises an order placement for the /tapi trade response
+type TradeResult struct {
+	Order    *Order
+	SpentIDR float64
+	GotIDR   float64
+	GotCoin  float64
+	Fee      float64
+}
+
+type account struct {
+	cfg  AccountConfig
+	free map[string]float64
+	hold map[string]float64
+}
+
+type book struct {
+	cfg    PairConfig
+	bids   []*Order // best (highest) first
+	asks   []*Order // best (lowest) first
+	trades []Trade  // oldest first
+	dirty  bool
+}
+
+// Engine is the matching engine shared by all simulator endpoints
+type Engine struct {
+	cfg      *Config
+	books    map[string]*book
+	accounts map[string]*account
+	orders   map[int64]*Order
+	byClient map[string]*Order // Key: account:client_order_id
+
+	nextOrderID int64
+	nextTradeID int64
+
+	onOrder func(OrderEvent)
+	onTrade func(Trade)
+
+	mu sync.Mutex
+}
+
+// maxTradesPerPair bounds memory used by trade history and 24h stats
+const maxTradesPerPair = 50000
+
+// NewEngine creates an engine with the configured pairs and accounts
+func NewEngine(cfg *Config) *Engine {
+	e := &Engine{
+		cfg:         cfg,
+		books:       make(map[string]*book),
+		accounts:    make(map[string]*account),
+		orders:      make(map[int64]*Order),
+		byClient:    make(map[string]*Order),
+		nextOrderID: 1000,
+		nextTradeID: 1,
+	}
+
+	for _, p := range cfg.Pairs {
+		e.books[p.ID] = &book{cfg: p}
+	}
+
+	for _, a := range cfg.Accounts {
+		acc := &account{
+			cfg:  a,
+			free: make(map[string]float64),
+			hold: make(map[string]float64),
+		}
+		acc.free["idr"] = 0
+		for _, p := range cfg.Pairs {
+			acc.free[p.BaseCurrency] = 0
+		}
+		for currency, amount := range a.Balances {
+			acc.free[strings.ToLower(currency)] = amount
+		}
+		e.accounts[a.APIKey] = acc
+	}
+
+	return e
+}
+
+// SetOrderHandler sets the callback for account order events (called under the engine lock)
+func (e *Engine) SetOrderHandler(handler func(OrderEvent)) {
+	e.onOrder = handler
+}
+
+// SetTradeHandler sets the callback for public trades (called under the engine lock)
+func (e *Engine) SetTradeHandler(handler func(Trade)) {
+	e.onTrade = handler
+}
+
+// NormalizePair converts "btc_idr" or "BTCIDR" into the internal "btcidr" form
+func NormalizePair(pair string) string {
+	return strings.ToLower(strings.ReplaceAll(pair, "_", ""))
+}
+
+// Account returns the config of an API key
+func (e *Engine) Account(apiKey string) (AccountConfig, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	acc, ok := e.accounts[apiKey]
+	if !ok {
+		return AccountConfig{}, false
+	}
+	return acc.cfg, true
+}
+
+// Balances returns copies of the free and held balances of an account
+func (e *Engine) Balances(apiKey string) (map[string]float64, map[string]float64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	acc, ok := e.accounts[apiKey]
+	if !ok {
+		return nil, nil
+	}
+	free := make(map[string]float64, len(acc.free))
+	hold := make(map[string]float64, len(acc.free))
+	for k, v := range acc.free {
+		free[k] = v
+		hold[k] = acc.hold[k]
+	}
+	return free, hold
+}
+
+// PairConfig returns the config of a pair
+func (e *Engine) PairConfig(pair string) (PairConfig, bool) {
+	b, ok := e.books[NormalizePair(pair)]
+	if !ok {
+		return PairConfig{}, false
+	}
+	return b.cfg, true
+}
+
+// PlaceOrderRequest describes a new order
+type PlaceOrderRequest struct {
+	Account       string
+	Pair          string
+	Side          string
+	Type          string
+	TimeInForce   string
+	Price         float64
+	Coin          float64
+	IDR           float64
+	ClientOrderID string
+}
+
+// PlaceOrder validates, locks funds and matches a new account order
+func (e *Engine) PlaceOrder(req PlaceOrderRequest) (*TradeResult, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return e.placeOrder(req)
+}
+
+func (e *Engine) placeOrder(req PlaceOrderRequest) (*TradeResult, error) {
+	// 1. Validate pair, side and amounts
+	b, ok := e.books[NormalizePair(req.Pair)]
+	if !ok {
+		return nil, ErrInvalidPair
+	}
+	if req.Side != "buy" && req.Side != "sell" {
+		return nil, fmt.Errorf("Invalid type, must be buy or sell")
+	}
+	if req.Type == "" {
+		req.Type = "limit"
+	}
+	if req.Type != "limit" && req.Type != "market" {
+		return nil, fmt.Errorf("Invalid order_type")
+	}
+	if req.TimeInForce == "" {
+		req.TimeInForce = "GTC"
+	}
+
+	synthetic := req.Account == syntheticAccount
+	var acc *account
+	if !synthetic {
+		acc, ok = e.accounts[req.Account]
+		if !ok {
+			return nil, fmt.Errorf("Invalid credentials. API not found or session has expired.")
+		}
+	}
+
+	if req.ClientOrderID != "" {
+		if _, exists := e.byClient[req.Account+":"+req.ClientOrderID]; exists {
+			return nil, ErrDuplicateClientID
+		}
+	}
+
+	pc := b.cfg
+	if req.Type == "limit" {
+		if req.Price <= 0 {
+			return nil, fmt.Errorf("Price must be greater than 0")
+		}
+		if !synthetic && !isMultiple(req.Price, pc.PriceIncrement) {
+			return nil, fmt.Errorf("Price must be a multiple of %s", formatPrice(pc.PriceIncrement))
+		}
+		if req.Coin <= 0 && req.IDR > 0 && req.Side == "buy" {
+			req.Coin = roundDown(req.IDR/req.Price, pc.VolumePrecision)
+		}
+		if req.Coin <= 0 {
+			return nil, fmt.Errorf("Minimum order is %d IDR", pc.MinOrderIDR)
+		}
+		if !synthetic && req.Coin*req.Price < float64(pc.MinOrderIDR) {
+			return nil, fmt.Errorf("Minimum order is %d IDR", pc.MinOrderIDR)
+		}
+	} else {
+		if req.Side == "buy" && req.IDR <= 0 {
+			return nil, fmt.Errorf("Minimum order is %d IDR", pc.MinOrderIDR)
+		}
+		if req.Side == "sell" && req.Coin <= 0 {
+			return nil, fmt.Errorf("Minimum order is %f %s", pc.MinOrderCoin, strings.ToUpper(pc.BaseCurrency))
+		}
+		if !synthetic && req.Side == "buy" && req.IDR < float64(pc.MinOrderIDR) {
+			return nil, fmt.Errorf("Minimum order is %d IDR", pc.MinOrderIDR)
+		}
+	}
+
+	order := &Order{
+		ID:            e.nextOrderID,
+		Pair:          pc.ID,
+		Account:       req.Account,
+		ClientOrderID: req.ClientOrderID,
+		Side:          req.Side,
+		Type:          req.Type,
+		TimeInForce:   strings.ToUpper(req.TimeInForce),
+		Price:         req.Price,
+		OrigCoin:      req.Coin,
+		RemainCoin:    req.Coin,
+		OrigIDR:       req.IDR,
+		RemainIDR:     req.IDR,
+		Status:        OrderStatusOpen,
+		SubmitTime:    time.Now(),
+	}
+	if order.Type == "market" && order.Side == "buy" {
+		order.OrigCoin = 0
+		order.RemainCoin = 0
+	}
+
+	// 2. Lock funds
+	if !synthetic {
+		var currency string
+		var amount float64
+		if order.Side == "buy" {
+			currency = "idr"
+			if order.Type == "market" {
+				amount = order.OrigIDR
+			} else {
+				amount = order.OrigCoin * order.Price * (1 + e.maxFeeRate())
+			}
+		} else {
+			currency = pc.BaseCurrency
+			amount = order.OrigCoin
+		}
+		if acc.free[currency]+1e-9 < amount {
+			return nil, ErrInsufficientBalance
+		}
+		acc.free[currency] -= amount
+		acc.hold[currency] += amount
+		order.Held = amount
+	}
+
+	e.nextOrderID++
+	e.orders[order.ID] = order
+	if order.ClientOrderID != "" {
+		e.byClient[order.Account+":"+order.ClientOrderID] = order
+	}
+	e.emitOrder(order, "NEW", 0, 0)
+
+	result := &TradeResult{Order: order}
+
+	// 3. Maker-or-cancel orders must not cross
+	if order.Type == "limit" && order.TimeInForce == "MOC" && b.crosses(order) {
+		e.finishOrder(order, OrderStatusCancelled)
+		return result, nil
+	}
+
+	// 4. Match against the opposite side
+	e.match(b, order, result)
+
+	// 5. Rest remaining limit quantity, close market orders
+	switch {
+	case order.Type == "limit" && order.RemainCoin > dust(pc.VolumePrecision):
+		b.insert(order)
+	case order.Type == "limit":
+		e.finishOrder(order, OrderStatusFilled)
+	case order.Executed > 0:
+		e.finishOrder(order, OrderStatusFilled)
+	default:
+		e.finishOrder(order, OrderStatusCancelled)
+	}
+	b.dirty = true
+
+	return result, nil
+}
+
+// CancelOrder cancels an open account order by ID
+func (e *Engine) CancelOrder(apiKey string, orderID int64) (*Order, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	order, ok := e.orders[orderID]
+	if !ok || order.Account != apiKey {
+		return nil, ErrOrderNotFound
+	}
+	return e.cancel(order)
+}
+
+// CancelByClientOrderID cancels an open account order by client order ID
+func (e *Engine) CancelByClientOrderID(apiKey, clientOrderID string) (*Order, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	order, ok := e.byClient[apiKey+":"+clientOrderID]
+	if !ok {
+		return nil, ErrOrderNotFound
+	}
+	return e.cancel(order)
+}
+
+// CancelAll cancels every open order of an account on a pair
+func (e *Engine) CancelAll(apiKey, pair string) int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	b, ok := e.books[NormalizePair(pair)]
+	if !ok {
+		return 0
+	}
+
+	var targets []*Order
+	for _, o := range append(append([]*Order{}, b.bids...), b.asks...) {
+		if o.Account == apiKey {
+			targets = append(targets, o)
+		}
+	}
+	for _, o := range targets {
+		e.cancel(o)
+	}
+	return len(targets)
+}
+
+func (e *Engine) cancel(order *Order) (*Order, error) {
+	if order.Status != OrderStatusOpen {
+		return nil, ErrOrderNotFound
+	}
+	b := e.books[order.Pair]
+	b.remove(order)
+	b.dirty = true
+	e.finishOrder(order, OrderStatusCancelled)
+
+	snapshot := *order
+	return &snapshot, nil
+}
+
+// GetOrder returns a copy of an account order by ID
+func (e *Engine) GetOrder(apiKey string, orderID int64) (*Order, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	order, ok := e.orders[orderID]
+	if !ok || order.Account != apiKey {
+		return nil, ErrOrderNotFound
+	}
+	snapshot := *order
+	return &snapshot, nil
+}
+
+// GetOrderByClientOrderID returns a copy of an account order by client order ID
+func (e *Engine) GetOrderByClientOrderID(apiKey, clientOrderID string) (*Order, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	order, ok := e.byClient[apiKey+":"+clientOrderID]
+	if !ok {
+		return nil, ErrOrderNotFound
+	}
+	snapshot := *order
+	return &snapshot, nil
+}
+
+// Orders returns account orders (newest first), optionally filtered by pair and open status
+func (e *Engine) Orders(apiKey, pair string, openOnly bool) []Order {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	pair = NormalizePair(pair)
+	var result []Order
+	for _, o := range e.orders {
+		if o.Account != apiKey {
+			continue
+		}
+		if pair != "" && o.Pair != pair {
+			continue
+		}
+		if openOnly && o.Status != OrderStatusOpen {
+			continue
+		}
+		result = append(result, *o)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID > result[j].ID })
+	return result
+}
+
+// match executes an incoming order against resting orders (price-time priority)
+func (e *Engine) match(b *book, taker *Order, result *TradeResult) {
+	prec := b.cfg.VolumePrecision
+	for {
+		var maker *Order
+		if taker.Side == "buy" {
+			if len(b.asks) == 0 {
+				return
+			}
+			maker = b.asks[0]
+			if taker.Type == "limit" && maker.Price > taker.Price {
+				return
+			}
+		} else {
+			if len(b.bids) == 0 {
+				return
+			}
+			maker = b.bids[0]
+			if taker.Type == "limit" && maker.Price < taker.Price {
+				return
+			}
+		}
+
+		price := maker.Price
+		qty := maker.RemainCoin
+		if taker.Type == "market" && taker.Side == "buy" {
+			// Spend the IDR budget including fees
+			affordable := roundDown(taker.RemainIDR/(price*(1+e.takerFeeRate(taker))), prec)
+			if affordable < qty {
+				qty = affordable
+			}
+		} else if taker.RemainCoin < qty {
+			qty = taker.RemainCoin
+		}
+		if qty <= dust(prec) {
+			return
+		}
+
+		trade := Trade{
+			ID:        e.nextTradeID,
+			Pair:      b.cfg.ID,
+			Price:     price,
+			Amount:    qty,
+			Side:      taker.Side,
+			Timestamp: time.Now(),
+		}
+		e.nextTradeID++
+
+		e.fill(b, maker, qty, price, e.makerFeeRate(maker), trade.ID, nil)
+		e.fill(b, taker, qty, price, e.takerFeeRate(taker), trade.ID, result)
+
+		if maker.RemainCoin <= dust(prec) {
+			b.remove(maker)
+			e.finishOrder(maker, OrderStatusFilled)
+			// Filled synthetic quotes are not needed for history
+			if maker.Account == syntheticAccount {
+				delete(e.orders, maker.ID)
+			}
+		}
+
+		b.trades = append(b.trades, trade)
+		if len(b.trades) > maxTradesPerPair {
+			b.trades = b.trades[len(b.trades)-maxTradesPerPair:]
+		}
+		if e.onTrade != nil {
+			e.onTrade(trade)
+		}
+
+		if taker.Type == "market" && taker.Side == "buy" {
+			if taker.RemainIDR < price*math.Pow(10, -float64(prec)) {
+				return
+			}
+		} else if taker.RemainCoin <= dust(prec) {
+			return
+		}
+	}
+}
+
+// fill settles one side of a match and emits the order event
+func (e *Engine) fill(b *book, order *Order, qty, price, feeRate float64, tradeID int64, result *TradeResult) {
+	cost := qty * price
+	fee := cost * feeRate
+
+	order.Executed += qty
+	order.ExecutedIDR += cost
+	if order.Type == "market" && order.Side == "buy" {
+		order.OrigCoin += qty
+		order.RemainIDR -= cost + fee
+		if order.RemainIDR < 0 {
+			order.RemainIDR = 0
+		}
+	} else {
+		order.RemainCoin -= qty
+		if order.RemainCoin < 0 {
+			order.RemainCoin = 0
+		}
+	}
+
+	if result != nil {
+		result.Fee += fee
+		if order.Side == "buy" {
+			result.SpentIDR += cost + fee
+			result.GotCoin += qty
+		} else {
+			result.GotIDR += cost - fee
+		}
+	}
+
+	if order.Account == syntheticAccount {
+		return
+	}
+
+	acc := e.accounts[order.Account]
+	base := b.cfg.BaseCurrency
+	if order.Side == "buy" {
+		// Release the portion of the hold reserved for this quantity
+		released := cost + fee
+		if order.Type == "limit" {
+			released = qty * order.Price * (1 + e.maxFeeRate())
+		}
+		if released > order.Held {
+			released = order.Held
+		}
+		order.Held -= released
+		acc.hold["idr"] -= released
+		acc.free["idr"] += released - (cost + fee)
+		acc.free[base] += qty
+	} else {
+		order.Held -= qty
+		acc.hold[base] -= qty
+		acc.free["idr"] += cost - fee
+	}
+
+	// Market orders report a single DONE once matching completes (see finishOrder)
+	if order.Type == "market" {
+		return
+	}
+	status := "FILL"
+	if order.RemainCoin <= dust(b.cfg.VolumePrecision) {
+		status = "DONE"
+	}
+	e.emitOrder(order, status, price, tradeID)
+}
+
+// finishOrder closes an order, releases leftover holds and emits the final event
+func (e *Engine) finishOrder(order *Order, status string) {
+	order.Status = status
+	order.FinishTime = time.Now()
+
+	if order.Account != syntheticAccount && order.Held > 0 {
+		acc := e.accounts[order.Account]
+		currency := "idr"
+		if order.Side == "sell" {
+			currency = e.books[order.Pair].cfg.BaseCurrency
+		}
+		acc.hold[currency] -= order.Held
+		acc.free[currency] += order.Held
+		order.Held = 0
+	}
+
+	switch {
+	case status == OrderStatusCancelled:
+		e.emitOrder(order, "CANCELLED", 0, 0)
+	case order.Type == "market":
+		// Market orders report completion once all matches are done, at the average price
+		order.RemainCoin = 0
+		e.emitOrder(order, "DONE", order.ExecutedIDR/order.Executed, 0)
+	}
+}
+
+func (e *Engine) emitOrder(order *Order, status string, fillPrice float64, tradeID int64) {
+	if order.Account == syntheticAccount || e.onOrder == nil {
+		return
+	}
+	e.onOrder(OrderEvent{
+		Account:   order.Account,
+		Order:     *order,
+		Status:    status,
+		FillPrice: fillPrice,
+		TradeID:   tradeID,
+	})
+}
+
+func (e *Engine) maxFeeRate() float64 {
+	return math.Max(e.cfg.MakerFeeRate, e.cfg.TakerFeeRate)
+}
+
+func (e *Engine) makerFeeRate(order *Order) float64 {
+	if order.Account == syntheticAccount {
+		return 0
+	}
+	return e.cfg.MakerFeeRate
+}
+
+func (e *Engine) takerFeeRate(order *Order) float64 {
+	if order.Account == syntheticAccount {
+		return 0
+	}
+	return e.cfg.TakerFeeRate
+}
+
+// crosses reports whether a limit order would match immediately
+func (b *book) crosses(order *Order) bool {
+	if order.Side == "buy" {
+		return len(b.asks) > 0 && b.asks[0].Price <= order.Price
+	}
+	return len(b.bids) > 0 && b.bids[0].Price >= order.Price
+}
+
+// insert adds a resting order keeping price-time priority
+func (b *book) insert(order *Order) {
+	if order.Side == "buy" {
+		i := sort.Search(len(b.bids), func(i int) bool { return b.bids[i].Price < order.Price })
+		b.bids = append(b.bids, nil)
+		copy(b.bids[i+1:], b.bids[i:])
+		b.bids[i] = order
+		return
+	}
+	i := sort.Search(len(b.asks), func(i int) bool { return b.asks[i].Price > order.Price })
+	b.asks = append(b.asks, nil)
+	copy(b.asks[i+1:], b.asks[i:])
+	b.asks[i] = order
+}
+
+// remove deletes a resting order from the book
+func (b *book) remove(order *Order) {
+	side := &b.asks
+	if order.Side == "buy" {
+		side = &b.bids
+	}
+	for i, o := range *side {
+		if o == order {
+			*side = append((*side)[:i], (*side)[i+1:]...)
+			return
+		}
+	}
+}
+
+// DepthLevel is an aggregated price level
+type DepthLevel struct {
+	Price  float64
+	Amount float64
+}
+
+// Depth returns aggregated bid and ask levels (best first)
+func (e *Engine) Depth(pair string, limit int) ([]DepthLevel, []DepthLevel, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	b, ok := e.books[NormalizePair(pair)]
+	if !ok {
+		return nil, nil, false
+	}
+	return aggregate(b.bids, limit), aggregate(b.asks, limit), true
+}
+
+func aggregate(orders []*Order, limit int) []DepthLevel {
+	var levels []DepthLevel
+	for _, o := range orders {
+		if n := len(levels); n > 0 && levels[n-1].Price == o.Price {
+			levels[n-1].Amount += o.RemainCoin
+			continue
+		}
+		if limit > 0 && len(levels) == limit {
+			break
+		}
+		levels = append(levels, DepthLevel{Price: o.Price, Amount: o.RemainCoin})
+	}
+	return levels
+}
+
+// TakeDirtyBooks returns pairs whose book changed since the last call
+func (e *Engine) TakeDirtyBooks() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	var pairs []string
+	for id, b := range e.books {
+		if b.dirty {
+			b.dirty = false
+			pairs = append(pairs, id)
+		}
+	}
+	sort.Strings(pairs)
+	return pairs
+}
+
+// RecentTrades returns up to limit trades of a pair (newest first)
+func (e *Engine) RecentTrades(pair string, limit int) ([]Trade, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	b, ok := e.books[NormalizePair(pair)]
+	if !ok {
+		return nil, false
+	}
+	var trades []Trade
+	for i := len(b.trades) - 1; i >= 0 && (limit <= 0 || len(trades) < limit); i-- {
+		trades = append(trades, b.trades[i])
+	}
+	return trades, true
+}
+
+// Stats24h is the rolling 24h summary of a pair
+type Stats24h struct {
+	Pair    string
+	Last    float64
+	Open    float64
+	High    float64
+	Low     float64
+	VolCoin float64
+	VolIDR  float64
+	BestBid float64
+	BestAsk float64
+}
+
+// Stats returns 24h statistics for every pair (sorted by pair)
+func (e *Engine) Stats(mid func(pair string) float64) []Stats24h {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	cutoff := time.Now().Add(-24 * time.Hour)
+	stats := make([]Stats24h, 0, len(e.books))
+	for id, b := range e.books {
+		st := Stats24h{Pair: id, Last: mid(id)}
+		for _, t := range b.trades {
+			if t.Timestamp.Before(cutoff) {
+				continue
+			}
+			if st.Open == 0 {
+				st.Open = t.Price
+				st.High = t.Price
+				st.Low = t.Price
+			}
+			st.High = math.Max(st.High, t.Price)
+			st.Low = math.Min(st.Low, t.Price)
+			st.VolCoin += t.Amount
+			st.VolIDR += t.Amount * t.Price
+			st.Last = t.Price
+		}
+		if st.Open == 0 {
+			st.Open, st.High, st.Low = st.Last, st.Last, st.Last
+		}
+		if len(b.bids) > 0 {
+			st.BestBid = b.bids[0].Price
+		}
+		if len(b.asks) > 0 {
+			st.BestAsk = b.asks[0].Price
+		}
+		stats = append(stats, st)
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Pair < stats[j].Pair })
+	return stats
+}
+
+// Requote replaces the synthetic ladder of a pair around mid and fires optional taker flow.
+// The new ladder goes through the matching engine, so it trades through account orders
+// that the price path has crossed.
+func (e *Engine) Requote(pair string, mid float64, takerSide string, takerIDR float64, jitter func() float64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	b, ok := e.books[pair]
+	if !ok || mid <= 0 {
+		return
+	}
+	pc := b.cfg
+	liq := pc.Liquidity
+
+	// 1. Pull previous synthetic quotes
+	for _, side := range [][]*Order{append([]*Order{}, b.bids...), append([]*Order{}, b.asks...)} {
+		for _, o := range side {
+			if o.Account == syntheticAccount {
+				b.remove(o)
+				delete(e.orders, o.ID)
+			}
+		}
+	}
+
+	// 2. Quote a fresh ladder
+	halfSpread := mid * liq.SpreadPercent / 200
+	bestBid := roundToIncrement(mid-halfSpread, pc.PriceIncrement, false)
+	bestAsk := roundToIncrement(mid+halfSpread, pc.PriceIncrement, true)
+	if bestAsk <= bestBid {
+		bestAsk = bestBid + pc.PriceIncrement
+	}
+	for i := 0; i < liq.Levels; i++ {
+		step := 1 - float64(i)*liq.LevelStepPercent/100
+		bidPrice := roundToIncrement(bestBid*step, pc.PriceIncrement, false)
+		askPrice := roundToIncrement(bestAsk*(2-step), pc.PriceIncrement, true)
+		if bidPrice > 0 {
+			e.placeSynthetic(pair, "buy", "limit", bidPrice, roundDown(liq.LevelSizeIDR*jitter()/bidPrice, pc.VolumePrecision), 0)
+		}
+		e.placeSynthetic(pair, "sell", "limit", askPrice, roundDown(liq.LevelSizeIDR*jitter()/askPrice, pc.VolumePrecision), 0)
+	}
+
+	// 3. Optional market flow for trade prints
+	if takerIDR > 0 {
+		if takerSide == "buy" {
+			e.placeSynthetic(pair, "buy", "market", 0, 0, takerIDR)
+		} else {
+			e.placeSynthetic(pair, "sell", "market", 0, roundDown(takerIDR/mid, pc.VolumePrecision), 0)
+		}
+	}
+
+	b.dirty = true
+}
+
+func (e *Engine) placeSynthetic(pair, side, orderType string, price, coin, idr float64) {
+	if orderType == "limit" && coin <= 0 {
+		return
+	}
+	result, err := e.placeOrder(PlaceOrderRequest{
+		Account: syntheticAccount,
+		Pair:    pair,
+		Side:    side,
+		Type:    orderType,
+		Price:   price,
+		Coin:    coin,
+		IDR:     idr,
+	})
+	// Closed synthetic orders are not needed for history
+	if err == nil && result.Order.Status != OrderStatusOpen {
+		delete(e.orders, result.Order.ID)
+	}
+}
+
+// dust is the smallest representable coin amount for a precision
+func dust(precision int) float64 {
+	return math.Pow(10, -float64(precision)) / 2
+}
+
+func roundDown(v float64, precision int) float64 {
+	p := math.Pow(10, float64(precision))
+	return math.Floor(v*p+1e-9) / p
+}
+
+func roundToIncrement(v, increment float64, up bool) float64 {
+	if up {
+		return math.Ceil(v/increment-1e-9) * increment
+	}
+	return math.Floor(v/increment+1e-9) * increment
+}
+
+func isMultiple(v, increment float64) bool {
+	n := v / increment
+	return math.Abs(n-math.Round(n)) < 1e-6
+}
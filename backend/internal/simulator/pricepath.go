@@ -0,0 +1,82 @@
+package simulator
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// PricePath produces the mid price of a pair over time
+type PricePath struct {
+	cfg   PathConfig
+	start time.Time
+	rng   *rand.Rand
+
+	current float64
+	mu      sync.Mutex
+}
+
+// NewPricePath creates a price path starting at initial
+func NewPricePath(cfg PathConfig, initial float64, start time.Time, rng *rand.Rand) *PricePath {
+	return &PricePath{
+		cfg:     cfg,
+		start:   start,
+		rng:     rng,
+		current: initial,
+	}
+}
+
+// Current returns the last computed price
+func (p *PricePath) Current() float64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.current
+}
+
+// Step advances the path to now and returns the new price
+func (p *PricePath) Step(now time.Time) float64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	switch p.cfg.Mode {
+	case PathModeScript:
+		p.current = p.scripted(now.Sub(p.start))
+	case PathModeRandomWalk:
+		move := p.rng.NormFloat64()*p.cfg.VolatilityPercent/100 + p.cfg.DriftPercent/100
+		next := p.current * (1 + move)
+		if next > 0 {
+			p.current = next
+		}
+	}
+	return p.current
+}
+
+// scripted linearly interpolates between script points
+func (p *PricePath) scripted(elapsed time.Duration) float64 {
+	points := p.cfg.Points
+	sec := elapsed.Seconds()
+
+	last := points[len(points)-1]
+	if p.cfg.Loop && last.AtSec > 0 {
+		for sec >= float64(last.AtSec) {
+			sec -= float64(last.AtSec)
+		}
+	}
+
+	if sec <= float64(points[0].AtSec) {
+		return points[0].Price
+	}
+	for i := 1; i < len(points); i++ {
+		prev, next := points[i-1], points[i]
+		if sec > float64(next.AtSec) {
+			continue
+		}
+		span := float64(next.AtSec - prev.AtSec)
+		if span <= 0 {
+			return next.Price
+		}
+		ratio := (sec - float64(prev.AtSec)) / span
+		return prev.Price + (next.Price-prev.Price)*ratio
+	}
+	return last.Price
+}
@@ -0,0 +1,622 @@
+package simulator
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"tuyul/backend/pkg/indodax"
+)
+
+// Indodax error codes returned by the simulator
+const (
+	errCodeInvalidCredentials  = "invalid_credentials"
+	errCodeInvalidNonce        = "invalid_nonce"
+	errCodeInvalidPair         = "invalid_pair"
+	errCodeInsufficientBalance = "insufficient_balance"
+	errCodeOrderNotFound       = "order_not_found"
+	errCodeInvalidParameter    = "invalid_parameter"
+	errCodeInvalidMethod       = "invalid_method"
+	errCodeNoPermission        = "no_permission"
+)
+
+// ==================== Public REST ====================
+
+func (s *Simulator) handleServerTime(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"timezone":    "UTC",
+		"server_time": time.Now().UnixMilli(),
+	})
+}
+
+func (s *Simulator) handlePairs(w http.ResponseWriter, r *http.Request) {
+	pairs := make([]indodax.Pair, 0, len(s.cfg.Pairs))
+	for _, p := range s.cfg.Pairs {
+		pairs = append(pairs, indodax.Pair{
+			ID:                     p.ID,
+			Symbol:                 strings.ToUpper(p.ID),
+			BaseCurrency:           "idr",
+			TradedCurrency:         p.BaseCurrency,
+			TradedCurrencyUnit:     strings.ToUpper(p.BaseCurrency),
+			Description:            p.Description,
+			TickerID:               tickerID(p),
+			VolumePrecision:        p.VolumePrecision,
+			PricePrecision:         decimals(p.PriceIncrement),
+			PriceRound:             8,
+			PriceScale:             int(math.Pow(10, float64(decimals(p.PriceIncrement)))),
+			TradeMinBaseCurrency:   p.MinOrderIDR,
+			TradeMinTradedCurrency: p.MinOrderCoin,
+		})
+	}
+	writeJSON(w, http.StatusOK, pairs)
+}
+
+func (s *Simulator) handlePriceIncrements(w http.ResponseWriter, r *http.Request) {
+	increments := make(map[string]string, len(s.cfg.Pairs))
+	for _, p := range s.cfg.Pairs {
+		increments[tickerID(p)] = formatPrice(p.PriceIncrement)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"increments": increments})
+}
+
+func (s *Simulator) handleSummaries(w http.ResponseWriter, r *http.Request) {
+	tickers := make(map[string]interface{})
+	prices24h := make(map[string]string)
+	prices7d := make(map[string]string)
+	for _, st := range s.engine.Stats(s.midPrice) {
+		pc, _ := s.engine.PairConfig(st.Pair)
+		tickers[tickerID(pc)] = s.tickerJSON(pc, st)
+		prices24h[st.Pair] = formatPrice(st.Open)
+		prices7d[st.Pair] = formatPrice(st.Open)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"tickers":    tickers,
+		"prices_24h": prices24h,
+		"prices_7d":  prices7d,
+	})
+}
+
+func (s *Simulator) handleTickerAll(w http.ResponseWriter, r *http.Request) {
+	tickers := make(map[string]interface{})
+	for _, st := range s.engine.Stats(s.midPrice) {
+		pc, _ := s.engine.PairConfig(st.Pair)
+		tickers[tickerID(pc)] = s.tickerJSON(pc, st)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"tickers": tickers})
+}
+
+func (s *Simulator) handleTicker(w http.ResponseWriter, r *http.Request) {
+	pair := NormalizePair(r.PathValue("pair"))
+	for _, st := range s.engine.Stats(s.midPrice) {
+		if st.Pair != pair {
+			continue
+		}
+		pc, _ := s.engine.PairConfig(pair)
+		writeJSON(w, http.StatusOK, map[string]interface{}{"ticker": s.tickerJSON(pc, st)})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"error": "invalid_pair", "error_description": "Invalid Pair"})
+}
+
+func (s *Simulator) handleTrades(w http.ResponseWriter, r *http.Request) {
+	pair := r.PathValue("pair")
+	trades, ok := s.engine.RecentTrades(pair, 1000)
+	if !ok {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"error": "invalid_pair", "error_description": "Invalid Pair"})
+		return
+	}
+
+	prec := s.volumePrecision(NormalizePair(pair))
+	result := make([]indodax.Trade, 0, len(trades))
+	for _, t := range trades {
+		result = append(result, indodax.Trade{
+			Date:   strconv.FormatInt(t.Timestamp.Unix(), 10),
+			Price:  formatPrice(t.Price),
+			Amount: formatCoin(t.Amount, prec),
+			TID:    strconv.FormatInt(t.ID, 10),
+			Type:   t.Side,
+		})
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Simulator) handleDepth(w http.ResponseWriter, r *http.Request) {
+	pair := r.PathValue("pair")
+	bids, asks, ok := s.engine.Depth(pair, 150)
+	if !ok {
+		writeJSON(w, http.StatusOK, map[string]interface{}{"error": "invalid_pair", "error_description": "Invalid Pair"})
+		return
+	}
+
+	prec := s.volumePrecision(NormalizePair(pair))
+	toRows := func(levels []DepthLevel) [][]interface{} {
+		rows := make([][]interface{}, 0, len(levels))
+		for _, l := range levels {
+			rows = append(rows, []interface{}{l.Price, formatCoin(l.Amount, prec)})
+		}
+		return rows
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"buy":  toRows(bids),
+		"sell": toRows(asks),
+	})
+}
+
+func (s *Simulator) tickerJSON(pc PairConfig, st Stats24h) map[string]interface{} {
+	return map[string]interface{}{
+		"high":                   formatPrice(st.High),
+		"low":                    formatPrice(st.Low),
+		"vol_" + pc.BaseCurrency: formatCoin(st.VolCoin, pc.VolumePrecision),
+		"vol_idr":                strconv.FormatFloat(math.Floor(st.VolIDR), 'f', 0, 64),
+		"last":                   formatPrice(st.Last),
+		"buy":                    formatPrice(st.BestBid),
+		"sell":                   formatPrice(st.BestAsk),
+		"server_time":            time.Now().Unix(),
+		"name":                   pc.Description,
+	}
+}
+
+// ==================== Private REST ====================
+
+// handleTAPI serves the signed private API
+func (s *Simulator) handleTAPI(w http.ResponseWriter, r *http.Request) {
+	// 1. Authenticate
+	apiKey, form, ok := s.authenticate(w, r)
+	if !ok {
+		return
+	}
+
+	// 2. Nonce must increase per key
+	nonce, err := strconv.ParseInt(form.Get("nonce"), 10, 64)
+	if err != nil {
+		writeTAPIError(w, "Invalid nonce", errCodeInvalidNonce)
+		return
+	}
+	if last, ok := s.checkNonce(apiKey, nonce); !ok {
+		writeTAPIError(w, fmt.Sprintf("Invalid nonce, nonce must be greater than %d", last), errCodeInvalidNonce)
+		return
+	}
+
+	// 3. The key must have the scope of the method
+	if acc, _ := s.engine.Account(apiKey); !acc.HasPermission(tapiScope(form.Get("method"))) {
+		writeTAPIError(w, "No permission", errCodeNoPermission)
+		return
+	}
+
+	// 4. Dispatch
+	switch form.Get("method") {
+	case "getInfo":
+		s.tapiGetInfo(w, apiKey)
+	case "trade":
+		s.tapiTrade(w, apiKey, form)
+	case "openOrders":
+		s.tapiOpenOrders(w, apiKey, form)
+	case "orderHistory":
+		s.tapiOrderHistory(w, apiKey, form)
+	case "getOrder":
+		s.tapiGetOrder(w, apiKey, form)
+	case "getOrderByClientOrderId":
+		s.tapiGetOrderByClientOrderID(w, apiKey, form)
+	case "cancelOrder":
+		s.tapiCancelOrder(w, apiKey, form)
+	case "cancelByClientOrderId":
+		s.tapiCancelByClientOrderID(w, apiKey, form)
+	case "withdrawFee":
+		writeTAPISuccess(w, map[string]interface{}{
+			"server_time":  time.Now().Unix(),
+			"withdraw_fee": 0,
+			"currency":     form.Get("currency"),
+		})
+	case "withdrawCoin":
+		// Funds never leave the simulator
+		writeTAPIError(w, "Withdrawals are not supported by the simulator", errCodeInvalidParameter)
+	default:
+		writeTAPIError(w, "Invalid method", errCodeInvalidMethod)
+	}
+}
+
+// tapiScope returns the permission a /tapi method requires
+func tapiScope(method string) string {
+	switch method {
+	case "trade", "cancelOrder", "cancelByClientOrderId":
+		return "trade"
+	case "withdrawFee", "withdrawCoin":
+		return "withdraw"
+	default:
+		return "view"
+	}
+}
+
+// handleCountdownCancelAll serves the deadman switch endpoint
+func (s *Simulator) handleCountdownCancelAll(w http.ResponseWriter, r *http.Request) {
+	apiKey, form, ok := s.authenticate(w, r)
+	if !ok {
+		return
+	}
+	if acc, _ := s.engine.Account(apiKey); !acc.HasPermission("trade") {
+		writeTAPIError(w, "No permission", errCodeNoPermission)
+		return
+	}
+
+	// Timestamp must be within recvWindow
+	ts, err := strconv.ParseInt(form.Get("timestamp"), 10, 64)
+	if err != nil {
+		writeTAPIError(w, "Invalid timestamp", errCodeInvalidParameter)
+		return
+	}
+	recvWindow, err := strconv.ParseInt(form.Get("recvWindow"), 10, 64)
+	if err != nil || recvWindow <= 0 {
+		recvWindow = 5000
+	}
+	if diff := time.Now().UnixMilli() - ts; diff > recvWindow || diff < -recvWindow {
+		writeTAPIError(w, "Request timestamp is outside of the recvWindow", errCodeInvalidParameter)
+		return
+	}
+
+	countdownMs, err := strconv.ParseInt(form.Get("countdownTime"), 10, 64)
+	if err != nil || countdownMs < 0 {
+		writeTAPIError(w, "Invalid countdownTime", errCodeInvalidParameter)
+		return
+	}
+
+	pairs := strings.Split(form.Get("pair"), ",")
+	for _, pair := range pairs {
+		if _, ok := s.engine.PairConfig(pair); !ok {
+			writeTAPIError(w, "Invalid pair", errCodeInvalidPair)
+			return
+		}
+	}
+	for _, pair := range pairs {
+		s.armDeadman(apiKey, NormalizePair(pair), time.Duration(countdownMs)*time.Millisecond)
+	}
+
+	writeTAPISuccess(w, map[string]interface{}{
+		"pair":          form.Get("pair"),
+		"countdownTime": countdownMs,
+	})
+}
+
+// handleGenerateToken issues a private WebSocket token
+func (s *Simulator) handleGenerateToken(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeTAPIError(w, "Invalid request", errCodeInvalidParameter)
+		return
+	}
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		writeTAPIError(w, "Invalid request", errCodeInvalidParameter)
+		return
+	}
+
+	apiKey := form.Get("tapi_key")
+	acc, ok := s.engine.Account(apiKey)
+	if !ok || !validSignature(body, acc.APISecret, r.Header.Get("Sign")) {
+		writeTAPIError(w, "Invalid credentials", errCodeInvalidCredentials)
+		return
+	}
+
+	token, channel := s.private.issueToken(apiKey)
+	writeTAPISuccess(w, map[string]interface{}{
+		"connToken": token,
+		"channel":   channel,
+	})
+}
+
+// authenticate checks the Key and Sign headers and returns the parsed form
+func (s *Simulator) authenticate(w http.ResponseWriter, r *http.Request) (string, url.Values, bool) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		writeTAPIError(w, "Invalid request", errCodeInvalidParameter)
+		return "", nil, false
+	}
+
+	apiKey := r.Header.Get("Key")
+	acc, ok := s.engine.Account(apiKey)
+	if !ok {
+		writeTAPIError(w, "Invalid credentials. API not found or session has expired.", errCodeInvalidCredentials)
+		return "", nil, false
+	}
+	if !validSignature(body, acc.APISecret, r.Header.Get("Sign")) {
+		writeTAPIError(w, "Invalid credentials. Bad sign.", errCodeInvalidCredentials)
+		return "", nil, false
+	}
+
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		writeTAPIError(w, "Invalid request", errCodeInvalidParameter)
+		return "", nil, false
+	}
+	return apiKey, form, true
+}
+
+func (s *Simulator) tapiGetInfo(w http.ResponseWriter, apiKey string) {
+	acc, _ := s.engine.Account(apiKey)
+	free, hold := s.engine.Balances(apiKey)
+
+	balance := make(map[string]string, len(free))
+	balanceHold := make(map[string]string, len(hold))
+	for currency, v := range free {
+		balance[currency] = formatBalance(currency, v)
+		balanceHold[currency] = formatBalance(currency, hold[currency])
+	}
+
+	writeTAPISuccess(w, map[string]interface{}{
+		"server_time":         time.Now().Unix(),
+		"balance":             balance,
+		"balance_hold":        balanceHold,
+		"user_id":             acc.UserID,
+		"name":                acc.Name,
+		"email":               acc.Email,
+		"verification_status": "verified",
+	})
+}
+
+func (s *Simulator) tapiTrade(w http.ResponseWriter, apiKey string, form url.Values) {
+	pc, ok := s.engine.PairConfig(form.Get("pair"))
+	if !ok {
+		writeTAPIError(w, "Invalid pair", errCodeInvalidPair)
+		return
+	}
+
+	price, _ := strconv.ParseFloat(form.Get("price"), 64)
+	coin, _ := strconv.ParseFloat(form.Get(pc.BaseCurrency), 64)
+	idr, _ := strconv.ParseFloat(form.Get("idr"), 64)
+
+	result, err := s.engine.PlaceOrder(PlaceOrderRequest{
+		Account:       apiKey,
+		Pair:          pc.ID,
+		Side:          form.Get("type"),
+		Type:          form.Get("order_type"),
+		TimeInForce:   form.Get("time_in_force"),
+		Price:         price,
+		Coin:          coin,
+		IDR:           idr,
+		ClientOrderID: form.Get("client_order_id"),
+	})
+	if err != nil {
+		writeTAPIError(w, err.Error(), engineErrorCode(err))
+		return
+	}
+
+	order := result.Order
+	ret := map[string]interface{}{
+		"fee":             int64(math.Round(result.Fee)),
+		"order_id":        order.ID,
+		"client_order_id": order.ClientOrderID,
+	}
+	if order.Side == "buy" {
+		ret["receive_"+pc.BaseCurrency] = formatCoin(result.GotCoin, pc.VolumePrecision)
+		ret["spend_rp"] = int64(math.Round(result.SpentIDR))
+		ret["remain_rp"] = int64(math.Round(order.RemainCoin * order.Price))
+		if order.Type == "market" {
+			ret["remain_rp"] = int64(math.Round(order.RemainIDR))
+		}
+	} else {
+		ret["receive_rp"] = int64(math.Round(result.GotIDR))
+		ret["spend_"+pc.BaseCurrency] = formatCoin(order.Executed, pc.VolumePrecision)
+		ret["remain_"+pc.BaseCurrency] = formatCoin(order.RemainCoin, pc.VolumePrecision)
+	}
+	writeTAPISuccess(w, ret)
+}
+
+func (s *Simulator) tapiOpenOrders(w http.ResponseWriter, apiKey string, form url.Values) {
+	pair := form.Get("pair")
+	if pair != "" {
+		if _, ok := s.engine.PairConfig(pair); !ok {
+			writeTAPIError(w, "Invalid pair", errCodeInvalidPair)
+			return
+		}
+	}
+
+	orders := s.engine.Orders(apiKey, pair, true)
+	if pair != "" {
+		writeTAPISuccess(w, map[string]interface{}{"orders": s.orderInfos(orders)})
+		return
+	}
+
+	// Without pair, Indodax groups open orders by ticker ID
+	grouped := make(map[string][]map[string]string)
+	for _, o := range orders {
+		pc, _ := s.engine.PairConfig(o.Pair)
+		grouped[tickerID(pc)] = append(grouped[tickerID(pc)], s.orderInfo(o))
+	}
+	writeTAPISuccess(w, map[string]interface{}{"orders": grouped})
+}
+
+func (s *Simulator) tapiOrderHistory(w http.ResponseWriter, apiKey string, form url.Values) {
+	pair := form.Get("pair")
+	if _, ok := s.engine.PairConfig(pair); !ok {
+		writeTAPIError(w, "Invalid pair", errCodeInvalidPair)
+		return
+	}
+
+	count, err := strconv.Atoi(form.Get("count"))
+	if err != nil || count <= 0 {
+		count = 1000
+	}
+	orders := s.engine.Orders(apiKey, pair, false)
+	if len(orders) > count {
+		orders = orders[:count]
+	}
+	writeTAPISuccess(w, map[string]interface{}{"orders": s.orderInfos(orders)})
+}
+
+func (s *Simulator) tapiGetOrder(w http.ResponseWriter, apiKey string, form url.Values) {
+	orderID, err := strconv.ParseInt(form.Get("order_id"), 10, 64)
+	if err != nil {
+		writeTAPIError(w, "Invalid order_id", errCodeInvalidParameter)
+		return
+	}
+	order, err := s.engine.GetOrder(apiKey, orderID)
+	if err != nil {
+		writeTAPIError(w, err.Error(), engineErrorCode(err))
+		return
+	}
+	writeTAPISuccess(w, map[string]interface{}{"order": s.orderInfo(*order)})
+}
+
+func (s *Simulator) tapiGetOrderByClientOrderID(w http.ResponseWriter, apiKey string, form url.Values) {
+	order, err := s.engine.GetOrderByClientOrderID(apiKey, form.Get("client_order_id"))
+	if err != nil {
+		writeTAPIError(w, err.Error(), engineErrorCode(err))
+		return
+	}
+	writeTAPISuccess(w, map[string]interface{}{"order": s.orderInfo(*order)})
+}
+
+func (s *Simulator) tapiCancelOrder(w http.ResponseWriter, apiKey string, form url.Values) {
+	orderID, err := strconv.ParseInt(form.Get("order_id"), 10, 64)
+	if err != nil {
+		writeTAPIError(w, "Invalid order_id", errCodeInvalidParameter)
+		return
+	}
+	order, err := s.engine.CancelOrder(apiKey, orderID)
+	if err != nil {
+		writeTAPIError(w, err.Error(), engineErrorCode(err))
+		return
+	}
+	s.writeCancelResult(w, apiKey, order)
+}
+
+func (s *Simulator) tapiCancelByClientOrderID(w http.ResponseWriter, apiKey string, form url.Values) {
+	order, err := s.engine.CancelByClientOrderID(apiKey, form.Get("client_order_id"))
+	if err != nil {
+		writeTAPIError(w, err.Error(), engineErrorCode(err))
+		return
+	}
+	s.writeCancelResult(w, apiKey, order)
+}
+
+func (s *Simulator) writeCancelResult(w http.ResponseWriter, apiKey string, order *Order) {
+	free, _ := s.engine.Balances(apiKey)
+	balance := make(map[string]string, len(free))
+	for currency, v := range free {
+		balance[currency] = formatBalance(currency, v)
+	}
+
+	pc, _ := s.engine.PairConfig(order.Pair)
+	writeTAPISuccess(w, map[string]interface{}{
+		"order_id":        order.ID,
+		"client_order_id": order.ClientOrderID,
+		"type":            order.Side,
+		"pair":            tickerID(pc),
+		"balance":         balance,
+	})
+}
+
+func (s *Simulator) orderInfos(orders []Order) []map[string]string {
+	infos := make([]map[string]string, 0, len(orders))
+	for _, o := range orders {
+		infos = append(infos, s.orderInfo(o))
+	}
+	return infos
+}
+
+// orderInfo renders an order in the /tapi format (amount keys use the coin name, e.g. order_btc)
+func (s *Simulator) orderInfo(o Order) map[string]string {
+	pc, _ := s.engine.PairConfig(o.Pair)
+	info := map[string]string{
+		"order_id":        strconv.FormatInt(o.ID, 10),
+		"client_order_id": o.ClientOrderID,
+		"submit_time":     strconv.FormatInt(o.SubmitTime.Unix(), 10),
+		"price":           formatPrice(o.Price),
+		"type":            o.Side,
+		"order_type":      o.Type,
+		"status":          o.Status,
+	}
+	if !o.FinishTime.IsZero() {
+		info["finish_time"] = strconv.FormatInt(o.FinishTime.Unix(), 10)
+	}
+	if o.Type == "market" && o.Side == "buy" {
+		info["order_idr"] = formatPrice(o.OrigIDR)
+		info["remain_idr"] = formatPrice(o.RemainIDR)
+		return info
+	}
+	info["order_"+pc.BaseCurrency] = formatCoin(o.OrigCoin, pc.VolumePrecision)
+	info["remain_"+pc.BaseCurrency] = formatCoin(o.RemainCoin, pc.VolumePrecision)
+	return info
+}
+
+// engineErrorCode maps engine errors to Indodax error codes
+func engineErrorCode(err error) string {
+	switch err {
+	case ErrInvalidPair:
+		return errCodeInvalidPair
+	case ErrInsufficientBalance:
+		return errCodeInsufficientBalance
+	case ErrOrderNotFound:
+		return errCodeOrderNotFound
+	default:
+		return errCodeInvalidParameter
+	}
+}
+
+// ==================== Helpers ====================
+
+func validSignature(body []byte, secret, sign string) bool {
+	mac := hmac.New(sha512.New, []byte(secret))
+	mac.Write(body)
+	expected := hex.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(expected), []byte(strings.ToLower(sign)))
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeTAPISuccess(w http.ResponseWriter, ret interface{}) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success": 1,
+		"return":  ret,
+	})
+}
+
+func writeTAPIError(w http.ResponseWriter, msg, code string) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"success":    0,
+		"error":      msg,
+		"error_code": code,
+	})
+}
+
+// tickerID returns the underscore form of a pair (e.g. "btc_idr")
+func tickerID(pc PairConfig) string {
+	return pc.BaseCurrency + "_idr"
+}
+
+// decimals returns the number of decimals needed to represent an increment
+func decimals(increment float64) int {
+	d := 0
+	for d < 8 && math.Abs(increment-math.Round(increment)) > 1e-9 {
+		increment *= 10
+		d++
+	}
+	return d
+}
+
+func formatPrice(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func formatCoin(v float64, precision int) string {
+	return strconv.FormatFloat(v, 'f', precision, 64)
+}
+
+func formatBalance(currency string, v float64) string {
+	if currency == "idr" {
+		return strconv.FormatFloat(math.Floor(v), 'f', 0, 64)
+	}
+	return strconv.FormatFloat(v, 'f', 8, 64)
+}
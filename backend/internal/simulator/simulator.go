@@ -0,0 +1,230 @@
+package simulator
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"tuyul/backend/pkg/logger"
+)
+
+// Simulator ties the matching engine, price paths and network endpoints together
+type Simulator struct {
+	cfg     *Config
+	engine  *Engine
+	paths   map[string]*PricePath
+	public  *publicHub
+	private *privateHub
+	log     *logger.Logger
+
+	rng   *rand.Rand
+	rngMu sync.Mutex
+
+	// Last nonce per API key
+	nonces map[string]int64
+	// Deadman timers, Key: apiKey:pair
+	deadman map[string]*time.Timer
+	mu      sync.Mutex
+}
+
+// New creates a simulator from config
+func New(cfg *Config) *Simulator {
+	seed := cfg.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	s := &Simulator{
+		cfg:     cfg,
+		engine:  NewEngine(cfg),
+		paths:   make(map[string]*PricePath),
+		public:  newPublicHub(),
+		private: newPrivateHub(),
+		log:     logger.GetLogger(),
+		rng:     rand.New(rand.NewSource(seed)),
+		nonces:  make(map[string]int64),
+		deadman: make(map[string]*time.Timer),
+	}
+
+	start := time.Now()
+	for _, p := range cfg.Pairs {
+		s.paths[p.ID] = NewPricePath(p.Path, p.InitialPrice, start, rand.New(rand.NewSource(seed+int64(len(s.paths)))))
+	}
+
+	for _, a := range cfg.Accounts {
+		s.private.registerAccount(a)
+	}
+
+	// Engine callbacks run under the engine lock; hubs only enqueue messages
+	s.engine.SetOrderHandler(func(ev OrderEvent) {
+		s.private.publishOrder(ev.Account, toOrderUpdate(ev, s.volumePrecision(ev.Order.Pair)))
+	})
+	s.engine.SetTradeHandler(func(t Trade) {
+		s.public.publish(tradeActivityChannel(t.Pair), tradeActivityData(t, s.volumePrecision(t.Pair)))
+	})
+
+	return s
+}
+
+// Engine returns the matching engine
+func (s *Simulator) Engine() *Engine {
+	return s.engine
+}
+
+// Handler returns the HTTP handler serving REST and WebSocket endpoints
+func (s *Simulator) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	// Public REST
+	mux.HandleFunc("GET /api/server_time", s.handleServerTime)
+	mux.HandleFunc("GET /api/pairs", s.handlePairs)
+	mux.HandleFunc("GET /api/price_increments", s.handlePriceIncrements)
+	mux.HandleFunc("GET /api/summaries", s.handleSummaries)
+	mux.HandleFunc("GET /api/ticker_all", s.handleTickerAll)
+	mux.HandleFunc("GET /api/ticker/{pair}", s.handleTicker)
+	mux.HandleFunc("GET /api/trades/{pair}", s.handleTrades)
+	mux.HandleFunc("GET /api/depth/{pair}", s.handleDepth)
+
+	// Private REST
+	mux.HandleFunc("POST /tapi", s.handleTAPI)
+	mux.HandleFunc("POST /tapi/countdownCancelAll", s.handleCountdownCancelAll)
+	mux.HandleFunc("POST /api/private_ws/v1/generate_token", s.handleGenerateToken)
+
+	// WebSockets
+	mux.HandleFunc("/ws/", s.public.serveWS(s.orderBookSnapshot))
+	mux.HandleFunc("/pws/", s.private.serveWS)
+
+	return mux
+}
+
+// Run drives price paths and publishes market data until ctx is cancelled
+func (s *Simulator) Run(ctx context.Context) {
+	tick := time.NewTicker(s.cfg.TickInterval())
+	defer tick.Stop()
+
+	// Order book changes are coalesced and published at most every 250ms
+	bookTick := time.NewTicker(250 * time.Millisecond)
+	defer bookTick.Stop()
+
+	s.step(time.Now())
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-tick.C:
+			s.step(now)
+		case <-bookTick.C:
+			for _, pair := range s.engine.TakeDirtyBooks() {
+				s.public.publish(orderBookChannel(pair), s.orderBookSnapshot(pair))
+			}
+		}
+	}
+}
+
+// step advances every price path and re-quotes synthetic liquidity
+func (s *Simulator) step(now time.Time) {
+	for _, p := range s.cfg.Pairs {
+		path := s.paths[p.ID]
+		prev := path.Current()
+		mid := path.Step(now)
+
+		// Synthetic taker flow leans in the direction of the path
+		var takerSide string
+		var takerIDR float64
+		if p.Liquidity.TradeSizeIDR > 0 && s.random() < p.Liquidity.TradeProbability {
+			takerSide = "sell"
+			up := 0.5
+			if mid > prev {
+				up = 0.7
+			} else if mid < prev {
+				up = 0.3
+			}
+			if s.random() < up {
+				takerSide = "buy"
+			}
+			takerIDR = p.Liquidity.TradeSizeIDR * (0.1 + 0.9*s.random())
+		}
+
+		s.engine.Requote(p.ID, mid, takerSide, takerIDR, s.jitter)
+	}
+
+	s.public.publish(summaryChannel, summaryData(s.engine.Stats(s.midPrice)))
+}
+
+func (s *Simulator) midPrice(pair string) float64 {
+	if path, ok := s.paths[pair]; ok {
+		pc, _ := s.engine.PairConfig(pair)
+		return roundToIncrement(path.Current(), pc.PriceIncrement, false)
+	}
+	return 0
+}
+
+func (s *Simulator) volumePrecision(pair string) int {
+	if pc, ok := s.engine.PairConfig(pair); ok {
+		return pc.VolumePrecision
+	}
+	return 8
+}
+
+func (s *Simulator) random() float64 {
+	s.rngMu.Lock()
+	defer s.rngMu.Unlock()
+	return s.rng.Float64()
+}
+
+// jitter returns a size multiplier in [0.5, 1.5)
+func (s *Simulator) jitter() float64 {
+	return 0.5 + s.random()
+}
+
+// checkNonce enforces strictly increasing nonces per API key
+func (s *Simulator) checkNonce(apiKey string, nonce int64) (int64, bool) {
+	if !s.cfg.EnforceNonce {
+		return 0, true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	last := s.nonces[apiKey]
+	if nonce <= last {
+		return last, false
+	}
+	s.nonces[apiKey] = nonce
+	return last, true
+}
+
+// armDeadman (re)starts or stops the countdownCancelAll timer of an account pair
+func (s *Simulator) armDeadman(apiKey, pair string, countdown time.Duration) {
+	key := apiKey + ":" + pair
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if t, ok := s.deadman[key]; ok {
+		t.Stop()
+		delete(s.deadman, key)
+	}
+	if countdown <= 0 {
+		return
+	}
+
+	var timer *time.Timer
+	timer = time.AfterFunc(countdown, func() {
+		s.mu.Lock()
+		if s.deadman[key] != timer {
+			// Refreshed or stopped meanwhile
+			s.mu.Unlock()
+			return
+		}
+		delete(s.deadman, key)
+		s.mu.Unlock()
+
+		n := s.engine.CancelAll(apiKey, pair)
+		s.log.Warnf("Deadman switch fired for %s on %s: cancelled %d orders", apiKey, pair, n)
+	})
+	s.deadman[key] = timer
+}
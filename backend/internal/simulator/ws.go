@@ -0,0 +1,521 @@
+package simulator
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"tuyul/backend/pkg/indodax"
+)
+
+// Public channels
+const (
+	summaryChannel       = "market:summary-24h"
+	orderBookPrefix      = "market:order-book-"
+	tradeActivityPrefix  = "market:trade-activity-"
+	privateChannelPrefix = "pws:#"
+)
+
+// Centrifugo method numbers used by the public WebSocket
+const (
+	wsMethodConnect     = 0
+	wsMethodSubscribe   = 1
+	wsMethodUnsubscribe = 2
+	wsMethodPing        = 7
+)
+
+const (
+	wsSendBuffer   = 256
+	wsPingInterval = 25 * time.Second
+	wsWriteTimeout = 10 * time.Second
+	wsTokenTTL     = time.Hour
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  4096,
+	WriteBufferSize: 4096,
+	CheckOrigin: func(r *http.Request) bool {
+		return true
+	},
+}
+
+func orderBookChannel(pair string) string {
+	return orderBookPrefix + pair
+}
+
+func tradeActivityChannel(pair string) string {
+	return tradeActivityPrefix + pair
+}
+
+// wsConn is a single WebSocket connection with a buffered writer
+type wsConn struct {
+	conn *websocket.Conn
+	send chan []byte
+	done chan struct{}
+	once sync.Once
+}
+
+func newWSConn(conn *websocket.Conn) *wsConn {
+	c := &wsConn{
+		conn: conn,
+		send: make(chan []byte, wsSendBuffer),
+		done: make(chan struct{}),
+	}
+	go c.writePump()
+	return c
+}
+
+// enqueue sends a message without blocking; slow clients are disconnected
+func (c *wsConn) enqueue(v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	select {
+	case c.send <- data:
+	case <-c.done:
+	default:
+		c.close()
+	}
+}
+
+func (c *wsConn) close() {
+	c.once.Do(func() {
+		close(c.done)
+		c.conn.Close()
+	})
+}
+
+func (c *wsConn) writePump() {
+	ping := time.NewTicker(wsPingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case data := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+			if err := c.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				c.close()
+				return
+			}
+		case <-ping.C:
+			c.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				c.close()
+				return
+			}
+		}
+	}
+}
+
+// ==================== Public WebSocket ====================
+
+// publicHub serves market channels using the Centrifugo-style public protocol
+type publicHub struct {
+	// Subscriptions per connection
+	clients map[*wsConn]map[string]bool
+	// Publication offset per channel
+	offsets map[string]int64
+	mu      sync.Mutex
+}
+
+func newPublicHub() *publicHub {
+	return &publicHub{
+		clients: make(map[*wsConn]map[string]bool),
+		offsets: make(map[string]int64),
+	}
+}
+
+type publicRequest struct {
+	ID     int64             `json:"id"`
+	Method int               `json:"method"`
+	Params map[string]string `json:"params"`
+}
+
+// serveWS handles a public connection; snapshot returns the current order book for new subscribers
+func (h *publicHub) serveWS(snapshot func(pair string) interface{}) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		c := newWSConn(conn)
+
+		h.mu.Lock()
+		h.clients[c] = make(map[string]bool)
+		h.mu.Unlock()
+
+		defer func() {
+			h.mu.Lock()
+			delete(h.clients, c)
+			h.mu.Unlock()
+			c.close()
+		}()
+
+		for {
+			_, message, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+
+			var req publicRequest
+			if err := json.Unmarshal(message, &req); err != nil {
+				continue
+			}
+
+			switch req.Method {
+			case wsMethodConnect:
+				c.enqueue(map[string]interface{}{
+					"id": req.ID,
+					"result": map[string]interface{}{
+						"client":  randomHex(16),
+						"version": "simulator",
+						"expires": true,
+						"ttl":     int(wsTokenTTL.Seconds()),
+					},
+				})
+			case wsMethodSubscribe:
+				channel := req.Params["channel"]
+				h.mu.Lock()
+				h.clients[c][channel] = true
+				offset := h.offsets[channel]
+				h.mu.Unlock()
+
+				c.enqueue(map[string]interface{}{
+					"id": req.ID,
+					"result": map[string]interface{}{
+						"recoverable": true,
+						"epoch":       "simulator",
+						"offset":      offset,
+					},
+				})
+
+				// New order book subscribers get the current book right away
+				if strings.HasPrefix(channel, orderBookPrefix) {
+					data := snapshot(strings.TrimPrefix(channel, orderBookPrefix))
+					if data != nil {
+						h.publishTo(c, channel, data)
+					}
+				}
+			case wsMethodUnsubscribe:
+				h.mu.Lock()
+				delete(h.clients[c], req.Params["channel"])
+				h.mu.Unlock()
+				c.enqueue(map[string]interface{}{"id": req.ID, "result": map[string]interface{}{}})
+			case wsMethodPing:
+				c.enqueue(map[string]interface{}{"id": req.ID})
+			}
+		}
+	}
+}
+
+// publish sends data to all subscribers of a channel
+func (h *publicHub) publish(channel string, data interface{}) {
+	h.mu.Lock()
+	h.offsets[channel]++
+	msg := publicPush(channel, data, h.offsets[channel])
+	var targets []*wsConn
+	for c, subs := range h.clients {
+		if subs[channel] {
+			targets = append(targets, c)
+		}
+	}
+	h.mu.Unlock()
+
+	for _, c := range targets {
+		c.enqueue(msg)
+	}
+}
+
+// publishTo sends data to a single subscriber
+func (h *publicHub) publishTo(c *wsConn, channel string, data interface{}) {
+	h.mu.Lock()
+	offset := h.offsets[channel]
+	h.mu.Unlock()
+	c.enqueue(publicPush(channel, data, offset))
+}
+
+func publicPush(channel string, data interface{}, offset int64) map[string]interface{} {
+	return map[string]interface{}{
+		"result": map[string]interface{}{
+			"channel": channel,
+			"data": map[string]interface{}{
+				"data":   data,
+				"offset": offset,
+			},
+		},
+	}
+}
+
+// summaryData renders market:summary-24h rows: [pair, ts, last, low, high, open, volIDR, volCoin]
+func summaryData(stats []Stats24h) [][]interface{} {
+	now := time.Now().Unix()
+	rows := make([][]interface{}, 0, len(stats))
+	for _, st := range stats {
+		rows = append(rows, []interface{}{
+			st.Pair,
+			now,
+			st.Last,
+			st.Low,
+			st.High,
+			st.Open,
+			strconv.FormatFloat(st.VolIDR, 'f', 2, 64),
+			strconv.FormatFloat(st.VolCoin, 'f', 8, 64),
+		})
+	}
+	return rows
+}
+
+// tradeActivityData renders market:trade-activity rows: [pair, ts, seq, side, price, idrVol, coinVol]
+func tradeActivityData(t Trade, precision int) [][]interface{} {
+	return [][]interface{}{{
+		t.Pair,
+		t.Timestamp.Unix(),
+		t.ID,
+		t.Side,
+		t.Price,
+		strconv.FormatFloat(t.Price*t.Amount, 'f', 2, 64),
+		formatCoin(t.Amount, precision),
+	}}
+}
+
+// orderBookSnapshot renders a market:order-book push (volume keys use the coin name, e.g. btc_volume)
+func (s *Simulator) orderBookSnapshot(pair string) interface{} {
+	pc, ok := s.engine.PairConfig(pair)
+	if !ok {
+		return nil
+	}
+	bids, asks, _ := s.engine.Depth(pair, 100)
+
+	toRows := func(levels []DepthLevel) []map[string]string {
+		rows := make([]map[string]string, 0, len(levels))
+		for _, l := range levels {
+			rows = append(rows, map[string]string{
+				"price":                     formatPrice(l.Price),
+				pc.BaseCurrency + "_volume": formatCoin(l.Amount, pc.VolumePrecision),
+				"idr_volume":                strconv.FormatFloat(l.Price*l.Amount, 'f', 0, 64),
+			})
+		}
+		return rows
+	}
+
+	return map[string]interface{}{
+		"pair": pc.ID,
+		"ask":  toRows(asks),
+		"bid":  toRows(bids),
+	}
+}
+
+// ==================== Private WebSocket ====================
+
+// privateHub serves per-account order updates using the private WebSocket protocol
+type privateHub struct {
+	// Private channel per API key
+	channels map[string]string
+	// Issued tokens, Key: token
+	tokens map[string]privateToken
+	// Subscribed connections per API key
+	subscribers map[string]map[*wsConn]bool
+	offsets     map[string]int64
+	mu          sync.Mutex
+}
+
+type privateToken struct {
+	apiKey  string
+	expires time.Time
+}
+
+func newPrivateHub() *privateHub {
+	return &privateHub{
+		channels:    make(map[string]string),
+		tokens:      make(map[string]privateToken),
+		subscribers: make(map[string]map[*wsConn]bool),
+		offsets:     make(map[string]int64),
+	}
+}
+
+// registerAccount derives a stable private channel for an account
+func (h *privateHub) registerAccount(acc AccountConfig) {
+	sum := sha256.Sum256([]byte(acc.APIKey))
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.channels[acc.APIKey] = privateChannelPrefix + hex.EncodeToString(sum[:8])
+}
+
+// issueToken creates a connection token for an account
+func (h *privateHub) issueToken(apiKey string) (string, string) {
+	token := randomHex(32)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// Drop expired tokens
+	now := time.Now()
+	for t, info := range h.tokens {
+		if now.After(info.expires) {
+			delete(h.tokens, t)
+		}
+	}
+
+	h.tokens[token] = privateToken{apiKey: apiKey, expires: now.Add(wsTokenTTL)}
+	return token, h.channels[apiKey]
+}
+
+type privateRequest struct {
+	ID      int64 `json:"id"`
+	Connect *struct {
+		Token string `json:"token"`
+	} `json:"connect"`
+	Subscribe *struct {
+		Channel string `json:"channel"`
+	} `json:"subscribe"`
+}
+
+func (h *privateHub) serveWS(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	c := newWSConn(conn)
+
+	var apiKey string
+	defer func() {
+		if apiKey != "" {
+			h.mu.Lock()
+			delete(h.subscribers[apiKey], c)
+			h.mu.Unlock()
+		}
+		c.close()
+	}()
+
+	for {
+		_, message, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var req privateRequest
+		if err := json.Unmarshal(message, &req); err != nil {
+			continue
+		}
+
+		switch {
+		case req.Connect != nil:
+			h.mu.Lock()
+			info, ok := h.tokens[req.Connect.Token]
+			h.mu.Unlock()
+			if !ok || time.Now().After(info.expires) {
+				c.enqueue(wsError(req.ID, 109, "token expired"))
+				continue
+			}
+			apiKey = info.apiKey
+			c.enqueue(map[string]interface{}{
+				"id": req.ID,
+				"connect": map[string]interface{}{
+					"client":  randomHex(16),
+					"version": "simulator",
+					"expires": true,
+					"ttl":     int(time.Until(info.expires).Seconds()),
+				},
+			})
+		case req.Subscribe != nil:
+			h.mu.Lock()
+			allowed := apiKey != "" && h.channels[apiKey] == req.Subscribe.Channel
+			if allowed {
+				if h.subscribers[apiKey] == nil {
+					h.subscribers[apiKey] = make(map[*wsConn]bool)
+				}
+				h.subscribers[apiKey][c] = true
+			}
+			h.mu.Unlock()
+
+			if !allowed {
+				c.enqueue(wsError(req.ID, 103, "permission denied"))
+				continue
+			}
+			c.enqueue(map[string]interface{}{"id": req.ID, "subscribe": map[string]interface{}{}})
+		}
+	}
+}
+
+// publishOrder pushes an order update to all connections of an account
+func (h *privateHub) publishOrder(apiKey string, update indodax.OrderUpdate) {
+	h.mu.Lock()
+	channel := h.channels[apiKey]
+	h.offsets[apiKey]++
+	offset := h.offsets[apiKey]
+	var targets []*wsConn
+	for c := range h.subscribers[apiKey] {
+		targets = append(targets, c)
+	}
+	h.mu.Unlock()
+
+	msg := map[string]interface{}{
+		"push": map[string]interface{}{
+			"channel": channel,
+			"pub": map[string]interface{}{
+				"data": []map[string]interface{}{{
+					"eventType": "order_update",
+					"order":     update,
+				}},
+				"offset": offset,
+			},
+		},
+	}
+	for _, c := range targets {
+		c.enqueue(msg)
+	}
+}
+
+// toOrderUpdate renders an engine event in the private WebSocket order format
+func toOrderUpdate(ev OrderEvent, precision int) indodax.OrderUpdate {
+	o := ev.Order
+	price := o.Price
+	if o.Type == "market" {
+		price = ev.FillPrice
+	}
+
+	update := indodax.OrderUpdate{
+		OrderID:         o.Pair + "-" + o.Type + "-" + strconv.FormatInt(o.ID, 10),
+		Symbol:          o.Pair,
+		Side:            strings.ToUpper(o.Side),
+		OrigQty:         formatCoin(o.OrigCoin, precision),
+		UnfilledQty:     formatCoin(o.RemainCoin, precision),
+		ExecutedQty:     formatCoin(o.Executed, precision),
+		Price:           formatPrice(price),
+		Status:          ev.Status,
+		ClientOrderID:   o.ClientOrderID,
+		TransactionTime: time.Now().UnixMilli(),
+	}
+	if ev.TradeID > 0 {
+		update.TradeID = strconv.FormatInt(ev.TradeID, 10)
+	}
+	return update
+}
+
+func wsError(id int64, code int, message string) map[string]interface{} {
+	return map[string]interface{}{
+		"id": id,
+		"error": map[string]interface{}{
+			"code":    code,
+			"message": message,
+		},
+	}
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -3,7 +3,8 @@ package util
 import (
 	"errors"
 	"net/http"
-	"strings"
+
+	"tuyul/backend/internal/exchange"
 )
 
 // AppError represents an application error with HTTP status code
@@ -38,6 +39,8 @@ const (
 	ErrCodeTokenExpired     = "TOKEN_EXPIRED"
 	ErrCodeTokenInvalid     = "TOKEN_INVALID"
 	ErrCodeAPIKeyInvalid    = "API_KEY_INVALID"
+	ErrCodeAPIKeyWithdraw   = "API_KEY_WITHDRAW_PERMISSION"
+	ErrCodeAPIKeyNotFound   = "API_KEY_NOT_FOUND"
 	ErrCodeOrderNotFound    = "ORDER_NOT_FOUND"
 	ErrCodeBotNotFound      = "BOT_NOT_FOUND"
 	ErrCodeIndodaxAPI       = "INDODAX_API_ERROR"
@@ -90,6 +93,10 @@ func ErrNotFound(message string) *AppError {
 	return NewAppError(http.StatusNotFound, ErrCodeNotFound, message)
 }
 
+func ErrAPIKeyNotFound() *AppError {
+	return NewAppError(http.StatusNotFound, ErrCodeAPIKeyNotFound, "API key not found")
+}
+
 func ErrConflict(message string) *AppError {
 	return NewAppError(http.StatusConflict, ErrCodeConflict, message)
 }
@@ -126,12 +133,10 @@ func IsAPIKeyError(err error) bool {
 	if err == nil {
 		return false
 	}
-	errStr := strings.ToLower(err.Error())
-	return strings.Contains(errStr, "api key") ||
-		strings.Contains(errStr, "invalid credentials") ||
-		strings.Contains(errStr, "bad sign") ||
-		strings.Contains(errStr, ErrCodeAPIKeyInvalid) ||
-		strings.Contains(errStr, "api_key_invalid")
+	if appErr := GetAppError(err); appErr != nil && (appErr.Code == ErrCodeAPIKeyInvalid || appErr.Code == ErrCodeAPIKeyNotFound) {
+		return true
+	}
+	return exchange.IsKind(err, exchange.ErrorKindAuth) || exchange.IsKind(err, exchange.ErrorKindPermission)
 }
 
 // IsCriticalTradingError checks if an error is critical and should stop the bot
@@ -139,23 +144,40 @@ func IsCriticalTradingError(err error) bool {
 	if err == nil {
 		return false
 	}
-	errStr := strings.ToLower(err.Error())
 	// API key errors
 	if IsAPIKeyError(err) {
 		return true
 	}
 	// Invalid pair - configuration issue that prevents trading
-	if strings.Contains(errStr, "invalid pair") {
-		return true
-	}
-	return false
+	// Trading disabled - the process refuses live orders (market data replay)
+	return exchange.IsKind(err, exchange.ErrorKindInvalidPair) || exchange.IsKind(err, exchange.ErrorKindTradingDisabled)
 }
 
 // IsOrderNotFoundError checks if an error is "Order not found" (non-critical, order already filled/cancelled)
 func IsOrderNotFoundError(err error) bool {
-	if err == nil {
-		return false
+	return exchange.IsKind(err, exchange.ErrorKindOrderNotFound)
+}
+
+// NewExchangeAppError maps an exchange failure to an AppError with a matching status and code
+func NewExchangeAppError(message string, err error) *AppError {
+	status, code := http.StatusBadRequest, ErrCodeIndodaxAPI
+
+	switch exchange.KindOf(err) {
+	case exchange.ErrorKindInsufficientBalance:
+		code = ErrCodeInsufficientBalance
+	case exchange.ErrorKindAuth, exchange.ErrorKindPermission:
+		code = ErrCodeAPIKeyInvalid
+	case exchange.ErrorKindInvalidPair, exchange.ErrorKindOrderBelowMinimum:
+		code = ErrCodeValidation
+	case exchange.ErrorKindOrderNotFound:
+		status, code = http.StatusNotFound, ErrCodeOrderNotFound
+	case exchange.ErrorKindTradingDisabled:
+		status, code = http.StatusForbidden, ErrCodeForbidden
+	case exchange.ErrorKindRateLimited:
+		status, code = http.StatusTooManyRequests, ErrCodeRateLimit
+	case exchange.ErrorKindMaintenance, exchange.ErrorKindNetwork, exchange.ErrorKindInvalidNonce:
+		status = http.StatusServiceUnavailable
 	}
-	errStr := strings.ToLower(err.Error())
-	return strings.Contains(errStr, "order not found")
+
+	return NewAppErrorWithDetails(status, code, message, err.Error())
 }
@@ -2,7 +2,7 @@ package util
 
 import (
 	"fmt"
-	"tuyul/backend/pkg/indodax"
+	"tuyul/backend/internal/exchange"
 	"tuyul/backend/pkg/logger"
 )
 
@@ -20,7 +20,7 @@ func ValidateOrderAmount(
 	botID int64,
 	amount float64,
 	price float64,
-	pairInfo indodax.Pair,
+	pairInfo exchange.Pair,
 	volumePrecision int,
 	baseCurrency string,
 	log *logger.Logger,
@@ -29,10 +29,10 @@ func ValidateOrderAmount(
 	roundedAmount := FloorToPrecision(amount, volumePrecision)
 	orderValue := roundedAmount * price
 
-	// Validate minimum coin amount (TradeMinTradedCurrency is the minimum coin amount, e.g., 0.001 BTC, 1.0 CST)
-	if roundedAmount < pairInfo.TradeMinTradedCurrency {
+	// Validate minimum coin amount (MinBaseAmount is the minimum coin amount, e.g., 0.001 BTC, 1.0 CST)
+	if roundedAmount < pairInfo.MinBaseAmount {
 		reason := fmt.Sprintf("Coin amount too small - %.8f < minimum %.8f",
-			roundedAmount, pairInfo.TradeMinTradedCurrency)
+			roundedAmount, pairInfo.MinBaseAmount)
 		log.Debugf("Bot %d: %s", botID, reason)
 		return OrderValidationResult{
 			Valid:  false,
@@ -40,10 +40,10 @@ func ValidateOrderAmount(
 		}
 	}
 
-	// Validate minimum order value in IDR (TradeMinBaseCurrency is the minimum IDR value)
-	if orderValue < float64(pairInfo.TradeMinBaseCurrency) {
-		reason := fmt.Sprintf("Order value too small - %.2f IDR < minimum %d IDR",
-			orderValue, pairInfo.TradeMinBaseCurrency)
+	// Validate minimum order value in IDR (MinQuoteAmount is the minimum IDR value)
+	if orderValue < pairInfo.MinQuoteAmount {
+		reason := fmt.Sprintf("Order value too small - %.2f IDR < minimum %.0f IDR",
+			orderValue, pairInfo.MinQuoteAmount)
 		log.Debugf("Bot %d: %s (amount=%.8f * price=%.2f)", botID, reason, roundedAmount, price)
 		return OrderValidationResult{
 			Valid:  false,
@@ -51,9 +51,9 @@ func ValidateOrderAmount(
 		}
 	}
 
-	log.Debugf("Bot %d: Order validation passed - amount=%.8f %s (min=%.8f), value=%.2f IDR (min=%d)",
-		botID, roundedAmount, baseCurrency, pairInfo.TradeMinTradedCurrency,
-		orderValue, pairInfo.TradeMinBaseCurrency)
+	log.Debugf("Bot %d: Order validation passed - amount=%.8f %s (min=%.8f), value=%.2f IDR (min=%.0f)",
+		botID, roundedAmount, baseCurrency, pairInfo.MinBaseAmount,
+		orderValue, pairInfo.MinQuoteAmount)
 
 	return OrderValidationResult{
 		Valid:      true,
@@ -1,12 +1,12 @@
 package util
 
 import (
-	"tuyul/backend/pkg/indodax"
+	"tuyul/backend/internal/exchange"
 )
 
 // GetVolumePrecision returns the volume precision for a pair
 // Uses VolumePrecision if valid, otherwise falls back to PriceRound, then default to 8
-func GetVolumePrecision(pairInfo indodax.Pair) int {
+func GetVolumePrecision(pairInfo exchange.Pair) int {
 	volumePrecision := pairInfo.VolumePrecision
 	if volumePrecision == 0 {
 		if pairInfo.PriceRound > 0 {
@@ -24,7 +24,7 @@ type PriceIncrementGetter interface {
 	GetPriceIncrement(pairID string) (float64, bool)
 }
 
-func GetTickSize(pair indodax.Pair, incrementGetter PriceIncrementGetter) float64 {
+func GetTickSize(pair exchange.Pair, incrementGetter PriceIncrementGetter) float64 {
 	// Try to get actual price increment from market data service first
 	if incrementGetter != nil {
 		if increment, ok := incrementGetter.GetPriceIncrement(pair.ID); ok && increment > 0 {
@@ -6,24 +6,36 @@ import (
 	"crypto/sha512"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"tuyul/backend/pkg/logger"
+
 	"golang.org/x/time/rate"
 )
 
 // Client represents Indodax API client
 type Client struct {
-	apiURL               string
-	httpClient           *http.Client
-	publicLimiter        *rate.Limiter
-	privateTradeLimiter  *rate.Limiter // 20 requests per second
-	privateCancelLimiter *rate.Limiter // 30 requests per second
+	apiURL         string
+	httpClient     *http.Client
+	publicLimiter  *rate.Limiter
+	deadmanLimiter *rate.Limiter // 10 requests per 10 seconds (per IP)
+
+	// Private API limiters and nonces, Key: API key
+	keys    map[string]*keyState
+	keysMu  sync.Mutex
+	metrics clientMetrics
+
+	// Private WebSocket URL (defaults to PrivateWSURL)
+	privateWSURL string
 }
 
 // NewClient creates a new Indodax client
@@ -35,11 +47,32 @@ func NewClient(apiURL string) *Client {
 		},
 		// Public API rate limited to 180 requests/minute (3 requests/second)
 		publicLimiter: rate.NewLimiter(rate.Limit(3), 1),
-		// Trade API rate limited to 20 requests per second
-		privateTradeLimiter: rate.NewLimiter(rate.Limit(20), 5),
-		// Cancel Order API rate limited to 30 requests per second
-		privateCancelLimiter: rate.NewLimiter(rate.Limit(30), 5),
+		// Deadman switch API rate limited to 10 requests per 10 seconds per IP
+		deadmanLimiter: rate.NewLimiter(rate.Limit(1), 5),
+		keys:           make(map[string]*keyState),
+	}
+}
+
+// SetPrivateWSURL overrides the Private WebSocket URL (e.g. for a local simulator).
+// The cf_ws_frame_ping_pong=true parameter Indodax needs for ping/pong is added if missing.
+func (c *Client) SetPrivateWSURL(wsURL string) {
+	if u, err := url.Parse(wsURL); err == nil && wsURL != "" {
+		q := u.Query()
+		if q.Get("cf_ws_frame_ping_pong") == "" {
+			q.Set("cf_ws_frame_ping_pong", "true")
+			u.RawQuery = q.Encode()
+			wsURL = u.String()
+		}
+	}
+	c.privateWSURL = wsURL
+}
+
+// PrivateWSURL returns the Private WebSocket URL in use
+func (c *Client) PrivateWSURL() string {
+	if c.privateWSURL == "" {
+		return PrivateWSURL
 	}
+	return c.privateWSURL
 }
 
 // CommonResponse represents the common structure of Indodax responses
@@ -189,6 +222,32 @@ type OrderInfo struct {
 	Status        string `json:"status"`
 }
 
+// UnmarshalJSON reads the coin amounts, whose keys are named after the coin (e.g. order_eth, remain_eth)
+func (o *OrderInfo) UnmarshalJSON(data []byte) error {
+	type plain OrderInfo
+	if err := json.Unmarshal(data, (*plain)(o)); err != nil {
+		return err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	for key, raw := range fields {
+		switch key {
+		case "order_id", "order_type", "order_idr", "remain_idr", "order_rp", "remain_rp":
+			continue
+		}
+		value := strings.Trim(string(raw), `"`)
+		if o.OrderCoin == "" && strings.HasPrefix(key, "order_") {
+			o.OrderCoin = value
+		} else if o.RemainCoin == "" && strings.HasPrefix(key, "remain_") {
+			o.RemainCoin = value
+		}
+	}
+	return nil
+}
+
 type OrderHistoryResponse struct {
 	CommonResponse
 	Return *OrderHistoryReturn `json:"return"`
@@ -340,26 +399,51 @@ func (c *Client) createSignature(message, secret string) string {
 	return hex.EncodeToString(mac.Sum(nil))
 }
 
-// doPrivateRequest executes a private API request
+// doPrivateRequest executes a private API request.
+// Requests are rate limited per API key and retried with backoff when
+// rejected for rate limit or nonce.
 func (c *Client) doPrivateRequest(ctx context.Context, method string, params url.Values, key, secret string, result interface{}) error {
-	// Rate Limiting
-	if method == "trade" {
-		if err := c.privateTradeLimiter.Wait(ctx); err != nil {
+	state := c.keyState(key)
+
+	if params == nil {
+		params = url.Values{}
+	}
+
+	delay := privateRetryDelay
+	for attempt := 1; ; attempt++ {
+		// Rate Limiting
+		if err := state.limiter(method).Wait(ctx); err != nil {
 			return err
 		}
-	} else if method == "cancelOrder" {
-		if err := c.privateCancelLimiter.Wait(ctx); err != nil {
+
+		err := c.doPrivateRequestOnce(ctx, method, params, key, secret, state.nextNonce(), result)
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || !apiErr.Retryable() {
 			return err
 		}
-	} else {
-		// Default reasonable limit logic could go here, or shared limiter
-	}
 
-	nonce := time.Now().UnixMilli()
+		if attempt >= maxPrivateAttempts {
+			atomic.AddInt64(&c.metrics.retriesExhausted, 1)
+			return err
+		}
 
-	if params == nil {
-		params = url.Values{}
+		if apiErr.Kind == ErrKindInvalidNonce {
+			atomic.AddInt64(&c.metrics.nonceRetries, 1)
+			state.bumpNonce(apiErr.MinNonce)
+		} else {
+			atomic.AddInt64(&c.metrics.rateLimitRetries, 1)
+		}
+		logger.Warnf("Indodax %s rejected (%v), retrying in %s (attempt %d/%d)", method, err, delay, attempt, maxPrivateAttempts)
+
+		if err := sleepCtx(ctx, delay); err != nil {
+			return err
+		}
+		delay *= 2
 	}
+}
+
+// doPrivateRequestOnce signs and sends one private API request
+func (c *Client) doPrivateRequestOnce(ctx context.Context, method string, params url.Values, key, secret string, nonce int64, result interface{}) error {
 	params.Set("method", method)
 	params.Set("nonce", fmt.Sprintf("%d", nonce))
 	params.Set("timestamp", fmt.Sprintf("%d", nonce)) // Good practice even if nonce used
@@ -378,7 +462,7 @@ func (c *Client) doPrivateRequest(ctx context.Context, method string, params url
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to send request: %w", err)
+		return newNetworkError(err)
 	}
 	defer resp.Body.Close()
 
@@ -387,8 +471,15 @@ func (c *Client) doPrivateRequest(ctx context.Context, method string, params url
 		return fmt.Errorf("failed to read response: %w", err)
 	}
 
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= http.StatusInternalServerError {
+		return newAPIError(http.StatusText(resp.StatusCode), "", resp.StatusCode)
+	}
+
 	// First unmarshal into common response to check success
-	var common CommonResponse
+	var common struct {
+		CommonResponse
+		ErrorCode string `json:"error_code,omitempty"`
+	}
 	if err := json.Unmarshal(body, &common); err != nil {
 		return fmt.Errorf("failed to parse response: %w", err)
 	}
@@ -401,7 +492,7 @@ func (c *Client) doPrivateRequest(ctx context.Context, method string, params url
 		if errMsg == "" {
 			errMsg = "unknown error from indodax"
 		}
-		return fmt.Errorf("indodax API error: %s", errMsg)
+		return newAPIError(errMsg, common.ErrorCode, resp.StatusCode)
 	}
 
 	// If successful, unmarshal into the specific result
@@ -413,13 +504,11 @@ func (c *Client) doPrivateRequest(ctx context.Context, method string, params url
 }
 
 // ValidateAPIKey validates Indodax API credentials by calling getInfo
-func (c *Client) ValidateAPIKey(key, secret string) (bool, error) {
-	_, err := c.GetInfo(context.Background(), key, secret)
+func (c *Client) ValidateAPIKey(ctx context.Context, key, secret string) (bool, error) {
+	_, err := c.GetInfo(ctx, key, secret)
 	if err != nil {
 		// If error is related to credentials, return false, nil
-		// Note: We need a better way to check specifically for auth errors
-		// For now simple error check
-		if strings.Contains(err.Error(), "Invalid credentials") || strings.Contains(err.Error(), "invalid_credentials") {
+		if IsErrorKind(err, ErrKindAuth) {
 			return false, nil
 		}
 		return false, err
@@ -600,6 +689,72 @@ func (c *Client) CancelByClientOrderID(ctx context.Context, key, secret string,
 	return result.Return, nil
 }
 
+// CountdownCancelAll arms (or refreshes) the deadman switch for the given pairs.
+// If it is not called again within countdownMs, all open orders of those pairs are cancelled.
+// A countdownMs of 0 stops the timer.
+// pairs use the ticker_id format (e.g. "btc_idr").
+func (c *Client) CountdownCancelAll(ctx context.Context, key, secret string, pairs []string, countdownMs int64) error {
+	if len(pairs) == 0 {
+		return fmt.Errorf("pair is empty")
+	}
+
+	if err := c.deadmanLimiter.Wait(ctx); err != nil {
+		return err
+	}
+
+	timestamp := time.Now().UnixMilli()
+
+	params := url.Values{}
+	params.Set("pair", strings.Join(pairs, ","))
+	params.Set("countdownTime", fmt.Sprintf("%d", countdownMs))
+	params.Set("timestamp", fmt.Sprintf("%d", timestamp))
+	params.Set("recvWindow", "5000")
+
+	payload := params.Encode()
+	signature := c.createSignature(payload, secret)
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.apiURL+"/tapi/countdownCancelAll", strings.NewReader(payload))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Key", key)
+	req.Header.Set("Sign", signature)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return newNetworkError(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var result struct {
+		CommonResponse
+		ErrorCode string `json:"error_code,omitempty"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+
+	if result.Success != 1 {
+		errMsg := result.Error
+		if errMsg == "" {
+			errMsg = result.Message
+		}
+		if errMsg == "" {
+			errMsg = "unknown error from indodax"
+		}
+		return newAPIError(errMsg, result.ErrorCode, resp.StatusCode)
+	}
+
+	return nil
+}
+
 // PrivateWSTokenResponse represents the response for generating private WS token
 type PrivateWSTokenResponse struct {
 	Success int                 `json:"success"`
@@ -615,6 +770,10 @@ type PrivateWSTokenInfo struct {
 
 // GeneratePrivateWSToken generates a token for Private WebSocket
 func (c *Client) GeneratePrivateWSToken(ctx context.Context, key, secret string) (*PrivateWSTokenInfo, error) {
+	if err := c.keyState(key).defaultLimiter.Wait(ctx); err != nil {
+		return nil, err
+	}
+
 	u := c.apiURL + "/api/private_ws/v1/generate_token"
 
 	// Create body
@@ -638,7 +797,7 @@ func (c *Client) GeneratePrivateWSToken(ctx context.Context, key, secret string)
 	// Send request
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request: %w", err)
+		return nil, newNetworkError(err)
 	}
 	defer resp.Body.Close()
 
@@ -657,7 +816,7 @@ func (c *Client) GeneratePrivateWSToken(ctx context.Context, key, secret string)
 		if errorMsg == "" {
 			errorMsg = "unknown error"
 		}
-		return nil, fmt.Errorf("failed to generate token: %s", errorMsg)
+		return nil, fmt.Errorf("failed to generate token: %w", newAPIError(errorMsg, "", resp.StatusCode))
 	}
 
 	if result.Return.ConnToken == "" {
@@ -686,7 +845,7 @@ func (c *Client) doPublicRequest(ctx context.Context, endpoint string, result in
 	// Send request
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to send request: %w", err)
+		return newNetworkError(err)
 	}
 	defer resp.Body.Close()
 
@@ -698,7 +857,7 @@ func (c *Client) doPublicRequest(ctx context.Context, endpoint string, result in
 
 	// Check status code
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected status code: %d, body: %s", resp.StatusCode, string(body))
+		return newAPIError(fmt.Sprintf("unexpected status code: %d, body: %s", resp.StatusCode, string(body)), "", resp.StatusCode)
 	}
 
 	// Parse response
@@ -0,0 +1,108 @@
+package indodax
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ErrorKind classifies failed Indodax requests
+type ErrorKind string
+
+const (
+	ErrKindUnknown             ErrorKind = "unknown"
+	ErrKindInsufficientBalance ErrorKind = "insufficient_balance"
+	ErrKindInvalidPair         ErrorKind = "invalid_pair"
+	ErrKindOrderBelowMinimum   ErrorKind = "order_below_minimum"
+	ErrKindOrderNotFound       ErrorKind = "order_not_found"
+	ErrKindInvalidNonce        ErrorKind = "invalid_nonce"
+	ErrKindAuth                ErrorKind = "auth"
+	ErrKindPermission          ErrorKind = "permission" // Key lacks the scope of the method
+	ErrKindRateLimited         ErrorKind = "rate_limited"
+	ErrKindMaintenance         ErrorKind = "maintenance"
+	ErrKindNetwork             ErrorKind = "network"
+)
+
+// APIError is a failed Indodax request
+type APIError struct {
+	Kind    ErrorKind
+	Message string // Message returned by Indodax
+	Code    string // Indodax error_code, if any
+	Status  int    // HTTP status, 0 if no response was received
+
+	// MinNonce is the nonce Indodax expects to be exceeded (invalid nonce only, 0 if unknown)
+	MinNonce int64
+
+	// Err is the underlying transport error (network only)
+	Err error
+}
+
+func (e *APIError) Error() string {
+	if e.Kind == ErrKindNetwork && e.Err != nil {
+		return fmt.Sprintf("indodax network error: %v", e.Err)
+	}
+	return fmt.Sprintf("indodax API error: %s", e.Message)
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// Retryable reports whether the request was rejected before being processed
+// and can be sent again as is (rate limit or nonce)
+func (e *APIError) Retryable() bool {
+	return e.Kind == ErrKindRateLimited || e.Kind == ErrKindInvalidNonce
+}
+
+// IsErrorKind reports whether err is an APIError of the given kind
+func IsErrorKind(err error, kind ErrorKind) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Kind == kind
+}
+
+var nonceFloorPattern = regexp.MustCompile(`(?i)nonce.*?(\d{6,})`)
+
+// newAPIError classifies an error response by HTTP status, error_code and message
+func newAPIError(msg, code string, status int) *APIError {
+	e := &APIError{Kind: ErrKindUnknown, Message: msg, Code: code, Status: status}
+	lower := strings.ToLower(msg + " " + code)
+
+	switch {
+	case status == http.StatusTooManyRequests ||
+		strings.Contains(lower, "too many request") || strings.Contains(lower, "too_many_request") ||
+		strings.Contains(lower, "rate limit"):
+		e.Kind = ErrKindRateLimited
+	case strings.Contains(lower, "nonce"):
+		e.Kind = ErrKindInvalidNonce
+		if m := nonceFloorPattern.FindStringSubmatch(msg); m != nil {
+			e.MinNonce, _ = strconv.ParseInt(m[1], 10, 64)
+		}
+	case strings.Contains(lower, "permission"):
+		e.Kind = ErrKindPermission
+	case strings.Contains(lower, "invalid credentials") || strings.Contains(lower, "invalid_credentials") ||
+		strings.Contains(lower, "bad sign") || strings.Contains(lower, "api not found") ||
+		status == http.StatusUnauthorized || status == http.StatusForbidden:
+		e.Kind = ErrKindAuth
+	case strings.Contains(lower, "insufficient balance") || strings.Contains(lower, "insufficient_balance"):
+		e.Kind = ErrKindInsufficientBalance
+	case strings.Contains(lower, "invalid pair") || strings.Contains(lower, "invalid_pair"):
+		e.Kind = ErrKindInvalidPair
+	case strings.Contains(lower, "minimum order") || strings.Contains(lower, "minimum_order") ||
+		strings.Contains(lower, "min_order") || strings.Contains(lower, "below minimum"):
+		e.Kind = ErrKindOrderBelowMinimum
+	case strings.Contains(lower, "order not found") || strings.Contains(lower, "order_not_found"):
+		e.Kind = ErrKindOrderNotFound
+	case strings.Contains(lower, "maintenance") ||
+		status == http.StatusServiceUnavailable || status == http.StatusBadGateway || status == http.StatusGatewayTimeout:
+		e.Kind = ErrKindMaintenance
+	}
+	return e
+}
+
+// newNetworkError wraps a transport failure (no response received)
+func newNetworkError(err error) *APIError {
+	return &APIError{Kind: ErrKindNetwork, Message: err.Error(), Err: err}
+}
@@ -0,0 +1,125 @@
+package indodax
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	// Attempts of one private request when rejected for rate limit or nonce
+	maxPrivateAttempts = 4
+	// Initial retry delay, doubled on every attempt
+	privateRetryDelay = 250 * time.Millisecond
+)
+
+// keyState holds the rate limiters and nonce counter of one API key.
+// Indodax enforces private limits per account, so keys must not share them.
+type keyState struct {
+	tradeLimiter   *rate.Limiter // 20 requests per second
+	cancelLimiter  *rate.Limiter // 30 requests per second
+	defaultLimiter *rate.Limiter // Other tapi methods, 180 requests per minute
+
+	nonceMu   sync.Mutex
+	lastNonce int64
+}
+
+func newKeyState() *keyState {
+	return &keyState{
+		tradeLimiter:   rate.NewLimiter(rate.Limit(20), 5),
+		cancelLimiter:  rate.NewLimiter(rate.Limit(30), 5),
+		defaultLimiter: rate.NewLimiter(rate.Limit(3), 5),
+	}
+}
+
+// limiter returns the limiter of a tapi method
+func (k *keyState) limiter(method string) *rate.Limiter {
+	switch method {
+	case "trade":
+		return k.tradeLimiter
+	case "cancelOrder":
+		return k.cancelLimiter
+	default:
+		return k.defaultLimiter
+	}
+}
+
+// nextNonce returns a strictly increasing nonce, based on the clock in milliseconds
+func (k *keyState) nextNonce() int64 {
+	k.nonceMu.Lock()
+	defer k.nonceMu.Unlock()
+
+	nonce := time.Now().UnixMilli()
+	if nonce <= k.lastNonce {
+		nonce = k.lastNonce + 1
+	}
+	k.lastNonce = nonce
+	return nonce
+}
+
+// bumpNonce makes the next nonce greater than min (e.g. after the server rejected ours)
+func (k *keyState) bumpNonce(min int64) {
+	k.nonceMu.Lock()
+	defer k.nonceMu.Unlock()
+
+	if min > k.lastNonce {
+		k.lastNonce = min
+	}
+}
+
+// keyState returns the state of an API key, creating it on first use
+func (c *Client) keyState(key string) *keyState {
+	c.keysMu.Lock()
+	defer c.keysMu.Unlock()
+
+	state, ok := c.keys[key]
+	if !ok {
+		state = newKeyState()
+		c.keys[key] = state
+	}
+	return state
+}
+
+// ClientMetrics counts private request retries since start
+type ClientMetrics struct {
+	RateLimitRetries int64 `json:"rate_limit_retries"` // Retries after a rate limit rejection
+	NonceRetries     int64 `json:"nonce_retries"`      // Retries after a nonce rejection
+	RetriesExhausted int64 `json:"retries_exhausted"`  // Requests that still failed after all retries
+	ActiveKeys       int   `json:"active_keys"`        // API keys with limiter state
+}
+
+type clientMetrics struct {
+	rateLimitRetries int64
+	nonceRetries     int64
+	retriesExhausted int64
+}
+
+// Metrics returns a snapshot of the retry counters
+func (c *Client) Metrics() ClientMetrics {
+	c.keysMu.Lock()
+	activeKeys := len(c.keys)
+	c.keysMu.Unlock()
+
+	return ClientMetrics{
+		RateLimitRetries: atomic.LoadInt64(&c.metrics.rateLimitRetries),
+		NonceRetries:     atomic.LoadInt64(&c.metrics.nonceRetries),
+		RetriesExhausted: atomic.LoadInt64(&c.metrics.retriesExhausted),
+		ActiveKeys:       activeKeys,
+	}
+}
+
+// sleepCtx waits for d or until ctx is done
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
@@ -0,0 +1,73 @@
+package indodax
+
+import (
+	"context"
+	"errors"
+	"net/url"
+)
+
+// KeyPermissions are the scopes granted to an API key
+type KeyPermissions struct {
+	View     bool
+	Trade    bool
+	Withdraw bool
+}
+
+// GetPermissions detects the scopes of an API key.
+// Indodax has no method listing them, so each scope is probed with a method that
+// requires it but cannot move funds: getInfo (view), a cancel of order 0 (trade)
+// and withdrawFee (withdraw). A "No permission" error means the scope is missing,
+// any other outcome means the request passed the permission check.
+func (c *Client) GetPermissions(ctx context.Context, key, secret string) (*KeyPermissions, error) {
+	// 1. View: required by getInfo, also rejects invalid credentials
+	if _, err := c.GetInfo(ctx, key, secret); err != nil {
+		return nil, err
+	}
+	perms := &KeyPermissions{View: true}
+
+	// 2. Trade
+	cancel := url.Values{}
+	cancel.Set("pair", "btc_idr")
+	cancel.Set("order_id", "0")
+	cancel.Set("type", "buy")
+	granted, err := c.probePermission(ctx, "cancelOrder", cancel, key, secret)
+	if err != nil {
+		return nil, err
+	}
+	perms.Trade = granted
+
+	// 3. Withdraw
+	withdraw := url.Values{}
+	withdraw.Set("currency", "btc")
+	granted, err = c.probePermission(ctx, "withdrawFee", withdraw, key, secret)
+	if err != nil {
+		return nil, err
+	}
+	perms.Withdraw = granted
+
+	return perms, nil
+}
+
+// probePermission sends a harmless request and reports whether it was
+// rejected for a missing scope (false) or not (true)
+func (c *Client) probePermission(ctx context.Context, method string, params url.Values, key, secret string) (bool, error) {
+	var result CommonResponse
+	err := c.doPrivateRequest(ctx, method, params, key, secret, &result)
+	if err == nil {
+		return true, nil
+	}
+
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return false, err
+	}
+	switch apiErr.Kind {
+	case ErrKindPermission:
+		return false, nil
+	case ErrKindAuth, ErrKindNetwork, ErrKindRateLimited, ErrKindInvalidNonce, ErrKindMaintenance:
+		// Inconclusive, the request did not reach the permission check
+		return false, err
+	default:
+		return true, nil
+	}
+}
@@ -31,6 +31,7 @@ type PrivateWSClient struct {
 
 	onOrderUpdate func(order *OrderUpdate)
 	onError       func(err error)
+	onReconnect   func()
 
 	done      chan struct{}
 	writeChan chan interface{}
@@ -62,6 +63,25 @@ type OrderUpdate struct {
 	Status          string `json:"status"`
 	ClientOrderID   string `json:"clientOrderId"`
 	TransactionTime int64  `json:"transactionTime"`
+
+	// What the fill of this update charged, only set on fills
+	FillInformation *FillInformation `json:"fillInformation,omitempty"`
+}
+
+// FillInformation is the fill of an order update with its fee, tax and clearing charges
+type FillInformation struct {
+	Participant   string  `json:"participant"` // MAKER or TAKER
+	FilledQty     string  `json:"filledQty"`
+	Qty           string  `json:"qty"`
+	FeeAsset      string  `json:"feeAsset"`
+	FeeRate       float64 `json:"feeRate"`
+	Fee           string  `json:"fee"`
+	TaxAsset      string  `json:"taxAsset"`
+	TaxRate       float64 `json:"taxRate"`
+	Tax           string  `json:"tax"`
+	ClearingAsset string  `json:"clearingAsset"`
+	ClearingRate  float64 `json:"clearingRate"`
+	Clearing      string  `json:"clearing"`
 }
 
 // NewPrivateWSClient creates a new Private WebSocket client
@@ -87,6 +107,12 @@ func (c *PrivateWSClient) SetErrorHandler(handler func(err error)) {
 	c.onError = handler
 }
 
+// SetReconnectHandler sets the handler called after an automatic reconnect.
+// Order updates sent while disconnected are lost, so callers should reconcile.
+func (c *PrivateWSClient) SetReconnectHandler(handler func()) {
+	c.onReconnect = handler
+}
+
 // Connect connects to the WebSocket server
 func (c *PrivateWSClient) Connect(ctx context.Context) error {
 	c.mu.Lock()
@@ -125,7 +151,7 @@ func (c *PrivateWSClient) Connect(ctx context.Context) error {
 	c.userChannel = tokenInfo.Channel
 
 	// 2. Connect WS
-	u, err := url.Parse(PrivateWSURL)
+	u, err := url.Parse(c.restClient.PrivateWSURL())
 	if err != nil {
 		return fmt.Errorf("invalid websocket url: %w", err)
 	}
@@ -319,6 +345,9 @@ func (c *PrivateWSClient) readPump() {
 					if c.onError != nil {
 						c.onError(fmt.Errorf("Private WebSocket reconnected successfully"))
 					}
+					if c.onReconnect != nil {
+						go c.onReconnect()
+					}
 					return
 				}
 				
@@ -41,6 +41,7 @@ type WSClient struct {
 	subscriptions map[string]bool
 	handlers      []func(channel string, data []byte)
 	errHandlers   []func(err error)
+	connHandlers  []func()
 
 	done      chan struct{}
 	writeChan chan interface{}
@@ -107,6 +108,13 @@ func (c *WSClient) SetErrorHandler(handler func(err error)) {
 	c.errHandlers = []func(err error){handler}
 }
 
+// AddConnectHandler adds a handler called after every successful (re)connect
+func (c *WSClient) AddConnectHandler(handler func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.connHandlers = append(c.connHandlers, handler)
+}
+
 // Connect connects to the WebSocket server
 func (c *WSClient) Connect() error {
 	c.mu.Lock()
@@ -162,6 +170,13 @@ func (c *WSClient) Connect() error {
 	c.resubscribe()
 
 	logger.Infof("Successfully connected to Indodax WebSocket at %s", c.url)
+
+	c.mu.Lock()
+	connHandlers := c.connHandlers
+	c.mu.Unlock()
+	for _, h := range connHandlers {
+		go h()
+	}
 	return nil
 }
 
@@ -181,6 +196,21 @@ func (c *WSClient) Close() {
 	// Do not close channels here to avoid panic on send if multiple goroutines call Close
 }
 
+// Reconnect drops the connection and reconnects. Used when the connection looks
+// open but stopped delivering data; also restarts reconnection after it gave up.
+func (c *WSClient) Reconnect() {
+	c.mu.Lock()
+	connected := c.isConnected
+	c.mu.Unlock()
+
+	if connected {
+		// readPump fails on the closed connection and starts the reconnection
+		c.Close()
+		return
+	}
+	c.reconnect()
+}
+
 // Subscribe subscribes to a channel
 func (c *WSClient) Subscribe(channel string) {
 	c.mu.Lock()
@@ -44,7 +44,16 @@ func TokenBlacklistKey(token string) string {
 }
 
 // API Key keys
-func APIKeyKey(userID string) string {
+func APIKeyKey(keyID string) string {
+	return fmtKey("api_keys:%s", keyID)
+}
+
+func UserAPIKeysKey(userID string) string {
+	return fmtKey("user_api_keys:%s", userID)
+}
+
+// LegacyAPIKeyKey holds the single key per user of older versions, migrated on boot
+func LegacyAPIKeyKey(userID string) string {
 	return fmtKey("api_key:%s", userID)
 }
 
@@ -77,6 +86,66 @@ func ChangeRankKey() string {
 	return fmtKey("market:sorted:change_24h")
 }
 
+// PumpScoreProfilesKey holds the admin-defined pump score profiles (hash of name → JSON)
+func PumpScoreProfilesKey() string {
+	return fmtKey("market:pump_score_profiles")
+}
+
+// PumpScoreProfileRankKey ranks pairs by the score of a profile
+func PumpScoreProfileRankKey(profile string) string {
+	return fmtKey("market:sorted:pump_score:%s", profile)
+}
+
+// ScreenKey holds a saved screener query
+func ScreenKey(screenID int64) string {
+	return fmtKey("screen:%d", screenID)
+}
+
+// UserScreensKey indexes the saved screens of a user
+func UserScreensKey(userID string) string {
+	return fmtKey("user_screens:%s", userID)
+}
+
+// StreamingScreensKey indexes the screens streamed over the WebSocket
+func StreamingScreensKey() string {
+	return fmtKey("screens:streaming")
+}
+
+// AlertKey holds a user alert
+func AlertKey(alertID int64) string {
+	return fmtKey("alert:%d", alertID)
+}
+
+// UserAlertsKey indexes the alerts of a user
+func UserAlertsKey(userID string) string {
+	return fmtKey("user_alerts:%s", userID)
+}
+
+// ActiveAlertsKey indexes the alerts evaluated on market updates
+func ActiveAlertsKey() string {
+	return fmtKey("alerts:active")
+}
+
+// BacktestKey holds a backtest job and its result
+func BacktestKey(backtestID int64) string {
+	return fmtKey("backtest:%d", backtestID)
+}
+
+// UserBacktestsKey indexes the backtests of a user
+func UserBacktestsKey(userID string) string {
+	return fmtKey("user_backtests:%s", userID)
+}
+
+// ActiveBacktestsKey indexes the queued and running backtests
+func ActiveBacktestsKey() string {
+	return fmtKey("backtests:active")
+}
+
+// CandlesKey holds the closed candles of a pair and timeframe, scored by open time
+func CandlesKey(pair, timeframe string) string {
+	return fmtKey("candles:%s:%s", pair, timeframe)
+}
+
 // Trade keys (for Copilot/Bot trades)
 func TradeKey(tradeID string) string {
 	return fmtKey("trade:%s", tradeID)
@@ -184,8 +253,9 @@ func IndodaxWSTokenKey(userID string) string {
 }
 
 // Cache keys
+// CachePairsKey holds []exchange.Pair (v2: exchange-neutral format, replaces raw Indodax pairs)
 func CachePairsKey() string {
-	return fmtKey("cache:pairs")
+	return fmtKey("cache:pairs:v2")
 }
 
 func CachePriceIncrementsKey() string {
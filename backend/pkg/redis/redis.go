@@ -144,6 +144,11 @@ func (c *Client) ZRevRangeWithScores(ctx context.Context, key string, start, sto
 	return c.client.ZRevRangeWithScores(ctx, key, start, stop).Result()
 }
 
+// ZRevRangeByScore gets members from a sorted set by score range (descending)
+func (c *Client) ZRevRangeByScore(ctx context.Context, key, max, min string, offset, count int64) ([]string, error) {
+	return c.client.ZRevRangeByScore(ctx, key, &redis.ZRangeBy{Min: min, Max: max, Offset: offset, Count: count}).Result()
+}
+
 // ZRem removes members from a sorted set
 func (c *Client) ZRem(ctx context.Context, key string, members ...interface{}) error {
 	return c.client.ZRem(ctx, key, members...).Err()